@@ -0,0 +1,86 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"errors"
+	"sync"
+)
+
+// adaptiveRepetitions is the max-repetitions tuned for an agent, see
+// GoSNMP.AdaptiveMaxRepetitions
+type adaptiveRepetitions struct {
+	mu    sync.Mutex
+	reps  int // 0 until the first walk
+	limit int // below the smallest max-repetitions that failed, 0 if none has
+}
+
+func (x *GoSNMP) adaptiveState() *adaptiveRepetitions {
+	lazyInitMu.Lock()
+	defer lazyInitMu.Unlock()
+	if x.adaptive == nil {
+		x.adaptive = new(adaptiveRepetitions)
+	}
+	return x.adaptive
+}
+
+// bulkRepetitions returns the max-repetitions for the next GETBULK of a
+// walk, where max is the configured value
+func (x *GoSNMP) bulkRepetitions(max int) int {
+	if !x.AdaptiveMaxRepetitions {
+		return max
+	}
+	a := x.adaptiveState()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.reps == 0 || a.reps > max {
+		a.reps = max
+	}
+	return a.reps
+}
+
+// adaptRepetitions tunes the max-repetitions after a GETBULK asking for
+// reps repetitions, reporting whether the request should be retried with
+// fewer
+func (x *GoSNMP) adaptRepetitions(reps, max int, response *SnmpPacket, err error) (retry bool) {
+	if !x.AdaptiveMaxRepetitions {
+		return false
+	}
+	a := x.adaptiveState()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	switch {
+	case err != nil && isTimeout(err), err == nil && response.Error == TooBig:
+		if reps <= 1 {
+			return false
+		}
+		a.reps = reps / 2
+		if a.limit == 0 || reps-1 < a.limit {
+			a.limit = reps - 1
+		}
+		x.logPrintf("Reducing max-repetitions to %d", a.reps)
+		return true
+	case err != nil || response.Error != NoError:
+	case len(response.Variables) > 0 && len(response.Variables) < reps &&
+		response.Variables[len(response.Variables)-1].Type != EndOfMibView:
+		// the agent returned as many as fit in its response
+		a.reps = len(response.Variables)
+	case a.reps < max && a.reps != a.limit:
+		a.reps += a.reps/4 + 1
+		if a.reps > max {
+			a.reps = max
+		}
+		if a.limit > 0 && a.reps > a.limit {
+			a.reps = a.limit
+		}
+	}
+	return false
+}
+
+// isTimeout reports whether err is a request timing out
+func isTimeout(err error) bool {
+	return errors.Is(err, ErrTimeout)
+}
@@ -0,0 +1,109 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestAdaptiveMaxRepetitions(t *testing.T) {
+	var pdus []SnmpPDU
+	for i := 1; i <= 60; i++ {
+		pdus = append(pdus, SnmpPDU{Name: fmt.Sprintf(".1.3.6.1.2.1.2.2.1.2.%d", i), Type: Integer, Value: i})
+	}
+	r := newTestResponder(t, pdus)
+	defer r.Close()
+
+	// agents that fail in different ways when asked for more than 10
+	tests := []struct {
+		name    string
+		handler func(req *SnmpPacket) *SnmpPacket
+		reps    int // the max-repetitions tuned to
+	}{
+		{"tooBig", func(req *SnmpPacket) *SnmpPacket {
+			if req.MaxRepetitions > 10 {
+				return &SnmpPacket{Error: TooBig, Variables: req.Variables}
+			}
+			return r.respond(req)
+		}, 10},
+		{"timeout", func(req *SnmpPacket) *SnmpPacket {
+			if req.MaxRepetitions > 10 {
+				return nil
+			}
+			return r.respond(req)
+		}, 10},
+		{"truncated", func(req *SnmpPacket) *SnmpPacket {
+			rsp := r.respond(req)
+			if len(rsp.Variables) > 10 {
+				rsp.Variables = rsp.Variables[:10]
+			}
+			return rsp
+		}, 10},
+	}
+	for _, test := range tests {
+		r.setHandler(test.handler)
+		x := r.client(t)
+		x.Timeout = 50 * time.Millisecond
+		x.Retries = 0
+		x.MaxRepetitions = 40
+		x.AdaptiveMaxRepetitions = true
+
+		results, err := x.BulkWalkAll(".1.3.6.1.2.1.2.2")
+		x.Conn.Close()
+		if err != nil {
+			t.Errorf("%s: BulkWalkAll() err: %v", test.name, err)
+			continue
+		}
+		if len(results) != len(pdus) {
+			t.Errorf("%s: got %d results, want %d", test.name, len(results), len(pdus))
+		}
+		if reps := x.bulkRepetitions(40); reps != test.reps {
+			t.Errorf("%s: max-repetitions tuned to %d, want %d", test.name, reps, test.reps)
+		}
+	}
+
+	// without tuning, the walk doesn't get past the first tooBig
+	r.setHandler(tests[0].handler)
+	x := r.client(t)
+	defer x.Conn.Close()
+	x.MaxRepetitions = 40
+	if results, _ := x.BulkWalkAll(".1.3.6.1.2.1.2.2"); len(results) == len(pdus) {
+		t.Errorf("untuned walk: got all %d results", len(results))
+	}
+}
+
+func TestAdaptiveMaxRepetitionsGrowth(t *testing.T) {
+	x := &GoSNMP{AdaptiveMaxRepetitions: true}
+	if reps := x.bulkRepetitions(40); reps != 40 {
+		t.Fatalf("got initial max-repetitions %d, want 40", reps)
+	}
+	if retry := x.adaptRepetitions(40, 40, &SnmpPacket{Error: TooBig}, nil); !retry {
+		t.Errorf("no retry after tooBig")
+	}
+	if reps := x.bulkRepetitions(40); reps != 20 {
+		t.Fatalf("got max-repetitions %d after tooBig, want 20", reps)
+	}
+
+	// grows back to just below the max-repetitions that failed
+	whole := &SnmpPacket{Variables: make([]SnmpPDU, 40)}
+	var got []int
+	for i := 0; i < 5; i++ {
+		x.adaptRepetitions(x.bulkRepetitions(40), 40, whole, nil)
+		got = append(got, x.bulkRepetitions(40))
+	}
+	if want := []int{26, 33, 39, 39, 39}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got growth %v, want %v", got, want)
+	}
+
+	if retry := x.adaptRepetitions(1, 40, &SnmpPacket{Error: TooBig}, nil); retry {
+		t.Errorf("retry with max-repetitions of 1")
+	}
+	untuned := &GoSNMP{}
+	if retry := untuned.adaptRepetitions(40, 40, &SnmpPacket{Error: TooBig}, nil); retry {
+		t.Errorf("retry without AdaptiveMaxRepetitions")
+	}
+}
@@ -0,0 +1,864 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+//
+// Agent, ie GoSNMP acting as an SNMP agent, answering requests
+//
+
+// defaultAgentMaxMessageSize is the largest response an Agent sends unless
+// MaxMessageSize is set, a udp datagram that fits an ethernet frame
+const defaultAgentMaxMessageSize = 1472
+
+// agentTimeWindow is how far (in seconds) the engine time of an SNMPv3
+// request may be from the agent's, RFC 3414 section 3.2
+const agentTimeWindow = 150
+
+// the usmStats counters, reported to SNMPv3 managers whose requests an
+// Agent can't process
+const (
+	usmStatsUnsupportedSecLevels = ".1.3.6.1.6.3.15.1.1.1.0"
+	usmStatsNotInTimeWindows     = ".1.3.6.1.6.3.15.1.1.2.0"
+	usmStatsUnknownUserNames     = ".1.3.6.1.6.3.15.1.1.3.0"
+	usmStatsUnknownEngineIDs     = ".1.3.6.1.6.3.15.1.1.4.0"
+	usmStatsWrongDigests         = ".1.3.6.1.6.3.15.1.1.5.0"
+	usmStatsDecryptionErrors     = ".1.3.6.1.6.3.15.1.1.6.0"
+)
+
+// AgentHandler serves an object registered with an Agent, see
+// Agent.Register
+type AgentHandler interface {
+	// Get returns the object's value, as a varbind whose Name is ignored.
+	// A NoSuchInstance varbind says that the object has no value, eg
+	// because it isn't available just now; GETNEXT skips over it.
+	Get(oid string) (SnmpPDU, error)
+
+	// Set sets the object to the value of pdu. An SNMPError (eg WrongType
+	// or NotWritable) is returned to the manager as the error-status of
+	// the response; other errors as genErr. Handlers of objects set along
+	// with others should be SetTesters and SetUndoers too.
+	Set(pdu SnmpPDU) error
+}
+
+// AgentHandlerFunc is an AgentHandler for a read-only object, whose value
+// is returned by the function. Sets fail with NotWritable.
+type AgentHandlerFunc func(oid string) (SnmpPDU, error)
+
+// Get calls f(oid)
+func (f AgentHandlerFunc) Get(oid string) (SnmpPDU, error) {
+	return f(oid)
+}
+
+// Set fails with NotWritable
+func (f AgentHandlerFunc) Set(pdu SnmpPDU) error {
+	return NotWritable
+}
+
+// Agent answers the GET, GETNEXT, GETBULK and SET requests of managers,
+// for the objects registered with it. SNMPv1 and SNMPv2c requests are
+// answered if they have the agent's Community (or for SET, its
+// WriteCommunity), and SNMPv3 requests if they are from one of its Users,
+// at the security level of the user's protocols (others are answered with
+// a usmStatsUnsupportedSecLevels report). Users who authenticate can read
+// and write all objects, and noAuthNoPriv users only read them.
+//
+// Requests are read from connections given to Serve (udp) or ServeTCP
+// (tcp, see RFC 3430), or opened by Listen, and are answered one at a
+// time on each connection. An Agent is safe for concurrent use, but must
+// not be copied after first use.
+type Agent struct {
+	// Community is the community of SNMPv1 and SNMPv2c requests; requests
+	// with other communities are dropped (default: "public")
+	Community string
+
+	// WriteCommunity is the community of SNMPv1 and SNMPv2c SETs. SETs
+	// with other communities fail with noAccess, so if it isn't set there
+	// are none. Requests other than SETs may have it instead of Community.
+	WriteCommunity string
+
+	// Users are the SNMPv3 users: their UserName, protocols and
+	// passphrases. The keys are localized to EngineID when the agent
+	// starts serving, after which changes aren't seen.
+	Users []*UsmSecurityParameters
+
+	// EngineID is the agent's snmpEngineID, the authoritative engine of
+	// its SNMPv3 messages (default: random, for the life of the Agent).
+	// EngineBoots is the number of times the agent has restarted with the
+	// EngineID, which should be kept by the application and incremented
+	// on each restart.
+	EngineID    string
+	EngineBoots uint32
+
+	// MaxMessageSize is the size of the largest response. GETBULK responses
+	// are cut short to fit, and other requests that would need a larger
+	// response fail with tooBig. It is the msgMaxSize of SNMPv3 responses,
+	// which are no larger than the msgMaxSize of their request either.
+	// (default: 1472)
+	MaxMessageSize int
+
+	// Vacm, if set, decides which objects can be read and written by
+	// whom. SNMPv1 and SNMPv2c requests are then answered if their
+	// community is in a group, rather than if it is Community or
+	// WriteCommunity, and SNMPv3 requests are given the access of the
+	// user's group.
+	Vacm *Vacm
+
+	// Proxy, if set, forwards the requests for some communities and
+	// contexts to other agents, rather than the agent answering them
+	Proxy *ProxyForwarder
+
+	// DecodeLimits, if set, makes the decoding of requests strict, as for
+	// GoSNMP.DecodeLimits
+	DecodeLimits *DecodeLimits
+
+	// Logger is given the debugging output, as for GoSNMP.Logger
+	Logger Logger
+
+	mu       sync.RWMutex
+	setMu    sync.Mutex    // held for the phases of a SET
+	objects  []agentObject // in oid order, replaced rather than changed
+	closed   bool
+	closers  map[io.Closer]struct{} // connections and listeners to Close
+	once     sync.Once
+	x        *GoSNMP // for decoding and logging
+	engineID string
+	start    time.Time
+	users    map[string]*agentUser
+
+	// usmStats counters, in the order of their oids
+	unsupportedSecLevels, notInTimeWindows, unknownUserNames,
+	unknownEngineIDs, wrongDigests, decryptionErrors uint32
+}
+
+// agentObject is an object, or subtree, registered with an Agent
+type agentObject struct {
+	oid     Oid
+	name    string // oid, formatted
+	handler AgentHandler
+	subtree SubtreeHandler // set for a subtree, as is handler
+}
+
+// agentUser is an SNMPv3 user, with keys localized to the agent's engine
+type agentUser struct {
+	sp    *UsmSecurityParameters // not changed, as it's copied unlocked
+	salts *UsmSecurityParameters // allocates the salts of responses
+	x     *GoSNMP                // decodes the user's requests
+}
+
+// level returns the security level of the user's requests, that of its
+// protocols
+func (u *agentUser) level() SnmpV3MsgFlags {
+	switch {
+	case u.sp.AuthenticationProtocol <= NoAuth:
+		return NoAuthNoPriv
+	case u.sp.PrivacyProtocol <= NoPriv:
+		return AuthNoPriv
+	}
+	return AuthPriv
+}
+
+// Register registers handler to serve the object instance oid, eg
+// ".1.3.6.1.2.1.1.5.0" for sysName. Registering an oid again replaces its
+// handler.
+func (a *Agent) Register(oid string, handler AgentHandler) error {
+	o, err := ParseOid(oid)
+	if err != nil {
+		return err
+	}
+	a.register(agentObject{oid: o, name: o.String(), handler: handler})
+	return nil
+}
+
+// register adds object, replacing any registered at its oid
+func (a *Agent) register(object agentObject) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	i := sort.Search(len(a.objects), func(i int) bool {
+		return a.objects[i].oid.Compare(object.oid) >= 0
+	})
+	objects := make([]agentObject, 0, len(a.objects)+1)
+	objects = append(objects, a.objects[:i]...)
+	objects = append(objects, object)
+	if i < len(a.objects) && a.objects[i].oid.Equal(object.oid) {
+		i++
+	}
+	a.objects = append(objects, a.objects[i:]...)
+}
+
+// Unregister removes the object or subtree registered at oid, if any
+func (a *Agent) Unregister(oid string) {
+	o, err := ParseOid(oid)
+	if err != nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for i, object := range a.objects {
+		if object.oid.Equal(o) {
+			objects := make([]agentObject, 0, len(a.objects)-1)
+			objects = append(objects, a.objects[:i]...)
+			a.objects = append(objects, a.objects[i+1:]...)
+			return
+		}
+	}
+}
+
+// Listen listens on addr, for network "udp" or "tcp" (or "udp4" etc), and
+// answers requests until the agent is closed.
+func (a *Agent) Listen(network, addr string) error {
+	switch network {
+	case "udp", "udp4", "udp6":
+		conn, err := net.ListenPacket(network, addr)
+		if err != nil {
+			return err
+		}
+		return a.Serve(conn)
+	case "tcp", "tcp4", "tcp6":
+		l, err := net.Listen(network, addr)
+		if err != nil {
+			return err
+		}
+		return a.ServeTCP(l)
+	}
+	return fmt.Errorf("Unsupported network %q", network)
+}
+
+// Serve answers the requests read from conn until the agent is closed,
+// when it returns nil, or reading fails.
+func (a *Agent) Serve(conn net.PacketConn) error {
+	if !a.track(conn) {
+		return nil
+	}
+	defer a.untrack(conn)
+	buf := make([]byte, rxBufSize)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			if a.isClosed() {
+				return nil
+			}
+			return err
+		}
+		if response := a.handle(buf[:n]); response != nil {
+			if _, err = conn.WriteTo(response, addr); err != nil {
+				a.x.logWarn("Unable to send response", "addr", addr, "err", err)
+			}
+		}
+	}
+}
+
+// ServeTCP answers the requests on the connections accepted from l until
+// the agent is closed, when it returns nil, or accepting fails.
+func (a *Agent) ServeTCP(l net.Listener) error {
+	if !a.track(l) {
+		return nil
+	}
+	defer a.untrack(l)
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if a.isClosed() {
+				return nil
+			}
+			return err
+		}
+		if !a.track(conn) {
+			return nil
+		}
+		go func() {
+			defer a.untrack(conn)
+			a.serveStream(conn)
+		}()
+	}
+}
+
+// serveStream answers the requests on a tcp connection, each a BER encoded
+// message, until it is closed
+func (a *Agent) serveStream(conn net.Conn) {
+	for {
+		msg, err := readStreamMessage(conn)
+		if err != nil {
+			if err != io.EOF && !a.isClosed() {
+				a.x.logWarn("Unable to read request", "addr", conn.RemoteAddr(), "err", err)
+			}
+			return
+		}
+		if response := a.handle(msg); response != nil {
+			if _, err = conn.Write(response); err != nil {
+				a.x.logWarn("Unable to send response", "addr", conn.RemoteAddr(), "err", err)
+				return
+			}
+		}
+	}
+}
+
+// readStreamMessage reads a BER encoded message from a stream
+func readStreamMessage(r io.Reader) ([]byte, error) {
+	header := make([]byte, 2, 6)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	if header[0] != byte(Sequence) {
+		return nil, fmt.Errorf("Message doesn't start with a sequence: %#x", header[0])
+	}
+	length := int(header[1])
+	if length > 0x80 {
+		n := length & 0x7f
+		if n > 4 {
+			return nil, fmt.Errorf("Message length of %d bytes is too long", n)
+		}
+		header = header[:2+n]
+		if _, err := io.ReadFull(r, header[2:]); err != nil {
+			return nil, err
+		}
+		length = 0
+		for _, b := range header[2:] {
+			length = length<<8 | int(b)
+		}
+	} else if length == 0x80 {
+		return nil, fmt.Errorf("Message has an indefinite length")
+	}
+	if length > rxBufSize {
+		return nil, fmt.Errorf("Message of %d bytes is too large", length)
+	}
+	msg := make([]byte, len(header)+length)
+	copy(msg, header)
+	if _, err := io.ReadFull(r, msg[len(header):]); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// Close closes the connections and listeners the agent is serving,
+// stopping Serve and ServeTCP.
+func (a *Agent) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.closed = true
+	for c := range a.closers {
+		c.Close()
+	}
+	a.closers = nil
+	return nil
+}
+
+// track adds c to the connections closed by Close, returning false (having
+// closed it) if the agent is closed already
+func (a *Agent) track(c io.Closer) bool {
+	a.init()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.closed {
+		c.Close()
+		return false
+	}
+	if a.closers == nil {
+		a.closers = make(map[io.Closer]struct{})
+	}
+	a.closers[c] = struct{}{}
+	return true
+}
+
+func (a *Agent) untrack(c io.Closer) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.closers, c)
+	c.Close()
+}
+
+func (a *Agent) isClosed() bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.closed
+}
+
+// init sets up the agent's engine and users, when it starts serving
+func (a *Agent) init() {
+	a.once.Do(func() {
+		a.x = &GoSNMP{Logger: a.Logger, DecodeLimits: a.DecodeLimits}
+		a.x.validateParameters()
+		a.start = time.Now()
+		a.engineID = a.EngineID
+		if a.engineID == "" {
+			a.engineID = randomEngineID()
+		}
+		a.users = make(map[string]*agentUser)
+		for _, user := range a.Users {
+			sp := user.Copy().(*UsmSecurityParameters)
+			sp.AuthoritativeEngineID = a.engineID
+			sp.secretKey, sp.privacyKey = nil, nil
+			sp.localizeKeys()
+			sp.Logger = a.x.Logger
+			salts := &UsmSecurityParameters{PrivacyProtocol: sp.PrivacyProtocol}
+			if err := salts.init(a.x.Logger); err != nil {
+				a.x.logError("Unable to set up SNMPv3 user", "user", sp.UserName, "err", err)
+				continue
+			}
+			x := &GoSNMP{Logger: a.x.Logger, loggingEnabled: a.x.loggingEnabled, SecurityParameters: sp,
+				DecodeLimits: a.DecodeLimits}
+			a.users[sp.UserName] = &agentUser{sp: sp, salts: salts, x: x}
+		}
+	})
+}
+
+// engineTime is the agent's snmpEngineTime, the seconds since it started
+func (a *Agent) engineTime() uint32 {
+	return uint32(time.Since(a.start) / time.Second)
+}
+
+// handle returns the response to msg, or nil if there is none
+func (a *Agent) handle(msg []byte) (response []byte) {
+	defer func() {
+		if e := recover(); e != nil {
+			a.x.logError("Panic handling request", "err", e)
+			response = nil
+		}
+	}()
+
+	// the header, to find the version and for SNMPv3 the user
+	header := &SnmpPacket{SecurityParameters: &UsmSecurityParameters{Logger: a.x.Logger}}
+	if _, err := a.x.unmarshalHeader(append([]byte(nil), msg...), header); err != nil {
+		a.x.logWarn("Unable to decode request header", "err", err)
+		return nil
+	}
+	if header.Version == Version3 {
+		return a.handleV3(msg, header)
+	}
+
+	community := a.Community
+	if community == "" {
+		community = "public"
+	}
+	canWrite := a.WriteCommunity != "" && header.Community == a.WriteCommunity
+	known := header.Community == community || canWrite
+	if a.Vacm != nil {
+		known = a.Vacm.hasGroup(header.Version, header.Community)
+	}
+	target := a.Proxy.target(header)
+	if !known && target == nil {
+		a.x.logWarn("Dropping request with the wrong community", "version", header.Version)
+		return nil
+	}
+	request, err := a.x.Decode(msg)
+	if err != nil {
+		a.x.logWarn("Unable to decode request", "err", err)
+		return nil
+	}
+	reply := &SnmpPacket{
+		Version:   request.Version,
+		Community: request.Community,
+	}
+	if target != nil {
+		return a.forward(request, reply, target)
+	}
+	return a.respond(request, reply, a.access(request.Version, request.Community, NoAuthNoPriv, "", canWrite))
+}
+
+// handleV3 returns the response to an SNMPv3 message, whose header has been
+// decoded; or a report, if it can't be processed
+func (a *Agent) handleV3(msg []byte, header *SnmpPacket) []byte {
+	if header.SecurityModel != UserSecurityModel {
+		a.x.logWarn("Dropping request with an unsupported security model", "model", header.SecurityModel)
+		return nil
+	}
+	sp := header.SecurityParameters.(*UsmSecurityParameters)
+	if sp.AuthoritativeEngineID != a.engineID {
+		// eg the discovery of the engine by a manager
+		return a.report(msg, header, nil, usmStatsUnknownEngineIDs, &a.unknownEngineIDs)
+	}
+	user := a.users[sp.UserName]
+	if user == nil {
+		return a.report(msg, header, nil, usmStatsUnknownUserNames, &a.unknownUserNames)
+	}
+	if header.MsgFlags&AuthPriv != user.level() {
+		// requests at a lower level than the user's protocols would go
+		// unauthenticated, and those at a higher level can't be checked
+		return a.report(msg, header, nil, usmStatsUnsupportedSecLevels, &a.unsupportedSecLevels)
+	}
+
+	request, err := user.x.Decode(msg)
+	switch {
+	case errors.Is(err, ErrAuthentication):
+		return a.report(msg, header, nil, usmStatsWrongDigests, &a.wrongDigests)
+	case err != nil && header.MsgFlags&AuthPriv > AuthNoPriv:
+		return a.report(msg, header, nil, usmStatsDecryptionErrors, &a.decryptionErrors)
+	case err != nil:
+		a.x.logWarn("Unable to decode request", "user", sp.UserName, "err", err)
+		return nil
+	}
+
+	if request.MsgFlags&AuthNoPriv > 0 {
+		rsp := request.SecurityParameters.(*UsmSecurityParameters)
+		now := a.engineTime()
+		if rsp.AuthoritativeEngineBoots != a.EngineBoots ||
+			rsp.AuthoritativeEngineTime > now+agentTimeWindow ||
+			rsp.AuthoritativeEngineTime+agentTimeWindow < now {
+			return a.report(msg, request, user, usmStatsNotInTimeWindows, &a.notInTimeWindows)
+		}
+	}
+	response := &SnmpPacket{
+		Version:            Version3,
+		MsgID:              request.MsgID,
+		MsgFlags:           request.MsgFlags &^ Reportable,
+		SecurityModel:      UserSecurityModel,
+		SecurityParameters: a.usm(user, request.MsgFlags),
+		ContextEngineID:    request.ContextEngineID,
+		ContextName:        request.ContextName,
+		MsgMaxSize:         a.maxMessageSize(),
+	}
+	if target := a.Proxy.target(request); target != nil {
+		return a.forward(request, response, target)
+	}
+	// unauthenticated requests can't write, unless the Vacm says so
+	canWrite := request.MsgFlags&AuthNoPriv > 0
+	return a.respond(request, response, a.access(Version3, sp.UserName, request.MsgFlags, request.ContextName, canWrite))
+}
+
+// agentAccess is what a request may access
+type agentAccess struct {
+	vacm        *Vacm // if nil, all objects may be read, and written if canWrite
+	canWrite    bool
+	read, write string // the views of vacm, "" if there is none
+}
+
+// access returns what a request from securityName may access
+func (a *Agent) access(version SnmpVersion, securityName string, level SnmpV3MsgFlags,
+	context string, canWrite bool) *agentAccess {
+	access := &agentAccess{vacm: a.Vacm, canWrite: canWrite}
+	if a.Vacm != nil {
+		access.read, _ = a.Vacm.view(version, securityName, level, context, VacmRead)
+		access.write, _ = a.Vacm.view(version, securityName, level, context, VacmWrite)
+	}
+	return access
+}
+
+// readable reports whether oid may be read
+func (access *agentAccess) readable(oid Oid) bool {
+	return access.vacm == nil || access.read != "" && access.vacm.inView(access.read, oid)
+}
+
+// writable reports whether oid may be written
+func (access *agentAccess) writable(oid Oid) bool {
+	if access.vacm == nil {
+		return access.canWrite
+	}
+	return access.write != "" && access.vacm.inView(access.write, oid)
+}
+
+// usm returns the security parameters of a message from the agent to user
+// with flags, or of an unauthenticated message if user is nil
+func (a *Agent) usm(user *agentUser, flags SnmpV3MsgFlags) *UsmSecurityParameters {
+	sp := &UsmSecurityParameters{Logger: a.x.Logger}
+	if user != nil {
+		sp = user.sp.Copy().(*UsmSecurityParameters)
+	}
+	sp.AuthoritativeEngineID = a.engineID
+	sp.AuthoritativeEngineBoots = a.EngineBoots
+	sp.AuthoritativeEngineTime = a.engineTime()
+	if user != nil && flags&AuthPriv > AuthNoPriv {
+		salt, _ := user.salts.usmAllocateNewSalt()
+		sp.usmSetSalt(salt)
+	}
+	return sp
+}
+
+// report returns the Report of a usmStats counter for an SNMPv3 request
+// that can't be processed, if the request is reportable. The report is
+// authenticated for user, if set.
+func (a *Agent) report(msg []byte, request *SnmpPacket, user *agentUser, oid string, counter *uint32) []byte {
+	count := atomic.AddUint32(counter, 1)
+	a.x.logInfo("Reporting SNMPv3 request", "counter", oid)
+	if request.MsgFlags&Reportable == 0 {
+		return nil
+	}
+	if request.RequestID == 0 && request.MsgFlags&AuthNoPriv == 0 {
+		// the scoped PDU is in plaintext, so the request-id can be echoed
+		if decoded, err := a.x.Decode(msg); err == nil {
+			request = decoded
+		}
+	}
+	flags := NoAuthNoPriv
+	if user != nil {
+		flags = AuthNoPriv
+	}
+	report := &SnmpPacket{
+		Version:            Version3,
+		MsgID:              request.MsgID,
+		MsgFlags:           flags,
+		SecurityModel:      UserSecurityModel,
+		SecurityParameters: a.usm(user, flags),
+		ContextEngineID:    a.engineID,
+		ContextName:        request.ContextName,
+		MsgMaxSize:         a.maxMessageSize(),
+		PDUType:            Report,
+		RequestID:          request.RequestID,
+		Variables:          []SnmpPDU{{Name: oid, Type: Counter32, Value: count}},
+	}
+	if user == nil {
+		report.SecurityParameters.(*UsmSecurityParameters).UserName =
+			request.SecurityParameters.(*UsmSecurityParameters).UserName
+	}
+	out, err := report.marshalMsg()
+	if err != nil {
+		a.x.logError("Unable to encode report", "err", err)
+		return nil
+	}
+	return out
+}
+
+// respond processes request, returning response, whose header has been
+// set, encoded; or nil if there is no response to the request
+func (a *Agent) respond(request, response *SnmpPacket, access *agentAccess) []byte {
+	response.PDUType = GetResponse
+	response.RequestID = request.RequestID
+
+	switch request.PDUType {
+	case GetRequest, GetNextRequest, GetBulkRequest:
+		if access.vacm != nil && access.read == "" {
+			response.Error = AuthorizationError
+			break
+		}
+		switch request.PDUType {
+		case GetRequest:
+			response.Variables, response.Error, response.ErrorIndex = a.get(request.Variables, access)
+		case GetNextRequest:
+			response.Variables, response.Error, response.ErrorIndex = a.getNext(request.Variables, access)
+		default:
+			if request.Version == Version1 {
+				return nil
+			}
+			return a.getBulk(request, response, access)
+		}
+	case SetRequest:
+		switch {
+		case access.vacm != nil && access.write == "":
+			response.Error = AuthorizationError
+		case access.vacm == nil && !access.canWrite:
+			response.Error, response.ErrorIndex = NoAccess, 1
+		default:
+			response.Error, response.ErrorIndex = a.set(request.Variables, access)
+		}
+	default:
+		a.x.logWarn("Dropping unsupported request", "type", request.PDUType)
+		return nil
+	}
+	if response.Error != NoError || request.PDUType == SetRequest {
+		response.Variables = request.Variables
+	}
+	if request.Version == Version1 {
+		toV1Response(response, request)
+	}
+	return a.encode(request, response)
+}
+
+// encode encodes the response to request, or a tooBig response if it
+// doesn't fit the largest message of responseSize
+func (a *Agent) encode(request, response *SnmpPacket) []byte {
+	out, err := response.marshalMsg()
+	if err != nil {
+		a.x.logError("Unable to encode response", "err", err)
+		response.Error, response.ErrorIndex = GenErr, 0
+		out, err = response.marshalMsg()
+		if err != nil {
+			return nil
+		}
+	}
+	if len(out) > a.responseSize(request) && response.Error != TooBig {
+		response.Variables = nil
+		response.Error, response.ErrorIndex = TooBig, 0
+		return a.encode(request, response)
+	}
+	return out
+}
+
+func (a *Agent) maxMessageSize() int {
+	if a.MaxMessageSize > 0 {
+		return a.MaxMessageSize
+	}
+	return defaultAgentMaxMessageSize
+}
+
+// responseSize returns the largest response to request: MaxMessageSize,
+// or the msgMaxSize of an SNMPv3 request if that is smaller
+func (a *Agent) responseSize(request *SnmpPacket) int {
+	size := a.maxMessageSize()
+	if request.Version == Version3 && request.MsgMaxSize >= minMsgMaxSize && request.MsgMaxSize < size {
+		size = request.MsgMaxSize
+	}
+	return size
+}
+
+// lookup returns the agent's objects
+func (a *Agent) lookup() []agentObject {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.objects
+}
+
+func (a *Agent) get(pdus []SnmpPDU, access *agentAccess) ([]SnmpPDU, SNMPError, uint8) {
+	objects := a.lookup()
+	results := make([]SnmpPDU, len(pdus))
+	for i, pdu := range pdus {
+		oid, err := pdu.Oid()
+		if err != nil {
+			return nil, GenErr, uint8(i + 1)
+		}
+		object := agentOwner(objects, oid, true)
+		if object == nil || !access.readable(oid) {
+			results[i] = SnmpPDU{Name: pdu.Name, Type: NoSuchObject}
+			continue
+		}
+		name := oid.String()
+		if results[i], err = object.handler.Get(name); err != nil {
+			a.x.logWarn("Get failed", "oid", name, "err", err)
+			return nil, agentErrorStatus(err), uint8(i + 1)
+		}
+		results[i].Name = name
+	}
+	return results, NoError, 0
+}
+
+func (a *Agent) getNext(pdus []SnmpPDU, access *agentAccess) ([]SnmpPDU, SNMPError, uint8) {
+	objects := a.lookup()
+	results := make([]SnmpPDU, len(pdus))
+	for i, pdu := range pdus {
+		oid, err := pdu.Oid()
+		if err != nil {
+			return nil, GenErr, uint8(i + 1)
+		}
+		if results[i], err = a.next(objects, oid, access.readable); err != nil {
+			return nil, agentErrorStatus(err), uint8(i + 1)
+		}
+	}
+	return results, NoError, 0
+}
+
+// getBulk returns response to a GETBULK request encoded, with as many
+// repetitions as fit in its responseSize
+func (a *Agent) getBulk(request, response *SnmpPacket, access *agentAccess) []byte {
+	// the space left for varbinds, less a little for the lengths and
+	// encryption padding growing
+	empty, err := response.marshalMsg()
+	if err != nil {
+		a.x.logError("Unable to encode response", "err", err)
+		return nil
+	}
+	space := a.responseSize(request) - len(empty) - 16
+
+	objects := a.lookup()
+	nonRepeaters := int(request.NonRepeaters)
+	if nonRepeaters > len(request.Variables) {
+		nonRepeaters = len(request.Variables)
+	}
+	add := func(pdu SnmpPDU) bool {
+		encoded, err := marshalVarbind(&pdu)
+		if err != nil {
+			a.x.logWarn("Unable to encode value", "oid", pdu.Name, "err", err)
+			return false
+		}
+		if space -= len(encoded); space < 0 {
+			return false
+		}
+		response.Variables = append(response.Variables, pdu)
+		return true
+	}
+	fail := func(err error, index int) []byte {
+		response.Variables = request.Variables
+		response.Error, response.ErrorIndex = agentErrorStatus(err), uint8(index)
+		return a.encode(request, response)
+	}
+
+	for i, pdu := range request.Variables[:nonRepeaters] {
+		oid, err := pdu.Oid()
+		if err != nil {
+			return fail(GenErr, i+1)
+		}
+		next, err := a.next(objects, oid, access.readable)
+		if err != nil {
+			return fail(err, i+1)
+		}
+		if !add(next) {
+			return a.encode(request, response)
+		}
+	}
+
+	repeaters := request.Variables[nonRepeaters:]
+	last := make([]Oid, len(repeaters))
+	for i, pdu := range repeaters {
+		if last[i], err = pdu.Oid(); err != nil {
+			return fail(GenErr, nonRepeaters+i+1)
+		}
+	}
+	for r := 0; r < int(request.MaxRepetitions) && len(repeaters) > 0; r++ {
+		ended := 0
+		for i := range repeaters {
+			next, err := a.next(objects, last[i], access.readable)
+			if err != nil {
+				return fail(err, nonRepeaters+i+1)
+			}
+			if next.Type == EndOfMibView {
+				ended++
+			} else {
+				last[i], _ = ParseOid(next.Name)
+			}
+			if !add(next) {
+				return a.encode(request, response)
+			}
+		}
+		if ended == len(repeaters) {
+			break
+		}
+	}
+	return a.encode(request, response)
+}
+
+// agentErrorStatus returns the error-status for an error from a handler
+func agentErrorStatus(err error) SNMPError {
+	var status SNMPError
+	var statusErr *StatusError
+	switch {
+	case errors.As(err, &statusErr):
+		return statusErr.Status
+	case errors.As(err, &status):
+		return status
+	}
+	return GenErr
+}
+
+// toV1Response changes an SNMPv2 response to request into an SNMPv1 one,
+// as RFC 3584 section 4.4 says: exceptions become noSuchName errors, and
+// the errors SNMPv1 doesn't have are mapped to those it does
+func toV1Response(response, request *SnmpPacket) {
+	if response.Error == NoError {
+		for i, pdu := range response.Variables {
+			switch pdu.Type {
+			case NoSuchObject, NoSuchInstance, EndOfMibView, Counter64:
+				response.Error, response.ErrorIndex = NoSuchName, uint8(i+1)
+				response.Variables = request.Variables
+				return
+			}
+		}
+		return
+	}
+	switch response.Error {
+	case WrongValue, WrongEncoding, WrongType, WrongLength, InconsistentValue:
+		response.Error = BadValue
+	case NoAccess, NotWritable, NoCreation, InconsistentName, AuthorizationError:
+		response.Error = NoSuchName
+	case ResourceUnavailable, CommitFailed, UndoFailed:
+		response.Error = GenErr
+	}
+}
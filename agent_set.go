@@ -0,0 +1,213 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+//
+// Agent SETs, in the phases of RFC 3416 section 4.2.5
+//
+
+// SetTester is implemented by AgentHandlers that can check a Set without
+// making it. Every varbind of a SET is tested before any is set, so that a
+// SET failing a test changes nothing.
+type SetTester interface {
+	// Test returns the error Set would, eg WrongType, WrongValue or
+	// NotWritable, without setting the object. An SNMPError is returned
+	// to the manager as the error-status of the response; other errors
+	// as genErr.
+	Test(pdu SnmpPDU) error
+}
+
+// SetUndoer is implemented by AgentHandlers that can undo a Set. When a
+// Set of a SET fails, those before it are undone, in reverse order, and
+// the response is a commitFailed; or undoFailed, if one can't be undone
+// (as Sets by handlers that aren't SetUndoers can't).
+type SetUndoer interface {
+	// Undo sets the object back to its value before Set(pdu)
+	Undo(pdu SnmpPDU) error
+}
+
+// SetCommitter is implemented by AgentHandlers that process their varbinds
+// of a SET together, in one transaction, as AgentX subagents do. They are
+// given them all in one TestSet, instead of a Test of each; a CommitSet
+// instead of each Set, at the first of them; an UndoSet if the SET fails
+// after their CommitSet (even if it was theirs that failed); and, if
+// TestSet was called, a CleanupSet at the end of the SET. SetCommitters
+// must be comparable, eg pointers, as their varbinds are grouped by them.
+type SetCommitter interface {
+	// TestSet tests the varbinds as SetTester.Test does, returning the
+	// index in pdus of the one that fails
+	TestSet(pdus []SnmpPDU) (int, error)
+
+	// CommitSet sets the varbinds tested, returning the index of the one
+	// that fails
+	CommitSet() (int, error)
+
+	// UndoSet undoes CommitSet
+	UndoSet() error
+
+	// CleanupSet ends the SET
+	CleanupSet()
+}
+
+// Test fails with NotWritable
+func (f AgentHandlerFunc) Test(pdu SnmpPDU) error {
+	return NotWritable
+}
+
+// Test checks that the cell can be set, with the errors of Set
+func (t *agentTable) Test(pdu SnmpPDU) error {
+	if _, ok := t.provider.(TableSetter); !ok {
+		return NotWritable
+	}
+	o, err := pdu.Oid()
+	if err != nil {
+		return err
+	}
+	column, index, ok := t.cell(o)
+	if !ok || column <= 0 {
+		return NoCreation
+	}
+	if _, err = DecodeIndex(index, t.index); err != nil {
+		return NoCreation
+	}
+	return nil
+}
+
+// set sets the values of pdus, all or none of them: each is tested, then
+// each set, and if one fails those before it are undone. The error-status
+// and error-index of the response are returned.
+func (a *Agent) set(pdus []SnmpPDU, access *agentAccess) (SNMPError, uint8) {
+	a.setMu.Lock()
+	defer a.setMu.Unlock()
+
+	// find the handlers, and test the values; the varbinds of
+	// SetCommitters are grouped, to be tested together
+	objects := a.lookup()
+	handlers := make([]AgentHandler, len(pdus))
+	pdus = append([]SnmpPDU(nil), pdus...)
+	var committers, tested []SetCommitter
+	groups := make(map[SetCommitter][]int)
+	defer func() {
+		for _, c := range tested {
+			c.CleanupSet()
+		}
+	}()
+	for i, pdu := range pdus {
+		oid, err := pdu.Oid()
+		if err != nil {
+			return GenErr, uint8(i + 1)
+		}
+		if !access.writable(oid) {
+			return NoAccess, uint8(i + 1)
+		}
+		object := agentOwner(objects, oid, true)
+		if object == nil {
+			return NoCreation, uint8(i + 1)
+		}
+		pdus[i].Name = oid.String()
+		handlers[i] = object.handler
+		if c, ok := handlers[i].(SetCommitter); ok {
+			if _, ok = groups[c]; !ok {
+				committers = append(committers, c)
+			}
+			groups[c] = append(groups[c], i)
+			continue
+		}
+		if tester, ok := handlers[i].(SetTester); ok {
+			if err = tester.Test(pdus[i]); err != nil {
+				a.x.logInfo("Set test failed", "oid", pdus[i].Name, "err", err)
+				return agentErrorStatus(err), uint8(i + 1)
+			}
+		}
+	}
+	for _, c := range committers {
+		group := groups[c]
+		batch := make([]SnmpPDU, len(group))
+		for j, i := range group {
+			batch[j] = pdus[i]
+		}
+		tested = append(tested, c)
+		if j, err := c.TestSet(batch); err != nil {
+			i := groupIndex(group, j)
+			a.x.logInfo("Set test failed", "oid", pdus[i].Name, "err", err)
+			return agentErrorStatus(err), uint8(i + 1)
+		}
+	}
+
+	committed := make(map[SetCommitter]bool)
+	for n, pdu := range pdus {
+		// the varbind that fails is i, which for a SetCommitter may be
+		// after n, the one being set
+		i := n
+		var err error
+		c, isCommitter := handlers[n].(SetCommitter)
+		switch {
+		case isCommitter && committed[c]:
+			continue
+		case isCommitter:
+			committed[c] = true
+			var j int
+			if j, err = c.CommitSet(); err != nil {
+				i = groupIndex(groups[c], j)
+				pdu = pdus[i]
+			}
+		default:
+			err = handlers[n].Set(pdu)
+		}
+		if err == nil {
+			continue
+		}
+		a.x.logWarn("Set failed", "oid", pdu.Name, "err", err)
+
+		status := CommitFailed
+		if isCommitter {
+			if undoErr := c.UndoSet(); undoErr != nil {
+				a.x.logWarn("Undo failed", "oid", pdu.Name, "err", undoErr)
+				status = UndoFailed
+			}
+		}
+		for j := n - 1; j >= 0; j-- {
+			if c, ok := handlers[j].(SetCommitter); ok {
+				// undone once, at its last varbind
+				if committed[c] {
+					delete(committed, c)
+					if undoErr := c.UndoSet(); undoErr != nil {
+						a.x.logWarn("Undo failed", "oid", pdus[j].Name, "err", undoErr)
+						status = UndoFailed
+					}
+				}
+				continue
+			}
+			undoer, ok := handlers[j].(SetUndoer)
+			if !ok {
+				a.x.logWarn("Set can't be undone", "oid", pdus[j].Name)
+				status = UndoFailed
+				continue
+			}
+			if undoErr := undoer.Undo(pdus[j]); undoErr != nil {
+				a.x.logWarn("Undo failed", "oid", pdus[j].Name, "err", undoErr)
+				status = UndoFailed
+			}
+		}
+		switch {
+		case status == UndoFailed:
+			return UndoFailed, 0
+		case n == 0:
+			// nothing had changed, so the error is as a test's would be
+			return agentErrorStatus(err), uint8(i + 1)
+		}
+		return CommitFailed, uint8(i + 1)
+	}
+	return NoError, 0
+}
+
+// groupIndex returns the index in pdus of the varbind at index j of group,
+// or of the group's first varbind if j is out of range
+func groupIndex(group []int, j int) int {
+	if j >= 0 && j < len(group) {
+		return group[j]
+	}
+	return group[0]
+}
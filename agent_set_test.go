@@ -0,0 +1,219 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// testPhased is an AgentHandler, SetTester and SetUndoer of an integer,
+// recording the calls made to it
+type testPhased struct {
+	name                     string
+	value, old               int
+	testErr, setErr, undoErr error
+
+	mu    *sync.Mutex
+	calls *[]string
+}
+
+func (h *testPhased) record(call string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	*h.calls = append(*h.calls, call+" "+h.name)
+}
+
+func (h *testPhased) Get(oid string) (SnmpPDU, error) {
+	return SnmpPDU{Type: Integer, Value: h.value}, nil
+}
+
+func (h *testPhased) Test(pdu SnmpPDU) error {
+	h.record("test")
+	return h.testErr
+}
+
+func (h *testPhased) Set(pdu SnmpPDU) error {
+	h.record("set")
+	if h.setErr != nil {
+		return h.setErr
+	}
+	h.old, h.value = h.value, pdu.Value.(int)
+	return nil
+}
+
+func (h *testPhased) Undo(pdu SnmpPDU) error {
+	h.record("undo")
+	if h.undoErr != nil {
+		return h.undoErr
+	}
+	h.value = h.old
+	return nil
+}
+
+// testPlain is a testPhased that is neither a SetTester or SetUndoer
+type testPlain struct{ h *testPhased }
+
+func (p testPlain) Get(oid string) (SnmpPDU, error) { return p.h.Get(oid) }
+func (p testPlain) Set(pdu SnmpPDU) error           { return p.h.Set(pdu) }
+
+func TestAgentSetPhases(t *testing.T) {
+	a := &Agent{}
+	a.init()
+	tests := []struct {
+		name     string
+		handlers []*testPhased
+		plain    int // the index of a handler that can't be tested or undone, or -1
+		status   SNMPError
+		index    uint8
+		calls    string
+		values   string
+	}{
+		{"ok", []*testPhased{{}, {}}, -1, NoError, 0,
+			"[test 1 test 2 set 1 set 2]", "[1 2]"},
+		{"test fails", []*testPhased{{}, {testErr: WrongValue}, {}}, -1, WrongValue, 2,
+			"[test 1 test 2]", "[0 0 0]"},
+		{"test fails with an error", []*testPhased{{testErr: errors.New("busy")}}, -1, GenErr, 1,
+			"[test 1]", "[0]"},
+		{"first set fails", []*testPhased{{setErr: ResourceUnavailable}, {}}, -1, ResourceUnavailable, 1,
+			"[test 1 test 2 set 1]", "[0 0]"},
+		{"set fails", []*testPhased{{}, {}, {setErr: errors.New("disk full")}}, -1, CommitFailed, 3,
+			"[test 1 test 2 test 3 set 1 set 2 set 3 undo 2 undo 1]", "[0 0 0]"},
+		{"undo fails", []*testPhased{{undoErr: errors.New("lost")}, {setErr: CommitFailed}}, -1, UndoFailed, 0,
+			"[test 1 test 2 set 1 set 2 undo 1]", "[1 0]"},
+		{"can't undo", []*testPhased{{}, {}, {setErr: WrongValue}}, 0, UndoFailed, 0,
+			"[test 2 test 3 set 1 set 2 set 3 undo 2]", "[1 0 0]"},
+	}
+	for _, test := range tests {
+		var mu sync.Mutex
+		var calls []string
+		a.objects = nil
+		var pdus []SnmpPDU
+		for i, h := range test.handlers {
+			h.name, h.mu, h.calls = fmt.Sprint(i+1), &mu, &calls
+			oid := fmt.Sprintf(".1.3.6.1.4.1.99999.%d.0", i+1)
+			if i == test.plain {
+				a.Register(oid, testPlain{h})
+			} else {
+				a.Register(oid, h)
+			}
+			pdus = append(pdus, SnmpPDU{Name: oid, Type: Integer, Value: i + 1})
+		}
+
+		status, index := a.set(pdus, &agentAccess{canWrite: true})
+		if status != test.status || index != test.index {
+			t.Errorf("%s: got %s at %d, expected %s at %d", test.name, status, index, test.status, test.index)
+		}
+		if got := fmt.Sprint(calls); got != test.calls {
+			t.Errorf("%s: got calls %s, expected %s", test.name, got, test.calls)
+		}
+		var values []int
+		for _, h := range test.handlers {
+			values = append(values, h.value)
+		}
+		if got := fmt.Sprint(values); got != test.values {
+			t.Errorf("%s: got values %s, expected %s", test.name, got, test.values)
+		}
+	}
+}
+
+// testCommitter is a SetCommitter, recording the calls made to it among
+// those of testPhaseds
+type testCommitter struct {
+	name                   string
+	testErr, commitErr     error
+	testIndex, commitIndex int
+	mu                     *sync.Mutex
+	calls                  *[]string
+}
+
+func (c *testCommitter) record(call string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	*c.calls = append(*c.calls, call+" "+c.name)
+}
+
+func (c *testCommitter) Get(oid string) (SnmpPDU, error) {
+	return SnmpPDU{Type: Integer, Value: 0}, nil
+}
+func (c *testCommitter) Set(pdu SnmpPDU) error { return errors.New("not called") }
+
+func (c *testCommitter) TestSet(pdus []SnmpPDU) (int, error) {
+	var values []interface{}
+	for _, pdu := range pdus {
+		values = append(values, pdu.Value)
+	}
+	c.record(fmt.Sprint("testset", values))
+	return c.testIndex, c.testErr
+}
+
+func (c *testCommitter) CommitSet() (int, error) {
+	c.record("commit")
+	return c.commitIndex, c.commitErr
+}
+
+func (c *testCommitter) UndoSet() error {
+	c.record("undo")
+	return nil
+}
+
+func (c *testCommitter) CleanupSet() {
+	c.record("cleanup")
+}
+
+func TestAgentSetCommitter(t *testing.T) {
+	a := &Agent{}
+	a.init()
+	tests := []struct {
+		name      string
+		committer testCommitter
+		phased    testPhased
+		handlers  string // of the varbinds: the committer c, or the testPhased p
+		status    SNMPError
+		index     uint8
+		calls     string
+	}{
+		{"ok", testCommitter{}, testPhased{}, "cpc", NoError, 0,
+			"[test p testset[1 3] c commit c set p cleanup c]"},
+		{"test fails", testCommitter{testErr: WrongValue, testIndex: 1}, testPhased{}, "cpc", WrongValue, 3,
+			"[test p testset[1 3] c cleanup c]"},
+		{"phased test fails", testCommitter{}, testPhased{testErr: WrongType}, "cp", WrongType, 2,
+			"[test p]"},
+		{"commit fails first", testCommitter{commitErr: InconsistentValue, commitIndex: 1}, testPhased{}, "cpc",
+			InconsistentValue, 3, "[test p testset[1 3] c commit c undo c cleanup c]"},
+		{"commit fails", testCommitter{commitErr: CommitFailed}, testPhased{}, "pc", CommitFailed, 2,
+			"[test p testset[2] c set p commit c undo c undo p cleanup c]"},
+		{"set fails", testCommitter{}, testPhased{setErr: errors.New("disk full")}, "cp", CommitFailed, 2,
+			"[test p testset[1] c commit c set p undo c cleanup c]"},
+	}
+	for _, test := range tests {
+		var mu sync.Mutex
+		var calls []string
+		c, p := &test.committer, &test.phased
+		c.name, c.mu, c.calls = "c", &mu, &calls
+		p.name, p.mu, p.calls = "p", &mu, &calls
+		a.objects = nil
+		var pdus []SnmpPDU
+		for i, h := range test.handlers {
+			oid := fmt.Sprintf(".1.3.6.1.4.1.99999.%d.0", i+1)
+			if h == 'c' {
+				a.Register(oid, c)
+			} else {
+				a.Register(oid, p)
+			}
+			pdus = append(pdus, SnmpPDU{Name: oid, Type: Integer, Value: i + 1})
+		}
+
+		status, index := a.set(pdus, &agentAccess{canWrite: true})
+		if status != test.status || index != test.index {
+			t.Errorf("%s: got %s at %d, expected %s at %d", test.name, status, index, test.status, test.index)
+		}
+		if got := fmt.Sprint(calls); got != test.calls {
+			t.Errorf("%s: got calls %s, expected %s", test.name, got, test.calls)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"sort"
+)
+
+//
+// Agent subtrees, and the traversal of the registrations
+//
+
+// SubtreeHandler serves the objects in a subtree registered with an Agent,
+// see Agent.RegisterSubtree. Its Get and Set are given the oids of object
+// instances in the subtree, as an AgentHandler's are.
+type SubtreeHandler interface {
+	AgentHandler
+
+	// GetNext returns the varbind, with its Name set, of the first object
+	// instance in the subtree after oid, which may be before the subtree
+	// (eg its root); or an EndOfMibView varbind if there is none.
+	GetNext(oid string) (SnmpPDU, error)
+}
+
+// RegisterSubtree registers handler to serve the objects in the subtree at
+// oid, eg ".1.3.6.1.2.1.2" for the interfaces group. Requests are routed to
+// the registration with the longest prefix of their oids, so subtrees and
+// objects registered within a subtree are served by their own handlers,
+// and skipped over by its GetNext. Registering an oid again replaces its
+// handler.
+func (a *Agent) RegisterSubtree(oid string, handler SubtreeHandler) error {
+	o, err := ParseOid(oid)
+	if err != nil {
+		return err
+	}
+	a.register(agentObject{oid: o, name: o.String(), handler: handler, subtree: handler})
+	return nil
+}
+
+// agentOwner returns the registration serving oid: the subtree with the
+// longest prefix of it, or with objects, the object registered at oid
+func agentOwner(objects []agentObject, oid Oid, withObjects bool) *agentObject {
+	for n := len(oid); n > 0; n-- {
+		prefix := oid[:n]
+		i := sort.Search(len(objects), func(i int) bool {
+			return objects[i].oid.Compare(prefix) >= 0
+		})
+		if i == len(objects) || !objects[i].oid.Equal(prefix) {
+			continue
+		}
+		if objects[i].subtree != nil || withObjects && n == len(oid) {
+			return &objects[i]
+		}
+	}
+	return nil
+}
+
+// next returns the varbind of the first object instance after oid that has
+// a value and is visible, or an endOfMibView. Each registration is asked
+// for the instances in the part of the tree it owns, between the
+// registrations after it.
+func (a *Agent) next(objects []agentObject, oid Oid, visible func(Oid) bool) (SnmpPDU, error) {
+	cur, inclusive := oid, false
+	for {
+		if inclusive && visible(cur) {
+			// cur is the start of a registration, or the end of one
+			if object := agentOwner(objects, cur, true); object != nil {
+				pdu, ok, err := a.getValue(object, cur)
+				if err != nil || ok {
+					return pdu, err
+				}
+			}
+		}
+
+		// the owner of the oids just after cur, and where it stops owning
+		// them: at the next registration, or the end of its subtree
+		object := agentOwner(objects, cur, false)
+		var boundary Oid
+		i := sort.Search(len(objects), func(i int) bool {
+			return objects[i].oid.Compare(cur) > 0
+		})
+		if i < len(objects) {
+			boundary = objects[i].oid
+		}
+		if object != nil {
+			end := object.oid.NextSibling()
+			if len(end) > 0 && (boundary == nil || end.Compare(boundary) < 0) {
+				boundary = end
+			}
+
+			pdu, err := object.subtree.GetNext(cur.String())
+			if err != nil {
+				a.x.logWarn("GetNext failed", "oid", cur, "err", err)
+				return SnmpPDU{}, err
+			}
+			if isAgentValue(pdu) {
+				next, err := ParseOid(pdu.Name)
+				switch {
+				case err != nil || next.Compare(cur) <= 0 || !next.HasPrefix(object.oid):
+					a.x.logWarn("GetNext returned an oid out of order",
+						"subtree", object.name, "oid", cur, "next", pdu.Name)
+				case (boundary == nil || next.Compare(boundary) < 0) && !visible(next):
+					// carry on from it, in the same part of the tree
+					cur, inclusive = next, false
+					continue
+				case boundary == nil || next.Compare(boundary) < 0:
+					pdu.Name = next.String()
+					return pdu, nil
+				}
+			}
+		}
+		if boundary == nil {
+			return SnmpPDU{Name: oid.String(), Type: EndOfMibView}, nil
+		}
+		cur, inclusive = boundary, true
+	}
+}
+
+// getValue returns the value of the instance oid served by object, and
+// whether it has one
+func (a *Agent) getValue(object *agentObject, oid Oid) (SnmpPDU, bool, error) {
+	name := oid.String()
+	pdu, err := object.handler.Get(name)
+	if err != nil {
+		a.x.logWarn("Get failed", "oid", name, "err", err)
+		return SnmpPDU{}, false, err
+	}
+	pdu.Name = name
+	return pdu, isAgentValue(pdu), nil
+}
+
+// isAgentValue reports whether pdu is a value, rather than an exception
+func isAgentValue(pdu SnmpPDU) bool {
+	switch pdu.Type {
+	case NoSuchObject, NoSuchInstance, EndOfMibView:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,173 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+)
+
+// testSubtree is a SubtreeHandler serving integer values
+type testSubtree struct {
+	mu     sync.Mutex
+	values map[string]int
+}
+
+func (s *testSubtree) value(oid string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.values[oid]
+}
+
+func (s *testSubtree) Get(oid string) (SnmpPDU, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if v, ok := s.values[oid]; ok {
+		return SnmpPDU{Type: Integer, Value: v}, nil
+	}
+	return SnmpPDU{Type: NoSuchInstance}, nil
+}
+
+func (s *testSubtree) GetNext(oid string) (SnmpPDU, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var oids []string
+	for o := range s.values {
+		if CompareOids(o, oid) > 0 {
+			oids = append(oids, o)
+		}
+	}
+	if len(oids) == 0 {
+		return SnmpPDU{Name: oid, Type: EndOfMibView}, nil
+	}
+	sort.Slice(oids, func(i, j int) bool { return CompareOids(oids[i], oids[j]) < 0 })
+	return SnmpPDU{Name: oids[0], Type: Integer, Value: s.values[oids[0]]}, nil
+}
+
+func (s *testSubtree) Set(pdu SnmpPDU) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.values[pdu.Name]; !ok {
+		return NoCreation
+	}
+	s.values[pdu.Name] = pdu.Value.(int)
+	return nil
+}
+
+func TestAgentSubtree(t *testing.T) {
+	a := &Agent{WriteCommunity: "private"}
+	// a module serving .1.3.6.1.2.1, with its own values in .2 and .4
+	// overridden by another module's and an object's registrations
+	outer := &testSubtree{values: map[string]int{
+		".1.3.6.1.2.1.1.1.0": 1,
+		".1.3.6.1.2.1.2.1.0": -1, // hidden by inner
+		".1.3.6.1.2.1.3.1.0": 3,
+		".1.3.6.1.2.1.4.0":   -1, // hidden by the object
+		".1.3.6.1.2.1.5.1.0": 5,
+	}}
+	inner := &testSubtree{values: map[string]int{
+		".1.3.6.1.2.1.2.1.0": 2,
+		".1.3.6.1.2.1.2.2.0": 22,
+	}}
+	a.RegisterSubtree(".1.3.6.1.2.1", outer)
+	a.RegisterSubtree(".1.3.6.1.2.1.2", inner)
+	a.Register(".1.3.6.1.2.1.4.0", AgentHandlerFunc(func(string) (SnmpPDU, error) {
+		return SnmpPDU{Type: Integer, Value: 4}, nil
+	}))
+	a.RegisterSubtree(".1.3.6.1.4.1.99999", &testSubtree{})
+	defer a.Close()
+	x := startTestAgent(t, a, nil)
+	defer x.Conn.Close()
+
+	pdus, err := x.WalkAll(".1.3")
+	if err != nil {
+		t.Fatalf("WalkAll() err: %v", err)
+	}
+	var walked []string
+	for _, pdu := range pdus {
+		walked = append(walked, fmt.Sprintf("%s=%d", pdu.Name, pdu.Value))
+	}
+	want := []string{
+		".1.3.6.1.2.1.1.1.0=1",
+		".1.3.6.1.2.1.2.1.0=2",
+		".1.3.6.1.2.1.2.2.0=22",
+		".1.3.6.1.2.1.3.1.0=3",
+		".1.3.6.1.2.1.4.0=4",
+		".1.3.6.1.2.1.5.1.0=5",
+	}
+	if fmt.Sprint(walked) != fmt.Sprint(want) {
+		t.Errorf("walked %v, expected %v", walked, want)
+	}
+
+	bulk, err := x.BulkWalkAll(".1.3.6.1.2.1.2")
+	if err != nil || len(bulk) != 2 || bulk[1].Value != 22 {
+		t.Errorf("BulkWalkAll() of the inner subtree got %v, err %v", bulk, err)
+	}
+
+	result, err := x.Get([]string{".1.3.6.1.2.1.2.1.0", ".1.3.6.1.2.1.3.1.0", ".1.3.6.1.2.1.9.0", ".1.3.6.1.3.0"})
+	if err != nil {
+		t.Fatalf("Get() err: %v", err)
+	}
+	for i, want := range []interface{}{2, 3, nil, nil} {
+		if v := result.Variables[i]; v.Value != want {
+			t.Errorf("Get() varbind %d got %v, expected %v", i, v, want)
+		}
+	}
+	if result.Variables[2].Type != NoSuchInstance || result.Variables[3].Type != NoSuchObject {
+		t.Errorf("Get() got %v, expected noSuchInstance then noSuchObject", result.Variables[2:])
+	}
+
+	result, err = x.GetNext([]string{".1.3.6.1.2.1.5.1.0"})
+	if err != nil || result.Variables[0].Type != EndOfMibView || result.Variables[0].Name != ".1.3.6.1.2.1.5.1.0" {
+		t.Errorf("GetNext() of the last object got %v, err %v, expected endOfMibView", result, err)
+	}
+
+	x.Community = "private"
+	result, err = x.Set([]SnmpPDU{{Name: ".1.3.6.1.2.1.2.2.0", Type: Integer, Value: 23}})
+	if err != nil || result.Error != NoError || inner.value(".1.3.6.1.2.1.2.2.0") != 23 {
+		t.Errorf("Set() in the inner subtree got %v, err %v", result, err)
+	}
+	result, err = x.Set([]SnmpPDU{{Name: ".1.3.6.1.2.1.2.3.0", Type: Integer, Value: 1}})
+	if err != nil || result.Error != NoCreation {
+		t.Errorf("Set() of a missing instance got %v, err %v, expected noCreation", result, err)
+	}
+
+	a.Unregister(".1.3.6.1.2.1.2")
+	result, err = x.Get([]string{".1.3.6.1.2.1.2.1.0"})
+	if err != nil || result.Variables[0].Value != -1 {
+		t.Errorf("Get() after Unregister got %v, err %v, expected the outer value", result, err)
+	}
+}
+
+func TestAgentOwner(t *testing.T) {
+	a := &Agent{}
+	a.RegisterSubtree(".1.3.6", &testSubtree{})
+	a.RegisterSubtree(".1.3.6.1.2", &testSubtree{})
+	a.Register(".1.3.6.1.2.1.0", AgentHandlerFunc(nil))
+	tests := []struct {
+		oid         string
+		withObjects bool
+		want        string
+	}{
+		{".1.3.6.1.2.1.0", true, ".1.3.6.1.2.1.0"},
+		{".1.3.6.1.2.1.0", false, ".1.3.6.1.2"},
+		{".1.3.6.1.2.1.0.1", true, ".1.3.6.1.2"},
+		{".1.3.6.1.2", true, ".1.3.6.1.2"},
+		{".1.3.6.1.3", true, ".1.3.6"},
+		{".1.3.7", true, ""},
+	}
+	for _, test := range tests {
+		oid, _ := ParseOid(test.oid)
+		got := ""
+		if object := agentOwner(a.objects, oid, test.withObjects); object != nil {
+			got = object.name
+		}
+		if got != test.want {
+			t.Errorf("agentOwner(%s, %t) = %q, expected %q", test.oid, test.withObjects, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,263 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+//
+// The MIB-II system group, for an Agent
+//
+
+const (
+	oidSystem     = ".1.3.6.1.2.1.1"
+	sysORTableOid = ".1.3.6.1.2.1.1.9.1" // sysOREntry
+)
+
+// the system group's scalars, by their oids' last part but one
+const (
+	sysDescr        = 1
+	sysObjectID     = 2
+	sysUpTime       = 3
+	sysContact      = 4
+	sysName         = 5
+	sysLocation     = 6
+	sysServices     = 7
+	sysORLastChange = 8
+)
+
+// SystemGroup is the system group of SNMPv2-MIB (RFC 3418), describing the
+// system an Agent runs on, see Agent.RegisterSystem. sysContact, sysName
+// and sysLocation can be set by managers; once registered, the group
+// should only be read and changed with its methods.
+type SystemGroup struct {
+	// Descr is sysDescr, a description of the system, eg its hardware and
+	// software versions
+	Descr string
+
+	// ObjectID is sysObjectID, the oid identifying the kind of system, in
+	// its vendor's subtree of .1.3.6.1.4.1 (default: ".0.0")
+	ObjectID string
+
+	// Contact, Name and Location are sysContact, sysName and sysLocation,
+	// the person responsible for the system, its name (eg its fully
+	// qualified domain name) and where it is
+	Contact, Name, Location string
+
+	// Services is sysServices, the sum of 2^(L-1) for the layers L of the
+	// services the system offers (default: 72, applications and end-to-end
+	// hosts)
+	Services int
+
+	mu      sync.Mutex
+	start   time.Time          // when registered, the start of sysUpTime
+	changed time.Time          // of sysORTable
+	ors     []systemOR         // the sysORTable, by sysORIndex
+	old     map[*string]string // the values before the last Sets, to Undo them
+}
+
+// systemOR is a row of the sysORTable
+type systemOR struct {
+	id, descr string
+	added     time.Time
+}
+
+// RegisterSystem registers s to serve the system group, at
+// .1.3.6.1.2.1.1, with sysUpTime counting from now.
+func (a *Agent) RegisterSystem(s *SystemGroup) error {
+	if s.ObjectID == "" {
+		s.ObjectID = ".0.0"
+	}
+	if _, err := ParseOid(s.ObjectID); err != nil {
+		return fmt.Errorf("Invalid sysObjectID %q: %w", s.ObjectID, err)
+	}
+	if s.Services == 0 {
+		s.Services = 72
+	}
+	s.mu.Lock()
+	s.start = time.Now()
+	if s.changed.IsZero() {
+		s.changed = s.start
+	}
+	s.mu.Unlock()
+	return a.RegisterSubtree(oidSystem, s)
+}
+
+// AddOR adds a row to the sysORTable, listing a capability of the agent
+// (eg a MIB module it implements) identified by id, an AGENT-CAPABILITIES
+// or MODULE-IDENTITY oid, with a description. It returns the row's
+// sysORIndex.
+func (s *SystemGroup) AddOR(id, descr string) (int, error) {
+	if _, err := ParseOid(id); err != nil {
+		return 0, fmt.Errorf("Invalid sysORID %q: %w", id, err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	s.ors = append(s.ors, systemOR{id: id, descr: descr, added: now})
+	s.changed = now
+	return len(s.ors), nil
+}
+
+// Values returns the current sysContact, sysName and sysLocation, which
+// may have been set by managers
+func (s *SystemGroup) Values() (contact, name, location string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Contact, s.Name, s.Location
+}
+
+// ticks returns the sysUpTime of time t
+func (s *SystemGroup) ticks(t time.Time) uint32 {
+	if t.Before(s.start) {
+		return 0
+	}
+	return DurationToTimeTicks(t.Sub(s.start))
+}
+
+// objects returns the group's object instances, in oid order
+func (s *SystemGroup) objects() []SnmpPDU {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	scalar := func(n int, t Asn1BER, v interface{}) SnmpPDU {
+		return SnmpPDU{Name: fmt.Sprintf("%s.%d.0", oidSystem, n), Type: t, Value: v}
+	}
+	pdus := []SnmpPDU{
+		scalar(sysDescr, OctetString, s.Descr),
+		scalar(sysObjectID, ObjectIdentifier, s.ObjectID),
+		scalar(sysUpTime, TimeTicks, s.ticks(time.Now())),
+		scalar(sysContact, OctetString, s.Contact),
+		scalar(sysName, OctetString, s.Name),
+		scalar(sysLocation, OctetString, s.Location),
+		scalar(sysServices, Integer, s.Services),
+		scalar(sysORLastChange, TimeTicks, s.ticks(s.changed)),
+	}
+	// sysORID, sysORDescr and sysORUpTime; sysORIndex isn't accessible
+	for column := 2; column <= 4; column++ {
+		for i, or := range s.ors {
+			pdu := SnmpPDU{Name: fmt.Sprintf("%s.%d.%d", sysORTableOid, column, i+1)}
+			switch column {
+			case 2:
+				pdu.Type, pdu.Value = ObjectIdentifier, or.id
+			case 3:
+				pdu.Type, pdu.Value = OctetString, or.descr
+			case 4:
+				pdu.Type, pdu.Value = TimeTicks, s.ticks(or.added)
+			}
+			pdus = append(pdus, pdu)
+		}
+	}
+	return pdus
+}
+
+// Get returns an object instance of the group
+func (s *SystemGroup) Get(oid string) (SnmpPDU, error) {
+	pdus := s.objects()
+	i := sort.Search(len(pdus), func(i int) bool {
+		return CompareOids(pdus[i].Name, oid) >= 0
+	})
+	if i < len(pdus) && pdus[i].Name == oid {
+		return pdus[i], nil
+	}
+	return SnmpPDU{Name: oid, Type: NoSuchObject}, nil
+}
+
+// GetNext returns the group's first object instance after oid
+func (s *SystemGroup) GetNext(oid string) (SnmpPDU, error) {
+	pdus := s.objects()
+	i := sort.Search(len(pdus), func(i int) bool {
+		return CompareOids(pdus[i].Name, oid) > 0
+	})
+	if i < len(pdus) {
+		return pdus[i], nil
+	}
+	return SnmpPDU{Name: oid, Type: EndOfMibView}, nil
+}
+
+// field returns the writable field set by pdu, or NotWritable
+func (s *SystemGroup) field(pdu SnmpPDU) (*string, error) {
+	switch pdu.Name {
+	case fmt.Sprintf("%s.%d.0", oidSystem, sysContact):
+		return &s.Contact, nil
+	case fmt.Sprintf("%s.%d.0", oidSystem, sysName):
+		return &s.Name, nil
+	case fmt.Sprintf("%s.%d.0", oidSystem, sysLocation):
+		return &s.Location, nil
+	}
+	if p, _ := s.Get(pdu.Name); p.Type == NoSuchObject {
+		return nil, NoCreation
+	}
+	return nil, NotWritable
+}
+
+// displayString returns the value of a Set of a DisplayString (SIZE
+// (0..255)), or the error the Set fails with
+func displayString(pdu SnmpPDU) (string, error) {
+	if pdu.Type != OctetString {
+		return "", WrongType
+	}
+	var v string
+	switch value := pdu.Value.(type) {
+	case []byte:
+		v = string(value)
+	case string:
+		v = value
+	default:
+		return "", WrongType
+	}
+	if len(v) > 255 {
+		return "", WrongLength
+	}
+	return v, nil
+}
+
+// Test checks that pdu sets sysContact, sysName or sysLocation to a
+// DisplayString
+func (s *SystemGroup) Test(pdu SnmpPDU) error {
+	if _, err := s.field(pdu); err != nil {
+		return err
+	}
+	_, err := displayString(pdu)
+	return err
+}
+
+// Set sets sysContact, sysName or sysLocation
+func (s *SystemGroup) Set(pdu SnmpPDU) error {
+	field, err := s.field(pdu)
+	if err != nil {
+		return err
+	}
+	v, err := displayString(pdu)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.old == nil {
+		s.old = make(map[*string]string)
+	}
+	s.old[field] = *field
+	*field = v
+	return nil
+}
+
+// Undo sets sysContact, sysName or sysLocation back to its value before
+// the last Set
+func (s *SystemGroup) Undo(pdu SnmpPDU) error {
+	field, err := s.field(pdu)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if old, ok := s.old[field]; ok {
+		*field = old
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAgentSystem(t *testing.T) {
+	a := &Agent{WriteCommunity: "private"}
+	system := &SystemGroup{
+		Descr:    "test system",
+		ObjectID: ".1.3.6.1.4.1.99999.1",
+		Name:     "host",
+	}
+	if err := a.RegisterSystem(system); err != nil {
+		t.Fatalf("RegisterSystem() err: %v", err)
+	}
+	if n, err := system.AddOR(".1.3.6.1.2.1.31", "The MIB module to describe generic objects for network interface sub-layers"); err != nil || n != 1 {
+		t.Errorf("AddOR() = %d, %v", n, err)
+	}
+	if _, err := system.AddOR("not an oid", ""); err == nil {
+		t.Errorf("AddOR() of an invalid oid succeeded")
+	}
+	defer a.Close()
+	x := startTestAgent(t, a, nil)
+	defer x.Conn.Close()
+
+	pdus, err := x.WalkAll(oidSystem)
+	if err != nil {
+		t.Fatalf("WalkAll() err: %v", err)
+	}
+	want := []struct {
+		name  string
+		value string
+	}{
+		{".1.3.6.1.2.1.1.1.0", "test system"},
+		{".1.3.6.1.2.1.1.2.0", ".1.3.6.1.4.1.99999.1"},
+		{".1.3.6.1.2.1.1.3.0", ""},
+		{".1.3.6.1.2.1.1.4.0", ""},
+		{".1.3.6.1.2.1.1.5.0", "host"},
+		{".1.3.6.1.2.1.1.6.0", ""},
+		{".1.3.6.1.2.1.1.7.0", "72"},
+		{".1.3.6.1.2.1.1.8.0", ""},
+		{".1.3.6.1.2.1.1.9.1.2.1", ".1.3.6.1.2.1.31"},
+		{".1.3.6.1.2.1.1.9.1.3.1", "The MIB module"},
+		{".1.3.6.1.2.1.1.9.1.4.1", ""},
+	}
+	if len(pdus) != len(want) {
+		t.Fatalf("walked %v, expected %d objects", pdus, len(want))
+	}
+	for i, w := range want {
+		pdu := pdus[i]
+		var value string
+		switch v := pdu.Value.(type) {
+		case []byte:
+			value = string(v)
+		case string:
+			value = v
+		case int:
+			value = ToBigInt(v).String()
+		}
+		if pdu.Name != w.name || !strings.HasPrefix(value, w.value) {
+			t.Errorf("walked %s = %v, expected %s = %s", pdu.Name, pdu.Value, w.name, w.value)
+		}
+	}
+
+	x.Community = "private"
+	tests := []struct {
+		pdus   []SnmpPDU
+		status SNMPError
+		index  uint8
+	}{
+		{[]SnmpPDU{
+			{Name: ".1.3.6.1.2.1.1.4.0", Type: OctetString, Value: "admin"},
+			{Name: ".1.3.6.1.2.1.1.6.0", Type: OctetString, Value: "rack 1"},
+		}, NoError, 0},
+		{[]SnmpPDU{{Name: ".1.3.6.1.2.1.1.1.0", Type: OctetString, Value: "descr"}}, NotWritable, 1},
+		{[]SnmpPDU{{Name: ".1.3.6.1.2.1.1.5.0", Type: Integer, Value: 1}}, WrongType, 1},
+		{[]SnmpPDU{{Name: ".1.3.6.1.2.1.1.5.0", Type: OctetString, Value: strings.Repeat("x", 256)}}, WrongLength, 1},
+		{[]SnmpPDU{{Name: ".1.3.6.1.2.1.1.10.0", Type: OctetString, Value: "x"}}, NoCreation, 1},
+	}
+	for _, test := range tests {
+		result, err := x.Set(test.pdus)
+		if err != nil {
+			t.Errorf("Set(%v) err: %v", test.pdus, err)
+			continue
+		}
+		if result.Error != test.status || result.ErrorIndex != test.index {
+			t.Errorf("Set(%v) got %s at %d, expected %s at %d",
+				test.pdus, result.Error, result.ErrorIndex, test.status, test.index)
+		}
+	}
+	if contact, name, location := system.Values(); contact != "admin" || name != "host" || location != "rack 1" {
+		t.Errorf("Values() = %q, %q, %q", contact, name, location)
+	}
+
+	if err := system.Undo(SnmpPDU{Name: ".1.3.6.1.2.1.1.6.0"}); err != nil {
+		t.Errorf("Undo() err: %v", err)
+	}
+	if _, _, location := system.Values(); location != "" {
+		t.Errorf("Undo() left sysLocation %q", location)
+	}
+}
@@ -0,0 +1,234 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+//
+// Agent tables
+//
+
+// agentTableRowsTTL is how long the rows of a TableProvider are used for,
+// so that the GETNEXTs of a walk, and a GETBULK, see the same rows
+const agentTableRowsTTL = time.Second
+
+// TableRow is a row of a table served by an Agent
+type TableRow struct {
+	// Index holds the values of the objects in the table's INDEX clause,
+	// as for EncodeIndex
+	Index []interface{}
+
+	// Columns holds the row's cells by column number, the varbinds' Names
+	// being ignored. Columns without a cell are skipped, as in a sparse
+	// table.
+	Columns map[int]SnmpPDU
+}
+
+// TableProvider supplies the rows of a table served by an Agent, see
+// Agent.RegisterTable
+type TableProvider interface {
+	// Rows returns the table's rows, in any order. The agent orders them
+	// by index.
+	Rows() ([]TableRow, error)
+}
+
+// TableProviderFunc is a TableProvider whose rows are returned by the
+// function
+type TableProviderFunc func() ([]TableRow, error)
+
+// Rows calls f()
+func (f TableProviderFunc) Rows() ([]TableRow, error) {
+	return f()
+}
+
+// TableSetter is implemented by a TableProvider whose cells can be set;
+// Sets of the cells of other tables fail with NotWritable.
+type TableSetter interface {
+	// SetCell sets a cell, of a row that may not exist yet, to the value
+	// of pdu. index holds the values of the row's index objects, decoded
+	// as DecodeIndex does.
+	SetCell(index []interface{}, column int, pdu SnmpPDU) error
+}
+
+// RegisterTable registers provider to serve the table whose entry is at
+// entryOid (eg ".1.3.6.1.2.1.2.2.1" for IF-MIB::ifEntry), with the index
+// described by index. The agent encodes the rows' indexes, and answers
+// GETNEXTs and GETBULKs in column then index order. The rows are fetched
+// at most once a second, or after a Set, so a walk of the table sees them
+// as they were when it started.
+func (a *Agent) RegisterTable(entryOid string, index []IndexPart, provider TableProvider) error {
+	o, err := ParseOid(entryOid)
+	if err != nil {
+		return err
+	}
+	table := &agentTable{entry: o, index: index, provider: provider, agent: a}
+	a.register(agentObject{oid: o, name: o.String(), handler: table, subtree: table})
+	return nil
+}
+
+// agentTable is the SubtreeHandler of a table
+type agentTable struct {
+	entry    Oid
+	index    []IndexPart
+	provider TableProvider
+	agent    *Agent
+
+	mu      sync.Mutex
+	rows    []agentTableRow // sorted by index
+	columns []int           // of any row, sorted
+	fetched time.Time
+}
+
+// agentTableRow is a row with its index encoded
+type agentTableRow struct {
+	index Oid
+	row   TableRow
+}
+
+// snapshot returns the rows and columns of the table, fetching them if the
+// last fetched have expired
+func (t *agentTable) snapshot() ([]agentTableRow, []int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.fetched.IsZero() && time.Since(t.fetched) < agentTableRowsTTL {
+		return t.rows, t.columns, nil
+	}
+	rows, err := t.provider.Rows()
+	if err != nil {
+		return nil, nil, err
+	}
+	sorted := make([]agentTableRow, 0, len(rows))
+	seen := make(map[int]bool)
+	var columns []int
+	for _, row := range rows {
+		index, err := EncodeIndex(t.index, row.Index...)
+		if err != nil {
+			t.agent.x.logWarn("Skipping table row", "table", t.entry, "index", row.Index, "err", err)
+			continue
+		}
+		sorted = append(sorted, agentTableRow{index: index, row: row})
+		for column := range row.Columns {
+			if !seen[column] && column > 0 {
+				seen[column] = true
+				columns = append(columns, column)
+			}
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].index.Compare(sorted[j].index) < 0
+	})
+	sort.Ints(columns)
+	t.rows, t.columns, t.fetched = sorted, columns, time.Now()
+	return t.rows, t.columns, nil
+}
+
+// cell splits the oid of a cell into its column and index, reporting
+// whether it is in the table
+func (t *agentTable) cell(oid Oid) (int, Oid, bool) {
+	if len(oid) <= len(t.entry) || !oid.HasPrefix(t.entry) {
+		return 0, nil, false
+	}
+	return int(oid[len(t.entry)]), oid[len(t.entry)+1:], true
+}
+
+// Get returns the value of a cell
+func (t *agentTable) Get(oid string) (SnmpPDU, error) {
+	o, err := ParseOid(oid)
+	if err != nil {
+		return SnmpPDU{}, err
+	}
+	column, index, ok := t.cell(o)
+	if !ok {
+		return SnmpPDU{Name: oid, Type: NoSuchObject}, nil
+	}
+	rows, columns, err := t.snapshot()
+	if err != nil {
+		return SnmpPDU{}, err
+	}
+	if i := sort.SearchInts(columns, column); i == len(columns) || columns[i] != column {
+		return SnmpPDU{Name: oid, Type: NoSuchObject}, nil
+	}
+	i := sort.Search(len(rows), func(i int) bool {
+		return rows[i].index.Compare(index) >= 0
+	})
+	if i < len(rows) && rows[i].index.Equal(index) {
+		if pdu, ok := rows[i].row.Columns[column]; ok {
+			return pdu, nil
+		}
+	}
+	return SnmpPDU{Name: oid, Type: NoSuchInstance}, nil
+}
+
+// GetNext returns the first cell after oid, by column then index
+func (t *agentTable) GetNext(oid string) (SnmpPDU, error) {
+	o, err := ParseOid(oid)
+	if err != nil {
+		return SnmpPDU{}, err
+	}
+	rows, columns, err := t.snapshot()
+	if err != nil {
+		return SnmpPDU{}, err
+	}
+
+	// the first column to look in, and the index to start after in it
+	first, after := 0, Oid(nil)
+	if o.Compare(t.entry) > 0 {
+		if !o.HasPrefix(t.entry) {
+			return SnmpPDU{Name: oid, Type: EndOfMibView}, nil
+		}
+		if len(o) > len(t.entry) {
+			first, after = int(o[len(t.entry)]), o[len(t.entry)+1:]
+		}
+	}
+	for _, column := range columns[sort.SearchInts(columns, first):] {
+		i := 0
+		if column == first && after != nil {
+			i = sort.Search(len(rows), func(i int) bool {
+				return rows[i].index.Compare(after) > 0
+			})
+		}
+		for ; i < len(rows); i++ {
+			if pdu, ok := rows[i].row.Columns[column]; ok {
+				name := append(append(append(Oid(nil), t.entry...), uint32(column)), rows[i].index...)
+				pdu.Name = name.String()
+				return pdu, nil
+			}
+		}
+	}
+	return SnmpPDU{Name: oid, Type: EndOfMibView}, nil
+}
+
+// Set sets a cell, if the provider is a TableSetter
+func (t *agentTable) Set(pdu SnmpPDU) error {
+	setter, ok := t.provider.(TableSetter)
+	if !ok {
+		return NotWritable
+	}
+	o, err := pdu.Oid()
+	if err != nil {
+		return err
+	}
+	column, index, ok := t.cell(o)
+	if !ok || column <= 0 {
+		return NoCreation
+	}
+	values, err := DecodeIndex(index, t.index)
+	if err != nil {
+		return NoCreation
+	}
+	defer t.expire()
+	return setter.SetCell(values, column, pdu)
+}
+
+// expire makes the next request fetch the rows again
+func (t *agentTable) expire() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.fetched = time.Time{}
+}
@@ -0,0 +1,147 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// testTable is a TableProvider and TableSetter of rows indexed by a name
+// and a number, with a string and an integer column
+type testTable struct {
+	mu    sync.Mutex
+	rows  map[string]map[int]SnmpPDU
+	fetch int
+}
+
+func (t *testTable) Rows() ([]TableRow, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.fetch++
+	var rows []TableRow
+	for key, columns := range t.rows {
+		var name string
+		var n int
+		fmt.Sscanf(key, "%s %d", &name, &n)
+		rows = append(rows, TableRow{Index: []interface{}{name, n}, Columns: columns})
+	}
+	return rows, nil
+}
+
+func (t *testTable) SetCell(index []interface{}, column int, pdu SnmpPDU) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	key := fmt.Sprintf("%s %d", index[0], index[1])
+	if t.rows[key] == nil {
+		t.rows[key] = make(map[int]SnmpPDU)
+	}
+	t.rows[key][column] = pdu
+	return nil
+}
+
+func (t *testTable) fetches() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.fetch
+}
+
+func TestAgentTable(t *testing.T) {
+	const entry = ".1.3.6.1.4.1.99999.1.1"
+	table := &testTable{rows: map[string]map[int]SnmpPDU{
+		"b 1":  {1: {Type: OctetString, Value: "b1"}, 2: {Type: Integer, Value: 21}},
+		"a 10": {1: {Type: OctetString, Value: "a10"}, 2: {Type: Integer, Value: 210}},
+		"a 2":  {1: {Type: OctetString, Value: "a2"}}, // sparse, no column 2
+	}}
+	a := &Agent{WriteCommunity: "private"}
+	index := []IndexPart{{Kind: IndexString}, {Kind: IndexInteger}}
+	if err := a.RegisterTable(entry, index, table); err != nil {
+		t.Fatalf("RegisterTable() err: %v", err)
+	}
+	a.Register(".1.3.6.1.4.1.99999.2.0", AgentHandlerFunc(func(string) (SnmpPDU, error) {
+		return SnmpPDU{Type: Integer, Value: 3}, nil
+	}))
+	defer a.Close()
+	x := startTestAgent(t, a, nil)
+	defer x.Conn.Close()
+
+	// index "a".2 is .1.97.2, sorted before "a".10 and "b".1
+	want := []string{
+		entry + ".1.1.97.2=a2",
+		entry + ".1.1.97.10=a10",
+		entry + ".1.1.98.1=b1",
+		entry + ".2.1.97.10=210",
+		entry + ".2.1.98.1=21",
+		".1.3.6.1.4.1.99999.2.0=3",
+	}
+	for _, walk := range []func(string) ([]SnmpPDU, error){x.WalkAll, x.BulkWalkAll} {
+		pdus, err := walk(".1.3.6.1.4.1.99999")
+		if err != nil {
+			t.Fatalf("walk err: %v", err)
+		}
+		var walked []string
+		for _, pdu := range pdus {
+			v := pdu.Value
+			if b, ok := v.([]byte); ok {
+				v = string(b)
+			}
+			walked = append(walked, fmt.Sprintf("%s=%v", pdu.Name, v))
+		}
+		if fmt.Sprint(walked) != fmt.Sprint(want) {
+			t.Errorf("walked %v, expected %v", walked, want)
+		}
+	}
+	if n := table.fetches(); n != 1 {
+		t.Errorf("rows fetched %d times, expected once for both walks", n)
+	}
+
+	result, err := x.Get([]string{entry + ".2.1.98.1", entry + ".2.1.97.2", entry + ".3.1.98.1", entry + ".2.1.99.1"})
+	if err != nil {
+		t.Fatalf("Get() err: %v", err)
+	}
+	for i, want := range []Asn1BER{Integer, NoSuchInstance, NoSuchObject, NoSuchInstance} {
+		if v := result.Variables[i]; v.Type != want {
+			t.Errorf("Get() varbind %d got %v, expected %s", i, v, want)
+		}
+	}
+
+	x.Community = "private"
+	result, err = x.Set([]SnmpPDU{{Name: entry + ".2.1.99.7", Type: Integer, Value: 37}})
+	if err != nil || result.Error != NoError {
+		t.Fatalf("Set() of a new row got %v, err %v", result, err)
+	}
+	result, err = x.Get([]string{entry + ".2.1.99.7"})
+	if err != nil || result.Variables[0].Value != 37 {
+		t.Errorf("Get() of the new row got %v, err %v", result, err)
+	}
+}
+
+func TestAgentTableReadOnly(t *testing.T) {
+	const entry = ".1.3.6.1.4.1.99999.1.1"
+	a := &Agent{WriteCommunity: "private"}
+	a.RegisterTable(entry, []IndexPart{{Kind: IndexIPAddress}}, TableProviderFunc(func() ([]TableRow, error) {
+		return []TableRow{
+			{Index: []interface{}{"192.0.2.1"}, Columns: map[int]SnmpPDU{1: {Type: Integer, Value: 1}}},
+			{Index: []interface{}{"not an address"}, Columns: map[int]SnmpPDU{1: {Type: Integer, Value: 2}}},
+		}, nil
+	}))
+	defer a.Close()
+	x := startTestAgent(t, a, &GoSNMP{Community: "private", Version: Version2c})
+	defer x.Conn.Close()
+
+	result, err := x.GetNext([]string{entry})
+	if err != nil || result.Variables[0].Name != entry+".1.192.0.2.1" {
+		t.Errorf("GetNext() got %v, err %v", result, err)
+	}
+	result, err = x.GetNext([]string{entry + ".1.192.0.2.1"})
+	if err != nil || result.Variables[0].Type != EndOfMibView {
+		t.Errorf("GetNext() after the row with a valid index got %v, err %v", result, err)
+	}
+	result, err = x.Set([]SnmpPDU{{Name: entry + ".1.192.0.2.1", Type: Integer, Value: 3}})
+	if err != nil || result.Error != NotWritable {
+		t.Errorf("Set() got %v, err %v, expected notWritable", result, err)
+	}
+}
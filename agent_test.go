@@ -0,0 +1,433 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testScalar is an AgentHandler holding a value
+type testScalar struct {
+	mu  sync.Mutex
+	pdu SnmpPDU
+}
+
+func (s *testScalar) Get(oid string) (SnmpPDU, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pdu, nil
+}
+
+func (s *testScalar) Set(pdu SnmpPDU) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if pdu.Type != s.pdu.Type {
+		return WrongType
+	}
+	s.pdu = pdu
+	return nil
+}
+
+func (s *testScalar) value() interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pdu.Value
+}
+
+// startTestAgent serves a on a udp port, returning a client connected to it
+func startTestAgent(t *testing.T, a *Agent, x *GoSNMP) *GoSNMP {
+	t.Helper()
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket() err: %v", err)
+	}
+	go a.Serve(conn)
+	if x == nil {
+		x = &GoSNMP{Community: "public", Version: Version2c}
+	}
+	x.Target = "127.0.0.1"
+	x.Port = uint16(conn.LocalAddr().(*net.UDPAddr).Port)
+	x.Timeout = time.Second
+	x.Retries = 1
+	if err = x.Connect(); err != nil {
+		t.Fatalf("Connect() err: %v", err)
+	}
+	return x
+}
+
+const (
+	testSysDescr = ".1.3.6.1.2.1.1.1.0"
+	testSysName  = ".1.3.6.1.2.1.1.5.0"
+	testIfNumber = ".1.3.6.1.2.1.2.1.0"
+)
+
+func newTestAgent(t *testing.T) (*Agent, *testScalar) {
+	a := &Agent{WriteCommunity: "private"}
+	name := &testScalar{pdu: SnmpPDU{Type: OctetString, Value: []byte("name")}}
+	for oid, handler := range map[string]AgentHandler{
+		testSysDescr: AgentHandlerFunc(func(string) (SnmpPDU, error) {
+			return SnmpPDU{Type: OctetString, Value: []byte("descr")}, nil
+		}),
+		testSysName: name,
+		testIfNumber: AgentHandlerFunc(func(string) (SnmpPDU, error) {
+			return SnmpPDU{Type: Integer, Value: 2}, nil
+		}),
+		".1.3.6.1.2.1.1.4.0": AgentHandlerFunc(func(string) (SnmpPDU, error) {
+			return SnmpPDU{Type: NoSuchInstance}, nil
+		}),
+	} {
+		if err := a.Register(oid, handler); err != nil {
+			t.Fatalf("Register(%s) err: %v", oid, err)
+		}
+	}
+	return a, name
+}
+
+func TestAgentGet(t *testing.T) {
+	a, _ := newTestAgent(t)
+	defer a.Close()
+	x := startTestAgent(t, a, nil)
+	defer x.Conn.Close()
+
+	result, err := x.Get([]string{testSysDescr, ".1.3.6.1.2.1.1.2.0", ".1.3.6.1.2.1.1.4.0"})
+	if err != nil {
+		t.Fatalf("Get() err: %v", err)
+	}
+	if len(result.Variables) != 3 {
+		t.Fatalf("got %d varbinds, expected 3", len(result.Variables))
+	}
+	if v := result.Variables[0]; v.Name != testSysDescr || string(v.Value.([]byte)) != "descr" {
+		t.Errorf("got %v, expected sysDescr", v)
+	}
+	if v := result.Variables[1]; v.Type != NoSuchObject {
+		t.Errorf("got %v, expected noSuchObject", v)
+	}
+	if v := result.Variables[2]; v.Type != NoSuchInstance {
+		t.Errorf("got %v, expected noSuchInstance", v)
+	}
+
+	result, err = x.GetNext([]string{".1.3.6.1.2.1.1.3", testIfNumber})
+	if err != nil {
+		t.Fatalf("GetNext() err: %v", err)
+	}
+	// skipping .1.3.6.1.2.1.1.4.0, which has no value
+	if v := result.Variables[0]; v.Name != testSysName {
+		t.Errorf("got %v, expected sysName", v)
+	}
+	if v := result.Variables[1]; v.Type != EndOfMibView {
+		t.Errorf("got %v, expected endOfMibView", v)
+	}
+
+	pdus, err := x.BulkWalkAll(".1.3.6.1.2.1")
+	if err != nil {
+		t.Fatalf("BulkWalkAll() err: %v", err)
+	}
+	var names []string
+	for _, pdu := range pdus {
+		names = append(names, pdu.Name)
+	}
+	if got, want := fmt.Sprint(names), fmt.Sprint([]string{testSysDescr, testSysName, testIfNumber}); got != want {
+		t.Errorf("walked %s, expected %s", got, want)
+	}
+}
+
+func TestAgentSet(t *testing.T) {
+	a, name := newTestAgent(t)
+	defer a.Close()
+	x := startTestAgent(t, a, nil)
+	defer x.Conn.Close()
+
+	set := []SnmpPDU{{Name: testSysName, Type: OctetString, Value: "new"}}
+	result, err := x.Set(set)
+	if err != nil || result.Error != NoAccess {
+		t.Errorf("Set() with the read community got %v, err %v, expected noAccess", result, err)
+	}
+
+	x.Community = "private"
+	tests := []struct {
+		pdus   []SnmpPDU
+		status SNMPError
+		index  uint8
+	}{
+		{set, NoError, 0},
+		{[]SnmpPDU{set[0], {Name: testSysDescr, Type: OctetString, Value: "descr"}}, NotWritable, 2},
+		{[]SnmpPDU{{Name: testSysName, Type: Integer, Value: 1}}, WrongType, 1},
+		{[]SnmpPDU{{Name: ".1.3.6.1.2.1.1.9.0", Type: Integer, Value: 1}}, NoCreation, 1},
+	}
+	for _, test := range tests {
+		result, err = x.Set(test.pdus)
+		if err != nil {
+			t.Errorf("Set(%v) err: %v", test.pdus, err)
+			continue
+		}
+		if result.Error != test.status || result.ErrorIndex != test.index {
+			t.Errorf("Set(%v) got %s at %d, expected %s at %d",
+				test.pdus, result.Error, result.ErrorIndex, test.status, test.index)
+		}
+		if len(result.Variables) != len(test.pdus) {
+			t.Errorf("Set(%v) got varbinds %v", test.pdus, result.Variables)
+		}
+	}
+	if got := string(name.value().([]byte)); got != "new" {
+		t.Errorf("sysName is %q, expected \"new\"", got)
+	}
+
+	// with the wrong community, there's no response
+	x.Community = "wrong"
+	x.Retries = 0
+	x.Timeout = 100 * time.Millisecond
+	if _, err = x.Get([]string{testSysName}); err == nil {
+		t.Errorf("Get() with the wrong community succeeded")
+	}
+}
+
+func TestAgentV1(t *testing.T) {
+	a, _ := newTestAgent(t)
+	defer a.Close()
+	x := startTestAgent(t, a, &GoSNMP{Community: "private", Version: Version1})
+	defer x.Conn.Close()
+
+	tests := []struct {
+		name   string
+		call   func() (*SnmpPacket, error)
+		status SNMPError
+		index  uint8
+	}{
+		{"get", func() (*SnmpPacket, error) { return x.Get([]string{testSysDescr}) }, NoError, 0},
+		{"get missing", func() (*SnmpPacket, error) {
+			return x.Get([]string{testSysDescr, ".1.3.6.1.2.1.1.2.0"})
+		}, NoSuchName, 2},
+		{"getnext end", func() (*SnmpPacket, error) { return x.GetNext([]string{testIfNumber}) }, NoSuchName, 1},
+		{"set not writable", func() (*SnmpPacket, error) {
+			return x.Set([]SnmpPDU{{Name: testSysDescr, Type: OctetString, Value: "descr"}})
+		}, NoSuchName, 1},
+		{"set wrong type", func() (*SnmpPacket, error) {
+			return x.Set([]SnmpPDU{{Name: testSysName, Type: Integer, Value: 1}})
+		}, BadValue, 1},
+	}
+	for _, test := range tests {
+		result, err := test.call()
+		if err != nil {
+			t.Errorf("%s: err %v", test.name, err)
+			continue
+		}
+		if result.Error != test.status || result.ErrorIndex != test.index {
+			t.Errorf("%s: got %s at %d, expected %s at %d",
+				test.name, result.Error, result.ErrorIndex, test.status, test.index)
+		}
+	}
+}
+
+func TestAgentGetBulkSize(t *testing.T) {
+	a := &Agent{MaxMessageSize: 484}
+	for i := 1; i <= 100; i++ {
+		oid := fmt.Sprintf(".1.3.6.1.4.1.99999.%d.0", i)
+		a.Register(oid, AgentHandlerFunc(func(string) (SnmpPDU, error) {
+			return SnmpPDU{Type: OctetString, Value: []byte("a value of some length")}, nil
+		}))
+	}
+	a.Register(".1.3.6.1.4.1.99999.200.0", AgentHandlerFunc(func(string) (SnmpPDU, error) {
+		return SnmpPDU{Type: OctetString, Value: bytes.Repeat([]byte("x"), 500)}, nil
+	}))
+	defer a.Close()
+	x := startTestAgent(t, a, nil)
+	defer x.Conn.Close()
+
+	result, err := x.GetBulk([]string{".1.3.6.1.4.1.99999"}, 0, 50)
+	if err != nil {
+		t.Fatalf("GetBulk() err: %v", err)
+	}
+	if n := len(result.Variables); n == 0 || n >= 50 || result.Error != NoError {
+		t.Errorf("got %d varbinds and %s, expected fewer than 50 to fit 484 bytes", n, result.Error)
+	}
+	if result.Variables[0].Name != ".1.3.6.1.4.1.99999.1.0" {
+		t.Errorf("got %s first", result.Variables[0].Name)
+	}
+
+	// too large for any response, so tooBig; the client fails with no varbinds
+	if _, err = x.Get([]string{".1.3.6.1.4.1.99999.200.0"}); err == nil {
+		t.Errorf("Get() of a value larger than MaxMessageSize succeeded")
+	}
+}
+
+func TestAgentV3(t *testing.T) {
+	user := func(authPass string) *UsmSecurityParameters {
+		return &UsmSecurityParameters{
+			UserName:                 "user",
+			AuthenticationProtocol:   SHA,
+			AuthenticationPassphrase: authPass,
+			PrivacyProtocol:          AES,
+			PrivacyPassphrase:        "privpassphrase",
+		}
+	}
+	a, name := newTestAgent(t)
+	a.EngineBoots = 2
+	a.Users = []*UsmSecurityParameters{user("authpassphrase"), {UserName: "reader"}}
+	defer a.Close()
+
+	x := startTestAgent(t, a, &GoSNMP{
+		Version:            Version3,
+		MsgFlags:           AuthPriv,
+		SecurityModel:      UserSecurityModel,
+		SecurityParameters: user("authpassphrase"),
+	})
+	result, err := x.Get([]string{testSysDescr})
+	if err != nil {
+		t.Fatalf("Get() err: %v", err)
+	}
+	if v := result.Variables[0]; v.Name != testSysDescr || string(v.Value.([]byte)) != "descr" {
+		t.Errorf("got %v, expected sysDescr", v)
+	}
+	sp := x.SecurityParameters.(*UsmSecurityParameters)
+	if sp.AuthoritativeEngineID != a.engineID || sp.AuthoritativeEngineBoots != 2 {
+		t.Errorf("discovered engine %x boots %d", sp.AuthoritativeEngineID, sp.AuthoritativeEngineBoots)
+	}
+	if _, err = x.Set([]SnmpPDU{{Name: testSysName, Type: OctetString, Value: "set"}}); err != nil {
+		t.Errorf("Set() err: %v", err)
+	}
+	if got := string(name.value().([]byte)); got != "set" {
+		t.Errorf("sysName is %q", got)
+	}
+	x.Conn.Close()
+
+	// requests below the user's level are refused, whatever they are
+	for _, test := range []struct {
+		flags SnmpV3MsgFlags
+		sp    *UsmSecurityParameters
+	}{
+		{AuthNoPriv, &UsmSecurityParameters{UserName: "user", AuthenticationProtocol: SHA,
+			AuthenticationPassphrase: "authpassphrase"}},
+		{NoAuthNoPriv, &UsmSecurityParameters{UserName: "user"}},
+	} {
+		before := atomic.LoadUint32(&a.unsupportedSecLevels)
+		x := startTestAgent(t, a, &GoSNMP{
+			Version:            Version3,
+			MsgFlags:           test.flags,
+			SecurityModel:      UserSecurityModel,
+			SecurityParameters: test.sp,
+		})
+		result, err := x.Set([]SnmpPDU{{Name: testSysName, Type: OctetString, Value: "pwned"}})
+		if err == nil && (result.PDUType != Report || result.Variables[0].Name != usmStatsUnsupportedSecLevels) {
+			t.Errorf("%s: Set() got %s, expected a usmStatsUnsupportedSecLevels report", test.flags, result)
+		}
+		if atomic.LoadUint32(&a.unsupportedSecLevels) == before {
+			t.Errorf("%s: usmStatsUnsupportedSecLevels wasn't counted", test.flags)
+		}
+		if got := string(name.value().([]byte)); got != "set" {
+			t.Errorf("%s: sysName is %q", test.flags, got)
+		}
+		x.Conn.Close()
+	}
+
+	// noAuthNoPriv users can read but not write
+	x = startTestAgent(t, a, &GoSNMP{
+		Version:            Version3,
+		MsgFlags:           NoAuthNoPriv,
+		SecurityModel:      UserSecurityModel,
+		SecurityParameters: &UsmSecurityParameters{UserName: "reader"},
+	})
+	if result, err = x.Get([]string{testSysDescr}); err != nil || result.Variables[0].Name != testSysDescr {
+		t.Errorf("Get() as a noAuthNoPriv user got %v, %v", result, err)
+	}
+	result, err = x.Set([]SnmpPDU{{Name: testSysName, Type: OctetString, Value: "pwned"}})
+	if err == nil && result.Error == NoError {
+		t.Errorf("Set() as a noAuthNoPriv user succeeded")
+	}
+	if got := string(name.value().([]byte)); got != "set" {
+		t.Errorf("noAuthNoPriv: sysName is %q", got)
+	}
+	x.Conn.Close()
+
+	before := atomic.LoadUint32(&a.wrongDigests)
+	x = startTestAgent(t, a, &GoSNMP{
+		Version:            Version3,
+		MsgFlags:           AuthPriv,
+		SecurityModel:      UserSecurityModel,
+		SecurityParameters: user("wrongpassphrase"),
+	})
+	defer x.Conn.Close()
+	result, err = x.Get([]string{testSysDescr})
+	if err == nil && (result.PDUType != Report || result.Variables[0].Name != usmStatsWrongDigests) {
+		t.Errorf("Get() with the wrong passphrase got %s, expected a usmStatsWrongDigests report", result)
+	}
+	if atomic.LoadUint32(&a.wrongDigests) == before {
+		t.Errorf("usmStatsWrongDigests wasn't counted")
+	}
+}
+
+func TestAgentTCP(t *testing.T) {
+	a, _ := newTestAgent(t)
+	defer a.Close()
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() err: %v", err)
+	}
+	done := make(chan error)
+	go func() { done <- a.ServeTCP(l) }()
+
+	conn, err := net.Dial("tcp4", l.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial() err: %v", err)
+	}
+	defer conn.Close()
+	x := &GoSNMP{}
+	for i := 1; i <= 2; i++ {
+		msg, err := x.Encode(&SnmpPacket{Version: Version2c, Community: "public", PDUType: GetRequest,
+			RequestID: uint32(i), Variables: []SnmpPDU{{Name: testSysDescr, Type: Null}}})
+		if err != nil {
+			t.Fatalf("Encode() err: %v", err)
+		}
+		// written in two parts, to be put back together
+		conn.Write(msg[:3])
+		conn.Write(msg[3:])
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		response, err := readStreamMessage(conn)
+		if err != nil {
+			t.Fatalf("reading response err: %v", err)
+		}
+		result, err := x.Decode(response)
+		if err != nil {
+			t.Fatalf("Decode() err: %v", err)
+		}
+		if result.RequestID != uint32(i) || len(result.Variables) != 1 ||
+			string(result.Variables[0].Value.([]byte)) != "descr" {
+			t.Errorf("got response %s", result)
+		}
+	}
+
+	a.Close()
+	select {
+	case err = <-done:
+		if err != nil {
+			t.Errorf("ServeTCP() returned %v after Close, expected nil", err)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("ServeTCP() didn't return after Close")
+	}
+}
+
+func TestAgentErrorStatus(t *testing.T) {
+	tests := []struct {
+		err    error
+		status SNMPError
+	}{
+		{NotWritable, NotWritable},
+		{fmt.Errorf("checking: %w", WrongValue), WrongValue},
+		{&StatusError{Status: ResourceUnavailable}, ResourceUnavailable},
+		{errors.New("disk full"), GenErr},
+	}
+	for _, test := range tests {
+		if got := agentErrorStatus(test.err); got != test.status {
+			t.Errorf("agentErrorStatus(%v) = %s, expected %s", test.err, got, test.status)
+		}
+	}
+}
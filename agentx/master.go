@@ -0,0 +1,614 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package agentx
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/soniah/gosnmp"
+)
+
+// defaultTimeout is how long a Master waits for the responses of
+// subagents, unless Timeout or the subagents say otherwise
+const defaultTimeout = 5 * time.Second
+
+// maxRangeRegistrations is the most subtrees a Register with a range
+// (eg of the columns of a table row) may register
+const maxRangeRegistrations = 1024
+
+// Master is an AgentX master agent, answering the requests of managers
+// for subtrees registered by subagents. Requests for them are forwarded to
+// the subagents' sessions by the handlers Master registers with Agent, so
+// they are served alongside the Agent's own objects, with its
+// communities, users and Vacm.
+//
+// Where subagents register the same subtree, the registration with the
+// highest priority (the lowest value) is served, and the others only when
+// it is unregistered; registrations in other than the default context are
+// refused. A registration replaces any of the Agent's own at the same oid.
+type Master struct {
+	// Agent serves the subagents' subtrees
+	Agent *gosnmp.Agent
+
+	// Timeout is how long to wait for a subagent's responses, unless its
+	// Open or Register gives a timeout (default: 5 seconds)
+	Timeout time.Duration
+
+	// OnNotify, if set, is given the varbinds of the Notify PDUs of
+	// subagents, eg to send them on with GoSNMP.SendTrap. If it isn't,
+	// Notifies fail with processingError.
+	OnNotify func(varbinds []gosnmp.SnmpPDU)
+
+	// Logger is given warnings, eg of subagents that don't respond
+	Logger gosnmp.LeveledLogger
+
+	mu            sync.Mutex
+	start         time.Time // for sysUpTime
+	sessions      map[uint32]*masterSession
+	lastSessionID uint32
+	transactionID uint32
+	registrations []*masterRegistration // in the order registered
+	closed        bool
+	closers       map[io.Closer]struct{} // connections and listeners to Close
+}
+
+// masterConn is a connection from a subagent, which may carry several of
+// its sessions
+type masterConn struct {
+	m        *Master
+	conn     net.Conn
+	writeMu  sync.Mutex
+	mu       sync.Mutex
+	packetID uint32
+	pending  map[uint32]chan *pdu // the requests waiting for responses
+	done     chan struct{}        // closed when the connection is
+}
+
+// masterSession is a subagent's session
+type masterSession struct {
+	c       *masterConn
+	id      uint32
+	oid     string // the subagent's id
+	descr   string
+	timeout time.Duration
+	handler *masterHandler // of all its registrations, so SETs are one transaction
+
+	transactionID uint32 // of the SET in progress
+}
+
+// masterRegistration is a subtree, or an object instance, registered by a
+// subagent
+type masterRegistration struct {
+	session  *masterSession
+	oid      gosnmp.Oid
+	priority uint8
+	instance bool
+	timeout  time.Duration
+	active   bool // whether it is the one registered with the Agent
+}
+
+// Listen listens for subagents on addr over network, eg "unix" and
+// "/var/agentx/master", or "tcp" and ":705", and serves them as Serve does
+func (m *Master) Listen(network, addr string) error {
+	l, err := net.Listen(network, addr)
+	if err != nil {
+		return err
+	}
+	return m.Serve(l)
+}
+
+// Serve accepts the connections of subagents from l, and serves their
+// sessions, until Close
+func (m *Master) Serve(l net.Listener) error {
+	if m.Agent == nil {
+		return errors.New("AgentX Master has no Agent")
+	}
+	if !m.track(l) {
+		return nil
+	}
+	defer m.untrack(l)
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if m.isClosed() {
+				return nil
+			}
+			return err
+		}
+		if !m.track(conn) {
+			return nil
+		}
+		c := &masterConn{m: m, conn: conn, pending: make(map[uint32]chan *pdu), done: make(chan struct{})}
+		go func() {
+			defer m.untrack(conn)
+			c.serve()
+		}()
+	}
+}
+
+// Close stops Serve, and closes the connections of the subagents, whose
+// registrations are unregistered from the Agent
+func (m *Master) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closed = true
+	for c := range m.closers {
+		c.Close()
+	}
+	m.closers = nil
+	return nil
+}
+
+// Sessions returns the ids and descriptions of the subagents with open
+// sessions, by session id
+func (m *Master) Sessions() map[uint32]string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sessions := make(map[uint32]string, len(m.sessions))
+	for id, s := range m.sessions {
+		sessions[id] = fmt.Sprintf("%s %s", s.oid, s.descr)
+	}
+	return sessions
+}
+
+func (m *Master) track(c io.Closer) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		c.Close()
+		return false
+	}
+	if m.closers == nil {
+		m.closers = make(map[io.Closer]struct{})
+		m.start = time.Now()
+	}
+	m.closers[c] = struct{}{}
+	return true
+}
+
+func (m *Master) untrack(c io.Closer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.closers, c)
+}
+
+func (m *Master) isClosed() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.closed
+}
+
+func (m *Master) logWarn(msg string, args ...interface{}) {
+	if m.Logger != nil {
+		m.Logger.Warn(msg, args...)
+	}
+}
+
+// serve reads the PDUs of the subagent, answering its requests and passing
+// on its responses, until the connection is closed
+func (c *masterConn) serve() {
+	defer func() {
+		c.conn.Close()
+		close(c.done)
+		c.m.closeSessions(c)
+	}()
+	for {
+		p, err := readPDU(c.conn)
+		if p == nil {
+			if !errors.Is(err, io.EOF) && !c.m.isClosed() {
+				c.m.logWarn("AgentX connection failed", "err", err)
+			}
+			return
+		}
+		if err != nil {
+			c.m.logWarn("Unable to decode AgentX PDU", "err", err)
+			if p.typ != responsePDU {
+				response := c.m.response(p)
+				response.err = ParseError
+				c.send(response)
+			}
+			continue
+		}
+		if p.typ == responsePDU {
+			c.mu.Lock()
+			ch := c.pending[p.packetID]
+			delete(c.pending, p.packetID)
+			c.mu.Unlock()
+			if ch != nil {
+				ch <- p
+			}
+			continue
+		}
+		c.send(c.m.handle(c, p))
+	}
+}
+
+// response returns the Response to p
+func (m *Master) response(p *pdu) *pdu {
+	response := p.response()
+	m.mu.Lock()
+	response.sysUpTime = gosnmp.DurationToTimeTicks(time.Since(m.start))
+	m.mu.Unlock()
+	return response
+}
+
+// handle returns the Response to a subagent's PDU
+func (m *Master) handle(c *masterConn, p *pdu) *pdu {
+	response := m.response(p)
+	if p.typ == openPDU {
+		s := &masterSession{c: c, oid: p.id.String(), descr: p.descr, timeout: time.Duration(p.timeout) * time.Second}
+		m.mu.Lock()
+		m.lastSessionID++
+		s.id = m.lastSessionID
+		s.handler = &masterHandler{s: s, m: m}
+		if m.sessions == nil {
+			m.sessions = make(map[uint32]*masterSession)
+		}
+		m.sessions[s.id] = s
+		m.mu.Unlock()
+		response.sessionID = s.id
+		return response
+	}
+
+	m.mu.Lock()
+	s := m.sessions[p.sessionID]
+	m.mu.Unlock()
+	if s == nil || s.c != c {
+		response.err = NotOpen
+		return response
+	}
+	switch p.typ {
+	case closePDU:
+		m.closeSession(s)
+	case registerPDU:
+		response.err = m.register(s, p)
+	case unregisterPDU:
+		response.err = m.unregister(s, p)
+	case pingPDU:
+	case notifyPDU:
+		if m.OnNotify == nil {
+			response.err = ProcessingError
+			break
+		}
+		m.OnNotify(p.varbinds)
+	default:
+		// eg IndexAllocate and AddAgentCaps, which aren't supported
+		response.err = ProcessingError
+	}
+	return response
+}
+
+// register adds the registrations of a Register PDU
+func (m *Master) register(s *masterSession, p *pdu) Error {
+	if p.context != "" {
+		return RequestDenied
+	}
+	oids, err := rangeOids(p)
+	if err != 0 {
+		return err
+	}
+	priority := p.priority
+	if priority == 0 {
+		priority = defaultPriority
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, oid := range oids {
+		for _, r := range m.registrations {
+			if r.oid.Equal(oid) && r.priority == priority {
+				return DuplicateRegistration
+			}
+		}
+	}
+	var timeout time.Duration
+	if p.timeout != 0 {
+		timeout = time.Duration(p.timeout) * time.Second
+	}
+	for _, oid := range oids {
+		m.registrations = append(m.registrations, &masterRegistration{session: s, oid: oid,
+			priority: priority, instance: p.flags&flagInstanceRegistration != 0, timeout: timeout})
+		m.refresh(oid)
+	}
+	return 0
+}
+
+// unregister removes the registrations of an Unregister PDU
+func (m *Master) unregister(s *masterSession, p *pdu) Error {
+	if p.context != "" {
+		return UnknownRegistration
+	}
+	oids, err := rangeOids(p)
+	if err != 0 {
+		return err
+	}
+	priority := p.priority
+	if priority == 0 {
+		priority = defaultPriority
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var found []*masterRegistration
+	for _, oid := range oids {
+		for _, r := range m.registrations {
+			if r.session == s && r.oid.Equal(oid) && r.priority == priority {
+				found = append(found, r)
+			}
+		}
+	}
+	if len(found) != len(oids) {
+		return UnknownRegistration
+	}
+	for _, r := range found {
+		m.remove(r)
+	}
+	return 0
+}
+
+// closeSessions closes the sessions of a connection that has closed
+func (m *Master) closeSessions(c *masterConn) {
+	m.mu.Lock()
+	var sessions []*masterSession
+	for _, s := range m.sessions {
+		if s.c == c {
+			sessions = append(sessions, s)
+		}
+	}
+	m.mu.Unlock()
+	for _, s := range sessions {
+		m.closeSession(s)
+	}
+}
+
+// closeSession closes s, removing its registrations
+func (m *Master) closeSession(s *masterSession) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sessions, s.id)
+	for _, r := range append([]*masterRegistration(nil), m.registrations...) {
+		if r.session == s {
+			m.remove(r)
+		}
+	}
+}
+
+// remove removes the registration r; m.mu is held
+func (m *Master) remove(r *masterRegistration) {
+	for i := range m.registrations {
+		if m.registrations[i] == r {
+			m.registrations = append(m.registrations[:i:i], m.registrations[i+1:]...)
+			break
+		}
+	}
+	m.refresh(r.oid)
+}
+
+// refresh registers with the Agent the registration of oid with the highest
+// priority, or unregisters oid if there are none; m.mu is held
+func (m *Master) refresh(oid gosnmp.Oid) {
+	var best *masterRegistration
+	for _, r := range m.registrations {
+		if r.oid.Equal(oid) {
+			r.active = false
+			if best == nil || r.priority < best.priority {
+				best = r
+			}
+		}
+	}
+	if best == nil {
+		m.Agent.Unregister(oid.String())
+		return
+	}
+	best.active = true
+	if best.instance {
+		m.Agent.Register(oid.String(), best.session.handler)
+	} else {
+		m.Agent.RegisterSubtree(oid.String(), best.session.handler)
+	}
+}
+
+// rangeOids returns the oids registered by a Register or Unregister, which
+// has a range if p.rangeSubid is set
+func rangeOids(p *pdu) ([]gosnmp.Oid, Error) {
+	if len(p.subtree) == 0 {
+		return nil, ParseError
+	}
+	if p.rangeSubid == 0 {
+		return []gosnmp.Oid{p.subtree}, 0
+	}
+	i := int(p.rangeSubid) - 1
+	if i >= len(p.subtree) || p.upperBound < p.subtree[i] || p.upperBound-p.subtree[i] >= maxRangeRegistrations {
+		return nil, ParseError
+	}
+	var oids []gosnmp.Oid
+	for n := p.subtree[i]; ; n++ {
+		oid := append(gosnmp.Oid(nil), p.subtree...)
+		oid[i] = n
+		oids = append(oids, oid)
+		if n == p.upperBound {
+			return oids, 0
+		}
+	}
+}
+
+// masterHandler is the handler of subtrees registered by a session
+type masterHandler struct {
+	s *masterSession
+	m *Master
+}
+
+// timeout returns how long to wait for the subagent's response to a
+// request for oid
+func (h *masterHandler) timeout(oid gosnmp.Oid) time.Duration {
+	h.m.mu.Lock()
+	defer h.m.mu.Unlock()
+	timeout := h.s.timeout
+	if r := h.registration(oid); r != nil && r.timeout != 0 {
+		timeout = r.timeout
+	}
+	switch {
+	case timeout != 0:
+		return timeout
+	case h.m.Timeout != 0:
+		return h.m.Timeout
+	}
+	return defaultTimeout
+}
+
+// registration returns the session's active registration with the longest
+// prefix of oid; m.mu is held
+func (h *masterHandler) registration(oid gosnmp.Oid) *masterRegistration {
+	var best *masterRegistration
+	for _, r := range h.m.registrations {
+		if r.session == h.s && r.active && oid.HasPrefix(r.oid) && (best == nil || len(r.oid) > len(best.oid)) {
+			best = r
+		}
+	}
+	return best
+}
+
+// get sends a Get or GetNext for the search range, returning the varbind
+func (h *masterHandler) get(typ pduType, sr searchRange) (gosnmp.SnmpPDU, error) {
+	response, err := h.s.request(&pdu{typ: typ, ranges: []searchRange{sr}}, h.timeout(sr.start))
+	if err != nil {
+		return gosnmp.SnmpPDU{}, err
+	}
+	if len(response.varbinds) != 1 {
+		return gosnmp.SnmpPDU{}, fmt.Errorf("AgentX %s got %d varbinds, expected 1", typ, len(response.varbinds))
+	}
+	return response.varbinds[0], nil
+}
+
+// Get gets the object instance oid from the subagent
+func (h *masterHandler) Get(oid string) (gosnmp.SnmpPDU, error) {
+	o, err := gosnmp.ParseOid(oid)
+	if err != nil {
+		return gosnmp.SnmpPDU{}, err
+	}
+	return h.get(getPDU, searchRange{start: o})
+}
+
+// GetNext gets the next object instance from the subagent, in the subtree
+// registered
+func (h *masterHandler) GetNext(oid string) (gosnmp.SnmpPDU, error) {
+	o, err := gosnmp.ParseOid(oid)
+	if err != nil {
+		return gosnmp.SnmpPDU{}, err
+	}
+	sr := searchRange{start: o}
+	h.m.mu.Lock()
+	if r := h.registration(o); r != nil {
+		sr.end = r.oid.NextSibling()
+	}
+	h.m.mu.Unlock()
+	return h.get(getNextPDU, sr)
+}
+
+// Set isn't called, as masterHandler is a SetCommitter
+func (h *masterHandler) Set(pdu gosnmp.SnmpPDU) error {
+	return gosnmp.GenErr
+}
+
+// TestSet starts a SET transaction in the subagent, with its varbinds
+func (h *masterHandler) TestSet(pdus []gosnmp.SnmpPDU) (int, error) {
+	h.m.mu.Lock()
+	h.m.transactionID++
+	h.s.transactionID = h.m.transactionID
+	h.m.mu.Unlock()
+	oid, _ := gosnmp.ParseOid(pdus[0].Name)
+	return h.setPhase(&pdu{typ: testSetPDU, varbinds: pdus}, h.timeout(oid))
+}
+
+// CommitSet commits the SET in the subagent
+func (h *masterHandler) CommitSet() (int, error) {
+	return h.setPhase(&pdu{typ: commitSetPDU}, h.timeout(nil))
+}
+
+// UndoSet undoes the SET in the subagent
+func (h *masterHandler) UndoSet() error {
+	_, err := h.setPhase(&pdu{typ: undoSetPDU}, h.timeout(nil))
+	return err
+}
+
+// CleanupSet ends the SET in the subagent, which doesn't respond
+func (h *masterHandler) CleanupSet() {
+	p := &pdu{typ: cleanupSetPDU, sessionID: h.s.id, transactionID: h.s.transactionID, flags: flagNetworkByteOrder}
+	if err := h.s.c.send(p); err != nil {
+		h.m.logWarn("AgentX CleanupSet failed", "session", h.s.id, "err", err)
+	}
+}
+
+// setPhase sends a PDU of a SET transaction, returning the index (from 0)
+// and error-status of its varbind that failed
+func (h *masterHandler) setPhase(p *pdu, timeout time.Duration) (int, error) {
+	p.transactionID = h.s.transactionID
+	response, err := h.s.request(p, timeout)
+	if response == nil {
+		return 0, err
+	}
+	if response.err != 0 {
+		if response.err < OpenFailed {
+			return int(response.index) - 1, gosnmp.SNMPError(response.err)
+		}
+		return int(response.index) - 1, response.err
+	}
+	return 0, nil
+}
+
+// request sends p to the subagent, returning its response; and its error,
+// if it has one
+func (s *masterSession) request(p *pdu, timeout time.Duration) (*pdu, error) {
+	c := s.c
+	ch := make(chan *pdu, 1)
+	c.mu.Lock()
+	c.packetID++
+	p.sessionID, p.packetID = s.id, c.packetID
+	p.flags |= flagNetworkByteOrder
+	c.pending[p.packetID] = ch
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, p.packetID)
+		c.mu.Unlock()
+	}()
+
+	if err := c.send(p); err != nil {
+		return nil, err
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case response := <-ch:
+		if response.err != 0 {
+			return response, response.err
+		}
+		return response, nil
+	case <-c.done:
+		return nil, ErrClosed
+	case <-timer.C:
+		c.m.logWarn("AgentX subagent didn't respond", "session", s.id, "subagent", s.descr, "type", p.typ)
+		return nil, fmt.Errorf("No AgentX response to %s in %s", p.typ, timeout)
+	}
+}
+
+// send writes p to the subagent
+func (c *masterConn) send(p *pdu) error {
+	b, err := p.marshal()
+	if err != nil {
+		c.m.logWarn("Unable to marshal AgentX PDU", "type", p.typ, "err", err)
+		return err
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(defaultTimeout))
+	_, err = c.conn.Write(b)
+	return err
+}
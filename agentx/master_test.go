@@ -0,0 +1,269 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package agentx
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/soniah/gosnmp"
+)
+
+const (
+	testScalar = ".1.3.6.1.4.1.99999.1.1.0"
+	testOther  = ".1.3.6.1.4.1.99999.1.2.0"
+	testObject = ".1.3.6.1.4.1.99999.2.0"
+)
+
+// startTestMaster starts an Agent, with sysDescr, and a Master for it,
+// returning a manager of the Agent and the address of the Master
+func startTestMaster(t *testing.T) (*Master, *gosnmp.GoSNMP, string) {
+	t.Helper()
+	a := &gosnmp.Agent{WriteCommunity: "private"}
+	a.Register(".1.3.6.1.2.1.1.1.0", gosnmp.AgentHandlerFunc(func(string) (gosnmp.SnmpPDU, error) {
+		return gosnmp.SnmpPDU{Type: gosnmp.OctetString, Value: []byte("master")}, nil
+	}))
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket() err: %v", err)
+	}
+	go a.Serve(conn)
+	t.Cleanup(func() { a.Close() })
+
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() err: %v", err)
+	}
+	m := &Master{Agent: a, Timeout: time.Second}
+	go m.Serve(l)
+	t.Cleanup(func() { m.Close() })
+
+	x := &gosnmp.GoSNMP{
+		Target:    "127.0.0.1",
+		Port:      uint16(conn.LocalAddr().(*net.UDPAddr).Port),
+		Community: "private",
+		Version:   gosnmp.Version2c,
+		Timeout:   2 * time.Second,
+		Retries:   1,
+	}
+	if err = x.Connect(); err != nil {
+		t.Fatalf("Connect() err: %v", err)
+	}
+	t.Cleanup(func() { x.Conn.Close() })
+	return m, x, l.Addr().String()
+}
+
+// newTestTree returns a testTree of two scalars
+func newTestTree() *testTree {
+	return &testTree{
+		values: map[string]int{testScalar: 1, testOther: 2},
+		old:    map[string]int{},
+		setErr: map[string]error{},
+	}
+}
+
+// connectTestSubagent connects a Subagent serving tree at
+// .1.3.6.1.4.1.99999.1, and the object testObject
+func connectTestSubagent(t *testing.T, addr string, tree *testTree, priority uint8) (*Subagent, error) {
+	t.Helper()
+	s := &Subagent{ID: ".1.3.6.1.4.1.99999", Description: fmt.Sprint("test ", priority), Priority: priority}
+	s.RegisterSubtree(".1.3.6.1.4.1.99999.1", tree)
+	s.Register(testObject, gosnmp.AgentHandlerFunc(func(string) (gosnmp.SnmpPDU, error) {
+		return gosnmp.SnmpPDU{Type: gosnmp.Integer, Value: int(priority)}, nil
+	}))
+	return s, s.Connect("tcp", addr)
+}
+
+func TestMaster(t *testing.T) {
+	m, x, addr := startTestMaster(t)
+	tree := newTestTree()
+	s, err := connectTestSubagent(t, addr, tree, 0)
+	if err != nil {
+		t.Fatalf("Connect() err: %v", err)
+	}
+	defer s.Close()
+	if got := fmt.Sprint(m.Sessions()); got != "map[1:.1.3.6.1.4.1.99999 test 0]" {
+		t.Errorf("Sessions() got %s", got)
+	}
+
+	result, err := x.Get([]string{".1.3.6.1.2.1.1.1.0", testScalar, testObject, ".1.3.6.1.4.1.99999.1.9.0"})
+	if err != nil {
+		t.Fatalf("Get() err: %v", err)
+	}
+	if got, want := describe(result.Variables[1:]), testScalar+"=1 "+testObject+"=0 "+
+		".1.3.6.1.4.1.99999.1.9.0=NoSuchInstance"; got != want {
+		t.Errorf("Get() got %s, expected %s", got, want)
+	}
+	pdus, err := x.BulkWalkAll(".1.3.6.1")
+	if err != nil {
+		t.Fatalf("BulkWalkAll() err: %v", err)
+	}
+	if got, want := describe(pdus[1:]), testScalar+"=1 "+testOther+"=2 "+testObject+"=0"; len(pdus) != 4 || got != want {
+		t.Errorf("BulkWalkAll() got %s, expected %s", got, want)
+	}
+
+	// SETs, in one transaction of the subagent
+	tests := []struct {
+		pdus   []gosnmp.SnmpPDU
+		status gosnmp.SNMPError
+		index  uint8
+		values string
+	}{
+		{[]gosnmp.SnmpPDU{{Name: testScalar, Type: gosnmp.Integer, Value: 10},
+			{Name: testOther, Type: gosnmp.Integer, Value: 20}}, gosnmp.NoError, 0, "10 20"},
+		{[]gosnmp.SnmpPDU{{Name: testScalar, Type: gosnmp.Integer, Value: 11},
+			{Name: testOther, Type: gosnmp.OctetString, Value: "x"}}, gosnmp.WrongType, 2, "10 20"},
+		{[]gosnmp.SnmpPDU{{Name: testScalar, Type: gosnmp.Integer, Value: 11},
+			{Name: testObject, Type: gosnmp.Integer, Value: 1}}, gosnmp.NotWritable, 2, "10 20"},
+	}
+	for _, test := range tests {
+		result, err = x.Set(test.pdus)
+		if err != nil {
+			t.Fatalf("Set() err: %v", err)
+		}
+		if result.Error != test.status || result.ErrorIndex != test.index {
+			t.Errorf("Set(%v) got %s at %d, expected %s at %d", test.pdus, result.Error, result.ErrorIndex,
+				test.status, test.index)
+		}
+		if got := fmt.Sprint(tree.value(testScalar), " ", tree.value(testOther)); got != test.values {
+			t.Errorf("Set(%v) left values %s, expected %s", test.pdus, got, test.values)
+		}
+	}
+
+	// a commit failing, which the subagent undoes
+	tree.mu.Lock()
+	tree.setErr[testOther] = errors.New("disk full")
+	tree.mu.Unlock()
+	result, err = x.Set([]gosnmp.SnmpPDU{{Name: testScalar, Type: gosnmp.Integer, Value: 12},
+		{Name: testOther, Type: gosnmp.Integer, Value: 22}})
+	if err != nil || result.Error != gosnmp.CommitFailed || result.ErrorIndex != 2 {
+		t.Errorf("Set() got %v, err %v, expected commitFailed at 2", result, err)
+	}
+	if v := tree.value(testScalar); v != 10 {
+		t.Errorf("got %d, expected the Set undone", v)
+	}
+
+	// the subtrees are unregistered when the session closes
+	if err = s.Close(); err != nil {
+		t.Errorf("Close() err: %v", err)
+	}
+	result, err = x.Get([]string{testScalar, testObject})
+	if err != nil {
+		t.Fatalf("Get() err: %v", err)
+	}
+	if got, want := describe(result.Variables), testScalar+"=NoSuchObject "+testObject+"=NoSuchObject"; got != want {
+		t.Errorf("Get() after Close got %s, expected %s", got, want)
+	}
+	if n := len(m.Sessions()); n != 0 {
+		t.Errorf("got %d sessions after Close, expected none", n)
+	}
+}
+
+func TestMasterPriority(t *testing.T) {
+	_, x, addr := startTestMaster(t)
+	get := func() string {
+		t.Helper()
+		result, err := x.Get([]string{testObject})
+		if err != nil {
+			t.Fatalf("Get() err: %v", err)
+		}
+		return describe(result.Variables)
+	}
+
+	low, err := connectTestSubagent(t, addr, newTestTree(), 200)
+	if err != nil {
+		t.Fatalf("Connect() err: %v", err)
+	}
+	defer low.Close()
+	high, err := connectTestSubagent(t, addr, newTestTree(), 10)
+	if err != nil {
+		t.Fatalf("Connect() err: %v", err)
+	}
+	if got := get(); got != testObject+"=10" {
+		t.Errorf("got %s, expected the registration of priority 10", got)
+	}
+
+	if _, err = connectTestSubagent(t, addr, newTestTree(), 10); !errors.Is(err, DuplicateRegistration) {
+		t.Errorf("Connect() with the same priority got %v, expected duplicateRegistration", err)
+	}
+
+	high.Close()
+	if got := get(); got != testObject+"=200" {
+		t.Errorf("got %s, expected the registration of priority 200", got)
+	}
+}
+
+func TestMasterSession(t *testing.T) {
+	m, _, addr := startTestMaster(t)
+	m.OnNotify = func(varbinds []gosnmp.SnmpPDU) {}
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Dial() err: %v", err)
+	}
+	defer conn.Close()
+	c := &testMaster{t: t, conn: conn}
+	request := func(p *pdu) *pdu {
+		t.Helper()
+		p.flags |= flagNetworkByteOrder
+		c.write(p)
+		return c.read()
+	}
+
+	if p := request(&pdu{typ: pingPDU, sessionID: 1}); p.err != NotOpen {
+		t.Errorf("Ping without a session got %s, expected notOpen", p.err)
+	}
+	open := request(&pdu{typ: openPDU, id: mustOid(t, ".1.3.6.1.4.1.99999"), descr: "raw"})
+	if open.err != 0 || open.sessionID == 0 {
+		t.Fatalf("Open got %s, session %d", open.err, open.sessionID)
+	}
+	id := open.sessionID
+	tests := []struct {
+		p   *pdu
+		err Error
+	}{
+		{&pdu{typ: pingPDU}, 0},
+		{&pdu{typ: notifyPDU, varbinds: []gosnmp.SnmpPDU{{Name: ".1.3.6.1.2.1.1.3.0", Type: gosnmp.TimeTicks, Value: 1}}}, 0},
+		{&pdu{typ: registerPDU, subtree: mustOid(t, ".1.3.6.1.4.1.99999.3"), context: "vrf"}, RequestDenied},
+		{&pdu{typ: registerPDU, subtree: mustOid(t, ".1.3.6.1.4.1.99999.3.1.1"), rangeSubid: 10, upperBound: 4}, 0},
+		{&pdu{typ: unregisterPDU, subtree: mustOid(t, ".1.3.6.1.4.1.99999.3.1.2")}, 0},
+		{&pdu{typ: unregisterPDU, subtree: mustOid(t, ".1.3.6.1.4.1.99999.3.1.2")}, UnknownRegistration},
+		{&pdu{typ: registerPDU, subtree: mustOid(t, ".1.3.6.1.4.1.99999.3.1.1"), rangeSubid: 10, upperBound: 0}, ParseError},
+		{&pdu{typ: indexAllocatePDU}, ProcessingError},
+		{&pdu{typ: closePDU, reason: ReasonShutdown}, 0},
+		{&pdu{typ: pingPDU}, NotOpen},
+	}
+	for i, test := range tests {
+		test.p.sessionID, test.p.packetID = id, uint32(i+1)
+		p := request(test.p)
+		if p.typ != responsePDU || p.packetID != test.p.packetID || p.err != test.err {
+			t.Errorf("%s got %s %d with %s, expected a response with %s", test.p.typ, p.typ, p.packetID, p.err, test.err)
+		}
+	}
+}
+
+func TestRangeOids(t *testing.T) {
+	tests := []struct {
+		p    pdu
+		want string
+		err  Error
+	}{
+		{pdu{subtree: mustOid(t, ".1.3.6.1.2.1.2")}, "[.1.3.6.1.2.1.2]", 0},
+		{pdu{subtree: mustOid(t, ".1.3.6.1.2.1.2.2.1.1.7"), rangeSubid: 10, upperBound: 3},
+			"[.1.3.6.1.2.1.2.2.1.1.7 .1.3.6.1.2.1.2.2.1.2.7 .1.3.6.1.2.1.2.2.1.3.7]", 0},
+		{pdu{subtree: mustOid(t, ".1.3.6.1.2.1.2"), rangeSubid: 8, upperBound: 3}, "[]", ParseError},
+		{pdu{subtree: mustOid(t, ".1.3.6.1.2.1.2"), rangeSubid: 7, upperBound: 5000}, "[]", ParseError},
+		{pdu{}, "[]", ParseError},
+	}
+	for _, test := range tests {
+		oids, err := rangeOids(&test.p)
+		if got := fmt.Sprint(oids); got != test.want || err != test.err {
+			t.Errorf("rangeOids(%s, %d, %d) got %s, %v, expected %s, %v", test.p.subtree, test.p.rangeSubid,
+				test.p.upperBound, got, err, test.want, test.err)
+		}
+	}
+}
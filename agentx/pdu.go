@@ -0,0 +1,563 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package agentx
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"net"
+
+	"github.com/soniah/gosnmp"
+)
+
+//
+// AgentX PDUs, and their encoding (RFC 2741 section 5 and 6)
+//
+
+// the version of the AgentX protocol, in the header of each PDU
+const protocolVersion = 1
+
+// headerSize is the size of a PDU header
+const headerSize = 20
+
+// maxPayloadSize is the size of the largest PDU payload accepted, well
+// over that of any SNMP message
+const maxPayloadSize = 1 << 20
+
+// pduType is the type of a PDU, h.type
+type pduType uint8
+
+// The PDU types
+const (
+	openPDU            pduType = 1
+	closePDU           pduType = 2
+	registerPDU        pduType = 3
+	unregisterPDU      pduType = 4
+	getPDU             pduType = 5
+	getNextPDU         pduType = 6
+	getBulkPDU         pduType = 7
+	testSetPDU         pduType = 8
+	commitSetPDU       pduType = 9
+	undoSetPDU         pduType = 10
+	cleanupSetPDU      pduType = 11
+	notifyPDU          pduType = 12
+	pingPDU            pduType = 13
+	indexAllocatePDU   pduType = 14
+	indexDeallocatePDU pduType = 15
+	addAgentCapsPDU    pduType = 16
+	removeAgentCapsPDU pduType = 17
+	responsePDU        pduType = 18
+)
+
+func (t pduType) String() string {
+	names := [...]string{"", "Open", "Close", "Register", "Unregister", "Get", "GetNext", "GetBulk",
+		"TestSet", "CommitSet", "UndoSet", "CleanupSet", "Notify", "Ping", "IndexAllocate",
+		"IndexDeallocate", "AddAgentCaps", "RemoveAgentCaps", "Response"}
+	if int(t) > 0 && int(t) < len(names) {
+		return names[t]
+	}
+	return fmt.Sprintf("PDU type %d", t)
+}
+
+// the flags of h.flags
+const (
+	flagInstanceRegistration = 0x01
+	flagNewIndex             = 0x02
+	flagAnyIndex             = 0x04
+	flagNonDefaultContext    = 0x08
+	flagNetworkByteOrder     = 0x10
+)
+
+// Error is the res.error of an AgentX Response: an SNMP error-status
+// (gosnmp.SNMPError), or one of the AgentX errors
+type Error uint16
+
+// The AgentX errors, RFC 2741 section 6.2.16
+const (
+	OpenFailed            Error = 256
+	NotOpen               Error = 257
+	IndexWrongType        Error = 258
+	IndexAlreadyAllocated Error = 259
+	IndexNoneAvailable    Error = 260
+	NotRegistered         Error = 261
+	DuplicateRegistration Error = 262
+	UnknownRegistration   Error = 263
+	UnknownAgentCaps      Error = 264
+	ParseError            Error = 265
+	RequestDenied         Error = 266
+	ProcessingError       Error = 267
+)
+
+func (e Error) Error() string {
+	names := [...]string{"openFailed", "notOpen", "indexWrongType", "indexAlreadyAllocated",
+		"indexNoneAvailable", "notRegistered", "duplicateRegistration", "unknownRegistration",
+		"unknownAgentCaps", "parseError", "requestDenied", "processingError"}
+	if e >= OpenFailed && int(e-OpenFailed) < len(names) {
+		return names[e-OpenFailed]
+	}
+	return gosnmp.SNMPError(e).String()
+}
+
+// CloseReason is why an AgentX session was closed
+type CloseReason uint8
+
+// The reasons of Close PDUs, RFC 2741 section 6.2.2
+const (
+	ReasonOther         CloseReason = 1
+	ReasonParseError    CloseReason = 2
+	ReasonProtocolError CloseReason = 3
+	ReasonTimeouts      CloseReason = 4
+	ReasonShutdown      CloseReason = 5
+	ReasonByManager     CloseReason = 6
+)
+
+func (r CloseReason) String() string {
+	names := [...]string{"", "other", "parseError", "protocolError", "timeouts", "shutdown", "byManager"}
+	if int(r) > 0 && int(r) < len(names) {
+		return names[r]
+	}
+	return fmt.Sprintf("reason %d", r)
+}
+
+// searchRange is a SearchRange, the oids a Get or GetNext is for
+type searchRange struct {
+	start   gosnmp.Oid
+	include bool // whether start itself is in the range
+	end     gosnmp.Oid
+}
+
+// pdu is an AgentX PDU of any type, its header and the fields of its type
+type pdu struct {
+	typ                                pduType
+	flags                              uint8
+	sessionID, transactionID, packetID uint32
+	context                            string // with flagNonDefaultContext
+
+	// Open, Register, AddAgentCaps
+	timeout uint8
+	id      gosnmp.Oid // of the subagent, or of the agent capabilities
+	descr   string
+
+	// Close
+	reason CloseReason
+
+	// Register and Unregister
+	priority   uint8
+	rangeSubid uint8
+	subtree    gosnmp.Oid
+	upperBound uint32
+
+	// Get, GetNext and GetBulk
+	nonRepeaters, maxRepetitions uint16
+	ranges                       []searchRange
+
+	// TestSet, Notify, IndexAllocate, IndexDeallocate and Response
+	varbinds []gosnmp.SnmpPDU
+
+	// Response
+	sysUpTime uint32
+	err       Error
+	index     uint16
+}
+
+// order is the byte order of the PDU, as its flags say
+func (p *pdu) order() binary.ByteOrder {
+	if p.flags&flagNetworkByteOrder != 0 {
+		return binary.BigEndian
+	}
+	return binary.LittleEndian
+}
+
+// response returns the Response to p, with its header's ids
+func (p *pdu) response() *pdu {
+	return &pdu{
+		typ:           responsePDU,
+		flags:         flagNetworkByteOrder,
+		sessionID:     p.sessionID,
+		transactionID: p.transactionID,
+		packetID:      p.packetID,
+	}
+}
+
+// marshal returns the encoded PDU
+func (p *pdu) marshal() ([]byte, error) {
+	e := &encoder{buf: make([]byte, headerSize, 64), order: p.order()}
+	if p.context != "" {
+		p.flags |= flagNonDefaultContext
+	}
+	withContext := func() {
+		if p.flags&flagNonDefaultContext != 0 {
+			e.octets([]byte(p.context))
+		}
+	}
+
+	switch p.typ {
+	case openPDU:
+		e.buf = append(e.buf, p.timeout, 0, 0, 0)
+		e.oid(p.id, false)
+		e.octets([]byte(p.descr))
+	case closePDU:
+		e.buf = append(e.buf, byte(p.reason), 0, 0, 0)
+	case registerPDU, unregisterPDU:
+		withContext()
+		if p.typ == registerPDU {
+			e.buf = append(e.buf, p.timeout)
+		} else {
+			e.buf = append(e.buf, 0)
+		}
+		e.buf = append(e.buf, p.priority, p.rangeSubid, 0)
+		e.oid(p.subtree, false)
+		if p.rangeSubid != 0 {
+			e.uint32(p.upperBound)
+		}
+	case getPDU, getNextPDU, getBulkPDU:
+		withContext()
+		if p.typ == getBulkPDU {
+			e.uint16(p.nonRepeaters)
+			e.uint16(p.maxRepetitions)
+		}
+		for _, r := range p.ranges {
+			e.oid(r.start, r.include)
+			e.oid(r.end, false)
+		}
+	case testSetPDU, notifyPDU, indexAllocatePDU, indexDeallocatePDU:
+		withContext()
+		for _, v := range p.varbinds {
+			if err := e.varbind(v); err != nil {
+				return nil, err
+			}
+		}
+	case commitSetPDU, undoSetPDU, cleanupSetPDU:
+	case pingPDU:
+		withContext()
+	case addAgentCapsPDU, removeAgentCapsPDU:
+		withContext()
+		e.oid(p.id, false)
+		if p.typ == addAgentCapsPDU {
+			e.octets([]byte(p.descr))
+		}
+	case responsePDU:
+		e.uint32(p.sysUpTime)
+		e.uint16(uint16(p.err))
+		e.uint16(p.index)
+		for _, v := range p.varbinds {
+			if err := e.varbind(v); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, fmt.Errorf("Unable to marshal AgentX %s", p.typ)
+	}
+
+	b := e.buf
+	b[0], b[1], b[2], b[3] = protocolVersion, byte(p.typ), p.flags, 0
+	e.order.PutUint32(b[4:], p.sessionID)
+	e.order.PutUint32(b[8:], p.transactionID)
+	e.order.PutUint32(b[12:], p.packetID)
+	e.order.PutUint32(b[16:], uint32(len(b)-headerSize))
+	return b, nil
+}
+
+// readPDU reads and decodes a PDU from r. A PDU of an unknown type is
+// returned with the error, to be answered with a parseError.
+func readPDU(r io.Reader) (*pdu, error) {
+	h := make([]byte, headerSize)
+	if _, err := io.ReadFull(r, h); err != nil {
+		return nil, err
+	}
+	if h[0] != protocolVersion {
+		return nil, fmt.Errorf("Unsupported AgentX version %d", h[0])
+	}
+	p := &pdu{typ: pduType(h[1]), flags: h[2]}
+	order := p.order()
+	p.sessionID = order.Uint32(h[4:])
+	p.transactionID = order.Uint32(h[8:])
+	p.packetID = order.Uint32(h[12:])
+	length := order.Uint32(h[16:])
+	if length%4 != 0 || length > maxPayloadSize {
+		return nil, fmt.Errorf("Invalid AgentX payload length %d", length)
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+	if err := p.unmarshal(payload); err != nil {
+		return p, err
+	}
+	return p, nil
+}
+
+// unmarshal decodes the payload of p, whose header has been decoded
+func (p *pdu) unmarshal(payload []byte) error {
+	d := &decoder{buf: payload, order: p.order()}
+	withContext := func() {
+		if p.flags&flagNonDefaultContext != 0 {
+			p.context = string(d.octets())
+		}
+	}
+
+	switch p.typ {
+	case openPDU:
+		p.timeout = d.bytes(4)[0]
+		p.id, _ = d.oid()
+		p.descr = string(d.octets())
+	case closePDU:
+		p.reason = CloseReason(d.bytes(4)[0])
+	case registerPDU, unregisterPDU:
+		withContext()
+		b := d.bytes(4)
+		p.timeout, p.priority, p.rangeSubid = b[0], b[1], b[2]
+		p.subtree, _ = d.oid()
+		if p.rangeSubid != 0 {
+			p.upperBound = d.uint32()
+		}
+	case getPDU, getNextPDU, getBulkPDU:
+		withContext()
+		if p.typ == getBulkPDU {
+			p.nonRepeaters = d.uint16()
+			p.maxRepetitions = d.uint16()
+		}
+		for d.err == nil && len(d.buf) > 0 {
+			var r searchRange
+			r.start, r.include = d.oid()
+			r.end, _ = d.oid()
+			p.ranges = append(p.ranges, r)
+		}
+	case testSetPDU, notifyPDU, indexAllocatePDU, indexDeallocatePDU:
+		withContext()
+		p.varbinds = d.varbinds()
+	case commitSetPDU, undoSetPDU, cleanupSetPDU:
+	case pingPDU:
+		withContext()
+	case addAgentCapsPDU, removeAgentCapsPDU:
+		withContext()
+		p.id, _ = d.oid()
+		if p.typ == addAgentCapsPDU {
+			p.descr = string(d.octets())
+		}
+	case responsePDU:
+		p.sysUpTime = d.uint32()
+		p.err = Error(d.uint16())
+		p.index = d.uint16()
+		p.varbinds = d.varbinds()
+	default:
+		return fmt.Errorf("Unknown AgentX PDU type %d", p.typ)
+	}
+	if d.err != nil {
+		return fmt.Errorf("Unable to unmarshal AgentX %s: %w", p.typ, d.err)
+	}
+	return nil
+}
+
+// encoder appends the fields of a PDU to buf
+type encoder struct {
+	buf   []byte
+	order binary.ByteOrder
+}
+
+func (e *encoder) uint16(v uint16) {
+	e.buf = append(e.buf, 0, 0)
+	e.order.PutUint16(e.buf[len(e.buf)-2:], v)
+}
+
+func (e *encoder) uint32(v uint32) {
+	e.buf = append(e.buf, 0, 0, 0, 0)
+	e.order.PutUint32(e.buf[len(e.buf)-4:], v)
+}
+
+// octets appends an Octet String, padded to a multiple of 4 bytes
+func (e *encoder) octets(b []byte) {
+	e.uint32(uint32(len(b)))
+	e.buf = append(e.buf, b...)
+	for len(e.buf)%4 != 0 {
+		e.buf = append(e.buf, 0)
+	}
+}
+
+// oid appends an Object Identifier, whose .1.3.6.1.n prefix is shortened
+// to n when it can be
+func (e *encoder) oid(o gosnmp.Oid, include bool) {
+	var prefix uint8
+	if len(o) > 4 && o[0] == 1 && o[1] == 3 && o[2] == 6 && o[3] == 1 && o[4] > 0 && o[4] < 256 {
+		prefix, o = uint8(o[4]), o[5:]
+	}
+	var inc uint8
+	if include {
+		inc = 1
+	}
+	e.buf = append(e.buf, uint8(len(o)), prefix, inc, 0)
+	for _, n := range o {
+		e.uint32(n)
+	}
+}
+
+// varbind appends a VarBind
+func (e *encoder) varbind(v gosnmp.SnmpPDU) error {
+	name, err := gosnmp.ParseOid(v.Name)
+	if err != nil {
+		return err
+	}
+	typ := v.Type
+	if typ == gosnmp.Uinteger32 {
+		typ = gosnmp.Gauge32
+	}
+	e.uint16(uint16(typ))
+	e.uint16(0)
+	e.oid(name, false)
+
+	switch typ {
+	case gosnmp.Integer:
+		n, err := v.Int64()
+		if err != nil {
+			return err
+		}
+		if n < math.MinInt32 || n > math.MaxInt32 {
+			return fmt.Errorf("%s: value %d out of range for Integer", v.Name, n)
+		}
+		e.uint32(uint32(int32(n)))
+	case gosnmp.Counter32, gosnmp.Gauge32, gosnmp.TimeTicks:
+		n, err := v.Uint64()
+		if err != nil {
+			return err
+		}
+		if n > math.MaxUint32 {
+			return fmt.Errorf("%s: value %d out of range for %s", v.Name, n, v.Type)
+		}
+		e.uint32(uint32(n))
+	case gosnmp.Counter64:
+		n, err := v.Uint64()
+		if err != nil {
+			return err
+		}
+		e.uint32(uint32(n >> 32))
+		e.uint32(uint32(n))
+	case gosnmp.OctetString, gosnmp.Opaque:
+		switch value := v.Value.(type) {
+		case []byte:
+			e.octets(value)
+		case string:
+			e.octets([]byte(value))
+		default:
+			return fmt.Errorf("%s: Unable to marshal %s; not []byte or string", v.Name, v.Type)
+		}
+	case gosnmp.IPAddress:
+		var ip net.IP
+		switch value := v.Value.(type) {
+		case []byte:
+			ip = value
+		case string:
+			ip = net.ParseIP(value)
+		}
+		if ip = ip.To4(); ip == nil {
+			return fmt.Errorf("%s: Unable to marshal IPAddress %v", v.Name, v.Value)
+		}
+		e.octets(ip)
+	case gosnmp.ObjectIdentifier:
+		o, err := v.OidValue()
+		if err != nil {
+			return err
+		}
+		e.oid(o, false)
+	case gosnmp.Null, gosnmp.NoSuchObject, gosnmp.NoSuchInstance, gosnmp.EndOfMibView:
+	default:
+		return fmt.Errorf("%s: Unable to marshal %s for AgentX", v.Name, v.Type)
+	}
+	return nil
+}
+
+// decoder takes the fields of a PDU from buf, setting err if it runs out
+type decoder struct {
+	buf   []byte
+	order binary.ByteOrder
+	err   error
+}
+
+// bytes returns the next n bytes, or zeros if there aren't n
+func (d *decoder) bytes(n int) []byte {
+	if d.err != nil || n > len(d.buf) {
+		if d.err == nil {
+			d.err = io.ErrUnexpectedEOF
+		}
+		return make([]byte, n)
+	}
+	b := d.buf[:n]
+	d.buf = d.buf[n:]
+	return b
+}
+
+func (d *decoder) uint16() uint16 {
+	return d.order.Uint16(d.bytes(2))
+}
+
+func (d *decoder) uint32() uint32 {
+	return d.order.Uint32(d.bytes(4))
+}
+
+func (d *decoder) octets() []byte {
+	n := d.uint32()
+	if n > uint32(len(d.buf)) {
+		d.bytes(len(d.buf) + 1)
+		return nil
+	}
+	b := d.bytes(int(n))
+	d.bytes((4 - int(n)%4) % 4)
+	return b
+}
+
+// oid returns an Object Identifier, and its include field
+func (d *decoder) oid() (gosnmp.Oid, bool) {
+	b := d.bytes(4)
+	n, prefix, include := int(b[0]), b[1], b[2] != 0
+	var o gosnmp.Oid
+	if prefix != 0 {
+		o = gosnmp.Oid{1, 3, 6, 1, uint32(prefix)}
+	}
+	for i := 0; i < n && d.err == nil; i++ {
+		o = append(o, d.uint32())
+	}
+	return o, include
+}
+
+// varbinds returns the VarBinds of the rest of the payload
+func (d *decoder) varbinds() []gosnmp.SnmpPDU {
+	var varbinds []gosnmp.SnmpPDU
+	for d.err == nil && len(d.buf) > 0 {
+		typ := gosnmp.Asn1BER(d.uint16())
+		d.uint16()
+		name, _ := d.oid()
+		v := gosnmp.SnmpPDU{Name: name.String(), Type: typ}
+
+		// the values as gosnmp decodes them from SNMP messages
+		switch typ {
+		case gosnmp.Integer:
+			v.Value = int(int32(d.uint32()))
+		case gosnmp.Counter32, gosnmp.Gauge32:
+			v.Value = uint(d.uint32())
+		case gosnmp.TimeTicks:
+			v.Value = int(d.uint32())
+		case gosnmp.Counter64:
+			v.Value = uint64(d.uint32())<<32 | uint64(d.uint32())
+		case gosnmp.OctetString, gosnmp.Opaque:
+			v.Value = append([]byte(nil), d.octets()...)
+		case gosnmp.IPAddress:
+			b := d.octets()
+			if len(b) != 4 {
+				d.err = fmt.Errorf("%s: IpAddress of %d bytes", v.Name, len(b))
+				break
+			}
+			v.Value = net.IP(b).String()
+		case gosnmp.ObjectIdentifier:
+			o, _ := d.oid()
+			v.Value = o.String()
+		case gosnmp.Null, gosnmp.NoSuchObject, gosnmp.NoSuchInstance, gosnmp.EndOfMibView:
+		default:
+			d.err = fmt.Errorf("%s: Unknown VarBind type %d", v.Name, typ)
+		}
+		varbinds = append(varbinds, v)
+	}
+	return varbinds
+}
@@ -0,0 +1,159 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package agentx
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/soniah/gosnmp"
+)
+
+func mustOid(t *testing.T, s string) gosnmp.Oid {
+	t.Helper()
+	o, err := gosnmp.ParseOid(s)
+	if err != nil {
+		t.Fatalf("ParseOid(%s) err: %v", s, err)
+	}
+	return o
+}
+
+func TestPDUMarshal(t *testing.T) {
+	p := &pdu{typ: openPDU, flags: flagNetworkByteOrder, sessionID: 1, transactionID: 2, packetID: 3,
+		timeout: 5, id: mustOid(t, ".1.3.6.1.4.1.99999"), descr: "go"}
+	want := []byte{
+		1, 1, 0x10, 0, 0, 0, 0, 1, 0, 0, 0, 2, 0, 0, 0, 3, 0, 0, 0, 24,
+		5, 0, 0, 0, // timeout
+		2, 4, 0, 0, 0, 0, 0, 1, 0, 1, 0x86, 0x9f, // .1.3.6.1.4.1.99999, with the prefix 4
+		0, 0, 0, 2, 'g', 'o', 0, 0,
+	}
+	b, err := p.marshal()
+	if err != nil {
+		t.Fatalf("marshal() err: %v", err)
+	}
+	if !bytes.Equal(b, want) {
+		t.Errorf("marshal() got % x, expected % x", b, want)
+	}
+
+	// the same, in the subagent's byte order
+	p.flags = 0
+	b, err = p.marshal()
+	if err != nil {
+		t.Fatalf("marshal() err: %v", err)
+	}
+	if want := []byte{1, 1, 0, 0, 1, 0, 0, 0}; !bytes.Equal(b[:8], want) {
+		t.Errorf("marshal() little endian got % x, expected % x", b[:8], want)
+	}
+	if want := []byte{1, 0, 0, 0, 0x9f, 0x86, 1, 0}; !bytes.Equal(b[28:36], want) {
+		t.Errorf("marshal() little endian oid got % x, expected % x", b[28:36], want)
+	}
+}
+
+func TestPDURoundTrip(t *testing.T) {
+	varbinds := []gosnmp.SnmpPDU{
+		{Name: ".1.3.6.1.2.1.1.1.0", Type: gosnmp.OctetString, Value: []byte("descr")},
+		{Name: ".1.3.6.1.2.1.1.2.0", Type: gosnmp.ObjectIdentifier, Value: ".1.3.6.1.4.1.99999"},
+		{Name: ".1.3.6.1.2.1.1.3.0", Type: gosnmp.TimeTicks, Value: 12345},
+		{Name: ".1.3.6.1.2.1.2.2.1.7.1", Type: gosnmp.Integer, Value: -2},
+		{Name: ".1.3.6.1.2.1.2.2.1.10.1", Type: gosnmp.Counter32, Value: uint(4000000000)},
+		{Name: ".1.3.6.1.2.1.2.2.1.5.1", Type: gosnmp.Gauge32, Value: uint(100000000)},
+		{Name: ".1.3.6.1.2.1.31.1.1.1.6.1", Type: gosnmp.Counter64, Value: uint64(1<<40 + 7)},
+		{Name: ".1.3.6.1.2.1.4.20.1.1.10.0.0.1", Type: gosnmp.IPAddress, Value: "10.0.0.1"},
+		{Name: ".1.3.6.1.4.1.99999.1", Type: gosnmp.Opaque, Value: []byte{0x9f, 0x78, 4, 0, 0, 0, 0}},
+		{Name: ".1.3.6.1.4.1.99999.2", Type: gosnmp.Null},
+		{Name: ".1.3.6.1.4.1.99999.3", Type: gosnmp.NoSuchObject},
+		{Name: ".1.3.6.1.4.1.99999.4", Type: gosnmp.NoSuchInstance},
+		{Name: ".1.3.6.1.4.1.99999.5", Type: gosnmp.EndOfMibView},
+		{Name: ".2.1", Type: gosnmp.Integer, Value: 1},
+	}
+	ranges := []searchRange{
+		{start: mustOid(t, ".1.3.6.1.2.1.1"), include: true, end: mustOid(t, ".1.3.6.1.2.1.2")},
+		{start: mustOid(t, ".1.3.6.1.2.1.2.2.1.1")},
+	}
+	tests := []*pdu{
+		{typ: openPDU, timeout: 10, id: mustOid(t, ".1.3.6.1.4.1.99999"), descr: "a subagent"},
+		{typ: closePDU, reason: ReasonShutdown},
+		{typ: registerPDU, flags: flagInstanceRegistration, timeout: 1, priority: 127,
+			subtree: mustOid(t, ".1.3.6.1.2.1.1.5.0")},
+		{typ: registerPDU, priority: 1, rangeSubid: 10, subtree: mustOid(t, ".1.3.6.1.2.1.2.2.1.1.1"), upperBound: 24},
+		{typ: unregisterPDU, priority: 127, subtree: mustOid(t, ".1.3.6.1.2.1.2"), context: "vrf"},
+		{typ: getPDU, ranges: ranges},
+		{typ: getNextPDU, ranges: ranges, context: "vrf"},
+		{typ: getBulkPDU, nonRepeaters: 1, maxRepetitions: 10, ranges: ranges},
+		{typ: testSetPDU, varbinds: varbinds},
+		{typ: commitSetPDU},
+		{typ: undoSetPDU},
+		{typ: cleanupSetPDU},
+		{typ: notifyPDU, varbinds: varbinds[:3]},
+		{typ: indexAllocatePDU, flags: flagAnyIndex, varbinds: varbinds[3:4]},
+		{typ: pingPDU},
+		{typ: addAgentCapsPDU, id: mustOid(t, ".1.3.6.1.4.1.99999.2"), descr: "caps"},
+		{typ: removeAgentCapsPDU, id: mustOid(t, ".1.3.6.1.4.1.99999.2")},
+		{typ: responsePDU, sysUpTime: 100, err: DuplicateRegistration, index: 2},
+		{typ: responsePDU, sysUpTime: 100, varbinds: varbinds},
+	}
+	for _, flags := range []uint8{0, flagNetworkByteOrder} {
+		for i, test := range tests {
+			test.flags |= flags
+			test.sessionID, test.transactionID, test.packetID = 7, uint32(i), 1000+uint32(i)
+			b, err := test.marshal()
+			if err != nil {
+				t.Errorf("%s: marshal() err: %v", test.typ, err)
+				continue
+			}
+			got, err := readPDU(bytes.NewReader(b))
+			if err != nil {
+				t.Errorf("%s: readPDU() err: %v", test.typ, err)
+				continue
+			}
+			if !reflect.DeepEqual(got, test) {
+				t.Errorf("%s: readPDU() got\n%+v, expected\n%+v", test.typ, got, test)
+			}
+		}
+	}
+}
+
+func TestPDUErrors(t *testing.T) {
+	p := &pdu{typ: getPDU, flags: flagNetworkByteOrder, ranges: []searchRange{{start: mustOid(t, ".1.3.6.1")}}}
+	b, err := p.marshal()
+	if err != nil {
+		t.Fatalf("marshal() err: %v", err)
+	}
+
+	short := append([]byte(nil), b...)
+	short[19] -= 4
+	tests := []struct {
+		name string
+		b    []byte
+	}{
+		{"version", append([]byte{2}, b[1:]...)},
+		{"truncated", b[:len(b)-4]},
+		{"short payload", short[:len(b)-4]},
+		{"unknown type", append([]byte{1, 99}, b[2:]...)},
+	}
+	for _, test := range tests {
+		if _, err := readPDU(bytes.NewReader(test.b)); err == nil {
+			t.Errorf("%s: readPDU() succeeded", test.name)
+		}
+	}
+
+	bad := &pdu{typ: responsePDU, varbinds: []gosnmp.SnmpPDU{{Name: ".1.3.6.1", Type: gosnmp.Integer, Value: int64(1) << 40}}}
+	if _, err := bad.marshal(); err == nil {
+		t.Errorf("marshal() of an Integer out of range succeeded")
+	}
+}
+
+func TestErrorString(t *testing.T) {
+	for e, want := range map[Error]string{
+		DuplicateRegistration:     "duplicateRegistration",
+		ProcessingError:           "processingError",
+		Error(gosnmp.NotWritable): "NotWritable",
+	} {
+		if got := e.Error(); got != want {
+			t.Errorf("Error(%d) got %q, expected %q", e, got, want)
+		}
+	}
+}
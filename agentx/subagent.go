@@ -0,0 +1,741 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+// Package agentx implements the AgentX protocol (RFC 2741), by which
+// subagents serve parts of the object tree of a master agent, eg net-snmp's
+// snmpd.
+//
+// A Subagent serves objects with the handlers of a gosnmp.Agent, over a
+// session with the master agent:
+//
+//	s := &agentx.Subagent{ID: ".1.3.6.1.4.1.99999", Description: "myapp"}
+//	s.RegisterSubtree(".1.3.6.1.4.1.99999.1", handler)
+//	if err := s.Connect("unix", "/var/agentx/master"); err != nil {
+//		log.Fatal(err)
+//	}
+//	log.Print(s.Wait()) // when the master agent closes the session
+package agentx
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/soniah/gosnmp"
+)
+
+// defaultPriority is the priority of registrations unless Priority is set,
+// RFC 2741 section 6.2.3
+const defaultPriority = 127
+
+// responseTimeout is how long a Subagent waits for the responses of the
+// master agent
+const responseTimeout = 5 * time.Second
+
+// ErrClosed is returned for the requests of a Subagent whose session has
+// been closed, or was never opened
+var ErrClosed = errors.New("AgentX session closed")
+
+// Subagent is an AgentX subagent, serving the objects registered with it
+// for a master agent. Objects and subtrees are registered with the master
+// agent (in the default context) when the session is opened, or when they
+// are registered if it is open already. Their handlers are those of a
+// gosnmp.Agent: SETs are tested, committed and undone by the master agent
+// with the handlers' SetTester and SetUndoer methods.
+//
+// The master agent's requests are answered one at a time, in the order
+// they arrive. A Subagent is safe for concurrent use; it has one session,
+// and can't be opened again once closed.
+type Subagent struct {
+	// ID is the oid identifying the subagent, eg its sysObjectID, and
+	// Description describes it, for the master agent (default ID: ".0.0")
+	ID          string
+	Description string
+
+	// Timeout is how long the master agent waits for responses from the
+	// subagent, in whole seconds (default: the master agent's, usually 5
+	// seconds)
+	Timeout time.Duration
+
+	// Priority is the priority of the registrations, from 1 the highest
+	// to 255: where registrations of several subagents overlap, the
+	// master agent sends requests to the one with the longest subtree,
+	// then the highest priority (default: 127)
+	Priority uint8
+
+	// Logger is given warnings, eg of handlers failing and of PDUs that
+	// can't be decoded
+	Logger gosnmp.LeveledLogger
+
+	mu        sync.Mutex
+	objects   []registration // in oid order, replaced rather than changed
+	conn      net.Conn
+	opened    bool // once Open has been called
+	sessionID uint32
+	packetID  uint32
+	pending   map[uint32]chan *pdu // the requests waiting for responses
+	start     time.Time            // when the session was opened, for sysUpTime
+	closing   bool
+	err       error         // why the session ended
+	done      chan struct{} // closed when it has
+	writeMu   sync.Mutex
+
+	set *setTransaction // the SET in progress
+}
+
+// registration is an object, or subtree, registered with a Subagent
+type registration struct {
+	oid     gosnmp.Oid
+	name    string
+	handler gosnmp.AgentHandler
+	subtree gosnmp.SubtreeHandler // set for a subtree, as is handler
+}
+
+// setTransaction is a SET, between its TestSet and CleanupSet
+type setTransaction struct {
+	id       uint32
+	varbinds []gosnmp.SnmpPDU
+	handlers []gosnmp.AgentHandler
+	set      int // how many of the varbinds have been set
+}
+
+// Register registers handler to serve the object instance oid, eg
+// ".1.3.6.1.4.1.99999.1.0", as gosnmp.Agent.Register does. If the session
+// is open, oid is registered with the master agent, and an error returned
+// if it refuses it, eg with DuplicateRegistration.
+func (s *Subagent) Register(oid string, handler gosnmp.AgentHandler) error {
+	o, err := gosnmp.ParseOid(oid)
+	if err != nil {
+		return err
+	}
+	return s.register(registration{oid: o, name: o.String(), handler: handler})
+}
+
+// RegisterSubtree registers handler to serve the objects in the subtree
+// at oid, as gosnmp.Agent.RegisterSubtree does, and with the master agent
+// as Register does
+func (s *Subagent) RegisterSubtree(oid string, handler gosnmp.SubtreeHandler) error {
+	o, err := gosnmp.ParseOid(oid)
+	if err != nil {
+		return err
+	}
+	return s.register(registration{oid: o, name: o.String(), handler: handler, subtree: handler})
+}
+
+func (s *Subagent) register(r registration) error {
+	s.mu.Lock()
+	objects := make([]registration, 0, len(s.objects)+1)
+	var old *registration
+	for i := range s.objects {
+		if s.objects[i].oid.Equal(r.oid) {
+			old = &s.objects[i]
+			continue
+		}
+		objects = append(objects, s.objects[i])
+	}
+	objects = append(objects, r)
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].oid.Compare(objects[j].oid) < 0
+	})
+	s.objects = objects
+	open := s.conn != nil && !s.closing
+	s.mu.Unlock()
+
+	// a handler replaced is registered already
+	if !open || old != nil && (old.subtree == nil) == (r.subtree == nil) {
+		return nil
+	}
+	if old != nil {
+		if err := s.sendUnregister(*old); err != nil {
+			s.logWarn("Unregister failed", "oid", old.name, "err", err)
+		}
+	}
+	if err := s.sendRegister(r); err != nil {
+		s.remove(r.oid)
+		return err
+	}
+	return nil
+}
+
+// Unregister unregisters the object or subtree registered at oid, and if
+// the session is open, unregisters it with the master agent
+func (s *Subagent) Unregister(oid string) error {
+	o, err := gosnmp.ParseOid(oid)
+	if err != nil {
+		return err
+	}
+	r := s.remove(o)
+	s.mu.Lock()
+	open := s.conn != nil && !s.closing
+	s.mu.Unlock()
+	if r == nil || !open {
+		return nil
+	}
+	return s.sendUnregister(*r)
+}
+
+// remove removes the registration at oid, returning it
+func (s *Subagent) remove(oid gosnmp.Oid) *registration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.objects {
+		if s.objects[i].oid.Equal(oid) {
+			r := s.objects[i]
+			s.objects = append(append([]registration(nil), s.objects[:i]...), s.objects[i+1:]...)
+			return &r
+		}
+	}
+	return nil
+}
+
+// lookup returns the registrations, which mustn't be changed
+func (s *Subagent) lookup() []registration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.objects
+}
+
+// Connect connects to the master agent at addr over network, eg "unix"
+// and net-snmp's "/var/agentx/master", or "tcp" and "localhost:705", and
+// opens the session, as Open does
+func (s *Subagent) Connect(network, addr string) error {
+	conn, err := net.DialTimeout(network, addr, responseTimeout)
+	if err != nil {
+		return err
+	}
+	if err = s.Open(conn); err != nil {
+		conn.Close()
+		return err
+	}
+	return nil
+}
+
+// Open opens a session with the master agent over conn, and registers the
+// objects and subtrees registered so far. The master agent's requests are
+// then answered until Close, or until the session is closed by the master
+// agent or fails, see Wait.
+func (s *Subagent) Open(conn net.Conn) error {
+	id := s.ID
+	if id == "" {
+		id = ".0.0"
+	}
+	oid, err := gosnmp.ParseOid(id)
+	if err != nil {
+		return fmt.Errorf("Invalid subagent ID %q: %w", id, err)
+	}
+
+	s.mu.Lock()
+	if s.opened {
+		s.mu.Unlock()
+		return errors.New("AgentX session opened already")
+	}
+	s.opened = true
+	s.conn = conn
+	s.pending = make(map[uint32]chan *pdu)
+	s.done = make(chan struct{})
+	s.mu.Unlock()
+	go s.serve()
+
+	timeout := s.Timeout / time.Second
+	if timeout > 255 {
+		timeout = 255
+	}
+	response, err := s.request(&pdu{typ: openPDU, timeout: uint8(timeout), id: oid, descr: s.Description})
+	if err != nil {
+		s.end(fmt.Errorf("AgentX Open failed: %w", err))
+		return fmt.Errorf("AgentX Open failed: %w", err)
+	}
+	s.mu.Lock()
+	s.sessionID = response.sessionID
+	s.start = time.Now()
+	s.mu.Unlock()
+
+	for _, r := range s.lookup() {
+		if err = s.sendRegister(r); err != nil {
+			s.Close()
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes the session, telling the master agent it is shutting down,
+// and the connection
+func (s *Subagent) Close() error {
+	s.mu.Lock()
+	if s.conn == nil || s.closing {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closing = true
+	s.mu.Unlock()
+
+	_, err := s.request(&pdu{typ: closePDU, reason: ReasonShutdown})
+	s.end(nil)
+	if errors.Is(err, ErrClosed) {
+		return nil
+	}
+	return err
+}
+
+// Wait waits for the session to end, returning why: nil after Close, or
+// the error that ended it, eg its being closed by the master agent
+func (s *Subagent) Wait() error {
+	s.mu.Lock()
+	done := s.done
+	s.mu.Unlock()
+	if done == nil {
+		return ErrClosed
+	}
+	<-done
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// end ends the session with err, closing the connection
+func (s *Subagent) end(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	select {
+	case <-s.done:
+		return
+	default:
+	}
+	if !s.closing {
+		s.err = err
+	}
+	s.closing = true
+	s.conn.Close()
+	close(s.done)
+}
+
+// serve reads the PDUs of the master agent, answering its requests and
+// passing on its responses, until the session ends
+func (s *Subagent) serve() {
+	for {
+		p, err := readPDU(s.conn)
+		if p == nil {
+			if errors.Is(err, io.EOF) {
+				err = errors.New("AgentX connection closed by the master agent")
+			}
+			s.end(err)
+			return
+		}
+		if err != nil {
+			s.logWarn("Unable to decode AgentX PDU", "err", err)
+			if p.typ != responsePDU {
+				response := p.response()
+				response.err = ParseError
+				s.send(response)
+			}
+			continue
+		}
+
+		switch p.typ {
+		case responsePDU:
+			s.mu.Lock()
+			c := s.pending[p.packetID]
+			delete(s.pending, p.packetID)
+			s.mu.Unlock()
+			if c != nil {
+				c <- p
+			}
+		case closePDU:
+			s.send(p.response())
+			s.end(fmt.Errorf("AgentX session closed by the master agent: %s", p.reason))
+			return
+		case cleanupSetPDU:
+			s.set = nil
+		default:
+			s.send(s.handle(p))
+		}
+	}
+}
+
+// handle returns the Response to a request of the master agent
+func (s *Subagent) handle(p *pdu) *pdu {
+	response := p.response()
+	s.mu.Lock()
+	response.sysUpTime = gosnmp.DurationToTimeTicks(time.Since(s.start))
+	s.mu.Unlock()
+	objects := s.lookup()
+
+	var err error
+	switch p.typ {
+	case getPDU:
+		for i, r := range p.ranges {
+			var v gosnmp.SnmpPDU
+			if v, err = s.get(objects, r.start); err != nil {
+				response.err, response.index = Error(gosnmp.GenErr), uint16(i+1)
+				break
+			}
+			response.varbinds = append(response.varbinds, v)
+		}
+	case getNextPDU:
+		for i, r := range p.ranges {
+			var v gosnmp.SnmpPDU
+			if v, err = s.next(objects, r); err != nil {
+				response.err, response.index = Error(gosnmp.GenErr), uint16(i+1)
+				break
+			}
+			response.varbinds = append(response.varbinds, v)
+		}
+	case getBulkPDU:
+		response.varbinds, err = s.getBulk(objects, p)
+		if err != nil {
+			response.err = Error(gosnmp.GenErr)
+		}
+	case testSetPDU:
+		response.err, response.index = s.testSet(objects, p)
+	case commitSetPDU:
+		response.err, response.index = s.commitSet(p)
+	case undoSetPDU:
+		response.err, response.index = s.undoSet(p)
+	default:
+		s.logWarn("Unexpected AgentX PDU", "type", p.typ)
+		response.err = ProcessingError
+	}
+	if response.err != 0 {
+		response.varbinds = nil
+	}
+	return response
+}
+
+// get returns the value of the object instance oid
+func (s *Subagent) get(objects []registration, oid gosnmp.Oid) (gosnmp.SnmpPDU, error) {
+	r := owner(objects, oid, true)
+	if r == nil {
+		return gosnmp.SnmpPDU{Name: oid.String(), Type: gosnmp.NoSuchObject}, nil
+	}
+	v, _, err := s.getValue(r, oid)
+	return v, err
+}
+
+// next returns the varbind of the first object instance in the search
+// range that has a value, or an endOfMibView. Each registration is asked
+// for the instances in the part of the tree it owns, between the
+// registrations after it, as gosnmp.Agent does for GETNEXTs.
+func (s *Subagent) next(objects []registration, sr searchRange) (gosnmp.SnmpPDU, error) {
+	inRange := func(oid gosnmp.Oid) bool {
+		return len(sr.end) == 0 || oid.Compare(sr.end) < 0
+	}
+	cur, inclusive := sr.start, sr.include
+	for inRange(cur) {
+		if inclusive {
+			// cur is the start of the range, of a registration or the end
+			// of one
+			if r := owner(objects, cur, true); r != nil {
+				v, ok, err := s.getValue(r, cur)
+				if err != nil || ok {
+					return v, err
+				}
+			}
+		}
+
+		// the owner of the oids just after cur, and where it stops owning
+		// them: at the next registration, or the end of its subtree
+		r := owner(objects, cur, false)
+		var boundary gosnmp.Oid
+		i := sort.Search(len(objects), func(i int) bool {
+			return objects[i].oid.Compare(cur) > 0
+		})
+		if i < len(objects) {
+			boundary = objects[i].oid
+		}
+		if r != nil {
+			end := r.oid.NextSibling()
+			if len(end) > 0 && (boundary == nil || end.Compare(boundary) < 0) {
+				boundary = end
+			}
+
+			v, err := r.subtree.GetNext(cur.String())
+			if err != nil {
+				s.logWarn("GetNext failed", "oid", cur, "err", err)
+				return gosnmp.SnmpPDU{}, err
+			}
+			if isValue(v) {
+				next, err := gosnmp.ParseOid(v.Name)
+				switch {
+				case err != nil || next.Compare(cur) <= 0 || !next.HasPrefix(r.oid):
+					s.logWarn("GetNext returned an oid out of order",
+						"subtree", r.name, "oid", cur, "next", v.Name)
+				case boundary == nil || next.Compare(boundary) < 0:
+					if !inRange(next) {
+						return endOfMibView(sr), nil
+					}
+					v.Name = next.String()
+					return v, nil
+				}
+			}
+		}
+		if boundary == nil {
+			break
+		}
+		cur, inclusive = boundary, true
+	}
+	return endOfMibView(sr), nil
+}
+
+// getBulk returns the varbinds of a GetBulk, as RFC 3416 section 4.2.3
+// has them but for the search ranges
+func (s *Subagent) getBulk(objects []registration, p *pdu) ([]gosnmp.SnmpPDU, error) {
+	var varbinds []gosnmp.SnmpPDU
+	nonRepeaters := int(p.nonRepeaters)
+	if nonRepeaters > len(p.ranges) {
+		nonRepeaters = len(p.ranges)
+	}
+	for _, r := range p.ranges[:nonRepeaters] {
+		v, err := s.next(objects, r)
+		if err != nil {
+			return nil, err
+		}
+		varbinds = append(varbinds, v)
+	}
+
+	repeaters := append([]searchRange(nil), p.ranges[nonRepeaters:]...)
+	for n := 0; n < int(p.maxRepetitions) && len(repeaters) > 0; n++ {
+		ended := 0
+		for i, r := range repeaters {
+			v, err := s.next(objects, r)
+			if err != nil {
+				return nil, err
+			}
+			varbinds = append(varbinds, v)
+			if v.Type == gosnmp.EndOfMibView {
+				ended++
+				continue
+			}
+			repeaters[i].start, _ = gosnmp.ParseOid(v.Name)
+			repeaters[i].include = false
+		}
+		if ended == len(repeaters) {
+			break
+		}
+	}
+	return varbinds, nil
+}
+
+// testSet starts a SET, testing its varbinds
+func (s *Subagent) testSet(objects []registration, p *pdu) (Error, uint16) {
+	t := &setTransaction{id: p.transactionID}
+	s.set = t
+	for i, v := range p.varbinds {
+		oid, err := gosnmp.ParseOid(v.Name)
+		if err != nil {
+			return Error(gosnmp.GenErr), uint16(i + 1)
+		}
+		r := owner(objects, oid, true)
+		if r == nil {
+			return Error(gosnmp.NoCreation), uint16(i + 1)
+		}
+		t.varbinds = append(t.varbinds, v)
+		t.handlers = append(t.handlers, r.handler)
+		if tester, ok := r.handler.(gosnmp.SetTester); ok {
+			if err = tester.Test(v); err != nil {
+				s.logWarn("Set test failed", "oid", v.Name, "err", err)
+				return Error(errorStatus(err)), uint16(i + 1)
+			}
+		}
+	}
+	return 0, 0
+}
+
+// commitSet sets the values of the SET tested
+func (s *Subagent) commitSet(p *pdu) (Error, uint16) {
+	t := s.set
+	if t == nil || t.id != p.transactionID {
+		return Error(gosnmp.CommitFailed), 0
+	}
+	for i, v := range t.varbinds {
+		if err := t.handlers[i].Set(v); err != nil {
+			s.logWarn("Set failed", "oid", v.Name, "err", err)
+			return Error(gosnmp.CommitFailed), uint16(i + 1)
+		}
+		t.set = i + 1
+	}
+	return 0, 0
+}
+
+// undoSet undoes the Sets made by commitSet, in reverse order
+func (s *Subagent) undoSet(p *pdu) (Error, uint16) {
+	t := s.set
+	if t == nil || t.id != p.transactionID {
+		return Error(gosnmp.UndoFailed), 0
+	}
+	var status Error
+	var index uint16
+	for i := t.set - 1; i >= 0; i-- {
+		undoer, ok := t.handlers[i].(gosnmp.SetUndoer)
+		if !ok {
+			s.logWarn("Set can't be undone", "oid", t.varbinds[i].Name)
+			status, index = Error(gosnmp.UndoFailed), uint16(i+1)
+			continue
+		}
+		if err := undoer.Undo(t.varbinds[i]); err != nil {
+			s.logWarn("Undo failed", "oid", t.varbinds[i].Name, "err", err)
+			status, index = Error(gosnmp.UndoFailed), uint16(i+1)
+		}
+	}
+	t.set = 0
+	return status, index
+}
+
+// getValue returns the value of the instance oid served by r, and whether
+// it has one
+func (s *Subagent) getValue(r *registration, oid gosnmp.Oid) (gosnmp.SnmpPDU, bool, error) {
+	name := oid.String()
+	v, err := r.handler.Get(name)
+	if err != nil {
+		s.logWarn("Get failed", "oid", name, "err", err)
+		return gosnmp.SnmpPDU{}, false, err
+	}
+	v.Name = name
+	return v, isValue(v), nil
+}
+
+// sendRegister registers r with the master agent
+func (s *Subagent) sendRegister(r registration) error {
+	p := &pdu{typ: registerPDU, priority: s.priority(), subtree: r.oid}
+	if r.subtree == nil {
+		p.flags |= flagInstanceRegistration
+	}
+	if _, err := s.request(p); err != nil {
+		return fmt.Errorf("AgentX Register of %s failed: %w", r.name, err)
+	}
+	return nil
+}
+
+// sendUnregister unregisters r with the master agent
+func (s *Subagent) sendUnregister(r registration) error {
+	if _, err := s.request(&pdu{typ: unregisterPDU, priority: s.priority(), subtree: r.oid}); err != nil {
+		return fmt.Errorf("AgentX Unregister of %s failed: %w", r.name, err)
+	}
+	return nil
+}
+
+func (s *Subagent) priority() uint8 {
+	if s.Priority == 0 {
+		return defaultPriority
+	}
+	return s.Priority
+}
+
+// request sends p to the master agent, returning its response; and its
+// error, if it has one
+func (s *Subagent) request(p *pdu) (*pdu, error) {
+	c := make(chan *pdu, 1)
+	s.mu.Lock()
+	select {
+	case <-s.done:
+		s.mu.Unlock()
+		return nil, ErrClosed
+	default:
+	}
+	s.packetID++
+	p.sessionID, p.packetID = s.sessionID, s.packetID
+	p.flags |= flagNetworkByteOrder
+	s.pending[p.packetID] = c
+	done := s.done
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.pending, p.packetID)
+		s.mu.Unlock()
+	}()
+
+	if err := s.send(p); err != nil {
+		return nil, err
+	}
+	timer := time.NewTimer(responseTimeout)
+	defer timer.Stop()
+	select {
+	case response := <-c:
+		if response.err != 0 {
+			return response, response.err
+		}
+		return response, nil
+	case <-done:
+		return nil, ErrClosed
+	case <-timer.C:
+		return nil, fmt.Errorf("No AgentX response to %s in %s", p.typ, responseTimeout)
+	}
+}
+
+// send writes p to the master agent
+func (s *Subagent) send(p *pdu) error {
+	b, err := p.marshal()
+	if err != nil {
+		s.logWarn("Unable to marshal AgentX PDU", "type", p.typ, "err", err)
+		if p.typ != responsePDU {
+			return err
+		}
+		// the response is a genErr instead
+		p.err, p.index, p.varbinds = Error(gosnmp.GenErr), 0, nil
+		if b, err = p.marshal(); err != nil {
+			return err
+		}
+	}
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	s.conn.SetWriteDeadline(time.Now().Add(responseTimeout))
+	_, err = s.conn.Write(b)
+	return err
+}
+
+func (s *Subagent) logWarn(msg string, args ...interface{}) {
+	if s.Logger != nil {
+		s.Logger.Warn(msg, args...)
+	}
+}
+
+// owner returns the registration serving oid: the subtree with the longest
+// prefix of it, or with objects, the object registered at oid
+func owner(objects []registration, oid gosnmp.Oid, withObjects bool) *registration {
+	for n := len(oid); n > 0; n-- {
+		prefix := oid[:n]
+		i := sort.Search(len(objects), func(i int) bool {
+			return objects[i].oid.Compare(prefix) >= 0
+		})
+		if i == len(objects) || !objects[i].oid.Equal(prefix) {
+			continue
+		}
+		if objects[i].subtree != nil || withObjects && n == len(oid) {
+			return &objects[i]
+		}
+	}
+	return nil
+}
+
+// isValue reports whether v is a value, rather than an exception
+func isValue(v gosnmp.SnmpPDU) bool {
+	switch v.Type {
+	case gosnmp.NoSuchObject, gosnmp.NoSuchInstance, gosnmp.EndOfMibView:
+		return false
+	}
+	return true
+}
+
+// endOfMibView returns the varbind of a search range with no instances
+func endOfMibView(sr searchRange) gosnmp.SnmpPDU {
+	return gosnmp.SnmpPDU{Name: sr.start.String(), Type: gosnmp.EndOfMibView}
+}
+
+// errorStatus returns the error-status of a handler's error: an
+// SNMPError, or one in a *gosnmp.StatusError, or genErr
+func errorStatus(err error) gosnmp.SNMPError {
+	var status gosnmp.SNMPError
+	var statusErr *gosnmp.StatusError
+	switch {
+	case errors.As(err, &statusErr):
+		return statusErr.Status
+	case errors.As(err, &status):
+		return status
+	}
+	return gosnmp.GenErr
+}
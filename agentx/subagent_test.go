@@ -0,0 +1,368 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package agentx
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/soniah/gosnmp"
+)
+
+// testTree is a SubtreeHandler of integers, with SETs that can fail
+type testTree struct {
+	mu     sync.Mutex
+	values map[string]int
+	old    map[string]int
+	setErr map[string]error
+}
+
+func (h *testTree) Get(oid string) (gosnmp.SnmpPDU, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if v, ok := h.values[oid]; ok {
+		return gosnmp.SnmpPDU{Type: gosnmp.Integer, Value: v}, nil
+	}
+	return gosnmp.SnmpPDU{Type: gosnmp.NoSuchInstance}, nil
+}
+
+func (h *testTree) GetNext(oid string) (gosnmp.SnmpPDU, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var oids []string
+	for o := range h.values {
+		oids = append(oids, o)
+	}
+	sort.Slice(oids, func(i, j int) bool { return gosnmp.CompareOids(oids[i], oids[j]) < 0 })
+	for _, o := range oids {
+		if gosnmp.CompareOids(o, oid) > 0 {
+			return gosnmp.SnmpPDU{Name: o, Type: gosnmp.Integer, Value: h.values[o]}, nil
+		}
+	}
+	return gosnmp.SnmpPDU{Name: oid, Type: gosnmp.EndOfMibView}, nil
+}
+
+func (h *testTree) Test(pdu gosnmp.SnmpPDU) error {
+	if pdu.Type != gosnmp.Integer {
+		return gosnmp.WrongType
+	}
+	return nil
+}
+
+func (h *testTree) Set(pdu gosnmp.SnmpPDU) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err := h.setErr[pdu.Name]; err != nil {
+		return err
+	}
+	h.old[pdu.Name] = h.values[pdu.Name]
+	h.values[pdu.Name] = pdu.Value.(int)
+	return nil
+}
+
+func (h *testTree) Undo(pdu gosnmp.SnmpPDU) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.values[pdu.Name] = h.old[pdu.Name]
+	return nil
+}
+
+func (h *testTree) value(oid string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.values[oid]
+}
+
+// testMaster is the master agent's end of a session
+type testMaster struct {
+	t        *testing.T
+	conn     net.Conn
+	packetID uint32
+}
+
+func (m *testMaster) read() *pdu {
+	m.t.Helper()
+	m.conn.SetReadDeadline(time.Now().Add(time.Second))
+	p, err := readPDU(m.conn)
+	if err != nil {
+		m.t.Fatalf("readPDU() err: %v", err)
+	}
+	return p
+}
+
+func (m *testMaster) write(p *pdu) {
+	m.t.Helper()
+	b, err := p.marshal()
+	if err != nil {
+		m.t.Fatalf("marshal() err: %v", err)
+	}
+	if _, err = m.conn.Write(b); err != nil {
+		m.t.Fatalf("Write() err: %v", err)
+	}
+}
+
+// request sends p to the subagent, returning its response
+func (m *testMaster) request(p *pdu) *pdu {
+	m.t.Helper()
+	m.packetID++
+	p.sessionID, p.packetID = 42, m.packetID
+	m.write(p)
+	response := m.read()
+	if response.typ != responsePDU || response.packetID != p.packetID {
+		m.t.Fatalf("%s got %s %d, expected a response", p.typ, response.typ, response.packetID)
+	}
+	return response
+}
+
+// accept answers the Open and Registers of a subagent, returning the
+// registered subtrees
+func (m *testMaster) accept(registers int) []string {
+	m.t.Helper()
+	open := m.read()
+	if open.typ != openPDU {
+		m.t.Fatalf("got %s, expected Open", open.typ)
+	}
+	response := open.response()
+	response.sessionID = 42
+	m.write(response)
+
+	var registered []string
+	for i := 0; i < registers; i++ {
+		p := m.read()
+		if p.typ != registerPDU || p.sessionID != 42 {
+			m.t.Fatalf("got %s of session %d, expected Register", p.typ, p.sessionID)
+		}
+		registered = append(registered, fmt.Sprintf("%s %d %d", p.subtree, p.flags&flagInstanceRegistration, p.priority))
+		m.write(p.response())
+	}
+	return registered
+}
+
+func startTestSubagent(t *testing.T) (*Subagent, *testTree, *testMaster) {
+	t.Helper()
+	tree := &testTree{
+		values: map[string]int{".1.3.6.1.4.1.99999.1.1.0": 1, ".1.3.6.1.4.1.99999.1.2.0": 2,
+			".1.3.6.1.4.1.99999.1.3.1.0": 31, ".1.3.6.1.4.1.99999.1.4.0": 4},
+		old:    map[string]int{},
+		setErr: map[string]error{},
+	}
+	s := &Subagent{ID: ".1.3.6.1.4.1.99999", Description: "test", Timeout: 3 * time.Second}
+	if err := s.RegisterSubtree(".1.3.6.1.4.1.99999.1", tree); err != nil {
+		t.Fatalf("RegisterSubtree() err: %v", err)
+	}
+	// an object within the subtree, and one after it
+	for oid, v := range map[string]int{".1.3.6.1.4.1.99999.1.3.1.0": 310, ".1.3.6.1.4.1.99999.2.0": 5} {
+		v := v
+		s.Register(oid, gosnmp.AgentHandlerFunc(func(string) (gosnmp.SnmpPDU, error) {
+			return gosnmp.SnmpPDU{Type: gosnmp.Integer, Value: v}, nil
+		}))
+	}
+
+	conn, c := net.Pipe()
+	m := &testMaster{t: t, conn: c}
+	errs := make(chan error, 1)
+	go func() { errs <- s.Open(conn) }()
+	registered := m.accept(3)
+	if err := <-errs; err != nil {
+		t.Fatalf("Open() err: %v", err)
+	}
+	want := "[.1.3.6.1.4.1.99999.1 0 127 .1.3.6.1.4.1.99999.1.3.1.0 1 127 .1.3.6.1.4.1.99999.2.0 1 127]"
+	if got := fmt.Sprint(registered); got != want {
+		t.Errorf("registered %s, expected %s", got, want)
+	}
+	return s, tree, m
+}
+
+// describe formats varbinds as "name=value" for comparisons
+func describe(varbinds []gosnmp.SnmpPDU) string {
+	var s []string
+	for _, v := range varbinds {
+		if v.Type == gosnmp.Integer {
+			s = append(s, fmt.Sprintf("%s=%v", v.Name, v.Value))
+		} else {
+			s = append(s, fmt.Sprintf("%s=%s", v.Name, v.Type))
+		}
+	}
+	return strings.Join(s, " ")
+}
+
+func TestSubagentGet(t *testing.T) {
+	_, _, m := startTestSubagent(t)
+	defer m.conn.Close()
+	oid := func(s string) gosnmp.Oid { return mustOid(t, s) }
+
+	tests := []struct {
+		p    *pdu
+		want string
+	}{
+		{&pdu{typ: getPDU, ranges: []searchRange{
+			{start: oid(".1.3.6.1.4.1.99999.1.1.0")},
+			{start: oid(".1.3.6.1.4.1.99999.1.3.1.0")},
+			{start: oid(".1.3.6.1.4.1.99999.1.9.0")},
+			{start: oid(".1.3.6.1.4.1.99999.3.0")},
+		}}, ".1.3.6.1.4.1.99999.1.1.0=1 .1.3.6.1.4.1.99999.1.3.1.0=310 " +
+			".1.3.6.1.4.1.99999.1.9.0=NoSuchInstance .1.3.6.1.4.1.99999.3.0=NoSuchObject"},
+		{&pdu{typ: getNextPDU, ranges: []searchRange{
+			{start: oid(".1.3.6.1.4.1.99999.1")},
+			{start: oid(".1.3.6.1.4.1.99999.1.1.0"), include: true},
+			{start: oid(".1.3.6.1.4.1.99999.1.2.0")},
+			{start: oid(".1.3.6.1.4.1.99999.1.3.1.0")},
+			{start: oid(".1.3.6.1.4.1.99999.1.4.0")},
+			{start: oid(".1.3.6.1.4.1.99999.2.0")},
+			{start: oid(".1.3.6.1.4.1.99999.1.1.0"), end: oid(".1.3.6.1.4.1.99999.1.2.0")},
+		}}, ".1.3.6.1.4.1.99999.1.1.0=1 .1.3.6.1.4.1.99999.1.1.0=1 .1.3.6.1.4.1.99999.1.3.1.0=310 " +
+			".1.3.6.1.4.1.99999.1.4.0=4 .1.3.6.1.4.1.99999.2.0=5 .1.3.6.1.4.1.99999.2.0=EndOfMibView " +
+			".1.3.6.1.4.1.99999.1.1.0=EndOfMibView"},
+		{&pdu{typ: getBulkPDU, nonRepeaters: 1, maxRepetitions: 3, ranges: []searchRange{
+			{start: oid(".1.3.6.1.4.1.99999.1")},
+			{start: oid(".1.3.6.1.4.1.99999.1.3")},
+			{start: oid(".1.3.6.1.4.1.99999.1.4")},
+		}}, ".1.3.6.1.4.1.99999.1.1.0=1 " +
+			".1.3.6.1.4.1.99999.1.3.1.0=310 .1.3.6.1.4.1.99999.1.4.0=4 " +
+			".1.3.6.1.4.1.99999.1.4.0=4 .1.3.6.1.4.1.99999.2.0=5 " +
+			".1.3.6.1.4.1.99999.2.0=5 .1.3.6.1.4.1.99999.2.0=EndOfMibView"},
+	}
+	for _, test := range tests {
+		response := m.request(test.p)
+		if response.err != 0 {
+			t.Errorf("%s got %s", test.p.typ, response.err)
+			continue
+		}
+		if got := describe(response.varbinds); got != test.want {
+			t.Errorf("%s got\n%s, expected\n%s", test.p.typ, got, test.want)
+		}
+	}
+}
+
+func TestSubagentSet(t *testing.T) {
+	_, tree, m := startTestSubagent(t)
+	defer m.conn.Close()
+	v1, v2 := ".1.3.6.1.4.1.99999.1.1.0", ".1.3.6.1.4.1.99999.1.2.0"
+
+	set := func(transaction uint32, varbinds ...gosnmp.SnmpPDU) (*pdu, *pdu) {
+		test := m.request(&pdu{typ: testSetPDU, transactionID: transaction, varbinds: varbinds})
+		if test.err != 0 {
+			return test, nil
+		}
+		return test, m.request(&pdu{typ: commitSetPDU, transactionID: transaction})
+	}
+	cleanup := func(transaction uint32) {
+		m.packetID++
+		m.write(&pdu{typ: cleanupSetPDU, sessionID: 42, transactionID: transaction, packetID: m.packetID})
+	}
+
+	test, commit := set(1, gosnmp.SnmpPDU{Name: v1, Type: gosnmp.Integer, Value: 10},
+		gosnmp.SnmpPDU{Name: v2, Type: gosnmp.Integer, Value: 20})
+	if test.err != 0 || commit.err != 0 {
+		t.Fatalf("SET got %s and %s", test.err, commit.err)
+	}
+	cleanup(1)
+	if tree.value(v1) != 10 || tree.value(v2) != 20 {
+		t.Errorf("got values %d and %d, expected 10 and 20", tree.value(v1), tree.value(v2))
+	}
+
+	// tests failing
+	tests := []struct {
+		pdu   gosnmp.SnmpPDU
+		err   Error
+		index uint16
+	}{
+		{gosnmp.SnmpPDU{Name: v1, Type: gosnmp.OctetString, Value: "x"}, Error(gosnmp.WrongType), 2},
+		{gosnmp.SnmpPDU{Name: ".1.3.6.1.4.1.99999.2.0", Type: gosnmp.Integer, Value: 1}, Error(gosnmp.NotWritable), 2},
+		{gosnmp.SnmpPDU{Name: ".1.3.6.1.4.1.99999.3.0", Type: gosnmp.Integer, Value: 1}, Error(gosnmp.NoCreation), 2},
+	}
+	for i, tt := range tests {
+		test, _ := set(uint32(i+2), gosnmp.SnmpPDU{Name: v2, Type: gosnmp.Integer, Value: 0}, tt.pdu)
+		if test.err != tt.err || test.index != tt.index {
+			t.Errorf("TestSet of %s got %s at %d, expected %s at %d", tt.pdu.Name, test.err, test.index, tt.err, tt.index)
+		}
+		cleanup(uint32(i + 2))
+	}
+
+	// a commit failing, which is undone
+	tree.mu.Lock()
+	tree.setErr[v2] = errors.New("disk full")
+	tree.mu.Unlock()
+	test, commit = set(9, gosnmp.SnmpPDU{Name: v1, Type: gosnmp.Integer, Value: 11},
+		gosnmp.SnmpPDU{Name: v2, Type: gosnmp.Integer, Value: 21})
+	if test.err != 0 || commit.err != Error(gosnmp.CommitFailed) || commit.index != 2 {
+		t.Fatalf("SET got %s and %s at %d, expected commitFailed at 2", test.err, commit.err, commit.index)
+	}
+	if tree.value(v1) != 11 {
+		t.Errorf("got value %d, expected 11 before the undo", tree.value(v1))
+	}
+	if undo := m.request(&pdu{typ: undoSetPDU, transactionID: 9}); undo.err != 0 {
+		t.Errorf("UndoSet got %s", undo.err)
+	}
+	cleanup(9)
+	if tree.value(v1) != 10 || tree.value(v2) != 20 {
+		t.Errorf("got values %d and %d after the undo, expected 10 and 20", tree.value(v1), tree.value(v2))
+	}
+}
+
+func TestSubagentSession(t *testing.T) {
+	s, tree, m := startTestSubagent(t)
+
+	// registering while the session is open, refused by the master agent
+	errs := make(chan error, 1)
+	go func() { errs <- s.RegisterSubtree(".1.3.6.1.4.1.99999.5", tree) }()
+	p := m.read()
+	if p.typ != registerPDU || !p.subtree.Equal(mustOid(t, ".1.3.6.1.4.1.99999.5")) {
+		t.Fatalf("got %s of %s, expected Register", p.typ, p.subtree)
+	}
+	response := p.response()
+	response.err = DuplicateRegistration
+	m.write(response)
+	if err := <-errs; !errors.Is(err, DuplicateRegistration) {
+		t.Errorf("RegisterSubtree() got %v, expected duplicateRegistration", err)
+	}
+	if n := len(s.lookup()); n != 3 {
+		t.Errorf("got %d registrations, expected 3", n)
+	}
+
+	// a PDU a subagent doesn't expect
+	m.write(&pdu{typ: pingPDU, sessionID: 42, packetID: 99})
+	if p = m.read(); p.err != ProcessingError {
+		t.Errorf("Ping got %s, expected processingError", p.err)
+	}
+
+	// closed by the master agent
+	m.write(&pdu{typ: closePDU, sessionID: 42, packetID: 100, reason: ReasonByManager})
+	if p = m.read(); p.typ != responsePDU || p.packetID != 100 {
+		t.Errorf("Close got %s %d, expected its response", p.typ, p.packetID)
+	}
+	if err := s.Wait(); err == nil || !strings.Contains(err.Error(), "byManager") {
+		t.Errorf("Wait() got %v, expected the session closed byManager", err)
+	}
+	if err := s.Register(".1.3.6.1.4.1.99999.6.0", tree); err != nil {
+		t.Errorf("Register() after the session closed err: %v", err)
+	}
+	if err := s.Open(nil); err == nil {
+		t.Errorf("Open() of a closed Subagent succeeded")
+	}
+}
+
+func TestSubagentClose(t *testing.T) {
+	s, _, m := startTestSubagent(t)
+	errs := make(chan error, 1)
+	go func() { errs <- s.Close() }()
+	p := m.read()
+	if p.typ != closePDU || p.reason != ReasonShutdown {
+		t.Fatalf("got %s, expected Close", p.typ)
+	}
+	m.write(p.response())
+	if err := <-errs; err != nil {
+		t.Errorf("Close() err: %v", err)
+	}
+	if err := s.Wait(); err != nil {
+		t.Errorf("Wait() after Close got %v", err)
+	}
+}
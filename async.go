@@ -0,0 +1,114 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"context"
+	"sync"
+)
+
+//
+// Asynchronous requests
+//
+
+// AsyncCallback is the type of the function called with the outcome of a
+// request made by one of the *Async methods. Either result or err is nil.
+type AsyncCallback func(result *SnmpPacket, err error)
+
+// asyncQueue holds the requests made by the *Async methods. They are sent,
+// and their callbacks called, in order by a single goroutine which is
+// started when a request is queued and exits once the queue is empty.
+type asyncQueue struct {
+	mu      sync.Mutex
+	pending []func()
+	running bool
+}
+
+// lazyInitMu guards lazy creation of GoSNMP's internal helpers, so the
+// GoSNMP struct itself stays free of locks and can still be copied.
+var lazyInitMu sync.Mutex
+
+func (x *GoSNMP) asyncQueue() *asyncQueue {
+	lazyInitMu.Lock()
+	defer lazyInitMu.Unlock()
+	if x.async == nil {
+		x.async = new(asyncQueue)
+	}
+	return x.async
+}
+
+func (q *asyncQueue) push(request func()) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.pending = append(q.pending, request)
+	if !q.running {
+		q.running = true
+		go q.run()
+	}
+}
+
+func (q *asyncQueue) run() {
+	for {
+		q.mu.Lock()
+		if len(q.pending) == 0 {
+			q.running = false
+			q.mu.Unlock()
+			return
+		}
+		request := q.pending[0]
+		q.pending[0] = nil
+		q.pending = q.pending[1:]
+		q.mu.Unlock()
+
+		request()
+	}
+}
+
+// GetAsync queues an SNMP GET request and returns immediately. callback is
+// called with the response once it arrives, or with the error if the
+// request fails.
+//
+// Requests made with the *Async methods are sent in the order they were
+// queued, and their callbacks are called in that same order from a single
+// goroutine; a slow callback therefore delays the requests queued after it.
+func (x *GoSNMP) GetAsync(oids []string, callback AsyncCallback) {
+	x.asyncQueue().push(func() {
+		callback(x.Get(oids))
+	})
+}
+
+// GetNextAsync queues an SNMP GETNEXT request and returns immediately. See
+// GetAsync for how callback is called.
+func (x *GoSNMP) GetNextAsync(oids []string, callback AsyncCallback) {
+	x.asyncQueue().push(func() {
+		callback(x.GetNext(oids))
+	})
+}
+
+// GetBulkAsync queues an SNMP GETBULK request and returns immediately. See
+// GetAsync for how callback is called.
+func (x *GoSNMP) GetBulkAsync(oids []string, nonRepeaters uint8, maxRepetitions uint8, callback AsyncCallback) {
+	x.asyncQueue().push(func() {
+		callback(x.GetBulk(oids, nonRepeaters, maxRepetitions))
+	})
+}
+
+// SetAsync queues an SNMP SET request and returns immediately. See GetAsync
+// for how callback is called.
+func (x *GoSNMP) SetAsync(pdus []SnmpPDU, callback AsyncCallback) {
+	x.asyncQueue().push(func() {
+		callback(x.Set(pdus))
+	})
+}
+
+// SendInformAsync queues an SNMP InformRequest and returns immediately.
+// callback is called with the receiver's response once the inform is
+// acknowledged, or with the error if it isn't, eg because its retries ran
+// out; see SendInformCtx, and GetAsync for how callback is called.
+func (x *GoSNMP) SendInformAsync(ctx context.Context, pdus []SnmpPDU, callback AsyncCallback) {
+	x.asyncQueue().push(func() {
+		callback(x.SendInformCtx(ctx, pdus))
+	})
+}
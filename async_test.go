@@ -0,0 +1,78 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestGetAsync(t *testing.T) {
+	var pdus []SnmpPDU
+	for i := 1; i <= 10; i++ {
+		pdus = append(pdus, SnmpPDU{Name: fmt.Sprintf(".1.3.6.1.2.1.1.%d.0", i), Type: Integer, Value: i})
+	}
+	r := newTestResponder(t, pdus)
+	defer r.Close()
+	x := r.client(t)
+	defer x.Conn.Close()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var order []int
+	for i := 1; i <= 10; i++ {
+		i := i
+		wg.Add(1)
+		x.GetAsync([]string{fmt.Sprintf(".1.3.6.1.2.1.1.%d.0", i)}, func(result *SnmpPacket, err error) {
+			defer wg.Done()
+			if err != nil {
+				t.Errorf("GetAsync() #%d err: %v", i, err)
+				return
+			}
+			if got := ToBigInt(result.Variables[0].Value).Int64(); got != int64(i) {
+				t.Errorf("GetAsync() #%d got value %d", i, got)
+			}
+			mu.Lock()
+			order = append(order, i)
+			mu.Unlock()
+		})
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for async callbacks")
+	}
+
+	for i, n := range order {
+		if n != i+1 {
+			t.Errorf("callbacks called out of order: %v", order)
+			break
+		}
+	}
+}
+
+func TestGetAsyncError(t *testing.T) {
+	x := &GoSNMP{MaxOids: 1}
+	errc := make(chan error, 1)
+	x.GetAsync([]string{".1.1", ".1.2"}, func(result *SnmpPacket, err error) {
+		errc <- err
+	})
+	select {
+	case err := <-errc:
+		if err == nil {
+			t.Errorf("GetAsync() with too many oids did not return an error")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for async callback")
+	}
+}
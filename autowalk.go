@@ -0,0 +1,70 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+)
+
+// AutoWalk walks the subtree at rootOid with GETBULK, as BulkWalk does,
+// or with GETNEXT, as Walk does, for SNMPv1 or an agent that has
+// mishandled GETBULK.
+//
+// If a GETBULK times out or gets an error status such as genErr, the walk
+// carries on with GETNEXT from the last value received; if this happens
+// on the first request of a walk, later walks on x use GETNEXT from the
+// start.
+func (x *GoSNMP) AutoWalk(rootOid string, walkFn WalkFunc) error {
+	return x.AutoWalkCtx(context.Background(), rootOid, walkFn)
+}
+
+// AutoWalkCtx is like AutoWalk, but the walk stops with ctx.Err() when ctx
+// is cancelled or its deadline passes.
+func (x *GoSNMP) AutoWalkCtx(ctx context.Context, rootOid string, walkFn WalkFunc) error {
+	ctx, cancel := x.withBudget(ctx)
+	defer cancel()
+	if x.Version == Version1 || atomic.LoadUint32(&x.bulkFailed) != 0 {
+		return x.walk(ctx, GetNextRequest, rootOid, walkFn)
+	}
+
+	var last string
+	var walkErr error
+	err := x.walk(ctx, GetBulkRequest, rootOid, func(pdu SnmpPDU) error {
+		last = pdu.Name
+		walkErr = walkFn(pdu)
+		return walkErr
+	})
+	if err == nil || err == walkErr || ctx.Err() != nil {
+		return err
+	}
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) && !isTimeout(err) {
+		return err
+	}
+	if last == "" {
+		atomic.StoreUint32(&x.bulkFailed, 1)
+	}
+	x.logInfo("AutoWalk continuing with GetNext", "oid", rootOid, "err", err)
+	return x.walkFrom(ctx, GetNextRequest, rootOid, last, walkFn)
+}
+
+// AutoWalkAll is like AutoWalk, but returns a slice of all the values
+// rather than calling a WalkFunc
+func (x *GoSNMP) AutoWalkAll(rootOid string) (results []SnmpPDU, err error) {
+	return x.AutoWalkAllCtx(context.Background(), rootOid)
+}
+
+// AutoWalkAllCtx is like AutoWalkAll, but the walk stops with ctx.Err()
+// when ctx is cancelled or its deadline passes. The values retrieved before
+// that point are returned along with the error.
+func (x *GoSNMP) AutoWalkAllCtx(ctx context.Context, rootOid string) (results []SnmpPDU, err error) {
+	err = x.AutoWalkCtx(ctx, rootOid, func(pdu SnmpPDU) error {
+		results = append(results, pdu)
+		return nil
+	})
+	return results, err
+}
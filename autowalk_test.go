@@ -0,0 +1,106 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestAutoWalk(t *testing.T) {
+	var pdus []SnmpPDU
+	for i := 1; i <= 30; i++ {
+		pdus = append(pdus, SnmpPDU{Name: fmt.Sprintf(".1.3.6.1.2.1.2.2.1.2.%d", i), Type: Integer, Value: i})
+	}
+	r := newTestResponder(t, pdus)
+	defer r.Close()
+
+	genErr := func(req *SnmpPacket) *SnmpPacket {
+		if req.PDUType == GetBulkRequest {
+			return &SnmpPacket{Error: GenErr, ErrorIndex: 1, Variables: req.Variables}
+		}
+		return r.respond(req)
+	}
+	bulks := 0
+	tests := []struct {
+		name    string
+		version SnmpVersion
+		handler func(req *SnmpPacket) *SnmpPacket
+		first   PDUType // the first request of the first walk
+		next    bool    // whether the first walk sends GETNEXTs
+		again   PDUType // the first request of a second walk
+	}{
+		{"bulk", Version2c, nil, GetBulkRequest, false, GetBulkRequest},
+		{"v1", Version1, nil, GetNextRequest, true, GetNextRequest},
+		{"genErr", Version2c, genErr, GetBulkRequest, true, GetNextRequest},
+		{"timeout", Version2c, func(req *SnmpPacket) *SnmpPacket {
+			if req.PDUType == GetBulkRequest {
+				return nil
+			}
+			return r.respond(req)
+		}, GetBulkRequest, true, GetNextRequest},
+		{"midway", Version2c, func(req *SnmpPacket) *SnmpPacket {
+			// GETBULK fails after the first request of each walk
+			if req.PDUType == GetBulkRequest {
+				if bulks++; bulks%2 == 0 {
+					return genErr(req)
+				}
+			}
+			return r.respond(req)
+		}, GetBulkRequest, true, GetBulkRequest},
+	}
+	for _, test := range tests {
+		r.setHandler(test.handler)
+		x := r.client(t)
+		x.Version = test.version
+		x.Timeout = 50 * time.Millisecond
+		x.Retries = 0
+		x.MaxRepetitions = 20
+
+		for walk := 0; walk < 2; walk++ {
+			before := len(r.received())
+			results, err := x.AutoWalkAll(".1.3.6.1.2.1.2.2")
+			if err != nil {
+				t.Errorf("%s: AutoWalkAll() err: %v", test.name, err)
+				continue
+			}
+			if len(results) != len(pdus) {
+				t.Errorf("%s: got %d results, want %d", test.name, len(results), len(pdus))
+			}
+			for i, pdu := range results {
+				if pdu.Name != pdus[i].Name {
+					t.Errorf("%s: result %d: got %s, want %s", test.name, i, pdu.Name, pdus[i].Name)
+					break
+				}
+			}
+
+			requests := r.received()[before:]
+			if walk == 1 {
+				if requests[0].PDUType != test.again {
+					t.Errorf("%s: second walk started with %s, want %s", test.name, requests[0].PDUType, test.again)
+				}
+				continue
+			}
+			next := false
+			for _, req := range requests {
+				next = next || req.PDUType == GetNextRequest
+			}
+			if requests[0].PDUType != test.first || next != test.next {
+				t.Errorf("%s: walk started with %s, GETNEXTs sent %t, want %s, %t",
+					test.name, requests[0].PDUType, next, test.first, test.next)
+			}
+		}
+		x.Conn.Close()
+	}
+
+	// BulkWalk itself reports the error
+	r.setHandler(genErr)
+	x := r.client(t)
+	defer x.Conn.Close()
+	if _, err := x.BulkWalkAll(".1.3.6.1.2.1.2.2"); !errorMatches(err, "GetBulk of .1.3.6.1.2.1.2.2 failed: GenErr at 1") {
+		t.Errorf("BulkWalkAll() err: %v", err)
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"fmt"
+)
+
+// Bits is the value of an SMIv2 BITS object, such as
+// LLDP-MIB::lldpLocSysCapEnabled or the docsis MIBs' capability flags.
+// BITS are carried as an OctetString, bit 0 being the most significant bit
+// of the first octet, so a received OctetString Value can be converted
+// with Bits(pdu.Value.([]byte)). A Bits value can be sent with Set as an
+// OctetString.
+//
+// The names of the bits are given by the object's SYNTAX, as a map from
+// name to position (see Syntax.BitNames in the mib package).
+type Bits []byte
+
+// BitsFromPositions returns Bits with the given bits set
+func BitsFromPositions(positions ...int) Bits {
+	var b Bits
+	for _, n := range positions {
+		b.Set(n)
+	}
+	return b
+}
+
+// Test reports whether bit n is set
+func (b Bits) Test(n int) bool {
+	if n < 0 || n/8 >= len(b) {
+		return false
+	}
+	return b[n/8]&(0x80>>uint(n%8)) != 0
+}
+
+// Set sets bit n, growing b as needed
+func (b *Bits) Set(n int) {
+	if n < 0 {
+		return
+	}
+	for n/8 >= len(*b) {
+		*b = append(*b, 0)
+	}
+	(*b)[n/8] |= 0x80 >> uint(n%8)
+}
+
+// Clear clears bit n. b isn't shortened, as some agents expect a fixed
+// number of octets.
+func (b Bits) Clear(n int) {
+	if n < 0 || n/8 >= len(b) {
+		return
+	}
+	b[n/8] &^= 0x80 >> uint(n%8)
+}
+
+// Positions returns the positions of the bits that are set, in order
+func (b Bits) Positions() []int {
+	var positions []int
+	for i, c := range b {
+		for bit := 0; bit < 8; bit++ {
+			if c&(0x80>>uint(bit)) != 0 {
+				positions = append(positions, 8*i+bit)
+			}
+		}
+	}
+	return positions
+}
+
+// Names returns the names of the bits that are set in position order.
+// Bits without a name are given as their position, eg "17".
+func (b Bits) Names(names map[string]int) []string {
+	byPosition := make(map[int]string, len(names))
+	for name, n := range names {
+		byPosition[n] = name
+	}
+	var set []string
+	for _, n := range b.Positions() {
+		if name, ok := byPosition[n]; ok {
+			set = append(set, name)
+		} else {
+			set = append(set, fmt.Sprint(n))
+		}
+	}
+	return set
+}
+
+// TestName reports whether the bit called name is set
+func (b Bits) TestName(names map[string]int, name string) (bool, error) {
+	n, ok := names[name]
+	if !ok {
+		return false, fmt.Errorf("Unknown bit %s", name)
+	}
+	return b.Test(n), nil
+}
+
+// SetName sets the bit called name
+func (b *Bits) SetName(names map[string]int, name string) error {
+	n, ok := names[name]
+	if !ok {
+		return fmt.Errorf("Unknown bit %s", name)
+	}
+	b.Set(n)
+	return nil
+}
+
+// ClearName clears the bit called name
+func (b Bits) ClearName(names map[string]int, name string) error {
+	n, ok := names[name]
+	if !ok {
+		return fmt.Errorf("Unknown bit %s", name)
+	}
+	b.Clear(n)
+	return nil
+}
+
+// BitsFromNames returns Bits with the named bits set, sized to hold every
+// bit in names (so that agents expecting a full length value accept it)
+func BitsFromNames(names map[string]int, set ...string) (Bits, error) {
+	last := -1
+	for _, n := range names {
+		if n > last {
+			last = n
+		}
+	}
+	var b Bits
+	if last >= 0 {
+		b = make(Bits, last/8+1)
+	}
+	for _, name := range set {
+		if err := b.SetName(names, name); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
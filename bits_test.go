@@ -0,0 +1,99 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+var lldpCapabilities = map[string]int{
+	"other": 0, "repeater": 1, "bridge": 2, "wlanAccessPoint": 3,
+	"router": 4, "telephone": 5, "docsisCableDevice": 6, "stationOnly": 7,
+	"cVLANComponent": 8,
+}
+
+func TestBits(t *testing.T) {
+	tests := []struct {
+		positions []int
+		want      Bits
+	}{
+		{nil, nil},
+		{[]int{0}, Bits{0x80}},
+		{[]int{2, 4}, Bits{0x28}},
+		{[]int{7, 8}, Bits{0x01, 0x80}},
+		{[]int{17}, Bits{0x00, 0x00, 0x40}},
+	}
+	for _, test := range tests {
+		b := BitsFromPositions(test.positions...)
+		if !bytes.Equal(b, test.want) {
+			t.Errorf("%v: got % x, want % x", test.positions, b, test.want)
+		}
+		if got := b.Positions(); !reflect.DeepEqual(got, test.positions) {
+			t.Errorf("% x: got positions %v, want %v", b, got, test.positions)
+		}
+		for _, n := range test.positions {
+			if !b.Test(n) {
+				t.Errorf("% x: bit %d not set", b, n)
+			}
+		}
+	}
+
+	b := BitsFromPositions(2, 4)
+	if b.Test(3) || b.Test(-1) || b.Test(100) {
+		t.Errorf("% x: unexpected bits set", b)
+	}
+	b.Clear(2)
+	b.Clear(100)
+	if !bytes.Equal(b, Bits{0x08}) {
+		t.Errorf("after Clear: got % x", b)
+	}
+}
+
+func TestNamedBits(t *testing.T) {
+	b, err := BitsFromNames(lldpCapabilities, "bridge", "router")
+	if err != nil || !bytes.Equal(b, Bits{0x28, 0x00}) {
+		t.Fatalf("got % x, %v", b, err)
+	}
+	if got := b.Names(lldpCapabilities); !reflect.DeepEqual(got, []string{"bridge", "router"}) {
+		t.Errorf("Names: got %v", got)
+	}
+	if set, err := b.TestName(lldpCapabilities, "router"); !set || err != nil {
+		t.Errorf("TestName(router): got %v, %v", set, err)
+	}
+	if err = b.SetName(lldpCapabilities, "cVLANComponent"); err != nil || !b.Test(8) {
+		t.Errorf("SetName(cVLANComponent): got % x, %v", b, err)
+	}
+	if err = b.ClearName(lldpCapabilities, "bridge"); err != nil || b.Test(2) {
+		t.Errorf("ClearName(bridge): got % x, %v", b, err)
+	}
+	b.Set(12)
+	if got := b.Names(lldpCapabilities); !reflect.DeepEqual(got, []string{"router", "cVLANComponent", "12"}) {
+		t.Errorf("Names with an unnamed bit: got %v", got)
+	}
+
+	if _, err = b.TestName(lldpCapabilities, "modem"); err == nil {
+		t.Errorf("TestName(modem): expected an error")
+	}
+	if err = b.SetName(lldpCapabilities, "modem"); err == nil {
+		t.Errorf("SetName(modem): expected an error")
+	}
+	if _, err = BitsFromNames(lldpCapabilities, "modem"); err == nil {
+		t.Errorf("BitsFromNames(modem): expected an error")
+	}
+}
+
+func TestMarshalBits(t *testing.T) {
+	pdu := SnmpPDU{Name: ".1.3.6.1.2.1.1.1.0", Type: OctetString, Value: BitsFromPositions(0, 9)}
+	got, err := marshalVarbind(&pdu)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{0x30, 0x0e, 0x06, 0x08, 0x2b, 0x06, 0x01, 0x02, 0x01, 0x01, 0x01, 0x00, 0x04, 0x02, 0x80, 0x40}
+	if !bytes.Equal(got, want) {
+		t.Errorf("got % x, want % x", got, want)
+	}
+}
@@ -0,0 +1,37 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"context"
+	"time"
+)
+
+// budgetKey marks a context as limited to the budget of an operation, so
+// that the requests and walks an operation is made of don't each get a
+// budget of their own
+type budgetKey struct{}
+
+// RequestOperationTimeout overrides GoSNMP.OperationTimeout
+func RequestOperationTimeout(timeout time.Duration) RequestOption {
+	return func(o *requestOptions) {
+		o.operationTimeout = &timeout
+	}
+}
+
+// withBudget returns ctx limited to the operation timeout for requests made
+// with it, unless there is none or ctx is already limited by the operation
+// it is part of. cancel must be called when the operation ends.
+func (x *GoSNMP) withBudget(ctx context.Context) (_ context.Context, cancel context.CancelFunc) {
+	budget := x.OperationTimeout
+	if o := optionsFrom(ctx); o.operationTimeout != nil {
+		budget = *o.operationTimeout
+	}
+	if budget <= 0 || ctx.Value(budgetKey{}) != nil {
+		return ctx, func() {}
+	}
+	ctx, cancel = context.WithTimeout(ctx, budget)
+	return context.WithValue(ctx, budgetKey{}, true), cancel
+}
@@ -0,0 +1,66 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestOperationTimeout(t *testing.T) {
+	const column = ".1.3.6.1.2.1.2.2.1.2"
+	var pdus []SnmpPDU
+	for i := 1; i <= 20; i++ {
+		pdus = append(pdus, SnmpPDU{Name: fmt.Sprintf("%s.%d", column, i), Type: Integer, Value: i})
+	}
+	r := newTestResponder(t, pdus)
+	defer r.Close()
+	x := r.client(t)
+	defer x.Conn.Close()
+	x.Timeout = 5 * time.Second
+	x.Retries = 3
+
+	// a slow agent, each request well within Timeout
+	r.setHandler(func(req *SnmpPacket) *SnmpPacket {
+		time.Sleep(20 * time.Millisecond)
+		return r.respond(req)
+	})
+	tests := []struct {
+		name      string
+		operation func() error
+	}{
+		{"OperationTimeout", func() error {
+			x.OperationTimeout = 100 * time.Millisecond
+			defer func() { x.OperationTimeout = 0 }()
+			return x.Walk(column, func(SnmpPDU) error { return nil })
+		}},
+		{"RequestOperationTimeout", func() error {
+			ctx := WithRequestOptions(context.Background(), RequestOperationTimeout(100*time.Millisecond))
+			return x.WalkCtx(ctx, column, func(SnmpPDU) error { return nil })
+		}},
+		{"silent agent", func() error {
+			r.setHandler(func(req *SnmpPacket) *SnmpPacket { return nil })
+			ctx := WithRequestOptions(context.Background(), RequestOperationTimeout(100*time.Millisecond))
+			_, err := x.GetCtx(ctx, []string{column + ".1"})
+			return err
+		}},
+	}
+	for _, test := range tests {
+		start := time.Now()
+		err := test.operation()
+		if elapsed := time.Since(start); !errors.Is(err, context.DeadlineExceeded) || elapsed > 300*time.Millisecond {
+			t.Errorf("%s: err: %v after %v, want context.DeadlineExceeded after 100ms", test.name, err, elapsed)
+		}
+	}
+
+	// without a budget the walk completes
+	r.setHandler(nil)
+	if results, err := x.WalkAll(column); err != nil || len(results) != len(pdus) {
+		t.Errorf("WalkAll() got %d values, err: %v", len(results), err)
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Cache holds values got from agents for a time, see GoSNMP.Cache. It is
+// meant for slow changing objects that are asked for often, such as
+// sysDescr or ifAlias. The zero value is an empty cache holding values for
+// DefaultCacheTTL. A Cache is safe for concurrent use, but must not be
+// copied after first use.
+type Cache struct {
+	// TTL is how long values are held (default: DefaultCacheTTL)
+	TTL time.Duration
+
+	mu      sync.Mutex
+	entries map[cacheKey]cacheEntry
+	swept   int // entries after the last sweep of expired ones
+}
+
+// DefaultCacheTTL is how long a Cache holds values unless its TTL is set
+const DefaultCacheTTL = 5 * time.Minute
+
+// cacheKey is an oid of a target, "host:port"
+type cacheKey struct {
+	target, oid string
+}
+
+type cacheEntry struct {
+	pdu     SnmpPDU
+	expires time.Time
+}
+
+// Invalidate removes the values of oids of target ("host:port", eg
+// "192.0.2.1:161") from the cache, or all of its values if there are no
+// oids.
+func (c *Cache) Invalidate(target string, oids ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(oids) == 0 {
+		for key := range c.entries {
+			if key.target == target {
+				delete(c.entries, key)
+			}
+		}
+		return
+	}
+	for _, oid := range oids {
+		delete(c.entries, cacheKey{target, "." + trimOidDot(oid)})
+	}
+}
+
+// Flush removes all values from the cache
+func (c *Cache) Flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = nil
+	c.swept = 0
+}
+
+func (c *Cache) get(key cacheKey, now time.Time) (SnmpPDU, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return SnmpPDU{}, false
+	}
+	if !now.Before(e.expires) {
+		delete(c.entries, key)
+		return SnmpPDU{}, false
+	}
+	return e.pdu, true
+}
+
+func (c *Cache) put(key cacheKey, pdu SnmpPDU, now time.Time) {
+	ttl := c.TTL
+	if ttl <= 0 {
+		ttl = DefaultCacheTTL
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[cacheKey]cacheEntry)
+	}
+	c.entries[key] = cacheEntry{pdu: pdu, expires: now.Add(ttl)}
+
+	// values that aren't asked for again are only removed by sweeps, made
+	// whenever the cache has doubled in size since the last
+	if len(c.entries) > 2*c.swept {
+		for k, e := range c.entries {
+			if !now.Before(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+		c.swept = len(c.entries)
+	}
+}
+
+// cacheTarget is the target of x, as its values are keyed in x.Cache
+func (x *GoSNMP) cacheTarget() string {
+	return net.JoinHostPort(x.Target, strconv.Itoa(int(x.Port)))
+}
+
+// cachedGet is Get with x.Cache, requesting only the oids that aren't in
+// it. A response with an error status is returned with its ErrorIndex
+// counted in oids, and Variables in the order of oids, but nothing from it
+// is cached.
+func (x *GoSNMP) cachedGet(ctx context.Context, oids []string) (result *SnmpPacket, err error) {
+	target := x.cacheTarget()
+	now := time.Now()
+	pdus := make([]SnmpPDU, len(oids))
+	var missing []string
+	var at []int // the index in oids of each missing oid
+	for i, oid := range oids {
+		if pdu, ok := x.Cache.get(cacheKey{target, "." + trimOidDot(oid)}, now); ok {
+			pdus[i] = pdu
+			continue
+		}
+		pdus[i] = SnmpPDU{Name: oid, Type: Null, Logger: x.Logger}
+		missing = append(missing, oid)
+		at = append(at, i)
+	}
+	if len(missing) == 0 {
+		return x.mkSnmpPacket(GetResponse, pdus, 0, 0), nil
+	}
+
+	result, err = x.get(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+	for k, pdu := range result.Variables {
+		if k >= len(at) {
+			break
+		}
+		pdus[at[k]] = pdu
+		if result.Error == NoError && !isException(pdu.Type) {
+			x.Cache.put(cacheKey{target, "." + trimOidDot(missing[k])}, pdu, now)
+		}
+	}
+	if i := int(result.ErrorIndex); result.Error != NoError && i > 0 && i <= len(at) {
+		if index := at[i-1] + 1; index <= 255 {
+			result.ErrorIndex = uint8(index)
+		} else {
+			result.ErrorIndex = 0
+		}
+	}
+	result.Variables = pdus
+	return result, nil
+}
+
+// invalidateCache removes the values of the varbinds of a Set from x.Cache
+func (x *GoSNMP) invalidateCache(pdus []SnmpPDU) {
+	oids := make([]string, len(pdus))
+	for i, pdu := range pdus {
+		oids[i] = pdu.Name
+	}
+	x.Cache.Invalidate(x.cacheTarget(), oids...)
+}
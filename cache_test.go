@@ -0,0 +1,93 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	const (
+		descr    = ".1.3.6.1.2.1.1.1.0"
+		name     = ".1.3.6.1.2.1.1.5.0"
+		location = ".1.3.6.1.2.1.1.6.0"
+	)
+	r := newTestResponder(t, []SnmpPDU{
+		{Name: descr, Type: OctetString, Value: []byte("descr")},
+		{Name: name, Type: OctetString, Value: []byte("name")},
+		{Name: location, Type: OctetString, Value: []byte("location")},
+	})
+	defer r.Close()
+	x := r.client(t)
+	defer x.Conn.Close()
+	x.Cache = &Cache{TTL: time.Hour}
+
+	tests := []struct {
+		name string
+		do   func()
+		oids []string
+		want []string // oids requested, nil for no request
+	}{
+		{"first", nil, []string{descr, name}, []string{descr, name}},
+		{"cached", nil, []string{name, descr}, nil},
+		{"partly cached", nil, []string{descr, location, name}, []string{location}},
+		{"invalidated", func() { x.Cache.Invalidate(x.cacheTarget(), descr) }, []string{descr, name}, []string{descr}},
+		{"set", func() {
+			r.setHandler(func(req *SnmpPacket) *SnmpPacket { return &SnmpPacket{Variables: req.Variables} })
+			if _, err := x.Set([]SnmpPDU{{Name: name, Type: OctetString, Value: "new"}}); err != nil {
+				t.Errorf("Set() err: %v", err)
+			}
+			r.setHandler(nil)
+		}, []string{name}, []string{name}},
+		{"flushed", func() { x.Cache.Flush() }, []string{location}, []string{location}},
+		{"expired", func() {
+			x.Cache.TTL = 10 * time.Millisecond
+			x.Get([]string{descr})
+			time.Sleep(20 * time.Millisecond)
+		}, []string{descr}, []string{descr}},
+	}
+	for _, test := range tests {
+		if test.do != nil {
+			test.do()
+		}
+		seen := len(r.received())
+		result, err := x.Get(test.oids)
+		if err != nil {
+			t.Errorf("%s: Get() err: %v", test.name, err)
+			continue
+		}
+		for i, pdu := range result.Variables {
+			if pdu.Name != test.oids[i] || pdu.Type != OctetString {
+				t.Errorf("%s: value %d is %s, want %s", test.name, i, pdu, test.oids[i])
+			}
+		}
+		requests := r.received()[seen:]
+		switch {
+		case test.want == nil && len(requests) != 0:
+			t.Errorf("%s: got %d requests, want none", test.name, len(requests))
+		case test.want != nil && (len(requests) != 1 || len(requests[0].Variables) != len(test.want)):
+			t.Errorf("%s: got requests %v, want one for %v", test.name, requests, test.want)
+		case test.want != nil:
+			for i, pdu := range requests[0].Variables {
+				if pdu.Name != test.want[i] {
+					t.Errorf("%s: requested %s, want %s", test.name, pdu.Name, test.want[i])
+				}
+			}
+		}
+	}
+
+	// an error status is for the position in the oids asked for
+	x.Cache.TTL = time.Hour
+	x.Cache.Flush()
+	x.Get([]string{descr})
+	r.setHandler(func(req *SnmpPacket) *SnmpPacket {
+		return &SnmpPacket{Error: NoSuchName, ErrorIndex: 1, Variables: req.Variables}
+	})
+	result, err := x.Get([]string{descr, location})
+	if err != nil || result.Error != NoSuchName || result.ErrorIndex != 2 {
+		t.Errorf("Get() got %v, err: %v, want NoSuchName at 2", result, err)
+	}
+}
@@ -0,0 +1,207 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+//
+// Packet capture
+//
+
+const (
+	pcapMagic       = 0xa1b2c3d4
+	pcapSnapLen     = 65535
+	pcapLinkTypeRaw = 101 // IPv4 or IPv6 packets, without a link layer
+)
+
+// Capture writes the datagrams sent and received by sessions, see
+// GoSNMP.Capture, in pcap format, for troubleshooting with tools such as
+// Wireshark or tcpdump. The datagrams are given IP and UDP headers made up
+// from the addresses of the session's connection. A Capture is safe for
+// concurrent use, so can be shared by sessions, eg those of a Pool.
+type Capture struct {
+	// Plaintext adds, after each SNMPv3 message encrypted for privacy, a
+	// copy of it re-encoded as noAuthNoPriv, so that its PDU can be read
+	// without the keys. The capture then holds what privacy is meant to
+	// hide, so should be kept as safe as the keys.
+	Plaintext bool
+
+	mu  sync.Mutex
+	w   io.Writer
+	err error // the first error writing to w
+}
+
+// NewCapture returns a Capture writing to w, having written the pcap file
+// header to it.
+func NewCapture(w io.Writer) (*Capture, error) {
+	header := make([]byte, 24)
+	binary.LittleEndian.PutUint32(header[0:], pcapMagic)
+	binary.LittleEndian.PutUint16(header[4:], 2) // version 2.4
+	binary.LittleEndian.PutUint16(header[6:], 4)
+	binary.LittleEndian.PutUint32(header[16:], pcapSnapLen)
+	binary.LittleEndian.PutUint32(header[20:], pcapLinkTypeRaw)
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return &Capture{w: w}, nil
+}
+
+// Err returns the first error writing to the capture's writer, after
+// which nothing more is written. Requests aren't failed by it.
+func (c *Capture) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// datagram adds msg, sent from src to dst
+func (c *Capture) datagram(src, dst net.Addr, msg []byte) {
+	packet := udpPacket(udpAddr(src), udpAddr(dst), msg)
+	if len(packet) > pcapSnapLen {
+		packet = packet[:pcapSnapLen]
+	}
+	now := time.Now()
+	record := make([]byte, 16, 16+len(packet))
+	binary.LittleEndian.PutUint32(record[0:], uint32(now.Unix()))
+	binary.LittleEndian.PutUint32(record[4:], uint32(now.Nanosecond()/1000))
+	binary.LittleEndian.PutUint32(record[8:], uint32(len(packet)))
+	binary.LittleEndian.PutUint32(record[12:], uint32(len(packet)))
+	record = append(record, packet...)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err == nil {
+		_, c.err = c.w.Write(record)
+	}
+}
+
+// plaintext adds a plaintext copy of packet, sent from src to dst, if it
+// is encrypted and c.Plaintext is set
+func (c *Capture) plaintext(src, dst net.Addr, packet *SnmpPacket) {
+	if !c.Plaintext || packet.Version != Version3 || packet.MsgFlags&AuthPriv != AuthPriv {
+		return
+	}
+	plain := *packet
+	plain.MsgFlags &^= AuthPriv
+	msg, err := plain.marshalMsg()
+	if err != nil {
+		// eg a response with values that can't be re-encoded
+		return
+	}
+	c.datagram(src, dst, msg)
+}
+
+// captureSent adds msg, sent for packet, to x.Capture
+func (x *GoSNMP) captureSent(msg []byte, packet *SnmpPacket) {
+	if x.Capture == nil {
+		return
+	}
+	x.Capture.datagram(x.Conn.LocalAddr(), x.Conn.RemoteAddr(), msg)
+	x.Capture.plaintext(x.Conn.LocalAddr(), x.Conn.RemoteAddr(), packet)
+}
+
+// captureReceived adds msg, received on conn from the address from, to
+// x.Capture
+func (x *GoSNMP) captureReceived(conn net.Conn, from net.Addr, msg []byte) {
+	if x.Capture == nil {
+		return
+	}
+	x.Capture.datagram(from, conn.LocalAddr(), msg)
+}
+
+// captureDecoded adds the plaintext copy of a response, once decrypted,
+// to x.Capture
+func (x *GoSNMP) captureDecoded(packet *SnmpPacket) {
+	if x.Capture == nil {
+		return
+	}
+	x.Capture.plaintext(x.Conn.RemoteAddr(), x.Conn.LocalAddr(), packet)
+}
+
+// udpAddr returns addr as a *net.UDPAddr (for a tcp address, with its IP
+// and port), or an unspecified IPv4 address if it isn't one (eg the
+// address of a Conn that isn't udp or tcp)
+func udpAddr(addr net.Addr) *net.UDPAddr {
+	switch a := addr.(type) {
+	case *net.UDPAddr:
+		if a.IP != nil {
+			return a
+		}
+	case *net.TCPAddr:
+		if a.IP != nil {
+			return &net.UDPAddr{IP: a.IP, Port: a.Port, Zone: a.Zone}
+		}
+	}
+	return &net.UDPAddr{IP: net.IPv4zero}
+}
+
+// udpPacket returns an IPv4, or IPv6, packet holding a udp datagram of
+// payload from src to dst
+func udpPacket(src, dst *net.UDPAddr, payload []byte) []byte {
+	udp := make([]byte, 8, 8+len(payload))
+	binary.BigEndian.PutUint16(udp[0:], uint16(src.Port))
+	binary.BigEndian.PutUint16(udp[2:], uint16(dst.Port))
+	binary.BigEndian.PutUint16(udp[4:], uint16(8+len(payload)))
+	udp = append(udp, payload...)
+
+	src4, dst4 := src.IP.To4(), dst.IP.To4()
+	if src4 != nil && dst4 != nil {
+		ip := make([]byte, 20, 20+len(udp))
+		ip[0] = 0x45 // version 4, 5 word header
+		binary.BigEndian.PutUint16(ip[2:], uint16(20+len(udp)))
+		ip[6] = 0x40 // don't fragment
+		ip[8] = 64   // ttl
+		ip[9] = 17   // udp
+		copy(ip[12:], src4)
+		copy(ip[16:], dst4)
+		binary.BigEndian.PutUint16(ip[10:], uint16(^checksum(0, ip)))
+		binary.BigEndian.PutUint16(udp[6:], udpChecksum(src4, dst4, udp))
+		return append(ip, udp...)
+	}
+
+	src6, dst6 := src.IP.To16(), dst.IP.To16()
+	ip := make([]byte, 40, 40+len(udp))
+	ip[0] = 0x60 // version 6
+	binary.BigEndian.PutUint16(ip[4:], uint16(len(udp)))
+	ip[6] = 17 // udp
+	ip[7] = 64 // hop limit
+	copy(ip[8:], src6)
+	copy(ip[24:], dst6)
+	binary.BigEndian.PutUint16(udp[6:], udpChecksum(src6, dst6, udp))
+	return append(ip, udp...)
+}
+
+// udpChecksum returns the checksum of udp, over its pseudo header too
+func udpChecksum(src, dst net.IP, udp []byte) uint16 {
+	sum := checksum(0, src)
+	sum = checksum(sum, dst)
+	sum += 17 + uint32(len(udp))
+	c := uint16(^checksum(sum, udp))
+	if c == 0 {
+		// zero means no checksum
+		c = 0xffff
+	}
+	return c
+}
+
+// checksum adds b to the ones' complement sum, folded to 16 bits
+func checksum(sum uint32, b []byte) uint32 {
+	for i := 0; i+1 < len(b); i += 2 {
+		sum += uint32(b[i])<<8 | uint32(b[i+1])
+	}
+	if len(b)%2 == 1 {
+		sum += uint32(b[len(b)-1]) << 8
+	}
+	for sum > 0xffff {
+		sum = sum>>16 + sum&0xffff
+	}
+	return sum
+}
@@ -0,0 +1,231 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net"
+	"testing"
+)
+
+// capturedDatagram is a udp datagram read back from a capture
+type capturedDatagram struct {
+	src, dst *net.UDPAddr
+	payload  []byte
+}
+
+// readCapture decodes a pcap file written by a Capture, checking the
+// headers made up for each datagram
+func readCapture(t *testing.T, b []byte) []capturedDatagram {
+	t.Helper()
+	if len(b) < 24 || binary.LittleEndian.Uint32(b) != pcapMagic ||
+		binary.LittleEndian.Uint32(b[20:]) != pcapLinkTypeRaw {
+		t.Fatalf("bad pcap file header % x", b)
+	}
+	b = b[24:]
+	var datagrams []capturedDatagram
+	for len(b) > 0 {
+		if len(b) < 16 {
+			t.Fatalf("truncated pcap record header")
+		}
+		n := int(binary.LittleEndian.Uint32(b[8:]))
+		if n != int(binary.LittleEndian.Uint32(b[12:])) || len(b) < 16+n {
+			t.Fatalf("bad pcap record length %d", n)
+		}
+		packet := b[16 : 16+n]
+		b = b[16+n:]
+
+		var src, dst net.IP
+		var udp []byte
+		switch packet[0] >> 4 {
+		case 4:
+			if checksum(0, packet[:20]) != 0xffff {
+				t.Errorf("bad IPv4 header checksum")
+			}
+			if int(binary.BigEndian.Uint16(packet[2:])) != len(packet) || packet[9] != 17 {
+				t.Errorf("bad IPv4 header % x", packet[:20])
+			}
+			src, dst, udp = net.IP(packet[12:16]), net.IP(packet[16:20]), packet[20:]
+		case 6:
+			if int(binary.BigEndian.Uint16(packet[4:])) != len(packet)-40 || packet[6] != 17 {
+				t.Errorf("bad IPv6 header % x", packet[:40])
+			}
+			src, dst, udp = net.IP(packet[8:24]), net.IP(packet[24:40]), packet[40:]
+		default:
+			t.Fatalf("bad IP version in % x", packet)
+		}
+		if int(binary.BigEndian.Uint16(udp[4:])) != len(udp) {
+			t.Errorf("bad udp length in % x", udp[:8])
+		}
+		sum := checksum(checksum(0, src), dst) + 17 + uint32(len(udp))
+		if checksum(sum, udp) != 0xffff {
+			t.Errorf("bad udp checksum")
+		}
+		datagrams = append(datagrams, capturedDatagram{
+			src:     &net.UDPAddr{IP: src, Port: int(binary.BigEndian.Uint16(udp[0:]))},
+			dst:     &net.UDPAddr{IP: dst, Port: int(binary.BigEndian.Uint16(udp[2:]))},
+			payload: udp[8:],
+		})
+	}
+	return datagrams
+}
+
+func TestCapture(t *testing.T) {
+	const oid = ".1.3.6.1.2.1.1.5.0"
+	r := newTestResponder(t, []SnmpPDU{{Name: oid, Type: OctetString, Value: []byte("name")}})
+	defer r.Close()
+	x := r.client(t)
+	defer x.Conn.Close()
+	var buf bytes.Buffer
+	capture, err := NewCapture(&buf)
+	if err != nil {
+		t.Fatalf("NewCapture() err: %v", err)
+	}
+	x.Capture = capture
+
+	if _, err = x.Get([]string{oid}); err != nil {
+		t.Fatalf("Get() err: %v", err)
+	}
+	datagrams := readCapture(t, buf.Bytes())
+	if len(datagrams) != 2 {
+		t.Fatalf("got %d datagrams, expected the request and response", len(datagrams))
+	}
+	agent := r.conn.LocalAddr().(*net.UDPAddr).Port
+	request, response := datagrams[0], datagrams[1]
+	if request.dst.Port != agent || response.src.Port != agent || request.src.Port != response.dst.Port {
+		t.Errorf("got ports %d->%d and %d->%d, expected to and from %d",
+			request.src.Port, request.dst.Port, response.src.Port, response.dst.Port, agent)
+	}
+	if p, err := decodeMessage(x, request.payload); err != nil || p.PDUType != GetRequest {
+		t.Errorf("got request %v, err %v", p, err)
+	}
+	if p, err := decodeMessage(x, response.payload); err != nil || p.PDUType != GetResponse {
+		t.Errorf("got response %v, err %v", p, err)
+	}
+	if capture.Err() != nil {
+		t.Errorf("Err() = %v", capture.Err())
+	}
+}
+
+func TestCapturePlaintext(t *testing.T) {
+	x := &GoSNMP{
+		Version:       Version3,
+		MsgFlags:      AuthPriv,
+		SecurityModel: UserSecurityModel,
+		SecurityParameters: &UsmSecurityParameters{
+			UserName:                 "user",
+			AuthenticationProtocol:   SHA,
+			AuthenticationPassphrase: "authpassphrase",
+			PrivacyProtocol:          AES,
+			PrivacyPassphrase:        "privpassphrase",
+		},
+	}
+	if err := x.validateParameters(); err != nil {
+		t.Fatalf("validateParameters() err: %v", err)
+	}
+	discovered := &SnmpPacket{
+		Version:            Version3,
+		SecurityModel:      UserSecurityModel,
+		SecurityParameters: &UsmSecurityParameters{AuthoritativeEngineID: "engine"},
+	}
+	if err := x.storeSecurityParameters(discovered); err != nil {
+		t.Fatalf("storeSecurityParameters() err: %v", err)
+	}
+	const oid = ".1.3.6.1.2.1.1.1.0"
+	packet := x.mkSnmpPacket(GetRequest, []SnmpPDU{{Name: oid, Type: Null}}, 0, 0)
+	if err := x.initPacket(packet); err != nil {
+		t.Fatalf("initPacket() err: %v", err)
+	}
+	out, err := packet.marshalMsg()
+	if err != nil {
+		t.Fatalf("marshalMsg() err: %v", err)
+	}
+
+	for _, plaintext := range []bool{false, true} {
+		var buf bytes.Buffer
+		capture, _ := NewCapture(&buf)
+		capture.Plaintext = plaintext
+		capture.datagram(nil, nil, out)
+		capture.plaintext(nil, nil, packet)
+
+		datagrams := readCapture(t, buf.Bytes())
+		if !plaintext {
+			if len(datagrams) != 1 {
+				t.Errorf("got %d datagrams without Plaintext, expected 1", len(datagrams))
+			}
+			continue
+		}
+		if len(datagrams) != 2 || !bytes.Equal(datagrams[0].payload, out) {
+			t.Fatalf("got %d datagrams, expected the message and a plaintext copy", len(datagrams))
+		}
+
+		plain := datagrams[1].payload
+		decoded := &SnmpPacket{SecurityParameters: &UsmSecurityParameters{Logger: x.Logger}}
+		cursor, err := x.unmarshalHeader(plain, decoded)
+		if err != nil {
+			t.Fatalf("unmarshalHeader() err: %v", err)
+		}
+		if decoded.MsgFlags&AuthPriv != 0 {
+			t.Errorf("plaintext copy has flags %s", decoded.MsgFlags)
+		}
+		plain, cursor, err = x.decryptPacket(plain, cursor, decoded)
+		if err != nil {
+			t.Fatalf("decryptPacket() err: %v", err)
+		}
+		decoded.PDUType = PDUType(plain[cursor])
+		if err = x.unmarshalResponse(plain[cursor:], decoded); err != nil {
+			t.Fatalf("unmarshalResponse() err: %v", err)
+		}
+		if len(decoded.Variables) != 1 || decoded.Variables[0].Name != oid {
+			t.Errorf("plaintext copy has varbinds %v, expected %s", decoded.Variables, oid)
+		}
+	}
+}
+
+func TestCaptureIPv6(t *testing.T) {
+	var buf bytes.Buffer
+	capture, _ := NewCapture(&buf)
+	src := &net.UDPAddr{IP: net.ParseIP("2001:db8::1"), Port: 50000}
+	dst := &net.UDPAddr{IP: net.ParseIP("2001:db8::2"), Port: 161}
+	capture.datagram(src, dst, []byte("odd length"))
+
+	datagrams := readCapture(t, buf.Bytes())
+	if len(datagrams) != 1 {
+		t.Fatalf("got %d datagrams", len(datagrams))
+	}
+	d := datagrams[0]
+	if d.src.String() != src.String() || d.dst.String() != dst.String() || string(d.payload) != "odd length" {
+		t.Errorf("got %s->%s %q", d.src, d.dst, d.payload)
+	}
+}
+
+type failingWriter struct{ n int }
+
+func (w *failingWriter) Write(b []byte) (int, error) {
+	if w.n == 0 {
+		return 0, errors.New("disk full")
+	}
+	w.n--
+	return len(b), nil
+}
+
+func TestCaptureErr(t *testing.T) {
+	if _, err := NewCapture(&failingWriter{}); err == nil {
+		t.Errorf("NewCapture() with a failing writer succeeded")
+	}
+	w := &failingWriter{n: 2}
+	capture, err := NewCapture(w)
+	if err != nil {
+		t.Fatalf("NewCapture() err: %v", err)
+	}
+	capture.datagram(nil, nil, []byte("one"))
+	capture.datagram(nil, nil, []byte("two"))
+	capture.datagram(nil, nil, []byte("three"))
+	if capture.Err() == nil || w.n != 0 {
+		t.Errorf("got Err() %v, after %d writes left", capture.Err(), w.n)
+	}
+}
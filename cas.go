@@ -0,0 +1,86 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// CompareAndSet gets oid, calls update with its value to compute the new
+// one, gets oid again, and sets it to the new value only if the value is
+// still the one update was given; otherwise it fails with an error
+// matching ErrValueChanged, and nothing is set. eg to toggle an enum
+//
+//	err := x.CompareAndSet(oid, func(current gosnmp.SnmpPDU) (gosnmp.SnmpPDU, error) {
+//		n, err := current.Int64()
+//		return gosnmp.SnmpPDU{Type: gosnmp.Integer, Value: 3 - int(n)}, err
+//	})
+//
+// The varbind update returns is set with the name oid. An error from
+// update is returned as it is, and nothing is set. update is given
+// exceptions (eg NoSuchInstance) as values too. The values are got from
+// the agent rather than the Cache.
+//
+// The value is checked again just before the SET, but SNMP has no atomic
+// compare-and-set: a manager setting oid between that GET and the SET
+// isn't noticed, unless the agent guards it with a TestAndIncr lock such
+// as snmpSetSerialNo, which can be set along with it with SetCtx.
+func (x *GoSNMP) CompareAndSet(oid string, update func(current SnmpPDU) (SnmpPDU, error)) error {
+	return x.CompareAndSetCtx(context.Background(), oid, update)
+}
+
+// CompareAndSetCtx is like CompareAndSet, but the requests are abandoned
+// when ctx is cancelled or its deadline passes.
+func (x *GoSNMP) CompareAndSetCtx(ctx context.Context, oid string,
+	update func(current SnmpPDU) (SnmpPDU, error)) error {
+	ctx, cancel := x.withBudget(ctx)
+	defer cancel()
+
+	current, err := x.getOne(ctx, oid)
+	if err != nil {
+		return err
+	}
+	pdu, err := update(current)
+	if err != nil {
+		return err
+	}
+	again, err := x.getOne(ctx, oid)
+	if err != nil {
+		return err
+	}
+	if again.Type != current.Type || !reflect.DeepEqual(again.Value, current.Value) {
+		return fmt.Errorf("%w: %s is %v %v, not %v %v", ErrValueChanged, oid,
+			again.Type, again.Value, current.Type, current.Value)
+	}
+
+	pdu.Name = oid
+	pdus := []SnmpPDU{pdu}
+	result, err := x.SetCtx(ctx, pdus)
+	if err != nil {
+		return err
+	}
+	if result.Error != NoError {
+		return fmt.Errorf("Unable to set %s: %w",
+			oid, newStatusError(result.Error, int(result.ErrorIndex), pdus))
+	}
+	return nil
+}
+
+// getOne gets the value of oid from the agent
+func (x *GoSNMP) getOne(ctx context.Context, oid string) (SnmpPDU, error) {
+	result, err := x.get(ctx, []string{oid})
+	if err != nil {
+		return SnmpPDU{}, err
+	}
+	if result.Error != NoError {
+		return SnmpPDU{}, fmt.Errorf("Unable to get %s: %w", oid, result.Err())
+	}
+	if len(result.Variables) != 1 {
+		return SnmpPDU{}, fmt.Errorf("Unable to get %s: %d varbinds returned", oid, len(result.Variables))
+	}
+	return result.Variables[0], nil
+}
@@ -0,0 +1,65 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCompareAndSet(t *testing.T) {
+	a, _ := newTestAgent(t)
+	defer a.Close()
+	const oid = ".1.3.6.1.4.1.99.1.0"
+	status := &testScalar{pdu: SnmpPDU{Type: Integer, Value: 1}}
+	if err := a.Register(oid, status); err != nil {
+		t.Fatalf("Register() err: %v", err)
+	}
+	x := startTestAgent(t, a, &GoSNMP{Community: "private", Version: Version2c})
+	defer x.Conn.Close()
+
+	toggle := func(current SnmpPDU) (SnmpPDU, error) {
+		n, err := current.Int64()
+		return SnmpPDU{Type: Integer, Value: 3 - int(n)}, err
+	}
+	for _, want := range []int{2, 1} {
+		if err := x.CompareAndSet(oid, toggle); err != nil {
+			t.Fatalf("CompareAndSet() err: %v", err)
+		}
+		if got := status.value(); got != want {
+			t.Errorf("got %v, want %d", got, want)
+		}
+	}
+
+	// another manager sets it while the new value is computed
+	err := x.CompareAndSet(oid, func(current SnmpPDU) (SnmpPDU, error) {
+		status.Set(SnmpPDU{Type: Integer, Value: 7})
+		return toggle(current)
+	})
+	if !errors.Is(err, ErrValueChanged) {
+		t.Errorf("CompareAndSet() err: %v, want ErrValueChanged", err)
+	}
+	if got := status.value(); got != 7 {
+		t.Errorf("got %v, want the other manager's 7", got)
+	}
+
+	// errors of update, the GET and the SET
+	refused := errors.New("refused")
+	if err = x.CompareAndSet(oid, func(SnmpPDU) (SnmpPDU, error) { return SnmpPDU{}, refused }); err != refused {
+		t.Errorf("CompareAndSet() err: %v, want update's", err)
+	}
+	if err = x.CompareAndSet(oid, func(SnmpPDU) (SnmpPDU, error) {
+		return SnmpPDU{Type: OctetString, Value: "x"}, nil
+	}); !errors.Is(err, WrongType) {
+		t.Errorf("CompareAndSet() of the wrong type err: %v, want WrongType", err)
+	}
+	var exception bool
+	if err = x.CompareAndSet(testSysDescr+".9", func(current SnmpPDU) (SnmpPDU, error) {
+		exception = current.Err() != nil
+		return SnmpPDU{Type: OctetString, Value: "x"}, nil
+	}); err == nil || !exception {
+		t.Errorf("CompareAndSet() of a missing object err: %v, update given an exception: %v", err, exception)
+	}
+}
@@ -0,0 +1,38 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+// Command gosnmpget gets the values of oids from an agent, as net-snmp's
+// snmpget does, eg
+//
+//	gosnmpget -v 2c -c public 192.0.2.1 1.3.6.1.2.1.1.1.0 1.3.6.1.2.1.1.5.0
+package main
+
+import (
+	"os"
+
+	"github.com/soniah/gosnmp/cmd/internal/cli"
+)
+
+func main() {
+	c := cli.New(os.Args[0], "AGENT OID [OID]...", 161)
+	args, err := c.Parse(os.Args[1:])
+	if err != nil {
+		os.Exit(1)
+	}
+	if len(args) < 2 {
+		c.Flags.Usage()
+		os.Exit(1)
+	}
+	x, err := c.Session(args[0])
+	if err != nil {
+		c.Fatal(err)
+	}
+	defer x.Conn.Close()
+
+	result, err := x.Get(args[1:])
+	if err != nil {
+		c.Fatal(err)
+	}
+	c.PrintResult(os.Stdout, result)
+}
@@ -0,0 +1,44 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+// Command gosnmpset sets values on an agent, as net-snmp's snmpset does,
+// eg
+//
+//	gosnmpset -v 2c -c private 192.0.2.1 1.3.6.1.2.1.1.5.0 s router
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/soniah/gosnmp/cmd/internal/cli"
+)
+
+func main() {
+	c := cli.New(os.Args[0], "AGENT OID TYPE VALUE [OID TYPE VALUE]...\n\n"+cli.Types, 161)
+	args, err := c.Parse(os.Args[1:])
+	if err != nil {
+		os.Exit(1)
+	}
+	if len(args) < 4 {
+		c.Flags.Usage()
+		os.Exit(1)
+	}
+	pdus, err := cli.ParseVarbinds(args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	x, err := c.Session(args[0])
+	if err != nil {
+		c.Fatal(err)
+	}
+	defer x.Conn.Close()
+
+	result, err := x.Set(pdus)
+	if err != nil {
+		c.Fatal(err)
+	}
+	c.PrintResult(os.Stdout, result)
+}
@@ -0,0 +1,137 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+// Command gosnmptrap sends a trap, or with -Ci an inform, as net-snmp's
+// snmptrap does, eg
+//
+//	gosnmptrap -v 2c -c public 192.0.2.1 '' 1.3.6.1.6.3.1.1.5.3 1.3.6.1.2.1.2.2.1.1.2 i 2
+//	gosnmptrap -v 1 -c public 192.0.2.1 1.3.6.1.4.1.8072 192.0.2.2 6 17 '' 1.3.6.1.2.1.1.5.0 s router
+//
+// An empty UPTIME is the one SendTrap gives SNMPv2 notifications, or 0 for
+// SNMPv1 traps.
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/soniah/gosnmp"
+	"github.com/soniah/gosnmp/cmd/internal/cli"
+)
+
+// defaultEnterprise is the enterprise of SNMPv1 traps given an empty one,
+// as for net-snmp
+const defaultEnterprise = ".1.3.6.1.4.1.3.1.1"
+
+const usage = `AGENT UPTIME TRAP-OID [OID TYPE VALUE]...
+  or with -v 1: AGENT ENTERPRISE AGENT-ADDR GENERIC-TRAP SPECIFIC-TRAP UPTIME [OID TYPE VALUE]...
+
+-C options:
+  i: send an inform, and wait for it to be acknowledged
+
+`
+
+func main() {
+	c := cli.New(os.Args[0], usage+cli.Types, 162)
+	engineID := c.Flags.String("e", "", "SNMPv3 engine `id` of the trap's sender, in hex")
+	args, err := c.Parse(os.Args[1:])
+	if err != nil {
+		os.Exit(1)
+	}
+	if len(args) < 3 {
+		c.Flags.Usage()
+		os.Exit(1)
+	}
+	x, err := c.Session(args[0])
+	if err != nil {
+		c.Fatal(err)
+	}
+	defer x.Conn.Close()
+	if *engineID != "" {
+		id, err := hex.DecodeString(strings.TrimPrefix(*engineID, "0x"))
+		if err != nil {
+			c.Fatal(fmt.Errorf("Bad engine id: %w", err))
+		}
+		x.EngineID = string(id)
+	}
+
+	if x.Version == gosnmp.Version1 {
+		err = sendV1Trap(x, args[1:])
+	} else {
+		_, inform := c.AppOption('i')
+		err = sendTrap(x, args[1:], inform)
+	}
+	if err != nil {
+		c.Fatal(err)
+	}
+}
+
+// sendTrap sends an SNMPv2 trap or inform, of the arguments UPTIME
+// TRAP-OID [OID TYPE VALUE]...
+func sendTrap(x *gosnmp.GoSNMP, args []string, inform bool) error {
+	if len(args) < 2 {
+		return fmt.Errorf("UPTIME and TRAP-OID are needed")
+	}
+	var pdus []gosnmp.SnmpPDU
+	if args[0] != "" {
+		uptime, err := strconv.ParseUint(args[0], 10, 32)
+		if err != nil {
+			return fmt.Errorf("Bad uptime %q", args[0])
+		}
+		pdus = append(pdus, gosnmp.SnmpPDU{Name: ".1.3.6.1.2.1.1.3.0", Type: gosnmp.TimeTicks, Value: uint32(uptime)})
+	}
+	trapOid, err := cli.ParseVarbind(".1.3.6.1.6.3.1.1.4.1.0", "o", args[1])
+	if err != nil {
+		return err
+	}
+	varbinds, err := cli.ParseVarbinds(args[2:])
+	if err != nil {
+		return err
+	}
+	pdus = append(append(pdus, trapOid), varbinds...)
+	if inform {
+		_, err = x.SendInform(pdus)
+		return err
+	}
+	_, err = x.SendTrap(pdus)
+	return err
+}
+
+// sendV1Trap sends an SNMPv1 trap, of the arguments ENTERPRISE AGENT-ADDR
+// GENERIC-TRAP SPECIFIC-TRAP UPTIME [OID TYPE VALUE]...
+func sendV1Trap(x *gosnmp.GoSNMP, args []string) error {
+	if len(args) < 5 {
+		return fmt.Errorf("ENTERPRISE, AGENT-ADDR, GENERIC-TRAP, SPECIFIC-TRAP and UPTIME are needed")
+	}
+	enterprise := args[0]
+	if enterprise == "" {
+		enterprise = defaultEnterprise
+	}
+	oid, err := gosnmp.ParseOid(enterprise)
+	if err != nil {
+		return fmt.Errorf("Bad enterprise: %w", err)
+	}
+	subids := make([]int, len(oid))
+	for i, n := range oid {
+		subids[i] = int(n)
+	}
+	var numbers [3]int
+	for i, arg := range args[2:5] {
+		if i == 2 && arg == "" {
+			continue
+		}
+		if numbers[i], err = strconv.Atoi(arg); err != nil || numbers[i] < 0 {
+			return fmt.Errorf("Bad number %q", arg)
+		}
+	}
+	pdus, err := cli.ParseVarbinds(args[5:])
+	if err != nil {
+		return err
+	}
+	_, err = x.SendV1Trap(pdus, subids, args[1], numbers[0], numbers[1], numbers[2])
+	return err
+}
@@ -0,0 +1,40 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+// Command gosnmpwalk walks a subtree of an agent with GETNEXT requests,
+// as net-snmp's snmpwalk does, eg
+//
+//	gosnmpwalk -v 2c -c public 192.0.2.1 1.3.6.1.2.1.2.2
+package main
+
+import (
+	"os"
+
+	"github.com/soniah/gosnmp/cmd/internal/cli"
+)
+
+func main() {
+	c := cli.New(os.Args[0], "AGENT [OID]\n\n"+cli.WalkOptions, 161)
+	args, err := c.Parse(os.Args[1:])
+	if err != nil {
+		os.Exit(1)
+	}
+	if len(args) < 1 || len(args) > 2 {
+		c.Flags.Usage()
+		os.Exit(1)
+	}
+	x, err := c.Session(args[0])
+	if err != nil {
+		c.Fatal(err)
+	}
+	defer x.Conn.Close()
+
+	oid := ""
+	if len(args) > 1 {
+		oid = args[1]
+	}
+	if err = c.Walk(os.Stdout, x, oid, false); err != nil {
+		c.Fatal(err)
+	}
+}
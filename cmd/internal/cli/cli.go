@@ -0,0 +1,303 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+// Package cli is the command line handling shared by the gosnmp commands,
+// which take the common options of the net-snmp commands they are named
+// after, eg
+//
+//	gosnmpget -v 3 -u user -l authPriv -a SHA -A authpass -x AES -X privpass host 1.3.6.1.2.1.1.1.0
+//
+// As with net-snmp, the values of single letter options may be joined to
+// them, eg -v2c or -Cr20.
+package cli
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/soniah/gosnmp"
+)
+
+// Command is a command's flags and the session they configure
+type Command struct {
+	// Flags are the command's flags: the common options, and any the
+	// command adds before calling Parse
+	Flags *flag.FlagSet
+
+	// Port is the port of agents that don't give one, eg 161
+	Port uint16
+
+	version     string
+	community   string
+	timeout     float64
+	retries     int
+	secName     string
+	secLevel    string
+	authProto   string
+	authPass    string
+	privProto   string
+	privPass    string
+	contextName string
+	output      string
+	appOptions  string
+	debug       bool
+	outputOpts  map[byte]bool
+	appOpts     map[byte]string
+}
+
+// New returns a command named name, whose arguments after the options
+// are args, eg "AGENT OID [OID]...", sending to port unless an agent
+// gives another
+func New(name, args string, port uint16) *Command {
+	c := &Command{Flags: flag.NewFlagSet(name, flag.ContinueOnError), Port: port}
+	f := c.Flags
+	f.StringVar(&c.version, "v", "2c", "SNMP `version`: 1, 2c or 3")
+	f.StringVar(&c.community, "c", "public", "`community` of SNMPv1 and SNMPv2c")
+	f.Float64Var(&c.timeout, "t", 1, "`seconds` to wait for a response")
+	f.IntVar(&c.retries, "r", 5, "number of `retries`")
+	f.StringVar(&c.secName, "u", "", "SNMPv3 security `name` (user name)")
+	f.StringVar(&c.secLevel, "l", "noAuthNoPriv", "SNMPv3 security `level`: noAuthNoPriv, authNoPriv or authPriv")
+	f.StringVar(&c.authProto, "a", "MD5", "SNMPv3 authentication `protocol`: MD5 or SHA")
+	f.StringVar(&c.authPass, "A", "", "SNMPv3 authentication `passphrase`")
+	f.StringVar(&c.privProto, "x", "DES", "SNMPv3 privacy `protocol`: DES or AES")
+	f.StringVar(&c.privPass, "X", "", "SNMPv3 privacy `passphrase`")
+	f.StringVar(&c.contextName, "n", "", "SNMPv3 context `name`")
+	f.Var((*optionLetters)(&c.output), "O", "output `options`: q quick print, Q quick print with =, v values only, t raw timeticks, x hex strings")
+	f.Var((*optionLetters)(&c.appOptions), "C", "command specific `options`")
+	f.BoolVar(&c.debug, "d", false, "log the packets sent and received")
+	f.Usage = func() {
+		fmt.Fprintf(f.Output(), "Usage: %s [OPTIONS] %s\n\n", filepath.Base(name), args)
+		f.PrintDefaults()
+	}
+	return c
+}
+
+// Parse parses the options in args, returning the arguments after them
+func (c *Command) Parse(args []string) ([]string, error) {
+	if err := c.Flags.Parse(splitOptions(c.Flags, args)); err != nil {
+		return nil, err
+	}
+	c.outputOpts = make(map[byte]bool)
+	for i := 0; i < len(c.output); i++ {
+		c.outputOpts[c.output[i]] = true
+	}
+	var err error
+	if c.appOpts, err = parseAppOptions(c.appOptions); err != nil {
+		return nil, err
+	}
+	return c.Flags.Args(), nil
+}
+
+// splitOptions returns args with the values of single letter options
+// joined to them split off, eg "-v2c" as "-v", "2c"
+func splitOptions(f *flag.FlagSet, args []string) []string {
+	var split []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" || len(arg) < 2 || arg[0] != '-' {
+			// the options have ended
+			return append(split, args[i:]...)
+		}
+		fl := f.Lookup(arg[1:2])
+		if fl == nil || isBoolFlag(fl) || arg[1] == '-' ||
+			strings.Contains(arg, "=") && f.Lookup(arg[1:strings.Index(arg, "=")]) != nil {
+			split = append(split, arg)
+			continue
+		}
+		if len(arg) > 2 {
+			split = append(split, arg[:2], arg[2:])
+			continue
+		}
+		// the value is the next argument, whatever it starts with
+		split = append(split, arg)
+		if i+1 < len(args) {
+			i++
+			split = append(split, args[i])
+		}
+	}
+	return split
+}
+
+func isBoolFlag(fl *flag.Flag) bool {
+	b, ok := fl.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// optionLetters is the value of an option given letters, which are added to
+// those of the option given before, eg -Cc -Cp as -Ccp
+type optionLetters string
+
+func (o *optionLetters) String() string { return string(*o) }
+
+func (o *optionLetters) Set(s string) error {
+	*o += optionLetters(s)
+	return nil
+}
+
+// parseAppOptions parses the -C options, letters of which r and n take a
+// number, eg "cr20"
+func parseAppOptions(s string) (map[byte]string, error) {
+	opts := make(map[byte]string)
+	for i := 0; i < len(s); i++ {
+		opt := s[i]
+		if opt != 'r' && opt != 'n' {
+			opts[opt] = ""
+			continue
+		}
+		j := i + 1
+		for j < len(s) && s[j] >= '0' && s[j] <= '9' {
+			j++
+		}
+		if j == i+1 {
+			return nil, fmt.Errorf("-C%c needs a number", opt)
+		}
+		opts[opt] = s[i+1 : j]
+		i = j - 1
+	}
+	return opts, nil
+}
+
+// AppOption returns the value of a -C option, and whether it was given
+func (c *Command) AppOption(opt byte) (string, bool) {
+	value, ok := c.appOpts[opt]
+	return value, ok
+}
+
+// AppNumber returns the number given with a -C option, eg -Cr20, or def
+func (c *Command) AppNumber(opt byte, def int) int {
+	if value, ok := c.appOpts[opt]; ok {
+		if n, err := strconv.Atoi(value); err == nil {
+			return n
+		}
+	}
+	return def
+}
+
+// Session returns a connected session to agent, "[udp:]host[:port]", as
+// the options say
+func (c *Command) Session(agent string) (*gosnmp.GoSNMP, error) {
+	host, port, err := ParseAgent(agent, c.Port)
+	if err != nil {
+		return nil, err
+	}
+	x := &gosnmp.GoSNMP{
+		Target:    host,
+		Port:      port,
+		Community: c.community,
+		Timeout:   time.Duration(c.timeout * float64(time.Second)),
+		Retries:   c.retries,
+		MaxOids:   gosnmp.MaxOids,
+	}
+	if c.debug {
+		x.Logger = log.New(os.Stderr, "", 0)
+	}
+	switch c.version {
+	case "1":
+		x.Version = gosnmp.Version1
+	case "2c":
+		x.Version = gosnmp.Version2c
+	case "3":
+		if err = c.v3(x); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("Unknown SNMP version %q", c.version)
+	}
+	if err = x.Connect(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// v3 sets up x for SNMPv3, as the options say
+func (c *Command) v3(x *gosnmp.GoSNMP) error {
+	usm := &gosnmp.UsmSecurityParameters{
+		UserName:                 c.secName,
+		AuthenticationPassphrase: c.authPass,
+		PrivacyPassphrase:        c.privPass,
+	}
+	x.Version = gosnmp.Version3
+	x.SecurityModel = gosnmp.UserSecurityModel
+	x.ContextName = c.contextName
+	x.Community = ""
+	switch strings.ToLower(c.secLevel) {
+	case "noauthnopriv", "noauth", "nanp":
+		x.MsgFlags = gosnmp.NoAuthNoPriv
+	case "authnopriv", "auth", "anp":
+		x.MsgFlags = gosnmp.AuthNoPriv
+	case "authpriv", "priv", "ap":
+		x.MsgFlags = gosnmp.AuthPriv
+	default:
+		return fmt.Errorf("Unknown security level %q", c.secLevel)
+	}
+	if x.MsgFlags&gosnmp.AuthNoPriv != 0 {
+		switch strings.ToUpper(c.authProto) {
+		case "MD5":
+			usm.AuthenticationProtocol = gosnmp.MD5
+		case "SHA", "SHA1":
+			usm.AuthenticationProtocol = gosnmp.SHA
+		default:
+			return fmt.Errorf("Unknown authentication protocol %q", c.authProto)
+		}
+	}
+	if x.MsgFlags&gosnmp.AuthPriv == gosnmp.AuthPriv {
+		switch strings.ToUpper(c.privProto) {
+		case "DES":
+			usm.PrivacyProtocol = gosnmp.DES
+		case "AES", "AES128":
+			usm.PrivacyProtocol = gosnmp.AES
+		default:
+			return fmt.Errorf("Unknown privacy protocol %q", c.privProto)
+		}
+	}
+	x.SecurityParameters = usm
+	return nil
+}
+
+// ParseAgent returns the host and port of an agent,
+// "[udp:|udp6:]host[:port]", eg "udp6:[2001:db8::1]:1161"; port is that
+// of agents without one
+func ParseAgent(agent string, port uint16) (string, uint16, error) {
+	if i := strings.Index(agent, ":"); i > 0 {
+		switch transport := strings.ToLower(agent[:i]); transport {
+		case "udp", "udp6", "udpv6", "udpipv6":
+			agent = agent[i+1:]
+		case "tcp", "tcp6", "tcpv6", "tcpipv6", "unix", "ssh", "dtlsudp", "tls", "tlstcp":
+			return "", 0, fmt.Errorf("Unsupported transport %q", transport)
+		}
+	}
+	host, portStr, err := net.SplitHostPort(agent)
+	if err != nil {
+		host = strings.TrimSuffix(strings.TrimPrefix(agent, "["), "]")
+		if host == "" {
+			return "", 0, fmt.Errorf("No agent host")
+		}
+		return host, port, nil
+	}
+	n, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil || host == "" {
+		return "", 0, fmt.Errorf("Bad agent %q", agent)
+	}
+	return host, uint16(n), nil
+}
+
+// Fatal prints err, as the command's, and exits
+func (c *Command) Fatal(err error) {
+	fmt.Fprintf(os.Stderr, "%s: %v\n", filepath.Base(c.Flags.Name()), err)
+	os.Exit(1)
+}
+
+// decodeHex decodes hex digits, ignoring spaces and a leading 0x
+func decodeHex(s string) ([]byte, error) {
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X")
+	return hex.DecodeString(strings.Join(strings.Fields(s), ""))
+}
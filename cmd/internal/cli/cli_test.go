@@ -0,0 +1,201 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package cli
+
+import (
+	"bytes"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/soniah/gosnmp"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		args    []string
+		rest    []string
+		version string
+		output  string
+		app     map[byte]string
+	}{
+		{[]string{"host", "1.3.6"}, []string{"host", "1.3.6"}, "2c", "", map[byte]string{}},
+		{[]string{"-v1", "-cprivate", "host"}, []string{"host"}, "1", "", map[byte]string{}},
+		{[]string{"-v", "3", "-A", "-secret", "host"}, []string{"host"}, "3", "", map[byte]string{}},
+		{[]string{"-v=1", "-Oqv", "-d", "host"}, []string{"host"}, "1", "qv", map[byte]string{}},
+		{[]string{"-Cr20", "-Ccp", "--", "-host"}, []string{"-host"}, "2c", "", map[byte]string{'r': "20", 'c': "", 'p': ""}},
+		{[]string{"-Cci", "-Cn1r5", "host"}, []string{"host"}, "2c", "", map[byte]string{'c': "", 'i': "", 'n': "1", 'r': "5"}},
+	}
+	for i, test := range tests {
+		c := New("test", "AGENT", 161)
+		rest, err := c.Parse(test.args)
+		if err != nil {
+			t.Fatalf("%d: Parse(%q) err: %v", i, test.args, err)
+		}
+		if !reflect.DeepEqual(rest, test.rest) || c.version != test.version || c.output != test.output ||
+			!reflect.DeepEqual(c.appOpts, test.app) {
+			t.Errorf("%d: Parse(%q) got %q, version %s, -O %s, -C %v", i, test.args, rest, c.version, c.output, c.appOpts)
+		}
+	}
+
+	if _, err := New("test", "AGENT", 161).Parse([]string{"-Cr", "host"}); err == nil {
+		t.Error("Parse() of -Cr without a number succeeded")
+	}
+}
+
+func TestParseAgent(t *testing.T) {
+	tests := []struct {
+		agent string
+		host  string
+		port  uint16
+		ok    bool
+	}{
+		{"192.0.2.1", "192.0.2.1", 161, true},
+		{"192.0.2.1:1161", "192.0.2.1", 1161, true},
+		{"udp:router.example.com:1161", "router.example.com", 1161, true},
+		{"udp6:[2001:db8::1]:1161", "2001:db8::1", 1161, true},
+		{"[2001:db8::1]", "2001:db8::1", 161, true},
+		{"2001:db8::1", "2001:db8::1", 161, true},
+		{"tcp:192.0.2.1", "", 0, false},
+		{"192.0.2.1:snmp", "", 0, false},
+		{"", "", 0, false},
+	}
+	for _, test := range tests {
+		host, port, err := ParseAgent(test.agent, 161)
+		if (err == nil) != test.ok || host != test.host || port != test.port {
+			t.Errorf("ParseAgent(%q) got %q, %d, err %v", test.agent, host, port, err)
+		}
+	}
+}
+
+func TestParseVarbind(t *testing.T) {
+	tests := []struct {
+		typ, value string
+		pdu        gosnmp.SnmpPDU
+	}{
+		{"i", "-5", gosnmp.SnmpPDU{Type: gosnmp.Integer, Value: -5}},
+		{"u", "7", gosnmp.SnmpPDU{Type: gosnmp.Gauge32, Value: uint32(7)}},
+		{"c", "0x10", gosnmp.SnmpPDU{Type: gosnmp.Counter32, Value: uint32(16)}},
+		{"t", "100", gosnmp.SnmpPDU{Type: gosnmp.TimeTicks, Value: uint32(100)}},
+		{"C", "18446744073709551615", gosnmp.SnmpPDU{Type: gosnmp.Counter64, Value: uint64(18446744073709551615)}},
+		{"a", "192.0.2.1", gosnmp.SnmpPDU{Type: gosnmp.IPAddress, Value: "192.0.2.1"}},
+		{"o", ".1.3.6.1.4.1", gosnmp.SnmpPDU{Type: gosnmp.ObjectIdentifier, Value: ".1.3.6.1.4.1"}},
+		{"s", "router", gosnmp.SnmpPDU{Type: gosnmp.OctetString, Value: []byte("router")}},
+		{"x", "0a 1B", gosnmp.SnmpPDU{Type: gosnmp.OctetString, Value: []byte{0x0a, 0x1b}}},
+		{"d", "192.0.2.1", gosnmp.SnmpPDU{Type: gosnmp.OctetString, Value: []byte{192, 0, 2, 1}}},
+		{"b", "0,9", gosnmp.SnmpPDU{Type: gosnmp.OctetString, Value: gosnmp.Bits{0x80, 0x40}}},
+		{"F", "1.5", gosnmp.SnmpPDU{Type: gosnmp.OpaqueFloat, Value: float32(1.5)}},
+		{"D", "1.5", gosnmp.SnmpPDU{Type: gosnmp.OpaqueDouble, Value: 1.5}},
+		{"n", "", gosnmp.SnmpPDU{Type: gosnmp.Null}},
+	}
+	for _, test := range tests {
+		pdu, err := ParseVarbind(".1.3.6.1.2.1.1.5.0", test.typ, test.value)
+		test.pdu.Name = ".1.3.6.1.2.1.1.5.0"
+		if err != nil || !reflect.DeepEqual(pdu, test.pdu) {
+			t.Errorf("ParseVarbind(%s, %q) got %v, err %v", test.typ, test.value, pdu, err)
+		}
+	}
+
+	for _, bad := range [][]string{
+		{"1.3.6.1", "i", "x"}, {"1.3.6.1", "i", "2147483648"}, {"1.3.6.1", "u", "-1"},
+		{"1.3.6.1", "a", "2001:db8::1"}, {"1.3.6.1", "o", "1.3.x"}, {"1.3.6.1", "x", "0g"},
+		{"1.3.6.1", "d", "256"}, {"1.3.6.1", "b", "-1"}, {"1.3.6.1", "q", "1"}, {"1.3.x", "i", "1"},
+		{"1.3.6.1", "i"},
+	} {
+		if _, err := ParseVarbinds(bad); err == nil {
+			t.Errorf("ParseVarbinds(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestFormatValue(t *testing.T) {
+	tests := []struct {
+		pdu      gosnmp.SnmpPDU
+		expected string
+	}{
+		{gosnmp.SnmpPDU{Type: gosnmp.OctetString, Value: []byte("router")}, `STRING: "router"`},
+		{gosnmp.SnmpPDU{Type: gosnmp.OctetString, Value: []byte{0, 0x1b, 0xff}}, "Hex-STRING: 00 1B FF"},
+		{gosnmp.SnmpPDU{Type: gosnmp.Integer, Value: -1}, "INTEGER: -1"},
+		{gosnmp.SnmpPDU{Type: gosnmp.Counter32, Value: uint(5)}, "Counter32: 5"},
+		{gosnmp.SnmpPDU{Type: gosnmp.Gauge32, Value: uint(5)}, "Gauge32: 5"},
+		{gosnmp.SnmpPDU{Type: gosnmp.Counter64, Value: uint64(1) << 40}, "Counter64: 1099511627776"},
+		{gosnmp.SnmpPDU{Type: gosnmp.TimeTicks, Value: uint32(8642)}, "Timeticks: (8642) 0:01:26.42"},
+		{gosnmp.SnmpPDU{Type: gosnmp.TimeTicks, Value: uint32(8640000)}, "Timeticks: (8640000) 1 day, 0:00:00.00"},
+		{gosnmp.SnmpPDU{Type: gosnmp.TimeTicks, Value: uint32(123456789)}, "Timeticks: (123456789) 14 days, 6:56:07.89"},
+		{gosnmp.SnmpPDU{Type: gosnmp.ObjectIdentifier, Value: "1.3.6.1"}, "OID: .1.3.6.1"},
+		{gosnmp.SnmpPDU{Type: gosnmp.IPAddress, Value: "192.0.2.1"}, "IpAddress: 192.0.2.1"},
+		{gosnmp.SnmpPDU{Type: gosnmp.Null}, "NULL"},
+		{gosnmp.SnmpPDU{Type: gosnmp.NoSuchObject}, "No Such Object available on this agent at this OID"},
+	}
+	for _, test := range tests {
+		if got := FormatValue(test.pdu, true, false, false); got != test.expected {
+			t.Errorf("FormatValue(%v) got %q, expected %q", test.pdu, got, test.expected)
+		}
+	}
+
+	pdu := gosnmp.SnmpPDU{Name: "1.3.6.1.2.1.1.5.0", Type: gosnmp.OctetString, Value: []byte("router")}
+	for output, expected := range map[string]string{
+		"":   ".1.3.6.1.2.1.1.5.0 = STRING: \"router\"\n",
+		"q":  ".1.3.6.1.2.1.1.5.0 router\n",
+		"Q":  ".1.3.6.1.2.1.1.5.0 = router\n",
+		"v":  "STRING: \"router\"\n",
+		"qv": "router\n",
+		"x":  ".1.3.6.1.2.1.1.5.0 = Hex-STRING: 72 6F 75 74 65 72\n",
+	} {
+		c := New("test", "AGENT", 161)
+		if _, err := c.Parse([]string{"-O" + output, "--"}); output != "" && err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		c.Print(&buf, pdu)
+		if buf.String() != expected {
+			t.Errorf("Print() with -O%s got %q, expected %q", output, buf.String(), expected)
+		}
+	}
+}
+
+func TestWalk(t *testing.T) {
+	a := &gosnmp.Agent{}
+	if err := a.RegisterSystem(&gosnmp.SystemGroup{Descr: "descr", Name: "router"}); err != nil {
+		t.Fatalf("RegisterSystem() err: %v", err)
+	}
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go a.Serve(conn)
+	defer a.Close()
+
+	for _, test := range []struct {
+		args  []string
+		oid   string
+		lines int
+		line  string
+	}{
+		{[]string{"-Cp"}, ".1.3.6.1.2.1.1", 9, `.1.3.6.1.2.1.1.5.0 = STRING: "router"`},
+		{[]string{"-Cp", "-Cr2", "-Oqv"}, ".1.3.6.1.2.1.1", 9, "Variables found: 8"},
+		{[]string{"-Cp"}, ".1.3.6.1.2.1.1.1.0", 2, `.1.3.6.1.2.1.1.1.0 = STRING: "descr"`},
+		{[]string{"-Ci"}, ".1.3.6.1.2.1.1.1", 1, `.1.3.6.1.2.1.1.1.0 = STRING: "descr"`},
+	} {
+		c := New("test", "AGENT [OID]", 161)
+		if _, err = c.Parse(test.args); err != nil {
+			t.Fatal(err)
+		}
+		for _, bulk := range []bool{false, true} {
+			x, err := c.Session(conn.LocalAddr().String())
+			if err != nil {
+				t.Fatalf("Session() err: %v", err)
+			}
+			var buf bytes.Buffer
+			err = c.Walk(&buf, x, test.oid, bulk)
+			x.Conn.Close()
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			if err != nil || len(lines) != test.lines || !strings.Contains(buf.String(), test.line+"\n") {
+				t.Errorf("Walk(%s) %q got %q, err %v", test.oid, test.args, buf.String(), err)
+			}
+		}
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/soniah/gosnmp"
+)
+
+//
+// Printing varbinds as the net-snmp commands do, eg
+//
+//	.1.3.6.1.2.1.1.5.0 = STRING: "router"
+//	.1.3.6.1.2.1.1.3.0 = Timeticks: (8642) 0:01:26.42
+//
+
+// Print prints a varbind as the -O options say
+func (c *Command) Print(w io.Writer, pdu gosnmp.SnmpPDU) {
+	quick := c.outputOpts['q'] || c.outputOpts['Q']
+	value := FormatValue(pdu, !quick, c.outputOpts['t'], c.outputOpts['x'])
+	name := "." + strings.TrimPrefix(pdu.Name, ".")
+	switch {
+	case c.outputOpts['v']:
+		fmt.Fprintln(w, value)
+	case c.outputOpts['q']:
+		fmt.Fprintln(w, name, value)
+	default:
+		fmt.Fprintln(w, name, "=", value)
+	}
+}
+
+// FormatValue formats the value of a varbind as net-snmp does, prefixed
+// with its type if typed, eg `STRING: "router"`, with TimeTicks as a
+// number if rawTicks and OctetStrings in hex if hex
+func FormatValue(pdu gosnmp.SnmpPDU, typed, rawTicks, hex bool) string {
+	var typ, value string
+	switch pdu.Type {
+	case gosnmp.OctetString:
+		b, _ := pdu.Value.([]byte)
+		if s, ok := pdu.Value.(string); ok {
+			b = []byte(s)
+		}
+		if hex || !gosnmp.PrintableOctetString(b) {
+			typ, value = "Hex-STRING", formatHex(b)
+		} else {
+			typ, value = "STRING", string(b)
+			if typed {
+				value = fmt.Sprintf("%q", value)
+			}
+		}
+	case gosnmp.Integer:
+		typ, value = "INTEGER", gosnmp.ToBigInt(pdu.Value).String()
+	case gosnmp.Counter32:
+		typ, value = "Counter32", gosnmp.ToBigInt(pdu.Value).String()
+	case gosnmp.Gauge32:
+		typ, value = "Gauge32", gosnmp.ToBigInt(pdu.Value).String()
+	case gosnmp.Uinteger32:
+		typ, value = "UInteger32", gosnmp.ToBigInt(pdu.Value).String()
+	case gosnmp.Counter64:
+		typ, value = "Counter64", gosnmp.ToBigInt(pdu.Value).String()
+	case gosnmp.TimeTicks:
+		typ, value = "Timeticks", formatTicks(gosnmp.ToBigInt(pdu.Value).Uint64(), rawTicks)
+	case gosnmp.ObjectIdentifier:
+		typ, value = "OID", "."+strings.TrimPrefix(fmt.Sprint(pdu.Value), ".")
+	case gosnmp.IPAddress:
+		typ, value = "IpAddress", fmt.Sprint(pdu.Value)
+	case gosnmp.OpaqueFloat:
+		typ, value = "Opaque: Float", fmt.Sprint(pdu.Value)
+	case gosnmp.OpaqueDouble:
+		typ, value = "Opaque: Double", fmt.Sprint(pdu.Value)
+	case gosnmp.Opaque:
+		b, _ := pdu.Value.([]byte)
+		typ, value = "OPAQUE", formatHex(b)
+	case gosnmp.Null:
+		return "NULL"
+	case gosnmp.NoSuchObject:
+		return "No Such Object available on this agent at this OID"
+	case gosnmp.NoSuchInstance:
+		return "No Such Instance currently exists at this OID"
+	case gosnmp.EndOfMibView:
+		return "No more variables left in this MIB View (It is past the end of the MIB tree)"
+	default:
+		typ, value = pdu.Type.String(), fmt.Sprint(pdu.Value)
+	}
+	if !typed {
+		return value
+	}
+	return typ + ": " + value
+}
+
+// formatHex formats b as net-snmp's Hex-STRINGs are, eg "0A 1B"
+func formatHex(b []byte) string {
+	parts := make([]string, len(b))
+	for i, c := range b {
+		parts[i] = fmt.Sprintf("%02X", c)
+	}
+	return strings.Join(parts, " ")
+}
+
+// formatTicks formats TimeTicks, hundredths of a second, as net-snmp
+// does, eg "(8642) 0:01:26.42", or as the number if raw
+func formatTicks(ticks uint64, raw bool) string {
+	if raw {
+		return fmt.Sprint(ticks)
+	}
+	days := ticks / (100 * 60 * 60 * 24)
+	rest := ticks % (100 * 60 * 60 * 24)
+	s := fmt.Sprintf("%d:%02d:%02d.%02d", rest/(100*60*60), rest/(100*60)%60, rest/100%60, rest%100)
+	switch {
+	case days == 1:
+		s = "1 day, " + s
+	case days > 1:
+		s = fmt.Sprintf("%d days, %s", days, s)
+	}
+	return fmt.Sprintf("(%d) %s", ticks, s)
+}
+
+// PrintResult prints the varbinds of a response, or if it has an error
+// status, the error, and exits
+func (c *Command) PrintResult(w io.Writer, result *gosnmp.SnmpPacket) {
+	if err := result.Err(); err != nil {
+		c.Fatal(fmt.Errorf("Error in packet: %w", err))
+	}
+	for _, pdu := range result.Variables {
+		c.Print(w, pdu)
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package cli
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/soniah/gosnmp"
+)
+
+//
+// Parsing "OID TYPE VALUE" varbinds, as snmpset and snmptrap take them
+//
+
+// Types is the usage of the TYPE of varbinds
+const Types = `TYPE is one of
+  i: INTEGER, u: Gauge32, c: Counter32, C: Counter64, t: TIMETICKS,
+  a: IPADDRESS, o: OBJID, s: STRING, x: HEX STRING, d: DECIMAL STRING,
+  b: BITS (bit numbers), F: float, D: double, n: NULL`
+
+// ParseVarbinds parses args, triples of oid, type and value
+func ParseVarbinds(args []string) ([]gosnmp.SnmpPDU, error) {
+	if len(args)%3 != 0 {
+		return nil, fmt.Errorf("Varbinds are OID TYPE VALUE, %d arguments given", len(args))
+	}
+	var pdus []gosnmp.SnmpPDU
+	for i := 0; i < len(args); i += 3 {
+		pdu, err := ParseVarbind(args[i], args[i+1], args[i+2])
+		if err != nil {
+			return nil, err
+		}
+		pdus = append(pdus, pdu)
+	}
+	return pdus, nil
+}
+
+// ParseVarbind parses the varbind of oid, of type typ, eg "i", and value
+func ParseVarbind(oid, typ, value string) (gosnmp.SnmpPDU, error) {
+	if _, err := gosnmp.ParseOid(oid); err != nil {
+		return gosnmp.SnmpPDU{}, err
+	}
+	pdu := gosnmp.SnmpPDU{Name: oid}
+	var err error
+	switch typ {
+	case "i":
+		var n int64
+		if n, err = strconv.ParseInt(value, 0, 32); err == nil {
+			pdu.Type, pdu.Value = gosnmp.Integer, int(n)
+		}
+	case "u", "c", "t":
+		var n uint64
+		if n, err = strconv.ParseUint(value, 0, 32); err == nil {
+			pdu.Type = map[string]gosnmp.Asn1BER{"u": gosnmp.Gauge32, "c": gosnmp.Counter32, "t": gosnmp.TimeTicks}[typ]
+			pdu.Value = uint32(n)
+		}
+	case "C":
+		var n uint64
+		if n, err = strconv.ParseUint(value, 0, 64); err == nil {
+			pdu.Type, pdu.Value = gosnmp.Counter64, n
+		}
+	case "a":
+		if ip := net.ParseIP(value).To4(); ip == nil {
+			err = fmt.Errorf("not an IPv4 address")
+		} else {
+			pdu.Type, pdu.Value = gosnmp.IPAddress, ip.String()
+		}
+	case "o":
+		if _, err = gosnmp.ParseOid(value); err == nil {
+			pdu.Type, pdu.Value = gosnmp.ObjectIdentifier, value
+		}
+	case "s":
+		pdu.Type, pdu.Value = gosnmp.OctetString, []byte(value)
+	case "x":
+		var b []byte
+		if b, err = decodeHex(value); err == nil {
+			pdu.Type, pdu.Value = gosnmp.OctetString, b
+		}
+	case "d":
+		var b []byte
+		for _, field := range strings.FieldsFunc(value, func(r rune) bool { return r == '.' || r == ' ' }) {
+			var n uint64
+			if n, err = strconv.ParseUint(field, 10, 8); err != nil {
+				break
+			}
+			b = append(b, byte(n))
+		}
+		pdu.Type, pdu.Value = gosnmp.OctetString, b
+	case "b":
+		var positions []int
+		for _, field := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' }) {
+			var n int
+			if n, err = strconv.Atoi(field); err != nil || n < 0 {
+				err = fmt.Errorf("bad bit number %q", field)
+				break
+			}
+			positions = append(positions, n)
+		}
+		pdu.Type, pdu.Value = gosnmp.OctetString, gosnmp.BitsFromPositions(positions...)
+	case "F":
+		var f float64
+		if f, err = strconv.ParseFloat(value, 32); err == nil {
+			pdu.Type, pdu.Value = gosnmp.OpaqueFloat, float32(f)
+		}
+	case "D":
+		var f float64
+		if f, err = strconv.ParseFloat(value, 64); err == nil && !math.IsInf(f, 0) {
+			pdu.Type, pdu.Value = gosnmp.OpaqueDouble, f
+		}
+	case "n":
+		pdu.Type = gosnmp.Null
+	default:
+		return pdu, fmt.Errorf("Unknown type %q of %s", typ, oid)
+	}
+	if err != nil {
+		return pdu, fmt.Errorf("Bad value %q of %s: %w", value, oid, err)
+	}
+	return pdu, nil
+}
@@ -0,0 +1,88 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package cli
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/soniah/gosnmp"
+)
+
+// defaultWalkOid is the subtree walked if none is given, mib-2
+const defaultWalkOid = ".1.3.6.1.2.1"
+
+// WalkOptions and BulkWalkOptions are the usage of the -C options of
+// walks
+const (
+	WalkOptions = `-C options:
+  c: don't check that the oids returned are increasing
+  i: include the value of OID itself, if it has one
+  p: print the number of variables found`
+	BulkWalkOptions = WalkOptions + `
+  r<N>: the max-repetitions of the GETBULK requests (default: 10)`
+)
+
+// Walk walks the subtree at oid ("" for mib-2) with GETNEXTs, or GETBULKs of
+// -Cr max-repetitions if bulk, printing its varbinds as snmpwalk does. A
+// subtree with no varbinds under it is printed as a get of oid would be.
+func (c *Command) Walk(w io.Writer, x *gosnmp.GoSNMP, oid string, bulk bool) error {
+	if oid == "" {
+		oid = defaultWalkOid
+	}
+	if _, ok := c.AppOption('c'); ok {
+		x.OidOrder = gosnmp.OidOrderTolerate
+	}
+	if n := c.AppNumber('r', 10); bulk && n > 0 {
+		if n > 255 {
+			n = 255
+		}
+		x.MaxRepetitions = uint8(n)
+	}
+
+	count := 0
+	if _, ok := c.AppOption('i'); ok {
+		if err := c.printInstance(w, x, oid, &count); err != nil {
+			return err
+		}
+	}
+	walk := x.Walk
+	if bulk {
+		walk = x.BulkWalk
+	}
+	found := count
+	err := walk(oid, func(pdu gosnmp.SnmpPDU) error {
+		c.Print(w, pdu)
+		count++
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if count == found && found == 0 {
+		// eg a scalar's instance
+		if err = c.printInstance(w, x, oid, &count); err != nil {
+			return err
+		}
+	}
+	if _, ok := c.AppOption('p'); ok {
+		fmt.Fprintf(w, "Variables found: %d\n", count)
+	}
+	return nil
+}
+
+// printInstance prints the value of oid, if it has one
+func (c *Command) printInstance(w io.Writer, x *gosnmp.GoSNMP, oid string, count *int) error {
+	result, err := x.Get([]string{oid})
+	if err != nil {
+		return err
+	}
+	if result.Err() != nil || len(result.Variables) != 1 || result.Variables[0].Err() != nil {
+		return nil
+	}
+	c.Print(w, result.Variables[0])
+	*count++
+	return nil
+}
@@ -0,0 +1,58 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// CounterDelta returns how much a counter has increased between two
+// successive samples of it, prev and cur, allowing for it wrapping: a
+// Counter32 that goes from 4294967290 to 5 has increased by 11. Both
+// samples must be Counter32, or both Counter64.
+//
+// A counter that wraps more than once between samples, or that is reset
+// (eg by the agent restarting, see sysUpTime or the object's
+// discontinuity time), can't be told from one that wrapped once; sample
+// 32 bit counters often enough that they can't wrap twice - on a 10Gbit/s
+// interface ifInOctets wraps in under 4 seconds, so use the 64 bit ifHC
+// counters there.
+func CounterDelta(prev, cur SnmpPDU) (uint64, error) {
+	if prev.Type != cur.Type {
+		return 0, fmt.Errorf("Counter samples have different types %#x and %#x", byte(prev.Type), byte(cur.Type))
+	}
+	p := ToBigInt(prev.Value)
+	c := ToBigInt(cur.Value)
+	if !p.IsUint64() || !c.IsUint64() {
+		return 0, fmt.Errorf("Counter samples %v and %v aren't unsigned", prev.Value, cur.Value)
+	}
+	p64, c64 := p.Uint64(), c.Uint64()
+
+	switch cur.Type {
+	case Counter32:
+		if p64 > math.MaxUint32 || c64 > math.MaxUint32 {
+			return 0, fmt.Errorf("Counter32 samples %d and %d out of range", p64, c64)
+		}
+		return uint64(uint32(c64) - uint32(p64)), nil
+	case Counter64:
+		return c64 - p64, nil
+	}
+	return 0, fmt.Errorf("Not a counter type: %#x", byte(cur.Type))
+}
+
+// CounterRate returns the rate per second at which a counter increased
+// between two samples taken interval apart, see CounterDelta
+func CounterRate(prev, cur SnmpPDU, interval time.Duration) (float64, error) {
+	if interval <= 0 {
+		return 0, fmt.Errorf("Invalid interval between counter samples %v", interval)
+	}
+	delta, err := CounterDelta(prev, cur)
+	if err != nil {
+		return 0, err
+	}
+	return float64(delta) / interval.Seconds(), nil
+}
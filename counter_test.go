@@ -0,0 +1,46 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestCounterDelta(t *testing.T) {
+	tests := []struct {
+		prev, cur SnmpPDU
+		want      uint64
+		err       bool
+	}{
+		{SnmpPDU{Type: Counter32, Value: uint(100)}, SnmpPDU{Type: Counter32, Value: uint(250)}, 150, false},
+		{SnmpPDU{Type: Counter32, Value: uint(4294967290)}, SnmpPDU{Type: Counter32, Value: uint(5)}, 11, false},
+		{SnmpPDU{Type: Counter32, Value: uint(7)}, SnmpPDU{Type: Counter32, Value: uint(7)}, 0, false},
+		{SnmpPDU{Type: Counter64, Value: uint64(math.MaxUint64 - 1)}, SnmpPDU{Type: Counter64, Value: uint64(3)}, 5, false},
+		{SnmpPDU{Type: Counter64, Value: uint64(1 << 40)}, SnmpPDU{Type: Counter64, Value: uint64(1<<40 + 9)}, 9, false},
+		{SnmpPDU{Type: Counter32, Value: uint(1)}, SnmpPDU{Type: Counter64, Value: uint64(2)}, 0, true},
+		{SnmpPDU{Type: Gauge32, Value: uint(1)}, SnmpPDU{Type: Gauge32, Value: uint(2)}, 0, true},
+		{SnmpPDU{Type: Counter32, Value: uint64(1 << 32)}, SnmpPDU{Type: Counter32, Value: uint(2)}, 0, true},
+		{SnmpPDU{Type: Counter32, Value: -1}, SnmpPDU{Type: Counter32, Value: uint(2)}, 0, true},
+	}
+	for i, test := range tests {
+		got, err := CounterDelta(test.prev, test.cur)
+		if got != test.want || (err != nil) != test.err {
+			t.Errorf("%d: got %d, %v, want %d", i, got, err, test.want)
+		}
+	}
+}
+
+func TestCounterRate(t *testing.T) {
+	prev := SnmpPDU{Type: Counter32, Value: uint(4294967000)}
+	cur := SnmpPDU{Type: Counter32, Value: uint(704)}
+	if rate, err := CounterRate(prev, cur, 10*time.Second); err != nil || rate != 100 {
+		t.Errorf("got %v, %v, want 100", rate, err)
+	}
+	if _, err := CounterRate(prev, cur, 0); err == nil {
+		t.Errorf("expected an error for a zero interval")
+	}
+}
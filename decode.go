@@ -0,0 +1,139 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+)
+
+//
+// Offline decoding and encoding
+//
+
+// Decode decodes an SNMP message of any version and PDU type, eg one
+// read from a packet capture, without sending anything. SNMPv3 messages
+// are authenticated and decrypted, as their flags say, with the
+// credentials in x.SecurityParameters, localized to the message's
+// authoritative engine. x needn't be connected, and msg isn't changed.
+//
+// A message failing authentication fails with ErrAuthentication, and one
+// that can't be decoded with ErrDecode. The packet's SecurityParameters
+// hold the localized keys, so that it can be encoded again with Encode.
+func (x *GoSNMP) Decode(msg []byte) (result *SnmpPacket, err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			result, err = nil, fmt.Errorf("%w: recover: %v", ErrDecode, e)
+		}
+	}()
+	// copied, as the authentication parameters are blanked to check them
+	msg = append([]byte(nil), msg...)
+
+	var sp SnmpV3SecurityParameters = new(UsmSecurityParameters)
+	if x.SecurityParameters != nil {
+		sp = x.SecurityParameters.Copy()
+	}
+	usm, isUsm := sp.(*UsmSecurityParameters)
+	if isUsm && usm.AuthoritativeEngineID != "" {
+		// unmarshalling localizes the keys to engines other than this one
+		usm.localizeKeys()
+	}
+	if isUsm && usm.Logger == nil {
+		usm.Logger = log.New(ioutil.Discard, "", 0)
+	}
+	result = &SnmpPacket{Logger: x.Logger, SecurityParameters: sp}
+
+	cursor, err := x.unmarshalHeader(msg, result)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDecode, err)
+	}
+	// replaced if the message is of another security model
+	usm, isUsm = result.SecurityParameters.(*UsmSecurityParameters)
+	if result.Version == Version3 {
+		if isUsm && result.MsgFlags&AuthNoPriv > 0 && usm.AuthenticationProtocol <= NoAuth {
+			return nil, fmt.Errorf("%w: no AuthenticationProtocol to check the message with", ErrAuthentication)
+		}
+		if isUsm && result.MsgFlags&AuthPriv > AuthNoPriv && usm.PrivacyProtocol <= NoPriv {
+			return nil, fmt.Errorf("%w: no PrivacyProtocol to decrypt the message with", ErrDecode)
+		}
+		if result.MsgFlags&AuthNoPriv > 0 {
+			authentic, err := sp.isAuthentic(msg, result)
+			if err != nil {
+				return nil, err
+			}
+			if !authentic {
+				return nil, ErrAuthentication
+			}
+		}
+		msg, cursor, err = x.decryptPacket(msg, cursor, result)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrDecode, err)
+		}
+	}
+	if cursor >= len(msg) {
+		return nil, fmt.Errorf("%w: no PDU", ErrDecode)
+	}
+
+	result.PDUType = PDUType(msg[cursor])
+	switch result.PDUType {
+	case Trap:
+		err = x.unmarshalTrapV1(msg[cursor:], result)
+	case GetRequest, GetNextRequest, GetResponse, SetRequest, GetBulkRequest,
+		InformRequest, SNMPv2Trap, Report:
+		err = x.unmarshalResponse(msg[cursor:], result)
+	default:
+		err = fmt.Errorf("Unknown PDUType %#x", byte(result.PDUType))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDecode, err)
+	}
+	return result, nil
+}
+
+// Encode encodes packet as an SNMP message, eg to replay a packet from
+// Decode, or to make up messages for testing agents and decoders. SNMPv3
+// messages are authenticated and encrypted as their MsgFlags say, with the
+// credentials of the packet's SecurityParameters (or if it has none, of
+// x's) localized to their AuthoritativeEngineID, and the salt in their
+// PrivacyParameters. Values are encoded as for a Set, which some types
+// (eg Counter64) can't be.
+func (x *GoSNMP) Encode(packet *SnmpPacket) ([]byte, error) {
+	if packet.Version == Version3 {
+		sp := packet.SecurityParameters
+		if sp == nil {
+			sp = x.SecurityParameters
+		}
+		if sp == nil {
+			return nil, fmt.Errorf("SNMPv3 packet has no SecurityParameters")
+		}
+		sp = sp.Copy()
+		if usm, ok := sp.(*UsmSecurityParameters); ok {
+			usm.localizeKeys()
+			if usm.Logger == nil {
+				usm.Logger = log.New(ioutil.Discard, "", 0)
+			}
+		}
+		p := *packet
+		p.SecurityParameters = sp
+		packet = &p
+	}
+	return packet.marshalMsg()
+}
+
+// localizeKeys generates the keys from the passphrases, for
+// AuthoritativeEngineID, unless they have been already
+func (sp *UsmSecurityParameters) localizeKeys() {
+	if sp.AuthenticationProtocol > NoAuth && len(sp.secretKey) == 0 {
+		sp.secretKey = genlocalkey(sp.AuthenticationProtocol,
+			sp.AuthenticationPassphrase,
+			sp.AuthoritativeEngineID)
+	}
+	if sp.PrivacyProtocol > NoPriv && len(sp.privacyKey) == 0 {
+		sp.privacyKey = genlocalkey(sp.AuthenticationProtocol,
+			sp.PrivacyPassphrase,
+			sp.AuthoritativeEngineID)
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestDecodeEncode(t *testing.T) {
+	x := &GoSNMP{}
+	tests := []struct {
+		name   string
+		packet *SnmpPacket
+	}{
+		{"get", &SnmpPacket{Version: Version2c, Community: "public", PDUType: GetRequest, RequestID: 1,
+			Variables: []SnmpPDU{{Name: ".1.3.6.1.2.1.1.1.0", Type: Null}}}},
+		{"set", &SnmpPacket{Version: Version1, Community: "private", PDUType: SetRequest, RequestID: 2,
+			Variables: []SnmpPDU{{Name: ".1.3.6.1.2.1.1.5.0", Type: OctetString, Value: []byte("name")}}}},
+		{"bulk", &SnmpPacket{Version: Version2c, Community: "public", PDUType: GetBulkRequest, RequestID: 3,
+			MaxRepetitions: 10, Variables: []SnmpPDU{{Name: ".1.3.6.1.2.1.2", Type: Null}}}},
+		{"response", &SnmpPacket{Version: Version2c, Community: "public", PDUType: GetResponse, RequestID: 4,
+			Error: NoSuchName, ErrorIndex: 1,
+			Variables: []SnmpPDU{{Name: ".1.3.6.1.2.1.1.3.0", Type: TimeTicks, Value: uint32(42)}}}},
+		{"inform", &SnmpPacket{Version: Version2c, Community: "public", PDUType: InformRequest, RequestID: 5,
+			Variables: []SnmpPDU{{Name: ".1.3.6.1.2.1.1.3.0", Type: TimeTicks, Value: uint32(42)}}}},
+		{"trap", &SnmpPacket{Version: Version1, Community: "public", PDUType: Trap,
+			Enterprise: []int{1, 3, 6, 1, 4, 1, 8072}, AgentAddr: "192.0.2.1", GenericTrap: 6, SpecificTrap: 2,
+			Timestamp: 300, Variables: []SnmpPDU{{Name: ".1.3.6.1.2.1.1.5.0", Type: OctetString, Value: []byte("name")}}}},
+	}
+	for _, test := range tests {
+		msg, err := x.Encode(test.packet)
+		if err != nil {
+			t.Errorf("%s: Encode() err: %v", test.name, err)
+			continue
+		}
+		decoded, err := x.Decode(msg)
+		if err != nil {
+			t.Errorf("%s: Decode() err: %v", test.name, err)
+			continue
+		}
+		if got, want := decoded.String(), test.packet.String(); got != want {
+			t.Errorf("%s: decoded %s, expected %s", test.name, got, want)
+		}
+		if decoded.Community != test.packet.Community {
+			t.Errorf("%s: decoded community %q, expected %q", test.name, decoded.Community, test.packet.Community)
+		}
+		again, err := x.Encode(decoded)
+		if err != nil || !bytes.Equal(again, msg) {
+			t.Errorf("%s: encoded again to % x, err %v, expected % x", test.name, again, err, msg)
+		}
+	}
+}
+
+func TestDecodeV3(t *testing.T) {
+	credentials := func(authPass string) *UsmSecurityParameters {
+		return &UsmSecurityParameters{
+			UserName:                 "user",
+			AuthenticationProtocol:   SHA,
+			AuthenticationPassphrase: authPass,
+			PrivacyProtocol:          AES,
+			PrivacyPassphrase:        "privpassphrase",
+		}
+	}
+	x := &GoSNMP{
+		Version:            Version3,
+		MsgFlags:           AuthPriv,
+		SecurityModel:      UserSecurityModel,
+		SecurityParameters: credentials("authpassphrase"),
+	}
+	if err := x.validateParameters(); err != nil {
+		t.Fatalf("validateParameters() err: %v", err)
+	}
+	discovered := &SnmpPacket{
+		Version:            Version3,
+		SecurityModel:      UserSecurityModel,
+		SecurityParameters: &UsmSecurityParameters{AuthoritativeEngineID: "engine", AuthoritativeEngineBoots: 3},
+	}
+	if err := x.storeSecurityParameters(discovered); err != nil {
+		t.Fatalf("storeSecurityParameters() err: %v", err)
+	}
+	const oid = ".1.3.6.1.2.1.1.1.0"
+	packet := x.mkSnmpPacket(GetRequest, []SnmpPDU{{Name: oid, Type: Null}}, 0, 0)
+	packet.RequestID, packet.MsgID = 7, 8
+	if err := x.initPacket(packet); err != nil {
+		t.Fatalf("initPacket() err: %v", err)
+	}
+	msg, err := packet.marshalMsg()
+	if err != nil {
+		t.Fatalf("marshalMsg() err: %v", err)
+	}
+	original := append([]byte(nil), msg...)
+
+	// a decoder with just the credentials, not connected or discovered
+	decoder := &GoSNMP{SecurityParameters: credentials("authpassphrase")}
+	decoded, err := decoder.Decode(msg)
+	if err != nil {
+		t.Fatalf("Decode() err: %v", err)
+	}
+	if !bytes.Equal(msg, original) {
+		t.Errorf("Decode() changed the message")
+	}
+	sp := decoded.SecurityParameters.(*UsmSecurityParameters)
+	if decoded.Version != Version3 || decoded.MsgFlags&AuthPriv != AuthPriv || decoded.MsgID != 8 ||
+		decoded.RequestID != 7 || sp.AuthoritativeEngineID != "engine" || sp.AuthoritativeEngineBoots != 3 {
+		t.Errorf("decoded %s", decoded)
+	}
+	if len(decoded.Variables) != 1 || decoded.Variables[0].Name != oid {
+		t.Errorf("decoded varbinds %v, expected %s", decoded.Variables, oid)
+	}
+	again, err := decoder.Encode(decoded)
+	if err != nil || !bytes.Equal(again, msg) {
+		t.Errorf("encoded again to % x, err %v, expected % x", again, err, msg)
+	}
+
+	wrong := &GoSNMP{SecurityParameters: credentials("wrongpassphrase")}
+	if _, err = wrong.Decode(msg); !errors.Is(err, ErrAuthentication) {
+		t.Errorf("Decode() with the wrong passphrase got err %v, expected ErrAuthentication", err)
+	}
+	noCredentials := &GoSNMP{}
+	if _, err = noCredentials.Decode(msg); !errors.Is(err, ErrAuthentication) {
+		t.Errorf("Decode() without credentials got err %v, expected ErrAuthentication", err)
+	}
+}
+
+func TestDecodeMalformed(t *testing.T) {
+	x := &GoSNMP{}
+	msg, err := x.Encode(&SnmpPacket{Version: Version2c, Community: "public", PDUType: GetRequest, RequestID: 1,
+		Variables: []SnmpPDU{{Name: ".1.3.6.1.2.1.1.1.0", Type: Null}}})
+	if err != nil {
+		t.Fatalf("Encode() err: %v", err)
+	}
+	for n := 0; n < len(msg); n++ {
+		if _, err := x.Decode(msg[:n]); !errors.Is(err, ErrDecode) {
+			t.Errorf("Decode() of %d of %d bytes got err %v, expected ErrDecode", n, len(msg), err)
+		}
+	}
+	if _, err := x.Encode(&SnmpPacket{Version: Version3, PDUType: GetRequest}); err == nil {
+		t.Errorf("Encode() of an SNMPv3 packet without SecurityParameters succeeded")
+	}
+}
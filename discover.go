@@ -0,0 +1,201 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+//
+// Discovering the agents of a LAN, with a request to a broadcast or
+// multicast address
+//
+
+const (
+	sysDescrOid    = ".1.3.6.1.2.1.1.1.0"
+	sysObjectIDOid = ".1.3.6.1.2.1.1.2.0"
+)
+
+// Responder is an agent that answered Discover
+type Responder struct {
+	// Addr is the address the response came from
+	Addr *net.UDPAddr
+
+	// RTT is the time from sending the request to receiving the response
+	RTT time.Duration
+
+	// SysDescr and SysObjectID are the agent's sysDescr.0 and
+	// sysObjectID.0, for SNMPv1 and SNMPv2c; empty if the agent didn't
+	// return them
+	SysDescr    string
+	SysObjectID string
+
+	// EngineID, EngineBoots and EngineTime are those of the agent's
+	// SNMPv3 engine, from its report to an SNMPv3 discovery
+	EngineID    string
+	EngineBoots uint32
+	EngineTime  uint32
+}
+
+// Discover sends one request to x.Target and x.Port, a broadcast or
+// multicast address (or any other), from an unconnected socket, and
+// returns the agents that answer within window, in the order they
+// answered: the responses may come from any address. For SNMPv1 and
+// SNMPv2c the request is a GET of sysDescr.0 and sysObjectID.0 with x's
+// Community; for SNMPv3 it is the empty request that agents answer with a
+// report of their snmpEngineID, and x's SecurityParameters aren't needed.
+// The socket is bound to LocalAddr and LocalPort, if set; x needn't be
+// connected, and isn't changed.
+//
+// Agents answering more than once are returned once, and responses that
+// aren't to the request are ignored.
+func (x *GoSNMP) Discover(window time.Duration) ([]Responder, error) {
+	return x.DiscoverCtx(context.Background(), window)
+}
+
+// DiscoverCtx is like Discover, but stops collecting responses when ctx is
+// cancelled or its deadline passes, returning those collected and
+// ctx.Err().
+func (x *GoSNMP) DiscoverCtx(ctx context.Context, window time.Duration) ([]Responder, error) {
+	to, err := net.ResolveUDPAddr("udp", net.JoinHostPort(x.Target, strconv.Itoa(int(x.Port))))
+	if err != nil {
+		return nil, fmt.Errorf("Error resolving discovery address: %w", err)
+	}
+	network := "udp6"
+	if to.IP.To4() != nil {
+		network = "udp4"
+	}
+	var local *net.UDPAddr
+	if x.LocalAddr != "" || x.LocalPort != 0 {
+		localAddr := net.JoinHostPort(x.LocalAddr, strconv.Itoa(int(x.LocalPort)))
+		if local, err = net.ResolveUDPAddr(network, localAddr); err != nil {
+			return nil, fmt.Errorf("Error resolving local address %s: %w", localAddr, err)
+		}
+	}
+	// udp sockets are opened with SO_BROADCAST set
+	conn, err := net.ListenUDP(network, local)
+	if err != nil {
+		return nil, fmt.Errorf("Error opening discovery socket: %w", err)
+	}
+	defer conn.Close()
+
+	// responses are decoded without x's security parameters, which the
+	// SNMPv3 reports don't need
+	logger := x.Logger
+	if logger == nil {
+		logger = log.New(ioutil.Discard, "", 0)
+	}
+	decoder := &GoSNMP{Logger: logger, loggingEnabled: x.loggingEnabled, LeveledLogger: x.LeveledLogger,
+		Lenient: x.Lenient}
+
+	id := uint32(rand.Int31n(maxID)) + 1
+	request := &SnmpPacket{
+		Version:   x.Version,
+		Community: x.Community,
+		PDUType:   GetRequest,
+		RequestID: id,
+		Logger:    logger,
+		Variables: []SnmpPDU{{Name: sysDescrOid, Type: Null}, {Name: sysObjectIDOid, Type: Null}},
+	}
+	if x.Version == Version3 {
+		request = (&UsmSecurityParameters{Logger: logger}).discoveryRequired()
+		request.MsgID, request.RequestID = id, id
+	}
+	out, err := request.marshalMsg()
+	if err != nil {
+		return nil, fmt.Errorf("Error marshalling discovery request: %w", err)
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.SetReadDeadline(time.Now())
+		case <-done:
+		}
+	}()
+	start := time.Now()
+	if err = conn.SetReadDeadline(start.Add(window)); err != nil {
+		return nil, err
+	}
+	if _, err = conn.WriteToUDP(out, to); err != nil {
+		return nil, fmt.Errorf("Error sending discovery request: %w", err)
+	}
+
+	var found []Responder
+	seen := make(map[string]bool)
+	buf := make([]byte, rxBufSize)
+	for {
+		n, from, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return found, ctx.Err()
+			}
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				return found, nil
+			}
+			if isICMPError(err) {
+				// eg a host of a multicast group without an agent
+				continue
+			}
+			return found, err
+		}
+		response, err := decoder.Decode(buf[:n])
+		if err != nil {
+			decoder.logPrintf("Discover: ignoring the response from %v: %v", from, err)
+			continue
+		}
+		r, ok := discovered(request, response)
+		if !ok || seen[from.String()] {
+			continue
+		}
+		seen[from.String()] = true
+		r.Addr, r.RTT = from, time.Since(start)
+		found = append(found, r)
+	}
+}
+
+// discovered returns the Responder of response, and whether it is a
+// response to request
+func discovered(request, response *SnmpPacket) (Responder, bool) {
+	var r Responder
+	if response.Version != request.Version {
+		return r, false
+	}
+	if request.Version == Version3 {
+		usm, ok := response.SecurityParameters.(*UsmSecurityParameters)
+		if !ok || response.MsgID != request.MsgID || usm.AuthoritativeEngineID == "" {
+			return r, false
+		}
+		r.EngineID = usm.AuthoritativeEngineID
+		r.EngineBoots, r.EngineTime = usm.AuthoritativeEngineBoots, usm.AuthoritativeEngineTime
+		return r, true
+	}
+	if response.PDUType != GetResponse || response.RequestID != request.RequestID ||
+		response.Community != request.Community {
+		return r, false
+	}
+	for _, pdu := range response.Variables {
+		switch {
+		case pdu.Name == sysDescrOid && pdu.Type == OctetString:
+			if b, ok := pdu.Value.([]byte); ok {
+				r.SysDescr = string(b)
+			}
+		case pdu.Name == sysObjectIDOid && pdu.Type == ObjectIdentifier:
+			r.SysObjectID, _ = pdu.Text()
+		}
+	}
+	return r, true
+}
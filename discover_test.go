@@ -0,0 +1,129 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+// startTestBroadcast listens on a local address standing in for a
+// broadcast address: each request it receives is answered by each of
+// agents, from an address of its own, as the agents of a LAN answer a
+// broadcast. The first agent answers twice.
+func startTestBroadcast(t *testing.T, agents ...*Agent) *GoSNMP {
+	t.Helper()
+	listen := func() net.PacketConn {
+		conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("ListenPacket() err: %v", err)
+		}
+		return conn
+	}
+	target := listen()
+	t.Cleanup(func() { target.Close() })
+	conns := make([]net.PacketConn, len(agents))
+	for i, a := range agents {
+		a.init()
+		conns[i] = listen()
+		t.Cleanup(func() { conns[i].Close() })
+	}
+	go func() {
+		buf := make([]byte, rxBufSize)
+		for {
+			n, addr, err := target.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			target.WriteTo([]byte("not snmp"), addr)
+			for i, a := range agents {
+				if response := a.handle(buf[:n]); response != nil {
+					conns[i].WriteTo(response, addr)
+					if i == 0 {
+						conns[i].WriteTo(response, addr)
+					}
+				}
+			}
+		}
+	}()
+	return &GoSNMP{
+		Target:    "127.0.0.1",
+		Port:      uint16(target.LocalAddr().(*net.UDPAddr).Port),
+		Community: "public",
+	}
+}
+
+func newTestDiscoverAgent(t *testing.T, descr, engineID string) *Agent {
+	a := &Agent{EngineID: engineID}
+	if err := a.Register(testSysDescr, AgentHandlerFunc(func(string) (SnmpPDU, error) {
+		return SnmpPDU{Type: OctetString, Value: []byte(descr)}, nil
+	})); err != nil {
+		t.Fatalf("Register() err: %v", err)
+	}
+	return a
+}
+
+func TestDiscover(t *testing.T) {
+	tests := []struct {
+		version SnmpVersion
+		descrs  []string
+		engines []string
+	}{
+		{Version1, nil, nil},
+		{Version2c, []string{"first", "second"}, []string{"", ""}},
+		{Version3, []string{"", ""}, []string{"engine1", "engine2"}},
+	}
+	for _, test := range tests {
+		x := startTestBroadcast(t,
+			newTestDiscoverAgent(t, "first", "engine1"),
+			newTestDiscoverAgent(t, "second", "engine2"))
+		x.Version = test.version
+		found, err := x.Discover(200 * time.Millisecond)
+		if err != nil {
+			t.Errorf("%v: Discover() err: %v", test.version, err)
+			continue
+		}
+		if test.version == Version1 {
+			// the agents don't have sysObjectID.0, and answer noSuchName
+			if len(found) != 2 || found[0].SysDescr != "" {
+				t.Errorf("%v: Discover() = %+v, expected 2 responders without sysDescr", test.version, found)
+			}
+			continue
+		}
+		if len(found) != len(test.descrs) {
+			t.Errorf("%v: Discover() found %d responders, expected %d: %+v", test.version, len(found), len(test.descrs), found)
+			continue
+		}
+		for i, r := range found {
+			if r.SysDescr != test.descrs[i] || r.EngineID != test.engines[i] {
+				t.Errorf("%v: responder %d = %+v, expected sysDescr %q and engine %q",
+					test.version, i, r, test.descrs[i], test.engines[i])
+			}
+			if r.Addr == nil || r.Addr.Port == int(x.Port) || r.RTT <= 0 {
+				t.Errorf("%v: responder %d has Addr %v and RTT %v", test.version, i, r.Addr, r.RTT)
+			}
+		}
+		if found[0].Addr.String() == found[1].Addr.String() {
+			t.Errorf("%v: responders have the same Addr %v", test.version, found[0].Addr)
+		}
+	}
+}
+
+func TestDiscoverCtx(t *testing.T) {
+	x := startTestBroadcast(t)
+	x.Version = Version2c
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	found, err := x.DiscoverCtx(ctx, 10*time.Second)
+	if err != context.DeadlineExceeded || len(found) != 0 {
+		t.Errorf("DiscoverCtx() = %v, %v, expected context.DeadlineExceeded", found, err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("DiscoverCtx() returned after %v", elapsed)
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"errors"
+	"fmt"
+)
+
+//
+// Typed errors, for use with errors.Is and errors.As, eg
+//
+//	if errors.Is(err, gosnmp.NotWritable) { ...
+//
+//	var statusErr *gosnmp.StatusError
+//	if errors.As(err, &statusErr) {
+//		log.Printf("%s failed", statusErr.PDU.Name)
+//	}
+//
+// An SNMPError is itself an error, one that a *StatusError with that
+// status matches. Exception varbinds give an *ExceptionError, matching
+// ErrNoSuchObject, ErrNoSuchInstance or ErrEndOfMibView; see exception.go.
+// Requests that get no usable response match ErrTimeout, ErrDecode or
+// ErrAuthentication, reported informs ErrReport, SNMPv3 requests too large
+// for their agent ErrMessageTooLarge, requests to targets reported
+// unreachable by ICMP ErrPortUnreachable or ErrHostUnreachable, and
+// compare-and-sets of objects that changed ErrValueChanged. Errors are
+// wrapped with %w, so the underlying error (eg a *net.OpError) can be
+// reached with errors.As too.
+//
+
+// The errors that failed requests match with errors.Is, for retry logic
+// that needs to tell them apart
+var (
+	// ErrTimeout is matched when a request gets no response before its
+	// timeout, after any retries
+	ErrTimeout = errors.New("Request timeout")
+
+	// ErrDecode is matched when a response can't be decoded. A request
+	// that times out after getting undecodable responses matches both
+	// ErrTimeout and ErrDecode.
+	ErrDecode = errors.New("Unable to decode packet")
+
+	// ErrAuthentication is matched when an SNMPv3 response fails
+	// authentication
+	ErrAuthentication = errors.New("Incoming packet is not authentic")
+
+	// ErrReport is matched when an inform is answered with an SNMPv3
+	// Report rather than acknowledged. (Other requests return the Report.)
+	ErrReport = errors.New("Request answered with a report")
+
+	// ErrMessageTooLarge is matched when an SNMPv3 request is larger than
+	// the msgMaxSize its agent advertises, and isn't sent
+	ErrMessageTooLarge = errors.New("Message exceeds the agent's msgMaxSize")
+
+	// ErrPortUnreachable is matched when a request gets an ICMP port
+	// unreachable, as nothing listens on the Target's port, and
+	// ErrHostUnreachable when it gets an ICMP host or network unreachable.
+	// The request fails at once, without retrying, unless the session's
+	// RedialOnUnreachable is set.
+	ErrPortUnreachable = errors.New("Port unreachable")
+	ErrHostUnreachable = errors.New("Host unreachable")
+
+	// ErrValueChanged is matched when the object of a CompareAndSet
+	// changes before it is set, and isn't set
+	ErrValueChanged = errors.New("Value changed before it was set")
+)
+
+// timeoutError is the error for a request timing out, which wraps the
+// error decoding the last response if one was received but undecodable
+type timeoutError struct {
+	msg  string
+	last error
+}
+
+func (e *timeoutError) Error() string {
+	return e.msg
+}
+
+func (e *timeoutError) Is(target error) bool {
+	return target == ErrTimeout
+}
+
+func (e *timeoutError) Unwrap() error {
+	return e.last
+}
+
+// Error makes an SNMPError an error, so that errors.Is(err, WrongType)
+// reports whether err is (or wraps) a *StatusError with that status
+func (e SNMPError) Error() string {
+	return e.String()
+}
+
+// StatusError is the error for a response with an error status other than
+// noError
+type StatusError struct {
+	Status SNMPError
+
+	// Index is the error-index of the response, the position (from 1) of
+	// the varbind that caused the error, or 0 if it isn't known
+	Index int
+
+	// PDU is the varbind that caused the error, if Index refers to one
+	PDU *SnmpPDU
+}
+
+// newStatusError returns the StatusError for status and index, with the
+// offending varbind taken from pdus
+func newStatusError(status SNMPError, index int, pdus []SnmpPDU) *StatusError {
+	e := &StatusError{Status: status, Index: index}
+	if index > 0 && index <= len(pdus) {
+		pdu := pdus[index-1]
+		e.PDU = &pdu
+	}
+	return e
+}
+
+func (e *StatusError) Error() string {
+	switch {
+	case e.PDU != nil:
+		return fmt.Sprintf("%s at %d (%s)", e.Status, e.Index, e.PDU.Name)
+	case e.Index > 0:
+		return fmt.Sprintf("%s at %d", e.Status, e.Index)
+	}
+	return e.Status.String()
+}
+
+// Unwrap returns the status, which errors.Is compares with its target
+func (e *StatusError) Unwrap() error {
+	return e.Status
+}
+
+// Err returns a *StatusError if the packet has an error status, and
+// otherwise nil
+func (packet *SnmpPacket) Err() error {
+	if packet.Error == NoError {
+		return nil
+	}
+	return newStatusError(packet.Error, int(packet.ErrorIndex), packet.Variables)
+}
+
+// Err returns an *ExceptionError if the varbind is an exception
+// (NoSuchObject, NoSuchInstance or EndOfMibView), and otherwise nil
+func (p SnmpPDU) Err() error {
+	if !isException(p.Type) {
+		return nil
+	}
+	return &ExceptionError{PDU: p}
+}
@@ -0,0 +1,150 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStatusError(t *testing.T) {
+	pdus := []SnmpPDU{
+		{Name: ".1.3.6.1.2.1.1.5.0", Type: OctetString, Value: []byte("a")},
+		{Name: ".1.3.6.1.2.1.1.6.0", Type: OctetString, Value: []byte("b")},
+	}
+	tests := []struct {
+		packet *SnmpPacket
+		want   string
+	}{
+		{&SnmpPacket{Error: NotWritable, ErrorIndex: 2, Variables: pdus}, "NotWritable at 2 (.1.3.6.1.2.1.1.6.0)"},
+		{&SnmpPacket{Error: NotWritable, ErrorIndex: 3, Variables: pdus}, "NotWritable at 3"},
+		{&SnmpPacket{Error: NotWritable}, "NotWritable"},
+	}
+	for i, test := range tests {
+		err := fmt.Errorf("Unable to set: %w", test.packet.Err())
+		if got := err.Error(); got != "Unable to set: "+test.want {
+			t.Errorf("#%d: got %q, want %q", i, got, "Unable to set: "+test.want)
+		}
+		if !errors.Is(err, NotWritable) || errors.Is(err, WrongType) {
+			t.Errorf("#%d: errors.Is doesn't match the status", i)
+		}
+		var statusErr *StatusError
+		if !errors.As(err, &statusErr) || statusErr.Index != int(test.packet.ErrorIndex) {
+			t.Errorf("#%d: errors.As got %v", i, statusErr)
+		}
+		if (statusErr.PDU != nil) != (test.packet.ErrorIndex == 2) {
+			t.Errorf("#%d: got varbind %v", i, statusErr.PDU)
+		}
+	}
+	if err := (&SnmpPacket{Variables: pdus}).Err(); err != nil {
+		t.Errorf("got %v for a packet without an error status", err)
+	}
+}
+
+func TestExceptionErrors(t *testing.T) {
+	tests := []struct {
+		pdu  SnmpPDU
+		want error
+	}{
+		{SnmpPDU{Name: ".1.3.6.1.2.1.1.5.0", Type: NoSuchObject}, ErrNoSuchObject},
+		{SnmpPDU{Name: ".1.3.6.1.2.1.1.5.0", Type: NoSuchInstance}, ErrNoSuchInstance},
+		{SnmpPDU{Name: ".1.3.6.1.2.1.1.5.0", Type: EndOfMibView}, ErrEndOfMibView},
+		{SnmpPDU{Name: ".1.3.6.1.2.1.1.5.0", Type: OctetString, Value: []byte("a")}, nil},
+	}
+	exceptions := []error{ErrNoSuchObject, ErrNoSuchInstance, ErrEndOfMibView}
+	for i, test := range tests {
+		err := test.pdu.Err()
+		if (err == nil) != (test.want == nil) {
+			t.Errorf("#%d: got %v", i, err)
+		}
+		// the accessors wrap the exception in their errors
+		_, intErr := test.pdu.Int64()
+		for _, target := range exceptions {
+			want := target == test.want
+			if errors.Is(err, target) != want || errors.Is(intErr, target) != want {
+				t.Errorf("#%d: errors.Is(%v, %v) != %t", i, err, target, want)
+			}
+		}
+	}
+}
+
+func TestStatusErrorFromAgent(t *testing.T) {
+	r := newTestResponder(t, nil)
+	defer r.Close()
+	r.setHandler(func(req *SnmpPacket) *SnmpPacket {
+		return &SnmpPacket{Error: NotWritable, ErrorIndex: 1, Variables: req.Variables}
+	})
+	x := r.client(t)
+	defer x.Conn.Close()
+
+	const statusOid = ".1.3.6.1.6.3.12.1.2.1.9.3.110.109.115"
+	err := x.DestroyRow(statusOid)
+	var statusErr *StatusError
+	if !errors.Is(err, NotWritable) || !errors.As(err, &statusErr) ||
+		statusErr.PDU == nil || statusErr.PDU.Name != statusOid {
+		t.Errorf("DestroyRow() err: %v, want NotWritable for %s", err, statusOid)
+	}
+}
+
+func TestSentinelErrors(t *testing.T) {
+	// an agent that doesn't answer, and one that answers with junk
+	r := newTestResponder(t, nil)
+	defer r.Close()
+	r.setHandler(func(req *SnmpPacket) *SnmpPacket { return nil })
+	x := r.client(t)
+	defer x.Conn.Close()
+	x.Timeout = 20 * time.Millisecond
+	_, err := x.Get([]string{".1.3.6.1.2.1.1.5.0"})
+	if !errors.Is(err, ErrTimeout) || !strings.HasPrefix(err.Error(), "Request timeout") {
+		t.Errorf("Get() from a silent agent err: %v, want ErrTimeout", err)
+	}
+
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			// the request back as a response, cut off in its varbind
+			rsp := append([]byte(nil), buf[:n-4]...)
+			rsp[bytes.IndexByte(rsp, byte(GetRequest))] = byte(GetResponse)
+			conn.WriteTo(rsp, addr)
+		}
+	}()
+	junk := &GoSNMP{
+		Target:    "127.0.0.1",
+		Port:      uint16(conn.LocalAddr().(*net.UDPAddr).Port),
+		Community: "public",
+		Version:   Version2c,
+		Timeout:   100 * time.Millisecond,
+		MaxOids:   MaxOids,
+	}
+	if err := junk.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer junk.Conn.Close()
+	if _, err := junk.Get([]string{".1.3.6.1.2.1.1.5.0"}); !errors.Is(err, ErrDecode) || !errors.Is(err, ErrTimeout) {
+		t.Errorf("Get() from a broken agent err: %v, want ErrTimeout and ErrDecode", err)
+	}
+
+	// the causes of errors are wrapped
+	_, err = ParseOid(".1.3.x")
+	var numErr *strconv.NumError
+	if !errors.As(err, &numErr) {
+		t.Errorf("ParseOid() err: %v, want a wrapped *strconv.NumError", err)
+	}
+}
@@ -0,0 +1,170 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"strings"
+)
+
+//
+// Estimating the size of messages before they are sent
+//
+
+// placeholders for what an SNMPv3 estimate can't know before discovery:
+// the longest snmpEngineID of RFC 3411, and a salt
+var (
+	estimateEngineID = strings.Repeat("\x00", 32)
+	estimateSalt     = make([]byte, 8)
+	estimateKey      = make([]byte, 20)
+)
+
+// EstimateSize returns the size of the message x would send with the PDU
+// of packet: its PDUType, Variables, error-status, non-repeaters and
+// max-repetitions (and SNMPv1 trap header), with x's version, community
+// or SNMPv3 header and security parameters. Before the SNMPv3 engine is
+// discovered the estimate allows for the longest snmpEngineID, so it may be
+// larger than the message sent, but isn't smaller. The message isn't
+// encrypted or authenticated with the keys of x.
+//
+// Compare it with the agent's buffer (PeerMaxMessageSize) or the path MTU,
+// eg to decide how many oids to get in a request; see too FitOids and
+// FitRepetitions.
+func (x *GoSNMP) EstimateSize(packet *SnmpPacket) (int, error) {
+	p := x.mkSnmpPacket(packet.PDUType, packet.Variables, packet.NonRepeaters, packet.MaxRepetitions)
+	p.Error, p.ErrorIndex = packet.Error, packet.ErrorIndex
+	p.Enterprise, p.AgentAddr = packet.Enterprise, packet.AgentAddr
+	p.GenericTrap, p.SpecificTrap, p.Timestamp = packet.GenericTrap, packet.SpecificTrap, packet.Timestamp
+	if p.Version == Version3 {
+		usm, ok := p.SecurityParameters.(*UsmSecurityParameters)
+		if !ok {
+			return 0, fmt.Errorf("Unable to estimate an SNMPv3 packet without UsmSecurityParameters")
+		}
+		if usm.AuthoritativeEngineID == "" {
+			usm.AuthoritativeEngineID = estimateEngineID
+		}
+		if p.ContextEngineID == "" {
+			p.ContextEngineID = estimateEngineID
+		}
+		if len(usm.PrivacyParameters) == 0 {
+			usm.PrivacyParameters = estimateSalt
+		}
+		// the size doesn't depend on the keys, which are slow to make
+		usm.secretKey, usm.privacyKey = estimateKey, estimateKey
+		usm.Logger = log.New(ioutil.Discard, "", 0)
+	}
+	out, err := p.marshalMsg()
+	if err != nil {
+		return 0, err
+	}
+	return len(out), nil
+}
+
+// EstimateResponseSize returns the size of the response to request, a
+// GET, GETNEXT or GETBULK, if each of its varbinds were like sample, eg a
+// varbind of the last response: with sample's value, and sample's name or
+// if it has none the name requested. GETBULK responses are estimated with
+// all the repetitions asked for. SNMPv3 responses may be a byte or two
+// smaller, with a smaller msgMaxSize than x's.
+func (x *GoSNMP) EstimateResponseSize(request *SnmpPacket, sample SnmpPDU) (int, error) {
+	names := make([]string, len(request.Variables))
+	for i, pdu := range request.Variables {
+		names[i] = pdu.Name
+	}
+	if request.PDUType == GetBulkRequest {
+		nonRepeaters := int(request.NonRepeaters)
+		if nonRepeaters > len(names) {
+			nonRepeaters = len(names)
+		}
+		repeaters := names[nonRepeaters:]
+		names = names[:nonRepeaters:nonRepeaters]
+		for r := 0; r < int(request.MaxRepetitions); r++ {
+			names = append(names, repeaters...)
+		}
+	}
+	response := &SnmpPacket{PDUType: GetResponse, Variables: make([]SnmpPDU, len(names))}
+	for i, name := range names {
+		response.Variables[i] = sample
+		if sample.Name == "" {
+			response.Variables[i].Name = name
+		}
+	}
+	return x.EstimateSize(response)
+}
+
+// sizeLimit returns limit, or if it is 0 the msgMaxSize of the agent, or
+// if that isn't known a udp datagram that fits an ethernet frame
+func (x *GoSNMP) sizeLimit(limit int) int {
+	switch {
+	case limit > 0:
+		return limit
+	case x.PeerMaxMessageSize() > 0:
+		return x.PeerMaxMessageSize()
+	}
+	return defaultAgentMaxMessageSize
+}
+
+// FitOids returns how many of oids, from the first, can be got by a GET
+// whose request and response (estimated with sample, as for
+// EstimateResponseSize) are no larger than limit bytes. If limit is 0 it
+// is PeerMaxMessageSize, or if that isn't known 1472, a udp datagram that
+// fits an ethernet frame. It is at most MaxOids.
+func (x *GoSNMP) FitOids(oids []string, sample SnmpPDU, limit int) (int, error) {
+	limit = x.sizeLimit(limit)
+	if len(oids) > x.MaxOids && x.MaxOids > 0 {
+		oids = oids[:x.MaxOids]
+	}
+	fits := func(n int) (bool, error) {
+		request := &SnmpPacket{PDUType: GetRequest, Variables: make([]SnmpPDU, n)}
+		for i, oid := range oids[:n] {
+			request.Variables[i] = SnmpPDU{Name: oid, Type: Null}
+		}
+		size, err := x.EstimateSize(request)
+		if err != nil || size > limit {
+			return false, err
+		}
+		size, err = x.EstimateResponseSize(request, sample)
+		return err == nil && size <= limit, err
+	}
+	return fitSearch(len(oids), fits)
+}
+
+// FitRepetitions returns the largest max-repetitions (up to 255) of a
+// GETBULK of request's varbinds and non-repeaters whose response,
+// estimated with sample as for EstimateResponseSize, is no larger than
+// limit bytes, with limit as for FitOids; 0 if not even one repetition
+// fits.
+func (x *GoSNMP) FitRepetitions(request *SnmpPacket, sample SnmpPDU, limit int) (uint8, error) {
+	limit = x.sizeLimit(limit)
+	bulk := *request
+	bulk.PDUType = GetBulkRequest
+	n, err := fitSearch(255, func(n int) (bool, error) {
+		bulk.MaxRepetitions = uint8(n)
+		size, err := x.EstimateResponseSize(&bulk, sample)
+		return err == nil && size <= limit, err
+	})
+	return uint8(n), err
+}
+
+// fitSearch returns the largest n up to most for which fits is true,
+// fits being true of all the numbers below one it is true of
+func fitSearch(most int, fits func(n int) (bool, error)) (int, error) {
+	low, high := 0, most // fits(low) is taken as true
+	for low < high {
+		mid := (low + high + 1) / 2
+		ok, err := fits(mid)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			low = mid
+		} else {
+			high = mid - 1
+		}
+	}
+	return low, nil
+}
@@ -0,0 +1,117 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"testing"
+)
+
+func TestEstimateSize(t *testing.T) {
+	a, _ := newTestAgent(t)
+	a.Users = []*UsmSecurityParameters{{
+		UserName:                 "user",
+		AuthenticationProtocol:   SHA,
+		AuthenticationPassphrase: "authpassphrase",
+		PrivacyProtocol:          AES,
+		PrivacyPassphrase:        "privpassphrase",
+	}}
+	defer a.Close()
+	oids := []string{testSysDescr, testSysDescr, testSysDescr}
+	sample := SnmpPDU{Type: OctetString, Value: []byte("descr")}
+
+	tests := []struct {
+		name string
+		x    *GoSNMP
+	}{
+		{"v2c", nil},
+		{"v3", &GoSNMP{
+			Version:            Version3,
+			MsgFlags:           AuthPriv,
+			SecurityModel:      UserSecurityModel,
+			SecurityParameters: a.Users[0].Copy(),
+		}},
+	}
+	for _, test := range tests {
+		x := startTestAgent(t, a, test.x)
+		var sent, received int
+		x.OnSend = func(packet *SnmpPacket, out []byte) ([]byte, error) {
+			sent = len(out)
+			return out, nil
+		}
+		x.OnReceive = func(packet *SnmpPacket, in []byte) error {
+			received = len(in)
+			return nil
+		}
+		request := &SnmpPacket{PDUType: GetRequest}
+		for _, oid := range oids {
+			request.Variables = append(request.Variables, SnmpPDU{Name: oid, Type: Null})
+		}
+		before, err := x.EstimateSize(request)
+		if err != nil {
+			t.Fatalf("%s: EstimateSize() err: %v", test.name, err)
+		}
+		if _, err = x.Get(oids); err != nil {
+			t.Fatalf("%s: Get() err: %v", test.name, err)
+		}
+		if before < sent {
+			t.Errorf("%s: EstimateSize() before discovery = %d, %d bytes sent", test.name, before, sent)
+		}
+
+		// with the engine known, the estimates are exact, but for the
+		// msgMaxSize of the response
+		size, err := x.EstimateSize(request)
+		if err != nil || size != sent {
+			t.Errorf("%s: EstimateSize() = %d, %v, %d bytes sent", test.name, size, err, sent)
+		}
+		size, err = x.EstimateResponseSize(request, sample)
+		if err != nil || size < received || size > received+2 {
+			t.Errorf("%s: EstimateResponseSize() = %d, %v, %d bytes received", test.name, size, err, received)
+		}
+		x.Conn.Close()
+	}
+}
+
+func TestFit(t *testing.T) {
+	x := &GoSNMP{Community: "public", Version: Version2c, MaxOids: MaxOids}
+	sample := SnmpPDU{Name: ".1.3.6.1.2.1.2.2.1.2.1", Type: OctetString, Value: []byte("GigabitEthernet0/1")}
+	var oids []string
+	for i := 0; i < 100; i++ {
+		oids = append(oids, testSysDescr)
+	}
+
+	n, err := x.FitOids(oids, sample, 500)
+	if err != nil {
+		t.Fatalf("FitOids() err: %v", err)
+	}
+	request := func(n int) *SnmpPacket {
+		p := &SnmpPacket{PDUType: GetRequest}
+		for _, oid := range oids[:n] {
+			p.Variables = append(p.Variables, SnmpPDU{Name: oid, Type: Null})
+		}
+		return p
+	}
+	fits, _ := x.EstimateResponseSize(request(n), sample)
+	over, _ := x.EstimateResponseSize(request(n+1), sample)
+	if n == 0 || fits > 500 || over <= 500 {
+		t.Errorf("FitOids() = %d, with responses of %d and %d bytes", n, fits, over)
+	}
+	if n, _ = x.FitOids(oids, sample, 65535); n != MaxOids {
+		t.Errorf("FitOids() with a large limit = %d, expected MaxOids", n)
+	}
+
+	bulk := &SnmpPacket{PDUType: GetBulkRequest, Variables: []SnmpPDU{
+		{Name: ".1.3.6.1.2.1.2.2.1.2", Type: Null}, {Name: ".1.3.6.1.2.1.2.2.1.10", Type: Null}}}
+	r, err := x.FitRepetitions(bulk, sample, 0)
+	if err != nil {
+		t.Fatalf("FitRepetitions() err: %v", err)
+	}
+	bulk.MaxRepetitions = r
+	fits, _ = x.EstimateResponseSize(bulk, sample)
+	bulk.MaxRepetitions = r + 1
+	over, _ = x.EstimateResponseSize(bulk, sample)
+	if r == 0 || fits > 1472 || over <= 1472 {
+		t.Errorf("FitRepetitions() = %d, with responses of %d and %d bytes", r, fits, over)
+	}
+}
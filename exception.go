@@ -0,0 +1,76 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"errors"
+	"fmt"
+)
+
+// EndOfMib is what a walk does when it reaches an exception varbind
+// (endOfMibView, noSuchObject or noSuchInstance), see GoSNMP.EndOfMib
+type EndOfMib int
+
+const (
+	// EndOfMibStop ends the walk without reporting the exception
+	EndOfMibStop EndOfMib = iota
+
+	// EndOfMibReturn passes the exception varbind to the WalkFunc (or
+	// includes it in the results), and then ends the walk
+	EndOfMibReturn
+
+	// EndOfMibError ends the walk with an *ExceptionError
+	EndOfMibError
+)
+
+// The errors that an *ExceptionError matches with errors.Is, by the type
+// of its varbind
+var (
+	ErrNoSuchObject   = errors.New("NoSuchObject")
+	ErrNoSuchInstance = errors.New("NoSuchInstance")
+	ErrEndOfMibView   = errors.New("EndOfMibView")
+)
+
+// ExceptionError is the error for an exception varbind, one with the type
+// NoSuchObject, NoSuchInstance or EndOfMibView
+type ExceptionError struct {
+	PDU SnmpPDU
+}
+
+func (e *ExceptionError) Error() string {
+	return fmt.Sprintf("%s: %s", e.PDU.Name, e.PDU.Type)
+}
+
+// Is reports whether target is the ErrNoSuchObject, ErrNoSuchInstance or
+// ErrEndOfMibView for the type of the varbind
+func (e *ExceptionError) Is(target error) bool {
+	switch e.PDU.Type {
+	case NoSuchObject:
+		return target == ErrNoSuchObject
+	case NoSuchInstance:
+		return target == ErrNoSuchInstance
+	case EndOfMibView:
+		return target == ErrEndOfMibView
+	}
+	return false
+}
+
+// isException reports whether t is the type of an exception varbind
+func isException(t Asn1BER) bool {
+	return t == EndOfMibView || t == NoSuchObject || t == NoSuchInstance
+}
+
+// walkException handles the exception varbind v ending a walk, as
+// x.EndOfMib says
+func (x *GoSNMP) walkException(v SnmpPDU, walkFn WalkFunc) error {
+	x.Logger.Printf("Walk terminated with type %s", v.Type)
+	switch x.EndOfMib {
+	case EndOfMibReturn:
+		return walkFn(v)
+	case EndOfMibError:
+		return &ExceptionError{PDU: v}
+	}
+	return nil
+}
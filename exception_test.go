@@ -0,0 +1,60 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"testing"
+)
+
+func TestEndOfMib(t *testing.T) {
+	const column = ".1.3.6.1.2.1.2.2.1.2"
+	r := newTestResponder(t, []SnmpPDU{
+		{Name: column + ".1", Type: Integer, Value: 1},
+		{Name: column + ".2", Type: Integer, Value: 2},
+		{Name: column + ".3", Type: EndOfMibView},
+	})
+	defer r.Close()
+	x := r.client(t)
+	defer x.Conn.Close()
+
+	all := func(LazyPDU) bool { return true }
+	walks := map[string]func(walkFn WalkFunc) error{
+		"Walk":            func(walkFn WalkFunc) error { return x.Walk(column, walkFn) },
+		"BulkWalk":        func(walkFn WalkFunc) error { return x.BulkWalk(column, walkFn) },
+		"BulkWalkColumns": func(walkFn WalkFunc) error { return x.BulkWalkColumns([]string{column}, walkFn) },
+		"WalkFiltered":    func(walkFn WalkFunc) error { return x.WalkFiltered(column, all, walkFn) },
+	}
+	tests := []struct {
+		policy EndOfMib
+		want   int // values reported
+		err    bool
+	}{
+		{EndOfMibStop, 2, false},
+		{EndOfMibReturn, 3, false},
+		{EndOfMibError, 2, true},
+	}
+	for _, test := range tests {
+		x.EndOfMib = test.policy
+		for name, walk := range walks {
+			var got []SnmpPDU
+			err := walk(func(pdu SnmpPDU) error {
+				got = append(got, pdu)
+				return nil
+			})
+			if len(got) != test.want {
+				t.Errorf("%d: %s() got %d values, want %d", test.policy, name, len(got), test.want)
+			} else if test.policy == EndOfMibReturn && got[2].Type != EndOfMibView {
+				t.Errorf("%d: %s() got %s, want an EndOfMibView", test.policy, name, got[2])
+			}
+			exception, ok := err.(*ExceptionError)
+			switch {
+			case !test.err && err != nil:
+				t.Errorf("%d: %s() err: %v", test.policy, name, err)
+			case test.err && (!ok || exception.PDU.Type != EndOfMibView || exception.PDU.Name != column+".3"):
+				t.Errorf("%d: %s() err: %v, want an *ExceptionError for the EndOfMibView", test.policy, name, err)
+			}
+		}
+	}
+}
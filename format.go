@@ -0,0 +1,213 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+)
+
+//
+// One line summaries of packets and their parts, for logging and debugging.
+// They're modelled on tcpdump's SNMP output, eg
+//
+//	SNMPv2c C="public" GetResponse R=1234 .1.3.6.1.2.1.1.3.0=TimeTicks:1234
+//
+
+func (t PDUType) String() string {
+	switch t {
+	case Sequence:
+		return "Sequence"
+	case GetRequest:
+		return "GetRequest"
+	case GetNextRequest:
+		return "GetNextRequest"
+	case GetResponse:
+		return "GetResponse"
+	case SetRequest:
+		return "SetRequest"
+	case Trap:
+		return "Trap"
+	case GetBulkRequest:
+		return "GetBulkRequest"
+	case InformRequest:
+		return "InformRequest"
+	case SNMPv2Trap:
+		return "SNMPv2Trap"
+	case Report:
+		return "Report"
+	}
+	return fmt.Sprintf("PDUType(%#02x)", byte(t))
+}
+
+var snmpErrorNames = [...]string{
+	"NoError", "TooBig", "NoSuchName", "BadValue", "ReadOnly", "GenErr",
+	"NoAccess", "WrongType", "WrongLength", "WrongEncoding", "WrongValue",
+	"NoCreation", "InconsistentValue", "ResourceUnavailable", "CommitFailed",
+	"UndoFailed", "AuthorizationError", "NotWritable", "InconsistentName",
+}
+
+func (e SNMPError) String() string {
+	if int(e) < len(snmpErrorNames) {
+		return snmpErrorNames[e]
+	}
+	return fmt.Sprintf("SNMPError(%d)", uint8(e))
+}
+
+func (p SnmpV3AuthProtocol) String() string {
+	switch p {
+	case NoAuth:
+		return "NoAuth"
+	case MD5:
+		return "MD5"
+	case SHA:
+		return "SHA"
+	}
+	return fmt.Sprintf("SnmpV3AuthProtocol(%d)", uint8(p))
+}
+
+func (p SnmpV3PrivProtocol) String() string {
+	switch p {
+	case NoPriv:
+		return "NoPriv"
+	case DES:
+		return "DES"
+	case AES:
+		return "AES"
+	}
+	return fmt.Sprintf("SnmpV3PrivProtocol(%d)", uint8(p))
+}
+
+func (f SnmpV3MsgFlags) String() string {
+	var s string
+	switch f &^ Reportable {
+	case NoAuthNoPriv:
+		s = "NoAuthNoPriv"
+	case AuthNoPriv:
+		s = "AuthNoPriv"
+	case AuthPriv:
+		s = "AuthPriv"
+	default:
+		s = fmt.Sprintf("SnmpV3MsgFlags(%#x)", uint8(f&^Reportable))
+	}
+	if f&Reportable != 0 {
+		s += "|Reportable"
+	}
+	return s
+}
+
+// String formats the varbind as name=type:value, eg
+// .1.3.6.1.2.1.1.5.0=OctetString:"router". OctetStrings that aren't
+// printable are shown as hex.
+func (p SnmpPDU) String() string {
+	var buf bytes.Buffer
+	p.writeString(&buf)
+	return buf.String()
+}
+
+func (p SnmpPDU) writeString(buf *bytes.Buffer) {
+	buf.WriteString(p.Name)
+	buf.WriteByte('=')
+	buf.WriteString(p.Type.String())
+	switch p.Type {
+	case Null, NoSuchObject, NoSuchInstance, EndOfMibView:
+		return
+	}
+	buf.WriteByte(':')
+	switch v := p.Value.(type) {
+	case []byte:
+		writeOctetString(buf, v)
+	case Bits:
+		writeOctetString(buf, v)
+	case string:
+		if p.Type == OctetString {
+			writeOctetString(buf, []byte(v))
+		} else {
+			buf.WriteString(v)
+		}
+	default:
+		fmt.Fprint(buf, v)
+	}
+}
+
+func writeOctetString(buf *bytes.Buffer, b []byte) {
+	s, isHex := OctetStringRenderer{}.Render(b)
+	if isHex {
+		buf.WriteString(s)
+	} else {
+		buf.WriteString(strconv.Quote(s))
+	}
+}
+
+// String summarises the packet on one line: its version and (masked)
+// community or SNMPv3 header (with secrets masked), PDU type, request id,
+// error and varbinds
+func (packet SnmpPacket) String() string {
+	var buf bytes.Buffer
+	buf.WriteString("SNMPv")
+	buf.WriteString(packet.Version.String())
+	if packet.Version == Version3 {
+		fmt.Fprintf(&buf, " ID=%d F=%s", packet.MsgID, packet.MsgFlags)
+		if packet.SecurityParameters != nil {
+			fmt.Fprintf(&buf, " %s", packet.SecurityParameters)
+		}
+		if packet.ContextEngineID != "" {
+			fmt.Fprintf(&buf, " CE=%x", packet.ContextEngineID)
+		}
+		if packet.ContextName != "" {
+			fmt.Fprintf(&buf, " CN=%q", packet.ContextName)
+		}
+	} else {
+		fmt.Fprintf(&buf, " C=%s", redact(packet.Community))
+	}
+
+	fmt.Fprintf(&buf, " %s", packet.PDUType)
+	if packet.PDUType == Trap {
+		fmt.Fprintf(&buf, " E=%s A=%s G=%d S=%d T=%d", oidToString(packet.Enterprise),
+			packet.AgentAddr, packet.GenericTrap, packet.SpecificTrap, packet.Timestamp)
+	} else {
+		fmt.Fprintf(&buf, " R=%d", packet.RequestID)
+	}
+	switch {
+	case packet.PDUType == GetBulkRequest:
+		fmt.Fprintf(&buf, " N=%d M=%d", packet.NonRepeaters, packet.MaxRepetitions)
+	case packet.Error != NoError || packet.ErrorIndex != 0:
+		fmt.Fprintf(&buf, " E=%s I=%d", packet.Error, packet.ErrorIndex)
+	}
+
+	for _, v := range packet.Variables {
+		buf.WriteByte(' ')
+		v.writeString(&buf)
+	}
+	if len(packet.LazyVariables) > 0 {
+		fmt.Fprintf(&buf, " (%d undecoded varbinds)", len(packet.LazyVariables))
+	}
+	return buf.String()
+}
+
+// String summarises the security parameters, masking the passphrases. The
+// keys derived from them, and salts, aren't shown.
+func (sp *UsmSecurityParameters) String() string {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "U=%q", sp.UserName)
+	if sp.AuthoritativeEngineID != "" {
+		fmt.Fprintf(&buf, " EID=%x", sp.AuthoritativeEngineID)
+	}
+	fmt.Fprintf(&buf, " B=%d T=%d", sp.AuthoritativeEngineBoots, sp.AuthoritativeEngineTime)
+	if sp.AuthenticationProtocol != 0 {
+		fmt.Fprintf(&buf, " Auth=%s", sp.AuthenticationProtocol)
+	}
+	if sp.AuthenticationPassphrase != "" {
+		buf.WriteString(" AuthPass=" + redacted)
+	}
+	if sp.PrivacyProtocol != 0 {
+		fmt.Fprintf(&buf, " Priv=%s", sp.PrivacyProtocol)
+	}
+	if sp.PrivacyPassphrase != "" {
+		buf.WriteString(" PrivPass=" + redacted)
+	}
+	return buf.String()
+}
@@ -0,0 +1,111 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestPDUString(t *testing.T) {
+	tests := []struct {
+		pdu  SnmpPDU
+		want string
+	}{
+		{SnmpPDU{Name: ".1.3.6.1.2.1.1.5.0", Type: OctetString, Value: []byte("router")}, `.1.3.6.1.2.1.1.5.0=OctetString:"router"`},
+		{SnmpPDU{Name: ".1.3.6.1.2.1.2.2.1.6.1", Type: OctetString, Value: []byte{0, 0x1b}}, `.1.3.6.1.2.1.2.2.1.6.1=OctetString:00 1B`},
+		{SnmpPDU{Name: ".1.3.6.1.2.1.1.3.0", Type: TimeTicks, Value: 1234}, `.1.3.6.1.2.1.1.3.0=TimeTicks:1234`},
+		{SnmpPDU{Name: ".1.3.6.1.2.1.1.2.0", Type: ObjectIdentifier, Value: ".1.3.6.1.4.1.8072"}, `.1.3.6.1.2.1.1.2.0=ObjectIdentifier:.1.3.6.1.4.1.8072`},
+		{SnmpPDU{Name: ".1.3.6.1.2.1.1.9.0", Type: NoSuchInstance}, `.1.3.6.1.2.1.1.9.0=NoSuchInstance`},
+		{SnmpPDU{Name: ".1.3", Type: Asn1BER(0x99), Value: nil}, `.1.3=Asn1BER(0x99):<nil>`},
+	}
+	for _, test := range tests {
+		if got := test.pdu.String(); got != test.want {
+			t.Errorf("got %s, want %s", got, test.want)
+		}
+	}
+}
+
+func TestPacketString(t *testing.T) {
+	v2 := SnmpPacket{
+		Version:    Version2c,
+		Community:  "public",
+		PDUType:    GetResponse,
+		RequestID:  1234,
+		Error:      NoSuchName,
+		ErrorIndex: 1,
+		Variables: []SnmpPDU{
+			{Name: ".1.3.6.1.2.1.1.3.0", Type: TimeTicks, Value: 1},
+			{Name: ".1.3.6.1.2.1.1.9.0", Type: NoSuchObject},
+		},
+	}
+	want := `SNMPv2c C=*** GetResponse R=1234 E=NoSuchName I=1 .1.3.6.1.2.1.1.3.0=TimeTicks:1 .1.3.6.1.2.1.1.9.0=NoSuchObject`
+	if got := v2.String(); got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+
+	bulk := SnmpPacket{Version: Version2c, Community: "c", PDUType: GetBulkRequest, RequestID: 7, MaxRepetitions: 10,
+		Variables: []SnmpPDU{{Name: ".1.3.6.1.2.1.2", Type: Null}}}
+	if got, want := bulk.String(), `SNMPv2c C=*** GetBulkRequest R=7 N=0 M=10 .1.3.6.1.2.1.2=Null`; got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+
+	trap := SnmpPacket{Version: Version1, Community: "public", PDUType: Trap, Enterprise: []int{1, 3, 6, 1, 4, 1, 8072},
+		AgentAddr: "192.0.2.1", GenericTrap: 6, SpecificTrap: 2, Timestamp: 300}
+	if got, want := trap.String(), `SNMPv1 C=*** Trap E=.1.3.6.1.4.1.8072 A=192.0.2.1 G=6 S=2 T=300`; got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+
+	v3 := SnmpPacket{
+		Version:       Version3,
+		MsgID:         99,
+		MsgFlags:      AuthPriv | Reportable,
+		SecurityModel: UserSecurityModel,
+		SecurityParameters: &UsmSecurityParameters{
+			UserName:                 "bob",
+			AuthoritativeEngineID:    "\x80\x00\x1f\x88",
+			AuthoritativeEngineBoots: 3,
+			AuthoritativeEngineTime:  1000,
+			AuthenticationProtocol:   SHA,
+			AuthenticationPassphrase: "authsecret",
+			PrivacyProtocol:          AES,
+			PrivacyPassphrase:        "privsecret",
+		},
+		ContextName: "vrf1",
+		PDUType:     GetRequest,
+		RequestID:   5,
+	}
+	got := v3.String()
+	want = `SNMPv3 ID=99 F=AuthPriv|Reportable U="bob" EID=80001f88 B=3 T=1000 Auth=SHA AuthPass=*** Priv=AES PrivPass=*** CN="vrf1" GetRequest R=5`
+	if got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+	if strings.Contains(got, "secret") || strings.Contains(fmt.Sprintf("%+v", v3.SecurityParameters), "secret") {
+		t.Errorf("passphrases not masked")
+	}
+}
+
+func TestEnumStrings(t *testing.T) {
+	tests := []struct {
+		v    fmt.Stringer
+		want string
+	}{
+		{GetBulkRequest, "GetBulkRequest"},
+		{PDUType(0x01), "PDUType(0x01)"},
+		{InconsistentName, "InconsistentName"},
+		{SNMPError(200), "SNMPError(200)"},
+		{MD5, "MD5"},
+		{DES, "DES"},
+		{NoAuthNoPriv, "NoAuthNoPriv"},
+		{AuthNoPriv | Reportable, "AuthNoPriv|Reportable"},
+		{Asn1BER(Counter64), "Counter64"},
+	}
+	for _, test := range tests {
+		if got := test.v.String(); got != test.want {
+			t.Errorf("got %s, want %s", got, test.want)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"context"
+	"fmt"
+)
+
+// GetNextMany sends a GETNEXT for any number of oids, splitting them into
+// requests of at most MaxOids oids where GetNext would return an error.
+// The varbinds of the responses are returned together, in the order of
+// oids. If a request gets an error status, eg noSuchName from an SNMPv1
+// agent, the packet holds the varbinds before it, with its Error and an
+// ErrorIndex counted from the start of oids (or 0, past the 255 that
+// ErrorIndex can hold).
+func (x *GoSNMP) GetNextMany(oids []string) (result *SnmpPacket, err error) {
+	return x.GetNextManyCtx(context.Background(), oids)
+}
+
+// GetNextManyCtx is like GetNextMany, but the requests are abandoned when
+// ctx is cancelled or its deadline passes, in which case ctx.Err() is
+// returned.
+func (x *GoSNMP) GetNextManyCtx(ctx context.Context, oids []string) (result *SnmpPacket, err error) {
+	ctx, cancel := x.withBudget(ctx)
+	defer cancel()
+	maxOids := x.MaxOids
+	if maxOids <= 0 {
+		maxOids = MaxOids
+	}
+	var variables []SnmpPDU
+	for start := 0; start < len(oids) || start == 0; start += maxOids {
+		end := start + maxOids
+		if end > len(oids) {
+			end = len(oids)
+		}
+		response, err := x.GetNextCtx(ctx, oids[start:end])
+		if err != nil {
+			return nil, err
+		}
+		if result == nil {
+			result = response
+		}
+		if response.Error != NoError {
+			index := int(response.ErrorIndex)
+			result.Error = response.Error
+			result.ErrorIndex = 0
+			if index > 0 && index+start <= 255 {
+				result.ErrorIndex = uint8(index + start)
+			}
+			break
+		}
+		variables = append(variables, response.Variables...)
+	}
+	result.Variables = variables
+	return result, nil
+}
+
+// GetNextBulk emulates GETBULK with GETNEXT requests, for SNMPv1 agents
+// that don't support it: the first nonRepeaters oids are asked for the
+// next value once, and the rest maxRepetitions times. The varbinds are
+// returned laid out as in a GETBULK response, the values of the
+// non-repeaters followed by rows of one value of each of the others.
+//
+// As with GETBULK, there may be fewer rows than maxRepetitions. An oid
+// that reaches the end of the mib (noSuchName in SNMPv1) is given
+// EndOfMibView values in the remaining rows, and no more rows are asked
+// for once every oid has.
+func (x *GoSNMP) GetNextBulk(oids []string, nonRepeaters uint8, maxRepetitions uint8) (result *SnmpPacket, err error) {
+	return x.GetNextBulkCtx(context.Background(), oids, nonRepeaters, maxRepetitions)
+}
+
+// GetNextBulkCtx is like GetNextBulk, but the requests are abandoned when
+// ctx is cancelled or its deadline passes, in which case ctx.Err() is
+// returned.
+func (x *GoSNMP) GetNextBulkCtx(ctx context.Context, oids []string, nonRepeaters uint8, maxRepetitions uint8) (result *SnmpPacket, err error) {
+	ctx, cancel := x.withBudget(ctx)
+	defer cancel()
+	n := int(nonRepeaters)
+	if n > len(oids) {
+		n = len(oids)
+	}
+	result = x.mkSnmpPacket(GetResponse, nil, 0, 0)
+	var variables []SnmpPDU
+	if n > 0 {
+		if variables, err = x.getNextEach(ctx, result, oids[:n], nil); err != nil {
+			return nil, err
+		}
+	}
+
+	repeaters := append([]string(nil), oids[n:]...)
+	ended := make([]bool, len(repeaters))
+	for rep := 0; rep < int(maxRepetitions) && len(repeaters) > 0; rep++ {
+		row, err := x.getNextEach(ctx, result, repeaters, ended)
+		if err != nil {
+			return nil, err
+		}
+		variables = append(variables, row...)
+
+		all := true
+		for i, pdu := range row {
+			repeaters[i] = pdu.Name
+			ended[i] = pdu.Type == EndOfMibView
+			all = all && ended[i]
+		}
+		if all {
+			break
+		}
+	}
+	result.Variables = variables
+	return result, nil
+}
+
+// getNextEach returns the varbind after each of oids, or an EndOfMibView
+// varbind for those ended or getting noSuchName, which are left out of the
+// requests. result takes the header of the last response.
+func (x *GoSNMP) getNextEach(ctx context.Context, result *SnmpPacket, oids []string, ended []bool) ([]SnmpPDU, error) {
+	next := make([]SnmpPDU, len(oids))
+	var pending []int // indexes into oids
+	for i, oid := range oids {
+		if ended != nil && ended[i] {
+			next[i] = SnmpPDU{Name: oid, Type: EndOfMibView, Logger: x.Logger}
+			continue
+		}
+		pending = append(pending, i)
+	}
+	for len(pending) > 0 {
+		asked := make([]string, len(pending))
+		for j, i := range pending {
+			asked[j] = oids[i]
+		}
+		response, err := x.GetNextManyCtx(ctx, asked)
+		if err != nil {
+			return nil, err
+		}
+		*result = *response
+		switch j := int(response.ErrorIndex) - 1; {
+		case response.Error == NoError && len(response.Variables) == len(asked):
+			for j, i := range pending {
+				next[i] = response.Variables[j]
+			}
+			pending = nil
+		case response.Error == NoSuchName && j >= 0 && j < len(pending):
+			i := pending[j]
+			next[i] = SnmpPDU{Name: oids[i], Type: EndOfMibView, Logger: x.Logger}
+			pending = append(pending[:j], pending[j+1:]...)
+		case response.Error != NoError:
+			return nil, fmt.Errorf("GetNext of %d oids failed: %w", len(asked), response.Err())
+		default:
+			return nil, fmt.Errorf("GetNext of %d oids returned %d varbinds", len(asked), len(response.Variables))
+		}
+	}
+	result.Error = NoError
+	result.ErrorIndex = 0
+	return next, nil
+}
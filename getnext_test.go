@@ -0,0 +1,134 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"fmt"
+	"testing"
+)
+
+// testV1Agent answers GETNEXTs as an SNMPv1 agent, with noSuchName for the
+// first oid at the end of the responder's data
+func testV1Agent(r *testResponder) func(req *SnmpPacket) *SnmpPacket {
+	return func(req *SnmpPacket) *SnmpPacket {
+		rsp := r.respond(req)
+		for i, pdu := range rsp.Variables {
+			if pdu.Name == ".2.0" {
+				return &SnmpPacket{Error: NoSuchName, ErrorIndex: uint8(i + 1), Variables: req.Variables}
+			}
+		}
+		return rsp
+	}
+}
+
+func TestGetNextMany(t *testing.T) {
+	var pdus []SnmpPDU
+	for i := 1; i <= 200; i++ {
+		pdus = append(pdus, SnmpPDU{Name: fmt.Sprintf(".1.3.6.1.2.1.2.2.1.2.%d", i), Type: Integer, Value: i % 100})
+	}
+	r := newTestResponder(t, pdus)
+	defer r.Close()
+	r.setHandler(testV1Agent(r))
+	x := r.client(t)
+	defer x.Conn.Close()
+	x.Version = Version1
+
+	oids := make([]string, 150)
+	for i := range oids {
+		oids[i] = pdus[i].Name
+	}
+	result, err := x.GetNextMany(oids)
+	if err != nil {
+		t.Fatalf("GetNextMany() err: %v", err)
+	}
+	if n := len(r.received()); n != 3 {
+		t.Errorf("got %d requests, want 3", n)
+	}
+	if len(result.Variables) != len(oids) {
+		t.Fatalf("got %d varbinds, want %d", len(result.Variables), len(oids))
+	}
+	for i, pdu := range result.Variables {
+		if pdu.Name != pdus[i+1].Name || pdu.Value != pdus[i+1].Value {
+			t.Errorf("varbind %d: got %s, want %s", i, pdu, pdus[i+1])
+		}
+	}
+
+	// the end of the mib in the second request
+	oids[70] = pdus[len(pdus)-1].Name
+	result, err = x.GetNextMany(oids)
+	if err != nil {
+		t.Fatalf("GetNextMany() err: %v", err)
+	}
+	if result.Error != NoSuchName || result.ErrorIndex != 71 || len(result.Variables) != 60 {
+		t.Errorf("got %s at %d with %d varbinds, want NoSuchName at 71 with 60",
+			result.Error, result.ErrorIndex, len(result.Variables))
+	}
+}
+
+func TestGetNextBulk(t *testing.T) {
+	pdus := []SnmpPDU{
+		{Name: ".1.3.6.1.2.1.1.3.0", Type: Integer, Value: 100},
+		{Name: ".1.3.6.1.4.1.1.1", Type: Integer, Value: 11},
+		{Name: ".1.3.6.1.4.1.1.2", Type: Integer, Value: 12},
+		{Name: ".1.3.6.1.4.1.1.3", Type: Integer, Value: 13},
+		{Name: ".1.3.6.1.4.1.2.1", Type: Integer, Value: 21},
+		{Name: ".1.3.6.1.4.1.2.2", Type: Integer, Value: 22},
+	}
+	r := newTestResponder(t, pdus)
+	defer r.Close()
+	r.setHandler(testV1Agent(r))
+	x := r.client(t)
+	defer x.Conn.Close()
+	x.Version = Version1
+
+	tests := []struct {
+		oids         []string
+		nonRepeaters uint8
+		reps         uint8
+		want         []string
+	}{
+		{
+			[]string{".1.3.6.1.2.1.1.3", ".1.3.6.1.4.1.1", ".1.3.6.1.4.1.2"}, 1, 4,
+			[]string{
+				".1.3.6.1.2.1.1.3.0=Integer:100",
+				".1.3.6.1.4.1.1.1=Integer:11", ".1.3.6.1.4.1.2.1=Integer:21",
+				".1.3.6.1.4.1.1.2=Integer:12", ".1.3.6.1.4.1.2.2=Integer:22",
+				".1.3.6.1.4.1.1.3=Integer:13", ".1.3.6.1.4.1.2.2=EndOfMibView",
+				".1.3.6.1.4.1.2.1=Integer:21", ".1.3.6.1.4.1.2.2=EndOfMibView",
+			},
+		},
+		{
+			// stops once every oid has ended
+			[]string{".1.3.6.1.4.1.2.1"}, 0, 5,
+			[]string{".1.3.6.1.4.1.2.2=Integer:22", ".1.3.6.1.4.1.2.2=EndOfMibView"},
+		},
+		{
+			// only non-repeaters
+			[]string{".1.3.6.1.2.1.1.3", ".1.3.6.1.4.1.2.2"}, 5, 5,
+			[]string{".1.3.6.1.2.1.1.3.0=Integer:100", ".1.3.6.1.4.1.2.2=EndOfMibView"},
+		},
+	}
+	for i, test := range tests {
+		result, err := x.GetNextBulk(test.oids, test.nonRepeaters, test.reps)
+		if err != nil {
+			t.Errorf("#%d: GetNextBulk() err: %v", i, err)
+			continue
+		}
+		var got []string
+		for _, pdu := range result.Variables {
+			if pdu.Type == EndOfMibView {
+				got = append(got, pdu.Name+"=EndOfMibView")
+			} else {
+				got = append(got, fmt.Sprintf("%s=%s:%v", pdu.Name, pdu.Type, pdu.Value))
+			}
+		}
+		if fmt.Sprint(got) != fmt.Sprint(test.want) {
+			t.Errorf("#%d: got %v, want %v", i, got, test.want)
+		}
+		if result.Error != NoError || result.PDUType != GetResponse {
+			t.Errorf("#%d: got %s %s, want a GetResponse without error", i, result.PDUType, result.Error)
+		}
+	}
+}
@@ -9,6 +9,7 @@
 package gosnmp
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"log"
@@ -16,6 +17,7 @@ import (
 	"math/rand"
 	"net"
 	"strconv"
+	"sync"
 	"time"
 )
 
@@ -34,6 +36,12 @@ const (
 )
 
 // GoSNMP represents GoSNMP library state
+//
+// Once connected, a GoSNMP may be used by many goroutines at once: requests
+// are sent on the one connection, and each response is matched to its
+// request by request id (see dispatcher). The exported fields are the
+// session's configuration, and must not be changed while requests are in
+// flight.
 type GoSNMP struct {
 	// Conn is net connection to use, typically established using GoSNMP.Connect()
 	Conn net.Conn
@@ -44,6 +52,41 @@ type GoSNMP struct {
 	// Port is a udp port
 	Port uint16
 
+	// LocalAddr is the local address that requests and traps are sent
+	// from, eg when a firewall or agent checks the manager's source ip.
+	// If empty the operating system chooses the address
+	LocalAddr string
+
+	// LocalPort is the local udp port that requests and traps are sent
+	// from. If 0 the operating system chooses the port
+	LocalPort uint16
+
+	// Dial, if set, opens Conn in Connect in place of a udp socket, given
+	// "udp" and the "host:port" of the Target, eg to use a MockConn
+	Dial func(network, address string) (net.Conn, error)
+
+	// AnySource, if set, makes Connect open an unconnected udp socket, to
+	// accept responses from any address rather than only the Target's:
+	// multi-homed agents may answer from another of their addresses. They
+	// are matched to requests by request-id (and SNMPv3 msgID) alone, so
+	// any host could answer; SNMPv3 responses must at least be from the
+	// agent's engine, once it's discovered. A response's Source is the
+	// address it came from. ICMP errors aren't received.
+	AnySource bool
+
+	// ResolveInterval, if set and the Target is a hostname, makes retries
+	// resolve it again when it was last resolved at least this long
+	// before, and send to its new address if the one in use is no longer
+	// among its addresses, so that long lived sessions follow DNS changes.
+	// Connect always resolves it. Go's resolver doesn't give the TTL of
+	// DNS records, so it can't be respected: set ResolveInterval to it.
+	ResolveInterval time.Duration
+
+	// RedialOnUnreachable, if set, makes a request that gets an ICMP
+	// unreachable (see ErrPortUnreachable) go on to its next attempt on a
+	// new socket, resolving a Target hostname again, rather than failing
+	RedialOnUnreachable bool
+
 	// Community is an SNMP Community string
 	Community string
 
@@ -56,11 +99,34 @@ type GoSNMP struct {
 	// Set the number of retries to attempt within timeout.
 	Retries int
 
+	// RetryPolicy decides how many times requests are sent and how long
+	// each attempt waits, eg an *ExponentialBackoff. If nil, Retries+1
+	// attempts are made, each waiting Timeout/(Retries+1), jittered.
+	RetryPolicy RetryPolicy
+
+	// OperationTimeout limits the time an operation can take, across all of
+	// its requests and their retries: a Get, a whole walk, and so on. An
+	// operation running out of time fails with context.DeadlineExceeded.
+	// (default: no limit)
+	OperationTimeout time.Duration
+
+	// RateLimiters make each packet wait until all of them allow it to be
+	// sent, eg a limiter per target and a limiter shared by all sessions.
+	// Time spent waiting counts towards OperationTimeout and the deadline
+	// of the RetryPolicy, but not the wait of an attempt.
+	RateLimiters []*RateLimiter
+
 	// Logger is the GoSNMP.Logger to use for debugging. If nil, debugging
 	// output will be discarded (/dev/null). For verbose logging to stdout:
 	// x.Logger = log.New(os.Stdout, "", 0)
 	Logger Logger
 
+	// LeveledLogger, if set, is given warnings and errors (eg responses
+	// that can't be decoded) with key/value pairs, and is used at debug
+	// level for the output otherwise given to Logger, unless Logger is
+	// set too. A *slog.Logger can be used, see LeveledLogger.
+	LeveledLogger LeveledLogger
+
 	// loggingEnabled is set if the Logger is nil, short circuits any 'Logger' calls
 	loggingEnabled bool
 
@@ -74,15 +140,99 @@ type GoSNMP struct {
 	// See comments in https://github.com/soniah/gosnmp/issues/100
 	MaxRepetitions uint8
 
+	// AdaptiveMaxRepetitions makes BulkWalk* tune max-repetitions to the
+	// agent, starting from MaxRepetitions: it is halved and the request
+	// retried when a GETBULK times out or gets a tooBig error, cut to what
+	// the agent returns when it returns fewer values than asked for, and
+	// grown back towards MaxRepetitions while responses come back whole.
+	// It never grows back to a value that failed. The tuned value is kept
+	// between walks.
+	AdaptiveMaxRepetitions bool
+
+	// EndOfMib is what walks do on reaching an endOfMibView, noSuchObject
+	// or noSuchInstance varbind (default: EndOfMibStop, ending the walk
+	// without reporting it). SNMPv1 agents end walks with a noSuchName
+	// error instead, which always ends the walk silently.
+	EndOfMib EndOfMib
+
+	// OidOrder is what walks do when an agent returns an oid that isn't
+	// after the one before it, as some broken agents do (default:
+	// OidOrderStrict, failing the walk)
+	OidOrder OidOrder
+
+	// OidOrderLimit is the number of out of order oids a walk accepts with
+	// OidOrderTolerate before failing (default: 100)
+	OidOrderLimit int
+
+	// Cache, if set, holds the values got by Get for its TTL, so that Gets
+	// of them in that time are answered without a request. Values are
+	// removed from it when they are Set. A Cache may be shared by
+	// sessions, eg those of a Pool.
+	Cache *Cache
+
 	// NonRepeaters sets the GETBULK max-repeaters used by BulkWalk*
 	// (default: 0 as per RFC 1905)
 	NonRepeaters int
 
+	// Tracer, if set, is given a span for each request and each attempt
+	// at it, see Tracer
+	Tracer Tracer
+
+	// OnSend, if set, is called with each packet just before it is sent,
+	// and the message encoded from it (authenticated and encrypted, for
+	// SNMPv3). It returns the bytes to send: out, a changed copy of it, or
+	// nil to send nothing, as if the packet were lost. An error fails the
+	// request with it.
+	OnSend func(packet *SnmpPacket, out []byte) ([]byte, error)
+
+	// OnReceive, if set, is called with each response decoded for a
+	// request, and the message as it was received. The packet may be
+	// changed. An error discards the response, as one that couldn't be
+	// decoded: the request goes on waiting for another, and if it times
+	// out its error wraps the error.
+	OnReceive func(packet *SnmpPacket, in []byte) error
+
+	// OnRetry, if set, is called before each attempt at a request after
+	// the first, with the attempt number (from 2) and the error the
+	// attempt before it failed with.
+	OnRetry func(packet *SnmpPacket, attempt int, err error)
+
+	// Capture, if set, is given every datagram sent and received, see
+	// Capture
+	Capture *Capture
+
+	// DecodeLimits, if set, makes the decoding of messages received
+	// strict, rejecting those beyond the limits before decoding their
+	// values, eg StrictDecodeLimits for a TrapListener's Params
+	DecodeLimits *DecodeLimits
+
+	// Lenient, if set, makes the decoding of messages received tolerate
+	// the bugs of broken agents: lengths that don't agree with what was
+	// received, integers with redundant leading octets, and zero-length
+	// values. The faults are recorded in the packet's Warnings, and a
+	// varbind that can't be decoded is left out of its Variables rather
+	// than failing the packet. Lazy varbinds aren't decoded leniently, and
+	// messages DecodeLimits rejects are still rejected.
+	Lenient bool
+
+	// OidValues, if set, makes OBJECT IDENTIFIER values received decode as
+	// Oids rather than dotted strings, eg a sysObjectID, so that they can
+	// be compared and prefix checked without parsing them. Names are
+	// dotted strings either way.
+	OidValues bool
+
+	// StrictIDs, if set, makes responses match the ids of a request
+	// strictly: the request-id of an SNMPv3 response must be the one sent
+	// with its msgID (rather than that of any attempt at the request), and
+	// only reports are accepted with request-id 0
+	StrictIDs bool
+
 	// Internal - used to sync requests to responses
 	requestID uint32
 	random    *rand.Rand
 
-	rxBuf *[rxBufSize]byte // has to be pointer due to https://github.com/golang/go/issues/11728
+	// Internal - set once the agent has mishandled GETBULK, see AutoWalk
+	bulkFailed uint32
 
 	// MsgFlags is an SNMPV3 MsgFlags
 	MsgFlags SnmpV3MsgFlags
@@ -99,8 +249,46 @@ type GoSNMP struct {
 	// ContextName is SNMPV3 ContextName in ScopedPDU
 	ContextName string
 
+	// MaxMessageSize is the SNMPv3 msgMaxSize the session advertises, the
+	// largest response it accepts (default: and at most, 65535). Requests
+	// larger than the msgMaxSize the agent advertises fail with
+	// ErrMessageTooLarge, see PeerMaxMessageSize.
+	MaxMessageSize int
+
+	// Internal - the msgMaxSize of the agent, see PeerMaxMessageSize
+	peerMaxSize uint32
+
+	// EngineID is the session's own snmpEngineID, the authoritative engine
+	// of the SNMPv3 traps it sends, which their receivers must be
+	// configured with (default: random, for the life of the session).
+	// EngineBoots is the number of times it has restarted, as for
+	// Agent.EngineBoots. The engine of informs is their receiver's, found
+	// by discovery as for requests.
+	EngineID    string
+	EngineBoots uint32
+
 	// Internal - used to sync requests to responses - snmpv3
 	msgID uint32
+
+	// Internal - requests queued by the *Async methods
+	async *asyncQueue
+
+	// Internal - the tuned max-repetitions, see AdaptiveMaxRepetitions
+	adaptive *adaptiveRepetitions
+
+	// Internal - reads responses, see dispatcher
+	mux *dispatcher
+
+	// Internal - the counts returned by Stats
+	stats *counters
+
+	// Internal - guards the SNMPv3 state updated by requests,
+	// SecurityParameters and ContextEngineID, see securityLock
+	secMu *sync.Mutex
+
+	// Internal - the session's own engine, see localEngine
+	localEngineID string
+	localStart    time.Time
 }
 
 // Default connection settings
@@ -127,6 +315,11 @@ type SnmpPDU struct {
 
 	// Logger implements the Logger interface
 	Logger Logger
+
+	// NameOid is the oid in Name as an Oid, filled in on the varbinds
+	// received. When sending, it is used if Name is empty; Name takes
+	// precedence otherwise. See SnmpPDU.Oid.
+	NameOid Oid
 }
 
 // Asn1BER is the type of the SNMP PDU
@@ -154,6 +347,13 @@ const (
 	NoSuchObject              = 0x80
 	NoSuchInstance            = 0x81
 	EndOfMibView              = 0x82
+
+	// OpaqueFloat and OpaqueDouble aren't BER types, but the types of
+	// float32 and float64 values carried in an Opaque, in the encoding used
+	// by net-snmp (the ASN.1 application types 120 and 121, wrapped in the
+	// Opaque); see opaque.go
+	OpaqueFloat  = 0x78
+	OpaqueDouble = 0x79
 )
 
 // SNMPError is the type for standard SNMP errors.
@@ -200,27 +400,50 @@ func (x *GoSNMP) Connect() error {
 		return err
 	}
 
-	addr := net.JoinHostPort(x.Target, strconv.Itoa(int(x.Port)))
-	x.Conn, err = net.DialTimeout("udp", addr, x.Timeout)
+	dialer := net.Dialer{Timeout: x.Timeout}
+	if x.LocalAddr != "" || x.LocalPort != 0 {
+		localAddr := net.JoinHostPort(x.LocalAddr, strconv.Itoa(int(x.LocalPort)))
+		dialer.LocalAddr, err = net.ResolveUDPAddr("udp", localAddr)
+		if err != nil {
+			return fmt.Errorf("Error resolving local address %s: %w", localAddr, err)
+		}
+	}
+
+	dial := func(addr string) (net.Conn, error) {
+		switch {
+		case x.Dial != nil:
+			return x.Dial("udp", addr)
+		case x.AnySource:
+			local, _ := dialer.LocalAddr.(*net.UDPAddr)
+			return listenAnySource(local, addr)
+		}
+		return dialer.Dial("udp", addr)
+	}
+	port := strconv.Itoa(int(x.Port))
+	if x.ResolveInterval > 0 && net.ParseIP(x.Target) == nil || x.RedialOnUnreachable {
+		x.Conn, err = x.dialResolving(x.Target, port, dial)
+	} else {
+		x.Conn, err = dial(net.JoinHostPort(x.Target, port))
+	}
 	if err != nil {
-		return fmt.Errorf("Error establishing connection to host: %s\n", err.Error())
+		return fmt.Errorf("Error establishing connection to host: %w\n", err)
 	}
 	if x.random == nil {
 		x.random = rand.New(rand.NewSource(time.Now().UTC().UnixNano()))
 	}
 	// http://tools.ietf.org/html/rfc3412#section-6 - msgID only
-	// uses the first 31 bits
-	// msgID INTEGER (0..2147483647)
-	x.msgID = uint32(x.random.Int31())
-	// RequestID is Integer32 from SNMPV2-SMI and uses all 32 bits
-	x.requestID = x.random.Uint32()
-
-	x.rxBuf = new([rxBufSize]byte)
+	// uses the first 31 bits, msgID INTEGER (0..2147483647). The
+	// request-id is independent of it, see ids.go.
+	x.msgID = x.randomID()
+	x.requestID = x.randomID()
 
 	return nil
 }
 
 func (x *GoSNMP) validateParameters() error {
+	if x.Logger == nil && x.LeveledLogger != nil {
+		x.Logger = debugLogger{x.LeveledLogger}
+	}
 	if x.Logger == nil {
 		x.Logger = log.New(ioutil.Discard, "", 0)
 	} else {
@@ -265,17 +488,22 @@ func (x *GoSNMP) mkSnmpPacketV1Trap(pdutype PDUType, enterprise []int, agentAddr
 
 func (x *GoSNMP) mkSnmpPacket(pdutype PDUType, pdus []SnmpPDU, nonRepeaters uint8, maxRepetitions uint8) *SnmpPacket {
 	var newSecParams SnmpV3SecurityParameters
+	mu := x.securityLock()
+	mu.Lock()
 	if x.SecurityParameters != nil {
 		newSecParams = x.SecurityParameters.Copy()
 	}
+	contextEngineID := x.ContextEngineID
+	mu.Unlock()
 	return &SnmpPacket{
 		Version:            x.Version,
 		Community:          x.Community,
 		MsgFlags:           x.MsgFlags,
 		SecurityModel:      x.SecurityModel,
 		SecurityParameters: newSecParams,
-		ContextEngineID:    x.ContextEngineID,
+		ContextEngineID:    contextEngineID,
 		ContextName:        x.ContextName,
+		MsgMaxSize:         x.MaxMessageSize,
 		Error:              0,
 		ErrorIndex:         0,
 		PDUType:            pdutype,
@@ -287,36 +515,65 @@ func (x *GoSNMP) mkSnmpPacket(pdutype PDUType, pdus []SnmpPDU, nonRepeaters uint
 
 // Get sends an SNMP GET request
 func (x *GoSNMP) Get(oids []string) (result *SnmpPacket, err error) {
+	return x.GetCtx(context.Background(), oids)
+}
+
+// GetCtx is like Get, but the request is abandoned when ctx is cancelled
+// or its deadline passes, in which case ctx.Err() is returned.
+func (x *GoSNMP) GetCtx(ctx context.Context, oids []string) (result *SnmpPacket, err error) {
 	oidCount := len(oids)
 	if oidCount > x.MaxOids {
 		return nil, fmt.Errorf("oid count (%d) is greater than MaxOids (%d)",
 			oidCount, x.MaxOids)
 	}
+	if x.Cache != nil && !optionsFrom(ctx).scoped() {
+		return x.cachedGet(ctx, oids)
+	}
+	return x.get(ctx, oids)
+}
+
+// get sends a GET request for oids
+func (x *GoSNMP) get(ctx context.Context, oids []string) (result *SnmpPacket, err error) {
 	// convert oids slice to pdu slice
 	var pdus []SnmpPDU
 	for _, oid := range oids {
-		pdus = append(pdus, SnmpPDU{oid, Null, nil, x.Logger})
+		pdus = append(pdus, SnmpPDU{Name: oid, Type: Null, Logger: x.Logger})
 	}
-	// build up SnmpPacket
-	packetOut := x.mkSnmpPacket(GetRequest, pdus, 0, 0)
-	return x.send(packetOut, true)
+	return x.sendSplitting(ctx, GetRequest, pdus)
 }
 
 // Set sends an SNMP SET request
 func (x *GoSNMP) Set(pdus []SnmpPDU) (result *SnmpPacket, err error) {
+	return x.SetCtx(context.Background(), pdus)
+}
+
+// SetCtx is like Set, but the request is abandoned when ctx is cancelled
+// or its deadline passes, in which case ctx.Err() is returned.
+func (x *GoSNMP) SetCtx(ctx context.Context, pdus []SnmpPDU) (result *SnmpPacket, err error) {
 	var packetOut *SnmpPacket
 	switch pdus[0].Type {
-	// TODO test Gauge32
-	case Integer, OctetString, Gauge32:
+	case Integer, OctetString, Gauge32, TimeTicks, ObjectIdentifier, IPAddress:
 		packetOut = x.mkSnmpPacket(SetRequest, pdus, 0, 0)
 	default:
-		return nil, fmt.Errorf("ERR:gosnmp currently only supports SNMP SETs for Integers and OctetStrings")
+		return nil, fmt.Errorf("ERR:gosnmp currently only supports SNMP SETs for Integers, OctetStrings, " +
+			"Gauge32s, TimeTicks, ObjectIdentifiers and IPAddresses")
+	}
+	if x.Cache != nil {
+		// the values may have changed even if the set seems to fail, eg
+		// when only the response is lost
+		defer x.invalidateCache(pdus)
 	}
-	return x.send(packetOut, true)
+	return x.send(ctx, packetOut, true)
 }
 
 // GetNext sends an SNMP GETNEXT request
 func (x *GoSNMP) GetNext(oids []string) (result *SnmpPacket, err error) {
+	return x.GetNextCtx(context.Background(), oids)
+}
+
+// GetNextCtx is like GetNext, but the request is abandoned when ctx is
+// cancelled or its deadline passes, in which case ctx.Err() is returned.
+func (x *GoSNMP) GetNextCtx(ctx context.Context, oids []string) (result *SnmpPacket, err error) {
 	oidCount := len(oids)
 	if oidCount > x.MaxOids {
 		return nil, fmt.Errorf("oid count (%d) is greater than MaxOids (%d)",
@@ -326,19 +583,22 @@ func (x *GoSNMP) GetNext(oids []string) (result *SnmpPacket, err error) {
 	// convert oids slice to pdu slice
 	var pdus []SnmpPDU
 	for _, oid := range oids {
-		pdus = append(pdus, SnmpPDU{oid, Null, nil, x.Logger})
+		pdus = append(pdus, SnmpPDU{Name: oid, Type: Null, Logger: x.Logger})
 	}
 
-	// Marshal and send the packet
-	packetOut := x.mkSnmpPacket(GetNextRequest, pdus, 0, 0)
-
-	return x.send(packetOut, true)
+	return x.sendSplitting(ctx, GetNextRequest, pdus)
 }
 
 // GetBulk sends an SNMP GETBULK request
 //
 // For maxRepetitions greater than 255, use BulkWalk() or BulkWalkAll()
 func (x *GoSNMP) GetBulk(oids []string, nonRepeaters uint8, maxRepetitions uint8) (result *SnmpPacket, err error) {
+	return x.GetBulkCtx(context.Background(), oids, nonRepeaters, maxRepetitions)
+}
+
+// GetBulkCtx is like GetBulk, but the request is abandoned when ctx is
+// cancelled or its deadline passes, in which case ctx.Err() is returned.
+func (x *GoSNMP) GetBulkCtx(ctx context.Context, oids []string, nonRepeaters uint8, maxRepetitions uint8) (result *SnmpPacket, err error) {
 	oidCount := len(oids)
 	if oidCount > x.MaxOids {
 		return nil, fmt.Errorf("oid count (%d) is greater than MaxOids (%d)",
@@ -348,12 +608,12 @@ func (x *GoSNMP) GetBulk(oids []string, nonRepeaters uint8, maxRepetitions uint8
 	// convert oids slice to pdu slice
 	var pdus []SnmpPDU
 	for _, oid := range oids {
-		pdus = append(pdus, SnmpPDU{oid, Null, nil, x.Logger})
+		pdus = append(pdus, SnmpPDU{Name: oid, Type: Null, Logger: x.Logger})
 	}
 
 	// Marshal and send the packet
 	packetOut := x.mkSnmpPacket(GetBulkRequest, pdus, nonRepeaters, maxRepetitions)
-	return x.send(packetOut, true)
+	return x.send(ctx, packetOut, true)
 }
 
 //
@@ -369,13 +629,43 @@ type WalkFunc func(dataUnit SnmpPDU) error
 // an error if either there is an underlaying SNMP error (e.g. GetBulk fails),
 // or if walkFn returns an error.
 func (x *GoSNMP) BulkWalk(rootOid string, walkFn WalkFunc) error {
-	return x.walk(GetBulkRequest, rootOid, walkFn)
+	return x.walk(context.Background(), GetBulkRequest, rootOid, walkFn)
+}
+
+// BulkWalkCtx is like BulkWalk, but the walk stops with ctx.Err() when ctx
+// is cancelled or its deadline passes.
+func (x *GoSNMP) BulkWalkCtx(ctx context.Context, rootOid string, walkFn WalkFunc) error {
+	return x.walk(ctx, GetBulkRequest, rootOid, walkFn)
 }
 
 // BulkWalkAll is similar to BulkWalk but returns a filled array of all values
 // rather than using a callback function to stream results.
 func (x *GoSNMP) BulkWalkAll(rootOid string) (results []SnmpPDU, err error) {
-	return x.walkAll(GetBulkRequest, rootOid)
+	return x.walkAll(context.Background(), GetBulkRequest, rootOid)
+}
+
+// BulkWalkAllCtx is like BulkWalkAll, but the walk stops with ctx.Err()
+// when ctx is cancelled or its deadline passes. The values retrieved before
+// that point are returned along with the error.
+func (x *GoSNMP) BulkWalkAllCtx(ctx context.Context, rootOid string) (results []SnmpPDU, err error) {
+	return x.walkAll(ctx, GetBulkRequest, rootOid)
+}
+
+// BulkWalkColumns walks several columns of a table side by side, as
+// snmptable does, asking for the next values of every column in each
+// GETBULK request rather than walking the columns one after another. This
+// takes far fewer requests than walking the whole of a wide table when
+// only a few of its columns are needed. walkFn is called for each value,
+// in the order the agent returns them (ie roughly row by row). SNMPv1
+// sessions use GETNEXT.
+func (x *GoSNMP) BulkWalkColumns(columnOids []string, walkFn WalkFunc) error {
+	return x.walkColumns(context.Background(), columnOids, walkFn)
+}
+
+// BulkWalkColumnsCtx is like BulkWalkColumns, but the walk stops with
+// ctx.Err() when ctx is cancelled or its deadline passes.
+func (x *GoSNMP) BulkWalkColumnsCtx(ctx context.Context, columnOids []string, walkFn WalkFunc) error {
+	return x.walkColumns(ctx, columnOids, walkFn)
 }
 
 // Walk retrieves a subtree of values using GETNEXT - a request is made for each
@@ -384,13 +674,26 @@ func (x *GoSNMP) BulkWalkAll(rootOid string) (results []SnmpPDU, err error) {
 // an error if either there is an underlaying SNMP error (e.g. GetNext fails),
 // or if walkFn returns an error.
 func (x *GoSNMP) Walk(rootOid string, walkFn WalkFunc) error {
-	return x.walk(GetNextRequest, rootOid, walkFn)
+	return x.walk(context.Background(), GetNextRequest, rootOid, walkFn)
+}
+
+// WalkCtx is like Walk, but the walk stops with ctx.Err() when ctx is
+// cancelled or its deadline passes.
+func (x *GoSNMP) WalkCtx(ctx context.Context, rootOid string, walkFn WalkFunc) error {
+	return x.walk(ctx, GetNextRequest, rootOid, walkFn)
 }
 
 // WalkAll is similar to Walk but returns a filled array of all values rather
 // than using a callback function to stream results.
 func (x *GoSNMP) WalkAll(rootOid string) (results []SnmpPDU, err error) {
-	return x.walkAll(GetNextRequest, rootOid)
+	return x.walkAll(context.Background(), GetNextRequest, rootOid)
+}
+
+// WalkAllCtx is like WalkAll, but the walk stops with ctx.Err() when ctx is
+// cancelled or its deadline passes. The values retrieved before that point
+// are returned along with the error.
+func (x *GoSNMP) WalkAllCtx(ctx context.Context, rootOid string) (results []SnmpPDU, err error) {
+	return x.walkAll(ctx, GetNextRequest, rootOid)
 }
 
 //
@@ -10,6 +10,7 @@
 package gosnmp_test // force external view
 
 import (
+	"context"
 	"io/ioutil"
 	"log"
 	"net"
@@ -23,6 +24,8 @@ func TestAPIConfigTypes(t *testing.T) {
 	g := &gosnmp.GoSNMP{}
 	g.Target = ""
 	g.Port = 0
+	g.LocalAddr = ""
+	g.LocalPort = 0
 	g.Community = ""
 	g.Version = gosnmp.Version1
 	g.Version = gosnmp.Version2c
@@ -56,6 +59,46 @@ func TestAPIGetMethodSignature(t *testing.T) {
 	_ = f
 }
 
+func TestAPIGetCtxMethodSignature(t *testing.T) {
+	var f func(context.Context, []string) (*gosnmp.SnmpPacket, error)
+	f = gosnmp.Default.GetCtx
+	f = gosnmp.Default.GetNextCtx
+	_ = f
+}
+
+func TestAPISetCtxMethodSignature(t *testing.T) {
+	var f func(context.Context, []gosnmp.SnmpPDU) (*gosnmp.SnmpPacket, error)
+	f = gosnmp.Default.SetCtx
+	_ = f
+}
+
+func TestAPIGetBulkCtxMethodSignature(t *testing.T) {
+	var f func(context.Context, []string, uint8, uint8) (*gosnmp.SnmpPacket, error)
+	f = gosnmp.Default.GetBulkCtx
+	_ = f
+}
+
+func TestAPIWalkCtxMethodSignature(t *testing.T) {
+	var f func(context.Context, string, gosnmp.WalkFunc) error
+	f = gosnmp.Default.WalkCtx
+	f = gosnmp.Default.BulkWalkCtx
+	_ = f
+}
+
+func TestAPIWalkAllCtxMethodSignature(t *testing.T) {
+	var f func(context.Context, string) ([]gosnmp.SnmpPDU, error)
+	f = gosnmp.Default.WalkAllCtx
+	f = gosnmp.Default.BulkWalkAllCtx
+	_ = f
+}
+
+func TestAPIGetAsyncMethodSignature(t *testing.T) {
+	var f func([]string, gosnmp.AsyncCallback)
+	f = gosnmp.Default.GetAsync
+	f = gosnmp.Default.GetNextAsync
+	_ = f
+}
+
 func TestAPISetMethodSignature(t *testing.T) {
 	var f func([]gosnmp.SnmpPDU) (*gosnmp.SnmpPacket, error)
 	f = gosnmp.Default.Set
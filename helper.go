@@ -77,7 +77,7 @@ func (x *GoSNMP) decodeValue(data []byte, msg string) (retVal *variable, err err
 		var err error
 		if ret, err = parseInt(data[cursor:length]); err != nil {
 			x.logPrintf("%v:", err)
-			return retVal, fmt.Errorf("bytes: % x err: %v", data, err)
+			return retVal, fmt.Errorf("bytes: % x err: %w", data, err)
 		}
 		retVal.Type = Integer
 		retVal.Value = ret
@@ -95,17 +95,17 @@ func (x *GoSNMP) decodeValue(data []byte, msg string) (retVal *variable, err err
 	case ObjectIdentifier:
 		// 0x06
 		x.logPrint("decodeValue: type is ObjectIdentifier")
-		rawOid, _, err := parseRawField(data, "OID")
+		length, cursor := parseLength(data)
+		oid, err := parseOid(data[cursor:length])
 		if err != nil {
-			return nil, fmt.Errorf("Error parsing OID Value: %s", err.Error())
-		}
-		var oid []int
-		var ok bool
-		if oid, ok = rawOid.([]int); !ok {
-			return nil, fmt.Errorf("unable to type assert rawOid |%v| to []int", rawOid)
+			return nil, fmt.Errorf("Error parsing OID Value: %w", err)
 		}
 		retVal.Type = ObjectIdentifier
-		retVal.Value = oidToString(oid)
+		if x.OidValues {
+			retVal.Value = oid
+		} else {
+			retVal.Value = oid.String()
+		}
 	case IPAddress:
 		// 0x40
 		x.logPrint("decodeValue: type is IPAddress")
@@ -162,6 +162,14 @@ func (x *GoSNMP) decodeValue(data []byte, msg string) (retVal *variable, err err
 		}
 		retVal.Type = TimeTicks
 		retVal.Value = ret
+	case Opaque:
+		// 0x44
+		x.logPrint("decodeValue: type is Opaque")
+		length, cursor := parseLength(data)
+		if length > len(data) {
+			return nil, fmt.Errorf("not enough data for opaque: %x", data)
+		}
+		retVal.Type, retVal.Value = parseOpaque(data[cursor:length])
 	case Counter64:
 		// 0x46
 		x.logPrint("decodeValue: type is Counter64")
@@ -325,16 +333,19 @@ func marshalBase128Int(out *bytes.Buffer, n int64) (err error) {
 
 // marshalInt16 builds a byte representation of
 // a 16 bit int in BigEndian form.
-func marshalInt16(value int) (rs []byte, err error) {
-	if value <= 0xff {
-		rs = []byte{byte(value)}
-		return rs, nil
+// marshalInt32 encodes an INTEGER or Integer32 as the fewest two's
+// complement octets
+func marshalInt32(value int) ([]byte, error) {
+	if value < math.MinInt32 || value > math.MaxInt32 {
+		return nil, fmt.Errorf("Unable to marshal %d: out of range for an Integer32", value)
 	}
-	if value > 0xff && value <= 0xffff {
-		rs = []byte{byte(((value >> 8) & 0xff)), byte((value & 0xff))}
-		return rs, nil
+	bs := make([]byte, 4)
+	binary.BigEndian.PutUint32(bs, uint32(int32(value)))
+	// drop leading octets that are only a sign extension of the next
+	for len(bs) > 1 && (bs[0] == 0 && bs[1]&0x80 == 0 || bs[0] == 0xff && bs[1]&0x80 != 0) {
+		bs = bs[1:]
 	}
-	return nil, fmt.Errorf("Unable to marshal %v", rs)
+	return bs, nil
 }
 
 // Counter32, Gauge32, TimeTicks, Unsigned32
@@ -355,6 +366,28 @@ func marshalUint32(v interface{}) ([]byte, error) {
 	return bs, nil
 }
 
+// marshalUint64 encodes a Counter64 as the fewest octets, with a leading
+// zero octet if the first would otherwise be taken as a sign
+func marshalUint64(v uint64) []byte {
+	bs := make([]byte, 9)
+	binary.BigEndian.PutUint64(bs[1:], v)
+	for len(bs) > 1 && bs[0] == 0 && bs[1]&0x80 == 0 {
+		bs = bs[1:]
+	}
+	return bs
+}
+
+// shortLengths holds the encodings of the short form lengths, so that
+// marshalLength doesn't allocate for them. Slices of it are returned with a
+// capacity of 1, so appending to one copies it rather than overwriting the
+// table.
+var shortLengths = func() (lengths [127]byte) {
+	for i := range lengths {
+		lengths[i] = byte(i)
+	}
+	return
+}()
+
 // marshalLength builds a byte representation of length
 //
 // http://luca.ntop.org/Teaching/Appunti/asn1.html
@@ -362,16 +395,16 @@ func marshalUint32(v interface{}) ([]byte, error) {
 // Length octets. There are two forms: short (for lengths between 0 and 127),
 // and long definite (for lengths between 0 and 2^1008 -1).
 //
-// * Short form. One octet. Bit 8 has value "0" and bits 7-1 give the length.
-// * Long form. Two to 127 octets. Bit 8 of first octet has value "1" and bits
-//   7-1 give the number of additional length octets. Second and following
-//   octets give the length, base 256, most significant digit first.
+//   - Short form. One octet. Bit 8 has value "0" and bits 7-1 give the length.
+//   - Long form. Two to 127 octets. Bit 8 of first octet has value "1" and bits
+//     7-1 give the number of additional length octets. Second and following
+//     octets give the length, base 256, most significant digit first.
 func marshalLength(length int) ([]byte, error) {
 	// more convenient to pass length as int than uint64. Therefore check < 0
 	if length < 0 {
 		return nil, fmt.Errorf("length must be greater than zero")
 	} else if length < 127 {
-		return []byte{byte(length)}, nil
+		return shortLengths[length : length+1 : length+1], nil
 	}
 
 	buf := new(bytes.Buffer)
@@ -394,10 +427,11 @@ func marshalLength(length int) ([]byte, error) {
 }
 
 func marshalObjectIdentifier(oid []int) (ret []byte, err error) {
-	out := new(bytes.Buffer)
 	if len(oid) < 2 || oid[0] > 6 || oid[1] >= 40 {
 		return nil, errors.New("invalid object identifier")
 	}
+	// most sub-identifiers fit in one or two bytes
+	out := bytes.NewBuffer(make([]byte, 0, 2*len(oid)))
 
 	err = out.WriteByte(byte(oid[0]*40 + oid[1]))
 	if err != nil {
@@ -419,21 +453,29 @@ func marshalOID(oid string) ([]byte, error) {
 
 	// Encode the oid
 	oid = strings.Trim(oid, ".")
-	oidParts := strings.Split(oid, ".")
-	oidBytes := make([]int, len(oidParts))
 
-	// Convert the string OID to an array of integers
-	for i := 0; i < len(oidParts); i++ {
-		oidBytes[i], err = strconv.Atoi(oidParts[i])
+	// Convert the string OID to an array of integers, without allocating
+	// for the common case of an oid of up to 32 parts
+	var parts [32]uint32
+	oidBytes := parts[:0]
+	for oid != "" {
+		var part string
+		if i := strings.IndexByte(oid, '.'); i >= 0 {
+			part, oid = oid[:i], oid[i+1:]
+		} else {
+			part, oid = oid, ""
+		}
+		n, err := strconv.ParseUint(part, 10, 32)
 		if err != nil {
-			return nil, fmt.Errorf("Unable to parse OID: %s\n", err.Error())
+			return nil, fmt.Errorf("Unable to parse OID: %w\n", err)
 		}
+		oidBytes = append(oidBytes, uint32(n))
 	}
 
-	mOid, err := marshalObjectIdentifier(oidBytes)
+	mOid, err := marshalOid(oidBytes)
 
 	if err != nil {
-		return nil, fmt.Errorf("Unable to marshal OID: %s\n", err.Error())
+		return nil, fmt.Errorf("Unable to marshal OID: %w\n", err)
 	}
 
 	return mOid, err
@@ -534,10 +576,10 @@ func parseInt(bytes []byte) (int, error) {
 // Length octets. There are two forms: short (for lengths between 0 and 127),
 // and long definite (for lengths between 0 and 2^1008 -1).
 //
-// * Short form. One octet. Bit 8 has value "0" and bits 7-1 give the length.
-// * Long form. Two to 127 octets. Bit 8 of first octet has value "1" and bits
-//   7-1 give the number of additional length octets. Second and following
-//   octets give the length, base 256, most significant digit first.
+//   - Short form. One octet. Bit 8 has value "0" and bits 7-1 give the length.
+//   - Long form. Two to 127 octets. Bit 8 of first octet has value "1" and bits
+//     7-1 give the number of additional length octets. Second and following
+//     octets give the length, base 256, most significant digit first.
 func parseLength(bytes []byte) (length int, cursor int) {
 	if len(bytes) <= 2 {
 		// handle null octet strings ie "0x04 0x00"
@@ -594,7 +636,7 @@ func parseRawField(data []byte, msg string) (interface{}, int, error) {
 		length, cursor := parseLength(data)
 		i, err := parseInt(data[cursor:length])
 		if err != nil {
-			return nil, 0, fmt.Errorf("Unable to parse raw INTEGER: %x err: %v", data, err)
+			return nil, 0, fmt.Errorf("Unable to parse raw INTEGER: %x err: %w", data, err)
 		}
 		return i, length, nil
 	case OctetString:
@@ -621,7 +663,7 @@ func parseRawField(data []byte, msg string) (interface{}, int, error) {
 		length, cursor := parseLength(data)
 		ret, err := parseInt(data[cursor:length])
 		if err != nil {
-			return nil, 0, fmt.Errorf("Error in parseInt: %s", err)
+			return nil, 0, fmt.Errorf("Error in parseInt: %w", err)
 		}
 		return ret, length, nil
 	}
@@ -727,3 +769,37 @@ func (s SnmpVersion) String() string {
 	}
 	return "3"
 }
+
+// -- Asn1BER ------------------------------------------------------------------
+
+var asn1BERNames = map[Asn1BER]string{
+	EndOfContents:     "EndOfContents",
+	Boolean:           "Boolean",
+	Integer:           "Integer",
+	BitString:         "BitString",
+	OctetString:       "OctetString",
+	Null:              "Null",
+	ObjectIdentifier:  "ObjectIdentifier",
+	ObjectDescription: "ObjectDescription",
+	IPAddress:         "IPAddress",
+	Counter32:         "Counter32",
+	Gauge32:           "Gauge32",
+	TimeTicks:         "TimeTicks",
+	Opaque:            "Opaque",
+	NsapAddress:       "NsapAddress",
+	Counter64:         "Counter64",
+	Uinteger32:        "Uinteger32",
+	NoSuchObject:      "NoSuchObject",
+	NoSuchInstance:    "NoSuchInstance",
+	EndOfMibView:      "EndOfMibView",
+	OpaqueFloat:       "OpaqueFloat",
+	OpaqueDouble:      "OpaqueDouble",
+}
+
+// String returns the name of the type's constant, eg "Counter32"
+func (a Asn1BER) String() string {
+	if name, ok := asn1BERNames[a]; ok {
+		return name
+	}
+	return fmt.Sprintf("Asn1BER(%#02x)", byte(a))
+}
@@ -4,7 +4,11 @@
 
 package gosnmp
 
-import "testing"
+import (
+	"bytes"
+	"strconv"
+	"testing"
+)
 
 func TestOidToString(t *testing.T) {
 	oid := []int{1, 2, 3, 4, 5}
@@ -59,6 +63,61 @@ func TestMarshalUint32(t *testing.T) {
 	}
 }
 
+func TestMarshalInt32(t *testing.T) {
+	tests := []struct {
+		value int
+		bytes []byte
+	}{
+		{0, []byte{0x00}},
+		{127, []byte{0x7f}},
+		{128, []byte{0x00, 0x80}},
+		{210, []byte{0x00, 0xd2}},
+		{256, []byte{0x01, 0x00}},
+		{-1, []byte{0xff}},
+		{-128, []byte{0x80}},
+		{-129, []byte{0xff, 0x7f}},
+		{2147483647, []byte{0x7f, 0xff, 0xff, 0xff}},
+		{-2147483648, []byte{0x80, 0x00, 0x00, 0x00}},
+	}
+	for _, test := range tests {
+		result, err := marshalInt32(test.value)
+		if err != nil || !bytes.Equal(result, test.bytes) {
+			t.Errorf("marshalInt32(%d) = % x, %v want % x", test.value, result, err, test.bytes)
+		}
+		if n, err := parseInt(result); err != nil || n != test.value {
+			t.Errorf("parseInt(% x) = %d, %v want %d", result, n, err, test.value)
+		}
+	}
+	if strconv.IntSize == 64 {
+		tooBig := int64(1) << 31
+		if _, err := marshalInt32(int(tooBig)); err == nil {
+			t.Errorf("marshalInt32(1<<31) succeeded")
+		}
+	}
+}
+
+func TestMarshalUint64(t *testing.T) {
+	tests := []struct {
+		value uint64
+		bytes []byte
+	}{
+		{0, []byte{0x00}},
+		{127, []byte{0x7f}},
+		{128, []byte{0x00, 0x80}},
+		{1 << 40, []byte{0x01, 0x00, 0x00, 0x00, 0x00, 0x00}},
+		{18446744073694786495, []byte{0x00, 0xff, 0xff, 0xff, 0xff, 0xff, 0x1e, 0xb3, 0xbf}},
+	}
+	for _, test := range tests {
+		result := marshalUint64(test.value)
+		if !bytes.Equal(result, test.bytes) {
+			t.Errorf("marshalUint64(%d) = % x want % x", test.value, result, test.bytes)
+		}
+		if n, err := parseUint64(result); err != nil || n != test.value {
+			t.Errorf("parseUint64(% x) = %d, %v want %d", result, n, err, test.value)
+		}
+	}
+}
+
 func TestParseUint64(t *testing.T) {
 	tests := []struct {
 		data []byte
@@ -0,0 +1,121 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// decodeMessage decodes a v1 or v2c message, as testResponder does
+func decodeMessage(x *GoSNMP, msg []byte) (*SnmpPacket, error) {
+	packet := new(SnmpPacket)
+	cursor, err := x.unmarshalHeader(msg, packet)
+	if err != nil {
+		return nil, err
+	}
+	packet.PDUType = PDUType(msg[cursor])
+	if err = x.unmarshalResponse(msg[cursor:], packet); err != nil {
+		return nil, err
+	}
+	return packet, nil
+}
+
+func TestHooks(t *testing.T) {
+	const oid = ".1.3.6.1.2.1.1.5.0"
+	r := newTestResponder(t, []SnmpPDU{{Name: oid, Type: OctetString, Value: []byte("name")}})
+	defer r.Close()
+	x := r.client(t)
+	defer x.Conn.Close()
+
+	var sent, received [][]byte
+	x.OnSend = func(packet *SnmpPacket, out []byte) ([]byte, error) {
+		if packet.PDUType != GetRequest {
+			t.Errorf("OnSend got a %s", packet.PDUType)
+		}
+		sent = append(sent, out)
+		return out, nil
+	}
+	x.OnReceive = func(packet *SnmpPacket, in []byte) error {
+		received = append(received, in)
+		// responses can be changed
+		packet.Variables[0].Value = "changed"
+		return nil
+	}
+	x.OnRetry = func(*SnmpPacket, int, error) {
+		t.Errorf("OnRetry called without a retry")
+	}
+	result, err := x.Get([]string{oid})
+	if err != nil {
+		t.Fatalf("Get() err: %v", err)
+	}
+	if len(sent) != 1 || len(received) != 1 {
+		t.Fatalf("got %d sent and %d received, expected 1 of each", len(sent), len(received))
+	}
+	if request, err := decodeMessage(x, sent[0]); err != nil || request.Variables[0].Name != oid {
+		t.Errorf("OnSend got %v, err %v, expected the request for %s", request, err, oid)
+	}
+	if response, err := decodeMessage(x, received[0]); err != nil || response.PDUType != GetResponse {
+		t.Errorf("OnReceive got %v, err %v, expected the response", response, err)
+	}
+	if got := result.Variables[0].Value; got != "changed" {
+		t.Errorf("got %v, expected the value changed by OnReceive", got)
+	}
+}
+
+func TestHooksFaultInjection(t *testing.T) {
+	const oid = ".1.3.6.1.2.1.1.5.0"
+	r := newTestResponder(t, []SnmpPDU{{Name: oid, Type: OctetString, Value: []byte("name")}})
+	defer r.Close()
+	x := r.client(t)
+	defer x.Conn.Close()
+	x.Timeout = 300 * time.Millisecond
+
+	// the first attempt is lost, and the retry succeeds
+	sends := 0
+	x.OnSend = func(packet *SnmpPacket, out []byte) ([]byte, error) {
+		sends++
+		if sends == 1 {
+			return nil, nil
+		}
+		return out, nil
+	}
+	var attempts []int
+	x.OnRetry = func(packet *SnmpPacket, attempt int, err error) {
+		attempts = append(attempts, attempt)
+		if !errors.Is(err, ErrTimeout) {
+			t.Errorf("OnRetry got err %v, expected a timeout", err)
+		}
+	}
+	if _, err := x.Get([]string{oid}); err != nil {
+		t.Fatalf("Get() err: %v", err)
+	}
+	if len(attempts) != 1 || attempts[0] != 2 {
+		t.Errorf("OnRetry got attempts %v, expected [2]", attempts)
+	}
+	if n := len(r.received()); n != 1 {
+		t.Errorf("responder got %d requests, expected 1", n)
+	}
+
+	// an OnSend error fails the request, without retries
+	failed := errors.New("injected")
+	x.OnRetry = nil
+	x.OnSend = func(*SnmpPacket, []byte) ([]byte, error) {
+		return nil, failed
+	}
+	if _, err := x.Get([]string{oid}); !errors.Is(err, failed) {
+		t.Errorf("got err %v, expected the OnSend error", err)
+	}
+
+	// discarded responses time out, with the OnReceive error
+	x.OnSend = nil
+	x.OnReceive = func(*SnmpPacket, []byte) error {
+		return failed
+	}
+	if _, err := x.Get([]string{oid}); !errors.Is(err, ErrTimeout) || !errors.Is(err, failed) {
+		t.Errorf("got err %v, expected a timeout wrapping the OnReceive error", err)
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"sync/atomic"
+)
+
+//
+// The request-ids and SNMPv3 msgIDs of requests
+//
+
+// maxID is the largest request-id and msgID sent: msgID is INTEGER
+// (0..2147483647), RFC 3412 section 6, and request-ids are kept to the
+// same range, as some agents mishandle negative ones
+const maxID = 1<<31 - 1
+
+// nextID advances the id counter, returning the new id: from maxID it
+// wraps to 1, as 0 is the request-id of reports about requests whose PDU
+// couldn't be decoded
+func nextID(counter *uint32) uint32 {
+	for {
+		id := atomic.LoadUint32(counter)
+		next := id%maxID + 1
+		if atomic.CompareAndSwapUint32(counter, id, next) {
+			return next
+		}
+	}
+}
+
+// randomID returns a random first id, from 1 to maxID
+func (x *GoSNMP) randomID() uint32 {
+	return uint32(x.random.Int31n(maxID)) + 1
+}
+
+// validResponseID reports whether result, a response matched to a request
+// by its request-id (or SNMPv3 msgID), has the ids of the request: one of
+// reqIDs, the request-ids of its attempts, or 0. With StrictIDs the
+// request-id of an SNMPv3 response must be the one sent with its msgID,
+// the request-id of each msgID being in msgIDs, and only reports may have
+// request-id 0.
+func (x *GoSNMP) validResponseID(result *SnmpPacket, reqIDs []uint32, msgIDs map[uint32]uint32) bool {
+	if result.RequestID == 0 {
+		return !x.StrictIDs || result.PDUType == Report
+	}
+	if x.StrictIDs && result.Version == Version3 {
+		reqID, ok := msgIDs[result.MsgID]
+		return ok && reqID == result.RequestID
+	}
+	for _, id := range reqIDs {
+		if id == result.RequestID {
+			return true
+		}
+	}
+	return false
+}
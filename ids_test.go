@@ -0,0 +1,65 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"testing"
+)
+
+func TestNextID(t *testing.T) {
+	tests := []struct {
+		counter, next uint32
+	}{
+		{0, 1},
+		{1, 2},
+		{maxID - 1, maxID},
+		{maxID, 1},
+		{1 << 31, 2},
+		{1<<32 - 1, 2},
+	}
+	for _, test := range tests {
+		counter := test.counter
+		if got := nextID(&counter); got != test.next || counter != test.next {
+			t.Errorf("nextID(%d) = %d, counter %d, expected %d", test.counter, got, counter, test.next)
+		}
+	}
+
+	x := &GoSNMP{Target: "mock", Dial: (&MockConn{}).Dial}
+	for i := 0; i < 100; i++ {
+		if err := x.Connect(); err != nil {
+			t.Fatalf("Connect() err: %v", err)
+		}
+		if x.requestID == 0 || x.requestID > maxID || x.msgID == 0 || x.msgID > maxID {
+			t.Fatalf("Connect() started at request-id %d, msgID %d", x.requestID, x.msgID)
+		}
+	}
+}
+
+func TestValidResponseID(t *testing.T) {
+	reqIDs := []uint32{10, 11}
+	msgIDs := map[uint32]uint32{100: 10, 101: 11}
+	tests := []struct {
+		strict bool
+		result SnmpPacket
+		valid  bool
+	}{
+		{false, SnmpPacket{Version: Version2c, PDUType: GetResponse, RequestID: 11}, true},
+		{false, SnmpPacket{Version: Version2c, PDUType: GetResponse, RequestID: 12}, false},
+		{false, SnmpPacket{Version: Version2c, PDUType: GetResponse, RequestID: 0}, true},
+		{true, SnmpPacket{Version: Version2c, PDUType: GetResponse, RequestID: 0}, false},
+		{false, SnmpPacket{Version: Version3, PDUType: GetResponse, MsgID: 100, RequestID: 11}, true},
+		{true, SnmpPacket{Version: Version3, PDUType: GetResponse, MsgID: 100, RequestID: 11}, false},
+		{true, SnmpPacket{Version: Version3, PDUType: GetResponse, MsgID: 101, RequestID: 11}, true},
+		{true, SnmpPacket{Version: Version3, PDUType: GetResponse, MsgID: 102, RequestID: 11}, false},
+		{true, SnmpPacket{Version: Version3, PDUType: GetResponse, MsgID: 100, RequestID: 0}, false},
+		{true, SnmpPacket{Version: Version3, PDUType: Report, MsgID: 100, RequestID: 0}, true},
+	}
+	for i, test := range tests {
+		x := &GoSNMP{StrictIDs: test.strict}
+		if got := x.validResponseID(&test.result, reqIDs, msgIDs); got != test.valid {
+			t.Errorf("%d: validResponseID() = %t, expected %t", i, got, test.valid)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/netip"
+)
+
+// IndexKind is the kind of an object in a table's INDEX clause, which
+// decides how its value is encoded in the instance oid of a row (RFC 2578
+// section 7.7)
+type IndexKind int
+
+const (
+	// IndexInteger is an integer object (INTEGER, Unsigned32 etc), encoded
+	// as one sub-identifier
+	IndexInteger IndexKind = iota
+
+	// IndexString is a variable length OCTET STRING, encoded as its length
+	// followed by a sub-identifier per octet
+	IndexString
+
+	// IndexFixedString is an OCTET STRING with a fixed SIZE, eg a
+	// MacAddress, encoded without its length
+	IndexFixedString
+
+	// IndexIPAddress is an IpAddress, encoded as four sub-identifiers
+	IndexIPAddress
+
+	// IndexOid is an OBJECT IDENTIFIER, encoded as its length followed by
+	// its sub-identifiers
+	IndexOid
+)
+
+// IndexPart describes an object in a table's INDEX clause
+type IndexPart struct {
+	Kind IndexKind
+
+	// Size is the length of an IndexFixedString
+	Size int
+
+	// Implied is set for an IndexString or IndexOid that is the last
+	// object of the INDEX and marked IMPLIED, whose length isn't encoded
+	Implied bool
+}
+
+// DecodeIndex decodes the index of a row, the part of a cell's oid after
+// its column, into the values of the objects described by parts. Integers
+// are decoded as uint32, strings as []byte, IpAddresses as dotted strings
+// (as in an IpAddress SnmpPDU) and OBJECT IDENTIFIERs as Oids.
+func DecodeIndex(index Oid, parts []IndexPart) ([]interface{}, error) {
+	values := make([]interface{}, 0, len(parts))
+	for i, part := range parts {
+		if part.Implied && i != len(parts)-1 {
+			return nil, fmt.Errorf("Index part %d: only the last part can be IMPLIED", i)
+		}
+
+		size := 0
+		switch part.Kind {
+		case IndexInteger:
+			size = 1
+		case IndexIPAddress:
+			size = 4
+		case IndexFixedString:
+			if part.Size <= 0 {
+				return nil, fmt.Errorf("Index part %d: invalid size %d", i, part.Size)
+			}
+			size = part.Size
+		case IndexString, IndexOid:
+			switch {
+			case part.Implied:
+				size = len(index)
+			case len(index) == 0:
+				return nil, fmt.Errorf("Index part %d: missing length", i)
+			case uint64(index[0]) > uint64(len(index)-1):
+				// checked before converting, as an int may not hold it
+				return nil, fmt.Errorf("Index part %d: %d sub-identifiers needed, %d left", i, index[0], len(index)-1)
+			default:
+				size = int(index[0])
+				index = index[1:]
+			}
+		default:
+			return nil, fmt.Errorf("Index part %d: unknown kind %d", i, part.Kind)
+		}
+		if size > len(index) {
+			return nil, fmt.Errorf("Index part %d: %d sub-identifiers needed, %d left", i, size, len(index))
+		}
+		raw := index[:size]
+		index = index[size:]
+
+		switch part.Kind {
+		case IndexInteger:
+			values = append(values, raw[0])
+		case IndexIPAddress:
+			b := make(net.IP, 4)
+			for j, n := range raw {
+				if n > math.MaxUint8 {
+					return nil, fmt.Errorf("Index part %d: invalid IpAddress %s", i, raw)
+				}
+				b[j] = byte(n)
+			}
+			values = append(values, b.String())
+		case IndexString, IndexFixedString:
+			b := make([]byte, size)
+			for j, n := range raw {
+				if n > math.MaxUint8 {
+					return nil, fmt.Errorf("Index part %d: invalid octet %d", i, n)
+				}
+				b[j] = byte(n)
+			}
+			values = append(values, b)
+		case IndexOid:
+			values = append(values, append(Oid(nil), raw...))
+		}
+	}
+	if len(index) > 0 {
+		return nil, fmt.Errorf("Index has %d sub-identifiers left over", len(index))
+	}
+	return values, nil
+}
+
+// EncodeIndex encodes the values of the objects described by parts as the
+// index of a row, to be appended to a column's oid for a GET or SET of a
+// cell. Integers may be of any integer type, strings a string or []byte,
+// IpAddresses a dotted string, net.IP or netip.Addr, and OBJECT
+// IDENTIFIERs an Oid or a dotted string.
+func EncodeIndex(parts []IndexPart, values ...interface{}) (Oid, error) {
+	if len(values) != len(parts) {
+		return nil, fmt.Errorf("Index has %d parts, %d values given", len(parts), len(values))
+	}
+	var index Oid
+	for i, part := range parts {
+		if part.Implied && i != len(parts)-1 {
+			return nil, fmt.Errorf("Index part %d: only the last part can be IMPLIED", i)
+		}
+		value := values[i]
+		invalid := fmt.Errorf("Index part %d: invalid value %v (%T)", i, value, value)
+
+		switch part.Kind {
+		case IndexInteger:
+			n, ok := integerValue(value)
+			if !ok || n.Sign() < 0 || !n.IsUint64() || n.Uint64() > math.MaxUint32 {
+				return nil, invalid
+			}
+			index = append(index, uint32(n.Uint64()))
+		case IndexIPAddress:
+			var ip netip.Addr
+			switch v := value.(type) {
+			case string:
+				ip, _ = netip.ParseAddr(v)
+			case net.IP:
+				ip, _ = netip.AddrFromSlice(v)
+			case netip.Addr:
+				ip = v
+			}
+			if ip = ip.Unmap(); !ip.Is4() {
+				return nil, invalid
+			}
+			for _, b := range ip.As4() {
+				index = append(index, uint32(b))
+			}
+		case IndexString, IndexFixedString:
+			var b []byte
+			switch v := value.(type) {
+			case string:
+				b = []byte(v)
+			case []byte:
+				b = v
+			default:
+				return nil, invalid
+			}
+			if part.Kind == IndexFixedString && part.Size <= 0 {
+				return nil, fmt.Errorf("Index part %d: invalid size %d", i, part.Size)
+			}
+			if part.Kind == IndexFixedString && len(b) != part.Size {
+				return nil, fmt.Errorf("Index part %d: length %d, want %d", i, len(b), part.Size)
+			}
+			if part.Kind == IndexString && !part.Implied {
+				index = append(index, uint32(len(b)))
+			}
+			for _, c := range b {
+				index = append(index, uint32(c))
+			}
+		case IndexOid:
+			var oid Oid
+			switch v := value.(type) {
+			case Oid:
+				oid = v
+			case string:
+				var err error
+				if oid, err = ParseOid(v); err != nil {
+					return nil, invalid
+				}
+			default:
+				return nil, invalid
+			}
+			if !part.Implied {
+				index = append(index, uint32(len(oid)))
+			}
+			index = append(index, oid...)
+		default:
+			return nil, fmt.Errorf("Index part %d: unknown kind %d", i, part.Kind)
+		}
+	}
+	return index, nil
+}
@@ -0,0 +1,131 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIndexRoundTrip(t *testing.T) {
+	tests := []struct {
+		parts  []IndexPart
+		values []interface{}
+		index  Oid
+	}{
+		{
+			// ifTable
+			[]IndexPart{{Kind: IndexInteger}},
+			[]interface{}{uint32(3)},
+			Oid{3},
+		},
+		{
+			// ipNetToMediaTable: ifIndex, IpAddress
+			[]IndexPart{{Kind: IndexInteger}, {Kind: IndexIPAddress}},
+			[]interface{}{uint32(2), "192.0.2.1"},
+			Oid{2, 192, 0, 2, 1},
+		},
+		{
+			// vacmSecurityToGroupTable: model, name
+			[]IndexPart{{Kind: IndexInteger}, {Kind: IndexString}},
+			[]interface{}{uint32(3), []byte("bob")},
+			Oid{3, 3, 98, 111, 98},
+		},
+		{
+			// snmpTargetAddrTable: IMPLIED name
+			[]IndexPart{{Kind: IndexString, Implied: true}},
+			[]interface{}{[]byte("nms")},
+			Oid{110, 109, 115},
+		},
+		{
+			// dot1dTpFdbTable: a MacAddress
+			[]IndexPart{{Kind: IndexFixedString, Size: 6}},
+			[]interface{}{[]byte{0, 0x1b, 0x21, 0x3c, 0x4d, 0x5e}},
+			Oid{0, 0x1b, 0x21, 0x3c, 0x4d, 0x5e},
+		},
+		{
+			[]IndexPart{{Kind: IndexOid}, {Kind: IndexOid, Implied: true}},
+			[]interface{}{Oid{1, 3, 6}, Oid{9, 9}},
+			Oid{3, 1, 3, 6, 9, 9},
+		},
+		{
+			[]IndexPart{{Kind: IndexString}, {Kind: IndexInteger}},
+			[]interface{}{[]byte{}, uint32(0)},
+			Oid{0, 0},
+		},
+	}
+	for _, test := range tests {
+		index, err := EncodeIndex(test.parts, test.values...)
+		if err != nil || !index.Equal(test.index) {
+			t.Errorf("EncodeIndex(%v): got %s, %v, want %s", test.values, index, err, test.index)
+		}
+		values, err := DecodeIndex(test.index, test.parts)
+		if err != nil || !reflect.DeepEqual(values, test.values) {
+			t.Errorf("DecodeIndex(%s): got %v, %v, want %v", test.index, values, err, test.values)
+		}
+	}
+}
+
+func TestEncodeIndexValues(t *testing.T) {
+	tests := []struct {
+		part  IndexPart
+		value interface{}
+		index Oid
+		err   string
+	}{
+		{IndexPart{Kind: IndexInteger}, 7, Oid{7}, ""},
+		{IndexPart{Kind: IndexInteger}, uint64(1) << 32, nil, "invalid value"},
+		{IndexPart{Kind: IndexInteger}, -1, nil, "invalid value"},
+		{IndexPart{Kind: IndexInteger}, "7", nil, "invalid value"},
+		{IndexPart{Kind: IndexString}, "ab", Oid{2, 97, 98}, ""},
+		{IndexPart{Kind: IndexFixedString, Size: 2}, "abc", nil, "length 3, want 2"},
+		{IndexPart{Kind: IndexFixedString}, "", nil, "invalid size 0"},
+		{IndexPart{Kind: IndexIPAddress}, net.IPv4(10, 0, 0, 1), Oid{10, 0, 0, 1}, ""},
+		{IndexPart{Kind: IndexIPAddress}, "2001:db8::1", nil, "invalid value"},
+		{IndexPart{Kind: IndexOid}, ".1.3", Oid{2, 1, 3}, ""},
+		{IndexPart{Kind: IndexOid}, 5, nil, "invalid value"},
+		{IndexPart{Kind: IndexKind(99)}, 5, nil, "unknown kind"},
+	}
+	for _, test := range tests {
+		index, err := EncodeIndex([]IndexPart{test.part}, test.value)
+		if !errorMatches(err, test.err) || !index.Equal(test.index) {
+			t.Errorf("EncodeIndex(%v): got %s, %v", test.value, index, err)
+		}
+	}
+
+	if _, err := EncodeIndex([]IndexPart{{Kind: IndexInteger}}); err == nil {
+		t.Errorf("EncodeIndex with no values: no error")
+	}
+	parts := []IndexPart{{Kind: IndexString, Implied: true}, {Kind: IndexInteger}}
+	if _, err := EncodeIndex(parts, "a", 1); err == nil || !strings.Contains(err.Error(), "IMPLIED") {
+		t.Errorf("EncodeIndex with IMPLIED first: got %v", err)
+	}
+}
+
+func TestDecodeIndexErrors(t *testing.T) {
+	tests := []struct {
+		index Oid
+		parts []IndexPart
+		err   string
+	}{
+		{Oid{}, []IndexPart{{Kind: IndexInteger}}, "1 sub-identifiers needed, 0 left"},
+		{Oid{1, 2}, []IndexPart{{Kind: IndexInteger}}, "1 sub-identifiers left over"},
+		{Oid{}, []IndexPart{{Kind: IndexString}}, "missing length"},
+		{Oid{5, 97}, []IndexPart{{Kind: IndexString}}, "5 sub-identifiers needed"},
+		{Oid{4294967295, 1}, []IndexPart{{Kind: IndexOid}}, "4294967295 sub-identifiers needed, 1 left"},
+		{Oid{1, 2}, []IndexPart{{Kind: IndexFixedString}}, "invalid size 0"},
+		{Oid{1, 2}, []IndexPart{{Kind: IndexFixedString, Size: -1}}, "invalid size -1"},
+		{Oid{1, 256}, []IndexPart{{Kind: IndexString}}, "invalid octet 256"},
+		{Oid{10, 0, 300, 1}, []IndexPart{{Kind: IndexIPAddress}}, "invalid IpAddress"},
+		{Oid{1, 1}, []IndexPart{{Kind: IndexOid, Implied: true}, {Kind: IndexInteger}}, "IMPLIED"},
+	}
+	for _, test := range tests {
+		if _, err := DecodeIndex(test.index, test.parts); !errorMatches(err, test.err) {
+			t.Errorf("DecodeIndex(%s): got %v, want %q", test.index, err, test.err)
+		}
+	}
+}
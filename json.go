@@ -0,0 +1,306 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+//
+// JSON encoding of PDUs and packets
+//
+// The default encoding of an SnmpPDU loses its type, and turns Values into
+// whatever encoding/json makes of them; so a PDU can't be decoded back
+// into what was received. SnmpPDU and SnmpPacket implement json.Marshaler
+// and json.Unmarshaler instead, giving the type by name and the value in a
+// form particular to it:
+//
+//	{"name": ".1.3.6.1.2.1.2.2.1.10.1", "type": "Counter32", "value": 1234}
+//	{"name": ".1.3.6.1.2.1.2.2.1.6.1", "type": "OctetString", "value": "001b213c9df8"}
+//
+// Numbers are JSON numbers, OctetStrings and Opaques are hex, oids and IP
+// addresses are strings, and floats that JSON can't represent are the
+// strings "NaN", "+Inf" and "-Inf". NoSuchObject etc have a null value.
+// Decoding gives a Value of the Go type that a received PDU of that type
+// has (eg a uint for Counter32, an int for TimeTicks).
+//
+
+type jsonPDU struct {
+	Name  string          `json:"name"`
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+// MarshalJSON encodes the PDU with its type, see the JSON notes above
+func (p SnmpPDU) MarshalJSON() ([]byte, error) {
+	var value interface{}
+	switch p.Type {
+	case OctetString, Opaque:
+		switch v := p.Value.(type) {
+		case []byte:
+			value = hex.EncodeToString(v)
+		case Bits:
+			value = hex.EncodeToString(v)
+		case string:
+			value = hex.EncodeToString([]byte(v))
+		default:
+			return nil, fmt.Errorf("Unable to marshal %s %s to JSON; not []byte or string", p.Name, p.Type)
+		}
+	case OpaqueFloat, OpaqueDouble:
+		var f float64
+		switch v := p.Value.(type) {
+		case float32:
+			f = float64(v)
+		case float64:
+			f = v
+		default:
+			return nil, fmt.Errorf("Unable to marshal %s %s to JSON; not float32 or float64", p.Name, p.Type)
+		}
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			// "NaN", "+Inf" or "-Inf"
+			value = strconv.FormatFloat(f, 'g', -1, 64)
+		} else {
+			value = f
+		}
+	case ObjectIdentifier:
+		switch v := p.Value.(type) {
+		case Oid:
+			value = v.String()
+		default:
+			value = p.Value
+		}
+	case Null, NoSuchObject, NoSuchInstance, EndOfMibView:
+		value = nil
+	case Integer, Counter32, Gauge32, TimeTicks, Counter64, Uinteger32:
+		switch p.Value.(type) {
+		case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		default:
+			return nil, fmt.Errorf("Unable to marshal %s %s to JSON; value %v isn't a number", p.Name, p.Type, p.Value)
+		}
+		n := ToBigInt(p.Value)
+		if n.Sign() < 0 && p.Type != Integer {
+			return nil, fmt.Errorf("Unable to marshal %s %s to JSON; value %v is negative", p.Name, p.Type, p.Value)
+		}
+		value = json.Number(n.String())
+	default:
+		value = p.Value
+	}
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to marshal %s %s to JSON: %w", p.Name, p.Type, err)
+	}
+	return json.Marshal(jsonPDU{p.Name, p.Type.String(), raw})
+}
+
+// UnmarshalJSON decodes a PDU encoded by MarshalJSON
+func (p *SnmpPDU) UnmarshalJSON(data []byte) error {
+	var j jsonPDU
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	t, ok := parseAsn1BER(j.Type)
+	if !ok {
+		return fmt.Errorf("Unable to unmarshal PDU %s: unknown type %q", j.Name, j.Type)
+	}
+	value, err := unmarshalJSONValue(t, j.Value)
+	if err != nil {
+		return fmt.Errorf("Unable to unmarshal PDU %s: %w", j.Name, err)
+	}
+	p.Name, p.Type, p.Value = j.Name, t, value
+	return nil
+}
+
+func parseAsn1BER(name string) (Asn1BER, bool) {
+	for t, n := range asn1BERNames {
+		if n == name {
+			return t, true
+		}
+	}
+	return 0, false
+}
+
+func unmarshalJSONValue(t Asn1BER, raw json.RawMessage) (interface{}, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		switch t {
+		case Null, NoSuchObject, NoSuchInstance, EndOfMibView, IPAddress:
+			return nil, nil
+		}
+		return nil, fmt.Errorf("%s value is missing", t)
+	}
+
+	switch t {
+	case OctetString, Opaque:
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return nil, err
+		}
+		b, err := hex.DecodeString(s)
+		if err != nil {
+			return nil, fmt.Errorf("%s value isn't hex: %w", t, err)
+		}
+		return b, nil
+
+	case OpaqueFloat, OpaqueDouble:
+		var f float64
+		var s string
+		if err := json.Unmarshal(raw, &s); err == nil {
+			if s != "NaN" && s != "+Inf" && s != "-Inf" {
+				return nil, fmt.Errorf("%s value %q isn't a number", t, s)
+			}
+			f, _ = strconv.ParseFloat(s, 64)
+		} else if err = json.Unmarshal(raw, &f); err != nil {
+			return nil, err
+		}
+		if t == OpaqueFloat {
+			return float32(f), nil
+		}
+		return f, nil
+
+	case Integer, TimeTicks:
+		var n int
+		err := json.Unmarshal(raw, &n)
+		return n, err
+	case Counter32, Gauge32:
+		var n uint32
+		err := json.Unmarshal(raw, &n)
+		return uint(n), err
+	case Uinteger32:
+		var n uint32
+		err := json.Unmarshal(raw, &n)
+		return n, err
+	case Counter64:
+		var n uint64
+		err := json.Unmarshal(raw, &n)
+		return n, err
+
+	case ObjectIdentifier, IPAddress:
+		var s string
+		err := json.Unmarshal(raw, &s)
+		return s, err
+	}
+
+	var v interface{}
+	err := json.Unmarshal(raw, &v)
+	return v, err
+}
+
+type jsonPacket struct {
+	Version         string    `json:"version"`
+	Community       string    `json:"community,omitempty"`
+	MsgFlags        uint8     `json:"msgFlags,omitempty"`
+	SecurityModel   uint8     `json:"securityModel,omitempty"`
+	MsgID           uint32    `json:"msgID,omitempty"`
+	ContextEngineID string    `json:"contextEngineID,omitempty"`
+	ContextName     string    `json:"contextName,omitempty"`
+	PDUType         uint8     `json:"pduType"`
+	RequestID       uint32    `json:"requestID"`
+	Error           uint8     `json:"error"`
+	ErrorIndex      uint8     `json:"errorIndex"`
+	NonRepeaters    uint8     `json:"nonRepeaters,omitempty"`
+	MaxRepetitions  uint8     `json:"maxRepetitions,omitempty"`
+	Variables       []SnmpPDU `json:"variables"`
+
+	// Trap V1 header
+	Enterprise   string `json:"enterprise,omitempty"`
+	AgentAddr    string `json:"agentAddr,omitempty"`
+	GenericTrap  int    `json:"genericTrap,omitempty"`
+	SpecificTrap int    `json:"specificTrap,omitempty"`
+	Timestamp    int    `json:"timestamp,omitempty"`
+}
+
+// MarshalJSON encodes the packet, a ContextEngineID as hex. The
+// SecurityParameters aren't included, as they hold keys; nor are undecoded
+// LazyVariables, decode them into Variables first.
+func (packet SnmpPacket) MarshalJSON() ([]byte, error) {
+	j := jsonPacket{
+		Version:         packet.Version.String(),
+		Community:       packet.Community,
+		MsgFlags:        uint8(packet.MsgFlags),
+		SecurityModel:   uint8(packet.SecurityModel),
+		MsgID:           packet.MsgID,
+		ContextEngineID: hex.EncodeToString([]byte(packet.ContextEngineID)),
+		ContextName:     packet.ContextName,
+		PDUType:         uint8(packet.PDUType),
+		RequestID:       packet.RequestID,
+		Error:           uint8(packet.Error),
+		ErrorIndex:      packet.ErrorIndex,
+		NonRepeaters:    packet.NonRepeaters,
+		MaxRepetitions:  packet.MaxRepetitions,
+		Variables:       packet.Variables,
+		AgentAddr:       packet.AgentAddr,
+		GenericTrap:     packet.GenericTrap,
+		SpecificTrap:    packet.SpecificTrap,
+		Timestamp:       packet.Timestamp,
+	}
+	if j.Variables == nil {
+		j.Variables = []SnmpPDU{}
+	}
+	if len(packet.Enterprise) > 0 {
+		j.Enterprise = oidToString(packet.Enterprise)
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON decodes a packet encoded by MarshalJSON
+func (packet *SnmpPacket) UnmarshalJSON(data []byte) error {
+	var j jsonPacket
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	var version SnmpVersion
+	switch j.Version {
+	case "1":
+		version = Version1
+	case "2c":
+		version = Version2c
+	case "3":
+		version = Version3
+	default:
+		return fmt.Errorf("Unable to unmarshal packet: unknown version %q", j.Version)
+	}
+	engineID, err := hex.DecodeString(j.ContextEngineID)
+	if err != nil {
+		return fmt.Errorf("Unable to unmarshal packet: contextEngineID isn't hex: %w", err)
+	}
+	var enterprise []int
+	if j.Enterprise != "" {
+		oid, err := ParseOid(j.Enterprise)
+		if err != nil {
+			return fmt.Errorf("Unable to unmarshal packet: enterprise: %w", err)
+		}
+		enterprise = make([]int, len(oid))
+		for i, n := range oid {
+			enterprise[i] = int(n)
+		}
+	}
+
+	*packet = SnmpPacket{
+		Version:         version,
+		Community:       j.Community,
+		MsgFlags:        SnmpV3MsgFlags(j.MsgFlags),
+		SecurityModel:   SnmpV3SecurityModel(j.SecurityModel),
+		MsgID:           j.MsgID,
+		ContextEngineID: string(engineID),
+		ContextName:     j.ContextName,
+		PDUType:         PDUType(j.PDUType),
+		RequestID:       j.RequestID,
+		Error:           SNMPError(j.Error),
+		ErrorIndex:      j.ErrorIndex,
+		NonRepeaters:    j.NonRepeaters,
+		MaxRepetitions:  j.MaxRepetitions,
+		Variables:       j.Variables,
+		Enterprise:      enterprise,
+		AgentAddr:       j.AgentAddr,
+		GenericTrap:     j.GenericTrap,
+		SpecificTrap:    j.SpecificTrap,
+		Timestamp:       j.Timestamp,
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPDUJSON(t *testing.T) {
+	tests := []struct {
+		pdu  SnmpPDU
+		json string
+	}{
+		{SnmpPDU{Name: ".1.3.6.1.2.1.2.2.1.8.1", Type: Integer, Value: -3},
+			`{"name":".1.3.6.1.2.1.2.2.1.8.1","type":"Integer","value":-3}`},
+		{SnmpPDU{Name: ".1.3.6.1.2.1.2.2.1.6.1", Type: OctetString, Value: []byte{0, 0x1b, 0x21}},
+			`{"name":".1.3.6.1.2.1.2.2.1.6.1","type":"OctetString","value":"001b21"}`},
+		{SnmpPDU{Name: ".1.3.6.1.2.1.1.2.0", Type: ObjectIdentifier, Value: ".1.3.6.1.4.1.8072.3.2.10"},
+			`{"name":".1.3.6.1.2.1.1.2.0","type":"ObjectIdentifier","value":".1.3.6.1.4.1.8072.3.2.10"}`},
+		{SnmpPDU{Name: ".1.3.6.1.2.1.4.20.1.1.127.0.0.1", Type: IPAddress, Value: "127.0.0.1"},
+			`{"name":".1.3.6.1.2.1.4.20.1.1.127.0.0.1","type":"IPAddress","value":"127.0.0.1"}`},
+		{SnmpPDU{Name: ".1.3.6.1.2.1.2.2.1.10.1", Type: Counter32, Value: uint(4294967295)},
+			`{"name":".1.3.6.1.2.1.2.2.1.10.1","type":"Counter32","value":4294967295}`},
+		{SnmpPDU{Name: ".1.3.6.1.2.1.2.2.1.5.1", Type: Gauge32, Value: uint(1000000000)},
+			`{"name":".1.3.6.1.2.1.2.2.1.5.1","type":"Gauge32","value":1000000000}`},
+		{SnmpPDU{Name: ".1.3.6.1.2.1.1.3.0", Type: TimeTicks, Value: 123456},
+			`{"name":".1.3.6.1.2.1.1.3.0","type":"TimeTicks","value":123456}`},
+		{SnmpPDU{Name: ".1.3.6.1.2.1.31.1.1.1.6.1", Type: Counter64, Value: uint64(18446744073709551615)},
+			`{"name":".1.3.6.1.2.1.31.1.1.1.6.1","type":"Counter64","value":18446744073709551615}`},
+		{SnmpPDU{Name: ".1.3.6.1.4.1.2021.10.1.6.1", Type: OpaqueFloat, Value: float32(0.25)},
+			`{"name":".1.3.6.1.4.1.2021.10.1.6.1","type":"OpaqueFloat","value":0.25}`},
+		{SnmpPDU{Name: ".1.3.6.1.4.1.2021.10.1.6.1", Type: OpaqueDouble, Value: math.Inf(-1)},
+			`{"name":".1.3.6.1.4.1.2021.10.1.6.1","type":"OpaqueDouble","value":"-Inf"}`},
+		{SnmpPDU{Name: ".1.3.6.1.4.1.2021.10.1.6.1", Type: Opaque, Value: []byte{1, 2}},
+			`{"name":".1.3.6.1.4.1.2021.10.1.6.1","type":"Opaque","value":"0102"}`},
+		{SnmpPDU{Name: ".1.3.6.1.2.1.1.9.0", Type: NoSuchInstance},
+			`{"name":".1.3.6.1.2.1.1.9.0","type":"NoSuchInstance","value":null}`},
+		{SnmpPDU{Name: ".1.3.6.1.2.1.1.1.0", Type: Null},
+			`{"name":".1.3.6.1.2.1.1.1.0","type":"Null","value":null}`},
+	}
+	for _, test := range tests {
+		got, err := json.Marshal(test.pdu)
+		if err != nil {
+			t.Errorf("%s: %v", test.pdu.Name, err)
+			continue
+		}
+		if string(got) != test.json {
+			t.Errorf("%s: got %s, want %s", test.pdu.Name, got, test.json)
+		}
+		var back SnmpPDU
+		if err = json.Unmarshal(got, &back); err != nil {
+			t.Errorf("%s: unmarshal: %v", test.pdu.Name, err)
+			continue
+		}
+		if !reflect.DeepEqual(back, test.pdu) {
+			t.Errorf("%s: got back %#v, want %#v", test.pdu.Name, back, test.pdu)
+		}
+	}
+
+	var nan SnmpPDU
+	if err := json.Unmarshal([]byte(`{"name":".1","type":"OpaqueFloat","value":"NaN"}`), &nan); err != nil ||
+		!math.IsNaN(float64(nan.Value.(float32))) {
+		t.Errorf("NaN: got %#v, %v", nan, err)
+	}
+}
+
+func TestPDUJSONErrors(t *testing.T) {
+	for _, pdu := range []SnmpPDU{
+		{Name: ".1", Type: Counter32, Value: -1},
+		{Name: ".1", Type: Integer, Value: "1"},
+		{Name: ".1", Type: OctetString, Value: 1},
+		{Name: ".1", Type: OpaqueFloat, Value: 1},
+	} {
+		if _, err := json.Marshal(pdu); err == nil {
+			t.Errorf("%s %#v: expected an error", pdu.Type, pdu.Value)
+		}
+	}
+
+	for _, s := range []string{
+		`{"name":".1","type":"Float","value":1}`,
+		`{"name":".1","type":"OctetString","value":"xyz"}`,
+		`{"name":".1","type":"Counter32","value":4294967296}`,
+		`{"name":".1","type":"Integer","value":null}`,
+		`{"name":".1","type":"OpaqueDouble","value":"one"}`,
+	} {
+		var pdu SnmpPDU
+		if err := json.Unmarshal([]byte(s), &pdu); err == nil {
+			t.Errorf("%s: expected an error, got %#v", s, pdu)
+		}
+	}
+}
+
+func TestPacketJSON(t *testing.T) {
+	packet := SnmpPacket{
+		Version:         Version2c,
+		Community:       "public",
+		ContextEngineID: "\x80\x00\x1f\x88",
+		PDUType:         Trap,
+		RequestID:       1234,
+		Error:           NoSuchName,
+		ErrorIndex:      1,
+		Variables: []SnmpPDU{
+			{Name: ".1.3.6.1.2.1.1.3.0", Type: TimeTicks, Value: 1},
+			{Name: ".1.3.6.1.2.1.1.1.0", Type: OctetString, Value: []byte("router")},
+		},
+		Enterprise:   []int{1, 3, 6, 1, 4, 1, 8072},
+		AgentAddr:    "192.0.2.1",
+		GenericTrap:  6,
+		SpecificTrap: 2,
+		Timestamp:    100,
+	}
+	b, err := json.Marshal(&packet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `"contextEngineID":"80001f88"`) || !strings.Contains(string(b), `"enterprise":".1.3.6.1.4.1.8072"`) {
+		t.Errorf("got %s", b)
+	}
+	var back SnmpPacket
+	if err = json.Unmarshal(b, &back); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(back, packet) {
+		t.Errorf("got back %+v, want %+v", back, packet)
+	}
+
+	if err = json.Unmarshal([]byte(`{"version":"4"}`), &back); err == nil {
+		t.Errorf("expected an error for an unknown version")
+	}
+}
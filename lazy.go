@@ -0,0 +1,367 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+)
+
+//
+// Lazy decoding of responses
+//
+
+// LazyPDU is a varbind from a response that hasn't been decoded. It refers
+// to the received message rather than copying from it, and its name and
+// value are only decoded when asked for; so reading a few varbinds from a
+// large GETBULK response costs little more than receiving it.
+//
+// The slices returned by a LazyPDU share memory with the message, and must
+// not be modified.
+type LazyPDU struct {
+	name  []byte // contents of the encoded OBJECT IDENTIFIER
+	value []byte // the encoded value, type and length included
+	x     *GoSNMP
+}
+
+// Type returns the BER type of the value, without decoding it
+func (p LazyPDU) Type() Asn1BER {
+	return Asn1BER(p.value[0])
+}
+
+// Name decodes and returns the varbind's oid, in the same form as
+// SnmpPDU.Name (eg ".1.3.6.1.2.1.1.1.0")
+func (p LazyPDU) Name() (string, error) {
+	oid, err := p.Oid()
+	if err != nil {
+		return "", err
+	}
+	return oid.String(), nil
+}
+
+// Oid decodes and returns the varbind's oid
+func (p LazyPDU) Oid() (Oid, error) {
+	oid, err := parseOid(p.name)
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing OID Value: %w", err)
+	}
+	return oid, nil
+}
+
+// NameEquals reports whether the varbind's oid is oid, without decoding or
+// allocating. oid may be given with or without the leading dot.
+func (p LazyPDU) NameEquals(oid string) bool {
+	if len(oid) > 0 && oid[0] == '.' {
+		oid = oid[1:]
+	}
+	if len(p.name) == 0 || len(oid) == 0 || oid[len(oid)-1] == '.' {
+		return false
+	}
+
+	// The first byte is 40*value1 + value2
+	var ok bool
+	if oid, ok = consumeOidPart(oid, int(p.name[0])/40); !ok {
+		return false
+	}
+	if oid, ok = consumeOidPart(oid, int(p.name[0])%40); !ok {
+		return false
+	}
+	for offset := 1; offset < len(p.name); {
+		var v int
+		var err error
+		v, offset, err = parseBase128Int(p.name, offset)
+		if err != nil {
+			return false
+		}
+		if oid, ok = consumeOidPart(oid, v); !ok {
+			return false
+		}
+	}
+	return oid == ""
+}
+
+// consumeOidPart checks that oid starts with the number n, followed by a dot
+// or the end of oid, and returns what follows
+func consumeOidPart(oid string, n int) (string, bool) {
+	i := 0
+	v := 0
+	for i < len(oid) && oid[i] >= '0' && oid[i] <= '9' {
+		v = v*10 + int(oid[i]-'0')
+		i++
+	}
+	if i == 0 || v != n {
+		return oid, false
+	}
+	if i < len(oid) {
+		if oid[i] != '.' {
+			return oid, false
+		}
+		i++
+	}
+	return oid[i:], true
+}
+
+// Bytes returns the contents of the encoded value, eg the bytes of an
+// OctetString, without decoding them
+func (p LazyPDU) Bytes() []byte {
+	length, cursor := parseLength(p.value)
+	return p.value[cursor:length]
+}
+
+// Decode fully decodes the varbind
+func (p LazyPDU) Decode() (SnmpPDU, error) {
+	oid, err := p.Oid()
+	if err != nil {
+		return SnmpPDU{}, err
+	}
+	v, err := p.x.decodeValue(p.value, "value")
+	if err != nil {
+		return SnmpPDU{}, fmt.Errorf("Error decoding value: %w", err)
+	}
+	return SnmpPDU{Name: oid.String(), Type: v.Type, Value: v.Value, Logger: p.x.Logger, NameOid: oid}, nil
+}
+
+// GetLazy is like Get, but the varbinds of the response are returned
+// undecoded in result.LazyVariables (and result.Variables is empty).
+func (x *GoSNMP) GetLazy(oids []string) (result *SnmpPacket, err error) {
+	return x.sendLazy(context.Background(), GetRequest, oids, 0, 0)
+}
+
+// GetNextLazy is like GetNext, but the varbinds of the response are
+// returned undecoded in result.LazyVariables (and result.Variables is
+// empty).
+func (x *GoSNMP) GetNextLazy(oids []string) (result *SnmpPacket, err error) {
+	return x.sendLazy(context.Background(), GetNextRequest, oids, 0, 0)
+}
+
+// GetBulkLazy is like GetBulk, but the varbinds of the response are
+// returned undecoded in result.LazyVariables (and result.Variables is
+// empty).
+func (x *GoSNMP) GetBulkLazy(oids []string, nonRepeaters uint8, maxRepetitions uint8) (result *SnmpPacket, err error) {
+	return x.sendLazy(context.Background(), GetBulkRequest, oids, nonRepeaters, maxRepetitions)
+}
+
+func (x *GoSNMP) sendLazy(ctx context.Context, pdutype PDUType, oids []string, nonRepeaters uint8, maxRepetitions uint8) (result *SnmpPacket, err error) {
+	oidCount := len(oids)
+	if oidCount > x.MaxOids {
+		return nil, fmt.Errorf("oid count (%d) is greater than MaxOids (%d)",
+			oidCount, x.MaxOids)
+	}
+
+	// convert oids slice to pdu slice
+	var pdus []SnmpPDU
+	for _, oid := range oids {
+		pdus = append(pdus, SnmpPDU{Name: oid, Type: Null, Logger: x.Logger})
+	}
+
+	packetOut := x.mkSnmpPacket(pdutype, pdus, nonRepeaters, maxRepetitions)
+	packetOut.lazyVarbinds = true
+	return x.send(ctx, packetOut, true)
+}
+
+// unmarshalLazyVarbind splits an encoded varbind into its oid and value
+func (x *GoSNMP) unmarshalLazyVarbind(packet []byte) (pdu LazyPDU, err error) {
+	if Asn1BER(packet[0]) != ObjectIdentifier {
+		return pdu, fmt.Errorf("Error parsing OID Value: expected an OID, got %x", packet[0])
+	}
+	oidLength, oidCursor := parseLength(packet)
+	if oidLength > len(packet) {
+		return pdu, fmt.Errorf("Error parsing OID Value: truncated")
+	}
+	valueLength, _ := parseLength(packet[oidLength:])
+	if valueLength == 0 || oidLength+valueLength > len(packet) {
+		return pdu, fmt.Errorf("Error decoding value: truncated")
+	}
+	return LazyPDU{
+		name:  packet[oidCursor:oidLength],
+		value: packet[oidLength : oidLength+valueLength],
+		x:     x,
+	}, nil
+}
+
+// NameHasSuffix reports whether the varbind's oid ends with suffix, eg
+// Oid{0} for a scalar or Oid{3} for row 3 of a table with a single
+// integer index, without decoding or allocating
+func (p LazyPDU) NameHasSuffix(suffix Oid) bool {
+	end := len(p.name)
+	for i := len(suffix) - 1; i >= 0; i-- {
+		// each sub-identifier after the first byte ends with a byte with
+		// the top bit clear
+		start := end - 1
+		for start > 1 && p.name[start-1]&0x80 != 0 {
+			start--
+		}
+		if start < 1 {
+			// the suffix reaches into the first two sub-identifiers, which
+			// share a byte
+			oid, err := p.Oid()
+			return err == nil && len(oid) >= len(suffix) && oid[len(oid)-len(suffix):].Equal(suffix)
+		}
+		v, next, err := parseBase128Int(p.name, start)
+		if err != nil || next != end || uint32(v) != suffix[i] {
+			return false
+		}
+		end = start
+	}
+	return true
+}
+
+// WalkFilter chooses which varbinds are reported by a filtered walk, before
+// they're decoded. Filters mustn't keep the LazyPDU after returning.
+type WalkFilter func(pdu LazyPDU) bool
+
+// SuffixFilter returns a WalkFilter passing varbinds whose oid ends with
+// one of suffixes, eg []Oid{{1}, {3}} for rows 1 and 3 of ifTable
+func SuffixFilter(suffixes ...Oid) WalkFilter {
+	return func(pdu LazyPDU) bool {
+		for _, suffix := range suffixes {
+			if pdu.NameHasSuffix(suffix) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// WalkFiltered is like Walk, but only the varbinds that filter passes are
+// decoded and given to walkFn. The others are skipped as the responses
+// arrive, without the cost of decoding them.
+func (x *GoSNMP) WalkFiltered(rootOid string, filter WalkFilter, walkFn WalkFunc) error {
+	return x.walkLazy(context.Background(), GetNextRequest, rootOid, filter, walkFn)
+}
+
+// WalkFilteredCtx is like WalkFiltered, but the walk stops with ctx.Err()
+// when ctx is cancelled or its deadline passes.
+func (x *GoSNMP) WalkFilteredCtx(ctx context.Context, rootOid string, filter WalkFilter, walkFn WalkFunc) error {
+	return x.walkLazy(ctx, GetNextRequest, rootOid, filter, walkFn)
+}
+
+// BulkWalkFiltered is like BulkWalk, but filtered, see WalkFiltered
+func (x *GoSNMP) BulkWalkFiltered(rootOid string, filter WalkFilter, walkFn WalkFunc) error {
+	return x.walkLazy(context.Background(), GetBulkRequest, rootOid, filter, walkFn)
+}
+
+// BulkWalkFilteredCtx is like BulkWalkFiltered, but the walk stops with
+// ctx.Err() when ctx is cancelled or its deadline passes.
+func (x *GoSNMP) BulkWalkFilteredCtx(ctx context.Context, rootOid string, filter WalkFilter, walkFn WalkFunc) error {
+	return x.walkLazy(ctx, GetBulkRequest, rootOid, filter, walkFn)
+}
+
+// walkLazy walks like walk, with lazily decoded responses
+func (x *GoSNMP) walkLazy(ctx context.Context, getRequestType PDUType, rootOid string, filter WalkFilter, walkFn WalkFunc) error {
+	ctx, cancel := x.withBudget(ctx)
+	defer cancel()
+	rootOid = walkRoot(rootOid)
+	root, err := ParseOid(rootOid)
+	if err != nil {
+		return err
+	}
+	if len(root) < 2 {
+		// the encoding of the first two parts is shared, so the subtree
+		// of a one part oid can't be matched by prefix
+		return fmt.Errorf("Unable to filter a walk of %s", rootOid)
+	}
+	prefix, err := marshalOid(root)
+	if err != nil {
+		return err
+	}
+	maxReps := x.maxRepetitions(ctx)
+
+	oid := rootOid
+	var prev []byte
+	order := x.newWalkOrder(oid)
+	requests := 0
+	for {
+		requests++
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var response *SnmpPacket
+		if getRequestType == GetBulkRequest {
+			response, err = x.sendLazy(ctx, GetBulkRequest, []string{oid}, uint8(x.NonRepeaters), uint8(maxReps))
+		} else {
+			response, err = x.sendLazy(ctx, GetNextRequest, []string{oid}, 0, 0)
+		}
+		if err != nil {
+			return err
+		}
+		if response.Error == NoSuchName || len(response.LazyVariables) == 0 {
+			break
+		}
+
+		end, accepted := false, false
+		for _, v := range response.LazyVariables {
+			if isException(v.Type()) {
+				pdu, err := v.Decode()
+				if err != nil {
+					return err
+				}
+				report := walkFn
+				if !filter(v) {
+					report = func(SnmpPDU) error { return nil }
+				}
+				if err := x.walkException(pdu, report); err != nil {
+					return err
+				}
+				end = true
+				break
+			}
+			// encoded oids sort as their numbers do, and one is in the
+			// subtree of another if its encoding starts with the other's
+			if len(v.name) <= len(prefix) || !bytes.HasPrefix(v.name, prefix) {
+				end = true
+				break
+			}
+			if x.OidOrder == OidOrderStrict {
+				if prev != nil && bytes.Compare(v.name, prev) <= 0 {
+					name, _ := v.Name()
+					return fmt.Errorf("OID not increasing: %s", name)
+				}
+				prev = append(prev[:0], v.name...)
+			} else {
+				// the other policies need the names decoded
+				name, err := v.Name()
+				if err != nil {
+					return err
+				}
+				ok, err := order.accept(name)
+				if err != nil {
+					return err
+				}
+				if !ok {
+					continue
+				}
+			}
+			accepted = true
+			if !filter(v) {
+				continue
+			}
+			pdu, err := v.Decode()
+			if err != nil {
+				return err
+			}
+			if err := walkFn(pdu); err != nil {
+				return err
+			}
+		}
+		if end {
+			break
+		}
+		if !accepted {
+			return loopError(oid)
+		}
+		if x.OidOrder != OidOrderStrict {
+			oid = order.prev
+			continue
+		}
+		last := response.LazyVariables[len(response.LazyVariables)-1]
+		if oid, err = last.Name(); err != nil {
+			return err
+		}
+	}
+	x.Logger.Printf("Filtered walk completed in %d requests", requests)
+	return nil
+}
@@ -0,0 +1,138 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestGetBulkLazy(t *testing.T) {
+	var pdus []SnmpPDU
+	for i := 1; i <= 20; i++ {
+		pdus = append(pdus, SnmpPDU{Name: fmt.Sprintf(".1.3.6.1.2.1.2.2.1.2.%d", i), Type: OctetString, Value: []byte(fmt.Sprintf("eth%d", i))})
+	}
+	r := newTestResponder(t, pdus)
+	defer r.Close()
+	x := r.client(t)
+	defer x.Conn.Close()
+
+	result, err := x.GetBulkLazy([]string{".1.3.6.1.2.1.2.2.1.2"}, 0, 20)
+	if err != nil {
+		t.Fatalf("GetBulkLazy() err: %v", err)
+	}
+	if len(result.Variables) != 0 {
+		t.Errorf("GetBulkLazy(): expected no decoded Variables, got %d", len(result.Variables))
+	}
+	if len(result.LazyVariables) != len(pdus) {
+		t.Fatalf("GetBulkLazy(): got %d LazyVariables, want %d", len(result.LazyVariables), len(pdus))
+	}
+
+	for i, lazy := range result.LazyVariables {
+		want := pdus[i]
+		if lazy.Type() != OctetString {
+			t.Errorf("%d: Type() got %v, want %v", i, lazy.Type(), OctetString)
+		}
+		if !lazy.NameEquals(want.Name) {
+			t.Errorf("%d: NameEquals(%s) got false", i, want.Name)
+		}
+		if name, err := lazy.Name(); err != nil || name != want.Name {
+			t.Errorf("%d: Name() got %s, %v, want %s", i, name, err, want.Name)
+		}
+		if got := lazy.Bytes(); !reflect.DeepEqual(got, want.Value) {
+			t.Errorf("%d: Bytes() got %q, want %q", i, got, want.Value)
+		}
+		pdu, err := lazy.Decode()
+		if err != nil {
+			t.Errorf("%d: Decode() err: %v", i, err)
+		} else if pdu.Name != want.Name || pdu.Type != want.Type || !reflect.DeepEqual(pdu.Value, want.Value) {
+			t.Errorf("%d: Decode() got %v, want %v", i, pdu, want)
+		}
+	}
+}
+
+func TestLazyPDUNameEquals(t *testing.T) {
+	lazy := LazyPDU{name: []byte{0x2b, 6, 1, 2, 1, 2, 2, 1, 0x82, 0x2c}} // 1.3.6.1.2.1.2.2.1.300
+	for _, tt := range []struct {
+		oid  string
+		want bool
+	}{
+		{".1.3.6.1.2.1.2.2.1.300", true},
+		{"1.3.6.1.2.1.2.2.1.300", true},
+		{".1.3.6.1.2.1.2.2.1.30", false},
+		{".1.3.6.1.2.1.2.2.1.3000", false},
+		{".1.3.6.1.2.1.2.2.1", false},
+		{".1.3.6.1.2.1.2.2.1.300.1", false},
+		{".1.3.6.1.2.1.2.2.1.300.", false},
+		{"", false},
+	} {
+		if got := lazy.NameEquals(tt.oid); got != tt.want {
+			t.Errorf("NameEquals(%q) got %v, want %v", tt.oid, got, tt.want)
+		}
+	}
+}
+
+func TestLazyPDUNameHasSuffix(t *testing.T) {
+	lazy := LazyPDU{name: []byte{0x2b, 6, 1, 2, 1, 2, 2, 1, 0x82, 0x2c}} // 1.3.6.1.2.1.2.2.1.300
+	for _, tt := range []struct {
+		suffix Oid
+		want   bool
+	}{
+		{Oid{300}, true},
+		{Oid{1, 300}, true},
+		{Oid{}, true},
+		{Oid{3, 6, 1, 2, 1, 2, 2, 1, 300}, true},
+		{Oid{1, 3, 6, 1, 2, 1, 2, 2, 1, 300}, true},
+		{Oid{0, 1, 3, 6, 1, 2, 1, 2, 2, 1, 300}, false},
+		{Oid{44}, false},
+		{Oid{2, 300}, false},
+		{Oid{2, 6, 1, 2, 1, 2, 2, 1, 300}, false},
+	} {
+		if got := lazy.NameHasSuffix(tt.suffix); got != tt.want {
+			t.Errorf("NameHasSuffix(%v) got %t, want %t", tt.suffix, got, tt.want)
+		}
+	}
+}
+
+func TestBulkWalkFiltered(t *testing.T) {
+	var pdus []SnmpPDU
+	for i := 1; i <= 200; i++ {
+		pdus = append(pdus, SnmpPDU{Name: fmt.Sprintf(".1.3.6.1.2.1.2.2.1.2.%d", i), Type: OctetString, Value: []byte(fmt.Sprintf("eth%d", i))})
+	}
+	pdus = append(pdus, SnmpPDU{Name: ".1.3.6.1.2.1.2.3.0", Type: Integer, Value: 1})
+	r := newTestResponder(t, pdus)
+	defer r.Close()
+
+	for _, version := range []SnmpVersion{Version1, Version2c} {
+		x := r.client(t)
+		x.Version = version
+		x.MaxRepetitions = 30
+
+		var names []string
+		walk := x.BulkWalkFiltered
+		if version == Version1 {
+			walk = x.WalkFiltered
+		}
+		err := walk(".1.3.6.1.2.1.2.2", SuffixFilter(Oid{2}, Oid{130}), func(pdu SnmpPDU) error {
+			names = append(names, pdu.Name)
+			return nil
+		})
+		x.Conn.Close()
+		if err != nil {
+			t.Fatalf("%s: err: %v", version, err)
+		}
+		want := []string{".1.3.6.1.2.1.2.2.1.2.2", ".1.3.6.1.2.1.2.2.1.2.130"}
+		if !reflect.DeepEqual(names, want) {
+			t.Errorf("%s: got %v, want %v", version, names, want)
+		}
+	}
+
+	x := r.client(t)
+	defer x.Conn.Close()
+	if err := x.BulkWalkFiltered(".1", SuffixFilter(), func(SnmpPDU) error { return nil }); err == nil {
+		t.Errorf("walk of .1: no error")
+	}
+}
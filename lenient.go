@@ -0,0 +1,190 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"fmt"
+	"strconv"
+)
+
+//
+// Lenient decoding, tolerating the bugs of broken agents
+//
+
+// DecodeWarning is a fault of a message decoded leniently (see
+// GoSNMP.Lenient), which didn't stop it being decoded
+type DecodeWarning struct {
+	// Index is the index of the varbind with the fault in the packet's
+	// varbinds as received, or -1 for a fault of the message
+	Index int
+
+	// Name is the oid of the varbind, if it could be decoded
+	Name string
+
+	// Msg describes the fault
+	Msg string
+}
+
+func (w DecodeWarning) Error() string {
+	switch {
+	case w.Index < 0:
+		return w.Msg
+	case w.Name == "":
+		return "varbind " + strconv.Itoa(w.Index) + ": " + w.Msg
+	}
+	return "varbind " + strconv.Itoa(w.Index) + " (" + w.Name + "): " + w.Msg
+}
+
+// warn records a DecodeWarning in response
+func (x *GoSNMP) warn(response *SnmpPacket, index int, name, format string, v ...interface{}) {
+	w := DecodeWarning{Index: index, Name: name, Msg: fmt.Sprintf(format, v...)}
+	x.logWarn("Decoding leniently", "warning", w.Error())
+	response.Warnings = append(response.Warnings, w)
+}
+
+// lenientCut returns packet, a TLV whose header says it is length bytes
+// long, cut to that length if it is shorter, warning that it isn't
+func (x *GoSNMP) lenientCut(response *SnmpPacket, packet []byte, length int, what string) []byte {
+	x.warn(response, -1, "", "%s length %d, %d bytes received", what, length, len(packet))
+	if length > 0 && length < len(packet) {
+		return packet[:length]
+	}
+	return packet
+}
+
+// lenientTLV returns the length of the header and of the contents of the
+// TLV at the start of b, and false if the header can't be read; the
+// contents may be longer than what remains of b
+func lenientTLV(b []byte) (header, length int, ok bool) {
+	if len(b) < 2 || b[1] == 0x80 {
+		return 0, 0, false
+	}
+	if b[1] < 0x80 {
+		return 2, int(b[1]), true
+	}
+	n := int(b[1] & 0x7f)
+	if n > 4 || len(b) < 2+n {
+		return 0, 0, false
+	}
+	for _, c := range b[2 : 2+n] {
+		length = length<<8 | int(c)
+	}
+	return 2 + n, length, true
+}
+
+// unmarshalVBLLenient is unmarshalVBL for Lenient sessions: lengths that
+// don't agree with what was received are corrected, and varbinds that
+// can't be decoded are left out, with warnings
+func (x *GoSNMP) unmarshalVBLLenient(packet []byte, response *SnmpPacket) error {
+	if len(packet) < 2 || packet[0] != 0x30 {
+		return fmt.Errorf("Expected a sequence when unmarshalling a VBL, got % x", packet)
+	}
+	header, length, ok := lenientTLV(packet)
+	if !ok {
+		return fmt.Errorf("Unable to read the VBL length: % x", packet)
+	}
+	end := header + length
+	if end != len(packet) {
+		x.warn(response, -1, "", "varbind list length %d, %d bytes received", length, len(packet)-header)
+		if end > len(packet) {
+			end = len(packet)
+		}
+	}
+
+	for cursor, index := header, 0; cursor < end; index++ {
+		if packet[cursor] != 0x30 {
+			x.warn(response, index, "", "expected a varbind sequence, got %#x; ignoring the rest", packet[cursor])
+			break
+		}
+		vbHeader, vbLength, ok := lenientTLV(packet[cursor:end])
+		if !ok {
+			x.warn(response, index, "", "unable to read the varbind length; ignoring the rest")
+			break
+		}
+		vbEnd := cursor + vbHeader + vbLength
+		if vbEnd > end {
+			x.warn(response, index, "", "varbind length %d exceeds the varbind list", vbLength)
+			vbEnd = end
+		}
+		vb := packet[cursor+vbHeader : vbEnd]
+		cursor = vbEnd
+
+		if len(vb) == 0 || Asn1BER(vb[0]) != ObjectIdentifier {
+			x.warn(response, index, "", "varbind has no name")
+			continue
+		}
+		oidHeader, oidLength, ok := lenientTLV(vb)
+		if !ok || oidHeader+oidLength > len(vb) {
+			x.warn(response, index, "", "varbind name length exceeds the varbind")
+			continue
+		}
+		oid, err := parseOid(vb[oidHeader : oidHeader+oidLength])
+		if err != nil {
+			x.warn(response, index, "", "unable to decode the name: %v", err)
+			continue
+		}
+		name := oid.String()
+
+		v, err := x.lenientValue(response, index, name, vb[oidHeader+oidLength:])
+		if err != nil {
+			x.warn(response, index, name, "%v", err)
+			continue
+		}
+		response.Variables = append(response.Variables, SnmpPDU{Name: name, Type: v.Type, Value: v.Value, Logger: x.Logger, NameOid: oid})
+	}
+	return nil
+}
+
+// lenientValue decodes the value of a varbind, b, correcting a length past
+// the end of the varbind, integers with redundant leading octets and empty
+// OBJECT IDENTIFIERs, with warnings
+func (x *GoSNMP) lenientValue(response *SnmpPacket, index int, name string, b []byte) (*variable, error) {
+	header, length, ok := lenientTLV(b)
+	if !ok {
+		return nil, fmt.Errorf("unable to read the value's length")
+	}
+	tag := Asn1BER(b[0])
+	if header+length > len(b) {
+		x.warn(response, index, name, "value length %d exceeds the varbind", length)
+		length = len(b) - header
+	}
+	contents := b[header : header+length]
+
+	switch tag {
+	case Integer, TimeTicks, Counter32, Gauge32, Counter64, Uinteger32:
+		n := 0
+		for n < len(contents)-1 && (contents[n] == 0 && contents[n+1]&0x80 == 0 ||
+			contents[n] == 0xff && contents[n+1]&0x80 != 0 && (tag == Integer || tag == TimeTicks)) {
+			n++
+		}
+		if n > 0 {
+			x.warn(response, index, name, "%s has redundant leading octets (%d)", tag, n)
+			contents = contents[n:]
+		}
+		if len(contents) == 0 {
+			x.warn(response, index, name, "zero-length %s", tag)
+		}
+	case ObjectIdentifier:
+		if len(contents) == 0 {
+			x.warn(response, index, name, "zero-length ObjectIdentifier, taken as .0.0")
+			if x.OidValues {
+				return &variable{Type: ObjectIdentifier, Value: Oid{0, 0}}, nil
+			}
+			return &variable{Type: ObjectIdentifier, Value: ".0.0"}, nil
+		}
+	}
+
+	// the value again, with its corrected length
+	tlv, err := marshalLength(len(contents))
+	if err != nil {
+		return nil, err
+	}
+	tlv = append(append([]byte{byte(tag)}, tlv...), contents...)
+	v, err := x.decodeValue(tlv, "value")
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode the value: %w", err)
+	}
+	return v, nil
+}
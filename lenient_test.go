@@ -0,0 +1,152 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testTLV encodes a BER TLV of tag, whose contents are parts
+func testTLV(tag byte, parts ...[]byte) []byte {
+	var contents []byte
+	for _, p := range parts {
+		contents = append(contents, p...)
+	}
+	length, _ := marshalLength(len(contents))
+	return append(append([]byte{tag}, length...), contents...)
+}
+
+// testResponseMessage encodes an SNMPv2c response of vbl, the varbind list
+func testResponseMessage(vbl []byte) []byte {
+	return testTLV(0x30,
+		testTLV(0x02, []byte{0x01}),
+		testTLV(0x04, []byte("public")),
+		testTLV(byte(GetResponse),
+			testTLV(0x02, []byte{0x07}),
+			testTLV(0x02, []byte{0x00}),
+			testTLV(0x02, []byte{0x00}),
+			vbl))
+}
+
+func TestLenientDecode(t *testing.T) {
+	sysDescr := []byte{0x06, 0x08, 0x2b, 0x06, 0x01, 0x02, 0x01, 0x01, 0x01, 0x00}
+	sysName := []byte{0x06, 0x08, 0x2b, 0x06, 0x01, 0x02, 0x01, 0x01, 0x05, 0x00}
+	descr := testTLV(0x30, sysDescr, testTLV(0x04, []byte("descr")))
+
+	tests := []struct {
+		name     string
+		msg      []byte
+		want     []SnmpPDU
+		warnings []string
+		strictOK bool
+	}{
+		{
+			name:     "well formed",
+			msg:      testResponseMessage(testTLV(0x30, descr)),
+			want:     []SnmpPDU{{Name: testSysDescr, Type: OctetString, Value: []byte("descr")}},
+			strictOK: true,
+		},
+		{
+			name: "redundant leading octets",
+			msg: testResponseMessage(testTLV(0x30,
+				testTLV(0x30, sysDescr, testTLV(0x02, []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 5})),
+				testTLV(0x30, sysName, testTLV(0x02, []byte{0xff, 0xff, 0xfe})),
+				testTLV(0x30, sysName, testTLV(byte(Counter32), []byte{0, 0, 0x80})))),
+			want: []SnmpPDU{
+				{Name: testSysDescr, Type: Integer, Value: 5},
+				{Name: testSysName, Type: Integer, Value: -2},
+				{Name: testSysName, Type: Counter32, Value: uint(0x80)},
+			},
+			warnings: []string{
+				"varbind 0 (.1.3.6.1.2.1.1.1.0): Integer has redundant leading octets (9)",
+				"varbind 1 (.1.3.6.1.2.1.1.5.0): Integer has redundant leading octets (2)",
+				"varbind 2 (.1.3.6.1.2.1.1.5.0): Counter32 has redundant leading octets (1)",
+			},
+		},
+		{
+			name: "zero-length values",
+			msg: testResponseMessage(testTLV(0x30,
+				testTLV(0x30, sysDescr, testTLV(byte(Counter32))),
+				testTLV(0x30, sysName, testTLV(0x06)))),
+			want: []SnmpPDU{
+				{Name: testSysDescr, Type: Counter32, Value: uint(0)},
+				{Name: testSysName, Type: ObjectIdentifier, Value: ".0.0"},
+			},
+			warnings: []string{
+				"varbind 0 (.1.3.6.1.2.1.1.1.0): zero-length Counter32",
+				"varbind 1 (.1.3.6.1.2.1.1.5.0): zero-length ObjectIdentifier, taken as .0.0",
+			},
+		},
+		{
+			name: "value length past its varbind",
+			msg: testResponseMessage(testTLV(0x30,
+				testTLV(0x30, sysDescr, []byte{0x04, 0x05, 'a', 'b'}), descr)),
+			want: []SnmpPDU{
+				{Name: testSysDescr, Type: OctetString, Value: []byte("ab")},
+				{Name: testSysDescr, Type: OctetString, Value: []byte("descr")},
+			},
+			warnings: []string{"varbind 0 (.1.3.6.1.2.1.1.1.0): value length 5 exceeds the varbind"},
+		},
+		{
+			name:     "varbind list length past the PDU",
+			msg:      testResponseMessage(append([]byte{0x30, byte(len(descr) + 3)}, descr...)),
+			want:     []SnmpPDU{{Name: testSysDescr, Type: OctetString, Value: []byte("descr")}},
+			warnings: []string{"varbind list length 22, 19 bytes received"},
+		},
+		{
+			name: "message length past the datagram",
+			msg: func() []byte {
+				msg := testResponseMessage(testTLV(0x30, descr))
+				msg[1] += 3
+				return msg
+			}(),
+			want:     []SnmpPDU{{Name: testSysDescr, Type: OctetString, Value: []byte("descr")}},
+			warnings: []string{"message length 48, 45 bytes received"},
+		},
+		{
+			name: "undecodable varbind",
+			msg: testResponseMessage(testTLV(0x30,
+				testTLV(0x30, testTLV(0x04, []byte("not an oid")), testTLV(0x05)),
+				testTLV(0x30, sysName, testTLV(0x06, []byte{0x2b, 0x86})),
+				descr)),
+			want: []SnmpPDU{{Name: testSysDescr, Type: OctetString, Value: []byte("descr")}},
+			warnings: []string{
+				"varbind 0: varbind has no name",
+				"varbind 1 (.1.3.6.1.2.1.1.5.0): unable to decode the value",
+			},
+		},
+	}
+	for _, test := range tests {
+		strict := &GoSNMP{}
+		if _, err := strict.Decode(test.msg); (err == nil) != test.strictOK {
+			t.Errorf("%s: Decode() not leniently err: %v", test.name, err)
+		}
+
+		x := &GoSNMP{Lenient: true}
+		packet, err := x.Decode(test.msg)
+		if err != nil {
+			t.Errorf("%s: Decode() err: %v", test.name, err)
+			continue
+		}
+		var got []SnmpPDU
+		for _, pdu := range packet.Variables {
+			got = append(got, SnmpPDU{Name: pdu.Name, Type: pdu.Type, Value: pdu.Value})
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: decoded %v, expected %v", test.name, got, test.want)
+		}
+		if len(packet.Warnings) != len(test.warnings) {
+			t.Errorf("%s: warnings %v, expected %q", test.name, packet.Warnings, test.warnings)
+			continue
+		}
+		for i, w := range packet.Warnings {
+			if !strings.HasPrefix(w.Error(), test.warnings[i]) {
+				t.Errorf("%s: warning %d is %q, expected %q", test.name, i, w, test.warnings[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,179 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"fmt"
+)
+
+//
+// Strict decoding, with limits on the resources a message can use
+//
+
+// DecodeLimits are the limits of a strict decode of messages received, eg
+// by a TrapListener open to the Internet: messages beyond them, or whose
+// BER isn't well formed (indefinite or overlong lengths, high tag numbers,
+// lengths past the end of what contains them, truncated sub-identifiers),
+// are rejected before their values are decoded, as ErrDecode. A limit of
+// 0 is none.
+type DecodeLimits struct {
+	// MaxMessageSize is the most bytes of a message
+	MaxMessageSize int
+
+	// MaxVarbinds is the most varbinds of a PDU
+	MaxVarbinds int
+
+	// MaxOidLength is the most sub-identifiers of an OBJECT IDENTIFIER,
+	// in a varbind's name or value
+	MaxOidLength int
+
+	// MaxDepth is the most levels of nested SEQUENCEs and PDUs (the
+	// message being the first level). SNMP messages have 5 at most.
+	MaxDepth int
+}
+
+// StrictDecodeLimits are limits no well behaved SNMP entity reaches: the
+// largest udp datagram, 1024 varbinds, the 128 sub-identifiers of RFC
+// 2578 section 3.5, and 8 levels of nesting
+var StrictDecodeLimits = DecodeLimits{
+	MaxMessageSize: 65507,
+	MaxVarbinds:    1024,
+	MaxOidLength:   128,
+	MaxDepth:       8,
+}
+
+// check checks msg, a whole message, against the limits; nil limits pass
+// everything
+func (l *DecodeLimits) check(msg []byte) error {
+	if l == nil {
+		return nil
+	}
+	if l.MaxMessageSize > 0 && len(msg) > l.MaxMessageSize {
+		return fmt.Errorf("Message of %d bytes exceeds MaxMessageSize %d", len(msg), l.MaxMessageSize)
+	}
+	return l.scan(msg, 1)
+}
+
+// checkScopedPDU checks a decrypted SNMPv3 scoped PDU against the limits,
+// ignoring the padding after it
+func (l *DecodeLimits) checkScopedPDU(decrypted []byte) error {
+	if l == nil {
+		return nil
+	}
+	_, _, length, err := readTLV(decrypted)
+	if err != nil {
+		return err
+	}
+	return l.scan(decrypted[:length], 2)
+}
+
+// checkVarbinds checks the number of varbinds of vbl, the contents of a
+// varbind list
+func (l *DecodeLimits) checkVarbinds(vbl []byte) error {
+	if l == nil || l.MaxVarbinds <= 0 {
+		return nil
+	}
+	n := 0
+	for len(vbl) > 0 {
+		_, _, length, err := readTLV(vbl)
+		if err != nil {
+			return err
+		}
+		if n++; n > l.MaxVarbinds {
+			return fmt.Errorf("Varbind list exceeds MaxVarbinds %d", l.MaxVarbinds)
+		}
+		vbl = vbl[length:]
+	}
+	return nil
+}
+
+// scan checks the BER TLVs of data, which are at level depth
+func (l *DecodeLimits) scan(data []byte, depth int) error {
+	for len(data) > 0 {
+		tag, header, length, err := readTLV(data)
+		if err != nil {
+			return err
+		}
+		contents := data[header:length]
+		switch {
+		case tag&0x20 != 0:
+			// constructed: a SEQUENCE or PDU
+			if l.MaxDepth > 0 && depth > l.MaxDepth {
+				return fmt.Errorf("Message nesting exceeds MaxDepth %d", l.MaxDepth)
+			}
+			if err = l.scan(contents, depth+1); err != nil {
+				return err
+			}
+		case Asn1BER(tag) == ObjectIdentifier:
+			if err = l.checkOid(contents); err != nil {
+				return err
+			}
+		}
+		data = data[length:]
+	}
+	return nil
+}
+
+// checkOid checks the contents of an OBJECT IDENTIFIER: that its last
+// sub-identifier ends, that none is wider than 32 bits, and that there
+// aren't more than MaxOidLength
+func (l *DecodeLimits) checkOid(contents []byte) error {
+	if len(contents) == 0 {
+		return fmt.Errorf("Empty OBJECT IDENTIFIER")
+	}
+	// the first octets encode the first two sub-identifiers
+	n, width := 1, 0
+	for _, b := range contents {
+		if width++; width > 5 {
+			return fmt.Errorf("OBJECT IDENTIFIER sub-identifier exceeds 32 bits")
+		}
+		if b&0x80 == 0 {
+			n, width = n+1, 0
+		}
+	}
+	if width != 0 {
+		return fmt.Errorf("Truncated OBJECT IDENTIFIER sub-identifier")
+	}
+	if l.MaxOidLength > 0 && n > l.MaxOidLength {
+		return fmt.Errorf("OBJECT IDENTIFIER of %d sub-identifiers exceeds MaxOidLength %d", n, l.MaxOidLength)
+	}
+	return nil
+}
+
+// readTLV reads the tag and length of the first TLV of data, strictly,
+// returning the length of its header and of the whole TLV
+func readTLV(data []byte) (tag byte, header, length int, err error) {
+	if len(data) < 2 {
+		return 0, 0, 0, fmt.Errorf("Truncated BER TLV")
+	}
+	tag = data[0]
+	if tag&0x1f == 0x1f {
+		return 0, 0, 0, fmt.Errorf("BER high tag number form isn't used by SNMP")
+	}
+	length, header = int(data[1]), 2
+	switch {
+	case length == 0x80:
+		return 0, 0, 0, fmt.Errorf("BER indefinite length isn't allowed")
+	case length > 0x80:
+		n := length & 0x7f
+		if n > 4 || len(data) < 2+n {
+			return 0, 0, 0, fmt.Errorf("BER length of %d bytes is too long", n)
+		}
+		// as a uint32, which an int can't hold on 32 bit platforms
+		var long uint32
+		for _, b := range data[2 : 2+n] {
+			long = long<<8 | uint32(b)
+		}
+		header += n
+		if uint64(long) > uint64(len(data)-header) {
+			return 0, 0, 0, fmt.Errorf("BER length %d exceeds the %d bytes remaining", long, len(data)-header)
+		}
+		length = int(long)
+	}
+	if length > len(data)-header {
+		return 0, 0, 0, fmt.Errorf("BER length %d exceeds the %d bytes remaining", length, len(data)-header)
+	}
+	return tag, header, header + length, nil
+}
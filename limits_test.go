@@ -0,0 +1,121 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDecodeLimits(t *testing.T) {
+	encoder := &GoSNMP{}
+	longOid := ".1.3" + strings.Repeat(".1", 98) // 100 sub-identifiers
+	oid11 := ".1.3.6.1.2.1.1.1.0.1.1"
+	msg := func(pdus ...SnmpPDU) []byte {
+		m, err := encoder.Encode(&SnmpPacket{Version: Version2c, Community: "public", PDUType: SNMPv2Trap,
+			RequestID: 1, Variables: pdus})
+		if err != nil {
+			t.Fatalf("Encode() err: %v", err)
+		}
+		return m
+	}
+	uptime := SnmpPDU{Name: sysUpTimeOid, Type: TimeTicks, Value: uint32(1)}
+	trapOid := SnmpPDU{Name: snmpTrapOID, Type: ObjectIdentifier, Value: trapTestOid}
+	descr := SnmpPDU{Name: testSysDescr, Type: OctetString, Value: []byte("descr")}
+
+	tests := []struct {
+		name   string
+		limits DecodeLimits
+		msg    []byte
+		ok     bool
+	}{
+		{"strict", StrictDecodeLimits, msg(uptime, trapOid, descr), true},
+		{"no limits", DecodeLimits{}, msg(uptime, trapOid, descr), true},
+		{"long oid", StrictDecodeLimits, msg(SnmpPDU{Name: longOid, Type: Null}), true},
+		{"oid", DecodeLimits{MaxOidLength: 11}, msg(SnmpPDU{Name: oid11, Type: Null}), true},
+		{"too long oid", DecodeLimits{MaxOidLength: 10}, msg(SnmpPDU{Name: oid11, Type: Null}), false},
+		{"too long oid value", DecodeLimits{MaxOidLength: 10},
+			msg(SnmpPDU{Name: testSysDescr, Type: ObjectIdentifier, Value: oid11}), false},
+		{"varbinds", DecodeLimits{MaxVarbinds: 3}, msg(uptime, trapOid, descr), true},
+		{"too many varbinds", DecodeLimits{MaxVarbinds: 2}, msg(uptime, trapOid, descr), false},
+		{"size", DecodeLimits{MaxMessageSize: len(msg(descr))}, msg(descr), true},
+		{"too large", DecodeLimits{MaxMessageSize: len(msg(descr)) - 1}, msg(descr), false},
+		{"depth", DecodeLimits{MaxDepth: 4}, msg(descr), true},
+		{"too deep", DecodeLimits{MaxDepth: 3}, msg(descr), false},
+		{"indefinite length", StrictDecodeLimits, []byte{0x30, 0x80, 0x02, 0x01, 0x01, 0x00, 0x00}, false},
+		{"overlong length", StrictDecodeLimits, []byte{0x30, 0x85, 0x00, 0x00, 0x00, 0x00, 0x03, 0x02, 0x01, 0x01}, false},
+		{"length negative as an int32", StrictDecodeLimits, []byte{0x30, 0x84, 0xff, 0xff, 0xff, 0xfe, 0x02, 0x01, 0x01}, false},
+		{"high tag number", StrictDecodeLimits, []byte{0x30, 0x04, 0x1f, 0x81, 0x01, 0x00}, false},
+		{"length past its sequence", StrictDecodeLimits, []byte{0x30, 0x05, 0x30, 0x04, 0x02, 0x01, 0x01}, false},
+		{"truncated sub-identifier", StrictDecodeLimits, []byte{0x30, 0x04, 0x06, 0x02, 0x2b, 0x86}, false},
+		{"wide sub-identifier", StrictDecodeLimits,
+			[]byte{0x30, 0x09, 0x06, 0x07, 0x2b, 0x81, 0x81, 0x81, 0x81, 0x81, 0x01}, false},
+	}
+	for _, test := range tests {
+		limits := test.limits
+		x := &GoSNMP{DecodeLimits: &limits}
+		_, err := x.Decode(test.msg)
+		if test.ok && err != nil {
+			t.Errorf("%s: Decode() err: %v", test.name, err)
+		}
+		if !test.ok && !errors.Is(err, ErrDecode) {
+			t.Errorf("%s: Decode() got err %v, expected ErrDecode", test.name, err)
+		}
+	}
+}
+
+func TestDecodeLimitsV3(t *testing.T) {
+	credentials := func() *UsmSecurityParameters {
+		return &UsmSecurityParameters{
+			UserName:                 "user",
+			AuthenticationProtocol:   SHA,
+			AuthenticationPassphrase: "authpassphrase",
+			PrivacyProtocol:          DES,
+			PrivacyPassphrase:        "privpassphrase",
+		}
+	}
+	x := &GoSNMP{
+		Version:            Version3,
+		MsgFlags:           AuthPriv,
+		SecurityModel:      UserSecurityModel,
+		SecurityParameters: credentials(),
+	}
+	if err := x.validateParameters(); err != nil {
+		t.Fatalf("validateParameters() err: %v", err)
+	}
+	discovered := &SnmpPacket{
+		Version:            Version3,
+		SecurityModel:      UserSecurityModel,
+		SecurityParameters: &UsmSecurityParameters{AuthoritativeEngineID: "engine", AuthoritativeEngineBoots: 3},
+	}
+	if err := x.storeSecurityParameters(discovered); err != nil {
+		t.Fatalf("storeSecurityParameters() err: %v", err)
+	}
+	pdus := []SnmpPDU{{Name: testSysDescr, Type: Null}, {Name: testSysName, Type: Null}}
+	packet := x.mkSnmpPacket(GetRequest, pdus, 0, 0)
+	packet.RequestID, packet.MsgID = 7, 8
+	if err := x.initPacket(packet); err != nil {
+		t.Fatalf("initPacket() err: %v", err)
+	}
+	msg, err := packet.marshalMsg()
+	if err != nil {
+		t.Fatalf("marshalMsg() err: %v", err)
+	}
+
+	// the encrypted scoped PDU, and its padding, is checked once decrypted
+	decoder := &GoSNMP{SecurityParameters: credentials(), DecodeLimits: &StrictDecodeLimits}
+	if decoded, err := decoder.Decode(msg); err != nil || len(decoded.Variables) != 2 {
+		t.Errorf("Decode() = %v, err %v", decoded, err)
+	}
+	decoder = &GoSNMP{SecurityParameters: credentials(), DecodeLimits: &DecodeLimits{MaxVarbinds: 1}}
+	if _, err = decoder.Decode(msg); !errors.Is(err, ErrDecode) {
+		t.Errorf("Decode() of too many varbinds got err %v, expected ErrDecode", err)
+	}
+	decoder = &GoSNMP{SecurityParameters: credentials(), DecodeLimits: &DecodeLimits{MaxDepth: 4}}
+	if _, err = decoder.Decode(msg); !errors.Is(err, ErrDecode) {
+		t.Errorf("Decode() of a too deep scoped PDU got err %v, expected ErrDecode", err)
+	}
+}
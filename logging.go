@@ -0,0 +1,82 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LeveledLogger is a structured logger with levels, see
+// GoSNMP.LeveledLogger. Its methods take a message followed by alternating
+// keys and values, as those of a *slog.Logger do, so one can be used as it
+// is:
+//
+//	x.LeveledLogger = slog.Default()
+type LeveledLogger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+// debugLogger is the Logger made from a LeveledLogger, for the detailed
+// tracing of packets and their decoding, which is all at debug level
+type debugLogger struct {
+	l LeveledLogger
+}
+
+func (d debugLogger) Print(v ...interface{}) {
+	d.l.Debug(fmt.Sprint(v...))
+}
+
+func (d debugLogger) Printf(format string, v ...interface{}) {
+	d.l.Debug(fmt.Sprintf(format, v...))
+}
+
+// logInfo logs msg and its key/value pairs at info level, to
+// x.LeveledLogger or else x.Logger
+func (x *GoSNMP) logInfo(msg string, args ...interface{}) {
+	if x.LeveledLogger != nil {
+		x.LeveledLogger.Info(msg, args...)
+	} else if x.loggingEnabled {
+		x.Logger.Print(formatLogLine("INFO", msg, args))
+	}
+}
+
+// logWarn is logInfo at warning level
+func (x *GoSNMP) logWarn(msg string, args ...interface{}) {
+	if x.LeveledLogger != nil {
+		x.LeveledLogger.Warn(msg, args...)
+	} else if x.loggingEnabled {
+		x.Logger.Print(formatLogLine("WARNING", msg, args))
+	}
+}
+
+// logError is logInfo at error level
+func (x *GoSNMP) logError(msg string, args ...interface{}) {
+	if x.LeveledLogger != nil {
+		x.LeveledLogger.Error(msg, args...)
+	} else if x.loggingEnabled {
+		x.Logger.Print(formatLogLine("ERROR", msg, args))
+	}
+}
+
+// formatLogLine formats a leveled message for a Logger, eg
+// "WARNING dropping response id=1234"
+func formatLogLine(level, msg string, args []interface{}) string {
+	var b strings.Builder
+	b.WriteString(level)
+	b.WriteByte(' ')
+	b.WriteString(msg)
+	for i := 0; i < len(args); i += 2 {
+		if i+1 < len(args) {
+			fmt.Fprintf(&b, " %v=%v", args[i], args[i+1])
+		} else {
+			fmt.Fprintf(&b, " %v", args[i])
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,93 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingLogger records the levels and messages logged to it
+type recordingLogger struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (r *recordingLogger) log(level, msg string, args []interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lines = append(r.lines, formatLogLine(level, msg, args))
+}
+
+func (r *recordingLogger) Debug(msg string, args ...interface{}) { r.log("DEBUG", msg, args) }
+func (r *recordingLogger) Info(msg string, args ...interface{})  { r.log("INFO", msg, args) }
+func (r *recordingLogger) Warn(msg string, args ...interface{})  { r.log("WARNING", msg, args) }
+func (r *recordingLogger) Error(msg string, args ...interface{}) { r.log("ERROR", msg, args) }
+
+func (r *recordingLogger) count(prefix string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := 0
+	for _, line := range r.lines {
+		if strings.HasPrefix(line, prefix) {
+			n++
+		}
+	}
+	return n
+}
+
+// printLogger is a Logger recording what is printed to it
+type printLogger struct {
+	recordingLogger
+}
+
+func (p *printLogger) Print(v ...interface{}) { p.log("PRINT", fmt.Sprint(v...), nil) }
+func (p *printLogger) Printf(format string, v ...interface{}) {
+	p.log("PRINT", fmt.Sprintf(format, v...), nil)
+}
+
+func TestLeveledLogger(t *testing.T) {
+	const oid = ".1.3.6.1.2.1.1.5.0"
+	r := newTestResponder(t, []SnmpPDU{{Name: oid, Type: OctetString, Value: []byte("name")}})
+	defer r.Close()
+	leveled := new(recordingLogger)
+	x := &GoSNMP{
+		Target:        "127.0.0.1",
+		Port:          uint16(r.conn.LocalAddr().(*net.UDPAddr).Port),
+		Community:     "public",
+		Version:       Version2c,
+		Timeout:       time.Second,
+		LeveledLogger: leveled,
+	}
+	if err := x.Connect(); err != nil {
+		t.Fatalf("Connect() err: %v", err)
+	}
+	defer x.Conn.Close()
+
+	if _, err := x.Get([]string{oid}); err != nil {
+		t.Fatalf("Get() err: %v", err)
+	}
+	if leveled.count("DEBUG ") == 0 {
+		t.Errorf("got no debug output from a Get")
+	}
+	x.dispatcher().deliver(1234, datagram{})
+	if n := leveled.count("WARNING Dropping response with unknown id id=1234"); n != 1 {
+		t.Errorf("got %d warnings for a response with an unknown id, in %q", n, leveled.lines)
+	}
+
+	// without a LeveledLogger the levels are in the message
+	printer := new(printLogger)
+	x.LeveledLogger = nil
+	x.Logger = printer
+	x.validateParameters()
+	x.dispatcher().deliver(1234, datagram{})
+	if n := printer.count("PRINT WARNING Dropping response with unknown id id=1234"); n != 1 {
+		t.Errorf("got %q", printer.lines)
+	}
+}
@@ -0,0 +1,219 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+//
+// Multi-target manager
+//
+
+// defaultManagerWorkers is the number of queries a Manager runs at once
+// unless Workers is set
+const defaultManagerWorkers = 16
+
+// Manager queries many targets concurrently, using sessions from a Pool of
+// its own. The zero value is a manager creating sessions from Default. A
+// Manager is safe for concurrent use, but must not be copied after first
+// use; Close closes its sessions.
+type Manager struct {
+	// Template holds the parameters (Port, Community, Version, Timeout etc)
+	// used for the sessions created by the manager, as for Pool.Template.
+	// If nil, Default is used.
+	Template *GoSNMP
+
+	// Workers is the maximum number of queries in progress at once, across
+	// all targets (default: 16)
+	Workers int
+
+	// PerTarget is the maximum number of queries in progress at once for
+	// each target, eg when a Get needs several requests for more oids than
+	// MaxOids (default: 1)
+	PerTarget int
+
+	// RateLimit and RateBurst limit the packets sent to each target, as
+	// for Pool.RateLimit
+	RateLimit float64
+	RateBurst int
+
+	once sync.Once
+	pool *Pool
+}
+
+// ManagerResult is the result of a Manager query for one target
+type ManagerResult struct {
+	Target string
+
+	// Variables are the values for the target: for a Get, one for each
+	// oid in the order of the oids, and for a Walk those of the subtree
+	Variables []SnmpPDU
+
+	// Err is the error that the query for the target failed with. A Get
+	// failing has no Variables; a Walk failing has those retrieved before
+	// the error.
+	Err error
+}
+
+// Get gets oids from each of targets, in as many requests as MaxOids
+// needs. The results are in the order of targets.
+func (m *Manager) Get(targets []string, oids []string) []ManagerResult {
+	return m.GetCtx(context.Background(), targets, oids)
+}
+
+// GetCtx is like Get, but the queries are abandoned when ctx is cancelled
+// or its deadline passes, failing with ctx.Err().
+func (m *Manager) GetCtx(ctx context.Context, targets []string, oids []string) []ManagerResult {
+	maxOids := m.template().MaxOids
+	if maxOids <= 0 {
+		maxOids = MaxOids
+	}
+	chunks := (len(oids) + maxOids - 1) / maxOids
+
+	// each target's first chunk, then each target's second and so on, so
+	// that workers don't queue up for one target's sessions
+	type getJob struct{ target, chunk int }
+	var jobs []getJob
+	for c := 0; c < chunks; c++ {
+		for t := range targets {
+			jobs = append(jobs, getJob{t, c})
+		}
+	}
+	variables := make([][]SnmpPDU, len(targets))
+	for t := range targets {
+		variables[t] = make([]SnmpPDU, len(oids))
+	}
+	errs := m.run(ctx, len(jobs), func(i int) string {
+		return targets[jobs[i].target]
+	}, func(ctx context.Context, i int, x *GoSNMP) error {
+		start := jobs[i].chunk * maxOids
+		end := start + maxOids
+		if end > len(oids) {
+			end = len(oids)
+		}
+		result, err := x.GetCtx(ctx, oids[start:end])
+		if err != nil {
+			return err
+		}
+		if err = result.Err(); err != nil {
+			return err
+		}
+		copy(variables[jobs[i].target][start:end], result.Variables)
+		return nil
+	})
+
+	results := make([]ManagerResult, len(targets))
+	for t, target := range targets {
+		results[t] = ManagerResult{Target: target, Variables: variables[t]}
+	}
+	for i, err := range errs {
+		if r := &results[jobs[i].target]; err != nil && r.Err == nil {
+			r.Variables, r.Err = nil, err
+		}
+	}
+	return results
+}
+
+// Walk walks the subtree at rootOid on each of targets, as AutoWalk does.
+// The results are in the order of targets.
+func (m *Manager) Walk(targets []string, rootOid string) []ManagerResult {
+	return m.WalkCtx(context.Background(), targets, rootOid)
+}
+
+// WalkCtx is like Walk, but the walks stop with ctx.Err() when ctx is
+// cancelled or its deadline passes.
+func (m *Manager) WalkCtx(ctx context.Context, targets []string, rootOid string) []ManagerResult {
+	results := make([]ManagerResult, len(targets))
+	for t, target := range targets {
+		results[t].Target = target
+	}
+	errs := m.run(ctx, len(targets), func(i int) string {
+		return targets[i]
+	}, func(ctx context.Context, i int, x *GoSNMP) (err error) {
+		results[i].Variables, err = x.AutoWalkAllCtx(ctx, rootOid)
+		return err
+	})
+	for i, err := range errs {
+		results[i].Err = err
+	}
+	return results
+}
+
+// Close closes the manager's idle sessions, see Pool.Close
+func (m *Manager) Close() error {
+	return m.sessions().Close()
+}
+
+func (m *Manager) template() *GoSNMP {
+	if m.Template == nil {
+		return Default
+	}
+	return m.Template
+}
+
+func (m *Manager) sessions() *Pool {
+	m.once.Do(func() {
+		perTarget := m.PerTarget
+		if perTarget <= 0 {
+			perTarget = 1
+		}
+		m.pool = &Pool{
+			Template:  m.Template,
+			MaxConns:  perTarget,
+			RateLimit: m.RateLimit,
+			RateBurst: m.RateBurst,
+		}
+	})
+	return m.pool
+}
+
+// run runs jobs 0 to n-1 with up to Workers at once, each with a session
+// for target(i), returning their errors
+func (m *Manager) run(ctx context.Context, n int, target func(i int) string,
+	job func(ctx context.Context, i int, x *GoSNMP) error) []error {
+	pool := m.sessions()
+	workers := m.Workers
+	if workers <= 0 {
+		workers = defaultManagerWorkers
+	}
+	if workers > n {
+		workers = n
+	}
+
+	errs := make([]error, n)
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				x, err := pool.BorrowCtx(ctx, target(i))
+				if err != nil {
+					errs[i] = err
+					continue
+				}
+				errs[i] = job(ctx, i, x)
+				var statusErr *StatusError
+				if errs[i] == nil || errors.As(errs[i], &statusErr) {
+					pool.Return(x)
+				} else {
+					// eg a timeout from an unreachable target, whose
+					// session isn't worth keeping
+					pool.Discard(x)
+				}
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return errs
+}
@@ -0,0 +1,67 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestManager(t *testing.T) {
+	oids := []string{".1.3.6.1.2.1.1.4.0", ".1.3.6.1.2.1.1.5.0", ".1.3.6.1.2.1.1.6.0"}
+	r := newTestResponder(t, []SnmpPDU{
+		{Name: oids[0], Type: OctetString, Value: []byte("contact")},
+		{Name: oids[1], Type: OctetString, Value: []byte("name")},
+		{Name: oids[2], Type: OctetString, Value: []byte("location")},
+	})
+	defer r.Close()
+	m := &Manager{
+		Template: &GoSNMP{
+			Port:      uint16(r.conn.LocalAddr().(*net.UDPAddr).Port),
+			Community: "public",
+			Version:   Version2c,
+			Timeout:   500 * time.Millisecond,
+			MaxOids:   2, // two requests per target
+		},
+		Workers:   4,
+		PerTarget: 1,
+	}
+	defer m.Close()
+
+	// nothing listens on the port at 127.0.0.2
+	targets := []string{"127.0.0.1", "127.0.0.2", "127.0.0.1"}
+	results := m.Get(targets, oids)
+	if len(results) != len(targets) {
+		t.Fatalf("Get() got %d results, want %d", len(results), len(targets))
+	}
+	for i, result := range results {
+		if result.Target != targets[i] {
+			t.Errorf("#%d: got target %s, want %s", i, result.Target, targets[i])
+		}
+		if targets[i] == "127.0.0.2" {
+			if result.Err == nil || result.Variables != nil {
+				t.Errorf("#%d: got %v, err: %v, want an error", i, result.Variables, result.Err)
+			}
+			continue
+		}
+		if result.Err != nil || len(result.Variables) != len(oids) {
+			t.Fatalf("#%d: got %d values, err: %v", i, len(result.Variables), result.Err)
+		}
+		for j, pdu := range result.Variables {
+			if pdu.Name != oids[j] {
+				t.Errorf("#%d: value %d is %s, want %s", i, j, pdu.Name, oids[j])
+			}
+		}
+	}
+	if n := m.pool.targets["127.0.0.1"].count; n != 1 {
+		t.Errorf("got %d sessions for a target, want PerTarget (1)", n)
+	}
+
+	results = m.Walk(targets[:1], ".1.3.6.1.2.1.1")
+	if len(results) != 1 || results[0].Err != nil || len(results[0].Variables) != len(oids) {
+		t.Errorf("Walk() got %+v", results)
+	}
+}
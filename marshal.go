@@ -6,10 +6,14 @@ package gosnmp
 
 import (
 	"bytes"
+	"context"
 	"encoding/asn1"
 	"encoding/binary"
+	"errors"
 	"fmt"
+	"math"
 	"net"
+	"sync"
 	"sync/atomic"
 	"time"
 )
@@ -50,6 +54,24 @@ type SnmpPacket struct {
 	Variables          []SnmpPDU
 	Logger             Logger
 
+	// Source is the address a response to a request was received from,
+	// which may not be the Target's with GoSNMP.AnySource
+	Source net.Addr
+
+	// MsgMaxSize is the SNMPv3 msgMaxSize: of a packet received, the
+	// largest message its sender accepts; of a packet sent, the largest
+	// response we accept (if 0, 65535)
+	MsgMaxSize int
+
+	// LazyVariables holds the undecoded varbinds of a response to one of
+	// the *Lazy methods, in place of Variables
+	LazyVariables []LazyPDU
+	lazyVarbinds  bool
+
+	// Warnings are the faults of a message decoded leniently, see
+	// GoSNMP.Lenient
+	Warnings []DecodeWarning
+
 	// Trap V1 header
 	Enterprise   []int
 	AgentAddr    string
@@ -90,7 +112,7 @@ const rxBufSize = 65535 // max size of IPv4 & IPv6 packet
 //
 // For verbose logging to stdout:
 //
-//     gosnmp_logger = log.New(os.Stdout, "", 0)
+//	gosnmp_logger = log.New(os.Stdout, "", 0)
 type Logger interface {
 	Print(v ...interface{})
 	Printf(format string, v ...interface{})
@@ -108,39 +130,121 @@ func (x *GoSNMP) logPrintf(format string, v ...interface{}) {
 	}
 }
 
-// send/receive one snmp request
-func (x *GoSNMP) sendOneRequest(packetOut *SnmpPacket,
+// responseBuffer is the number of responses to a request that can be
+// queued for it by the dispatcher, including late ones to earlier attempts
+const responseBuffer = 4
+
+// send/receive one snmp request, making the attempts x.retryPolicy(ctx) allows
+func (x *GoSNMP) sendOneRequest(ctx context.Context, packetOut *SnmpPacket,
 	wait bool) (result *SnmpPacket, err error) {
-	finalDeadline := time.Now().Add(x.Timeout)
+	policy := x.retryPolicy(ctx)
+	stats := x.counters()
+	defer func() {
+		if isTimeout(err) {
+			atomic.AddUint64(&stats.timeouts, 1)
+		}
+	}()
+	var finalDeadline time.Time // zero if the policy has no deadline
+	if d := policy.Deadline(); d > 0 {
+		finalDeadline = time.Now().Add(d)
+	}
+	ctxDeadline, hasCtxDeadline := ctx.Deadline()
+	if hasCtxDeadline && (finalDeadline.IsZero() || ctxDeadline.Before(finalDeadline)) {
+		finalDeadline = ctxDeadline
+	}
+
+	// responses are read by the connection's dispatcher, and the ones
+	// matching the ids used by this request are sent to us on responses
+	var mux *dispatcher
+	var waitIDs []uint32
+	responses := make(chan datagram, responseBuffer)
+	if wait {
+		mux = x.dispatcher()
+		defer func() {
+			mux.unregister(waitIDs)
+			// responses queued but not read, eg duplicates, are late too
+			for len(responses) > 0 {
+				if dg := <-responses; dg.data != nil {
+					atomic.AddUint64(&stats.late, 1)
+				}
+			}
+		}()
+	}
 
-	allReqIDs := make([]uint32, 0, x.Retries+1)
-	allMsgIDs := make([]uint32, 0, x.Retries+1)
+	var allReqIDs []uint32
+	msgIDs := make(map[uint32]uint32) // the request-id sent with each msgID
+
+	var decodeErr error // from the last response that couldn't be decoded
+	var endAttempt func(error)
+	defer func() {
+		if endAttempt != nil {
+			endAttempt(err)
+		}
+	}()
 	for retries := 0; ; retries++ {
+		if endAttempt != nil {
+			endAttempt(err)
+			endAttempt = nil
+		}
 		if retries > 0 {
 			x.logPrintf("Retry number %d. Last error was: %v", retries, err)
-			if time.Now().After(finalDeadline) {
-				err = fmt.Errorf("Request timeout (after %d retries)", retries-1)
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			if hasCtxDeadline && !time.Now().Before(ctxDeadline) {
+				return nil, context.DeadlineExceeded
+			}
+			if !finalDeadline.IsZero() && !time.Now().Before(finalDeadline) {
+				err = &timeoutError{fmt.Sprintf("Request timeout (after %d retries)", retries-1), decodeErr}
 				break
 			}
-			if retries > x.Retries {
-				// Report last error
+		}
+		var unreachable *unreachableError
+		if retries > 0 && errors.As(err, &unreachable) {
+			if !x.RedialOnUnreachable {
+				// Report last error, without waiting for more
 				break
 			}
+			x.redial(ctx)
+		}
+		attemptWait, ok := policy.Wait(retries)
+		if !ok && retries > 0 {
+			// Report last error
+			break
+		}
+		if retries > 0 && x.OnRetry != nil {
+			x.OnRetry(packetOut, retries+1, err)
+		}
+		if retries > 0 {
+			x.resolveAgain(ctx)
 		}
 		err = nil
 
-		reqDeadline := time.Now().Add(x.Timeout / time.Duration(x.Retries+1))
-		x.Conn.SetDeadline(reqDeadline)
+		// time queued by rate limiters isn't taken from the attempt's wait
+		if err = x.waitRateLimiters(ctx); err != nil {
+			return nil, err
+		}
+		reqDeadline := time.Now().Add(attemptWait)
+		if !finalDeadline.IsZero() && finalDeadline.Before(reqDeadline) {
+			reqDeadline = finalDeadline
+		}
 
 		// Request ID is an atomic counter (started at a random value)
-		reqID := atomic.AddUint32(&(x.requestID), 1) // TODO: fix overflows
+		reqID := nextID(&x.requestID)
+		if x.Version != Version3 {
+			reqID = mux.fresh(reqID, &x.requestID)
+		}
 		allReqIDs = append(allReqIDs, reqID)
 
 		packetOut.RequestID = reqID
+		waitID := reqID
+
+		_, endAttempt = x.startSpan(ctx, packetOut, retries+1)
 
 		if x.Version == Version3 {
-			msgID := atomic.AddUint32(&(x.msgID), 1) // TODO: fix overflows
-			allMsgIDs = append(allMsgIDs, msgID)
+			msgID := mux.fresh(nextID(&x.msgID), &x.msgID)
+			waitID = msgID
+			msgIDs[msgID] = reqID
 
 			packetOut.MsgID = msgID
 
@@ -150,7 +254,7 @@ func (x *GoSNMP) sendOneRequest(packetOut *SnmpPacket,
 			}
 
 		}
-		x.logPrintf("PACKET SENT: %#+v", *packetOut)
+		x.logPrintf("PACKET SENT: %s", packetOut)
 		if x.loggingEnabled && x.Version == Version3 {
 			packetOut.SecurityParameters.Log()
 		}
@@ -159,13 +263,37 @@ func (x *GoSNMP) sendOneRequest(packetOut *SnmpPacket,
 		outBuf, err = packetOut.marshalMsg()
 		if err != nil {
 			// Don't retry - not going to get any better!
-			err = fmt.Errorf("marshal: %v", err)
+			err = fmt.Errorf("marshal: %w", err)
+			break
+		}
+		if err = x.checkPeerMaxSize(outBuf); err != nil {
 			break
 		}
 
-		_, err = x.Conn.Write(outBuf)
-		if err != nil {
-			continue
+		// register before sending, so a fast response can't be missed
+		if wait {
+			waitIDs = append(waitIDs, waitID)
+			mux.register(waitID, responses)
+		}
+
+		if x.OnSend != nil {
+			if outBuf, err = x.OnSend(packetOut, outBuf); err != nil {
+				break
+			}
+		}
+		if outBuf != nil {
+			_, err = x.Conn.Write(outBuf)
+			if err != nil {
+				if isICMPError(err) {
+					err = &unreachableError{err}
+				}
+				continue
+			}
+			atomic.AddUint64(&stats.sent, 1)
+			x.captureSent(outBuf, packetOut)
+			if retries > 0 {
+				atomic.AddUint64(&stats.retransmissions, 1)
+			}
 		}
 
 		// all sends wait for the return packet, except for SNMPv2Trap
@@ -178,61 +306,90 @@ func (x *GoSNMP) sendOneRequest(packetOut *SnmpPacket,
 			// Receive response and try receiving again on any decoding error.
 			// Let the deadline abort us if we don't receive a valid response.
 
-			var resp []byte
-			resp, err = x.receive()
+			var dg datagram
+			dg, err = x.receive(ctx, mux, responses, reqDeadline)
 			if err != nil {
+				if ctx.Err() != nil {
+					return nil, ctx.Err()
+				}
+				if timeout, ok := err.(*timeoutError); ok {
+					timeout.last = decodeErr
+				}
 				// receive error. retrying won't help. abort
 				break
 			}
-			x.logPrint("GET RESPONSE OK : %+v", resp)
+			resp := dg.data
+			x.logPrintf("GET RESPONSE OK : %d bytes", len(resp))
 			result = new(SnmpPacket)
 			result.Logger = x.Logger
+			result.Source = dg.from
 
 			result.MsgFlags = packetOut.MsgFlags
+			result.lazyVarbinds = packetOut.lazyVarbinds
 			if packetOut.SecurityParameters != nil {
 				result.SecurityParameters = packetOut.SecurityParameters.Copy()
 			}
 
+			received := resp
 			var cursor int
 			cursor, err = x.unmarshalHeader(resp, result)
 			if err != nil {
-				x.logPrintf("ERROR on unmarshall header: %s", err)
-				err = fmt.Errorf("Unable to decode packet: %s", err.Error())
+				x.logWarn("Unable to decode response header", "target", x.Target, "err", err)
+				err = fmt.Errorf("%w: %v", ErrDecode, err)
+				decodeErr = err
+				atomic.AddUint64(&stats.decodeErrors, 1)
+				continue
+			}
+			if result.Version == Version3 && result.SecurityModel != packetOut.SecurityModel {
+				err = fmt.Errorf("%w: response of security model %d", ErrDecode, result.SecurityModel)
+				x.logWarn("Dropping response", "target", x.Target, "err", err)
+				decodeErr = err
+				atomic.AddUint64(&stats.decodeErrors, 1)
+				continue
+			}
+			if err = x.checkSource(packetOut, result); err != nil {
+				x.logWarn("Dropping response", "target", x.Target, "err", err)
+				decodeErr = err
 				continue
 			}
 
 			if x.Version == Version3 {
 				err = x.testAuthentication(resp, result)
 				if err != nil {
-					x.logPrintf("ERROR on Test Authentication on v3: %s", err)
+					x.logWarn("Response failed authentication", "target", x.Target, "err", err)
+					atomic.AddUint64(&stats.authErrors, 1)
 					break
 				}
+				x.learnPeerMaxSize(result)
 				resp, cursor, err = x.decryptPacket(resp, cursor, result)
 			}
 
 			err = x.unmarshalPayload(resp, cursor, result)
 			if err != nil {
-				x.logPrintf("ERROR on UnmarshalPayload on v3: %s", err)
-				err = fmt.Errorf("Unable to decode packet: %s", err.Error())
+				x.logWarn("Unable to decode response", "target", x.Target, "err", err)
+				err = fmt.Errorf("%w: %v", ErrDecode, err)
+				decodeErr = err
+				atomic.AddUint64(&stats.decodeErrors, 1)
 				continue
 			}
-			if result == nil || len(result.Variables) < 1 {
-				x.logPrintf("ERROR on UnmarshalPayload on v3: %s", err)
-				err = fmt.Errorf("Unable to decode packet: nil")
+			if result == nil || len(result.Variables)+len(result.LazyVariables) < 1 {
+				x.logWarn("Response has no varbinds", "target", x.Target)
+				err = fmt.Errorf("%w: nil", ErrDecode)
+				decodeErr = err
+				atomic.AddUint64(&stats.decodeErrors, 1)
 				continue
 			}
-
-			validID := false
-			for _, id := range allReqIDs {
-				if id == result.RequestID {
-					validID = true
+			x.captureDecoded(result)
+			if x.OnReceive != nil {
+				if err = x.OnReceive(result, received); err != nil {
+					x.logInfo("Response discarded by OnReceive", "target", x.Target, "err", err)
+					decodeErr = err
+					continue
 				}
 			}
-			if result.RequestID == 0 {
-				validID = true
-			}
-			if !validID {
-				x.logPrint("ERROR  out of order ")
+
+			if !x.validResponseID(result, allReqIDs, msgIDs) {
+				x.logWarn("Out of order response", "target", x.Target, "request_id", result.RequestID)
 				if result.Version == Version3 {
 					// detect out-of-time-window error and go out of this function with all data
 					// (outside it will be handled and retransmitted )
@@ -261,49 +418,54 @@ func (x *GoSNMP) sendOneRequest(packetOut *SnmpPacket,
 // generic "sender" that negotiate any version of snmp request
 //
 // all sends wait for the return packet, except for SNMPv2Trap
-func (x *GoSNMP) send(packetOut *SnmpPacket, wait bool) (result *SnmpPacket, err error) {
+func (x *GoSNMP) send(ctx context.Context, packetOut *SnmpPacket, wait bool) (result *SnmpPacket, err error) {
+	// deferred first, to end the span with any error from a panic
+	ctx, endSpan := x.startSpan(ctx, packetOut, 0)
+	defer func() {
+		endSpan(err)
+	}()
 	defer func() {
 		if e := recover(); e != nil {
 			err = fmt.Errorf("recover: %v", e)
 		}
 	}()
+	ctx, cancel := x.withBudget(ctx)
+	defer cancel()
 
 	if x.Conn == nil {
 		return nil, fmt.Errorf("&GoSNMP.Conn is missing. Provide a connection or use Connect()")
 	}
 
-	if x.Retries < 0 {
-		x.Retries = 0
-	}
 	x.logPrint("SEND INIT")
+	optionsFrom(ctx).scope(packetOut)
 	if packetOut.Version == Version3 {
 		x.logPrint("SEND INIT NEGOTIATE SECURITY PARAMS")
-		if err = x.negotiateInitialSecurityParameters(packetOut, wait); err != nil {
+		if err = x.negotiateInitialSecurityParameters(ctx, packetOut, wait); err != nil {
 			return &SnmpPacket{}, err
 		}
 		x.logPrint("SEND END NEGOTIATE SECURITY PARAMS")
 	}
 
 	// perform request
-	result, err = x.sendOneRequest(packetOut, wait)
+	result, err = x.sendOneRequest(ctx, packetOut, wait)
 	if err != nil {
 		x.logPrintf("SEND Error on the first Request Error: %s", err)
 		return result, err
 	}
 
 	if result.Version == Version3 {
-		x.logPrintf("SEND STORE SECURITY PARAMS from result: %+v", result)
+		x.logPrintf("SEND STORE SECURITY PARAMS from result: %s", result)
 		err = x.storeSecurityParameters(result)
 
 		// detect out-of-time-window error and retransmit with updated auth engine parameters
 		if len(result.Variables) == 1 && result.Variables[0].Name == ".1.3.6.1.6.3.15.1.1.2.0" {
-			x.logPrintf("WARNING detected out-of-time-window ERROR")
+			x.logInfo("Detected out-of-time-window error", "target", x.Target)
 			err = x.updatePktSecurityParameters(packetOut)
 			if err != nil {
-				x.logPrintf("ERROR  updatePktSecurityParameters error: %s", err)
+				x.logError("Unable to update security parameters", "target", x.Target, "err", err)
 				return nil, err
 			}
-			result, err = x.sendOneRequest(packetOut, wait)
+			result, err = x.sendOneRequest(ctx, packetOut, wait)
 		}
 	}
 	return result, err
@@ -311,10 +473,35 @@ func (x *GoSNMP) send(packetOut *SnmpPacket, wait bool) (result *SnmpPacket, err
 
 // -- Marshalling Logic --------------------------------------------------------
 
+// bufferPool holds the scratch buffers used while marshalling, so that
+// steady state polling doesn't allocate a new set for every packet
+var bufferPool = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+// maxPooledBuffer is the largest buffer returned to bufferPool, so that a
+// single huge packet doesn't pin its memory for ever
+const maxPooledBuffer = 64 * 1024
+
+func getBuffer() *bytes.Buffer {
+	return bufferPool.Get().(*bytes.Buffer)
+}
+
+// putBuffer returns buf to bufferPool. buf, and any slice obtained from
+// buf.Bytes(), must not be used afterwards.
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBuffer {
+		return
+	}
+	buf.Reset()
+	bufferPool.Put(buf)
+}
+
 // marshal an SNMP message
 func (packet *SnmpPacket) marshalMsg() ([]byte, error) {
 	var err error
-	buf := new(bytes.Buffer)
+	buf := getBuffer()
+	defer putBuffer(buf)
 
 	// version
 	buf.Write([]byte{2, 1, byte(packet.Version)})
@@ -329,25 +516,22 @@ func (packet *SnmpPacket) marshalMsg() ([]byte, error) {
 		buf.Write([]byte{4, uint8(len(packet.Community))})
 		buf.WriteString(packet.Community)
 		// pdu
-		pdu, err := packet.marshalPDU()
-		if err != nil {
+		if err = packet.writePDU(buf); err != nil {
 			return nil, err
 		}
-		buf.Write(pdu)
 	}
 
 	// build up resulting msg - sequence, length then the tail (buf)
-	msg := new(bytes.Buffer)
-	msg.WriteByte(byte(Sequence))
-
 	bufLengthBytes, err2 := marshalLength(buf.Len())
 	if err2 != nil {
 		return nil, err2
 	}
-	msg.Write(bufLengthBytes)
-	buf.WriteTo(msg) // reverse logic - want to do msg.Write(buf)
+	msg := make([]byte, 0, 1+len(bufLengthBytes)+buf.Len())
+	msg = append(msg, byte(Sequence))
+	msg = append(msg, bufLengthBytes...)
+	msg = append(msg, buf.Bytes()...)
 
-	authenticatedMessage, err := packet.authenticate(msg.Bytes())
+	authenticatedMessage, err := packet.authenticate(msg)
 	if err != nil {
 		return nil, err
 	}
@@ -357,15 +541,24 @@ func (packet *SnmpPacket) marshalMsg() ([]byte, error) {
 
 // marshal a PDU
 func (packet *SnmpPacket) marshalPDU() ([]byte, error) {
-	buf := new(bytes.Buffer)
+	pdu := new(bytes.Buffer)
+	if err := packet.writePDU(pdu); err != nil {
+		return nil, err
+	}
+	return pdu.Bytes(), nil
+}
+
+// writePDU marshals a PDU, appending it to pdu
+func (packet *SnmpPacket) writePDU(pdu *bytes.Buffer) error {
+	buf := getBuffer()
+	defer putBuffer(buf)
 
 	if packet.PDUType != Trap {
 		// requestid
+		var requestID [4]byte
+		binary.BigEndian.PutUint32(requestID[:], packet.RequestID)
 		buf.Write([]byte{2, 4})
-		err := binary.Write(buf, binary.BigEndian, packet.RequestID)
-		if err != nil {
-			return nil, err
-		}
+		buf.Write(requestID[:])
 	}
 
 	if packet.PDUType == GetBulkRequest {
@@ -379,27 +572,32 @@ func (packet *SnmpPacket) marshalPDU() ([]byte, error) {
 		// write objectIdentifier type, length and objectIdentifier value
 		mOid, err := marshalObjectIdentifier(packet.Enterprise)
 		if err != nil {
-			return nil, fmt.Errorf("Unable to marshal OID: %s\n", err.Error())
+			return fmt.Errorf("Unable to marshal OID: %w\n", err)
 		}
 
 		buf.Write([]byte{ObjectIdentifier, byte(len(mOid))})
 		buf.Write(mOid)
 
 		// write IPAddress type, length and ipAddress value
-		ip := net.ParseIP(packet.AgentAddr)
-		ipAddressBytes := ipv4toBytes(ip)
-		buf.Write([]byte{IPAddress, byte(len(ipAddressBytes))})
-		buf.Write(ipAddressBytes)
-
-		buf.Write([]byte{Integer, 1})
-		buf.WriteByte(byte(packet.GenericTrap))
+		ip := net.ParseIP(packet.AgentAddr).To4()
+		if ip == nil {
+			return fmt.Errorf("Unable to marshal agent-addr %q: not an IPv4 address", packet.AgentAddr)
+		}
+		buf.Write([]byte{IPAddress, byte(len(ip))})
+		buf.Write(ip)
 
-		buf.Write([]byte{Integer, 1})
-		buf.WriteByte(byte(packet.SpecificTrap))
+		for _, n := range []int{packet.GenericTrap, packet.SpecificTrap} {
+			intBytes, e := marshalInt32(n)
+			if e != nil {
+				return e
+			}
+			buf.Write([]byte{Integer, byte(len(intBytes))})
+			buf.Write(intBytes)
+		}
 
 		timeTicks, e := marshalUint32(uint32(packet.Timestamp))
 		if e != nil {
-			return nil, fmt.Errorf("Unable to Timestamp: %s\n", e.Error())
+			return fmt.Errorf("Unable to Timestamp: %w\n", e)
 		}
 
 		buf.Write([]byte{TimeTicks, byte(len(timeTicks))})
@@ -407,76 +605,89 @@ func (packet *SnmpPacket) marshalPDU() ([]byte, error) {
 
 	} else { // get and getnext have same packet format
 
-		// error
-		buf.Write([]byte{2, 1, 0})
+		// error, zero in requests
+		buf.Write([]byte{2, 1, byte(packet.Error)})
 
 		// error index
-		buf.Write([]byte{2, 1, 0})
+		buf.Write([]byte{2, 1, packet.ErrorIndex})
 	}
 
 	// varbind list
-	vbl, err := packet.marshalVBL()
-	if err != nil {
-		return nil, err
+	if err := packet.writeVBL(buf); err != nil {
+		return err
 	}
-	buf.Write(vbl)
 
 	// build up resulting pdu - request type, length, then the tail (buf)
-	pdu := new(bytes.Buffer)
 	pdu.WriteByte(byte(packet.PDUType))
 
 	bufLengthBytes, err2 := marshalLength(buf.Len())
 	if err2 != nil {
-		return nil, err2
+		return err2
 	}
 	pdu.Write(bufLengthBytes)
 
 	buf.WriteTo(pdu) // reverse logic - want to do pdu.Write(buf)
-	return pdu.Bytes(), nil
+	return nil
 }
 
 // marshal a varbind list
 func (packet *SnmpPacket) marshalVBL() ([]byte, error) {
+	result := new(bytes.Buffer)
+	if err := packet.writeVBL(result); err != nil {
+		return nil, err
+	}
+	return result.Bytes(), nil
+}
 
-	vblBuf := new(bytes.Buffer)
-	for _, pdu := range packet.Variables {
-		vb, err := marshalVarbind(&pdu)
-		if err != nil {
-			return nil, err
+// writeVBL marshals a varbind list, appending it to result
+func (packet *SnmpPacket) writeVBL(result *bytes.Buffer) error {
+	vblBuf := getBuffer()
+	defer putBuffer(vblBuf)
+	for i := range packet.Variables {
+		if err := writeVarbind(vblBuf, &packet.Variables[i]); err != nil {
+			return err
 		}
-		vblBuf.Write(vb)
 	}
 
-	vblBytes := vblBuf.Bytes()
-	vblLengthBytes, err := marshalLength(len(vblBytes))
+	vblLengthBytes, err := marshalLength(vblBuf.Len())
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	// FIX does bytes.Buffer give better performance than byte slices?
-	result := []byte{byte(Sequence)}
-	result = append(result, vblLengthBytes...)
-	result = append(result, vblBytes...)
-	return result, nil
+	result.WriteByte(byte(Sequence))
+	result.Write(vblLengthBytes)
+	vblBuf.WriteTo(result)
+	return nil
 }
 
 // marshal a varbind
 func marshalVarbind(pdu *SnmpPDU) ([]byte, error) {
-	oid, err := marshalOID(pdu.Name)
-	if err != nil {
+	pduBuf := new(bytes.Buffer)
+	if err := writeVarbind(pduBuf, pdu); err != nil {
 		return nil, err
 	}
-	pduBuf := new(bytes.Buffer)
-	tmpBuf := new(bytes.Buffer)
+	return pduBuf.Bytes(), nil
+}
+
+// writeVarbind marshals a varbind, appending it to pduBuf
+func writeVarbind(pduBuf *bytes.Buffer, pdu *SnmpPDU) error {
+	oid, err := pdu.marshalName()
+	if err != nil {
+		return err
+	}
+	tmpBuf := getBuffer()
+	defer putBuffer(tmpBuf)
 
 	// Marshal the PDU type into the appropriate BER
 	switch pdu.Type {
 
-	case Null:
+	case Null, NoSuchObject, NoSuchInstance, EndOfMibView:
+		// the exceptions of a response are encoded as a Null is, with
+		// their own tags
 		pduBuf.Write([]byte{byte(Sequence), byte(len(oid) + 4)})
 		pduBuf.Write([]byte{byte(ObjectIdentifier), byte(len(oid))})
 		pduBuf.Write(oid)
-		pduBuf.Write([]byte{Null, 0x00})
+		pduBuf.Write([]byte{byte(pdu.Type), 0x00})
 
 	/*
 		NUMBERS:
@@ -489,8 +700,6 @@ func marshalVarbind(pdu *SnmpPDU) ([]byte, error) {
 	*/
 
 	case Integer:
-		// TODO tests currently only cover positive integers
-
 		// Oid
 		tmpBuf.Write([]byte{byte(ObjectIdentifier), byte(len(oid))})
 		tmpBuf.Write(oid)
@@ -499,12 +708,14 @@ func marshalVarbind(pdu *SnmpPDU) ([]byte, error) {
 		var intBytes []byte
 		switch value := pdu.Value.(type) {
 		case byte:
-			intBytes = []byte{byte(pdu.Value.(int))}
+			intBytes, err = marshalInt32(int(value))
 		case int:
-			intBytes, err = marshalInt16(value)
-			pdu.Check(err)
+			intBytes, err = marshalInt32(value)
 		default:
-			return nil, fmt.Errorf("Unable to marshal PDU Integer; not byte or int.")
+			return fmt.Errorf("Unable to marshal PDU Integer; not byte or int.")
+		}
+		if err != nil {
+			return err
 		}
 		tmpBuf.Write([]byte{byte(Integer), byte(len(intBytes))})
 		tmpBuf.Write(intBytes)
@@ -525,8 +736,46 @@ func marshalVarbind(pdu *SnmpPDU) ([]byte, error) {
 		case uint32:
 			intBytes, err = marshalUint32(value)
 			pdu.Check(err)
+		case uint:
+			// as decoded, for Counter32 and Gauge32
+			if uint64(value) > math.MaxUint32 {
+				return fmt.Errorf("Unable to marshal pdu.Type %v; pdu.Value %v out of range", pdu.Type, pdu.Value)
+			}
+			intBytes, err = marshalUint32(uint32(value))
+			pdu.Check(err)
+		case int:
+			// as decoded, for TimeTicks
+			if value < 0 || int64(value) > math.MaxUint32 {
+				return fmt.Errorf("Unable to marshal pdu.Type %v; pdu.Value %v out of range", pdu.Type, pdu.Value)
+			}
+			intBytes, err = marshalUint32(uint32(value))
+			pdu.Check(err)
+		default:
+			return fmt.Errorf("Unable to marshal pdu.Type %v; unknown pdu.Value %v", pdu.Type, pdu.Value)
+		}
+		tmpBuf.Write([]byte{byte(pdu.Type), byte(len(intBytes))})
+		tmpBuf.Write(intBytes)
+
+		// Sequence, length of oid + integer, then oid/integer data
+		pduBuf.WriteByte(byte(Sequence))
+		pduBuf.WriteByte(byte(len(oid) + len(intBytes) + 4))
+		pduBuf.Write(tmpBuf.Bytes())
+
+	case Counter64:
+		// Oid
+		tmpBuf.Write([]byte{byte(ObjectIdentifier), byte(len(oid))})
+		tmpBuf.Write(oid)
+
+		// Number
+		var intBytes []byte
+		switch value := pdu.Value.(type) {
+		case uint64:
+			// as decoded
+			intBytes = marshalUint64(value)
+		case uint:
+			intBytes = marshalUint64(uint64(value))
 		default:
-			return nil, fmt.Errorf("Unable to marshal pdu.Type %v; unknown pdu.Value %v", pdu.Type, pdu.Value)
+			return fmt.Errorf("Unable to marshal pdu.Type %v; unknown pdu.Value %v", pdu.Type, pdu.Value)
 		}
 		tmpBuf.Write([]byte{byte(pdu.Type), byte(len(intBytes))})
 		tmpBuf.Write(intBytes)
@@ -547,16 +796,18 @@ func marshalVarbind(pdu *SnmpPDU) ([]byte, error) {
 		switch value := pdu.Value.(type) {
 		case []byte:
 			octetStringBytes = value
+		case Bits:
+			octetStringBytes = value
 		case string:
 			octetStringBytes = []byte(value)
 		default:
-			return nil, fmt.Errorf("Unable to marshal PDU OctetString; not []byte or String.")
+			return fmt.Errorf("Unable to marshal PDU OctetString; not []byte or String.")
 		}
 
 		var length []byte
 		length, err = marshalLength(len(octetStringBytes))
 		if err != nil {
-			return nil, err
+			return err
 		}
 		tmpBuf.WriteByte(byte(OctetString))
 		tmpBuf.Write(length)
@@ -566,7 +817,7 @@ func marshalVarbind(pdu *SnmpPDU) ([]byte, error) {
 
 		length, err = marshalLength(len(tmpBytes))
 		if err != nil {
-			return nil, err
+			return err
 		}
 		// Sequence, length of oid + octetstring, then oid/octetstring data
 		pduBuf.WriteByte(byte(Sequence))
@@ -579,15 +830,22 @@ func marshalVarbind(pdu *SnmpPDU) ([]byte, error) {
 		//Oid
 		tmpBuf.Write([]byte{byte(ObjectIdentifier), byte(len(oid))})
 		tmpBuf.Write(oid)
-		value := pdu.Value.(string)
-		oidBytes, err := marshalOID(value)
+		var oidBytes []byte
+		switch value := pdu.Value.(type) {
+		case string:
+			oidBytes, err = marshalOID(value)
+		case Oid:
+			oidBytes, err = marshalOid(value)
+		default:
+			return fmt.Errorf("Unable to marshal PDU ObjectIdentifier; not string or Oid.")
+		}
 		pdu.Check(err)
 
 		//Oid data
 		var length []byte
 		length, err = marshalLength(len(oidBytes))
 		if err != nil {
-			return nil, err
+			return err
 		}
 		tmpBuf.WriteByte(byte(pdu.Type))
 		tmpBuf.Write(length)
@@ -596,13 +854,42 @@ func marshalVarbind(pdu *SnmpPDU) ([]byte, error) {
 		tmpBytes := tmpBuf.Bytes()
 		length, err = marshalLength(len(tmpBytes))
 		if err != nil {
-			return nil, err
+			return err
 		}
 		// Sequence, length of oid + oid, then oid/oid data
 		pduBuf.WriteByte(byte(Sequence))
 		pduBuf.Write(length)
 		pduBuf.Write(tmpBytes)
 
+	case Opaque, OpaqueFloat, OpaqueDouble:
+		//Oid
+		tmpBuf.Write([]byte{byte(ObjectIdentifier), byte(len(oid))})
+		tmpBuf.Write(oid)
+
+		//Opaque
+		var opaqueBytes []byte
+		if opaqueBytes, err = marshalOpaque(pdu.Type, pdu.Value); err != nil {
+			return err
+		}
+		var length []byte
+		length, err = marshalLength(len(opaqueBytes))
+		if err != nil {
+			return err
+		}
+		tmpBuf.WriteByte(byte(Opaque))
+		tmpBuf.Write(length)
+		tmpBuf.Write(opaqueBytes)
+
+		tmpBytes := tmpBuf.Bytes()
+		length, err = marshalLength(len(tmpBytes))
+		if err != nil {
+			return err
+		}
+		// Sequence, length of oid + opaque, then oid/opaque data
+		pduBuf.WriteByte(byte(Sequence))
+		pduBuf.Write(length)
+		pduBuf.Write(tmpBytes)
+
 	// MrSpock changes. TODO NO tests for this yet - waiting for .pcap
 	case IPAddress:
 		//Oid
@@ -617,7 +904,7 @@ func marshalVarbind(pdu *SnmpPDU) ([]byte, error) {
 			ip := net.ParseIP(value)
 			ipAddressBytes = ipv4toBytes(ip)
 		default:
-			return nil, fmt.Errorf("Unable to marshal PDU IPAddress; not []byte or String.")
+			return fmt.Errorf("Unable to marshal PDU IPAddress; not []byte or String.")
 		}
 		tmpBuf.Write([]byte{byte(IPAddress), byte(len(ipAddressBytes))})
 		tmpBuf.Write(ipAddressBytes)
@@ -627,10 +914,10 @@ func marshalVarbind(pdu *SnmpPDU) ([]byte, error) {
 		pduBuf.Write(tmpBuf.Bytes())
 
 	default:
-		return nil, fmt.Errorf("Unable to marshal PDU: unknown BER type %q", pdu.Type)
+		return fmt.Errorf("Unable to marshal PDU: unknown BER type %q", pdu.Type)
 	}
 
-	return pduBuf.Bytes(), nil
+	return nil
 }
 
 // -- Unmarshalling Logic ------------------------------------------------------
@@ -642,8 +929,12 @@ func (x *GoSNMP) unmarshalHeader(packet []byte, response *SnmpPacket) (int, erro
 	if response == nil {
 		return 0, fmt.Errorf("Cannot unmarshal response into nil packet reference")
 	}
+	if err := x.DecodeLimits.check(packet); err != nil {
+		return 0, err
+	}
 
 	response.Variables = make([]SnmpPDU, 0, 5)
+	response.Warnings = nil
 
 	// Start parsing the packet
 	cursor := 0
@@ -655,14 +946,18 @@ func (x *GoSNMP) unmarshalHeader(packet []byte, response *SnmpPacket) (int, erro
 
 	length, cursor := parseLength(packet)
 	if len(packet) != length {
-		return 0, fmt.Errorf("Error verifying packet sanity: Got %d Expected: %d\n", len(packet), length)
+		if !x.Lenient {
+			return 0, fmt.Errorf("Error verifying packet sanity: Got %d Expected: %d\n", len(packet), length)
+		}
+		// the PDU is cut to its own length
+		x.warn(response, -1, "", "message length %d, %d bytes received", length, len(packet))
 	}
 	x.logPrintf("Packet sanity verified, we got all the bytes (%d)", length)
 
 	// Parse SNMP Version
 	rawVersion, count, err := parseRawField(packet[cursor:], "version")
 	if err != nil {
-		return 0, fmt.Errorf("Error parsing SNMP packet version: %s", err.Error())
+		return 0, fmt.Errorf("Error parsing SNMP packet version: %w", err)
 	}
 
 	cursor += count
@@ -680,12 +975,12 @@ func (x *GoSNMP) unmarshalHeader(packet []byte, response *SnmpPacket) (int, erro
 		// Parse community
 		rawCommunity, count, err := parseRawField(packet[cursor:], "community")
 		if err != nil {
-			return 0, fmt.Errorf("Error parsing community string: %s", err.Error())
+			return 0, fmt.Errorf("Error parsing community string: %w", err)
 		}
 		cursor += count
 		if community, ok := rawCommunity.(string); ok {
 			response.Community = community
-			x.logPrintf("Parsed community %s", community)
+			x.logPrintf("Parsed community %s", redact(community))
 		}
 	}
 	return cursor, nil
@@ -697,17 +992,17 @@ func (x *GoSNMP) unmarshalPayload(packet []byte, cursor int, response *SnmpPacke
 	requestType := PDUType(packet[cursor])
 	switch requestType {
 	// known, supported types
-	case GetResponse, GetNextRequest, GetBulkRequest, Report, SNMPv2Trap:
+	case GetResponse, GetNextRequest, GetBulkRequest, Report, SNMPv2Trap, InformRequest:
 		response.PDUType = requestType
 		err = x.unmarshalResponse(packet[cursor:], response)
 		if err != nil {
-			return fmt.Errorf("Error in unmarshalResponse: %s", err.Error())
+			return fmt.Errorf("Error in unmarshalResponse: %w", err)
 		}
 	case Trap:
 		response.PDUType = requestType
 		err = x.unmarshalTrapV1(packet[cursor:], response)
 		if err != nil {
-			return fmt.Errorf("Error in unmarshalTrapV1: %s", err.Error())
+			return fmt.Errorf("Error in unmarshalTrapV1: %w", err)
 		}
 	default:
 		return fmt.Errorf("Unknown PDUType %#x", requestType)
@@ -720,14 +1015,17 @@ func (x *GoSNMP) unmarshalResponse(packet []byte, response *SnmpPacket) error {
 
 	getResponseLength, cursor := parseLength(packet)
 	if len(packet) != getResponseLength {
-		return fmt.Errorf("Error verifying Response sanity: Got %d Expected: %d\n", len(packet), getResponseLength)
+		if !x.Lenient {
+			return fmt.Errorf("Error verifying Response sanity: Got %d Expected: %d\n", len(packet), getResponseLength)
+		}
+		packet = x.lenientCut(response, packet, getResponseLength, "PDU")
 	}
 	x.logPrintf("getResponseLength: %d", getResponseLength)
 
 	// Parse Request-ID
 	rawRequestID, count, err := parseRawField(packet[cursor:], "request id")
 	if err != nil {
-		return fmt.Errorf("Error parsing SNMP packet request ID: %s", err.Error())
+		return fmt.Errorf("Error parsing SNMP packet request ID: %w", err)
 	}
 	cursor += count
 	if requestid, ok := rawRequestID.(int); ok {
@@ -739,7 +1037,7 @@ func (x *GoSNMP) unmarshalResponse(packet []byte, response *SnmpPacket) error {
 		// Parse Non Repeaters
 		rawNonRepeaters, count, err := parseRawField(packet[cursor:], "non repeaters")
 		if err != nil {
-			return fmt.Errorf("Error parsing SNMP packet non repeaters: %s", err.Error())
+			return fmt.Errorf("Error parsing SNMP packet non repeaters: %w", err)
 		}
 		cursor += count
 		if nonRepeaters, ok := rawNonRepeaters.(int); ok {
@@ -749,7 +1047,7 @@ func (x *GoSNMP) unmarshalResponse(packet []byte, response *SnmpPacket) error {
 		// Parse Max Repetitions
 		rawMaxRepetitions, count, err := parseRawField(packet[cursor:], "max repetitions")
 		if err != nil {
-			return fmt.Errorf("Error parsing SNMP packet max repetitions: %s", err.Error())
+			return fmt.Errorf("Error parsing SNMP packet max repetitions: %w", err)
 		}
 		cursor += count
 		if maxRepetitions, ok := rawMaxRepetitions.(int); ok {
@@ -759,7 +1057,7 @@ func (x *GoSNMP) unmarshalResponse(packet []byte, response *SnmpPacket) error {
 		// Parse Error-Status
 		rawError, count, err := parseRawField(packet[cursor:], "error-status")
 		if err != nil {
-			return fmt.Errorf("Error parsing SNMP packet error: %s", err.Error())
+			return fmt.Errorf("Error parsing SNMP packet error: %w", err)
 		}
 		cursor += count
 		if errorStatus, ok := rawError.(int); ok {
@@ -770,7 +1068,7 @@ func (x *GoSNMP) unmarshalResponse(packet []byte, response *SnmpPacket) error {
 		// Parse Error-Index
 		rawErrorIndex, count, err := parseRawField(packet[cursor:], "error index")
 		if err != nil {
-			return fmt.Errorf("Error parsing SNMP packet error index: %s", err.Error())
+			return fmt.Errorf("Error parsing SNMP packet error index: %w", err)
 		}
 		cursor += count
 		if errorindex, ok := rawErrorIndex.(int); ok {
@@ -787,14 +1085,17 @@ func (x *GoSNMP) unmarshalTrapV1(packet []byte, response *SnmpPacket) error {
 
 	getResponseLength, cursor := parseLength(packet)
 	if len(packet) != getResponseLength {
-		return fmt.Errorf("Error verifying Response sanity: Got %d Expected: %d\n", len(packet), getResponseLength)
+		if !x.Lenient {
+			return fmt.Errorf("Error verifying Response sanity: Got %d Expected: %d\n", len(packet), getResponseLength)
+		}
+		packet = x.lenientCut(response, packet, getResponseLength, "PDU")
 	}
 	x.logPrintf("getResponseLength: %d", getResponseLength)
 
 	// Parse Enterprise
 	rawEnterprise, count, err := parseRawField(packet[cursor:], "enterprise")
 	if err != nil {
-		return fmt.Errorf("Error parsing SNMP packet error: %s", err.Error())
+		return fmt.Errorf("Error parsing SNMP packet error: %w", err)
 	}
 	cursor += count
 	if Enterpise, ok := rawEnterprise.([]int); ok {
@@ -805,7 +1106,7 @@ func (x *GoSNMP) unmarshalTrapV1(packet []byte, response *SnmpPacket) error {
 	// Parse AgentAddr
 	rawAgentAddr, count, err := parseRawField(packet[cursor:], "agent-addr")
 	if err != nil {
-		return fmt.Errorf("Error parsing SNMP packet error: %s", err.Error())
+		return fmt.Errorf("Error parsing SNMP packet error: %w", err)
 	}
 	cursor += count
 	if AgentAddr, ok := rawAgentAddr.(string); ok {
@@ -816,7 +1117,7 @@ func (x *GoSNMP) unmarshalTrapV1(packet []byte, response *SnmpPacket) error {
 	// Parse GenericTrap
 	rawGenericTrap, count, err := parseRawField(packet[cursor:], "generic-trap")
 	if err != nil {
-		return fmt.Errorf("Error parsing SNMP packet error: %s", err.Error())
+		return fmt.Errorf("Error parsing SNMP packet error: %w", err)
 	}
 	cursor += count
 	if GenericTrap, ok := rawGenericTrap.(int); ok {
@@ -827,7 +1128,7 @@ func (x *GoSNMP) unmarshalTrapV1(packet []byte, response *SnmpPacket) error {
 	// Parse SpecificTrap
 	rawSpecificTrap, count, err := parseRawField(packet[cursor:], "specific-trap")
 	if err != nil {
-		return fmt.Errorf("Error parsing SNMP packet error: %s", err.Error())
+		return fmt.Errorf("Error parsing SNMP packet error: %w", err)
 	}
 	cursor += count
 	if SpecificTrap, ok := rawSpecificTrap.(int); ok {
@@ -838,7 +1139,7 @@ func (x *GoSNMP) unmarshalTrapV1(packet []byte, response *SnmpPacket) error {
 	// Parse TimeStamp
 	rawTimestamp, count, err := parseRawField(packet[cursor:], "time-stamp")
 	if err != nil {
-		return fmt.Errorf("Error parsing SNMP packet error: %s", err.Error())
+		return fmt.Errorf("Error parsing SNMP packet error: %w", err)
 	}
 	cursor += count
 	if Timestamp, ok := rawTimestamp.(int); ok {
@@ -851,8 +1152,11 @@ func (x *GoSNMP) unmarshalTrapV1(packet []byte, response *SnmpPacket) error {
 
 // unmarshal a Varbind list
 func (x *GoSNMP) unmarshalVBL(packet []byte, response *SnmpPacket) error {
+	if x.Lenient && !response.lazyVarbinds {
+		return x.unmarshalVBLLenient(packet, response)
+	}
 
-	var cursor, cursorInc int
+	var cursor int
 	var vblLength int
 	if packet[cursor] != 0x30 {
 		return fmt.Errorf("Expected a sequence when unmarshalling a VBL, got %x", packet[cursor])
@@ -863,6 +1167,9 @@ func (x *GoSNMP) unmarshalVBL(packet []byte, response *SnmpPacket) error {
 		return fmt.Errorf("Error verifying: packet length %d vbl length %d\n", len(packet), vblLength)
 	}
 	x.logPrintf("vblLength: %d", vblLength)
+	if err := x.DecodeLimits.checkVarbinds(packet[cursor:vblLength]); err != nil {
+		return err
+	}
 
 	// check for an empty response
 	if vblLength == 2 && packet[1] == 0x00 {
@@ -875,49 +1182,61 @@ func (x *GoSNMP) unmarshalVBL(packet []byte, response *SnmpPacket) error {
 			return fmt.Errorf("Expected a sequence when unmarshalling a VB, got %x", packet[cursor])
 		}
 
-		_, cursorInc = parseLength(packet[cursor:])
+		vbLength, cursorInc := parseLength(packet[cursor:])
+
+		// report pdus are always decoded, send() looks at them
+		if response.lazyVarbinds && response.PDUType != Report {
+			if cursor+vbLength > len(packet) {
+				return fmt.Errorf("Error verifying: varbind length %d past end of vbl", vbLength)
+			}
+			pdu, err := x.unmarshalLazyVarbind(packet[cursor+cursorInc : cursor+vbLength])
+			if err != nil {
+				return err
+			}
+			response.LazyVariables = append(response.LazyVariables, pdu)
+			cursor += vbLength
+			continue
+		}
 		cursor += cursorInc
 
 		// Parse OID
-		rawOid, oidLength, err := parseRawField(packet[cursor:], "OID")
+		if Asn1BER(packet[cursor]) != ObjectIdentifier {
+			return fmt.Errorf("Error parsing OID Value: Unknown field type: %x", packet[cursor])
+		}
+		oidLength, oidCursor := parseLength(packet[cursor:])
+		oid, err := parseOid(packet[cursor+oidCursor : cursor+oidLength])
 		if err != nil {
-			return fmt.Errorf("Error parsing OID Value: %s", err.Error())
+			return fmt.Errorf("Error parsing OID Value: %w", err)
 		}
 		cursor += oidLength
-
-		var oid []int
-		var ok bool
-		if oid, ok = rawOid.([]int); !ok {
-			return fmt.Errorf("unable to type assert rawOid |%v| to []int", rawOid)
-		}
-		oidStr := oidToString(oid)
+		oidStr := oid.String()
 		x.logPrintf("OID: %s", oidStr)
 
 		// Parse Value
 		v, err := x.decodeValue(packet[cursor:], "value")
 		if err != nil {
-			return fmt.Errorf("Error decoding value: %v", err)
+			return fmt.Errorf("Error decoding value: %w", err)
 		}
 		valueLength, _ := parseLength(packet[cursor:])
 		cursor += valueLength
-		response.Variables = append(response.Variables, SnmpPDU{oidStr, v.Type, v.Value, x.Logger})
+		response.Variables = append(response.Variables, SnmpPDU{Name: oidStr, Type: v.Type, Value: v.Value, Logger: x.Logger, NameOid: oid})
 	}
 	return nil
 }
 
-// receive response from network and read into a byte array
-func (x *GoSNMP) receive() ([]byte, error) {
-	n, err := x.Conn.Read(x.rxBuf[:])
-	if err != nil {
-		return nil, fmt.Errorf("Error reading from UDP: %s", err.Error())
+// receive waits for the dispatcher to hand us a response, until deadline
+func (x *GoSNMP) receive(ctx context.Context, mux *dispatcher, responses chan datagram, deadline time.Time) (datagram, error) {
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+
+	select {
+	case dg := <-responses:
+		return dg, dg.err
+	case <-timer.C:
+		return datagram{}, &timeoutError{msg: "Request timeout waiting for response"}
+	case <-ctx.Done():
+		return datagram{}, ctx.Err()
+	case <-mux.stopped:
+		return datagram{}, mux.err
 	}
-
-	if n == rxBufSize {
-		// This should never happen unless we're using something like a unix domain socket.
-		return nil, fmt.Errorf("response buffer too small")
-	}
-
-	resp := make([]byte, n)
-	copy(resp, x.rxBuf[:n])
-	return resp, nil
 }
@@ -6,6 +6,7 @@ package gosnmp
 
 import (
 	"bytes"
+	"context"
 	"io/ioutil"
 	"log"
 	"net"
@@ -224,7 +225,7 @@ var testsEnmarshal = []testsEnmarshalT{
 // vbPosPdus returns a slice of oids in the given test
 func vbPosPdus(test testsEnmarshalT) (pdus []SnmpPDU) {
 	for _, vbp := range test.vbPositions {
-		pdu := SnmpPDU{vbp.oid, vbp.pduType, vbp.pduValue, nil}
+		pdu := SnmpPDU{Name: vbp.oid, Type: vbp.pduType, Value: vbp.pduValue}
 		pdus = append(pdus, pdu)
 	}
 	return
@@ -263,7 +264,7 @@ func TestEnmarshalVarbind(t *testing.T) {
 
 	for _, test := range testsEnmarshal {
 		for j, test2 := range test.vbPositions {
-			snmppdu := &SnmpPDU{test2.oid, test2.pduType, test2.pduValue, nil}
+			snmppdu := &SnmpPDU{Name: test2.oid, Type: test2.pduType, Value: test2.pduValue}
 			testBytes, err := marshalVarbind(snmppdu)
 			if err != nil {
 				t.Errorf("#%s:%d:%s err returned: %v",
@@ -1281,13 +1282,13 @@ func TestSendOneRequest_dups(t *testing.T) {
 	pdus := []SnmpPDU{SnmpPDU{Name: ".1.2", Type: Null}}
 	reqPkt := x.mkSnmpPacket(GetResponse, pdus, 0, 0) //not actually a GetResponse, but we need something our test server can unmarshal
 
-	_, err = x.sendOneRequest(reqPkt, true)
+	_, err = x.sendOneRequest(context.Background(), reqPkt, true)
 	if err != nil {
 		t.Errorf("Error: %s", err)
 		return
 	}
 
-	_, err = x.sendOneRequest(reqPkt, true)
+	_, err = x.sendOneRequest(context.Background(), reqPkt, true)
 	if err != nil {
 		t.Errorf("Error: %s", err)
 		return
@@ -1329,7 +1330,7 @@ func BenchmarkSendOneRequest(b *testing.B) {
 	reqPkt := x.mkSnmpPacket(GetRequest, pdus, 0, 0)
 
 	// make sure everything works before starting the test
-	_, err = x.sendOneRequest(reqPkt, true)
+	_, err = x.sendOneRequest(context.Background(), reqPkt, true)
 	if err != nil {
 		b.Fatalf("Precheck failed: %s", err)
 	}
@@ -1337,7 +1338,7 @@ func BenchmarkSendOneRequest(b *testing.B) {
 	b.StartTimer()
 
 	for n := 0; n < b.N; n++ {
-		_, err = x.sendOneRequest(reqPkt, true)
+		_, err = x.sendOneRequest(context.Background(), reqPkt, true)
 		if err != nil {
 			b.Fatalf("Error: %s", err)
 			return
@@ -1400,3 +1401,88 @@ func trap1() []byte {
 		0x08, 0x00, 0x0c, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x05, 0x00, 0x08, 0x00, 0x00, 0x00,
 		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x6c, 0x00, 0x00, 0x00}
 }
+
+// marshalled messages must not share memory with the pooled scratch buffers
+func TestMarshalMsgBuffersReused(t *testing.T) {
+	mk := func(community string, value string) *SnmpPacket {
+		return &SnmpPacket{
+			Version:   Version2c,
+			Community: community,
+			PDUType:   GetResponse,
+			RequestID: 1,
+			Variables: []SnmpPDU{{Name: ".1.3.6.1.2.1.1.1.0", Type: OctetString, Value: value}},
+		}
+	}
+	first, err := mk("public", "first").marshalMsg()
+	if err != nil {
+		t.Fatalf("marshalMsg() err: %v", err)
+	}
+	want := append([]byte(nil), first...)
+	for i := 0; i < 10; i++ {
+		if _, err = mk("private", "a much longer second value").marshalMsg(); err != nil {
+			t.Fatalf("marshalMsg() err: %v", err)
+		}
+	}
+	if !bytes.Equal(first, want) {
+		t.Errorf("marshalMsg() result changed by later calls:\n got %x\nwant %x", first, want)
+	}
+}
+
+func BenchmarkMarshalMsg(b *testing.B) {
+	packet := &SnmpPacket{
+		Version:   Version2c,
+		Community: "public",
+		PDUType:   GetResponse,
+		RequestID: 1,
+		Variables: []SnmpPDU{
+			{Name: ".1.3.6.1.2.1.1.1.0", Type: OctetString, Value: "Linux host 4.4.0"},
+			{Name: ".1.3.6.1.2.1.1.3.0", Type: TimeTicks, Value: uint32(12345)},
+			{Name: ".1.3.6.1.2.1.2.2.1.10.1", Type: Counter32, Value: uint32(67890)},
+			{Name: ".1.3.6.1.2.1.2.2.1.2.1", Type: Null},
+		},
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := packet.marshalMsg(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkMarshalMsgV3AuthPriv(b *testing.B) {
+	x := &GoSNMP{
+		Version:       Version3,
+		MsgFlags:      AuthPriv,
+		SecurityModel: UserSecurityModel,
+		SecurityParameters: &UsmSecurityParameters{
+			UserName:                 "user",
+			AuthenticationProtocol:   SHA,
+			AuthenticationPassphrase: "authpassphrase",
+			PrivacyProtocol:          AES,
+			PrivacyPassphrase:        "privpassphrase",
+		},
+	}
+	if err := x.validateParameters(); err != nil {
+		b.Fatal(err)
+	}
+	// as if discovery had taken place, so the keys localized to the engine
+	// are generated
+	discovered := &SnmpPacket{
+		Version:            Version3,
+		SecurityModel:      UserSecurityModel,
+		SecurityParameters: &UsmSecurityParameters{AuthoritativeEngineID: "engine"},
+	}
+	if err := x.storeSecurityParameters(discovered); err != nil {
+		b.Fatal(err)
+	}
+	packet := x.mkSnmpPacket(GetRequest, []SnmpPDU{{Name: ".1.3.6.1.2.1.1.1.0", Type: Null}}, 0, 0)
+	if err := x.initPacket(packet); err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := packet.marshalMsg(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package mib
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/soniah/gosnmp"
+)
+
+// AnnotatedPDU is a varbind described using the MIBs loaded in a Tree
+type AnnotatedPDU struct {
+	gosnmp.SnmpPDU
+
+	// Symbol is the varbind's name translated by TranslateOid, eg
+	// "IF-MIB::ifOperStatus.3"
+	Symbol string
+
+	// Object is the object the varbind is an instance of, or nil if it
+	// isn't in the tree
+	Object *Node
+
+	// Syntax is the object's declared syntax, eg "InterfaceIndex", or empty
+	// if Object is nil
+	Syntax string
+
+	// Label is the enumeration label of the value, eg "up", when the syntax
+	// is an enumerated INTEGER with a label for it
+	Label string
+
+	// oidValue is the value translated by TranslateOid, for an
+	// OBJECT IDENTIFIER
+	oidValue string
+}
+
+// String formats the varbind much as the net-snmp tools do, eg
+// "IF-MIB::ifOperStatus.3 = IfOperStatus: up(1)"
+func (p AnnotatedPDU) String() string {
+	syntax := p.Syntax
+	if syntax == "" {
+		syntax = typeName(p.Type)
+	}
+	return fmt.Sprintf("%s = %s: %s", p.Symbol, syntax, p.formatValue())
+}
+
+// MarshalJSON adds the symbol, syntax and label to the SnmpPDU's JSON, which
+// would otherwise be used on its own as SnmpPDU is embedded
+func (p AnnotatedPDU) MarshalJSON() ([]byte, error) {
+	b, err := p.SnmpPDU.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err = json.Unmarshal(b, &fields); err != nil {
+		return nil, err
+	}
+	for name, value := range map[string]string{"symbol": p.Symbol, "syntax": p.Syntax, "label": p.Label} {
+		if value != "" {
+			fields[name], _ = json.Marshal(value)
+		}
+	}
+	return json.Marshal(fields)
+}
+
+func (p AnnotatedPDU) formatValue() string {
+	if p.Label != "" {
+		return fmt.Sprintf("%s(%v)", p.Label, p.Value)
+	}
+	switch p.Type {
+	case gosnmp.ObjectIdentifier:
+		if p.oidValue != "" {
+			return p.oidValue
+		}
+	case gosnmp.OctetString:
+		b, _ := p.Value.([]byte)
+		s, isHex := gosnmp.OctetStringRenderer{}.Render(b)
+		if isHex {
+			return s
+		}
+		return fmt.Sprintf("%q", s)
+	case gosnmp.NoSuchObject, gosnmp.NoSuchInstance, gosnmp.EndOfMibView, gosnmp.Null:
+		return typeName(p.Type)
+	}
+	return fmt.Sprint(p.Value)
+}
+
+// typeName names the BER types of values
+func typeName(t gosnmp.Asn1BER) string {
+	switch t {
+	case gosnmp.Integer:
+		return "INTEGER"
+	case gosnmp.OctetString:
+		return "OCTET STRING"
+	case gosnmp.ObjectIdentifier:
+		return "OBJECT IDENTIFIER"
+	case gosnmp.IPAddress:
+		return "IpAddress"
+	case gosnmp.Counter32:
+		return "Counter32"
+	case gosnmp.Gauge32:
+		return "Gauge32"
+	case gosnmp.TimeTicks:
+		return "TimeTicks"
+	case gosnmp.Opaque:
+		return "Opaque"
+	case gosnmp.OpaqueFloat:
+		return "Opaque Float"
+	case gosnmp.OpaqueDouble:
+		return "Opaque Double"
+	case gosnmp.Counter64:
+		return "Counter64"
+	case gosnmp.Uinteger32:
+		return "Unsigned32"
+	case gosnmp.Null:
+		return "NULL"
+	case gosnmp.NoSuchObject:
+		return "No Such Object"
+	case gosnmp.NoSuchInstance:
+		return "No Such Instance"
+	case gosnmp.EndOfMibView:
+		return "End of MIB View"
+	}
+	return fmt.Sprintf("Type(%#x)", byte(t))
+}
+
+// Annotate describes pdu using the MIBs in the tree. Names that aren't
+// valid oids are left as they are.
+func (t *Tree) Annotate(pdu gosnmp.SnmpPDU) AnnotatedPDU {
+	a := AnnotatedPDU{SnmpPDU: pdu, Symbol: pdu.Name}
+	oid, err := pdu.Oid()
+	if err != nil {
+		return a
+	}
+	a.Symbol = t.TranslateOid(oid)
+	if pdu.Type == gosnmp.ObjectIdentifier {
+		// show oid values by name too
+		if v, err := pdu.OidValue(); err == nil {
+			a.oidValue = t.TranslateOid(v)
+		}
+	}
+
+	n, _ := t.LookupOid(oid)
+	if n == nil || n.Syntax == nil {
+		return a
+	}
+	a.Object = n
+	a.Syntax = n.Syntax.Type
+	if pdu.Type == gosnmp.Integer {
+		value := gosnmp.ToBigInt(pdu.Value).Int64()
+		for _, e := range t.BaseSyntax(*n.Syntax).Enums {
+			if e.Value == value {
+				a.Label = e.Name
+				break
+			}
+		}
+	}
+	return a
+}
+
+// AnnotatedWalkFunc is the type of the function called for each varbind
+// found by WalkAnnotated and BulkWalkAnnotated
+type AnnotatedWalkFunc func(pdu AnnotatedPDU) error
+
+// WalkAnnotated walks a subtree with x.Walk, calling walkFn with each
+// varbind annotated by Annotate. root may be an oid or a name, see
+// Translate.
+func (t *Tree) WalkAnnotated(x *gosnmp.GoSNMP, root string, walkFn AnnotatedWalkFunc) error {
+	return t.walkAnnotated(context.Background(), x.WalkCtx, root, walkFn)
+}
+
+// BulkWalkAnnotated is like WalkAnnotated, but walks with x.BulkWalk
+func (t *Tree) BulkWalkAnnotated(x *gosnmp.GoSNMP, root string, walkFn AnnotatedWalkFunc) error {
+	return t.walkAnnotated(context.Background(), x.BulkWalkCtx, root, walkFn)
+}
+
+func (t *Tree) walkAnnotated(ctx context.Context, walk func(context.Context, string, gosnmp.WalkFunc) error,
+	root string, walkFn AnnotatedWalkFunc) error {
+	oid, err := t.Translate(root)
+	if err != nil {
+		return err
+	}
+	return walk(ctx, oid.String(), func(pdu gosnmp.SnmpPDU) error {
+		return walkFn(t.Annotate(pdu))
+	})
+}
@@ -0,0 +1,141 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package mib
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/soniah/gosnmp"
+)
+
+func TestAnnotate(t *testing.T) {
+	tree := loadTestdata(t)
+
+	tests := []struct {
+		pdu    gosnmp.SnmpPDU
+		syntax string
+		label  string
+		str    string
+	}{
+		{
+			gosnmp.SnmpPDU{Name: `.1.3.6.1.4.1.99999.1.2.1.2.1.3.7.97.98`, Type: gosnmp.Integer, Value: 2},
+			"TestStatus", "down",
+			`TEST-MIB::testStatus.7."ab" = TestStatus: down(2)`,
+		},
+		{
+			gosnmp.SnmpPDU{Name: `.1.3.6.1.4.1.99999.1.2.1.2.1.3.7.97.98`, Type: gosnmp.Integer, Value: 9},
+			"TestStatus", "",
+			`TEST-MIB::testStatus.7."ab" = TestStatus: 9`,
+		},
+		{
+			gosnmp.SnmpPDU{Name: `.1.3.6.1.4.1.99998.1.0`, Type: gosnmp.Integer, Value: 1},
+			"INTEGER", "on",
+			`TEST-V1-MIB::testV1Flag.0 = INTEGER: on(1)`,
+		},
+		{
+			gosnmp.SnmpPDU{Name: `.1.3.6.1.4.1.99999.1.2.1.1.0`, Type: gosnmp.Counter64, Value: uint64(42)},
+			"Counter64", "",
+			`TEST-MIB::testCount.0 = Counter64: 42`,
+		},
+		{
+			gosnmp.SnmpPDU{Name: `.1.3.6.1.4.1.99999.1.2.1.2.1.2.7.97`, Type: gosnmp.OctetString, Value: []byte("a")},
+			"TestName", "",
+			`TEST-MIB::testName.7."a" = TestName: "a"`,
+		},
+		{
+			gosnmp.SnmpPDU{Name: `.1.3.6.1.4.1.99997.1`, Type: gosnmp.ObjectIdentifier, Value: ".1.3.6.1.4.1.99999.1.2.1.1"},
+			"", "",
+			`SNMPv2-SMI::enterprises.99997.1 = OBJECT IDENTIFIER: TEST-MIB::testCount`,
+		},
+		{
+			gosnmp.SnmpPDU{Name: `.1.3.6.1.4.1.99999.1.2.1.1.0`, Type: gosnmp.NoSuchInstance},
+			"Counter64", "",
+			`TEST-MIB::testCount.0 = Counter64: No Such Instance`,
+		},
+		{
+			gosnmp.SnmpPDU{Name: `.3.1`, Type: gosnmp.OctetString, Value: []byte{0xff, 0x01}},
+			"", "",
+			`.3.1 = OCTET STRING: FF 01`,
+		},
+	}
+	for _, test := range tests {
+		a := tree.Annotate(test.pdu)
+		if a.Syntax != test.syntax || a.Label != test.label {
+			t.Errorf("%s: got syntax %q label %q, want %q %q", test.pdu.Name, a.Syntax, a.Label, test.syntax, test.label)
+		}
+		if (a.Object == nil) != (test.syntax == "") {
+			t.Errorf("%s: got object %v", test.pdu.Name, a.Object)
+		}
+		if got := a.String(); got != test.str {
+			t.Errorf("%s: got %s, want %s", test.pdu.Name, got, test.str)
+		}
+	}
+}
+
+func TestWalkAnnotated(t *testing.T) {
+	tree := loadTestdata(t)
+
+	var walked string
+	walk := func(ctx context.Context, root string, walkFn gosnmp.WalkFunc) error {
+		walked = root
+		for _, pdu := range []gosnmp.SnmpPDU{
+			{Name: ".1.3.6.1.4.1.99998.1.0", Type: gosnmp.Integer, Value: 2},
+			{Name: ".1.3.6.1.4.1.99998.2.1.4.1.97.10.0.0.1.1.2.3.4.5.6", Type: gosnmp.Gauge32, Value: uint(5)},
+		} {
+			if err := walkFn(pdu); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var got []string
+	err := tree.walkAnnotated(context.Background(), walk, "TEST-V1-MIB::testV1", func(pdu AnnotatedPDU) error {
+		got = append(got, pdu.String())
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if walked != ".1.3.6.1.4.1.99998" {
+		t.Errorf("walked %s", walked)
+	}
+	want := []string{
+		"TEST-V1-MIB::testV1Flag.0 = INTEGER: off(2)",
+		`TEST-V1-MIB::testV1Value."a".10.0.0.1.1.2.3.4.5.6 = Gauge: 5`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if err = tree.walkAnnotated(context.Background(), walk, "noSuchObject", nil); err == nil {
+		t.Errorf("expected an error walking an unknown object")
+	}
+}
+
+func TestAnnotatedJSON(t *testing.T) {
+	tree := loadTestdata(t)
+
+	p := tree.Annotate(gosnmp.SnmpPDU{Name: `.1.3.6.1.4.1.99999.1.2.1.2.1.3.7.97.98`, Type: gosnmp.Integer, Value: 2})
+	b, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"label":"down","name":".1.3.6.1.4.1.99999.1.2.1.2.1.3.7.97.98","symbol":"TEST-MIB::testStatus.7.\"ab\"","syntax":"TestStatus","type":"Integer","value":2}`
+	if string(b) != want {
+		t.Errorf("got  %s\nwant %s", b, want)
+	}
+
+	var pdu gosnmp.SnmpPDU
+	if err := json.Unmarshal(b, &pdu); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(pdu, p.SnmpPDU) {
+		t.Errorf("got %#v, want %#v", pdu, p.SnmpPDU)
+	}
+}
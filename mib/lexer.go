@@ -0,0 +1,155 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package mib
+
+import (
+	"fmt"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokNumber
+	tokString // a quoted string
+	tokBinary // a binary or hex string, eg 'ff'H
+	tokPunct  // ::= { } ( ) , ; .. | [ ]
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	line int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of file"
+	case tokString:
+		return "quoted string"
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// lex splits the text of MIB modules into tokens, dropping comments
+func lex(src []byte) ([]token, error) {
+	var tokens []token
+	line := 1
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f':
+			i++
+
+		case c == '-' && i+1 < len(src) && src[i+1] == '-':
+			// a comment runs to the end of the line, or to the next "--".
+			// Longer runs of dashes, as in a line of them, don't end it.
+			i = skipDashes(src, i)
+			for i < len(src) && src[i] != '\n' {
+				if src[i] == '-' {
+					end := skipDashes(src, i)
+					if end-i == 2 {
+						i = end
+						break
+					}
+					i = end
+					continue
+				}
+				i++
+			}
+
+		case c == '"':
+			start, startLine := i+1, line
+			i++
+			for i < len(src) && src[i] != '"' {
+				if src[i] == '\n' {
+					line++
+				}
+				i++
+			}
+			if i == len(src) {
+				return nil, fmt.Errorf("line %d: Unterminated quoted string", startLine)
+			}
+			tokens = append(tokens, token{tokString, string(src[start:i]), startLine})
+			i++
+
+		case c == '\'':
+			start := i + 1
+			i++
+			for i < len(src) && src[i] != '\'' && src[i] != '\n' {
+				i++
+			}
+			if i == len(src) || src[i] != '\'' {
+				return nil, fmt.Errorf("line %d: Unterminated binary or hex string", line)
+			}
+			text := string(src[start:i])
+			i++
+			if i < len(src) && (src[i] == 'H' || src[i] == 'h' || src[i] == 'B' || src[i] == 'b') {
+				text += "'" + string(src[i])
+				i++
+			}
+			tokens = append(tokens, token{tokBinary, text, line})
+
+		case c == ':' && i+2 < len(src) && src[i+1] == ':' && src[i+2] == '=':
+			tokens = append(tokens, token{tokPunct, "::=", line})
+			i += 3
+
+		case c == '.' && i+1 < len(src) && src[i+1] == '.':
+			tokens = append(tokens, token{tokPunct, "..", line})
+			i += 2
+
+		case c == '{' || c == '}' || c == '(' || c == ')' || c == ',' ||
+			c == ';' || c == '|' || c == '[' || c == ']':
+			tokens = append(tokens, token{tokPunct, string(c), line})
+			i++
+
+		case isDigit(c) || (c == '-' && i+1 < len(src) && isDigit(src[i+1])):
+			start := i
+			i++
+			for i < len(src) && isDigit(src[i]) {
+				i++
+			}
+			tokens = append(tokens, token{tokNumber, string(src[start:i]), line})
+
+		case isLetter(c):
+			start := i
+			for i < len(src) && (isLetter(src[i]) || isDigit(src[i]) || src[i] == '-' || src[i] == '_') {
+				// an identifier can't contain "--", which starts a comment
+				if src[i] == '-' && i+1 < len(src) && src[i+1] == '-' {
+					break
+				}
+				i++
+			}
+			tokens = append(tokens, token{tokIdent, string(src[start:i]), line})
+
+		default:
+			return nil, fmt.Errorf("line %d: Unexpected character %q", line, c)
+		}
+	}
+	tokens = append(tokens, token{tokEOF, "", line})
+	return tokens, nil
+}
+
+// skipDashes returns the index following the run of dashes at src[i]
+func skipDashes(src []byte, i int) int {
+	for i < len(src) && src[i] == '-' {
+		i++
+	}
+	return i
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
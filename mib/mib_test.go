@@ -0,0 +1,228 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package mib
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/soniah/gosnmp"
+)
+
+func loadTestdata(t *testing.T) *Tree {
+	tree := NewTree()
+	if err := tree.LoadDir("testdata"); err != nil {
+		t.Fatalf("LoadDir: %v", err)
+	}
+	if u := tree.Unresolved(); len(u) > 0 {
+		t.Fatalf("Unresolved: %v", u)
+	}
+	return tree
+}
+
+func TestLookup(t *testing.T) {
+	tree := loadTestdata(t)
+
+	tests := []struct {
+		name   string
+		oid    string
+		kind   Kind
+		module string
+	}{
+		{"enterprises", ".1.3.6.1.4.1", KindOid, "SNMPv2-SMI"},
+		{"testTcMIB", ".1.3.6.1.4.1.99999.1", KindModuleIdentity, "TEST-TC-MIB"},
+		{"testObjects", ".1.3.6.1.4.1.99999.1.2.1", KindOid, "TEST-MIB"},
+		{"TEST-MIB::testCount", ".1.3.6.1.4.1.99999.1.2.1.1", KindScalar, "TEST-MIB"},
+		{"testTable", ".1.3.6.1.4.1.99999.1.2.1.2", KindTable, "TEST-MIB"},
+		{"testEntry", ".1.3.6.1.4.1.99999.1.2.1.2.1", KindRow, "TEST-MIB"},
+		{"testStatus", ".1.3.6.1.4.1.99999.1.2.1.2.1.3", KindColumn, "TEST-MIB"},
+		{"testStatusChange", ".1.3.6.1.4.1.99999.1.2.0.1", KindNotification, "TEST-MIB"},
+		{"testGroup", ".1.3.6.1.4.1.99999.1.2.3.1", KindGroup, "TEST-MIB"},
+		{"testCompliance", ".1.3.6.1.4.1.99999.1.2.3.2", KindCompliance, "TEST-MIB"},
+		{"testV1Flag", ".1.3.6.1.4.1.99998.1", KindScalar, "TEST-V1-MIB"},
+		{"testV1Trap", ".1.3.6.1.4.1.99998.0.3", KindNotification, "TEST-V1-MIB"},
+	}
+	for _, test := range tests {
+		n := tree.Lookup(test.name)
+		if n == nil {
+			t.Errorf("%s: not found", test.name)
+			continue
+		}
+		if n.Oid.String() != test.oid || n.Kind != test.kind || n.Module != test.module {
+			t.Errorf("%s: got %s %s %s, want %s %s %s", test.name,
+				n.Oid, n.Kind, n.Module, test.oid, test.kind, test.module)
+		}
+		if found := tree.LookupOidExact(n.Oid); found != n {
+			t.Errorf("%s: LookupOidExact(%s) found %v", test.name, n.Oid, found)
+		}
+	}
+
+	for _, name := range []string{"noSuchObject", "TEST-TC-MIB::testCount", "NO-MIB::testCount"} {
+		if n := tree.Lookup(name); n != nil {
+			t.Errorf("Lookup(%s) found %s", name, n)
+		}
+	}
+}
+
+func TestLookupOid(t *testing.T) {
+	tree := loadTestdata(t)
+
+	oid, _ := gosnmp.ParseOid(".1.3.6.1.4.1.99999.1.2.1.2.1.3.7.1.97")
+	n, index := tree.LookupOid(oid)
+	if n == nil || n.Name != "testStatus" || !index.Equal(gosnmp.Oid{7, 1, 97}) {
+		t.Errorf("got %v %v, want testStatus .7.1.97", n, index)
+	}
+
+	oid, _ = gosnmp.ParseOid(".1.3.6.1.4.1.99999.3")
+	if n, index = tree.LookupOid(oid); n == nil || n.Name != "99999" || !index.Equal(gosnmp.Oid{3}) {
+		t.Errorf("got %v %v, want 99999 .3", n, index)
+	}
+
+	if n, _ = tree.LookupOid(gosnmp.Oid{5}); n != nil {
+		t.Errorf("got %v for .5", n)
+	}
+}
+
+func TestObjectDetails(t *testing.T) {
+	tree := loadTestdata(t)
+
+	entry := tree.Lookup("testEntry")
+	if !reflect.DeepEqual(entry.Index, []string{"testIndex", "testName"}) || !entry.Implied {
+		t.Errorf("testEntry index: got %v implied %v", entry.Index, entry.Implied)
+	}
+	var names []string
+	for _, c := range entry.Children() {
+		names = append(names, c.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"testIndex", "testName", "testStatus"}) {
+		t.Errorf("testEntry children: got %v", names)
+	}
+
+	count := tree.Lookup("testCount")
+	if count.Syntax.Type != "Counter64" || count.Units != "packets" || count.Access != "read-only" ||
+		count.Status != "current" || count.Description != "A scalar." {
+		t.Errorf("testCount: got %+v", count)
+	}
+
+	if table := tree.Lookup("testTable"); table.Syntax.Type != "SEQUENCE OF TestEntry" {
+		t.Errorf("testTable syntax: got %q", table.Syntax.Type)
+	}
+	if index := tree.Lookup("testIndex"); !reflect.DeepEqual(index.Syntax.Ranges, []Range{{1, 2147483647}}) {
+		t.Errorf("testIndex ranges: got %v", index.Syntax.Ranges)
+	}
+
+	notification := tree.Lookup("testStatusChange")
+	if !reflect.DeepEqual(notification.Objects, []string{"testStatus", "testName"}) {
+		t.Errorf("testStatusChange objects: got %v", notification.Objects)
+	}
+	trap := tree.Lookup("testV1Trap")
+	if !reflect.DeepEqual(trap.Objects, []string{"testV1Flag"}) {
+		t.Errorf("testV1Trap variables: got %v", trap.Objects)
+	}
+
+	flag := tree.Lookup("testV1Flag")
+	if !reflect.DeepEqual(flag.Syntax.Enums, []NamedNumber{{"on", 1}, {"off", 2}}) || flag.Access != "read-write" {
+		t.Errorf("testV1Flag: got %+v", flag)
+	}
+
+	if m := tree.Module("TEST-MIB"); m == nil || m.Imports["TestStatus"] != "TEST-TC-MIB" || m.Imports["Counter64"] != "SNMPv2-SMI" {
+		t.Errorf("TEST-MIB imports: got %v", m)
+	}
+}
+
+func TestTextualConventions(t *testing.T) {
+	tree := loadTestdata(t)
+
+	tc := tree.TextualConvention("TEST-TC-MIB::TestName")
+	if tc == nil || tc.DisplayHint != "255a" || tc.Syntax.Type != "OCTET STRING" ||
+		!reflect.DeepEqual(tc.Syntax.Ranges, []Range{{0, 255}}) {
+		t.Errorf("TestName: got %+v", tc)
+	}
+	if tc = tree.TextualConvention("TestStatus"); tc == nil || !strings.HasPrefix(tc.Description, "An enumerated status") {
+		t.Errorf("TestStatus: got %+v", tc)
+	}
+	if tc = tree.TextualConvention("TestPercent"); tc == nil || tc.Syntax.Type != "Integer32" {
+		t.Errorf("TestPercent: got %+v", tc)
+	}
+	if tc = tree.TextualConvention("TestUnused"); tc == nil || tc.Syntax.Type != "OCTET STRING" {
+		t.Errorf("TestUnused, following a comment on the same line: got %+v", tc)
+	}
+	if tc = tree.TextualConvention("TestCapabilities"); tc == nil ||
+		!reflect.DeepEqual(tc.Syntax.BitNames(), map[string]int{"other": 0, "bridge": 2, "router": 4}) {
+		t.Errorf("TestCapabilities: got %+v", tc)
+	}
+	if names := tree.TextualConvention("TestStatus").Syntax.BitNames(); names != nil {
+		t.Errorf("BitNames of an INTEGER: got %v", names)
+	}
+	if tc = tree.TextualConvention("TestEntry"); tc != nil {
+		t.Errorf("TestEntry is a row type, got %+v", tc)
+	}
+
+	status := tree.Lookup("testStatus")
+	want := Syntax{Type: "INTEGER", Enums: []NamedNumber{{"up", 1}, {"down", 2}, {"testing", 3}}}
+	if got := tree.BaseSyntax(*status.Syntax); !reflect.DeepEqual(got, want) {
+		t.Errorf("BaseSyntax(testStatus): got %+v, want %+v", got, want)
+	}
+}
+
+func TestLoadOrder(t *testing.T) {
+	tree := NewTree()
+	if err := tree.LoadFile("testdata/TEST-MIB.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if u := tree.Unresolved(); len(u) == 0 || u[0] != "TEST-MIB::testCompliance" {
+		t.Errorf("Unresolved before loading TEST-TC-MIB: got %v", u)
+	}
+	if n := tree.Lookup("testCount"); n != nil {
+		t.Errorf("testCount resolved before its parent: %v", n.Oid)
+	}
+
+	if err := tree.LoadFile("testdata/TEST-TC-MIB.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if u := tree.Unresolved(); len(u) > 0 {
+		t.Errorf("Unresolved after loading TEST-TC-MIB: got %v", u)
+	}
+	if n := tree.Lookup("testStatus"); n == nil || n.Kind != KindColumn {
+		t.Errorf("testStatus: got %v", n)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		src string
+		err string
+	}{
+		{`A-MIB DEFINITIONS ::= BEGIN`, "Missing END"},
+		{`A-MIB DEFINITIONS ::= BEGIN a OBJECT IDENTIFIER ::= { } END`, "Empty OBJECT IDENTIFIER"},
+		{`A-MIB DEFINITIONS ::= BEGIN a OBJECT-TYPE STATUS current ::= { b 1 } END`, "has no SYNTAX"},
+		{`A-MIB DEFINITIONS ::= BEGIN a OBJECT IDENTIFIER ::= { b 99999999999 } END`, "Invalid sub-identifier"},
+		{"A-MIB DEFINITIONS ::= BEGIN a OBJECT-TYPE DESCRIPTION \"unterminated", "Unterminated quoted string"},
+		{`A-MIB DEFINITIONS ::= BEGIN a TRAP-TYPE ::= 1 END`, "has no ENTERPRISE"},
+		{`A-MIB DEFINITIONS ::= BEGIN @ END`, "Unexpected character"},
+		{`A-MIB ::= BEGIN END`, `Expected "DEFINITIONS"`},
+	}
+	for _, test := range tests {
+		err := NewTree().Load(strings.NewReader(test.src))
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: got error %v, want %q", test.src, err, test.err)
+		}
+	}
+}
+
+func TestWalk(t *testing.T) {
+	tree := loadTestdata(t)
+
+	var names []string
+	tree.Walk(tree.Lookup("testObjects"), func(n *Node) bool {
+		names = append(names, n.Name)
+		return n.Kind != KindRow
+	})
+	want := []string{"testObjects", "testCount", "testTable", "testEntry"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("got %v, want %v", names, want)
+	}
+}
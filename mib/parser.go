@@ -0,0 +1,643 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package mib
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parser reads the modules in a stream of tokens. It understands as much of
+// ASN.1 as MIB modules use; anything else (ranges on types, DEFVALs, the
+// details of compliance statements) is skipped.
+type parser struct {
+	tree   *Tree
+	tokens []token
+	pos    int
+	module *Module
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) peekAt(n int) token {
+	if p.pos+n >= len(p.tokens) {
+		return p.tokens[len(p.tokens)-1]
+	}
+	return p.tokens[p.pos+n]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) errorf(t token, format string, a ...interface{}) error {
+	return fmt.Errorf("line %d: %s", t.line, fmt.Sprintf(format, a...))
+}
+
+// expect consumes the next token, which must be text
+func (p *parser) expect(text string) error {
+	t := p.next()
+	if t.text != text || t.kind == tokString {
+		return p.errorf(t, "Expected %q, got %s", text, t)
+	}
+	return nil
+}
+
+// accept consumes the next token if it is text
+func (p *parser) accept(text string) bool {
+	if t := p.peek(); t.text == text && t.kind != tokString {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) ident() (string, error) {
+	t := p.next()
+	if t.kind != tokIdent {
+		return "", p.errorf(t, "Expected an identifier, got %s", t)
+	}
+	return t.text, nil
+}
+
+func (p *parser) quoted() (string, error) {
+	t := p.next()
+	if t.kind != tokString {
+		return "", p.errorf(t, "Expected a quoted string, got %s", t)
+	}
+	return t.text, nil
+}
+
+// skipBalanced skips a bracketed group starting at the next token, which
+// must be one of { ( [
+func (p *parser) skipBalanced() error {
+	start := p.next()
+	depth := 1
+	for depth > 0 {
+		t := p.next()
+		switch {
+		case t.kind == tokEOF:
+			return p.errorf(start, "Unbalanced %q", start.text)
+		case t.kind != tokPunct:
+		case t.text == "{" || t.text == "(" || t.text == "[":
+			depth++
+		case t.text == "}" || t.text == ")" || t.text == "]":
+			depth--
+		}
+	}
+	return nil
+}
+
+func (p *parser) atOpen() bool {
+	t := p.peek()
+	return t.kind == tokPunct && (t.text == "{" || t.text == "(" || t.text == "[")
+}
+
+// parseModules parses every module until the end of input
+func (p *parser) parseModules() error {
+	for p.peek().kind != tokEOF {
+		if err := p.parseModule(); err != nil {
+			if p.module != nil {
+				return fmt.Errorf("%s: %w", p.module.Name, err)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// parseModule parses "NAME DEFINITIONS ::= BEGIN ... END"
+func (p *parser) parseModule() error {
+	p.module = nil
+	name, err := p.ident()
+	if err != nil {
+		return err
+	}
+	if p.peek().text == "{" {
+		// the module's own oid, as ASN.1 allows
+		if err = p.skipBalanced(); err != nil {
+			return err
+		}
+	}
+	if err = p.expect("DEFINITIONS"); err != nil {
+		return err
+	}
+	for p.peek().text != "::=" && p.peek().kind != tokEOF {
+		// eg IMPLICIT TAGS
+		p.next()
+	}
+	if err = p.expect("::="); err != nil {
+		return err
+	}
+	if err = p.expect("BEGIN"); err != nil {
+		return err
+	}
+
+	// Reloading a module replaces its definitions
+	delete(p.tree.modules, name)
+	p.module = p.tree.module(name)
+
+	for {
+		t := p.peek()
+		switch {
+		case t.kind == tokEOF:
+			return p.errorf(t, "Missing END")
+		case t.text == "END":
+			p.next()
+			return nil
+		case t.text == "IMPORTS":
+			p.next()
+			err = p.parseImports()
+		case t.text == "EXPORTS":
+			for !p.accept(";") && p.peek().kind != tokEOF {
+				p.next()
+			}
+		case t.kind == tokIdent:
+			err = p.parseAssignment()
+		default:
+			return p.errorf(t, "Unexpected %s", t)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// parseImports parses "a, b FROM MODULE c FROM OTHER ;"
+func (p *parser) parseImports() error {
+	var symbols []string
+	for {
+		t := p.next()
+		switch {
+		case t.kind == tokEOF:
+			return p.errorf(t, "Unterminated IMPORTS")
+		case t.text == ";":
+			return nil
+		case t.text == ",":
+		case t.text == "FROM":
+			from, err := p.ident()
+			if err != nil {
+				return err
+			}
+			for _, s := range symbols {
+				p.module.Imports[s] = from
+			}
+			symbols = symbols[:0]
+		case t.kind == tokIdent:
+			symbols = append(symbols, t.text)
+		default:
+			return p.errorf(t, "Unexpected %s in IMPORTS", t)
+		}
+	}
+}
+
+// macros that define a value with an oid
+var oidMacros = map[string]Kind{
+	"MODULE-IDENTITY":    KindModuleIdentity,
+	"OBJECT-IDENTITY":    KindObjectIdentity,
+	"OBJECT-TYPE":        KindScalar,
+	"NOTIFICATION-TYPE":  KindNotification,
+	"TRAP-TYPE":          KindNotification,
+	"OBJECT-GROUP":       KindGroup,
+	"NOTIFICATION-GROUP": KindGroup,
+	"MODULE-COMPLIANCE":  KindCompliance,
+	"AGENT-CAPABILITIES": KindCapabilities,
+}
+
+// parseAssignment parses a definition starting with its name
+func (p *parser) parseAssignment() error {
+	nameTok := p.next()
+	name := nameTok.text
+	t := p.peek()
+
+	switch {
+	case t.text == "MACRO":
+		// macro definitions (in SNMPv2-SMI etc) aren't needed, the parser
+		// knows the standard macros
+		for p.peek().kind != tokEOF && p.next().text != "END" {
+		}
+		return nil
+
+	case t.text == "OBJECT" && p.peekAt(1).text == "IDENTIFIER":
+		p.pos += 2
+		if err := p.expect("::="); err != nil {
+			return err
+		}
+		oid, err := p.parseOidValue()
+		if err != nil {
+			return err
+		}
+		p.define(&Node{Name: name, Module: p.module.Name, Kind: KindOid}, oid, false)
+		return nil
+
+	case t.text == "::=":
+		p.next()
+		return p.parseTypeAssignment(name)
+	}
+
+	if kind, ok := oidMacros[t.text]; ok {
+		p.next()
+		return p.parseMacro(name, t.text, kind)
+	}
+
+	// another sort of value assignment, eg "maxFoo INTEGER ::= 100"
+	for p.peek().text != "::=" {
+		if p.peek().kind == tokEOF {
+			return p.errorf(nameTok, "Unable to parse the definition of %s", name)
+		}
+		if p.atOpen() {
+			if err := p.skipBalanced(); err != nil {
+				return err
+			}
+			continue
+		}
+		p.next()
+	}
+	p.next()
+	if p.atOpen() {
+		return p.skipBalanced()
+	}
+	p.next()
+	return nil
+}
+
+// parseTypeAssignment parses the type following "Name ::="
+func (p *parser) parseTypeAssignment(name string) error {
+	tc := &TextualConvention{Name: name, Module: p.module.Name}
+	if p.accept("TEXTUAL-CONVENTION") {
+		for !p.accept("SYNTAX") {
+			t := p.next()
+			var err error
+			switch t.text {
+			case "DISPLAY-HINT":
+				tc.DisplayHint, err = p.quoted()
+			case "STATUS":
+				tc.Status, err = p.ident()
+			case "DESCRIPTION":
+				tc.Description, err = p.quoted()
+			case "REFERENCE":
+				_, err = p.quoted()
+			default:
+				err = p.errorf(t, "Unexpected %s in TEXTUAL-CONVENTION %s", t, name)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	syntax, err := p.parseSyntax()
+	if err != nil {
+		return err
+	}
+	if syntax.Type == "SEQUENCE" || syntax.Type == "CHOICE" {
+		// the type of a row, or something like ObjectSyntax in SNMPv2-SMI
+		return nil
+	}
+	tc.Syntax = syntax
+	p.tree.addTextualConvention(p.module, tc)
+	return nil
+}
+
+// parseSyntax parses a type: the type itself, then any named numbers and
+// constraints
+func (p *parser) parseSyntax() (Syntax, error) {
+	var s Syntax
+	if p.peek().text == "[" {
+		// eg [APPLICATION 1] IMPLICIT INTEGER
+		if err := p.skipBalanced(); err != nil {
+			return s, err
+		}
+	}
+	p.accept("IMPLICIT")
+
+	t := p.next()
+	if t.kind != tokIdent {
+		return s, p.errorf(t, "Expected a type, got %s", t)
+	}
+	switch {
+	case t.text == "SEQUENCE" && p.accept("OF"):
+		row, err := p.ident()
+		if err != nil {
+			return s, err
+		}
+		s.Type = "SEQUENCE OF " + row
+		return s, nil
+	case t.text == "SEQUENCE" || t.text == "CHOICE":
+		s.Type = t.text
+		if p.peek().text == "{" {
+			return s, p.skipBalanced()
+		}
+		return s, nil
+	case t.text == "OCTET" || t.text == "BIT":
+		if err := p.expect("STRING"); err != nil {
+			return s, err
+		}
+		s.Type = t.text + " STRING"
+	case t.text == "OBJECT":
+		if err := p.expect("IDENTIFIER"); err != nil {
+			return s, err
+		}
+		s.Type = "OBJECT IDENTIFIER"
+	default:
+		s.Type = t.text
+	}
+
+	if p.peek().text == "{" {
+		enums, err := p.parseNamedNumbers()
+		if err != nil {
+			return s, err
+		}
+		s.Enums = enums
+	}
+	if p.peek().text == "(" {
+		ranges, err := p.parseConstraint()
+		if err != nil {
+			return s, err
+		}
+		s.Ranges = ranges
+	}
+	return s, nil
+}
+
+// parseNamedNumbers parses "{ up(1), down(2) }"
+func (p *parser) parseNamedNumbers() ([]NamedNumber, error) {
+	p.next()
+	var enums []NamedNumber
+	for {
+		name, err := p.ident()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expect("("); err != nil {
+			return nil, err
+		}
+		t := p.next()
+		v, err := strconv.ParseInt(t.text, 10, 64)
+		if t.kind != tokNumber || err != nil {
+			return nil, p.errorf(t, "Expected a number for %s, got %s", name, t)
+		}
+		if err = p.expect(")"); err != nil {
+			return nil, err
+		}
+		enums = append(enums, NamedNumber{name, v})
+		if p.accept("}") {
+			return enums, nil
+		}
+		if err = p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// parseConstraint parses "(0..255 | 1000)" or "(SIZE (0..255))". Bounds
+// that aren't numbers (MIN, MAX, hex strings) are left out.
+func (p *parser) parseConstraint() ([]Range, error) {
+	start := p.next()
+	size := p.accept("SIZE")
+	if size {
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+	}
+	var ranges []Range
+	for {
+		min, ok := p.rangeValue()
+		max := min
+		if p.accept("..") {
+			var maxOK bool
+			max, maxOK = p.rangeValue()
+			ok = ok && maxOK
+		}
+		if ok {
+			ranges = append(ranges, Range{min, max})
+		}
+		t := p.next()
+		if t.text == ")" {
+			break
+		}
+		if t.text != "|" {
+			return nil, p.errorf(start, "Unable to parse constraint")
+		}
+	}
+	if size {
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+	}
+	return ranges, nil
+}
+
+func (p *parser) rangeValue() (int64, bool) {
+	t := p.next()
+	switch t.kind {
+	case tokNumber:
+		v, err := strconv.ParseInt(t.text, 10, 64)
+		return v, err == nil
+	case tokBinary:
+		if i := strings.IndexByte(t.text, '\''); i >= 0 {
+			base := 16
+			if t.text[i+1] == 'B' || t.text[i+1] == 'b' {
+				base = 2
+			}
+			v, err := strconv.ParseInt(t.text[:i], base, 64)
+			return v, err == nil
+		}
+	}
+	return 0, false
+}
+
+// parseOidValue parses "{ parent 1 }", "{ iso org(3) dod(6) }" etc
+func (p *parser) parseOidValue() ([]oidComponent, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var oid []oidComponent
+	for !p.accept("}") {
+		t := p.next()
+		var c oidComponent
+		switch t.kind {
+		case tokNumber:
+			n, err := strconv.ParseUint(t.text, 10, 32)
+			if err != nil {
+				return nil, p.errorf(t, "Invalid sub-identifier %s", t.text)
+			}
+			c.number, c.hasNumber = uint32(n), true
+		case tokIdent:
+			c.name = t.text
+			if p.accept("(") {
+				nt := p.next()
+				n, err := strconv.ParseUint(nt.text, 10, 32)
+				if nt.kind != tokNumber || err != nil {
+					return nil, p.errorf(nt, "Invalid sub-identifier %s", nt)
+				}
+				c.number, c.hasNumber = uint32(n), true
+				if err = p.expect(")"); err != nil {
+					return nil, err
+				}
+			}
+		default:
+			return nil, p.errorf(t, "Unexpected %s in OBJECT IDENTIFIER value", t)
+		}
+		oid = append(oid, c)
+	}
+	if len(oid) == 0 {
+		return nil, fmt.Errorf("Empty OBJECT IDENTIFIER value")
+	}
+	return oid, nil
+}
+
+// parseMacro parses the clauses of a macro such as OBJECT-TYPE up to its
+// value
+func (p *parser) parseMacro(name, macro string, kind Kind) error {
+	n := &Node{Name: name, Module: p.module.Name, Kind: kind}
+	var enterprise string
+
+	for !p.accept("::=") {
+		t := p.next()
+		var err error
+		switch t.text {
+		case "SYNTAX":
+			var s Syntax
+			s, err = p.parseSyntax()
+			if macro == "OBJECT-TYPE" {
+				n.Syntax = &s
+			}
+		case "MAX-ACCESS", "ACCESS":
+			n.Access, err = p.ident()
+		case "STATUS":
+			n.Status, err = p.ident()
+		case "DESCRIPTION":
+			n.Description, err = p.quoted()
+		case "UNITS":
+			n.Units, err = p.quoted()
+		case "REFERENCE", "DISPLAY-HINT", "LAST-UPDATED", "ORGANIZATION", "CONTACT-INFO":
+			_, err = p.quoted()
+		case "REVISION":
+			if _, err = p.quoted(); err == nil {
+				if err = p.expect("DESCRIPTION"); err == nil {
+					_, err = p.quoted()
+				}
+			}
+		case "INDEX":
+			n.Index, n.Implied, err = p.parseIndex()
+		case "AUGMENTS":
+			var augments []string
+			if augments, err = p.parseNameList(); err == nil && len(augments) > 0 {
+				n.Augments = augments[0]
+			}
+		case "OBJECTS", "VARIABLES", "NOTIFICATIONS":
+			if macro != "MODULE-COMPLIANCE" && macro != "AGENT-CAPABILITIES" {
+				n.Objects, err = p.parseNameList()
+			} else if p.peek().text == "{" {
+				err = p.skipBalanced()
+			}
+		case "ENTERPRISE":
+			if p.peek().text == "{" {
+				err = p.skipBalanced()
+			} else {
+				enterprise, err = p.ident()
+			}
+		default:
+			switch {
+			case t.kind == tokEOF:
+				err = p.errorf(t, "Unterminated %s %s", macro, name)
+			case t.kind == tokPunct && (t.text == "{" || t.text == "(" || t.text == "["):
+				// eg DEFVAL { ... }, or the contents of compliance statements
+				p.pos--
+				err = p.skipBalanced()
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if macro == "OBJECT-TYPE" && n.Syntax == nil {
+		return p.errorf(p.peek(), "OBJECT-TYPE %s has no SYNTAX", name)
+	}
+
+	var oid []oidComponent
+	if macro == "TRAP-TYPE" {
+		// SMIv1 traps are numbered within their enterprise, and as SNMPv2
+		// notifications are enterprise.0.n (RFC 3584)
+		t := p.next()
+		number, err := strconv.ParseUint(t.text, 10, 32)
+		if t.kind != tokNumber || err != nil {
+			return p.errorf(t, "Expected a trap number, got %s", t)
+		}
+		if enterprise == "" {
+			return p.errorf(t, "TRAP-TYPE %s has no ENTERPRISE", name)
+		}
+		oid = []oidComponent{
+			{name: enterprise},
+			{number: 0, hasNumber: true},
+			{number: uint32(number), hasNumber: true},
+		}
+	} else {
+		var err error
+		if oid, err = p.parseOidValue(); err != nil {
+			return err
+		}
+	}
+	p.define(n, oid, macro == "OBJECT-TYPE")
+	return nil
+}
+
+// parseIndex parses "{ [IMPLIED] a, b }"
+func (p *parser) parseIndex() (index []string, implied bool, err error) {
+	if err = p.expect("{"); err != nil {
+		return nil, false, err
+	}
+	for !p.accept("}") {
+		if p.accept(",") {
+			continue
+		}
+		if p.accept("IMPLIED") {
+			implied = true
+		}
+		var name string
+		if name, err = p.ident(); err != nil {
+			return nil, false, err
+		}
+		index = append(index, name)
+	}
+	return index, implied, nil
+}
+
+// parseNameList parses "{ a, b, c }"
+func (p *parser) parseNameList() ([]string, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var names []string
+	for !p.accept("}") {
+		if p.accept(",") {
+			continue
+		}
+		name, err := p.ident()
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// define queues a definition to be placed in the tree
+func (p *parser) define(n *Node, oid []oidComponent, isObjType bool) {
+	p.tree.pending = append(p.tree.pending, &definition{
+		node:      n,
+		module:    p.module,
+		oid:       oid,
+		isObjType: isObjType,
+	})
+}
@@ -0,0 +1,374 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package mib
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/soniah/gosnmp"
+)
+
+// Translate converts an object name, as accepted by the net-snmp tools, to
+// its numeric oid. The name may be qualified with its module and followed
+// by an instance, eg "sysDescr.0", "IF-MIB::ifHCInOctets.3" or
+// `ifName."eth0"`. An instance is a dotted list of numbers and quoted
+// strings: numbers are used as they are, and strings are encoded as the
+// row's INDEX requires (with a leading length unless they're IMPLIED or of
+// fixed size). Once a string index object is given as numbers, any
+// strings following it get a leading length. Oids that are already
+// numeric are returned as they are.
+func (t *Tree) Translate(name string) (gosnmp.Oid, error) {
+	if name == "" {
+		return nil, fmt.Errorf("Unable to translate empty name")
+	}
+	if name[0] == '.' || isDigit(name[0]) {
+		return gosnmp.ParseOid(name)
+	}
+
+	symbol, instance := name, ""
+	if i := strings.IndexByte(name, '.'); i >= 0 {
+		symbol, instance = name[:i], name[i:]
+	}
+	n := t.Lookup(symbol)
+	if n == nil {
+		return nil, fmt.Errorf("Unknown object %s", symbol)
+	}
+	parts, err := splitInstance(instance)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to translate %s: %w", name, err)
+	}
+
+	oid := append(gosnmp.Oid{}, n.Oid...)
+	index, implied := t.indexObjects(n)
+	numbers := 0 // numbers given so far for the current index object
+	for _, part := range parts {
+		if len(index) == 0 {
+			// past the index, or no longer sure which object a part is for
+			if part.quoted {
+				oid = appendString(oid, part.text, false)
+			} else {
+				oid = append(oid, part.number)
+			}
+			continue
+		}
+
+		obj := index[0]
+		if part.quoted {
+			last := len(index) == 1
+			oid = appendString(oid, part.text, (last && implied) || t.fixedSize(obj) == len(part.text))
+			index = index[1:]
+			continue
+		}
+		oid = append(oid, part.number)
+		numbers++
+		switch BaseType(t.BaseSyntax(*obj.Syntax).Type) {
+		case "INTEGER", "Unsigned32", "Counter32", "Gauge32", "TimeTicks", "Counter64":
+			index, numbers = index[1:], 0
+		case "IpAddress":
+			if numbers == 4 {
+				index, numbers = index[1:], 0
+			}
+		default:
+			// a string or oid given as numbers
+			index = nil
+		}
+	}
+	return oid, nil
+}
+
+// TranslateOid converts an oid to its name, qualified with its module and
+// followed by any instance, eg "IF-MIB::ifHCInOctets.3". The instance of a
+// column is decoded using its row's INDEX, showing printable strings
+// quoted; instances that can't be decoded are shown as numbers. An oid
+// that isn't in the tree is returned in numeric form.
+func (t *Tree) TranslateOid(oid gosnmp.Oid) string {
+	n, suffix := t.LookupOid(oid)
+	for n != nil && n != t.root && n.Module == "" {
+		// a node only named by its number, eg in { enterprises 9 1 }
+		n = n.Parent
+		suffix = oid[len(n.Oid):]
+	}
+	if n == nil || n == t.root {
+		return oid.String()
+	}
+	name := n.String()
+	if len(suffix) == 0 {
+		return name
+	}
+	if parts, ok := t.decodeIndex(n, suffix); ok {
+		return name + "." + strings.Join(parts, ".")
+	}
+	return name + suffix.String()
+}
+
+// Unmarshal is gosnmp.Unmarshal, with tags that can be object names as
+// accepted by Translate, eg `snmp:"IF-MIB::ifDescr"` for a column or
+// `snmp:"sysUpTime.0"` for a scalar
+func (t *Tree) Unmarshal(target interface{}, pdus []gosnmp.SnmpPDU) error {
+	return gosnmp.UnmarshalWithResolver(target, pdus, func(tag string) (string, error) {
+		oid, err := t.Translate(tag)
+		if err != nil {
+			return "", err
+		}
+		return oid.String(), nil
+	})
+}
+
+// UnmarshalNotification is gosnmp.UnmarshalNotification, with tags that
+// can be object names as for Unmarshal, and with the notification's MIB
+// definition: untagged fields are set from the notification's OBJECTS (or
+// SMIv1 VARIABLES) whose names they have, ignoring case, eg a field
+// TestStatus from the varbind of testStatus
+func (t *Tree) UnmarshalNotification(target interface{}, packet *gosnmp.SnmpPacket) error {
+	var objects []string
+	if oid, err := gosnmp.ParseOid(packet.TrapOID()); err == nil {
+		if n := t.LookupOidExact(oid); n != nil && n.Kind == KindNotification {
+			objects = n.Objects
+		}
+	}
+	return gosnmp.UnmarshalNotificationWithResolver(target, packet, func(tag string) (string, error) {
+		for _, object := range objects {
+			if strings.EqualFold(object, tag) {
+				tag = object
+				break
+			}
+		}
+		oid, err := t.Translate(tag)
+		if err != nil {
+			return "", err
+		}
+		return oid.String(), nil
+	})
+}
+
+// instancePart is a number or a quoted string in an instance
+type instancePart struct {
+	number uint32
+	text   string
+	quoted bool
+}
+
+// splitInstance splits an instance such as `.3."eth0"` into its parts
+func splitInstance(instance string) ([]instancePart, error) {
+	var parts []instancePart
+	for instance != "" {
+		if instance[0] != '.' {
+			return nil, fmt.Errorf("Expected '.' before %s", instance)
+		}
+		instance = instance[1:]
+		if instance != "" && instance[0] == '"' {
+			end := strings.IndexByte(instance[1:], '"')
+			if end < 0 {
+				return nil, fmt.Errorf("Unterminated quoted string")
+			}
+			parts = append(parts, instancePart{text: instance[1 : end+1], quoted: true})
+			instance = instance[end+2:]
+			continue
+		}
+		end := strings.IndexByte(instance, '.')
+		if end < 0 {
+			end = len(instance)
+		}
+		n, err := strconv.ParseUint(instance[:end], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid sub-identifier %q", instance[:end])
+		}
+		parts = append(parts, instancePart{number: uint32(n)})
+		instance = instance[end:]
+	}
+	return parts, nil
+}
+
+// appendString appends the oid encoding of a string index
+func appendString(oid gosnmp.Oid, s string, implied bool) gosnmp.Oid {
+	if !implied {
+		oid = append(oid, uint32(len(s)))
+	}
+	for i := 0; i < len(s); i++ {
+		oid = append(oid, uint32(s[i]))
+	}
+	return oid
+}
+
+// indexObjects returns the objects indexing the row of column n, following
+// AUGMENTS, and whether the last of them is IMPLIED. It returns nil if n
+// isn't a column, or the objects aren't all known.
+func (t *Tree) indexObjects(n *Node) ([]*Node, bool) {
+	if n.Kind != KindColumn {
+		return nil, false
+	}
+	return t.rowIndexObjects(n.Parent)
+}
+
+// rowIndexObjects is indexObjects for a row
+func (t *Tree) rowIndexObjects(row *Node) ([]*Node, bool) {
+	if row.Augments != "" {
+		if row = t.lookupFrom(t.modules[row.Module], row.Augments); row == nil {
+			return nil, false
+		}
+	}
+	objects := make([]*Node, len(row.Index))
+	for i, name := range row.Index {
+		objects[i] = t.lookupFrom(t.modules[row.Module], name)
+		if objects[i] == nil || objects[i].Syntax == nil {
+			return nil, false
+		}
+	}
+	return objects, row.Implied
+}
+
+// fixedSize returns the size of a fixed size string object, or -1
+func (t *Tree) fixedSize(n *Node) int {
+	s := t.BaseSyntax(*n.Syntax)
+	if len(s.Ranges) == 1 && s.Ranges[0].Min == s.Ranges[0].Max {
+		return int(s.Ranges[0].Min)
+	}
+	return -1
+}
+
+// IndexParts describes the INDEX of a row, or of the row of a column, for
+// gosnmp.DecodeIndex and gosnmp.EncodeIndex
+func (t *Tree) IndexParts(n *Node) ([]gosnmp.IndexPart, error) {
+	var objects []*Node
+	var implied bool
+	switch n.Kind {
+	case KindColumn:
+		objects, implied = t.indexObjects(n)
+	case KindRow:
+		objects, implied = t.rowIndexObjects(n)
+	default:
+		return nil, fmt.Errorf("%s is not a row or column", n)
+	}
+	if objects == nil {
+		return nil, fmt.Errorf("Unable to resolve the index of %s", n)
+	}
+
+	parts := make([]gosnmp.IndexPart, len(objects))
+	for i, obj := range objects {
+		switch base := BaseType(t.BaseSyntax(*obj.Syntax).Type); base {
+		case "INTEGER", "Unsigned32", "Counter32", "Gauge32", "TimeTicks", "Counter64":
+			parts[i].Kind = gosnmp.IndexInteger
+		case "IpAddress":
+			parts[i].Kind = gosnmp.IndexIPAddress
+		case "OBJECT IDENTIFIER":
+			parts[i].Kind = gosnmp.IndexOid
+		case "OCTET STRING", "Opaque":
+			parts[i].Kind = gosnmp.IndexString
+			if size := t.fixedSize(obj); size >= 0 {
+				parts[i] = gosnmp.IndexPart{Kind: gosnmp.IndexFixedString, Size: size}
+			}
+		default:
+			return nil, fmt.Errorf("%s: unsupported index type %s", obj, base)
+		}
+	}
+	if last := len(parts) - 1; implied && last >= 0 {
+		parts[last].Implied = true
+	}
+	return parts, nil
+}
+
+// decodeIndex decodes the instance of column n, returning the values of its
+// index objects formatted for TranslateOid
+func (t *Tree) decodeIndex(n *Node, suffix gosnmp.Oid) ([]string, bool) {
+	index, implied := t.indexObjects(n)
+	if index == nil {
+		return nil, false
+	}
+
+	var parts []string
+	for i, obj := range index {
+		var raw, value gosnmp.Oid
+		base := BaseType(t.BaseSyntax(*obj.Syntax).Type)
+		switch base {
+		case "INTEGER", "Unsigned32", "Counter32", "Gauge32", "TimeTicks", "Counter64":
+			raw = takeOid(suffix, 1)
+		case "IpAddress":
+			raw = takeOid(suffix, 4)
+		case "OCTET STRING", "Opaque", "OBJECT IDENTIFIER":
+			size := -1
+			if base != "OBJECT IDENTIFIER" {
+				size = t.fixedSize(obj)
+			}
+			switch {
+			case size >= 0:
+				raw = takeOid(suffix, size)
+				value = raw
+			case i == len(index)-1 && implied:
+				raw, value = suffix, suffix
+			case len(suffix) > 0:
+				if raw = takeOid(suffix, 1+int(suffix[0])); raw != nil {
+					value = raw[1:]
+				}
+			}
+		default:
+			return nil, false
+		}
+		if raw == nil {
+			return nil, false
+		}
+		suffix = suffix[len(raw):]
+
+		if value != nil && base != "OBJECT IDENTIFIER" {
+			if s, ok := quoteIndex(value); ok {
+				parts = append(parts, s)
+				continue
+			}
+		}
+		for _, v := range raw {
+			parts = append(parts, strconv.FormatUint(uint64(v), 10))
+		}
+	}
+	if len(suffix) > 0 {
+		return nil, false
+	}
+	return parts, true
+}
+
+// takeOid returns the first n parts of oid, or nil if it is too short
+func takeOid(oid gosnmp.Oid, n int) gosnmp.Oid {
+	if n > len(oid) {
+		return nil
+	}
+	return oid[:n:n]
+}
+
+// quoteIndex returns a string index quoted, if it is printable
+func quoteIndex(value gosnmp.Oid) (string, bool) {
+	if len(value) == 0 {
+		return "", false
+	}
+	b := make([]byte, len(value))
+	for i, v := range value {
+		if v < 0x20 || v > 0x7e || v == '"' {
+			return "", false
+		}
+		b[i] = byte(v)
+	}
+	return `"` + string(b) + `"`, true
+}
+
+// BaseType returns the ASN.1 or SMI type that a base syntax type (see
+// Tree.BaseSyntax) holds its values as: "INTEGER" for the integer types
+// other than the unsigned SMI ones, "OCTET STRING", "OBJECT IDENTIFIER",
+// "BITS", and the SMI application types "IpAddress", "Counter32",
+// "Gauge32", "TimeTicks", "Counter64", "Unsigned32" and "Opaque". SMIv1
+// names (Counter, Gauge, NetworkAddress) are mapped to their SMIv2 types.
+func BaseType(typ string) string {
+	switch typ {
+	case "Integer32":
+		return "INTEGER"
+	case "Counter":
+		return "Counter32"
+	case "Gauge":
+		return "Gauge32"
+	case "UInteger32":
+		return "Unsigned32"
+	case "NetworkAddress":
+		return "IpAddress"
+	}
+	return typ
+}
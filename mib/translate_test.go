@@ -0,0 +1,182 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package mib
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/soniah/gosnmp"
+)
+
+func TestTranslate(t *testing.T) {
+	tree := loadTestdata(t)
+
+	tests := []struct {
+		name string
+		oid  string
+		back string // TranslateOid of oid, if not name
+	}{
+		{"testCount.0", ".1.3.6.1.4.1.99999.1.2.1.1.0", "TEST-MIB::testCount.0"},
+		{"TEST-MIB::testCount", ".1.3.6.1.4.1.99999.1.2.1.1", ""},
+		{"enterprises.99999", ".1.3.6.1.4.1.99999", "SNMPv2-SMI::enterprises.99999"},
+		{".1.3.6.1.4.1.99999.1.2", ".1.3.6.1.4.1.99999.1.2", "TEST-MIB::testMIB"},
+		{"1.3.6.1.4.1.99997", ".1.3.6.1.4.1.99997", "SNMPv2-SMI::enterprises.99997"},
+		// an IMPLIED string index has no length
+		{`TEST-MIB::testStatus.7."ab"`, ".1.3.6.1.4.1.99999.1.2.1.2.1.3.7.97.98", ""},
+		{"TEST-MIB::testStatus.7.97.1", ".1.3.6.1.4.1.99999.1.2.1.2.1.3.7.97.1", ""},
+		// a fixed size string has no length, others do
+		{`TEST-V1-MIB::testV1Value."eth0".10.0.0.1."abcdef"`,
+			".1.3.6.1.4.1.99998.2.1.4.4.101.116.104.48.10.0.0.1.97.98.99.100.101.102", ""},
+		{`testV1Value."a.b".10.0.0.1.0.1.2.3.4.5`,
+			".1.3.6.1.4.1.99998.2.1.4.3.97.46.98.10.0.0.1.0.1.2.3.4.5",
+			`TEST-V1-MIB::testV1Value."a.b".10.0.0.1.0.1.2.3.4.5`},
+		// an index that doesn't decode is shown as numbers
+		{"TEST-V1-MIB::testV1Value.9.1", ".1.3.6.1.4.1.99998.2.1.4.9.1", ""},
+		{".2.99", ".2.99", "SNMPv2-SMI::joint-iso-ccitt.99"},
+		{".3.1", ".3.1", ".3.1"},
+	}
+	for _, test := range tests {
+		oid, err := tree.Translate(test.name)
+		if err != nil {
+			t.Errorf("Translate(%s): %v", test.name, err)
+			continue
+		}
+		if oid.String() != test.oid {
+			t.Errorf("Translate(%s): got %s, want %s", test.name, oid, test.oid)
+		}
+		back := test.back
+		if back == "" {
+			back = test.name
+		}
+		if got := tree.TranslateOid(oid); got != back {
+			t.Errorf("TranslateOid(%s): got %s, want %s", oid, got, back)
+		}
+	}
+}
+
+func TestTranslateErrors(t *testing.T) {
+	tree := loadTestdata(t)
+
+	tests := []struct {
+		name string
+		err  string
+	}{
+		{"", "empty"},
+		{"noSuchObject.0", "Unknown object noSuchObject"},
+		{"NO-MIB::testCount", "Unknown object"},
+		{"testCount.x", "Invalid sub-identifier"},
+		{`testStatus."ab`, "Unterminated quoted string"},
+		{`testStatus."ab"7`, "Expected '.'"},
+		{"testCount.", "Invalid sub-identifier"},
+		{".1.3.x", "Unable to parse OID"},
+	}
+	for _, test := range tests {
+		if _, err := tree.Translate(test.name); err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("Translate(%s): got error %v, want %q", test.name, err, test.err)
+		}
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	tree := loadTestdata(t)
+
+	type row struct {
+		Index  string `snmp:",index"`
+		Name   string `snmp:"TEST-MIB::testName"`
+		Status int    `snmp:"testStatus"`
+	}
+	var target struct {
+		Count uint64 `snmp:"testCount.0"`
+		Rows  []row
+	}
+	pdus := []gosnmp.SnmpPDU{
+		{Name: ".1.3.6.1.4.1.99999.1.2.1.1.0", Type: gosnmp.Counter64, Value: uint64(42)},
+		{Name: ".1.3.6.1.4.1.99999.1.2.1.2.1.2.7.97", Type: gosnmp.OctetString, Value: []byte("a")},
+		{Name: ".1.3.6.1.4.1.99999.1.2.1.2.1.3.7.97", Type: gosnmp.Integer, Value: 2},
+	}
+	if err := tree.Unmarshal(&target, pdus); err != nil {
+		t.Fatal(err)
+	}
+	if target.Count != 42 || !reflect.DeepEqual(target.Rows, []row{{"7.97", "a", 2}}) {
+		t.Errorf("got %+v", target)
+	}
+
+	var bad struct {
+		X int `snmp:"noSuchObject.0"`
+	}
+	if err := tree.Unmarshal(&bad, pdus); err == nil || !strings.Contains(err.Error(), "Unknown object") {
+		t.Errorf("got error %v, want unknown object", err)
+	}
+}
+
+func TestUnmarshalNotification(t *testing.T) {
+	tree := loadTestdata(t)
+	notification, err := tree.Translate("testStatusChange")
+	if err != nil {
+		t.Fatal(err)
+	}
+	packet := &gosnmp.SnmpPacket{
+		Version: gosnmp.Version2c,
+		PDUType: gosnmp.SNMPv2Trap,
+		Variables: []gosnmp.SnmpPDU{
+			{Name: ".1.3.6.1.2.1.1.3.0", Type: gosnmp.TimeTicks, Value: uint32(100)},
+			{Name: ".1.3.6.1.6.3.1.1.4.1.0", Type: gosnmp.ObjectIdentifier, Value: notification.String()},
+			{Name: ".1.3.6.1.4.1.99999.1.2.1.2.1.3.7.97", Type: gosnmp.Integer, Value: 2},
+			{Name: ".1.3.6.1.4.1.99999.1.2.1.2.1.2.7.97", Type: gosnmp.OctetString, Value: []byte("a")},
+		},
+	}
+	var target struct {
+		Trap       string `snmp:",trap"`
+		Index      string `snmp:",index"`
+		TestStatus int
+		TestName   string
+		Name       string `snmp:"TEST-MIB::testName"`
+		TestCount  uint64 // not one of the notification's objects
+	}
+	if err := tree.UnmarshalNotification(&target, packet); err != nil {
+		t.Fatal(err)
+	}
+	if target.Trap != notification.String() || target.Index != "7.97" || target.TestStatus != 2 ||
+		target.TestName != "a" || target.Name != "a" || target.TestCount != 0 {
+		t.Errorf("got %+v", target)
+	}
+}
+
+func TestIndexParts(t *testing.T) {
+	tree := loadTestdata(t)
+
+	tests := []struct {
+		name  string
+		parts []gosnmp.IndexPart
+		err   string
+	}{
+		{"testStatus", []gosnmp.IndexPart{{Kind: gosnmp.IndexInteger}, {Kind: gosnmp.IndexString, Implied: true}}, ""},
+		{"testEntry", []gosnmp.IndexPart{{Kind: gosnmp.IndexInteger}, {Kind: gosnmp.IndexString, Implied: true}}, ""},
+		{"testV1Value", []gosnmp.IndexPart{{Kind: gosnmp.IndexString}, {Kind: gosnmp.IndexIPAddress}, {Kind: gosnmp.IndexFixedString, Size: 6}}, ""},
+		{"testCount", nil, "not a row or column"},
+	}
+	for _, test := range tests {
+		parts, err := tree.IndexParts(tree.Lookup(test.name))
+		if (err != nil) != (test.err != "") || (err != nil && !strings.Contains(err.Error(), test.err)) {
+			t.Errorf("IndexParts(%s): got error %v, want %q", test.name, err, test.err)
+		}
+		if !reflect.DeepEqual(parts, test.parts) {
+			t.Errorf("IndexParts(%s): got %+v, want %+v", test.name, parts, test.parts)
+		}
+	}
+
+	// the index encoded from the parts agrees with Translate
+	parts, _ := tree.IndexParts(tree.Lookup("testV1Value"))
+	index, err := gosnmp.EncodeIndex(parts, "ab", "192.0.2.1", []byte{1, 2, 3, 4, 5, 6})
+	if err != nil {
+		t.Fatal(err)
+	}
+	oid, _ := tree.Translate(`testV1Value."ab".192.0.2.1.1.2.3.4.5.6`)
+	if column := tree.Lookup("testV1Value").Oid; !append(column[:len(column):len(column)], index...).Equal(oid) {
+		t.Errorf("got index %s, want %s", index, oid[len(column):])
+	}
+}
@@ -0,0 +1,585 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+// Package mib parses SMIv1 and SMIv2 MIB modules, and builds the tree of
+// objects they define.
+//
+// Load MIB files into a Tree, then look objects up by name or by oid:
+//
+//	t := mib.NewTree()
+//	if err := t.LoadDir("/usr/share/snmp/mibs"); err != nil {
+//		log.Print(err) // the modules that could be parsed are still loaded
+//	}
+//	node := t.Lookup("IF-MIB::ifOperStatus")
+//	fmt.Println(node.Oid, node.Syntax.Enums)
+//
+// Modules may be loaded in any order. Definitions whose parent isn't known
+// yet, because the module that defines it hasn't been loaded, are resolved
+// when it is; see Tree.Unresolved.
+package mib
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/soniah/gosnmp"
+)
+
+// Kind says what sort of definition a Node comes from
+type Kind int
+
+// The kinds of Node
+const (
+	KindOid            Kind = iota // an OBJECT IDENTIFIER value, or a node named in another's oid
+	KindModuleIdentity             // MODULE-IDENTITY
+	KindObjectIdentity             // OBJECT-IDENTITY
+	KindScalar                     // OBJECT-TYPE that isn't part of a table
+	KindTable                      // OBJECT-TYPE with a SEQUENCE OF syntax
+	KindRow                        // OBJECT-TYPE that is the entry of a table
+	KindColumn                     // OBJECT-TYPE in a row
+	KindNotification               // NOTIFICATION-TYPE, or an SMIv1 TRAP-TYPE
+	KindGroup                      // OBJECT-GROUP or NOTIFICATION-GROUP
+	KindCompliance                 // MODULE-COMPLIANCE
+	KindCapabilities               // AGENT-CAPABILITIES
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindOid:
+		return "OBJECT IDENTIFIER"
+	case KindModuleIdentity:
+		return "MODULE-IDENTITY"
+	case KindObjectIdentity:
+		return "OBJECT-IDENTITY"
+	case KindScalar:
+		return "scalar"
+	case KindTable:
+		return "table"
+	case KindRow:
+		return "row"
+	case KindColumn:
+		return "column"
+	case KindNotification:
+		return "notification"
+	case KindGroup:
+		return "group"
+	case KindCompliance:
+		return "MODULE-COMPLIANCE"
+	case KindCapabilities:
+		return "AGENT-CAPABILITIES"
+	}
+	return fmt.Sprintf("Kind(%d)", int(k))
+}
+
+// Node is an object in the oid tree
+type Node struct {
+	Name   string
+	Module string
+	Oid    gosnmp.Oid
+	Kind   Kind
+
+	// Syntax of an OBJECT-TYPE, otherwise nil
+	Syntax *Syntax
+
+	Access      string // MAX-ACCESS, or ACCESS in SMIv1
+	Status      string
+	Description string
+	Units       string
+
+	// Index lists the objects indexing a row, Implied is set when the last
+	// of them is IMPLIED. Augments names the row that a row augments,
+	// whose index it shares.
+	Index    []string
+	Implied  bool
+	Augments string
+
+	// Objects lists the OBJECTS (VARIABLES in SMIv1) of a notification,
+	// or the members of a group
+	Objects []string
+
+	Parent   *Node
+	children map[uint32]*Node
+}
+
+// Child returns the child of n with the given last sub-identifier, or nil
+func (n *Node) Child(id uint32) *Node {
+	return n.children[id]
+}
+
+// Children returns the children of n, in oid order
+func (n *Node) Children() []*Node {
+	children := make([]*Node, 0, len(n.children))
+	for _, c := range n.children {
+		children = append(children, c)
+	}
+	sort.Slice(children, func(i, j int) bool {
+		return children[i].Oid[len(children[i].Oid)-1] < children[j].Oid[len(children[j].Oid)-1]
+	})
+	return children
+}
+
+func (n *Node) String() string {
+	if n.Module == "" {
+		return n.Name
+	}
+	return n.Module + "::" + n.Name
+}
+
+// Syntax describes the type of an object, or of a textual convention
+type Syntax struct {
+	// Type is the type as written in the MIB: a base type ("INTEGER",
+	// "OCTET STRING", "OBJECT IDENTIFIER", "BITS"), an SMI type such as
+	// "Counter32", a textual convention such as "DisplayString", or
+	// "SEQUENCE OF" followed by the row type for a table.
+	Type string
+
+	// Enums holds the named numbers of an enumerated INTEGER, or the named
+	// bits of BITS
+	Enums []NamedNumber
+
+	// Ranges holds the permitted values (of numbers) or sizes (of strings)
+	// when the syntax is constrained
+	Ranges []Range
+}
+
+// BitNames returns the named bits of a BITS syntax as a map from name to
+// position, for use with gosnmp.Bits. It returns nil for other syntaxes.
+func (s Syntax) BitNames() map[string]int {
+	if s.Type != "BITS" {
+		return nil
+	}
+	names := make(map[string]int, len(s.Enums))
+	for _, e := range s.Enums {
+		names[e.Name] = int(e.Value)
+	}
+	return names
+}
+
+// NamedNumber is a label for an enumerated value, eg up(1)
+type NamedNumber struct {
+	Name  string
+	Value int64
+}
+
+// Range is an inclusive range of values
+type Range struct {
+	Min, Max int64
+}
+
+// TextualConvention is a type defined by a module, either with
+// TEXTUAL-CONVENTION or a plain type assignment
+type TextualConvention struct {
+	Name        string
+	Module      string
+	DisplayHint string
+	Status      string
+	Description string
+	Syntax      Syntax
+}
+
+// Module is a loaded MIB module
+type Module struct {
+	Name string
+
+	// Imports maps each imported symbol to the module it is imported from
+	Imports map[string]string
+
+	// Nodes lists the module's resolved definitions
+	Nodes []*Node
+
+	TextualConventions map[string]*TextualConvention
+
+	names map[string]*Node
+}
+
+// Tree holds the objects defined by the modules loaded into it
+type Tree struct {
+	root    *Node
+	modules map[string]*Module
+	names   map[string]*Node // the first definition of each name
+	tcs     map[string]*TextualConvention
+	pending []*definition
+}
+
+// definition is a parsed definition waiting to be placed in the tree
+type definition struct {
+	node      *Node
+	module    *Module
+	oid       []oidComponent
+	isObjType bool
+}
+
+// oidComponent is a part of an oid value: a name, a number or both, as in
+// { iso org(3) dod(6) 1 }
+type oidComponent struct {
+	name      string
+	number    uint32
+	hasNumber bool
+}
+
+// NewTree returns a Tree holding the well known nodes at the top of the oid
+// tree (iso, internet, mib-2, enterprises etc, as defined by SNMPv2-SMI), so
+// that modules can be loaded without SNMPv2-SMI itself.
+func NewTree() *Tree {
+	t := &Tree{
+		root:    &Node{children: make(map[uint32]*Node)},
+		modules: make(map[string]*Module),
+		names:   make(map[string]*Node),
+		tcs:     make(map[string]*TextualConvention),
+	}
+	smi := t.module("SNMPv2-SMI")
+	for _, wk := range []struct {
+		name string
+		oid  gosnmp.Oid
+	}{
+		{"ccitt", gosnmp.Oid{0}},
+		{"zeroDotZero", gosnmp.Oid{0, 0}},
+		{"iso", gosnmp.Oid{1}},
+		{"org", gosnmp.Oid{1, 3}},
+		{"dod", gosnmp.Oid{1, 3, 6}},
+		{"internet", gosnmp.Oid{1, 3, 6, 1}},
+		{"directory", gosnmp.Oid{1, 3, 6, 1, 1}},
+		{"mgmt", gosnmp.Oid{1, 3, 6, 1, 2}},
+		{"mib-2", gosnmp.Oid{1, 3, 6, 1, 2, 1}},
+		{"transmission", gosnmp.Oid{1, 3, 6, 1, 2, 1, 10}},
+		{"experimental", gosnmp.Oid{1, 3, 6, 1, 3}},
+		{"private", gosnmp.Oid{1, 3, 6, 1, 4}},
+		{"enterprises", gosnmp.Oid{1, 3, 6, 1, 4, 1}},
+		{"security", gosnmp.Oid{1, 3, 6, 1, 5}},
+		{"snmpV2", gosnmp.Oid{1, 3, 6, 1, 6}},
+		{"snmpDomains", gosnmp.Oid{1, 3, 6, 1, 6, 1}},
+		{"snmpProxys", gosnmp.Oid{1, 3, 6, 1, 6, 2}},
+		{"snmpModules", gosnmp.Oid{1, 3, 6, 1, 6, 3}},
+		{"joint-iso-ccitt", gosnmp.Oid{2}},
+	} {
+		parent := t.LookupOidExact(wk.oid[:len(wk.oid)-1])
+		if parent == nil {
+			parent = t.root
+		}
+		t.insert(parent, &Node{Name: wk.name, Module: smi.Name, Oid: wk.oid}, smi)
+	}
+	return t
+}
+
+// LoadFile loads the MIB modules in the file at path
+func (t *Tree) LoadFile(path string) error {
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err = t.load(src); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// LoadDir loads the MIB modules in every file in dir. Files that can't be
+// parsed are skipped, and reported in the error returned once the rest have
+// been loaded.
+func (t *Tree) LoadDir(dir string) error {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	var failed []string
+	for _, f := range files {
+		if f.IsDir() || strings.HasPrefix(f.Name(), ".") {
+			continue
+		}
+		if err := t.LoadFile(filepath.Join(dir, f.Name())); err != nil {
+			if _, ok := err.(*os.PathError); ok {
+				return err
+			}
+			failed = append(failed, err.Error())
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("Unable to load %d files: %s", len(failed), strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// Load loads the MIB modules read from r
+func (t *Tree) Load(r io.Reader) error {
+	src, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return t.load(src)
+}
+
+func (t *Tree) load(src []byte) error {
+	tokens, err := lex(src)
+	if err != nil {
+		return err
+	}
+	p := &parser{tree: t, tokens: tokens}
+	if err = p.parseModules(); err != nil {
+		return err
+	}
+	t.resolve()
+	return nil
+}
+
+// Module returns the loaded module called name, or nil
+func (t *Tree) Module(name string) *Module {
+	return t.modules[name]
+}
+
+// Lookup returns the node called name, which may be qualified with its
+// module as in "IF-MIB::ifIndex", or nil if there is no such node. An
+// unqualified name that several modules define finds the first loaded.
+func (t *Tree) Lookup(name string) *Node {
+	if i := strings.Index(name, "::"); i >= 0 {
+		m := t.modules[name[:i]]
+		if m == nil {
+			return nil
+		}
+		return m.names[name[i+2:]]
+	}
+	return t.names[name]
+}
+
+// LookupOid returns the node with the longest oid that is a prefix of oid,
+// and the rest of oid following it (eg the index of a column's instance).
+// It returns nil if no node is a prefix of oid.
+func (t *Tree) LookupOid(oid gosnmp.Oid) (node *Node, suffix gosnmp.Oid) {
+	n := t.root
+	i := 0
+	for ; i < len(oid); i++ {
+		child := n.children[oid[i]]
+		if child == nil {
+			break
+		}
+		n = child
+	}
+	if n == t.root {
+		return nil, oid
+	}
+	return n, oid[i:]
+}
+
+// LookupOidExact returns the node with exactly the given oid, or nil
+func (t *Tree) LookupOidExact(oid gosnmp.Oid) *Node {
+	node, suffix := t.LookupOid(oid)
+	if len(suffix) > 0 {
+		return nil
+	}
+	return node
+}
+
+// TextualConvention returns the textual convention called name, which may
+// be qualified with its module, or nil
+func (t *Tree) TextualConvention(name string) *TextualConvention {
+	if i := strings.Index(name, "::"); i >= 0 {
+		m := t.modules[name[:i]]
+		if m == nil {
+			return nil
+		}
+		return m.TextualConventions[name[i+2:]]
+	}
+	return t.tcs[name]
+}
+
+// BaseSyntax follows the textual conventions that s is defined by down to
+// its base type. Enumerations and ranges given in s itself take precedence
+// over those of the textual conventions.
+func (t *Tree) BaseSyntax(s Syntax) Syntax {
+	seen := make(map[string]bool)
+	for !seen[s.Type] {
+		seen[s.Type] = true
+		tc := t.tcs[s.Type]
+		if tc == nil {
+			break
+		}
+		enums, ranges := s.Enums, s.Ranges
+		s = tc.Syntax
+		if enums != nil {
+			s.Enums = enums
+		}
+		if ranges != nil {
+			s.Ranges = ranges
+		}
+	}
+	return s
+}
+
+// Unresolved lists the definitions, as "MODULE::name", that couldn't be
+// placed in the tree because what their oid refers to isn't known; usually
+// because a module they import from hasn't been loaded.
+func (t *Tree) Unresolved() []string {
+	var names []string
+	for _, d := range t.pending {
+		names = append(names, d.node.String())
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Walk calls fn for every node in the tree in oid order, starting at start
+// (or the whole tree, if start is nil). Returning false from fn skips the
+// node's children.
+func (t *Tree) Walk(start *Node, fn func(n *Node) bool) {
+	if start == nil {
+		for _, c := range t.root.Children() {
+			t.Walk(c, fn)
+		}
+		return
+	}
+	if !fn(start) {
+		return
+	}
+	for _, c := range start.Children() {
+		t.Walk(c, fn)
+	}
+}
+
+// module returns the module called name, creating it if necessary
+func (t *Tree) module(name string) *Module {
+	m := t.modules[name]
+	if m == nil {
+		m = &Module{
+			Name:               name,
+			Imports:            make(map[string]string),
+			TextualConventions: make(map[string]*TextualConvention),
+			names:              make(map[string]*Node),
+		}
+		t.modules[name] = m
+	}
+	return m
+}
+
+func (t *Tree) addTextualConvention(m *Module, tc *TextualConvention) {
+	m.TextualConventions[tc.Name] = tc
+	if _, ok := t.tcs[tc.Name]; !ok {
+		t.tcs[tc.Name] = tc
+	}
+}
+
+// lookupFrom finds the node called name, as seen from module m
+func (t *Tree) lookupFrom(m *Module, name string) *Node {
+	if n := m.names[name]; n != nil {
+		return n
+	}
+	if from, ok := m.Imports[name]; ok {
+		if fm := t.modules[from]; fm != nil {
+			if n := fm.names[name]; n != nil {
+				return n
+			}
+		}
+	}
+	return t.names[name]
+}
+
+// resolve places as many pending definitions in the tree as possible
+func (t *Tree) resolve() {
+	for progress := true; progress; {
+		progress = false
+		pending := t.pending[:0]
+		for _, d := range t.pending {
+			if t.place(d) {
+				progress = true
+			} else {
+				pending = append(pending, d)
+			}
+		}
+		t.pending = pending
+	}
+}
+
+// place puts d in the tree if the start of its oid is known
+func (t *Tree) place(d *definition) bool {
+	if len(d.oid) == 0 {
+		return false
+	}
+	var parent *Node
+	first := d.oid[0]
+	if first.name != "" {
+		parent = t.lookupFrom(d.module, first.name)
+		if parent == nil {
+			if !first.hasNumber || len(d.oid) == 1 {
+				return false
+			}
+			// eg { iso(1) 3 }, name the top level node
+			parent = t.intermediate(t.root, first, d.module)
+		}
+	} else {
+		parent = t.intermediate(t.root, first, d.module)
+	}
+
+	rest := d.oid[1:]
+	if len(rest) == 0 {
+		// a new name for an existing node, eg { iso }
+		return false
+	}
+	for _, c := range rest[:len(rest)-1] {
+		parent = t.intermediate(parent, c, d.module)
+	}
+	last := rest[len(rest)-1]
+	if !last.hasNumber {
+		return false
+	}
+
+	n := d.node
+	n.Oid = append(append(gosnmp.Oid{}, parent.Oid...), last.number)
+	if d.isObjType {
+		switch {
+		case strings.HasPrefix(n.Syntax.Type, "SEQUENCE OF"):
+			n.Kind = KindTable
+		case parent.Kind == KindTable:
+			n.Kind = KindRow
+		case parent.Kind == KindRow:
+			n.Kind = KindColumn
+		default:
+			n.Kind = KindScalar
+		}
+	}
+	t.insert(parent, n, d.module)
+	return true
+}
+
+// intermediate returns the child of parent identified by c, creating it if
+// it's named (as in dod(6)) but not yet defined
+func (t *Tree) intermediate(parent *Node, c oidComponent, m *Module) *Node {
+	if child := parent.children[c.number]; child != nil {
+		return child
+	}
+	n := &Node{Name: c.name, Module: m.Name, Oid: append(append(gosnmp.Oid{}, parent.Oid...), c.number)}
+	if c.name == "" {
+		n.Name = fmt.Sprint(c.number)
+		n.Module = ""
+	}
+	t.insert(parent, n, m)
+	return n
+}
+
+// insert adds n to the tree below parent. A node already at n's oid is
+// replaced, n taking over its children.
+func (t *Tree) insert(parent *Node, n *Node, m *Module) {
+	id := n.Oid[len(n.Oid)-1]
+	if old := parent.children[id]; old != nil {
+		n.children = old.children
+		for _, c := range n.children {
+			c.Parent = n
+		}
+	}
+	if n.children == nil {
+		n.children = make(map[uint32]*Node)
+	}
+	n.Parent = parent
+	parent.children[id] = n
+
+	if n.Module != "" {
+		m.Nodes = append(m.Nodes, n)
+		m.names[n.Name] = n
+		if _, ok := t.names[n.Name]; !ok {
+			t.names[n.Name] = n
+		}
+	}
+}
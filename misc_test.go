@@ -6,8 +6,12 @@ package gosnmp
 
 import (
 	"bytes"
+	"context"
+	"net"
 	"reflect"
+	"sync"
 	"testing"
+	"time"
 )
 
 // Tests in alphabetical order of function being tested
@@ -74,6 +78,119 @@ func TestPartition(t *testing.T) {
 
 // ---------------------------------------------------------------------
 
+func TestConnectLocalAddr(t *testing.T) {
+	srvr, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("ListenUDP() err: %v", err)
+	}
+	defer srvr.Close()
+
+	// find a free local port to send from
+	tmp, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("ListenUDP() err: %v", err)
+	}
+	localPort := tmp.LocalAddr().(*net.UDPAddr).Port
+	tmp.Close()
+
+	x := &GoSNMP{
+		Target:    "127.0.0.1",
+		Port:      uint16(srvr.LocalAddr().(*net.UDPAddr).Port),
+		LocalAddr: "127.0.0.1",
+		LocalPort: uint16(localPort),
+		Timeout:   time.Second,
+	}
+	if err = x.Connect(); err != nil {
+		t.Fatalf("Connect() err: %v", err)
+	}
+	defer x.Conn.Close()
+
+	if _, err = x.Conn.Write([]byte{0x30, 0x00}); err != nil {
+		t.Fatalf("Write() err: %v", err)
+	}
+	srvr.SetDeadline(time.Now().Add(time.Second))
+	buf := make([]byte, 16)
+	_, addr, err := srvr.ReadFromUDP(buf)
+	if err != nil {
+		t.Fatalf("ReadFromUDP() err: %v", err)
+	}
+	if addr.Port != localPort || !addr.IP.Equal(net.IPv4(127, 0, 0, 1)) {
+		t.Errorf("got packet from %s, expected 127.0.0.1:%d", addr, localPort)
+	}
+}
+
+func TestConnectLocalAddrInvalid(t *testing.T) {
+	x := &GoSNMP{
+		Target:    "127.0.0.1",
+		Port:      161,
+		LocalAddr: "not an address",
+		Timeout:   time.Second,
+	}
+	if err := x.Connect(); err == nil {
+		t.Errorf("Connect() with invalid LocalAddr did not return an error")
+	}
+}
+
+// ---------------------------------------------------------------------
+
+// silentServer returns a GoSNMP connected to a udp socket that never answers
+func silentServer(t *testing.T) (*GoSNMP, func()) {
+	srvr, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("ListenUDP() err: %v", err)
+	}
+	x := &GoSNMP{
+		Target:  "127.0.0.1",
+		Port:    uint16(srvr.LocalAddr().(*net.UDPAddr).Port),
+		Version: Version2c,
+		Timeout: 10 * time.Second,
+		Retries: 1,
+	}
+	if err = x.Connect(); err != nil {
+		t.Fatalf("Connect() err: %v", err)
+	}
+	return x, func() {
+		x.Conn.Close()
+		srvr.Close()
+	}
+}
+
+func TestGetCtxCancel(t *testing.T) {
+	x, done := silentServer(t)
+	defer done()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := x.GetCtx(ctx, []string{".1.3.6.1.2.1.1.1.0"})
+	if err != context.Canceled {
+		t.Errorf("GetCtx() err = %v, expected %v", err, context.Canceled)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("GetCtx() took %s after cancel", elapsed)
+	}
+}
+
+func TestWalkCtxDeadline(t *testing.T) {
+	x, done := silentServer(t)
+	defer done()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := x.BulkWalkCtx(ctx, ".1.3.6.1.2.1.1", func(SnmpPDU) error { return nil })
+	if err != context.DeadlineExceeded {
+		t.Errorf("BulkWalkCtx() err = %v, expected %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("BulkWalkCtx() took %s, expected the context deadline to apply", elapsed)
+	}
+}
+
+// ---------------------------------------------------------------------
+
 var testsSnmpVersionString = []struct {
 	in  SnmpVersion
 	out string
@@ -150,3 +267,58 @@ func TestMarshalTimeticks(t *testing.T) {
 	}
 }
 */
+
+// Run with -race: requests on one v3 session copy and update its USM state
+func TestConcurrentV3SecurityParameters(t *testing.T) {
+	x := &GoSNMP{
+		Version:       Version3,
+		MsgFlags:      AuthPriv,
+		SecurityModel: UserSecurityModel,
+		SecurityParameters: &UsmSecurityParameters{
+			UserName:                 "user",
+			AuthenticationProtocol:   SHA,
+			AuthenticationPassphrase: "authpassphrase",
+			PrivacyProtocol:          AES,
+			PrivacyPassphrase:        "privpassphrase",
+		},
+	}
+	if err := x.validateParameters(); err != nil {
+		t.Fatalf("validateParameters() err: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				packet := x.mkSnmpPacket(GetRequest, nil, 0, 0)
+				if err := x.initPacket(packet); err != nil {
+					t.Errorf("initPacket() err: %v", err)
+					return
+				}
+				result := &SnmpPacket{
+					Version:       Version3,
+					SecurityModel: UserSecurityModel,
+					SecurityParameters: &UsmSecurityParameters{
+						AuthoritativeEngineID:    "engine",
+						AuthoritativeEngineBoots: uint32(i),
+						AuthoritativeEngineTime:  uint32(j),
+					},
+				}
+				if err := x.storeSecurityParameters(result); err != nil {
+					t.Errorf("storeSecurityParameters() err: %v", err)
+					return
+				}
+				if err := x.updatePktSecurityParameters(packet); err != nil {
+					t.Errorf("updatePktSecurityParameters() err: %v", err)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	if x.ContextEngineID != "engine" {
+		t.Errorf("ContextEngineID: got %q, want %q", x.ContextEngineID, "engine")
+	}
+}
@@ -0,0 +1,246 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+//
+// An in-memory transport, for testing without sockets
+//
+
+// MockExchange is a message a MockConn expects to be sent, and the
+// messages it receives in answer
+type MockExchange struct {
+	// Request, if not nil, is the message expected, byte for byte
+	Request []byte
+
+	// Match, if set, checks the message sent, eg by decoding it. An
+	// exchange with neither Request nor Match accepts any message.
+	Match func(request []byte) bool
+
+	// Responses are received in answer to the message, in order; none
+	// makes the request time out, as if it or its response were lost
+	Responses [][]byte
+
+	// Respond, if set, makes the responses from the message sent, eg to
+	// give them its request-id (see MockReply); they follow Responses
+	Respond func(request []byte) [][]byte
+}
+
+// MockConn is a net.Conn that sends messages to a script of exchanges
+// instead of a socket, for unit tests of code using a GoSNMP:
+//
+//	m := &gosnmp.MockConn{}
+//	m.Expect(gosnmp.MockExchange{Respond: gosnmp.MockReply(x, pdus)})
+//	x.Dial = m.Dial
+//	err := x.Connect()
+//	result, err := x.Get(oids)
+//	...
+//	if err := m.Verify(); err != nil {
+//
+// Each message written is checked against the next exchange expected,
+// and the exchange's responses queued to be read. Messages can also be
+// received unasked, with Deliver, eg to test late or duplicate responses.
+// The zero value expects nothing; a MockConn is safe for concurrent use.
+type MockConn struct {
+	mu        sync.Mutex
+	cond      *sync.Cond // signalled when received, deadline or closed change
+	script    []MockExchange
+	sent      [][]byte
+	received  [][]byte // queued to be read
+	deadline  time.Time
+	timer     *time.Timer
+	closed    bool
+	errs      []error
+	exchanged int
+}
+
+// mockAddr is the address of both ends of a MockConn
+type mockAddr struct{}
+
+func (mockAddr) Network() string { return "mock" }
+func (mockAddr) String() string  { return "mock" }
+
+// mockTimeout is the error of Reads past the deadline
+type mockTimeout struct{}
+
+func (mockTimeout) Error() string   { return "MockConn read timeout" }
+func (mockTimeout) Timeout() bool   { return true }
+func (mockTimeout) Temporary() bool { return true }
+
+func (m *MockConn) init() {
+	if m.cond == nil {
+		m.cond = sync.NewCond(&m.mu)
+	}
+}
+
+// Expect adds exchanges to those expected, after any expected already
+func (m *MockConn) Expect(exchanges ...MockExchange) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.script = append(m.script, exchanges...)
+}
+
+// Dial returns m, for GoSNMP.Dial
+func (m *MockConn) Dial(network, address string) (net.Conn, error) {
+	return m, nil
+}
+
+// Deliver queues msg to be read, as if it had been received
+func (m *MockConn) Deliver(msg []byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+	m.received = append(m.received, append([]byte(nil), msg...))
+	m.cond.Broadcast()
+}
+
+// Sent returns the messages written so far
+func (m *MockConn) Sent() [][]byte {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([][]byte(nil), m.sent...)
+}
+
+// Verify returns an error if a message was sent that wasn't expected, or
+// if expected exchanges haven't happened
+func (m *MockConn) Verify() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.errs) > 0 {
+		return m.errs[0]
+	}
+	if n := len(m.script); n > 0 {
+		return fmt.Errorf("MockConn: %d of %d expected exchanges didn't happen", n, n+m.exchanged)
+	}
+	return nil
+}
+
+// Write sends msg to the next exchange expected. It doesn't fail if msg
+// isn't the one expected, as a udp socket wouldn't; see Verify.
+func (m *MockConn) Write(msg []byte) (int, error) {
+	msg = append([]byte(nil), msg...)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+	if m.closed {
+		return 0, net.ErrClosed
+	}
+	m.sent = append(m.sent, msg)
+	if len(m.script) == 0 {
+		m.errs = append(m.errs, fmt.Errorf("MockConn: unexpected message %d (% x)", len(m.sent), msg))
+		return len(msg), nil
+	}
+	e := m.script[0]
+	if e.Request != nil && !bytes.Equal(e.Request, msg) ||
+		e.Match != nil && !e.Match(msg) {
+		m.errs = append(m.errs, fmt.Errorf("MockConn: message %d (% x) isn't the one expected", len(m.sent), msg))
+		return len(msg), nil
+	}
+	m.script = m.script[1:]
+	m.exchanged++
+	responses := e.Responses
+	if e.Respond != nil {
+		responses = append(append([][]byte(nil), responses...), e.Respond(msg)...)
+	}
+	for _, r := range responses {
+		m.received = append(m.received, append([]byte(nil), r...))
+	}
+	m.cond.Broadcast()
+	return len(msg), nil
+}
+
+// Read reads the next message received, waiting for one until the read
+// deadline
+func (m *MockConn) Read(b []byte) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+	for {
+		switch {
+		case m.closed:
+			return 0, net.ErrClosed
+		case len(m.received) > 0:
+			n := copy(b, m.received[0])
+			m.received = m.received[1:]
+			return n, nil
+		case !m.deadline.IsZero() && !time.Now().Before(m.deadline):
+			return 0, mockTimeout{}
+		}
+		m.cond.Wait()
+	}
+}
+
+// Close closes m, ending Reads
+func (m *MockConn) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+	m.closed = true
+	if m.timer != nil {
+		m.timer.Stop()
+	}
+	m.cond.Broadcast()
+	return nil
+}
+
+// LocalAddr and RemoteAddr return an address of the network "mock"
+func (m *MockConn) LocalAddr() net.Addr  { return mockAddr{} }
+func (m *MockConn) RemoteAddr() net.Addr { return mockAddr{} }
+
+// SetDeadline sets the read deadline; writes don't block
+func (m *MockConn) SetDeadline(t time.Time) error { return m.SetReadDeadline(t) }
+
+// SetReadDeadline sets the time Reads waiting for a message time out
+func (m *MockConn) SetReadDeadline(t time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+	m.deadline = t
+	if m.timer != nil {
+		m.timer.Stop()
+		m.timer = nil
+	}
+	if !t.IsZero() {
+		m.timer = time.AfterFunc(time.Until(t), func() {
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			m.cond.Broadcast()
+		})
+	}
+	m.cond.Broadcast()
+	return nil
+}
+
+// SetWriteDeadline does nothing, as writes don't block
+func (m *MockConn) SetWriteDeadline(t time.Time) error { return nil }
+
+// MockReply returns a MockExchange.Respond answering a request with a
+// GetResponse of pdus, with the request's version, community and
+// request-id, decoded and encoded by x (eg the session sending the
+// requests, of SNMPv1 or SNMPv2c). Requests that can't be decoded aren't
+// answered.
+func MockReply(x *GoSNMP, pdus []SnmpPDU) func(request []byte) [][]byte {
+	return func(request []byte) [][]byte {
+		packet, err := x.Decode(request)
+		if err != nil {
+			return nil
+		}
+		packet.PDUType = GetResponse
+		packet.Error, packet.ErrorIndex = NoError, 0
+		packet.Variables = pdus
+		response, err := x.Encode(packet)
+		if err != nil {
+			return nil
+		}
+		return [][]byte{response}
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func newMockSession(t *testing.T, m *MockConn) *GoSNMP {
+	t.Helper()
+	x := &GoSNMP{
+		Target:    "192.0.2.1",
+		Port:      161,
+		Community: "public",
+		Version:   Version2c,
+		Timeout:   200 * time.Millisecond,
+		Retries:   1,
+		Dial:      m.Dial,
+	}
+	if err := x.Connect(); err != nil {
+		t.Fatalf("Connect() err: %v", err)
+	}
+	return x
+}
+
+func TestMockConn(t *testing.T) {
+	m := &MockConn{}
+	x := newMockSession(t, m)
+	defer x.Conn.Close()
+	if x.Conn != m {
+		t.Fatalf("Connect() didn't use Dial")
+	}
+
+	pdus := []SnmpPDU{{Name: testSysDescr, Type: OctetString, Value: []byte("mock")}}
+	isGet := func(request []byte) bool {
+		packet, err := x.Decode(request)
+		return err == nil && packet.PDUType == GetRequest && packet.Variables[0].Name == testSysDescr
+	}
+	m.Expect(MockExchange{Match: isGet, Respond: MockReply(x, pdus)})
+	result, err := x.Get([]string{testSysDescr})
+	if err != nil {
+		t.Fatalf("Get() err: %v", err)
+	}
+	if string(result.Variables[0].Value.([]byte)) != "mock" {
+		t.Errorf("Get() = %v", result.Variables)
+	}
+	if err = m.Verify(); err != nil {
+		t.Errorf("Verify() err: %v", err)
+	}
+
+	// the first request is lost, the retry answered; a late response to
+	// the first and a duplicate are dropped
+	m.Expect(MockExchange{Match: isGet}, MockExchange{Match: isGet, Respond: MockReply(x, pdus)})
+	if _, err = x.Get([]string{testSysDescr}); err != nil {
+		t.Fatalf("Get() with a retry err: %v", err)
+	}
+	sent := m.Sent()
+	if len(sent) != 3 {
+		t.Fatalf("Sent() = %d messages, expected 3", len(sent))
+	}
+	for _, request := range sent[1:] {
+		m.Deliver(MockReply(x, pdus)(request)[0])
+	}
+	if err = m.Verify(); err != nil {
+		t.Errorf("Verify() err: %v", err)
+	}
+
+	// the exact request expected
+	m.Expect(MockExchange{Request: sent[0]})
+	x.Get([]string{testSysDescr})
+	if err = m.Verify(); err == nil || !strings.Contains(err.Error(), "isn't the one expected") {
+		t.Errorf("Verify() of a message not expected err: %v", err)
+	}
+}
+
+func TestMockConnUnexpected(t *testing.T) {
+	m := &MockConn{}
+	x := newMockSession(t, m)
+	defer x.Conn.Close()
+	x.Retries = 0
+	if _, err := x.Get([]string{testSysDescr}); err == nil {
+		t.Errorf("Get() without a response succeeded")
+	}
+	if err := m.Verify(); err == nil || !strings.Contains(err.Error(), "unexpected message 1") {
+		t.Errorf("Verify() err: %v", err)
+	}
+
+	m = &MockConn{}
+	m.Expect(MockExchange{}, MockExchange{})
+	if err := m.Verify(); err == nil || !strings.Contains(err.Error(), "2 of 2") {
+		t.Errorf("Verify() of exchanges not made err: %v", err)
+	}
+	m.Close()
+	if _, err := m.Read(make([]byte, 1)); err == nil {
+		t.Errorf("Read() after Close() succeeded")
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync/atomic"
+)
+
+//
+// The SNMPv3 msgMaxSize, negotiated with agents
+//
+
+// minMsgMaxSize is the least msgMaxSize of RFC 3412; smaller values
+// advertised are ignored
+const minMsgMaxSize = 484
+
+// PeerMaxMessageSize returns the msgMaxSize the session's SNMPv3 agent
+// last advertised, the largest request it accepts, or 0 if none has been
+// received yet. It is learned from the responses and reports received,
+// starting with those of discovery.
+func (x *GoSNMP) PeerMaxMessageSize() int {
+	return int(atomic.LoadUint32(&x.peerMaxSize))
+}
+
+// learnPeerMaxSize keeps the msgMaxSize of result, an authentic response
+func (x *GoSNMP) learnPeerMaxSize(result *SnmpPacket) {
+	if result.MsgMaxSize >= minMsgMaxSize {
+		atomic.StoreUint32(&x.peerMaxSize, uint32(result.MsgMaxSize))
+	}
+}
+
+// checkPeerMaxSize returns ErrMessageTooLarge if out, an encoded request,
+// is larger than the agent accepts
+func (x *GoSNMP) checkPeerMaxSize(out []byte) error {
+	if peer := x.PeerMaxMessageSize(); x.Version == Version3 && peer > 0 && len(out) > peer {
+		return fmt.Errorf("%w: %d bytes, msgMaxSize %d", ErrMessageTooLarge, len(out), peer)
+	}
+	return nil
+}
+
+// sendSplitting sends a GET or GETNEXT request for pdus. If it is too
+// large for the agent, the request is split in two, recursively, and the
+// responses merged, as the response to one request would have been.
+func (x *GoSNMP) sendSplitting(ctx context.Context, pduType PDUType, pdus []SnmpPDU) (*SnmpPacket, error) {
+	result, err := x.send(ctx, x.mkSnmpPacket(pduType, pdus, 0, 0), true)
+	if !errors.Is(err, ErrMessageTooLarge) || len(pdus) < 2 {
+		return result, err
+	}
+	x.logPrintf("Splitting a request of %d oids: %v", len(pdus), err)
+	half := len(pdus) / 2
+	first, err := x.sendSplitting(ctx, pduType, pdus[:half])
+	if err != nil || first.PDUType != GetResponse || first.Error != NoError {
+		return first, err
+	}
+	second, err := x.sendSplitting(ctx, pduType, pdus[half:])
+	if err != nil || second.PDUType != GetResponse {
+		return second, err
+	}
+	if second.Error != NoError {
+		// the index in the whole request, clamped to the largest an
+		// error-index holds
+		index := int(second.ErrorIndex)
+		if index > 0 {
+			index += half
+		}
+		if index > math.MaxUint8 {
+			index = math.MaxUint8
+		}
+		first.Error, first.ErrorIndex = second.Error, uint8(index)
+	}
+	first.Variables = append(first.Variables, second.Variables...)
+	first.LazyVariables = append(first.LazyVariables, second.LazyVariables...)
+	return first, nil
+}
@@ -0,0 +1,168 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func newTestMsgSizeSession(maxMessageSize int) *GoSNMP {
+	return &GoSNMP{
+		Version:        Version3,
+		MsgFlags:       AuthNoPriv,
+		SecurityModel:  UserSecurityModel,
+		MaxMessageSize: maxMessageSize,
+		SecurityParameters: &UsmSecurityParameters{
+			UserName:                 "user",
+			AuthenticationProtocol:   SHA,
+			AuthenticationPassphrase: "authpassphrase",
+		},
+	}
+}
+
+func TestPeerMaxMessageSize(t *testing.T) {
+	a, _ := newTestAgent(t)
+	a.MaxMessageSize = 700
+	a.Users = []*UsmSecurityParameters{newTestMsgSizeSession(0).SecurityParameters.(*UsmSecurityParameters)}
+	defer a.Close()
+	x := startTestAgent(t, a, newTestMsgSizeSession(0))
+	defer x.Conn.Close()
+
+	if _, err := x.Get([]string{testSysDescr}); err != nil {
+		t.Fatalf("Get() err: %v", err)
+	}
+	if got := x.PeerMaxMessageSize(); got != 700 {
+		t.Fatalf("PeerMaxMessageSize() = %d, expected 700", got)
+	}
+
+	// too large for the agent, the request is split
+	var oids []string
+	var sizes []int
+	x.OnSend = func(packet *SnmpPacket, out []byte) ([]byte, error) {
+		sizes = append(sizes, len(out))
+		return out, nil
+	}
+	for i := 0; i < 30; i++ {
+		oids = append(oids, testSysDescr, testIfNumber)
+	}
+	result, err := x.Get(oids)
+	if err != nil {
+		t.Fatalf("Get() of %d oids err: %v", len(oids), err)
+	}
+	if len(result.Variables) != len(oids) {
+		t.Fatalf("got %d varbinds, expected %d", len(result.Variables), len(oids))
+	}
+	for i, v := range result.Variables {
+		if v.Name != oids[i] {
+			t.Errorf("varbind %d is %s, expected %s", i, v.Name, oids[i])
+		}
+	}
+	if len(sizes) < 2 {
+		t.Errorf("sent %d requests, expected the request to be split", len(sizes))
+	}
+	for _, size := range sizes {
+		if size > 700 {
+			t.Errorf("sent a request of %d bytes, larger than the agent's msgMaxSize", size)
+		}
+	}
+
+	// a set can't be split
+	pdus := make([]SnmpPDU, 50)
+	for i := range pdus {
+		pdus[i] = SnmpPDU{Name: testSysName, Type: OctetString, Value: "name"}
+	}
+	if _, err = x.Set(pdus); !errors.Is(err, ErrMessageTooLarge) {
+		t.Errorf("Set() of %d varbinds err: %v, expected ErrMessageTooLarge", len(pdus), err)
+	}
+}
+
+func TestSplitErrorIndex(t *testing.T) {
+	a, _ := newTestAgent(t)
+	a.MaxMessageSize = 6000
+	a.Users = []*UsmSecurityParameters{newTestMsgSizeSession(0).SecurityParameters.(*UsmSecurityParameters)}
+	failing := ".1.3.6.1.4.1.99.1.0"
+	if err := a.Register(failing, AgentHandlerFunc(func(string) (SnmpPDU, error) {
+		return SnmpPDU{}, fmt.Errorf("failing")
+	})); err != nil {
+		t.Fatalf("Register() err: %v", err)
+	}
+	defer a.Close()
+	x := startTestAgent(t, a, newTestMsgSizeSession(0))
+	defer x.Conn.Close()
+	if _, err := x.Get([]string{testSysDescr}); err != nil {
+		t.Fatalf("Get() err: %v", err)
+	}
+
+	// the request is split in halves of 300, errors in the second are
+	// beyond the 255 an error-index holds
+	for _, test := range []struct {
+		at, index int
+	}{
+		{10, 11},
+		{320, 255},
+		{590, 255},
+	} {
+		pdus := make([]SnmpPDU, 600)
+		for i := range pdus {
+			pdus[i] = SnmpPDU{Name: testIfNumber, Type: Null}
+		}
+		pdus[test.at].Name = failing
+		result, err := x.sendSplitting(context.Background(), GetRequest, pdus)
+		if err != nil {
+			t.Fatalf("sendSplitting() err: %v", err)
+		}
+		if result.Error != GenErr || int(result.ErrorIndex) != test.index {
+			t.Errorf("failing varbind %d: got %s at %d, expected GenErr at %d",
+				test.at, result.Error, result.ErrorIndex, test.index)
+		}
+	}
+}
+
+func TestAgentRequestMsgMaxSize(t *testing.T) {
+	a, _ := newTestAgent(t)
+	a.Users = []*UsmSecurityParameters{newTestMsgSizeSession(0).SecurityParameters.(*UsmSecurityParameters)}
+	value := []byte(strings.Repeat("x", 50))
+	for i := 1; i <= 20; i++ {
+		oid := fmt.Sprintf(".1.3.6.1.4.1.99.%d.0", i)
+		if err := a.Register(oid, AgentHandlerFunc(func(string) (SnmpPDU, error) {
+			return SnmpPDU{Type: OctetString, Value: value}, nil
+		})); err != nil {
+			t.Fatalf("Register(%s) err: %v", oid, err)
+		}
+	}
+	defer a.Close()
+
+	tests := []struct {
+		maxMessageSize int
+		least, most    int // repetitions
+	}{
+		{0, 20, 20},
+		{484, 1, 6},
+	}
+	for _, test := range tests {
+		x := startTestAgent(t, a, newTestMsgSizeSession(test.maxMessageSize))
+		var received int
+		x.OnReceive = func(packet *SnmpPacket, in []byte) error {
+			received = len(in)
+			return nil
+		}
+		result, err := x.GetBulk([]string{".1.3.6.1.4.1.99"}, 0, 20)
+		x.Conn.Close()
+		if err != nil {
+			t.Errorf("%d: GetBulk() err: %v", test.maxMessageSize, err)
+			continue
+		}
+		if n := len(result.Variables); n < test.least || n > test.most {
+			t.Errorf("%d: got %d repetitions, expected %d to %d", test.maxMessageSize, n, test.least, test.most)
+		}
+		if test.maxMessageSize > 0 && received > test.maxMessageSize {
+			t.Errorf("%d: received a response of %d bytes", test.maxMessageSize, received)
+		}
+	}
+}
@@ -0,0 +1,286 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// datagram is either a message received on a connection, or an error from
+// the socket that can't be attributed to a single request (eg an ICMP port
+// unreachable on a connected udp socket)
+type datagram struct {
+	data []byte
+	from net.Addr // the address data was sent from
+	err  error
+}
+
+// recentIDs is the number of completed requests whose ids a dispatcher
+// remembers
+const recentIDs = 1024
+
+// dispatcher owns the read side of a connection. A single goroutine reads
+// every datagram and hands it to the request waiting for its request-id
+// (or msgID for SNMPv3), so that many requests may be outstanding on one
+// socket at the same time. Datagrams nobody is waiting for, such as late
+// duplicates from an earlier retry, are dropped; the ids of the requests
+// completed recently aren't used again while they are remembered.
+type dispatcher struct {
+	conn  net.Conn
+	x     *GoSNMP
+	stats *counters // x's
+
+	mu      sync.Mutex
+	waiters map[uint32]chan datagram
+
+	// the ids of the requests completed most recently, in a ring, so that
+	// their late responses are told apart and their ids aren't reused
+	recent     [recentIDs]uint32
+	recentNext int
+	recentSet  map[uint32]int // count of each id in recent
+
+	// stopped is closed when the read loop exits, err says why
+	stopped chan struct{}
+	err     error
+}
+
+// dispatcher returns the dispatcher reading from x.Conn, starting one if
+// this is the first request on the connection or if Conn has been replaced
+func (x *GoSNMP) dispatcher() *dispatcher {
+	lazyInitMu.Lock()
+	defer lazyInitMu.Unlock()
+	if d := x.mux; d != nil && d.conn == x.Conn {
+		select {
+		case <-d.stopped:
+		default:
+			return d
+		}
+	}
+	if x.stats == nil {
+		x.stats = new(counters)
+	}
+	d := &dispatcher{
+		conn:    x.Conn,
+		x:       x,
+		stats:   x.stats,
+		waiters: make(map[uint32]chan datagram),
+		stopped: make(chan struct{}),
+	}
+	x.mux = d
+	go d.readLoop()
+	return d
+}
+
+// register arranges for datagrams carrying id to be sent to ch
+func (d *dispatcher) register(id uint32, ch chan datagram) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.waiters[id] = ch
+}
+
+func (d *dispatcher) unregister(ids []uint32) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.recentSet == nil {
+		d.recentSet = make(map[uint32]int)
+	}
+	for _, id := range ids {
+		delete(d.waiters, id)
+		if old := d.recent[d.recentNext]; old != 0 {
+			if d.recentSet[old]--; d.recentSet[old] == 0 {
+				delete(d.recentSet, old)
+			}
+		}
+		d.recent[d.recentNext] = id
+		d.recentSet[id]++
+		d.recentNext = (d.recentNext + 1) % recentIDs
+	}
+}
+
+// fresh returns id, the next of counter, or if a request with it has
+// completed recently the next id of counter that hasn't, so that a late
+// response to that request can't be taken for the response to another.
+// d may be nil, for requests that don't wait.
+func (d *dispatcher) fresh(id uint32, counter *uint32) uint32 {
+	if d == nil {
+		return id
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for d.recentSet[id] > 0 {
+		id = nextID(counter)
+	}
+	return id
+}
+
+func (d *dispatcher) readLoop() {
+	buf := make([]byte, rxBufSize)
+	for {
+		n, from, err := readFrom(d.conn, buf)
+		if err != nil {
+			if isICMPError(err) {
+				d.broadcast(datagram{err: &unreachableError{err}})
+				continue
+			}
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				// a deadline set on Conn by the application
+				d.conn.SetReadDeadline(time.Time{})
+				continue
+			}
+			d.stop(fmt.Errorf("Error reading from UDP: %w", err))
+			return
+		}
+		atomic.AddUint64(&d.stats.received, 1)
+
+		if n == rxBufSize {
+			// This should never happen unless we're using something like a unix domain socket.
+			d.x.logError("Response buffer too small, discarding")
+			continue
+		}
+
+		data := make([]byte, n)
+		copy(data, buf[:n])
+		d.x.captureReceived(d.conn, from, data)
+		id, err := peekRequestID(data)
+		if err != nil {
+			d.x.logWarn("Unable to match response to a request", "err", err)
+			atomic.AddUint64(&d.stats.decodeErrors, 1)
+			continue
+		}
+		d.deliver(id, datagram{data: data, from: from})
+	}
+}
+
+func (d *dispatcher) deliver(id uint32, dg datagram) {
+	d.mu.Lock()
+	ch, ok := d.waiters[id]
+	late := d.recentSet[id] > 0
+	d.mu.Unlock()
+	switch {
+	case !ok && late:
+		d.x.logInfo("Dropping late response", "id", id)
+		atomic.AddUint64(&d.stats.late, 1)
+		return
+	case !ok:
+		d.x.logWarn("Dropping response with unknown id", "id", id)
+		return
+	}
+	select {
+	case ch <- dg:
+	default:
+		d.x.logWarn("Dropping response, receiver is busy", "id", id)
+	}
+}
+
+// broadcast sends dg to every waiting request
+func (d *dispatcher) broadcast(dg datagram) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	sent := make(map[chan datagram]bool)
+	for _, ch := range d.waiters {
+		if sent[ch] {
+			continue
+		}
+		sent[ch] = true
+		select {
+		case ch <- dg:
+		default:
+		}
+	}
+}
+
+func (d *dispatcher) stop(err error) {
+	d.err = err
+	close(d.stopped)
+}
+
+// isICMPError reports whether err is the result of an ICMP error received
+// on a connected udp socket, which doesn't stop the socket being usable
+func isICMPError(err error) bool {
+	return errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EHOSTUNREACH) ||
+		errors.Is(err, syscall.ENETUNREACH)
+}
+
+// unreachableError is the error for an ICMP error received on a connected
+// udp socket, which wraps the socket's error
+type unreachableError struct {
+	err error
+}
+
+func (e *unreachableError) Error() string {
+	return "Destination unreachable: " + e.err.Error()
+}
+
+func (e *unreachableError) Is(target error) bool {
+	switch target {
+	case ErrPortUnreachable:
+		return errors.Is(e.err, syscall.ECONNREFUSED)
+	case ErrHostUnreachable:
+		return errors.Is(e.err, syscall.EHOSTUNREACH) || errors.Is(e.err, syscall.ENETUNREACH)
+	}
+	return false
+}
+
+func (e *unreachableError) Unwrap() error {
+	return e.err
+}
+
+// peekRequestID extracts the id used to match a response to its request
+// without decoding the whole message: the msgID for SNMPv3 (whose PDU may be
+// encrypted), otherwise the PDU's request-id.
+func peekRequestID(packet []byte) (id uint32, err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			err = fmt.Errorf("truncated packet")
+		}
+	}()
+	if len(packet) < 2 || PDUType(packet[0]) != Sequence {
+		return 0, fmt.Errorf("Invalid packet header")
+	}
+	_, cursor := parseLength(packet)
+
+	rawVersion, count, err := parseRawField(packet[cursor:], "version")
+	if err != nil {
+		return 0, err
+	}
+	cursor += count
+
+	if version, _ := rawVersion.(int); SnmpVersion(version) == Version3 {
+		if PDUType(packet[cursor]) != Sequence {
+			return 0, fmt.Errorf("Invalid SNMPV3 Header")
+		}
+		_, count = parseLength(packet[cursor:])
+		cursor += count
+		rawMsgID, _, err := parseRawField(packet[cursor:], "msgID")
+		if err != nil {
+			return 0, err
+		}
+		msgID, _ := rawMsgID.(int)
+		return uint32(msgID), nil
+	}
+
+	// skip community, then the pdu type and length
+	_, count, err = parseRawField(packet[cursor:], "community")
+	if err != nil {
+		return 0, err
+	}
+	cursor += count
+	_, count = parseLength(packet[cursor:])
+	cursor += count
+
+	rawRequestID, _, err := parseRawField(packet[cursor:], "request id")
+	if err != nil {
+		return 0, err
+	}
+	requestID, _ := rawRequestID.(int)
+	return uint32(requestID), nil
+}
@@ -0,0 +1,145 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConcurrentRequests(t *testing.T) {
+	var pdus []SnmpPDU
+	for i := 0; i < 50; i++ {
+		pdus = append(pdus, SnmpPDU{Name: fmt.Sprintf(".1.3.6.1.2.1.2.2.1.2.%d", i), Type: Integer, Value: i})
+	}
+	r := newTestResponder(t, pdus)
+	defer r.Close()
+
+	// hold every request back until they're all outstanding, then answer
+	// them in reverse order
+	var mu sync.Mutex
+	var held []*SnmpPacket
+	release := make(chan struct{})
+	r.setHandler(func(req *SnmpPacket) *SnmpPacket {
+		mu.Lock()
+		rsp := r.respond(req)
+		rsp.RequestID = req.RequestID
+		held = append(held, rsp)
+		if len(held) == len(pdus) {
+			close(release)
+		}
+		mu.Unlock()
+		return nil
+	})
+	go func() {
+		<-release
+		mu.Lock()
+		defer mu.Unlock()
+		for i := len(held) - 1; i >= 0; i-- {
+			out, err := (&SnmpPacket{
+				Version:   Version2c,
+				Community: "public",
+				PDUType:   GetResponse,
+				RequestID: held[i].RequestID,
+				Variables: held[i].Variables,
+			}).marshalMsg()
+			if err != nil {
+				t.Errorf("marshalMsg() err: %v", err)
+				return
+			}
+			r.conn.WriteTo(out, r.lastAddr())
+		}
+	}()
+
+	x := r.client(t)
+	defer x.Conn.Close()
+	x.Timeout = 5 * time.Second
+	x.Retries = 0
+
+	var wg sync.WaitGroup
+	for i := range pdus {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			result, err := x.Get([]string{pdus[i].Name})
+			if err != nil {
+				t.Errorf("Get(%s) err: %v", pdus[i].Name, err)
+				return
+			}
+			if got := result.Variables[0]; got.Name != pdus[i].Name || got.Value != i {
+				t.Errorf("Get(%s) got %s = %v", pdus[i].Name, got.Name, got.Value)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestPeekRequestID(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		packet *SnmpPacket
+		want   uint32
+	}{
+		{"v1", &SnmpPacket{Version: Version1, Community: "public", PDUType: GetResponse, RequestID: 1}, 1},
+		{"v2c", &SnmpPacket{Version: Version2c, Community: "public", PDUType: GetResponse, RequestID: 0x87654321}, 0x87654321},
+	} {
+		out, err := tt.packet.marshalMsg()
+		if err != nil {
+			t.Fatalf("%s: marshalMsg() err: %v", tt.name, err)
+		}
+		got, err := peekRequestID(out)
+		if err != nil {
+			t.Errorf("%s: peekRequestID() err: %v", tt.name, err)
+		} else if got != tt.want {
+			t.Errorf("%s: peekRequestID() got %d, want %d", tt.name, got, tt.want)
+		}
+		if _, err = peekRequestID(out[:len(out)/2]); err == nil {
+			t.Errorf("%s: peekRequestID() of truncated packet: expected error", tt.name)
+		}
+	}
+}
+
+func TestLateResponses(t *testing.T) {
+	m := &MockConn{}
+	x := newMockSession(t, m)
+	defer x.Conn.Close()
+	reply := func(value string) func([]byte) [][]byte {
+		return MockReply(x, []SnmpPDU{{Name: testSysDescr, Type: OctetString, Value: []byte(value)}})
+	}
+
+	// the response to the first request is duplicated
+	var first []byte
+	m.Expect(MockExchange{Respond: func(request []byte) [][]byte {
+		r := reply("first")(request)
+		first = r[0]
+		return append(r, r...)
+	}})
+	result, err := x.Get([]string{testSysDescr})
+	if err != nil {
+		t.Fatalf("Get() err: %v", err)
+	}
+	for end := time.Now().Add(time.Second); x.Stats().LateResponses == 0 && time.Now().Before(end); {
+		time.Sleep(time.Millisecond)
+	}
+	if got := x.Stats().LateResponses; got != 1 {
+		t.Errorf("LateResponses = %d, expected 1", got)
+	}
+
+	// the next request would have the first's id, were it not skipped,
+	// and would then take the duplicate arriving during it for its answer
+	x.requestID = result.RequestID - 1
+	m.Expect(MockExchange{Responses: [][]byte{first}, Respond: reply("second")})
+	if result, err = x.Get([]string{testSysDescr}); err != nil {
+		t.Fatalf("Get() err: %v", err)
+	}
+	if got := string(result.Variables[0].Value.([]byte)); got != "second" {
+		t.Errorf("Get() = %q, expected the response to the second request", got)
+	}
+	if err = m.Verify(); err != nil {
+		t.Errorf("Verify() err: %v", err)
+	}
+}
@@ -0,0 +1,347 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//
+// The configuration of a notification originator (RFC 3413 sections 3 and
+// 4.1), the snmpTargetAddrTable, snmpTargetParamsTable and snmpNotifyTable
+//
+
+const (
+	// defaultNotifyPort is the port of snmpTargetAddrTAddress if it has none
+	defaultNotifyPort = 162
+
+	// defaultTargetTimeout and defaultTargetRetries are the DEFVALs of
+	// snmpTargetAddrTimeout and snmpTargetAddrRetryCount
+	defaultTargetTimeout = 1500 * time.Millisecond
+	defaultTargetRetries = 3
+)
+
+// NotifyType is the type of the notifications of a NotifyEntry
+type NotifyType int
+
+const (
+	// NotifyTrap sends traps, which aren't acknowledged
+	NotifyTrap NotifyType = iota + 1
+
+	// NotifyInform sends informs, which are sent again until acknowledged
+	// as the target's timeout and retries say
+	NotifyInform
+)
+
+func (t NotifyType) String() string {
+	switch t {
+	case NotifyTrap:
+		return "trap"
+	case NotifyInform:
+		return "inform"
+	}
+	return "NotifyType(" + strconv.Itoa(int(t)) + ")"
+}
+
+// TargetAddr is an entry of the snmpTargetAddrTable, a receiver of
+// notifications
+type TargetAddr struct {
+	// Name identifies the target
+	Name string
+
+	// Address is the receiver's udp address, "host:port" or host for
+	// port 162
+	Address string
+
+	// Timeout and Retries are those of informs sent to the target, as for
+	// GoSNMP.Timeout and Retries (default: 1.5s and 3; a Retries of -1
+	// is none)
+	Timeout time.Duration
+	Retries int
+
+	// Tags are the tags selecting the target, see NotifyEntry.Tag
+	Tags []string
+
+	// Params is the Name of the TargetParams notifications are sent with
+	Params string
+}
+
+// TargetParams is an entry of the snmpTargetParamsTable, the version and
+// credentials notifications are sent with
+type TargetParams struct {
+	// Name identifies the parameters
+	Name string
+
+	// Version is the version of the notifications, ie their message
+	// processing and security models. SNMPv2 notifications are sent to
+	// targets whose Version is Version1 as SNMPv1 traps, as RFC 3584
+	// section 3.2 says.
+	Version SnmpVersion
+
+	// SecurityName is the community of SNMPv1 and SNMPv2c notifications,
+	// or the user of SNMPv3 ones
+	SecurityName string
+
+	// MsgFlags is the security level of SNMPv3 notifications, eg AuthPriv
+	MsgFlags SnmpV3MsgFlags
+
+	// SecurityParameters are the protocols and passphrases of the SNMPv3
+	// user; their UserName is SecurityName
+	SecurityParameters *UsmSecurityParameters
+
+	// FilterProfile, if set, is the notification filter profile of the
+	// targets sent notifications with the parameters, see
+	// NotificationOriginator.AddNotifyFilter
+	FilterProfile string
+}
+
+// NotifyEntry is an entry of the snmpNotifyTable, selecting the targets
+// whose Tags include Tag for notifications of Type. An empty Tag selects
+// none.
+type NotifyEntry struct {
+	Name string
+	Tag  string
+	Type NotifyType
+}
+
+// NotifyResult is the result of sending a notification to one target
+type NotifyResult struct {
+	// Target is the Name of the TargetAddr
+	Target string
+
+	// Type is the type of the notification sent
+	Type NotifyType
+
+	// Err is the error sending the notification failed with, or for an
+	// inform, that it wasn't acknowledged with
+	Err error
+}
+
+// NotificationOriginator sends notifications to the targets configured
+// in its tables, as an SNMP entity's notification originator does (RFC
+// 3413 section 3.2): Notify sends a notification to each target selected
+// by a NotifyEntry, with the version, credentials, timeout and retries of
+// the target. The zero value has no targets; it is safe for concurrent use
+// and must not be copied after first use. Close closes its sessions.
+type NotificationOriginator struct {
+	// Template holds the other parameters of the sessions notifications
+	// are sent with, eg its Logger, and for SNMPv3 the EngineID and
+	// EngineBoots of the originator, the authoritative engine of traps.
+	// If nil, Default is used.
+	Template *GoSNMP
+
+	mu       sync.Mutex
+	addrs    []TargetAddr
+	params   map[string]TargetParams
+	notify   []NotifyEntry
+	filters  map[string][]vacmViewEntry // by profile
+	sessions map[string]*GoSNMP         // by target, connected once used
+}
+
+// AddTargetAddr adds a target, replacing any of the same Name
+func (o *NotificationOriginator) AddTargetAddr(addr TargetAddr) error {
+	if _, _, err := targetHostPort(addr.Address); err != nil {
+		return err
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.closeSession(addr.Name)
+	for i, a := range o.addrs {
+		if a.Name == addr.Name {
+			o.addrs[i] = addr
+			return nil
+		}
+	}
+	o.addrs = append(o.addrs, addr)
+	return nil
+}
+
+// AddTargetParams adds parameters, replacing any of the same Name
+func (o *NotificationOriginator) AddTargetParams(params TargetParams) error {
+	switch params.Version {
+	case Version1, Version2c:
+	case Version3:
+		if params.SecurityParameters == nil {
+			return fmt.Errorf("TargetParams %q has no SecurityParameters", params.Name)
+		}
+	default:
+		return fmt.Errorf("TargetParams %q has unknown version %s", params.Name, params.Version)
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.params == nil {
+		o.params = make(map[string]TargetParams)
+	}
+	o.params[params.Name] = params
+	for _, a := range o.addrs {
+		if a.Params == params.Name {
+			o.closeSession(a.Name)
+		}
+	}
+	return nil
+}
+
+// AddNotify adds a notify entry, replacing any of the same Name
+func (o *NotificationOriginator) AddNotify(entry NotifyEntry) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for i, e := range o.notify {
+		if e.Name == entry.Name {
+			o.notify[i] = entry
+			return
+		}
+	}
+	o.notify = append(o.notify, entry)
+}
+
+// Notify sends a notification of pdus, as for SendTrap, to each target
+// selected by a notify entry, at once, returning when all have been sent
+// (and informs acknowledged or timed out). A target selected by several
+// entries is sent one notification, an inform if any of them is for
+// informs. Targets whose filter profile excludes the notification aren't
+// sent it, and have no result. The results are in the order the targets
+// were added.
+func (o *NotificationOriginator) Notify(pdus []SnmpPDU) ([]NotifyResult, error) {
+	pdus, err := notificationPDUs("Notify", pdus, o.template().Logger)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []NotifyResult
+	var sessions []*GoSNMP
+	o.mu.Lock()
+	for _, a := range o.addrs {
+		r := NotifyResult{Target: a.Name, Type: o.selects(a)}
+		if r.Type == 0 || !o.passes(o.params[a.Params].FilterProfile, pdus) {
+			continue
+		}
+		var x *GoSNMP
+		x, r.Err = o.session(a)
+		results = append(results, r)
+		sessions = append(sessions, x)
+	}
+	o.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for i := range results {
+		if results[i].Err != nil {
+			continue
+		}
+		wg.Add(1)
+		go func(r *NotifyResult, x *GoSNMP) {
+			defer wg.Done()
+			packet := &SnmpPacket{Version: Version2c, PDUType: SNMPv2Trap, Variables: pdus}
+			if r.Type == NotifyInform {
+				packet.PDUType = InformRequest
+			}
+			r.Err = (&TrapDestination{Session: x}).forward(packet)
+		}(&results[i], sessions[i])
+	}
+	wg.Wait()
+	return results, nil
+}
+
+// Close closes the sessions of the targets
+func (o *NotificationOriginator) Close() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for name := range o.sessions {
+		o.closeSession(name)
+	}
+	return nil
+}
+
+func (o *NotificationOriginator) template() *GoSNMP {
+	if o.Template == nil {
+		return Default
+	}
+	return o.Template
+}
+
+// selects returns the type of the notifications the notify entries select
+// addr for, or 0 if they don't
+func (o *NotificationOriginator) selects(addr TargetAddr) NotifyType {
+	var selected NotifyType
+	for _, e := range o.notify {
+		if e.Tag != "" && containsString(addr.Tags, e.Tag) && e.Type > selected {
+			selected = e.Type
+		}
+	}
+	return selected
+}
+
+// session returns the connected session of addr, making it if there is
+// none
+func (o *NotificationOriginator) session(addr TargetAddr) (*GoSNMP, error) {
+	if x, ok := o.sessions[addr.Name]; ok {
+		return x, nil
+	}
+	params, ok := o.params[addr.Params]
+	if !ok {
+		return nil, fmt.Errorf("TargetParams %q of target %q not found", addr.Params, addr.Name)
+	}
+	host, port, _ := targetHostPort(addr.Address)
+	x := newSession(o.template(), host)
+	x.Port = port
+	x.Version = params.Version
+	x.Community = params.SecurityName
+	x.Timeout, x.Retries = addr.Timeout, addr.Retries
+	if x.Timeout <= 0 {
+		x.Timeout = defaultTargetTimeout
+	}
+	switch {
+	case x.Retries == 0:
+		x.Retries = defaultTargetRetries
+	case x.Retries < 0:
+		x.Retries = 0
+	}
+	x.RetryPolicy = nil
+	if params.Version == Version3 {
+		sp := params.SecurityParameters.Copy().(*UsmSecurityParameters)
+		sp.UserName = params.SecurityName
+		x.Community = ""
+		x.SecurityModel = UserSecurityModel
+		x.MsgFlags = params.MsgFlags
+		x.SecurityParameters = sp
+	}
+	if err := x.Connect(); err != nil {
+		return nil, err
+	}
+	if o.sessions == nil {
+		o.sessions = make(map[string]*GoSNMP)
+	}
+	o.sessions[addr.Name] = x
+	return x, nil
+}
+
+// closeSession closes the session of the target name, if it has one
+func (o *NotificationOriginator) closeSession(name string) {
+	if x, ok := o.sessions[name]; ok {
+		x.Conn.Close()
+		delete(o.sessions, name)
+	}
+}
+
+// targetHostPort returns the host and port of a TargetAddr's Address
+func targetHostPort(address string) (string, uint16, error) {
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		// no port, or a bare IPv6 address
+		if ip := net.ParseIP(address); ip != nil || address != "" && !strings.Contains(address, ":") {
+			return address, defaultNotifyPort, nil
+		}
+		return "", 0, fmt.Errorf("TargetAddr address %q: %w", address, err)
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return "", 0, fmt.Errorf("TargetAddr address %q has bad port %q", address, portStr)
+	}
+	return host, uint16(port), nil
+}
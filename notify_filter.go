@@ -0,0 +1,64 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+//
+// Notification filtering (RFC 3413 section 6), the snmpNotifyFilterTable
+//
+
+// AddNotifyFilter adds the subtree at oid to the filter profile, or
+// excludes it from the profile if included is false; a profile is used by
+// the targets of the TargetParams whose FilterProfile it is. mask is as
+// for Vacm.AddView, and an oid is in the profile if the longest of its
+// subtrees matching it is included.
+//
+// A notification is sent to a target whose profile has subtrees only if
+// its snmpTrapOID, and the name of each of its varbinds other than those
+// of sysUpTime and snmpTrapOID, are in the profile. Targets whose profile
+// has no subtrees aren't filtered.
+func (o *NotificationOriginator) AddNotifyFilter(profile, oid string, mask []byte, included bool) error {
+	subtree, err := ParseOid(oid)
+	if err != nil {
+		return err
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.filters == nil {
+		o.filters = make(map[string][]vacmViewEntry)
+	}
+	entries := o.filters[profile]
+	for i, e := range entries {
+		if e.subtree.Equal(subtree) {
+			entries[i] = vacmViewEntry{subtree, mask, included}
+			return nil
+		}
+	}
+	o.filters[profile] = append(entries, vacmViewEntry{subtree, mask, included})
+	return nil
+}
+
+// passes reports whether the notification of pdus passes the filter
+// profile, see AddNotifyFilter
+func (o *NotificationOriginator) passes(profile string, pdus []SnmpPDU) bool {
+	entries := o.filters[profile]
+	if profile == "" || len(entries) == 0 {
+		return true
+	}
+	trapOid, err := ParseOid(notificationOid(&SnmpPacket{Variables: pdus}))
+	if err != nil || !inViewEntries(entries, trapOid) {
+		return false
+	}
+	for _, pdu := range pdus {
+		switch name := "." + trimOidDot(pdu.Name); name {
+		case sysUpTimeOid, snmpTrapOID:
+		default:
+			oid, err := ParseOid(name)
+			if err != nil || !inViewEntries(entries, oid) {
+				return false
+			}
+		}
+	}
+	return true
+}
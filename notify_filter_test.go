@@ -0,0 +1,115 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestNotifyFilter(t *testing.T) {
+	o := &NotificationOriginator{}
+	for _, f := range []struct {
+		oid      string
+		mask     []byte
+		included bool
+	}{
+		{".1.3.6.1.6.3.1.1.5", nil, true},                    // the generic traps
+		{".1.3.6.1.6.3.1.1.5.1", nil, false},                 // but coldStart
+		{".1.3.6.1.2.1.2.2.1.1.0", []byte{0xff, 0xc0}, true}, // ifIndex, of any row
+	} {
+		if err := o.AddNotifyFilter("links", f.oid, f.mask, f.included); err != nil {
+			t.Fatalf("AddNotifyFilter(%s) err: %v", f.oid, err)
+		}
+	}
+	notification := func(trapOid string, names ...string) []SnmpPDU {
+		pdus := []SnmpPDU{
+			{Name: sysUpTimeOid, Type: TimeTicks, Value: uint32(1)},
+			{Name: snmpTrapOID, Type: ObjectIdentifier, Value: trapOid},
+		}
+		for _, name := range names {
+			pdus = append(pdus, SnmpPDU{Name: name, Type: Integer, Value: 1})
+		}
+		return pdus
+	}
+	tests := []struct {
+		profile string
+		pdus    []SnmpPDU
+		ok      bool
+	}{
+		{"", notification(".1.3.6.1.4.1.2.0.1"), true},
+		{"unknown", notification(".1.3.6.1.4.1.2.0.1"), true},
+		{"links", notification(".1.3.6.1.6.3.1.1.5.3", ".1.3.6.1.2.1.2.2.1.1.2"), true},
+		{"links", notification(".1.3.6.1.6.3.1.1.5.3", "1.3.6.1.2.1.2.2.1.1.2"), true},
+		{"links", notification(".1.3.6.1.6.3.1.1.5.1"), false},
+		{"links", notification(".1.3.6.1.4.1.2.0.1"), false},
+		{"links", notification(".1.3.6.1.6.3.1.1.5.3", ".1.3.6.1.2.1.2.2.1.2.2"), false},
+		{"links", notification(".1.3.6.1.6.3.1.1.5.3", ".1.3.6.1.2.1.2.2.1.7.2"), false},
+		{"links", notification(".1.3.6.1.6.3.1.1.5.3", ".1.3.6.1.2.1.1.5.0"), false},
+		{"links", []SnmpPDU{{Name: sysUpTimeOid, Type: TimeTicks, Value: uint32(1)}}, false},
+	}
+	for i, test := range tests {
+		if ok := o.passes(test.profile, test.pdus); ok != test.ok {
+			t.Errorf("%d: passes(%q) got %t, expected %t", i, test.profile, ok, test.ok)
+		}
+	}
+	if err := o.AddNotifyFilter("links", "1.3.x", nil, true); err == nil {
+		t.Error("AddNotifyFilter() of a bad oid succeeded")
+	}
+}
+
+func TestNotificationOriginatorFilter(t *testing.T) {
+	tl := NewTrapListener()
+	received := make(chan *SnmpPacket, 4)
+	tl.OnNewTrap = func(s *SnmpPacket, u *net.UDPAddr) { received <- s }
+	tl.Params = &GoSNMP{}
+	addr := listenTestTraps(t, tl)
+	defer tl.Close()
+
+	o := &NotificationOriginator{Template: &GoSNMP{MaxOids: MaxOids}}
+	defer o.Close()
+	for _, params := range []TargetParams{
+		{Name: "all", Version: Version2c, SecurityName: "all"},
+		{Name: "links", Version: Version2c, SecurityName: "links", FilterProfile: "links"},
+	} {
+		if err := o.AddTargetParams(params); err != nil {
+			t.Fatalf("AddTargetParams() err: %v", err)
+		}
+		if err := o.AddTargetAddr(TargetAddr{Name: params.Name, Address: addr.String(), Tags: []string{"nms"}, Params: params.Name}); err != nil {
+			t.Fatalf("AddTargetAddr() err: %v", err)
+		}
+	}
+	o.AddNotify(NotifyEntry{Name: "nms", Tag: "nms", Type: NotifyTrap})
+	if err := o.AddNotifyFilter("links", snmpTraps, nil, true); err != nil {
+		t.Fatalf("AddNotifyFilter() err: %v", err)
+	}
+
+	for _, test := range []struct {
+		trapOid string
+		targets []string
+	}{
+		{".1.3.6.1.6.3.1.1.5.3", []string{"all", "links"}},
+		{".1.3.6.1.4.1.2.0.1", []string{"all"}},
+	} {
+		results, err := o.Notify([]SnmpPDU{{Name: snmpTrapOID, Type: ObjectIdentifier, Value: test.trapOid}})
+		if err != nil {
+			t.Fatalf("Notify(%s) err: %v", test.trapOid, err)
+		}
+		if len(results) != len(test.targets) {
+			t.Fatalf("Notify(%s) got %v, expected %v", test.trapOid, results, test.targets)
+		}
+		for i, r := range results {
+			if r.Target != test.targets[i] || r.Err != nil {
+				t.Errorf("Notify(%s) got %+v", test.trapOid, r)
+			}
+			select {
+			case <-received:
+			case <-time.After(5 * time.Second):
+				t.Fatalf("timed out waiting for %s", test.trapOid)
+			}
+		}
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTargetHostPort(t *testing.T) {
+	tests := []struct {
+		address string
+		host    string
+		port    uint16
+		ok      bool
+	}{
+		{"192.0.2.1:1162", "192.0.2.1", 1162, true},
+		{"192.0.2.1", "192.0.2.1", 162, true},
+		{"nms.example.com", "nms.example.com", 162, true},
+		{"[2001:db8::1]:1162", "2001:db8::1", 1162, true},
+		{"2001:db8::1", "2001:db8::1", 162, true},
+		{"192.0.2.1:trap", "", 0, false},
+		{"", "", 0, false},
+	}
+	for _, test := range tests {
+		host, port, err := targetHostPort(test.address)
+		if (err == nil) != test.ok || host != test.host || port != test.port {
+			t.Errorf("targetHostPort(%q) got %q, %d, err %v", test.address, host, port, err)
+		}
+	}
+}
+
+func TestNotificationOriginator(t *testing.T) {
+	user := &UsmSecurityParameters{
+		AuthenticationProtocol:   SHA,
+		AuthenticationPassphrase: "authpassphrase",
+	}
+	listenerUser := user.Copy().(*UsmSecurityParameters)
+	listenerUser.UserName = "user"
+
+	// an SNMPv1 and SNMPv2c receiver of traps, and an SNMPv3 one of
+	// informs
+	type receiver struct {
+		tl       *TrapListener
+		received chan *SnmpPacket
+	}
+	var receivers []receiver
+	for _, params := range []*GoSNMP{{}, {
+		Version:            Version3,
+		MsgFlags:           AuthNoPriv,
+		SecurityModel:      UserSecurityModel,
+		SecurityParameters: listenerUser,
+		EngineID:           "\x80\x00\x00\x00\x04listener",
+	}} {
+		tl := NewTrapListener()
+		received := make(chan *SnmpPacket, 4)
+		tl.OnNewTrap = func(s *SnmpPacket, u *net.UDPAddr) { received <- s }
+		tl.Params = params
+		listenTestTraps(t, tl)
+		defer tl.Close()
+		receivers = append(receivers, receiver{tl, received})
+	}
+
+	o := &NotificationOriginator{Template: &GoSNMP{MaxOids: MaxOids, EngineID: "\x80\x00\x00\x00\x04originator"}}
+	defer o.Close()
+	for _, params := range []TargetParams{
+		{Name: "v1", Version: Version1, SecurityName: "public"},
+		{Name: "v2c", Version: Version2c, SecurityName: "public"},
+		{Name: "v3", Version: Version3, SecurityName: "user", MsgFlags: AuthNoPriv, SecurityParameters: user},
+	} {
+		if err := o.AddTargetParams(params); err != nil {
+			t.Fatalf("AddTargetParams(%s) err: %v", params.Name, err)
+		}
+	}
+	if err := o.AddTargetParams(TargetParams{Name: "usm", Version: Version3}); err == nil {
+		t.Error("AddTargetParams() without SecurityParameters succeeded")
+	}
+	for _, addr := range []TargetAddr{
+		{Name: "v1", Address: receivers[0].tl.Addrs()[0].String(), Tags: []string{"traps"}, Params: "v1"},
+		{Name: "v2c", Address: receivers[0].tl.Addrs()[0].String(), Tags: []string{"traps"}, Params: "v2c"},
+		{Name: "v3", Address: receivers[1].tl.Addrs()[0].String(), Tags: []string{"traps", "informs"}, Params: "v3"},
+		{Name: "unknown", Address: "127.0.0.1", Tags: []string{"traps"}, Params: "unknown"},
+		{Name: "untagged", Address: "127.0.0.1", Params: "v2c"},
+	} {
+		if err := o.AddTargetAddr(addr); err != nil {
+			t.Fatalf("AddTargetAddr(%s) err: %v", addr.Name, err)
+		}
+	}
+	o.AddNotify(NotifyEntry{Name: "traps", Tag: "traps", Type: NotifyTrap})
+	o.AddNotify(NotifyEntry{Name: "informs", Tag: "informs", Type: NotifyInform})
+	o.AddNotify(NotifyEntry{Name: "none", Tag: "", Type: NotifyInform})
+
+	pdus := []SnmpPDU{
+		{Name: snmpTrapOID, Type: ObjectIdentifier, Value: ".1.3.6.1.4.1.2.0.1"},
+		{Name: trapTestOid, Type: OctetString, Value: trapTestPayload},
+	}
+	results, err := o.Notify(pdus)
+	if err != nil {
+		t.Fatalf("Notify() err: %v", err)
+	}
+	var got []NotifyResult
+	for _, r := range results {
+		got = append(got, NotifyResult{Target: r.Target, Type: r.Type})
+		if (r.Err == nil) != (r.Target != "unknown") {
+			t.Errorf("Notify() to %s err: %v", r.Target, r.Err)
+		}
+	}
+	expected := []NotifyResult{{"v1", NotifyTrap, nil}, {"v2c", NotifyTrap, nil}, {"v3", NotifyInform, nil}, {"unknown", NotifyTrap, nil}}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Notify() got %v, expected %v", got, expected)
+	}
+
+	versions := map[SnmpVersion]PDUType{}
+	for i, n := range []int{2, 1} {
+		for ; n > 0; n-- {
+			select {
+			case s := <-receivers[i].received:
+				versions[s.Version] = s.PDUType
+			case <-time.After(5 * time.Second):
+				t.Fatalf("timed out waiting for receiver %d", i)
+			}
+		}
+	}
+	if !reflect.DeepEqual(versions, map[SnmpVersion]PDUType{Version1: Trap, Version2c: SNMPv2Trap, Version3: InformRequest}) {
+		t.Errorf("received %v", versions)
+	}
+
+	// a target's timeout and retries are those of informs
+	if err = o.AddTargetAddr(TargetAddr{Name: "v3", Address: "127.0.0.1:1", Timeout: 10 * time.Millisecond,
+		Retries: -1, Tags: []string{"informs"}, Params: "v2c"}); err != nil {
+		t.Fatalf("AddTargetAddr() err: %v", err)
+	}
+	start := time.Now()
+	results, _ = o.Notify(pdus)
+	if r := results[2]; r.Target != "v3" || r.Err == nil || errors.Is(r.Err, ErrReport) {
+		t.Errorf("Notify() to an unreachable target got %+v", r)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Notify() to an unreachable target took %s", elapsed)
+	}
+}
@@ -0,0 +1,296 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// Oid is an OBJECT IDENTIFIER held as its numeric parts, eg
+// Oid{1, 3, 6, 1, 2, 1, 1, 1, 0}. Comparing and prefix checking Oids is
+// cheap, unlike the dotted strings used by SnmpPDU.Name; use ParseOid and
+// Oid.String to convert between the two. Varbinds received carry their
+// names as Oids too, in SnmpPDU.NameOid, OBJECT IDENTIFIER values are
+// decoded as Oids with GoSNMP.OidValues, and both can be sent as Oids.
+type Oid []uint32
+
+// ParseOid parses a dotted oid such as ".1.3.6.1.2.1.1.1.0". The leading
+// dot is optional.
+func ParseOid(s string) (Oid, error) {
+	if len(s) > 0 && s[0] == '.' {
+		s = s[1:]
+	}
+	if s == "" {
+		return nil, fmt.Errorf("Unable to parse OID: empty")
+	}
+
+	oid := make(Oid, 0, len(s)/2+1)
+	start := 0
+	for i := 0; i <= len(s); i++ {
+		if i < len(s) && s[i] != '.' {
+			continue
+		}
+		n, err := strconv.ParseUint(s[start:i], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to parse OID: %w", err)
+		}
+		oid = append(oid, uint32(n))
+		start = i + 1
+	}
+	return oid, nil
+}
+
+// String formats o in the same form as SnmpPDU.Name, with a leading dot
+func (o Oid) String() string {
+	buf := make([]byte, 0, 4*len(o))
+	for _, n := range o {
+		buf = append(buf, '.')
+		buf = strconv.AppendUint(buf, uint64(n), 10)
+	}
+	return string(buf)
+}
+
+// Equal reports whether o and b are the same oid
+func (o Oid) Equal(b Oid) bool {
+	if len(o) != len(b) {
+		return false
+	}
+	for i := range o {
+		if o[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Compare compares o and b in lexicographic order, as used by GETNEXT: it
+// returns -1 if o comes before b, 0 if they are equal, and +1 if o comes
+// after b.
+func (o Oid) Compare(b Oid) int {
+	for i := 0; i < len(o) && i < len(b); i++ {
+		if o[i] != b[i] {
+			if o[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case len(o) < len(b):
+		return -1
+	case len(o) > len(b):
+		return 1
+	}
+	return 0
+}
+
+// HasPrefix reports whether o starts with prefix, ie whether o is prefix
+// itself or in the subtree below it
+func (o Oid) HasPrefix(prefix Oid) bool {
+	return len(o) >= len(prefix) && o[:len(prefix)].Equal(prefix)
+}
+
+// Oid returns the PDU's name as an Oid: NameOid if it names the same oid
+// as Name, as in varbinds received, without parsing; otherwise Name
+// parsed. Appending to the Oid returned doesn't change NameOid.
+func (p SnmpPDU) Oid() (Oid, error) {
+	if n := len(p.NameOid); n > 0 && (p.Name == "" || p.NameOid.equalsName(p.Name)) {
+		return p.NameOid[:n:n], nil
+	}
+	return ParseOid(p.Name)
+}
+
+// marshalName encodes the PDU's name: Name, or NameOid if Name is empty
+func (p *SnmpPDU) marshalName() ([]byte, error) {
+	if p.Name == "" && len(p.NameOid) > 0 {
+		return marshalOid(p.NameOid)
+	}
+	return marshalOID(p.Name)
+}
+
+// equalsName reports whether o is the dotted oid name, without allocating
+func (o Oid) equalsName(name string) bool {
+	name = trimOidDot(name)
+	for _, n := range o {
+		if name == "" {
+			return false
+		}
+		var part uint64
+		part, name = nextOidPart(name)
+		if part != uint64(n) {
+			return false
+		}
+	}
+	return name == ""
+}
+
+// marshalOid encodes an Oid as the contents of a BER OBJECT IDENTIFIER
+func marshalOid(oid Oid) ([]byte, error) {
+	if len(oid) < 2 || oid[0] > 6 || oid[1] >= 40 {
+		return nil, errors.New("invalid object identifier")
+	}
+	// most sub-identifiers fit in one or two bytes
+	out := bytes.NewBuffer(make([]byte, 0, 2*len(oid)))
+	out.WriteByte(byte(oid[0]*40 + oid[1]))
+	for _, n := range oid[2:] {
+		// from uint32, an int would overflow on 32 bit platforms
+		if err := marshalBase128Int(out, int64(n)); err != nil {
+			return nil, err
+		}
+	}
+	return out.Bytes(), nil
+}
+
+// parseOid decodes the contents of a BER OBJECT IDENTIFIER
+func parseOid(bytes []byte) (Oid, error) {
+	if len(bytes) == 0 {
+		return nil, fmt.Errorf("zero length OBJECT IDENTIFIER")
+	}
+
+	oid := make(Oid, 2, len(bytes)+1)
+
+	// The first byte is 40*value1 + value2:
+	oid[0] = uint32(bytes[0]) / 40
+	oid[1] = uint32(bytes[0]) % 40
+	for offset := 1; offset < len(bytes); {
+		var v uint32
+		var err error
+		v, offset, err = parseBase128Uint32(bytes, offset)
+		if err != nil {
+			return nil, err
+		}
+		oid = append(oid, v)
+	}
+	return oid, nil
+}
+
+// parseBase128Uint32 is parseBase128Int for sub-identifiers, which are
+// up to 2^32-1 whatever the size of an int
+func parseBase128Uint32(bytes []byte, offset int) (uint32, int, error) {
+	var n uint64
+	for shifted := 0; offset < len(bytes); shifted++ {
+		if shifted > 4 {
+			return 0, offset, fmt.Errorf("Structural Error: base 128 integer too large")
+		}
+		b := bytes[offset]
+		n = n<<7 | uint64(b&0x7f)
+		offset++
+		if b&0x80 == 0 {
+			if n > math.MaxUint32 {
+				return 0, offset, fmt.Errorf("Structural Error: base 128 integer too large")
+			}
+			return uint32(n), offset, nil
+		}
+	}
+	return 0, offset, fmt.Errorf("Syntax Error: truncated base 128 integer")
+}
+
+// NextSibling returns the oid following o and its whole subtree, ie o with
+// its last part incremented; every oid in o's subtree is less than it.
+// NextSibling of an empty Oid is empty.
+func (o Oid) NextSibling() Oid {
+	if len(o) == 0 {
+		return Oid{}
+	}
+	next := make(Oid, len(o))
+	copy(next, o)
+	for i := len(next) - 1; i >= 0; i-- {
+		if next[i] != ^uint32(0) {
+			next[i]++
+			return next[:i+1]
+		}
+		// the last part can't be incremented, move up a level
+	}
+	return Oid{}
+}
+
+//
+// Helpers for oids as dotted strings, as found in SnmpPDU.Name
+//
+
+// CompareOids compares two dotted oids numerically part by part, as GETNEXT
+// orders them (so ".1.3.6.1.10" comes after ".1.3.6.1.9"), without
+// allocating. It returns -1 if a comes before b, 0 if they are equal, and +1
+// if a comes after b. A leading dot is optional.
+func CompareOids(a, b string) int {
+	a, b = trimOidDot(a), trimOidDot(b)
+	for a != "" && b != "" {
+		var na, nb uint64
+		na, a = nextOidPart(a)
+		nb, b = nextOidPart(b)
+		if na != nb {
+			if na < nb {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case a == "" && b != "":
+		return -1
+	case a != "" && b == "":
+		return 1
+	}
+	return 0
+}
+
+// SortPDUs sorts pdus into oid order, see CompareOids
+func SortPDUs(pdus []SnmpPDU) {
+	sort.SliceStable(pdus, func(i, j int) bool {
+		return CompareOids(pdus[i].Name, pdus[j].Name) < 0
+	})
+}
+
+// OidInSubtree reports whether oid is root or lies in the subtree below
+// root. Unlike strings.HasPrefix, ".1.3.6.1.21" is not in the subtree of
+// ".1.3.6.1.2".
+func OidInSubtree(oid, root string) bool {
+	oid, root = trimOidDot(oid), trimOidDot(root)
+	if root == "" {
+		return true
+	}
+	if len(oid) < len(root) || oid[:len(root)] != root {
+		return false
+	}
+	return len(oid) == len(root) || oid[len(root)] == '.'
+}
+
+// NextSiblingOid returns the oid following oid and its whole subtree, see
+// Oid.NextSibling.
+func NextSiblingOid(oid string) (string, error) {
+	o, err := ParseOid(oid)
+	if err != nil {
+		return "", err
+	}
+	return o.NextSibling().String(), nil
+}
+
+func trimOidDot(oid string) string {
+	if len(oid) > 0 && oid[0] == '.' {
+		return oid[1:]
+	}
+	return oid
+}
+
+// nextOidPart returns the number at the start of oid, and the rest of oid
+// after the following dot. Anything other than digits is skipped.
+func nextOidPart(oid string) (uint64, string) {
+	var n uint64
+	i := 0
+	for ; i < len(oid) && oid[i] != '.'; i++ {
+		if c := oid[i]; c >= '0' && c <= '9' {
+			n = n*10 + uint64(c-'0')
+		}
+	}
+	if i < len(oid) {
+		i++
+	}
+	return n, oid[i:]
+}
@@ -0,0 +1,278 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestParseOid(t *testing.T) {
+	for _, tt := range []struct {
+		in     string
+		want   Oid
+		str    string
+		hasErr bool
+	}{
+		{".1.3.6.1.2.1.1.1.0", Oid{1, 3, 6, 1, 2, 1, 1, 1, 0}, ".1.3.6.1.2.1.1.1.0", false},
+		{"1.3.6.1.4.1.4294967295", Oid{1, 3, 6, 1, 4, 1, 4294967295}, ".1.3.6.1.4.1.4294967295", false},
+		{"1", Oid{1}, ".1", false},
+		{"", nil, "", true},
+		{".", nil, "", true},
+		{".1..3", nil, "", true},
+		{".1.3.", nil, "", true},
+		{".1.3.x", nil, "", true},
+		{".1.3.4294967296", nil, "", true},
+	} {
+		got, err := ParseOid(tt.in)
+		if (err != nil) != tt.hasErr {
+			t.Errorf("ParseOid(%q) err: %v", tt.in, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("ParseOid(%q) got %v, want %v", tt.in, got, tt.want)
+		}
+		if !tt.hasErr && got.String() != tt.str {
+			t.Errorf("ParseOid(%q).String() got %q, want %q", tt.in, got.String(), tt.str)
+		}
+	}
+}
+
+func TestOidCompare(t *testing.T) {
+	for _, tt := range []struct {
+		a, b      Oid
+		compare   int
+		hasPrefix bool
+	}{
+		{Oid{1, 3, 6}, Oid{1, 3, 6}, 0, true},
+		{Oid{1, 3, 6, 1}, Oid{1, 3, 6}, 1, true},
+		{Oid{1, 3, 6}, Oid{1, 3, 6, 1}, -1, false},
+		{Oid{1, 3, 10}, Oid{1, 3, 9, 1}, 1, false},
+		{Oid{1, 3, 2, 5}, Oid{1, 3, 10}, -1, false},
+		{Oid{}, Oid{}, 0, true},
+	} {
+		if got := tt.a.Compare(tt.b); got != tt.compare {
+			t.Errorf("%v.Compare(%v) got %d, want %d", tt.a, tt.b, got, tt.compare)
+		}
+		if got := tt.a.HasPrefix(tt.b); got != tt.hasPrefix {
+			t.Errorf("%v.HasPrefix(%v) got %v, want %v", tt.a, tt.b, got, tt.hasPrefix)
+		}
+	}
+}
+
+func TestMarshalOid(t *testing.T) {
+	oid := Oid{1, 3, 6, 1, 4, 1, 2021, 300, 4294967295}
+	fromOid, err := marshalOid(oid)
+	if err != nil {
+		t.Fatalf("marshalOid() err: %v", err)
+	}
+	fromString, err := marshalOID(oid.String())
+	if err != nil {
+		t.Fatalf("marshalOID() err: %v", err)
+	}
+	if !bytes.Equal(fromOid, fromString) {
+		t.Errorf("marshalOid() got % x, marshalOID() got % x", fromOid, fromString)
+	}
+	parsed, err := parseOid(fromOid)
+	if err != nil {
+		t.Fatalf("parseOid() err: %v", err)
+	}
+	if !parsed.Equal(oid) {
+		t.Errorf("parseOid() got %v, want %v", parsed, oid)
+	}
+	// .1.3.4294967296
+	if parsed, err = parseOid([]byte{0x2b, 0x90, 0x80, 0x80, 0x80, 0x00}); err == nil {
+		t.Errorf("parseOid() of a part beyond 2^32-1 got %v", parsed)
+	}
+
+	a, err := marshalVarbind(&SnmpPDU{Name: ".1.3.6.1.2.1.1.2.0", Type: ObjectIdentifier, Value: oid})
+	if err != nil {
+		t.Fatalf("marshalVarbind(Oid) err: %v", err)
+	}
+	b, err := marshalVarbind(&SnmpPDU{Name: ".1.3.6.1.2.1.1.2.0", Type: ObjectIdentifier, Value: oid.String()})
+	if err != nil {
+		t.Fatalf("marshalVarbind(string) err: %v", err)
+	}
+	if !bytes.Equal(a, b) {
+		t.Errorf("marshalVarbind() of an Oid value got % x, want % x", a, b)
+	}
+}
+
+func TestNameOid(t *testing.T) {
+	oid := Oid{1, 3, 6, 1, 4, 1, 2021, 300, 4294967295}
+	fromOid, err := marshalVarbind(&SnmpPDU{NameOid: oid, Type: Null})
+	if err != nil {
+		t.Fatalf("marshalVarbind(NameOid) err: %v", err)
+	}
+	fromName, err := marshalVarbind(&SnmpPDU{Name: oid.String(), Type: Null})
+	if err != nil {
+		t.Fatalf("marshalVarbind(Name) err: %v", err)
+	}
+	if !bytes.Equal(fromOid, fromName) {
+		t.Errorf("marshalVarbind() of a NameOid got % x, want % x", fromOid, fromName)
+	}
+	both, _ := marshalVarbind(&SnmpPDU{Name: oid.String(), NameOid: Oid{1, 3, 6, 1, 2, 1, 1, 5, 0}, Type: Null})
+	if !bytes.Equal(both, fromName) {
+		t.Errorf("marshalVarbind() of a Name and NameOid got % x, want the Name's % x", both, fromName)
+	}
+
+	x := &GoSNMP{Version: Version2c, Community: "public"}
+	out, err := x.mkSnmpPacket(GetResponse, []SnmpPDU{{Name: oid.String(), Type: Integer, Value: 1}}, 0, 0).marshalMsg()
+	if err != nil {
+		t.Fatalf("marshalMsg() err: %v", err)
+	}
+	result, err := x.Decode(out)
+	if err != nil {
+		t.Fatalf("Decode() err: %v", err)
+	}
+	pdu := result.Variables[0]
+	if pdu.Name != oid.String() || !pdu.NameOid.Equal(oid) {
+		t.Errorf("decoded Name %s, NameOid %v, want %s", pdu.Name, pdu.NameOid, oid)
+	}
+	if got, err := pdu.Oid(); err != nil || !got.Equal(oid) {
+		t.Errorf("Oid() = %v, %v, want %v", got, err, oid)
+	}
+
+	// a Name changed since takes precedence
+	pdu.Name = ".1.3.6.1.2.1.1.5.0"
+	if got, err := pdu.Oid(); err != nil || got.String() != pdu.Name {
+		t.Errorf("Oid() of a changed Name = %v, %v, want %s", got, err, pdu.Name)
+	}
+	pdu.Name = ""
+	if got, err := pdu.Oid(); err != nil || !got.Equal(oid) {
+		t.Errorf("Oid() without a Name = %v, %v, want %v", got, err, oid)
+	}
+}
+
+func TestOidValues(t *testing.T) {
+	oid := Oid{1, 3, 6, 1, 4, 1, 2021, 300, 4294967295}
+	contents, err := marshalOid(oid)
+	if err != nil {
+		t.Fatalf("marshalOid() err: %v", err)
+	}
+	tlv := append([]byte{byte(ObjectIdentifier), byte(len(contents))}, contents...)
+
+	var want []byte
+	for _, oidValues := range []bool{false, true} {
+		x := &GoSNMP{OidValues: oidValues}
+		v, err := x.decodeValue(tlv, "value")
+		if err != nil {
+			t.Fatalf("OidValues %v: decodeValue() err: %v", oidValues, err)
+		}
+		if _, isOid := v.Value.(Oid); isOid != oidValues {
+			t.Errorf("OidValues %v: decodeValue() got %T", oidValues, v.Value)
+		}
+		pdu := SnmpPDU{Name: ".1.3.6.1.2.1.1.2.0", Type: v.Type, Value: v.Value}
+		if got, err := pdu.OidValue(); err != nil || !got.Equal(oid) {
+			t.Errorf("OidValues %v: OidValue() = %v, %v, want %v", oidValues, got, err, oid)
+		}
+		if got, err := pdu.Text(); err != nil || got != oid.String() {
+			t.Errorf("OidValues %v: Text() = %q, %v, want %q", oidValues, got, err, oid.String())
+		}
+		j, err := pdu.MarshalJSON()
+		if err != nil {
+			t.Fatalf("OidValues %v: MarshalJSON() err: %v", oidValues, err)
+		}
+		if want == nil {
+			want = j
+		} else if !bytes.Equal(j, want) {
+			t.Errorf("OidValues %v: MarshalJSON() = %s, want %s", oidValues, j, want)
+		}
+	}
+}
+
+func TestCompareOids(t *testing.T) {
+	for _, tt := range []struct {
+		a, b string
+		want int
+	}{
+		{".1.3.6.1.9", ".1.3.6.1.10", -1},
+		{".1.3.6.1.10", ".1.3.6.1.9", 1},
+		{".1.3.6.1", "1.3.6.1", 0},
+		{".1.3.6", ".1.3.6.1", -1},
+		{".1.3.6.1", ".1.3.6", 1},
+		{"", ".1", -1},
+		{"", "", 0},
+	} {
+		if got := CompareOids(tt.a, tt.b); got != tt.want {
+			t.Errorf("CompareOids(%q, %q) got %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestSortPDUs(t *testing.T) {
+	pdus := []SnmpPDU{{Name: ".1.3.6.1.10"}, {Name: ".1.3.6.1.9.1"}, {Name: ".1.3.6.1.9"}, {Name: ".1.3.6.1.2"}}
+	SortPDUs(pdus)
+	want := []string{".1.3.6.1.2", ".1.3.6.1.9", ".1.3.6.1.9.1", ".1.3.6.1.10"}
+	for i := range want {
+		if pdus[i].Name != want[i] {
+			t.Errorf("SortPDUs() [%d] got %s, want %s", i, pdus[i].Name, want[i])
+		}
+	}
+}
+
+func TestOidInSubtree(t *testing.T) {
+	for _, tt := range []struct {
+		oid, root string
+		want      bool
+	}{
+		{".1.3.6.1.2.1", ".1.3.6.1.2", true},
+		{".1.3.6.1.2", ".1.3.6.1.2", true},
+		{"1.3.6.1.2.1", ".1.3.6.1.2", true},
+		{".1.3.6.1.21", ".1.3.6.1.2", false},
+		{".1.3.6.1", ".1.3.6.1.2", false},
+		{".1.3.6.1", "", true},
+	} {
+		if got := OidInSubtree(tt.oid, tt.root); got != tt.want {
+			t.Errorf("OidInSubtree(%q, %q) got %v, want %v", tt.oid, tt.root, got, tt.want)
+		}
+	}
+}
+
+func TestNextSiblingOid(t *testing.T) {
+	for _, tt := range []struct {
+		in, want string
+	}{
+		{".1.3.6.1.2.1.2.2", ".1.3.6.1.2.1.2.3"},
+		{"1.3.6.1.9", ".1.3.6.1.10"},
+		{".1.3.6.4294967295", ".1.3.7"},
+	} {
+		got, err := NextSiblingOid(tt.in)
+		if err != nil {
+			t.Errorf("NextSiblingOid(%q) err: %v", tt.in, err)
+		} else if got != tt.want {
+			t.Errorf("NextSiblingOid(%q) got %q, want %q", tt.in, got, tt.want)
+		}
+		if CompareOids(tt.in+".1", tt.want) >= 0 {
+			t.Errorf("NextSiblingOid(%q): %q is not after the subtree", tt.in, tt.want)
+		}
+	}
+	if _, err := NextSiblingOid("1.x"); err == nil {
+		t.Errorf("NextSiblingOid() of invalid oid: expected error")
+	}
+}
+
+func TestWalkNotIncreasing(t *testing.T) {
+	r := newTestResponder(t, nil)
+	defer r.Close()
+	r.setHandler(func(req *SnmpPacket) *SnmpPacket {
+		// go backwards, without repeating an oid
+		next := ".1.3.6.1.2.1.5.0"
+		if req.Variables[0].Name != ".1.3.6.1.2.1" {
+			next = ".1.3.6.1.2.1.3.0"
+		}
+		return &SnmpPacket{Variables: []SnmpPDU{{Name: next, Type: Integer, Value: 1}}}
+	})
+	x := r.client(t)
+	defer x.Conn.Close()
+
+	results, err := x.WalkAll(".1.3.6.1.2.1")
+	if err == nil {
+		t.Fatalf("WalkAll() of an agent going backwards: expected error")
+	}
+	if len(results) != 1 {
+		t.Errorf("WalkAll() of an agent going backwards: got %d results before the error, want 1", len(results))
+	}
+}
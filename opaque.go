@@ -0,0 +1,86 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+//
+// Floats in Opaques
+//
+// SNMP has no floating point type, so net-snmp (and the sensor MIBs that
+// followed it, eg UCD-SNMP-MIB::laLoadFloat) wrap one in an Opaque: the
+// Opaque's contents are themselves BER encoded, with the application tags
+// 120 (Float, 4 bytes) and 121 (Double, 8 bytes) in extended tag form, so a
+// float starts 9f 78 04 and a double 9f 79 08; the value follows as IEEE 754
+// big endian.
+//
+// Received floats and doubles are returned with the types OpaqueFloat and
+// OpaqueDouble and a float32 or float64 Value. Other Opaques are returned
+// as type Opaque with their contents as a []byte.
+//
+
+const opaqueTagExtension = 0x9f
+
+// parseOpaque decodes the contents of an Opaque
+func parseOpaque(b []byte) (Asn1BER, interface{}) {
+	if len(b) >= 3 && b[0] == opaqueTagExtension {
+		switch {
+		case b[1] == OpaqueFloat && b[2] == 4 && len(b) == 7:
+			return OpaqueFloat, math.Float32frombits(binary.BigEndian.Uint32(b[3:]))
+		case b[1] == OpaqueDouble && b[2] == 8 && len(b) == 11:
+			return OpaqueDouble, math.Float64frombits(binary.BigEndian.Uint64(b[3:]))
+		}
+	}
+	return Opaque, append([]byte(nil), b...)
+}
+
+// marshalOpaque encodes the contents of an Opaque. An OpaqueFloat Value
+// must be a float32 and an OpaqueDouble a float64 (or either for both, if
+// the value fits); an Opaque Value is the []byte contents.
+func marshalOpaque(t Asn1BER, value interface{}) ([]byte, error) {
+	var f float64
+	switch value := value.(type) {
+	case []byte:
+		if t == Opaque {
+			return value, nil
+		}
+		return nil, fmt.Errorf("Unable to marshal PDU %s; not float32 or float64.", opaqueTypeName(t))
+	case float32:
+		f = float64(value)
+	case float64:
+		f = value
+		if t == OpaqueFloat && float64(float32(f)) != f && !math.IsNaN(f) {
+			return nil, fmt.Errorf("Unable to marshal PDU OpaqueFloat; %v doesn't fit a float32", value)
+		}
+	default:
+		if t == Opaque {
+			return nil, fmt.Errorf("Unable to marshal PDU Opaque; not []byte.")
+		}
+		return nil, fmt.Errorf("Unable to marshal PDU %s; not float32 or float64.", opaqueTypeName(t))
+	}
+
+	switch t {
+	case OpaqueFloat:
+		b := []byte{opaqueTagExtension, OpaqueFloat, 4, 0, 0, 0, 0}
+		binary.BigEndian.PutUint32(b[3:], math.Float32bits(float32(f)))
+		return b, nil
+	case OpaqueDouble:
+		b := []byte{opaqueTagExtension, OpaqueDouble, 8, 0, 0, 0, 0, 0, 0, 0, 0}
+		binary.BigEndian.PutUint64(b[3:], math.Float64bits(f))
+		return b, nil
+	}
+	return nil, fmt.Errorf("Unable to marshal PDU Opaque; not []byte.")
+}
+
+func opaqueTypeName(t Asn1BER) string {
+	if t == OpaqueFloat {
+		return "OpaqueFloat"
+	}
+	return "OpaqueDouble"
+}
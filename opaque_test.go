@@ -0,0 +1,66 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestOpaque(t *testing.T) {
+	x := &GoSNMP{}
+	tests := []struct {
+		typ     Asn1BER
+		value   interface{}
+		encoded []byte // the encoded varbind value
+	}{
+		{OpaqueFloat, float32(1.5), []byte{0x44, 0x07, 0x9f, 0x78, 0x04, 0x3f, 0xc0, 0x00, 0x00}},
+		{OpaqueDouble, float64(-2.25), []byte{0x44, 0x0b, 0x9f, 0x79, 0x08, 0xc0, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}},
+		{Opaque, []byte{0x01, 0x02}, []byte{0x44, 0x02, 0x01, 0x02}},
+		// a float of the wrong length stays an Opaque
+		{Opaque, []byte{0x9f, 0x78, 0x03, 0x00, 0x00, 0x00}, []byte{0x44, 0x06, 0x9f, 0x78, 0x03, 0x00, 0x00, 0x00}},
+	}
+	for _, test := range tests {
+		pdu := SnmpPDU{Name: ".1.3.6.1.4.1.2021.10.1.6.1", Type: test.typ, Value: test.value}
+		got, err := marshalVarbind(&pdu)
+		if err != nil {
+			t.Errorf("%v: %v", test.value, err)
+			continue
+		}
+		if !bytes.HasSuffix(got, test.encoded) {
+			t.Errorf("%v: got % x, want suffix % x", test.value, got, test.encoded)
+		}
+
+		v, err := x.decodeValue(test.encoded, "value")
+		if err != nil {
+			t.Errorf("%v: decode: %v", test.value, err)
+			continue
+		}
+		if v.Type != test.typ || !reflect.DeepEqual(v.Value, test.value) {
+			t.Errorf("%v: decoded %v %#v", test.value, v.Type, v.Value)
+		}
+	}
+
+	// a float64 that fits may be sent as an OpaqueFloat
+	pdu := SnmpPDU{Name: ".1.3.6.1.4.1.2021.10.1.6.1", Type: OpaqueFloat, Value: float64(0.5)}
+	if _, err := marshalVarbind(&pdu); err != nil {
+		t.Errorf("float64 0.5 as OpaqueFloat: %v", err)
+	}
+	for _, bad := range []SnmpPDU{
+		{Name: ".1.3.6.1.4.1.2021.10.1.6.1", Type: OpaqueFloat, Value: float64(0.1)},
+		{Name: ".1.3.6.1.4.1.2021.10.1.6.1", Type: OpaqueDouble, Value: "1.5"},
+		{Name: ".1.3.6.1.4.1.2021.10.1.6.1", Type: OpaqueFloat, Value: []byte{1}},
+		{Name: ".1.3.6.1.4.1.2021.10.1.6.1", Type: Opaque, Value: 1.5},
+	} {
+		if _, err := marshalVarbind(&bad); err == nil {
+			t.Errorf("%v %#v: expected an error", bad.Type, bad.Value)
+		}
+	}
+
+	if _, err := x.decodeValue([]byte{0x44, 0x07, 0x9f}, "value"); err == nil {
+		t.Errorf("expected an error decoding a truncated Opaque")
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"context"
+	"time"
+)
+
+// RequestOption overrides a setting of a GoSNMP for the requests made with
+// a context, see WithRequestOptions
+type RequestOption func(*requestOptions)
+
+// requestOptions are the settings overridden for a context, nil if not
+type requestOptions struct {
+	timeout          *time.Duration
+	retries          *int
+	maxRepetitions   *uint8
+	operationTimeout *time.Duration
+	retryPolicy      RetryPolicy
+	community        *string
+	contextName      *string
+	contextEngineID  *string
+}
+
+type requestOptionsKey struct{}
+
+// WithRequestOptions returns a copy of ctx that makes the requests of the
+// Ctx methods it is passed to (GetCtx, SetCtx, WalkCtx, BulkWalkCtx and so
+// on) use the given settings instead of their GoSNMP's. This is how to
+// change the settings for a single call: changing the fields of a GoSNMP
+// in use by other goroutines is a data race. eg
+//
+//	ctx := gosnmp.WithRequestOptions(ctx, gosnmp.RequestTimeout(10*time.Second),
+//		gosnmp.RequestMaxRepetitions(10))
+//	err := x.BulkWalkCtx(ctx, ".1.3.6.1.2.1.31.1.1", walkFn)
+//
+// Options already on ctx are kept unless overridden.
+func WithRequestOptions(ctx context.Context, opts ...RequestOption) context.Context {
+	o := optionsFrom(ctx)
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return context.WithValue(ctx, requestOptionsKey{}, o)
+}
+
+// RequestTimeout overrides GoSNMP.Timeout
+func RequestTimeout(timeout time.Duration) RequestOption {
+	return func(o *requestOptions) {
+		o.timeout = &timeout
+	}
+}
+
+// RequestRetries overrides GoSNMP.Retries
+func RequestRetries(retries int) RequestOption {
+	return func(o *requestOptions) {
+		o.retries = &retries
+	}
+}
+
+// RequestMaxRepetitions overrides GoSNMP.MaxRepetitions for bulk walks
+func RequestMaxRepetitions(maxRepetitions uint8) RequestOption {
+	return func(o *requestOptions) {
+		o.maxRepetitions = &maxRepetitions
+	}
+}
+
+// RequestRetryPolicy overrides GoSNMP.RetryPolicy, and takes precedence
+// over RequestTimeout and RequestRetries
+func RequestRetryPolicy(policy RetryPolicy) RequestOption {
+	return func(o *requestOptions) {
+		o.retryPolicy = policy
+	}
+}
+
+// RequestCommunity overrides GoSNMP.Community for SNMPv1 and SNMPv2c
+// requests, eg to poll the contexts of an agent that are chosen by
+// community (as with "public@10" for a vlan) on one connection. Gets with
+// it don't use the Cache, which doesn't tell communities apart.
+func RequestCommunity(community string) RequestOption {
+	return func(o *requestOptions) {
+		o.community = &community
+	}
+}
+
+// RequestContextName overrides GoSNMP.ContextName for SNMPv3 requests, eg
+// to poll the VRFs or bridge instances of a device, each its own context,
+// on one session. Gets with it don't use the Cache.
+func RequestContextName(name string) RequestOption {
+	return func(o *requestOptions) {
+		o.contextName = &name
+	}
+}
+
+// RequestContextEngineID overrides GoSNMP.ContextEngineID for SNMPv3
+// requests, eg for the contexts of the agents behind a proxy. An empty
+// engine ID is that of the authoritative engine, once discovered. Gets
+// with it don't use the Cache.
+func RequestContextEngineID(engineID string) RequestOption {
+	return func(o *requestOptions) {
+		o.contextEngineID = &engineID
+	}
+}
+
+func optionsFrom(ctx context.Context) requestOptions {
+	o, _ := ctx.Value(requestOptionsKey{}).(requestOptions)
+	return o
+}
+
+// maxRepetitions returns the GETBULK max-repetitions for walks made with
+// ctx
+func (x *GoSNMP) maxRepetitions(ctx context.Context) uint8 {
+	maxReps := x.MaxRepetitions
+	if o := optionsFrom(ctx); o.maxRepetitions != nil {
+		maxReps = *o.maxRepetitions
+	}
+	if maxReps == 0 {
+		maxReps = defaultMaxRepetitions
+	}
+	return maxReps
+}
+
+// scoped reports whether the options change the community or the SNMPv3
+// context of requests, whose values the Cache doesn't tell apart
+func (o requestOptions) scoped() bool {
+	return o.community != nil || o.contextName != nil || o.contextEngineID != nil
+}
+
+// scope sets the community or the SNMPv3 context of packet, a request, to
+// those of the options
+func (o requestOptions) scope(packet *SnmpPacket) {
+	if packet.Version != Version3 {
+		if o.community != nil {
+			packet.Community = *o.community
+		}
+		return
+	}
+	if o.contextName != nil {
+		packet.ContextName = *o.contextName
+	}
+	if o.contextEngineID != nil {
+		packet.ContextEngineID = *o.contextEngineID
+	}
+}
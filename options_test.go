@@ -0,0 +1,129 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRequestOptions(t *testing.T) {
+	const column = ".1.3.6.1.2.1.2.2.1.2"
+	var pdus []SnmpPDU
+	for i := 1; i <= 5; i++ {
+		pdus = append(pdus, SnmpPDU{Name: column + "." + string(rune('0'+i)), Type: Integer, Value: i})
+	}
+	r := newTestResponder(t, pdus)
+	defer r.Close()
+	x := r.client(t)
+	defer x.Conn.Close()
+	x.Timeout = 5 * time.Second
+	x.Retries = 3
+
+	// a short timeout without retries, for a silent agent
+	r.setHandler(func(req *SnmpPacket) *SnmpPacket { return nil })
+	ctx := WithRequestOptions(context.Background(), RequestTimeout(30*time.Millisecond), RequestRetries(0))
+	start := time.Now()
+	_, err := x.GetCtx(ctx, []string{column + ".1"})
+	if !errors.Is(err, ErrTimeout) || time.Since(start) > time.Second {
+		t.Errorf("GetCtx() err: %v after %v, want a timeout after 30ms", err, time.Since(start))
+	}
+	if got := len(r.received()); got != 1 {
+		t.Errorf("got %d requests, want 1", got)
+	}
+	if x.Timeout != 5*time.Second || x.Retries != 3 {
+		t.Errorf("GoSNMP settings changed to %v and %d", x.Timeout, x.Retries)
+	}
+
+	// options are kept when more are added, and a policy beats them
+	ctx = WithRequestOptions(ctx, RequestRetries(2))
+	if p := x.retryPolicy(ctx).(*ExponentialBackoff); p.Attempts != 3 || p.MaxElapsed != 30*time.Millisecond {
+		t.Errorf("got %+v, want 3 attempts within 30ms", p)
+	}
+	policy := &fixedRetries{waits: []time.Duration{time.Millisecond}}
+	if p := x.retryPolicy(WithRequestOptions(ctx, RequestRetryPolicy(policy))); p != RetryPolicy(policy) {
+		t.Errorf("got %+v, want %+v", p, policy)
+	}
+
+	// max-repetitions for a bulk walk
+	r.setHandler(nil)
+	seen := len(r.received())
+	ctx = WithRequestOptions(context.Background(), RequestMaxRepetitions(2))
+	results, err := x.BulkWalkAllCtx(ctx, column)
+	if err != nil || len(results) != len(pdus) {
+		t.Fatalf("BulkWalkAllCtx() got %d values, err: %v", len(results), err)
+	}
+	for _, req := range r.received()[seen:] {
+		if req.MaxRepetitions != 2 {
+			t.Errorf("got max-repetitions %d, want 2", req.MaxRepetitions)
+		}
+	}
+	if got := x.maxRepetitions(context.Background()); got != defaultMaxRepetitions {
+		t.Errorf("got max-repetitions %d without options, want %d", got, defaultMaxRepetitions)
+	}
+
+	// a community for one call, which isn't answered from the Cache
+	x.Cache = &Cache{TTL: time.Minute}
+	if _, err = x.Get([]string{column + ".1"}); err != nil {
+		t.Fatalf("Get() err: %v", err)
+	}
+	seen = len(r.received())
+	ctx = WithRequestOptions(context.Background(), RequestCommunity("public@10"))
+	if _, err = x.GetCtx(ctx, []string{column + ".1"}); err != nil {
+		t.Fatalf("GetCtx() err: %v", err)
+	}
+	if _, err = x.WalkAllCtx(ctx, column); err != nil {
+		t.Fatalf("WalkAllCtx() err: %v", err)
+	}
+	received := r.received()[seen:]
+	if len(received) == 0 {
+		t.Fatalf("GetCtx() with RequestCommunity was answered from the Cache")
+	}
+	for _, req := range received {
+		if req.Community != "public@10" {
+			t.Errorf("got community %q, want public@10", req.Community)
+		}
+	}
+	if x.Community != "public" {
+		t.Errorf("GoSNMP Community changed to %q", x.Community)
+	}
+}
+
+func TestRequestContext(t *testing.T) {
+	a, _ := newTestAgent(t)
+	a.EngineID = "engine"
+	a.Users = []*UsmSecurityParameters{newTestMsgSizeSession(0).SecurityParameters.(*UsmSecurityParameters)}
+	defer a.Close()
+	x := startTestAgent(t, a, newTestMsgSizeSession(0))
+	defer x.Conn.Close()
+	x.ContextName = "default"
+
+	tests := []struct {
+		opts           []RequestOption
+		name, engineID string
+	}{
+		{nil, "default", "engine"},
+		{[]RequestOption{RequestContextName("vrf-blue")}, "vrf-blue", "engine"},
+		{[]RequestOption{RequestContextName(""), RequestContextEngineID("proxied")}, "", "proxied"},
+		// a community is of no use to SNMPv3
+		{[]RequestOption{RequestCommunity("other")}, "default", "engine"},
+	}
+	for i, test := range tests {
+		result, err := x.GetCtx(WithRequestOptions(context.Background(), test.opts...), []string{testSysDescr})
+		if err != nil {
+			t.Errorf("%d: GetCtx() err: %v", i, err)
+			continue
+		}
+		if result.ContextName != test.name || result.ContextEngineID != test.engineID {
+			t.Errorf("%d: got context %q of engine %q, want %q of %q",
+				i, result.ContextName, result.ContextEngineID, test.name, test.engineID)
+		}
+	}
+	if x.ContextName != "default" || x.ContextEngineID != "engine" {
+		t.Errorf("GoSNMP context changed to %q of engine %q", x.ContextName, x.ContextEngineID)
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import "fmt"
+
+// OidOrder is what a walk does when the agent returns an oid that isn't
+// after the one before it, as some broken agents do, see GoSNMP.OidOrder
+type OidOrder int
+
+const (
+	// OidOrderStrict fails the walk with an "OID not increasing" error
+	OidOrderStrict OidOrder = iota
+
+	// OidOrderSkip ignores the varbind, eg a duplicate, and carries on
+	// from the last oid that was in order
+	OidOrderSkip
+
+	// OidOrderTolerate reports the varbind unless the walk has already
+	// reported its oid, and carries on from it. The oids of the walk are
+	// kept to detect loops; after OidOrderLimit out of order oids the
+	// walk fails.
+	OidOrderTolerate
+)
+
+// defaultOidOrderLimit is the default GoSNMP.OidOrderLimit
+const defaultOidOrderLimit = 100
+
+// walkOrder applies GoSNMP.OidOrder to the oids returned in a walk
+type walkOrder struct {
+	policy  OidOrder
+	limit   int
+	prev    string          // the last oid accepted
+	late    int             // the out of order oids accepted
+	visited map[string]bool // for OidOrderTolerate
+}
+
+// newWalkOrder returns the walkOrder for a walk starting after start
+func (x *GoSNMP) newWalkOrder(start string) *walkOrder {
+	o := &walkOrder{policy: x.OidOrder, limit: x.OidOrderLimit, prev: start}
+	if o.limit <= 0 {
+		o.limit = defaultOidOrderLimit
+	}
+	if o.policy == OidOrderTolerate {
+		o.visited = make(map[string]bool)
+	}
+	return o
+}
+
+// accept reports whether the walk should report the varbind name, which
+// followed the last one accepted
+func (o *walkOrder) accept(name string) (bool, error) {
+	if o.visited[name] {
+		return false, nil
+	}
+	if CompareOids(name, o.prev) <= 0 {
+		switch o.policy {
+		case OidOrderSkip:
+			return false, nil
+		case OidOrderTolerate:
+			if o.late++; o.late > o.limit {
+				return false, fmt.Errorf("OID not increasing: %s, after %d others", name, o.limit)
+			}
+		default:
+			return false, fmt.Errorf("OID not increasing: %s", name)
+		}
+	}
+	o.prev = name
+	if o.visited != nil {
+		o.visited[name] = true
+	}
+	return true, nil
+}
+
+// loopError is the error for a response with no varbinds accepted after
+// oid, which asking again would only repeat
+func loopError(oid string) error {
+	return fmt.Errorf("Walk loop: no new OIDs after %s", oid)
+}
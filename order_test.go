@@ -0,0 +1,119 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestOidOrder(t *testing.T) {
+	const root = ".1.3.6.1.2.1.2.2.1.2"
+	r := newTestResponder(t, nil)
+	defer r.Close()
+
+	tests := []struct {
+		name   string
+		bulk   bool
+		policy OidOrder
+		limit  int
+		script map[string]string // the rows returned after each row, or after the root
+		want   string
+		err    string
+	}{
+		{"strict", true, OidOrderStrict, 0,
+			map[string]string{"": "1 2 2 3"},
+			"1 2", "OID not increasing: " + root + ".2"},
+		{"skip", true, OidOrderSkip, 0,
+			map[string]string{"": "1 2 2 3", "3": "4 1 5", "5": ""},
+			"1 2 3 4 5", ""},
+		{"skip loop", false, OidOrderSkip, 0,
+			map[string]string{"": "1", "1": "1"},
+			"1", "Walk loop: no new OIDs after " + root + ".1"},
+		{"tolerate", false, OidOrderTolerate, 0,
+			map[string]string{"": "1", "1": "3", "3": "2", "2": "4", "4": ""},
+			"1 3 2 4", ""},
+		{"tolerate bulk", true, OidOrderTolerate, 0,
+			map[string]string{"": "1 3 2 3 4", "4": "5", "5": ""},
+			"1 3 2 4 5", ""},
+		{"tolerate loop", false, OidOrderTolerate, 0,
+			map[string]string{"": "1", "1": "2", "2": "1"},
+			"1 2", "Walk loop: no new OIDs after " + root + ".2"},
+		{"tolerate limit", false, OidOrderTolerate, 1,
+			map[string]string{"": "3", "3": "2", "2": "1"},
+			"3 2", "OID not increasing: " + root + ".1, after 1 others"},
+	}
+	for _, test := range tests {
+		script := test.script
+		r.setHandler(func(req *SnmpPacket) *SnmpPacket {
+			row := strings.TrimPrefix(strings.TrimPrefix(req.Variables[0].Name, root), ".")
+			rows, ok := script[row]
+			rsp := &SnmpPacket{}
+			for _, row := range strings.Fields(rows) {
+				rsp.Variables = append(rsp.Variables, SnmpPDU{Name: root + "." + row, Type: Integer, Value: 1})
+			}
+			if !ok || rows == "" {
+				rsp.Variables = append(rsp.Variables, SnmpPDU{Name: ".2.0", Type: Null})
+			}
+			return rsp
+		})
+		x := r.client(t)
+		x.OidOrder = test.policy
+		x.OidOrderLimit = test.limit
+
+		walks := map[string]func(walkFn WalkFunc) error{
+			"Walk": func(walkFn WalkFunc) error {
+				if test.bulk {
+					return x.BulkWalk(root, walkFn)
+				}
+				return x.Walk(root, walkFn)
+			},
+			"WalkFiltered": func(walkFn WalkFunc) error {
+				all := func(LazyPDU) bool { return true }
+				if test.bulk {
+					return x.BulkWalkFiltered(root, all, walkFn)
+				}
+				return x.WalkFiltered(root, all, walkFn)
+			},
+		}
+		for name, walk := range walks {
+			var got []string
+			err := walk(func(pdu SnmpPDU) error {
+				got = append(got, strings.TrimPrefix(pdu.Name, root+"."))
+				return nil
+			})
+			if !errorMatches(err, test.err) {
+				t.Errorf("%s: %s() err: %v, want %q", test.name, name, err, test.err)
+			}
+			if strings.Join(got, " ") != test.want {
+				t.Errorf("%s: %s() got %v, want %s", test.name, name, got, test.want)
+			}
+		}
+		x.Conn.Close()
+	}
+}
+
+func TestWalkOrderAccept(t *testing.T) {
+	x := &GoSNMP{OidOrder: OidOrderTolerate, OidOrderLimit: 2}
+	o := x.newWalkOrder(".1.3")
+	var got []string
+	for _, name := range []string{".1.3.2", ".1.3.1", ".1.3.2", ".1.3.3", ".1.3.0", ".1.3.0.1", ".1.3.0.0"} {
+		ok, err := o.accept(name)
+		got = append(got, fmt.Sprintf("%s %t %t", name, ok, err != nil))
+	}
+	want := []string{
+		".1.3.2 true false",
+		".1.3.1 true false", // out of order
+		".1.3.2 false false",
+		".1.3.3 true false",
+		".1.3.0 true false", // out of order
+		".1.3.0.1 true false",
+		".1.3.0.0 false true", // one out of order too many
+	}
+	if strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestParallelWalk(t *testing.T) {
+	var pdus []SnmpPDU
+	for column := 1; column <= 6; column++ {
+		for row := 1; row <= 5; row++ {
+			pdus = append(pdus, SnmpPDU{Name: fmt.Sprintf(".1.3.6.1.2.1.2.2.1.%d.%d", column, row), Type: Integer, Value: row})
+		}
+	}
+	pdus = append(pdus, SnmpPDU{Name: ".1.3.6.1.2.1.2.3.0", Type: Integer, Value: 0})
+	r := newTestResponder(t, pdus)
+	defer r.Close()
+	x := r.client(t)
+	defer x.Conn.Close()
+	x.MaxRepetitions = 2
+
+	var want []string
+	for _, pdu := range pdus[:30] {
+		want = append(want, pdu.Name)
+	}
+
+	tests := []struct {
+		root        string
+		parallelism int
+		want        []string
+	}{
+		{".1.3.6.1.2.1.2.2", 3, want},
+		{".1.3.6.1.2.1.2.2", 1, want}, // three columns, and the rest of the table
+		{".1.3.6.1.2.1.2.2.1.4", 2, want[15:20]},
+		{".1.3.6.1.2.1.2", 8, append(want[:30:30], ".1.3.6.1.2.1.2.3.0")},
+		{".1.3.6.1.2.1.3", 2, nil},
+	}
+	for _, test := range tests {
+		var names []string
+		err := x.ParallelWalk(test.root, test.parallelism, func(pdu SnmpPDU) error {
+			names = append(names, pdu.Name)
+			return nil
+		})
+		if err != nil {
+			t.Errorf("ParallelWalk(%s, %d) err: %v", test.root, test.parallelism, err)
+			continue
+		}
+		sort.Slice(names, func(i, j int) bool { return CompareOids(names[i], names[j]) < 0 })
+		if !reflect.DeepEqual(names, test.want) {
+			t.Errorf("ParallelWalk(%s, %d): got %v, want %v", test.root, test.parallelism, names, test.want)
+		}
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	err := x.ParallelWalk(".1.3.6.1.2.1.2.2", 3, func(pdu SnmpPDU) error {
+		calls++
+		return stop
+	})
+	if err != stop || calls != 1 {
+		t.Errorf("got err %v after %d calls, want stop after 1", err, calls)
+	}
+}
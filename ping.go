@@ -0,0 +1,39 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"context"
+	"time"
+)
+
+// Ping checks that the agent answers, with a GET of sysUpTime.0 sent once,
+// without retries, that times out after timeout (if 0, the session's
+// Timeout). It returns the round-trip time, or the error the request
+// failed with, eg one matching ErrTimeout or ErrPortUnreachable. Any
+// response is an answer: one with an error-status, an exception for
+// sysUpTime.0 or an SNMPv3 report. The round-trip of an SNMPv3 session's
+// first request includes the discovery of the engine. The Cache isn't
+// used.
+//
+// It is meant for checks before polling, and circuit breakers.
+func (x *GoSNMP) Ping(timeout time.Duration) (time.Duration, error) {
+	return x.PingCtx(context.Background(), timeout)
+}
+
+// PingCtx is like Ping, but the request is abandoned when ctx is cancelled
+// or its deadline passes, in which case ctx.Err() is returned.
+func (x *GoSNMP) PingCtx(ctx context.Context, timeout time.Duration) (time.Duration, error) {
+	opts := []RequestOption{RequestRetries(0), RequestRetryPolicy(nil)}
+	if timeout > 0 {
+		opts = append(opts, RequestTimeout(timeout))
+	}
+	ctx = WithRequestOptions(ctx, opts...)
+	start := time.Now()
+	if _, err := x.get(ctx, []string{sysUpTimeOid}); err != nil {
+		return 0, err
+	}
+	return time.Since(start), nil
+}
@@ -0,0 +1,63 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestPing(t *testing.T) {
+	a, _ := newTestAgent(t)
+	defer a.Close()
+	x := startTestAgent(t, a, nil)
+	defer x.Conn.Close()
+	// the agent has no sysUpTime, which is an answer all the same
+	rtt, err := x.Ping(0)
+	if err != nil || rtt <= 0 {
+		t.Errorf("Ping() = %s, %v", rtt, err)
+	}
+
+	silent, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket() err: %v", err)
+	}
+	defer silent.Close()
+	tests := []struct {
+		name string
+		port int
+		err  error
+	}{
+		{"silent", silent.LocalAddr().(*net.UDPAddr).Port, ErrTimeout},
+		{"closed", closedPort(t), ErrPortUnreachable},
+	}
+	for _, test := range tests {
+		x := &GoSNMP{
+			Target:    "127.0.0.1",
+			Port:      uint16(test.port),
+			Community: "public",
+			Version:   Version2c,
+			Timeout:   5 * time.Second,
+			Retries:   3,
+		}
+		if err := x.Connect(); err != nil {
+			t.Fatalf("Connect() err: %v", err)
+		}
+		start := time.Now()
+		_, err := x.Ping(100 * time.Millisecond)
+		x.Conn.Close()
+		if !errors.Is(err, test.err) {
+			t.Errorf("%s: Ping() err: %v, expected %v", test.name, err, test.err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("%s: Ping() took %s", test.name, elapsed)
+		}
+		if sent := x.Stats().PacketsSent; sent != 1 {
+			t.Errorf("%s: Ping() sent %d packets", test.name, sent)
+		}
+	}
+}
@@ -0,0 +1,270 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+//
+// Session pool
+//
+
+// Pool manages connected GoSNMP sessions for any number of targets. Sessions
+// are taken from the pool with Borrow, and handed back with Return once the
+// caller has finished with them (or with Discard if they are no longer
+// usable). Returned sessions are kept open, and reused by later calls to
+// Borrow for the same target, until they have been idle for IdleTimeout.
+//
+// The zero value is an empty pool creating sessions from Default. A Pool is
+// safe for concurrent use, but must not be copied after first use.
+type Pool struct {
+	// Template holds the parameters (Port, Community, Version, Timeout etc)
+	// used for the sessions created by the pool. Target is set from the
+	// argument to Borrow. If nil, Default is used.
+	Template *GoSNMP
+
+	// MaxConns is the maximum number of sessions, borrowed and idle, per
+	// target. When reached, Borrow waits for a session to be returned.
+	// Zero means no limit.
+	MaxConns int
+
+	// IdleTimeout is how long a returned session is kept open for reuse.
+	// Zero means sessions are kept until the pool is closed.
+	IdleTimeout time.Duration
+
+	// RateLimit is the maximum number of packets per second sent to each
+	// target, by all of its sessions together, in bursts of up to
+	// RateBurst. Zero means no limit. A global limit can be set with a
+	// RateLimiter in the Template's RateLimiters.
+	RateLimit float64
+	RateBurst int
+
+	mu       sync.Mutex
+	targets  map[string]*poolTarget
+	borrowed map[*GoSNMP]*poolTarget
+	closed   bool
+}
+
+// poolTarget holds the sessions for one target
+type poolTarget struct {
+	idle  []idleSession // most recently returned last
+	count int           // sessions open, borrowed or idle
+
+	// released is closed, and replaced, whenever a session is released,
+	// waking any Borrow waiting for one
+	released chan struct{}
+
+	limiter *RateLimiter // shared by the target's sessions, if RateLimit is set
+}
+
+type idleSession struct {
+	x     *GoSNMP
+	since time.Time
+}
+
+// Borrow returns a connected session for target, reusing an idle session if
+// there is one. The session must be given back with Return or Discard.
+func (p *Pool) Borrow(target string) (*GoSNMP, error) {
+	return p.BorrowCtx(context.Background(), target)
+}
+
+// BorrowCtx is like Borrow, but gives up waiting for a session when ctx is
+// done.
+func (p *Pool) BorrowCtx(ctx context.Context, target string) (*GoSNMP, error) {
+	for {
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return nil, fmt.Errorf("Pool is closed")
+		}
+		p.expireIdle(time.Now())
+		t := p.target(target)
+
+		if n := len(t.idle); n > 0 {
+			x := t.idle[n-1].x
+			t.idle[n-1] = idleSession{}
+			t.idle = t.idle[:n-1]
+			p.borrowed[x] = t
+			p.mu.Unlock()
+			return x, nil
+		}
+
+		if p.MaxConns <= 0 || t.count < p.MaxConns {
+			t.count++
+			p.mu.Unlock()
+			x, err := p.connect(target, t.limiter)
+			p.mu.Lock()
+			if err != nil {
+				p.release(t)
+				p.mu.Unlock()
+				return nil, err
+			}
+			p.borrowed[x] = t
+			p.mu.Unlock()
+			return x, nil
+		}
+
+		released := t.released
+		p.mu.Unlock()
+		select {
+		case <-released:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Return hands a session obtained from Borrow back to the pool for reuse.
+func (p *Pool) Return(x *GoSNMP) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	t, ok := p.borrowed[x]
+	if !ok {
+		return
+	}
+	delete(p.borrowed, x)
+	if p.closed {
+		x.Conn.Close()
+		p.release(t)
+		return
+	}
+	now := time.Now()
+	t.idle = append(t.idle, idleSession{x: x, since: now})
+	p.wake(t)
+	p.expireIdle(now)
+}
+
+// Discard closes a session obtained from Borrow instead of returning it to
+// the pool, eg after an error which leaves it unusable.
+func (p *Pool) Discard(x *GoSNMP) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	t, ok := p.borrowed[x]
+	if !ok {
+		return
+	}
+	delete(p.borrowed, x)
+	x.Conn.Close()
+	p.release(t)
+}
+
+// Close closes all idle sessions, and stops the pool lending any more.
+// Sessions still borrowed are closed when they are returned.
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	for _, t := range p.targets {
+		for _, s := range t.idle {
+			s.x.Conn.Close()
+			t.count--
+		}
+		t.idle = nil
+		p.wake(t)
+	}
+	return nil
+}
+
+// target returns the sessions for target, p.mu must be held
+func (p *Pool) target(target string) *poolTarget {
+	if p.targets == nil {
+		p.targets = make(map[string]*poolTarget)
+		p.borrowed = make(map[*GoSNMP]*poolTarget)
+	}
+	t, ok := p.targets[target]
+	if !ok {
+		t = &poolTarget{released: make(chan struct{})}
+		if p.RateLimit > 0 {
+			t.limiter = NewRateLimiter(p.RateLimit, p.RateBurst)
+		}
+		p.targets[target] = t
+	}
+	return t
+}
+
+// expireIdle closes sessions idle for longer than IdleTimeout, p.mu must be
+// held
+func (p *Pool) expireIdle(now time.Time) {
+	if p.IdleTimeout <= 0 {
+		return
+	}
+	for _, t := range p.targets {
+		// idle is ordered oldest first
+		n := 0
+		for n < len(t.idle) && now.Sub(t.idle[n].since) > p.IdleTimeout {
+			t.idle[n].x.Conn.Close()
+			t.idle[n] = idleSession{}
+			n++
+		}
+		if n > 0 {
+			t.idle = t.idle[n:]
+			t.count -= n
+			p.wake(t)
+		}
+	}
+}
+
+// release gives up one of a target's sessions, p.mu must be held
+func (p *Pool) release(t *poolTarget) {
+	t.count--
+	p.wake(t)
+}
+
+func (p *Pool) wake(t *poolTarget) {
+	close(t.released)
+	t.released = make(chan struct{})
+}
+
+// connect makes a new session for target from the template, limited by
+// limiter as well as the template's RateLimiters
+func (p *Pool) connect(target string, limiter *RateLimiter) (*GoSNMP, error) {
+	template := p.Template
+	if template == nil {
+		template = Default
+	}
+	x := newSession(template, target)
+	if limiter != nil {
+		x.RateLimiters = append(append([]*RateLimiter(nil), template.RateLimiters...), limiter)
+	}
+	if err := x.Connect(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// sessionSeeds varies the seeds of the ids of sessions made at once
+var sessionSeeds uint64
+
+// newSession returns an unconnected session for target with the
+// parameters of template
+func newSession(template *GoSNMP, target string) *GoSNMP {
+	x := new(GoSNMP)
+	*x = *template
+	x.Target = target
+	x.Conn = nil
+	x.async = nil
+	x.mux = nil
+	x.secMu = nil
+	x.stats = nil
+	x.peerMaxSize = 0
+	// what the session learns of its agent, and its own ids and engine,
+	// aren't the template's or shared with the other sessions
+	x.random = rand.New(rand.NewSource(time.Now().UnixNano() ^ int64(atomic.AddUint64(&sessionSeeds, 1))))
+	x.requestID, x.msgID = 0, 0
+	x.bulkFailed = 0
+	x.adaptive = nil
+	x.localEngineID, x.localStart = "", time.Time{}
+	if template.SecurityParameters != nil {
+		// each session keeps its own USM state (engine boots, salts etc)
+		x.SecurityParameters = template.SecurityParameters.Copy()
+	}
+	return x
+}
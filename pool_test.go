@@ -0,0 +1,179 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"context"
+	"math/rand"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func poolForResponder(r *testResponder) *Pool {
+	return &Pool{Template: &GoSNMP{
+		Port:      uint16(r.conn.LocalAddr().(*net.UDPAddr).Port),
+		Community: "public",
+		Version:   Version2c,
+		Timeout:   time.Second,
+		Retries:   1,
+		MaxOids:   MaxOids,
+	}}
+}
+
+func TestPoolReuse(t *testing.T) {
+	r := newTestResponder(t, []SnmpPDU{{Name: ".1.3.6.1.2.1.1.5.0", Type: OctetString, Value: "host"}})
+	defer r.Close()
+	p := poolForResponder(r)
+	defer p.Close()
+
+	x, err := p.Borrow("127.0.0.1")
+	if err != nil {
+		t.Fatalf("Borrow() err: %v", err)
+	}
+	if _, err = x.Get([]string{".1.3.6.1.2.1.1.5.0"}); err != nil {
+		t.Fatalf("Get() err: %v", err)
+	}
+	p.Return(x)
+
+	y, err := p.Borrow("127.0.0.1")
+	if err != nil {
+		t.Fatalf("Borrow() err: %v", err)
+	}
+	if y != x {
+		t.Errorf("Borrow() after Return: expected the idle session to be reused")
+	}
+	z, err := p.Borrow("127.0.0.1")
+	if err != nil {
+		t.Fatalf("Borrow() err: %v", err)
+	}
+	if z == y {
+		t.Errorf("Borrow(): got a session that is already borrowed")
+	}
+	p.Return(y)
+	p.Discard(z)
+}
+
+func TestPoolSessionState(t *testing.T) {
+	r := newTestResponder(t, nil)
+	defer r.Close()
+	p := poolForResponder(r)
+	defer p.Close()
+	// a template that has been used itself
+	p.Template.random = rand.New(rand.NewSource(1))
+	p.Template.bulkFailed = 1
+	p.Template.adaptive = &adaptiveRepetitions{reps: 5}
+	p.Template.localEngineID = "template"
+
+	x, err := p.Borrow("127.0.0.1")
+	if err != nil {
+		t.Fatalf("Borrow() err: %v", err)
+	}
+	defer p.Discard(x)
+	y, err := p.Borrow("127.0.0.1")
+	if err != nil {
+		t.Fatalf("Borrow() err: %v", err)
+	}
+	defer p.Discard(y)
+
+	if x.random == p.Template.random || x.random == y.random {
+		t.Errorf("sessions share a random source")
+	}
+	if x.bulkFailed != 0 || x.adaptiveState() == y.adaptiveState() || x.adaptiveState().reps != 0 {
+		t.Errorf("sessions share what was learnt of the agent")
+	}
+	atomic.StoreUint32(&x.bulkFailed, 1)
+	x.adaptiveState().reps = 10
+	if y.bulkFailed != 0 || y.adaptiveState().reps != 0 {
+		t.Errorf("a session's GETBULK state changed another's")
+	}
+	xEngine, _ := x.localEngine()
+	yEngine, _ := y.localEngine()
+	if xEngine == "template" || xEngine == yEngine {
+		t.Errorf("sessions have engines %x and %x", xEngine, yEngine)
+	}
+}
+
+func TestPoolMaxConns(t *testing.T) {
+	r := newTestResponder(t, nil)
+	defer r.Close()
+	p := poolForResponder(r)
+	p.MaxConns = 1
+	defer p.Close()
+
+	x, err := p.Borrow("127.0.0.1")
+	if err != nil {
+		t.Fatalf("Borrow() err: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err = p.BorrowCtx(ctx, "127.0.0.1"); err != context.DeadlineExceeded {
+		t.Errorf("BorrowCtx() at MaxConns: expected DeadlineExceeded, got %v", err)
+	}
+
+	// a different target has its own limit
+	other, err := p.Borrow("127.0.0.2")
+	if err != nil {
+		t.Fatalf("Borrow() of other target err: %v", err)
+	}
+	p.Return(other)
+
+	done := make(chan *GoSNMP)
+	go func() {
+		y, err := p.Borrow("127.0.0.1")
+		if err != nil {
+			t.Errorf("Borrow() err: %v", err)
+		}
+		done <- y
+	}()
+	time.Sleep(10 * time.Millisecond)
+	p.Discard(x)
+	select {
+	case y := <-done:
+		if y == x {
+			t.Errorf("Borrow() after Discard: got the discarded session")
+		}
+		p.Return(y)
+	case <-time.After(time.Second):
+		t.Fatalf("Borrow() still waiting after Discard")
+	}
+}
+
+func TestPoolIdleTimeout(t *testing.T) {
+	r := newTestResponder(t, nil)
+	defer r.Close()
+	p := poolForResponder(r)
+	p.IdleTimeout = 10 * time.Millisecond
+	defer p.Close()
+
+	x, err := p.Borrow("127.0.0.1")
+	if err != nil {
+		t.Fatalf("Borrow() err: %v", err)
+	}
+	p.Return(x)
+	time.Sleep(20 * time.Millisecond)
+
+	y, err := p.Borrow("127.0.0.1")
+	if err != nil {
+		t.Fatalf("Borrow() err: %v", err)
+	}
+	if y == x {
+		t.Errorf("Borrow() after IdleTimeout: expected a new session")
+	}
+	if _, err = x.Conn.Write([]byte{0}); err == nil {
+		t.Errorf("expired session was not closed")
+	}
+	p.Return(y)
+}
+
+func TestPoolClose(t *testing.T) {
+	p := &Pool{}
+	p.Close()
+	if _, err := p.Borrow("127.0.0.1"); err == nil {
+		t.Errorf("Borrow() after Close: expected error")
+	}
+}
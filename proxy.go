@@ -0,0 +1,243 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+//
+// Proxy forwarder, RFC 3413 section 3.5
+//
+
+// ProxyForwarder forwards some of the requests an Agent receives to other
+// agents, as the proxy forwarder application of RFC 3413 does, eg to
+// bridge managers on one network to agents on another. It is set as the
+// Agent's Proxy: requests are received on the Agent's connections, with
+// its versions and credentials (SNMPv3 requests must be from one of its
+// Users), and each is forwarded to the first of the Targets it matches.
+// Requests matching none are answered by the Agent itself.
+//
+// Forwarded requests aren't subject to the Agent's Vacm; access is for the
+// targets to control. A ProxyForwarder must not be changed once the Agent
+// is serving.
+type ProxyForwarder struct {
+	Targets []*ProxyTarget
+}
+
+// ProxyTarget is an agent to which a ProxyForwarder forwards requests
+type ProxyTarget struct {
+	// Community selects the SNMPv1 and SNMPv2c requests forwarded: those
+	// with the community. If it is "", none are.
+	Community string
+
+	// ContextEngineID and ContextName select the SNMPv3 requests
+	// forwarded: those for the context. An empty ContextEngineID matches
+	// any, and a target with neither set matches none.
+	ContextEngineID string
+	ContextName     string
+
+	// Session is the connected session requests are forwarded with. Its
+	// Version, Community or SecurityParameters, and ContextEngineID and
+	// ContextName replace those of the requests, and its requests have
+	// request-ids of its own: responses are given back those of the
+	// requests forwarded. Requests are forwarded one at a time. If its
+	// Version isn't the request's, the request and response are
+	// translated as RFC 3584 says: GETBULKs are sent to SNMPv1 targets as
+	// GETNEXTs, Counter64s are skipped by SNMPv1 GETNEXTs, and errors are
+	// mapped to those of the request's version.
+	Session *GoSNMP
+
+	mu sync.Mutex
+}
+
+// target returns the target of a request, or nil if it isn't forwarded
+func (p *ProxyForwarder) target(request *SnmpPacket) *ProxyTarget {
+	if p == nil {
+		return nil
+	}
+	for _, target := range p.Targets {
+		if target.matches(request) {
+			return target
+		}
+	}
+	return nil
+}
+
+// matches reports whether request is forwarded to the target
+func (target *ProxyTarget) matches(request *SnmpPacket) bool {
+	if request.Version != Version3 {
+		return target.Community != "" && request.Community == target.Community
+	}
+	if target.ContextEngineID == "" && target.ContextName == "" {
+		return false
+	}
+	return (target.ContextEngineID == "" || request.ContextEngineID == target.ContextEngineID) &&
+		request.ContextName == target.ContextName
+}
+
+// forward forwards request to target, returning response, whose header
+// has been set, encoded; or nil if there is no response to the request,
+// eg because the target didn't answer
+func (a *Agent) forward(request, response *SnmpPacket, target *ProxyTarget) []byte {
+	switch request.PDUType {
+	case GetRequest, GetNextRequest, SetRequest:
+	case GetBulkRequest:
+		if request.Version == Version1 {
+			return nil
+		}
+	default:
+		a.x.logWarn("Dropping unsupported request", "type", request.PDUType)
+		return nil
+	}
+
+	var result *SnmpPacket
+	var err error
+	switch {
+	case target.Session.Version == Version1 && request.Version != Version1:
+		result, err = target.forwardV1(request)
+	case request.Version == Version1 && target.Session.Version != Version1 && request.PDUType == GetNextRequest:
+		result, err = target.forwardNextFromV1(request)
+	default:
+		result, err = target.send(request.PDUType, request.Variables, request.NonRepeaters, request.MaxRepetitions)
+	}
+	switch {
+	case err != nil:
+		a.x.logWarn("Unable to forward request", "target", target.Session.Target, "err", err)
+		return nil
+	case result.PDUType == Report:
+		a.x.logWarn("Dropping request reported by target", "target", target.Session.Target)
+		return nil
+	}
+
+	response.PDUType = GetResponse
+	response.RequestID = request.RequestID
+	response.Variables, response.Error, response.ErrorIndex = result.Variables, result.Error, result.ErrorIndex
+	if response.Error != NoError {
+		response.Variables = request.Variables
+	}
+	if request.Version == Version1 {
+		toV1Response(response, request)
+	}
+	return a.encode(request, response)
+}
+
+// send sends a request to the target, returning its response
+func (target *ProxyTarget) send(pduType PDUType, pdus []SnmpPDU, nonRepeaters, maxRepetitions uint8) (*SnmpPacket, error) {
+	target.mu.Lock()
+	defer target.mu.Unlock()
+	x := target.Session
+	return x.send(context.Background(), x.mkSnmpPacket(pduType, pdus, nonRepeaters, maxRepetitions), true)
+}
+
+// forwardV1 forwards an SNMPv2 request to an SNMPv1 target, as RFC 3584
+// says: a GETBULK is sent as a GETNEXT, and varbinds that the
+// target returns noSuchName for are given exceptions (noSuchObject, or
+// endOfMibView for a GETNEXT), the request being sent again without them.
+// SETs of Counter64s, which SNMPv1 hasn't got, fail with wrongType, and the
+// errors of SETs are mapped to SNMPv2's by v2ErrorStatus.
+func (target *ProxyTarget) forwardV1(request *SnmpPacket) (*SnmpPacket, error) {
+	pduType, exception := request.PDUType, Asn1BER(NoSuchObject)
+	switch pduType {
+	case GetBulkRequest:
+		pduType = GetNextRequest
+		fallthrough
+	case GetNextRequest:
+		exception = EndOfMibView
+	case SetRequest:
+		for i, pdu := range request.Variables {
+			if pdu.Type == Counter64 {
+				return &SnmpPacket{PDUType: GetResponse, Error: WrongType, ErrorIndex: uint8(i + 1)}, nil
+			}
+		}
+	}
+
+	results := make([]SnmpPDU, len(request.Variables))
+	pending := make([]int, len(request.Variables)) // the indexes of the varbinds to send
+	for i := range pending {
+		pending[i] = i
+	}
+	for len(pending) > 0 {
+		pdus := make([]SnmpPDU, len(pending))
+		for j, i := range pending {
+			pdus[j] = request.Variables[i]
+		}
+		result, err := target.send(pduType, pdus, 0, 0)
+		if err != nil || result.PDUType == Report {
+			return result, err
+		}
+		index := int(result.ErrorIndex)
+		switch {
+		case result.Error == NoError && len(result.Variables) == len(pending):
+			for j, i := range pending {
+				results[i] = result.Variables[j]
+			}
+			pending = nil
+		case result.Error == NoSuchName && pduType != SetRequest && index >= 1 && index <= len(pending):
+			i := pending[index-1]
+			results[i] = SnmpPDU{Name: request.Variables[i].Name, Type: exception}
+			pending = append(append([]int(nil), pending[:index-1]...), pending[index:]...)
+		case result.Error == NoError:
+			result.Error, result.ErrorIndex = GenErr, 0
+			return result, nil
+		default:
+			if index >= 1 && index <= len(pending) {
+				result.ErrorIndex = uint8(pending[index-1] + 1)
+			}
+			if pduType == SetRequest {
+				result.Error = v2ErrorStatus(result.Error)
+			}
+			return result, nil
+		}
+	}
+	return &SnmpPacket{PDUType: GetResponse, Variables: results}, nil
+}
+
+// v2ErrorStatus returns the SNMPv2 error-status for that of an SNMPv1
+// target's response to a SET: noSuchName becomes noAccess, badValue
+// wrongValue and readOnly notWritable
+func v2ErrorStatus(status SNMPError) SNMPError {
+	switch status {
+	case NoSuchName:
+		return NoAccess
+	case BadValue:
+		return WrongValue
+	case ReadOnly:
+		return NotWritable
+	}
+	return status
+}
+
+// forwardNextFromV1 forwards an SNMPv1 GETNEXT to an SNMPv2 target, as RFC
+// 3584 says: SNMPv1 can't carry Counter64s, so they are skipped, the
+// request being sent again with the names of any varbinds that are, until
+// none are. A Counter64 that doesn't follow the name asked for, which
+// asking again would only repeat, fails the request. (The responses to
+// SNMPv1 GETs of Counter64s are noSuchName errors, as for the Agent's own
+// objects.)
+func (target *ProxyTarget) forwardNextFromV1(request *SnmpPacket) (*SnmpPacket, error) {
+	pdus := append([]SnmpPDU(nil), request.Variables...)
+	for {
+		result, err := target.send(GetNextRequest, pdus, 0, 0)
+		if err != nil || result.PDUType == Report || result.Error != NoError || len(result.Variables) != len(pdus) {
+			return result, err
+		}
+		skipped := false
+		for i, pdu := range result.Variables {
+			if pdu.Type == Counter64 {
+				if CompareOids(pdu.Name, pdus[i].Name) <= 0 {
+					return nil, fmt.Errorf("OID not increasing: %s", pdu.Name)
+				}
+				pdus[i] = SnmpPDU{Name: pdu.Name, Type: Null}
+				skipped = true
+			}
+		}
+		if !skipped {
+			return result, nil
+		}
+	}
+}
@@ -0,0 +1,199 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// startTestProxy serves a proxy, forwarding the community "router" and the
+// SNMPv3 context "router" to a with SNMPv2c, and the community "router1"
+// with SNMPv1; it returns the proxy's client, and a function stopping
+// them
+func startTestProxy(t *testing.T, a *Agent, x *GoSNMP) (*GoSNMP, func()) {
+	t.Helper()
+	v2 := startTestAgent(t, a, &GoSNMP{Community: "private", Version: Version2c})
+	v1 := startTestAgent(t, a, &GoSNMP{Community: "private", Version: Version1})
+	proxy := &Agent{
+		Users: []*UsmSecurityParameters{{
+			UserName:                 "user",
+			AuthenticationProtocol:   SHA,
+			AuthenticationPassphrase: "authpassphrase",
+		}},
+		Proxy: &ProxyForwarder{Targets: []*ProxyTarget{
+			{Community: "router", ContextName: "router", Session: v2},
+			{Community: "router1", Session: v1},
+		}},
+	}
+	x = startTestAgent(t, proxy, x)
+	return x, func() {
+		x.Conn.Close()
+		proxy.Close()
+		v1.Conn.Close()
+		v2.Conn.Close()
+		a.Close()
+	}
+}
+
+// describeProxied formats varbinds as "name=value" for comparisons
+func describeProxied(varbinds []SnmpPDU) string {
+	var s []string
+	for _, v := range varbinds {
+		switch v.Type {
+		case OctetString:
+			s = append(s, fmt.Sprintf("%s=%s", v.Name, v.Value))
+		case Integer:
+			s = append(s, fmt.Sprintf("%s=%v", v.Name, v.Value))
+		default:
+			s = append(s, fmt.Sprintf("%s=%s", v.Name, v.Type))
+		}
+	}
+	return strings.Join(s, " ")
+}
+
+func TestProxyForwarder(t *testing.T) {
+	for _, community := range []string{"router", "router1"} {
+		a, name := newTestAgent(t)
+		x, stop := startTestProxy(t, a, &GoSNMP{Community: community, Version: Version2c})
+
+		result, err := x.Get([]string{testSysDescr, ".1.3.6.1.2.1.1.2.0", testSysName})
+		if err != nil {
+			t.Fatalf("%s: Get() err: %v", community, err)
+		}
+		if got := describeProxied(result.Variables); got != ".1.3.6.1.2.1.1.1.0=descr .1.3.6.1.2.1.1.2.0=NoSuchObject .1.3.6.1.2.1.1.5.0=name" {
+			t.Errorf("%s: Get() got %s", community, got)
+		}
+
+		results, err := x.BulkWalkAll(".1.3.6.1.2.1")
+		if err != nil {
+			t.Fatalf("%s: BulkWalkAll() err: %v", community, err)
+		}
+		if got := describeProxied(results); got != ".1.3.6.1.2.1.1.1.0=descr .1.3.6.1.2.1.1.5.0=name .1.3.6.1.2.1.2.1.0=2" {
+			t.Errorf("%s: BulkWalkAll() got %s", community, got)
+		}
+
+		if _, err = x.Set([]SnmpPDU{{Name: testSysName, Type: OctetString, Value: community}}); err != nil {
+			t.Errorf("%s: Set() err: %v", community, err)
+		}
+		if got := string(name.value().([]byte)); got != community {
+			t.Errorf("%s: sysName is %q", community, got)
+		}
+		result, err = x.Set([]SnmpPDU{{Name: testSysDescr, Type: OctetString, Value: "descr"}})
+		if err != nil || result.Error == NoError || result.ErrorIndex != 1 {
+			t.Errorf("%s: Set() of sysDescr got %v, err %v", community, result, err)
+		}
+		stop()
+	}
+}
+
+func TestProxyForwarderV1(t *testing.T) {
+	a, _ := newTestAgent(t)
+	x, stop := startTestProxy(t, a, &GoSNMP{Community: "router", Version: Version1})
+	defer stop()
+
+	result, err := x.Get([]string{testSysDescr, ".1.3.6.1.2.1.1.2.0"})
+	if err != nil {
+		t.Fatalf("Get() err: %v", err)
+	}
+	if result.Error != NoSuchName || result.ErrorIndex != 2 {
+		t.Errorf("Get() got error %s index %d, expected noSuchName 2", result.Error, result.ErrorIndex)
+	}
+}
+
+func TestProxyForwarderV3(t *testing.T) {
+	a, _ := newTestAgent(t)
+	x, stop := startTestProxy(t, a, &GoSNMP{
+		Version:       Version3,
+		MsgFlags:      AuthNoPriv,
+		SecurityModel: UserSecurityModel,
+		ContextName:   "router",
+		SecurityParameters: &UsmSecurityParameters{
+			UserName:                 "user",
+			AuthenticationProtocol:   SHA,
+			AuthenticationPassphrase: "authpassphrase",
+		},
+	})
+	defer stop()
+
+	result, err := x.Get([]string{testSysDescr})
+	if err != nil {
+		t.Fatalf("Get() err: %v", err)
+	}
+	if got := describeProxied(result.Variables); got != ".1.3.6.1.2.1.1.1.0=descr" {
+		t.Errorf("Get() got %s", got)
+	}
+
+	// other contexts are the proxy's own, which has no objects
+	x.ContextName = ""
+	if result, err = x.Get([]string{testSysDescr}); err != nil {
+		t.Fatalf("Get() err: %v", err)
+	}
+	if got := describeProxied(result.Variables); got != ".1.3.6.1.2.1.1.1.0=NoSuchObject" {
+		t.Errorf("Get() of the default context got %s", got)
+	}
+}
+
+func TestProxyForwarderCounter64(t *testing.T) {
+	a, _ := newTestAgent(t)
+	counter := &testScalar{pdu: SnmpPDU{Type: Counter64, Value: uint64(1 << 40)}}
+	if err := a.Register(".1.3.6.1.2.1.1.3.0", counter); err != nil {
+		t.Fatalf("Register() err: %v", err)
+	}
+
+	// SNMPv1 GETNEXTs skip a Counter64, and GETs of one fail
+	x, stop := startTestProxy(t, a, &GoSNMP{Community: "router", Version: Version1})
+	results, err := x.WalkAll(".1.3.6.1.2.1")
+	if err != nil {
+		t.Fatalf("WalkAll() err: %v", err)
+	}
+	if got := describeProxied(results); got != ".1.3.6.1.2.1.1.1.0=descr .1.3.6.1.2.1.1.5.0=name .1.3.6.1.2.1.2.1.0=2" {
+		t.Errorf("WalkAll() got %s", got)
+	}
+	result, err := x.Get([]string{testSysDescr, ".1.3.6.1.2.1.1.3.0"})
+	if err != nil {
+		t.Fatalf("Get() err: %v", err)
+	}
+	if result.Error != NoSuchName || result.ErrorIndex != 2 {
+		t.Errorf("Get() got error %s index %d, expected noSuchName 2", result.Error, result.ErrorIndex)
+	}
+	stop()
+
+	// a target returning the same Counter64 whatever is asked for isn't
+	// asked forever
+	r := newTestResponder(t, nil)
+	defer r.Close()
+	r.setHandler(func(req *SnmpPacket) *SnmpPacket {
+		return &SnmpPacket{Variables: []SnmpPDU{{Name: ".1.3.6.1.2.1.1.3.0", Type: Counter64, Value: uint64(1)}}}
+	})
+	target := &ProxyTarget{Session: r.client(t)}
+	defer target.Session.Conn.Close()
+	request := &SnmpPacket{Version: Version1, PDUType: GetNextRequest, Variables: []SnmpPDU{{Name: ".1.3.6.1.2.1.1", Type: Null}}}
+	if result, err := target.forwardNextFromV1(request); err == nil {
+		t.Errorf("forwardNextFromV1() got %v, expected an error", result)
+	}
+	if n := len(r.received()); n != 2 {
+		t.Errorf("forwardNextFromV1() sent %d requests, expected 2", n)
+	}
+
+	// SETs through an SNMPv1 target have SNMPv2 errors, and can't set
+	// Counter64s
+	a, _ = newTestAgent(t)
+	x, stop = startTestProxy(t, a, &GoSNMP{Community: "router1", Version: Version2c})
+	defer stop()
+	result, err = x.Set([]SnmpPDU{{Name: testSysDescr, Type: OctetString, Value: "descr"}})
+	if err != nil || result.Error != NoAccess || result.ErrorIndex != 1 {
+		t.Errorf("Set() of sysDescr got %v, err %v, expected noAccess", result, err)
+	}
+	result, err = x.send(context.Background(), x.mkSnmpPacket(SetRequest, []SnmpPDU{
+		{Name: testSysName, Type: OctetString, Value: "name"},
+		{Name: ".1.3.6.1.2.1.1.3.0", Type: Counter64, Value: uint64(1)},
+	}, 0, 0), true)
+	if err != nil || result.Error != WrongType || result.ErrorIndex != 2 {
+		t.Errorf("Set() of a Counter64 got %v, err %v, expected wrongType", result, err)
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimiter limits the rate packets are sent at, see
+// GoSNMP.RateLimiters. Packets over the limit wait their turn, in the order
+// they were sent, rather than being dropped. A RateLimiter is safe for
+// concurrent use, and is usually shared: by the sessions for a target
+// (see Pool.RateLimit), or by all sessions for a global limit.
+type RateLimiter struct {
+	rate  float64 // packets per second
+	burst float64
+
+	mu     sync.Mutex
+	tokens float64 // below zero when packets are waiting
+	last   time.Time
+}
+
+// NewRateLimiter returns a RateLimiter allowing rate packets per second, and
+// bursts of up to burst packets (at least 1) after a quiet spell.
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{rate: rate, burst: float64(burst), tokens: float64(burst)}
+}
+
+// Wait waits until a packet can be sent, returning ctx.Err() if ctx is
+// done first.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	if l.rate <= 0 {
+		return nil
+	}
+	l.mu.Lock()
+	now := time.Now()
+	if !l.last.IsZero() {
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+	}
+	l.last = now
+	// take a token even if there is none, so that packets queue in order
+	l.tokens--
+	delay := time.Duration(-l.tokens / l.rate * float64(time.Second))
+	l.mu.Unlock()
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		l.tokens++
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// waitRateLimiters waits for all of x.RateLimiters to allow a packet
+func (x *GoSNMP) waitRateLimiters(ctx context.Context) error {
+	for _, l := range x.RateLimiters {
+		if err := l.Wait(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	l := NewRateLimiter(100, 2)
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		if err := l.Wait(context.Background()); err != nil {
+			t.Fatalf("Wait() err: %v", err)
+		}
+	}
+	// a burst of 2, then 4 more at 10ms apart
+	if elapsed := time.Since(start); elapsed < 35*time.Millisecond || elapsed > 200*time.Millisecond {
+		t.Errorf("6 packets took %v, want about 40ms", elapsed)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	for i := 0; i < 3; i++ {
+		l.Wait(ctx)
+	}
+	if err := l.Wait(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Wait() err: %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestPoolRateLimit(t *testing.T) {
+	r := newTestResponder(t, []SnmpPDU{{Name: ".1.3.6.1.2.1.1.5.0", Type: OctetString, Value: []byte("host")}})
+	defer r.Close()
+	p := poolForResponder(r)
+	p.RateLimit = 50
+	global := NewRateLimiter(1000, 1)
+	p.Template.RateLimiters = []*RateLimiter{global}
+	defer p.Close()
+
+	// two sessions for the target share its limit
+	x, err := p.Borrow("127.0.0.1")
+	if err != nil {
+		t.Fatalf("Borrow() err: %v", err)
+	}
+	y, err := p.Borrow("127.0.0.1")
+	if err != nil {
+		t.Fatalf("Borrow() err: %v", err)
+	}
+	if len(x.RateLimiters) != 2 || x.RateLimiters[0] != global || x.RateLimiters[1] != y.RateLimiters[1] {
+		t.Fatalf("got limiters %v and %v, want the global and a shared target limiter", x.RateLimiters, y.RateLimiters)
+	}
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		for _, s := range []*GoSNMP{x, y} {
+			if _, err := s.Get([]string{".1.3.6.1.2.1.1.5.0"}); err != nil {
+				t.Fatalf("Get() err: %v", err)
+			}
+		}
+	}
+	// 6 requests at 20ms apart
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("6 requests took %v, want at least 100ms", elapsed)
+	}
+	p.Return(x)
+	p.Return(y)
+}
@@ -0,0 +1,120 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"time"
+)
+
+// sysUpTimeOid is polled along with the counters of a PollJob with Rates,
+// to tell when the agent restarts
+const sysUpTimeOid = ".1.3.6.1.2.1.1.3.0"
+
+// PollRate is the rate a counter increased at between two polls, see
+// PollJob.Rates
+type PollRate struct {
+	Name string
+
+	// Type is the counter's type, Counter32 or Counter64
+	Type Asn1BER
+
+	// Delta is the increase since the previous poll, over Interval, and
+	// Rate is that increase per second
+	Delta    uint64
+	Interval time.Duration
+	Rate     float64
+}
+
+// rateKey is a counter of a target
+type rateKey struct {
+	target, oid string
+}
+
+// counterSample is the previous sample of a counter
+type counterSample struct {
+	pdu SnmpPDU
+	at  time.Time
+}
+
+// pollOids returns the oids to get for j, which include sysUpTime for Rates
+func (j *pollJob) pollOids() []string {
+	if !j.Rates {
+		return j.Oids
+	}
+	for _, oid := range j.Oids {
+		if "."+trimOidDot(oid) == sysUpTimeOid {
+			return j.Oids
+		}
+	}
+	return append(j.Oids[:len(j.Oids):len(j.Oids)], sysUpTimeOid)
+}
+
+// rates turns the counters in the result of a poll of j into rates, from
+// the samples of the previous poll. The varbinds are returned without the
+// counters, and without sysUpTime if j didn't ask for it.
+func (s *Scheduler) rates(j *pollJob, variables []SnmpPDU, at time.Time) ([]SnmpPDU, []PollRate) {
+	asked := len(j.pollOids()) == len(j.Oids)
+	var uptime *SnmpPDU
+	var others []SnmpPDU
+	var counters []SnmpPDU
+	for i, pdu := range variables {
+		switch {
+		case pdu.Type == Counter32 || pdu.Type == Counter64:
+			counters = append(counters, pdu)
+			continue
+		case pdu.Name == sysUpTimeOid:
+			uptime = &variables[i]
+			if !asked {
+				continue
+			}
+		}
+		others = append(others, pdu)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.samples == nil {
+		s.samples = make(map[rateKey]counterSample)
+		s.boots = make(map[string]time.Time)
+	}
+	if uptime != nil {
+		prev := s.boots[j.Target]
+		if boot, err := BootTime(uptime.Value, at, prev); err == nil {
+			if !prev.IsZero() && !boot.Equal(prev) {
+				// the agent restarted, resetting its counters
+				for key := range s.samples {
+					if key.target == j.Target {
+						delete(s.samples, key)
+					}
+				}
+			}
+			s.boots[j.Target] = boot
+		}
+	}
+
+	var rates []PollRate
+	for _, pdu := range counters {
+		key := rateKey{j.Target, pdu.Name}
+		prev, ok := s.samples[key]
+		s.samples[key] = counterSample{pdu: pdu, at: at}
+		if !ok {
+			continue
+		}
+		interval := at.Sub(prev.at)
+		rate, err := CounterRate(prev.pdu, pdu, interval)
+		if err != nil {
+			continue
+		}
+		delta, _ := CounterDelta(prev.pdu, pdu)
+		rates = append(rates, PollRate{
+			Name:     pdu.Name,
+			Type:     pdu.Type,
+			Delta:    delta,
+			Interval: interval,
+			Rate:     rate,
+		})
+	}
+	return others, rates
+}
@@ -0,0 +1,55 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPollRates(t *testing.T) {
+	const octets = ".1.3.6.1.2.1.2.2.1.10.1"
+	const name = ".1.3.6.1.2.1.1.5.0"
+	j := &pollJob{PollJob: PollJob{Target: "127.0.0.1", Oids: []string{name, octets}, Interval: 10 * time.Second, Rates: true}}
+	if oids := j.pollOids(); len(oids) != 3 || oids[2] != sysUpTimeOid || len(j.Oids) != 2 {
+		t.Fatalf("pollOids() got %v", oids)
+	}
+
+	start := time.Now()
+	tests := []struct {
+		uptime  uint32 // centiseconds
+		counter uint32
+		want    []float64
+	}{
+		{100000, 4294967000, nil}, // no earlier sample
+		{101000, 704, []float64{100}},
+		{102000, 1704, []float64{100}},
+		{500, 50, nil}, // the agent restarted
+		{1500, 1050, []float64{100}},
+	}
+	s := new(Scheduler)
+	at := start
+	for i, test := range tests {
+		variables := []SnmpPDU{
+			{Name: name, Type: OctetString, Value: []byte("host")},
+			{Name: octets, Type: Counter32, Value: uint(test.counter)},
+			{Name: sysUpTimeOid, Type: TimeTicks, Value: test.uptime},
+		}
+		others, rates := s.rates(j, variables, at)
+		at = at.Add(10 * time.Second)
+		if len(others) != 1 || others[0].Name != name {
+			t.Errorf("#%d: got values %v, want only %s", i, others, name)
+		}
+		if len(rates) != len(test.want) {
+			t.Errorf("#%d: got rates %v, want %v", i, rates, test.want)
+			continue
+		}
+		for k, rate := range rates {
+			if rate.Name != octets || rate.Rate != test.want[k] || rate.Delta != 1000 || rate.Interval != 10*time.Second {
+				t.Errorf("#%d: got %+v, want a rate of %v", i, rate, test.want[k])
+			}
+		}
+	}
+}
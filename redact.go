@@ -0,0 +1,39 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"fmt"
+)
+
+// redacted replaces secrets (communities, passphrases, keys, and the
+// authentication and privacy parameters of packets) in log output and
+// String
+const redacted = "***"
+
+// redact returns redacted for a secret, or "" if it isn't set
+func redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return redacted
+}
+
+// redactBytes describes a binary secret by its length only
+func redactBytes(secret []byte) string {
+	return fmt.Sprintf("%s(%d bytes)", redacted, len(secret))
+}
+
+// GoString is the same as String, so that %#v doesn't show the community
+// either.
+func (packet SnmpPacket) GoString() string {
+	return packet.String()
+}
+
+// GoString is the same as String, so that %#v doesn't show the
+// passphrases or keys either.
+func (sp *UsmSecurityParameters) GoString() string {
+	return sp.String()
+}
@@ -0,0 +1,127 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// leaks returns the logged lines containing any of secrets
+func (r *recordingLogger) leaks(secrets ...string) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var leaks []string
+	for _, line := range r.lines {
+		for _, secret := range secrets {
+			if secret != "" && strings.Contains(line, secret) {
+				leaks = append(leaks, line)
+			}
+		}
+	}
+	return leaks
+}
+
+func TestRedactCommunity(t *testing.T) {
+	const community = "s3cret-community"
+	const oid = ".1.3.6.1.2.1.1.5.0"
+	r := newTestResponder(t, []SnmpPDU{{Name: oid, Type: OctetString, Value: []byte("name")}})
+	defer r.Close()
+	logger := new(printLogger)
+	x := &GoSNMP{
+		Target:    "127.0.0.1",
+		Port:      uint16(r.conn.LocalAddr().(*net.UDPAddr).Port),
+		Community: community,
+		Version:   Version2c,
+		Timeout:   time.Second,
+		Logger:    logger,
+	}
+	if err := x.Connect(); err != nil {
+		t.Fatalf("Connect() err: %v", err)
+	}
+	defer x.Conn.Close()
+
+	result, err := x.Get([]string{oid})
+	if err != nil {
+		t.Fatalf("Get() err: %v", err)
+	}
+	logger.Printf("%v %+v %#v %s", result, result, *result, *result)
+	if leaks := logger.leaks(community); len(leaks) > 0 {
+		t.Errorf("community logged in %q", leaks)
+	}
+}
+
+func TestRedactUsm(t *testing.T) {
+	const authPass, privPass = "authpassphrase", "privpassphrase"
+	logger := new(printLogger)
+	newSession := func() *GoSNMP {
+		x := &GoSNMP{
+			Version:       Version3,
+			MsgFlags:      AuthPriv,
+			SecurityModel: UserSecurityModel,
+			SecurityParameters: &UsmSecurityParameters{
+				UserName:                 "user",
+				AuthenticationProtocol:   SHA,
+				AuthenticationPassphrase: authPass,
+				PrivacyProtocol:          AES,
+				PrivacyPassphrase:        privPass,
+			},
+			Logger: logger,
+		}
+		if err := x.validateParameters(); err != nil {
+			t.Fatalf("validateParameters() err: %v", err)
+		}
+		// as if discovery had taken place, localizing the keys
+		discovered := &SnmpPacket{
+			Version:            Version3,
+			SecurityModel:      UserSecurityModel,
+			SecurityParameters: &UsmSecurityParameters{AuthoritativeEngineID: "engine"},
+		}
+		if err := x.storeSecurityParameters(discovered); err != nil {
+			t.Fatalf("storeSecurityParameters() err: %v", err)
+		}
+		return x
+	}
+
+	x := newSession()
+	packet := x.mkSnmpPacket(GetRequest, []SnmpPDU{{Name: ".1.3.6.1.2.1.1.1.0", Type: Null}}, 0, 0)
+	if err := x.initPacket(packet); err != nil {
+		t.Fatalf("initPacket() err: %v", err)
+	}
+	out, err := packet.marshalMsg()
+	if err != nil {
+		t.Fatalf("marshalMsg() err: %v", err)
+	}
+	sp := packet.SecurityParameters.(*UsmSecurityParameters)
+	sp.Log()
+	logger.Printf("%v %+v %#v %s", sp, sp, sp, packet)
+
+	y := newSession()
+	result := &SnmpPacket{Logger: y.Logger, SecurityParameters: y.SecurityParameters.Copy()}
+	cursor, err := y.unmarshalHeader(out, result)
+	if err != nil {
+		t.Fatalf("unmarshalHeader() err: %v", err)
+	}
+	if _, _, err = y.decryptPacket(out, cursor, result); err != nil {
+		t.Fatalf("decryptPacket() err: %v", err)
+	}
+
+	secrets := []string{authPass, privPass,
+		string(sp.secretKey), fmt.Sprintf("%x", sp.secretKey), fmt.Sprint(sp.secretKey),
+		string(sp.privacyKey), fmt.Sprintf("%x", sp.privacyKey), fmt.Sprint(sp.privacyKey),
+		sp.AuthenticationParameters, string(sp.PrivacyParameters)}
+	if len(sp.secretKey) == 0 || len(sp.privacyKey) == 0 {
+		t.Fatalf("keys weren't localized")
+	}
+	if leaks := logger.leaks(secrets...); len(leaks) > 0 {
+		t.Errorf("secrets logged in %q", leaks)
+	}
+	if logger.count("PRINT Parsed privacyParameters "+redacted) != 1 {
+		t.Errorf("privacy parameters weren't logged masked, in %q", logger.lines)
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// OctetStringRenderer renders OctetString values for display. Values that
+// are printable UTF-8 text are rendered as they are, and anything else
+// (MAC addresses, packed binary structures etc) as hex, as the net-snmp
+// tools do. The zero value is ready to use.
+type OctetStringRenderer struct {
+	// ForceHex renders every value as hex, even printable text
+	ForceHex bool
+
+	// Compact leaves out the spaces between the bytes of hex, eg "0A1B2C"
+	// rather than "0A 1B 2C"
+	Compact bool
+}
+
+// Render renders b, reporting whether it was rendered as hex
+func (r OctetStringRenderer) Render(b []byte) (s string, isHex bool) {
+	if !r.ForceHex && PrintableOctetString(b) {
+		return string(b), false
+	}
+	return r.hex(b), true
+}
+
+func (r OctetStringRenderer) hex(b []byte) string {
+	const digits = "0123456789ABCDEF"
+	size := 2 * len(b)
+	if !r.Compact && len(b) > 0 {
+		size += len(b) - 1
+	}
+	out := make([]byte, 0, size)
+	for i, c := range b {
+		if i > 0 && !r.Compact {
+			out = append(out, ' ')
+		}
+		out = append(out, digits[c>>4], digits[c&0x0f])
+	}
+	return string(out)
+}
+
+// FormatOctetString renders b with the default OctetStringRenderer: as
+// text if it is printable, otherwise as hex
+func FormatOctetString(b []byte) string {
+	s, _ := OctetStringRenderer{}.Render(b)
+	return s
+}
+
+// PrintableOctetString reports whether b is valid UTF-8 made up of
+// printable characters and white space
+func PrintableOctetString(b []byte) bool {
+	for len(b) > 0 {
+		r, size := utf8.DecodeRune(b)
+		if r == utf8.RuneError && size <= 1 {
+			return false
+		}
+		if !unicode.IsPrint(r) && r != '\t' && r != '\n' && r != '\r' {
+			return false
+		}
+		b = b[size:]
+	}
+	return true
+}
@@ -0,0 +1,37 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import "testing"
+
+func TestOctetStringRenderer(t *testing.T) {
+	tests := []struct {
+		renderer OctetStringRenderer
+		value    []byte
+		want     string
+		isHex    bool
+	}{
+		{OctetStringRenderer{}, []byte("Linux router 4.4.0"), "Linux router 4.4.0", false},
+		{OctetStringRenderer{}, []byte("línea\tdos\r\n"), "línea\tdos\r\n", false},
+		{OctetStringRenderer{}, []byte{}, "", false},
+		{OctetStringRenderer{}, []byte{0x00, 0x1b, 0x21, 0xff}, "00 1B 21 FF", true},
+		{OctetStringRenderer{}, []byte("abc\x00"), "61 62 63 00", true},
+		{OctetStringRenderer{}, []byte{0xc3, 0x28}, "C3 28", true},
+		{OctetStringRenderer{Compact: true}, []byte{0x00, 0x1b, 0x21}, "001B21", true},
+		{OctetStringRenderer{ForceHex: true}, []byte("ab"), "61 62", true},
+		{OctetStringRenderer{ForceHex: true}, []byte{}, "", true},
+		{OctetStringRenderer{ForceHex: true, Compact: true}, []byte("ab"), "6162", true},
+	}
+	for _, test := range tests {
+		got, isHex := test.renderer.Render(test.value)
+		if got != test.want || isHex != test.isHex {
+			t.Errorf("%+v % x: got %q %v, want %q %v", test.renderer, test.value, got, isHex, test.want, test.isHex)
+		}
+	}
+
+	if got := FormatOctetString([]byte{1, 2}); got != "01 02" {
+		t.Errorf("FormatOctetString: got %q", got)
+	}
+}
@@ -0,0 +1,193 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+)
+
+//
+// Following the DNS changes of a Target that is a hostname, see
+// ResolveInterval, and redialling, see RedialOnUnreachable
+//
+
+// lookupHost resolves hostnames; tests replace it
+var lookupHost = net.DefaultResolver.LookupHost
+
+// resolvingConn is the Conn of a session whose Target is a hostname to
+// be resolved again from time to time, or that is redialled: it sends to
+// the address the name last resolved to, dialling a new conn when that
+// changes or it is redialled.
+type resolvingConn struct {
+	x        *GoSNMP // for logging
+	host     string
+	port     string
+	interval time.Duration
+	dial     func(addr string) (net.Conn, error)
+
+	mu       sync.Mutex
+	conn     net.Conn
+	addr     string // the address conn was dialled to
+	resolved time.Time
+	closed   bool
+}
+
+// dialResolving resolves host and dials the first of its addresses
+func (x *GoSNMP) dialResolving(host, port string, dial func(addr string) (net.Conn, error)) (net.Conn, error) {
+	addrs, err := lookup(context.Background(), host)
+	if err != nil {
+		return nil, err
+	}
+	addr := net.JoinHostPort(addrs[0], port)
+	conn, err := dial(addr)
+	if err != nil {
+		return nil, err
+	}
+	return &resolvingConn{x: x, host: host, port: port, interval: x.ResolveInterval, dial: dial,
+		conn: conn, addr: addr, resolved: time.Now()}, nil
+}
+
+// resolve resolves the host again, if it was last resolved at least the
+// interval before, and if its addresses no longer include the address of
+// the conn dials the first of them in its place. Failures are logged, and
+// the conn kept.
+func (r *resolvingConn) resolve(ctx context.Context) {
+	r.mu.Lock()
+	if r.closed || r.interval <= 0 || net.ParseIP(r.host) != nil || time.Since(r.resolved) < r.interval {
+		r.mu.Unlock()
+		return
+	}
+	r.resolved = time.Now()
+	current := r.addr
+	r.mu.Unlock()
+
+	addrs, err := lookup(ctx, r.host)
+	if err != nil {
+		r.x.logWarn("Unable to resolve the target again", "target", r.host, "err", err)
+		return
+	}
+	for _, a := range addrs {
+		if net.JoinHostPort(a, r.port) == current {
+			return
+		}
+	}
+	r.replace(net.JoinHostPort(addrs[0], r.port), current)
+}
+
+// redial dials a new conn in place of the conn in use, to the first
+// address the host resolves to, or if it can't be resolved the address in
+// use
+func (r *resolvingConn) redial(ctx context.Context) {
+	r.mu.Lock()
+	r.resolved = time.Now()
+	current := r.addr
+	r.mu.Unlock()
+
+	addr := current
+	if addrs, err := lookup(ctx, r.host); err != nil {
+		r.x.logWarn("Unable to resolve the target again", "target", r.host, "err", err)
+	} else {
+		addr = net.JoinHostPort(addrs[0], r.port)
+	}
+	r.replace(addr, current)
+}
+
+// replace dials addr, and uses the new conn in place of the conn in use,
+// dialled to current
+func (r *resolvingConn) replace(addr, current string) {
+	conn, err := r.dial(addr)
+	if err != nil {
+		r.x.logWarn("Unable to connect to the target again", "target", r.host, "addr", addr, "err", err)
+		return
+	}
+	r.x.logInfo("Connected to the target again", "target", r.host, "addr", addr, "was", current)
+
+	r.mu.Lock()
+	old := r.conn
+	if r.closed {
+		old = conn
+	} else {
+		r.conn, r.addr = conn, addr
+	}
+	r.mu.Unlock()
+	old.Close()
+}
+
+// lookup resolves host, which may be an IP address
+func lookup(ctx context.Context, host string) ([]string, error) {
+	if net.ParseIP(host) != nil {
+		return []string{host}, nil
+	}
+	return lookupHost(ctx, host)
+}
+
+// current returns the conn in use
+func (r *resolvingConn) current() net.Conn {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.conn
+}
+
+// replaced reports whether conn has been replaced by a new one
+func (r *resolvingConn) replaced(conn net.Conn) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return !r.closed && r.conn != conn
+}
+
+// ReadFrom reads the next datagram from the conn in use, going on with
+// its replacement when the conn is replaced
+func (r *resolvingConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	for {
+		conn := r.current()
+		n, from, err := readFrom(conn, b)
+		if err != nil && r.replaced(conn) {
+			continue
+		}
+		return n, from, err
+	}
+}
+
+func (r *resolvingConn) Read(b []byte) (int, error) {
+	n, _, err := r.ReadFrom(b)
+	return n, err
+}
+
+func (r *resolvingConn) Write(b []byte) (int, error) {
+	return r.current().Write(b)
+}
+
+func (r *resolvingConn) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = true
+	return r.conn.Close()
+}
+
+func (r *resolvingConn) LocalAddr() net.Addr  { return r.current().LocalAddr() }
+func (r *resolvingConn) RemoteAddr() net.Addr { return r.current().RemoteAddr() }
+
+func (r *resolvingConn) SetDeadline(t time.Time) error      { return r.current().SetDeadline(t) }
+func (r *resolvingConn) SetReadDeadline(t time.Time) error  { return r.current().SetReadDeadline(t) }
+func (r *resolvingConn) SetWriteDeadline(t time.Time) error { return r.current().SetWriteDeadline(t) }
+
+// resolveAgain resolves the Target again before a retry, if its Conn
+// follows the hostname's changes
+func (x *GoSNMP) resolveAgain(ctx context.Context) {
+	if r, ok := x.Conn.(*resolvingConn); ok {
+		r.resolve(ctx)
+	}
+}
+
+// redial dials the Target again after an ICMP unreachable, if its Conn can
+// be, see RedialOnUnreachable
+func (x *GoSNMP) redial(ctx context.Context) {
+	if r, ok := x.Conn.(*resolvingConn); ok {
+		r.redial(ctx)
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestResolveInterval(t *testing.T) {
+	// agents on two addresses, with the same port
+	var port int
+	var agents []*Agent
+	for _, ip := range []string{"127.0.0.1", "127.0.0.2"} {
+		conn, err := net.ListenPacket("udp4", net.JoinHostPort(ip, strconv.Itoa(port)))
+		if err != nil {
+			t.Skipf("ListenPacket(%s) err: %v", ip, err)
+		}
+		port = conn.LocalAddr().(*net.UDPAddr).Port
+		descr := []byte(ip)
+		a := &Agent{}
+		if err = a.Register(testSysDescr, AgentHandlerFunc(func(string) (SnmpPDU, error) {
+			return SnmpPDU{Type: OctetString, Value: descr}, nil
+		})); err != nil {
+			t.Fatalf("Register() err: %v", err)
+		}
+		go a.Serve(conn)
+		defer a.Close()
+		agents = append(agents, a)
+	}
+
+	var mu sync.Mutex
+	addrs := []string{"127.0.0.1"}
+	lookups := 0
+	lookupHost = func(ctx context.Context, host string) ([]string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		lookups++
+		return addrs, nil
+	}
+	defer func() { lookupHost = net.DefaultResolver.LookupHost }()
+
+	x := &GoSNMP{
+		Target:          "agent.example",
+		Port:            uint16(port),
+		Community:       "public",
+		Version:         Version2c,
+		Timeout:         time.Second,
+		Retries:         2,
+		ResolveInterval: time.Nanosecond,
+	}
+	if err := x.Connect(); err != nil {
+		t.Fatalf("Connect() err: %v", err)
+	}
+	defer x.Conn.Close()
+	get := func() string {
+		t.Helper()
+		result, err := x.Get([]string{testSysDescr})
+		if err != nil {
+			t.Fatalf("Get() err: %v", err)
+		}
+		return string(result.Variables[0].Value.([]byte))
+	}
+	if got := get(); got != "127.0.0.1" {
+		t.Errorf("Get() answered by %s, expected 127.0.0.1", got)
+	}
+
+	// the name moves to the second agent, and the first stops answering
+	mu.Lock()
+	addrs = []string{"127.0.0.2"}
+	mu.Unlock()
+	agents[0].Close()
+	silent, err := net.ListenPacket("udp4", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+	if err != nil {
+		t.Fatalf("ListenPacket() err: %v", err)
+	}
+	defer silent.Close()
+	if got := get(); got != "127.0.0.2" {
+		t.Errorf("Get() after the move answered by %s, expected 127.0.0.2", got)
+	}
+	if got := get(); got != "127.0.0.2" {
+		t.Errorf("Get() answered by %s, expected 127.0.0.2", got)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if lookups != 2 {
+		t.Errorf("resolved %d times, expected at Connect and on the retry", lookups)
+	}
+}
@@ -0,0 +1,218 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"net"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testResponder is a minimal v1/v2c agent used by tests. It answers GET,
+// GETNEXT and GETBULK requests from a fixed set of pdus, echoing back the
+// request id.
+type testResponder struct {
+	t    *testing.T
+	conn *net.UDPConn
+
+	mu       sync.Mutex
+	pdus     []SnmpPDU // sorted by oid
+	requests []*SnmpPacket
+	addr     net.Addr // of the last request
+
+	// handler, if set, replaces the default GET/GETNEXT/GETBULK handling.
+	// Returning nil drops the request without answering.
+	handler func(req *SnmpPacket) *SnmpPacket
+}
+
+func newTestResponder(t *testing.T, pdus []SnmpPDU) *testResponder {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("ListenUDP() err: %v", err)
+	}
+	r := &testResponder{t: t, conn: conn, pdus: append([]SnmpPDU(nil), pdus...)}
+	sort.Slice(r.pdus, func(i, j int) bool {
+		return testOidLess(r.pdus[i].Name, r.pdus[j].Name)
+	})
+	go r.serve()
+	return r
+}
+
+// client returns a connected GoSNMP for talking to the responder
+func (r *testResponder) client(t *testing.T) *GoSNMP {
+	x := &GoSNMP{
+		Target:    "127.0.0.1",
+		Port:      uint16(r.conn.LocalAddr().(*net.UDPAddr).Port),
+		Community: "public",
+		Version:   Version2c,
+		Timeout:   time.Second,
+		Retries:   1,
+		MaxOids:   MaxOids,
+	}
+	if err := x.Connect(); err != nil {
+		t.Fatalf("Connect() err: %v", err)
+	}
+	return x
+}
+
+func (r *testResponder) Close() {
+	r.conn.Close()
+}
+
+// received returns a copy of the requests seen so far
+func (r *testResponder) received() []*SnmpPacket {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*SnmpPacket(nil), r.requests...)
+}
+
+// setHandler replaces the responder's handler, see testResponder.handler
+func (r *testResponder) setHandler(handler func(req *SnmpPacket) *SnmpPacket) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handler = handler
+}
+
+// lastAddr returns the address the last request came from
+func (r *testResponder) lastAddr() net.Addr {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.addr
+}
+
+func (r *testResponder) serve() {
+	x := &GoSNMP{Version: Version2c}
+	x.validateParameters()
+	buf := make([]byte, rxBufSize)
+	for {
+		n, addr, err := r.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		msg := append([]byte(nil), buf[:n]...)
+
+		req := new(SnmpPacket)
+		cursor, err := x.unmarshalHeader(msg, req)
+		if err != nil {
+			r.t.Errorf("testResponder: %s", err)
+			continue
+		}
+		req.PDUType = PDUType(msg[cursor])
+		if err = x.unmarshalResponse(msg[cursor:], req); err != nil {
+			r.t.Errorf("testResponder: %s", err)
+			continue
+		}
+
+		r.mu.Lock()
+		r.requests = append(r.requests, req)
+		r.addr = addr
+		handler := r.handler
+		r.mu.Unlock()
+
+		var rsp *SnmpPacket
+		if handler != nil {
+			rsp = handler(req)
+		} else {
+			rsp = r.respond(req)
+		}
+		if rsp == nil {
+			continue
+		}
+		rsp.Version = req.Version
+		rsp.Community = req.Community
+		rsp.PDUType = GetResponse
+		rsp.RequestID = req.RequestID
+		out, err := rsp.marshalMsg()
+		if err != nil {
+			r.t.Errorf("testResponder: %s", err)
+			continue
+		}
+		r.conn.WriteTo(out, addr)
+	}
+}
+
+func (r *testResponder) respond(req *SnmpPacket) *SnmpPacket {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rsp := &SnmpPacket{}
+	switch req.PDUType {
+	case GetRequest:
+		for _, v := range req.Variables {
+			rsp.Variables = append(rsp.Variables, r.get(v.Name))
+		}
+	case GetNextRequest:
+		for _, v := range req.Variables {
+			rsp.Variables = append(rsp.Variables, r.next(v.Name))
+		}
+	case GetBulkRequest:
+		// as RFC 3416 has it, the repetitions of the varbinds are
+		// interleaved, one of each per row
+		reps := int(req.MaxRepetitions)
+		oids := make([]string, len(req.Variables))
+		for i, v := range req.Variables {
+			oids[i] = v.Name
+		}
+		for rep := 0; rep < reps; rep++ {
+			for i, oid := range oids {
+				pdu := r.next(oid)
+				rsp.Variables = append(rsp.Variables, pdu)
+				oids[i] = pdu.Name
+			}
+		}
+	}
+	return rsp
+}
+
+func (r *testResponder) get(oid string) SnmpPDU {
+	for _, pdu := range r.pdus {
+		if pdu.Name == oid {
+			return pdu
+		}
+	}
+	return SnmpPDU{Name: oid, Type: Null}
+}
+
+// next returns the first pdu after oid, or a pdu outside of any tree the
+// tests walk when the end of the data is reached
+func (r *testResponder) next(oid string) SnmpPDU {
+	for _, pdu := range r.pdus {
+		if testOidLess(oid, pdu.Name) {
+			return pdu
+		}
+	}
+	return SnmpPDU{Name: ".2.0", Type: Null}
+}
+
+// testOidLess compares two dotted oids numerically
+func testOidLess(a, b string) bool {
+	pa, _ := parseTestOid(a)
+	pb, _ := parseTestOid(b)
+	for i := 0; i < len(pa) && i < len(pb); i++ {
+		if pa[i] != pb[i] {
+			return pa[i] < pb[i]
+		}
+	}
+	return len(pa) < len(pb)
+}
+
+func parseTestOid(oid string) ([]int, error) {
+	var parts []int
+	n, digits := 0, 0
+	for i := 0; i <= len(oid); i++ {
+		if i == len(oid) || oid[i] == '.' {
+			if digits > 0 {
+				parts = append(parts, n)
+			}
+			n, digits = 0, 0
+			continue
+		}
+		n = n*10 + int(oid[i]-'0')
+		digits++
+	}
+	return parts, nil
+}
@@ -0,0 +1,87 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// WalkToken is an opaque token for resuming a walk that failed part way,
+// see WalkAllResume. It is a plain string so it can be stored between
+// runs.
+type WalkToken string
+
+const walkTokenVersion = "1"
+
+func newWalkToken(rootOid, lastOid string) WalkToken {
+	s := strings.Join([]string{walkTokenVersion, rootOid, lastOid}, " ")
+	return WalkToken(base64.RawURLEncoding.EncodeToString([]byte(s)))
+}
+
+// resumeOid returns the oid to resume a walk of rootOid after
+func (t WalkToken) resumeOid(rootOid string) (string, error) {
+	b, err := base64.RawURLEncoding.DecodeString(string(t))
+	parts := strings.Split(string(b), " ")
+	if err != nil || len(parts) != 3 || parts[0] != walkTokenVersion {
+		return "", fmt.Errorf("Invalid walk token %q", t)
+	}
+	if parts[1] != rootOid {
+		return "", fmt.Errorf("Walk token is for %s, not %s", parts[1], rootOid)
+	}
+	if parts[2] != "" && !OidInSubtree(parts[2], rootOid) {
+		return "", fmt.Errorf("Invalid walk token %q", t)
+	}
+	return parts[2], nil
+}
+
+// WalkAllResume is like WalkAll, but if the walk fails (eg with a timeout
+// over a flaky link) it returns a token along with the values retrieved
+// and the error. Passing the token to a later call resumes the walk after
+// the last of those values, rather than starting again. The token is empty
+// when the walk completes; an empty token starts a walk from the
+// beginning.
+func (x *GoSNMP) WalkAllResume(rootOid string, token WalkToken) ([]SnmpPDU, WalkToken, error) {
+	return x.walkAllResume(context.Background(), GetNextRequest, rootOid, token)
+}
+
+// WalkAllResumeCtx is like WalkAllResume, but the walk stops with
+// ctx.Err() when ctx is cancelled or its deadline passes, returning a token
+// to resume it.
+func (x *GoSNMP) WalkAllResumeCtx(ctx context.Context, rootOid string, token WalkToken) ([]SnmpPDU, WalkToken, error) {
+	return x.walkAllResume(ctx, GetNextRequest, rootOid, token)
+}
+
+// BulkWalkAllResume is like BulkWalkAll, but resumable, see WalkAllResume
+func (x *GoSNMP) BulkWalkAllResume(rootOid string, token WalkToken) ([]SnmpPDU, WalkToken, error) {
+	return x.walkAllResume(context.Background(), GetBulkRequest, rootOid, token)
+}
+
+// BulkWalkAllResumeCtx is like BulkWalkAllResume, but the walk stops with
+// ctx.Err() when ctx is cancelled or its deadline passes.
+func (x *GoSNMP) BulkWalkAllResumeCtx(ctx context.Context, rootOid string, token WalkToken) ([]SnmpPDU, WalkToken, error) {
+	return x.walkAllResume(ctx, GetBulkRequest, rootOid, token)
+}
+
+func (x *GoSNMP) walkAllResume(ctx context.Context, getRequestType PDUType, rootOid string, token WalkToken) (results []SnmpPDU, next WalkToken, err error) {
+	rootOid = walkRoot(rootOid)
+	last := ""
+	if token != "" {
+		if last, err = token.resumeOid(rootOid); err != nil {
+			return nil, "", err
+		}
+	}
+	err = x.walkFrom(ctx, getRequestType, rootOid, last, func(pdu SnmpPDU) error {
+		results = append(results, pdu)
+		last = pdu.Name
+		return nil
+	})
+	if err != nil {
+		return results, newWalkToken(rootOid, last), err
+	}
+	return results, "", nil
+}
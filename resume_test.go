@@ -0,0 +1,72 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWalkAllResume(t *testing.T) {
+	r := newTestResponder(t, tableTestPdus)
+	defer r.Close()
+	x := r.client(t)
+	defer x.Conn.Close()
+	x.Timeout = 50 * time.Millisecond
+	x.Retries = 0
+
+	// the agent stops answering after the second request
+	requests := 0
+	r.setHandler(func(req *SnmpPacket) *SnmpPacket {
+		if requests++; requests > 2 {
+			return nil
+		}
+		return r.respond(req)
+	})
+
+	const root = ".1.3.6.1.2.1.2.2"
+	results, token, err := x.WalkAllResume(root, "")
+	if err == nil || token == "" {
+		t.Fatalf("WalkAllResume() got token %q, err %v", token, err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("got %d results before the failure, want 2", len(results))
+	}
+
+	if _, _, err := x.WalkAllResume(".1.3.6.1.2.1.2", token); err == nil || !strings.Contains(err.Error(), "not .1.3.6.1.2.1.2") {
+		t.Errorf("token for another root: got %v", err)
+	}
+	if _, _, err := x.WalkAllResume(root, "junk"); err == nil || !strings.Contains(err.Error(), "Invalid walk token") {
+		t.Errorf("invalid token: got %v", err)
+	}
+
+	// with the agent back, the walk carries on after the last value
+	r.setHandler(nil)
+	before := len(r.received())
+	rest, token, err := x.BulkWalkAllResume(root, token)
+	if err != nil || token != "" {
+		t.Fatalf("BulkWalkAllResume() got token %q, err %v", token, err)
+	}
+	if first := r.received()[before].Variables[0].Name; first != results[1].Name {
+		t.Errorf("resumed after %s, want %s", first, results[1].Name)
+	}
+
+	var names, want []string
+	for _, pdu := range append(results, rest...) {
+		names = append(names, pdu.Name)
+	}
+	sorted := append([]SnmpPDU(nil), tableTestPdus...)
+	SortPDUs(sorted)
+	for _, pdu := range sorted {
+		if OidInSubtree(pdu.Name, root) {
+			want = append(want, pdu.Name)
+		}
+	}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("got %v, want %v", names, want)
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy decides how many times a request is sent, and how long each
+// attempt waits for a response before the request is sent again, see
+// GoSNMP.RetryPolicy
+type RetryPolicy interface {
+	// Wait returns how long attempt n (counting from 0) waits for a
+	// response, or false if no attempt n should be made. Attempt 0 is
+	// always made.
+	Wait(n int) (time.Duration, bool)
+
+	// Deadline returns the time allowed for a request across all of its
+	// attempts, or 0 for no limit beyond the attempts' own waits
+	Deadline() time.Duration
+}
+
+// ExponentialBackoff is a RetryPolicy whose waits grow by Multiplier with
+// each attempt, and are varied at random by Jitter so that pollers that
+// lose responses from an agent together don't retry together.
+type ExponentialBackoff struct {
+	// Initial is the wait of the first attempt
+	Initial time.Duration
+
+	// Multiplier is the factor each wait is bigger than the one before it,
+	// eg 1 for equal waits (default: 2)
+	Multiplier float64
+
+	// Max is the longest wait of an attempt (default: no maximum)
+	Max time.Duration
+
+	// Jitter is the fraction of each wait, from 0 to 1, that is varied at
+	// random, eg 0.2 for waits between 80% and 120% of their nominal value
+	Jitter float64
+
+	// Attempts is the number of times a request is sent (default: 1)
+	Attempts int
+
+	// MaxElapsed is the time allowed for a request across all of its
+	// attempts (default: no limit)
+	MaxElapsed time.Duration
+}
+
+// Wait returns the jittered wait of attempt n, see RetryPolicy
+func (b *ExponentialBackoff) Wait(n int) (time.Duration, bool) {
+	if n > 0 && n >= b.Attempts {
+		return 0, false
+	}
+	multiplier := b.Multiplier
+	if multiplier <= 0 {
+		multiplier = 2
+	}
+	wait := float64(b.Initial) * math.Pow(multiplier, float64(n))
+	if b.Max > 0 && wait > float64(b.Max) {
+		wait = float64(b.Max)
+	}
+	if b.Jitter > 0 {
+		wait *= 1 + b.Jitter*(2*rand.Float64()-1)
+	}
+	if wait > math.MaxInt64 {
+		return math.MaxInt64, true
+	}
+	return time.Duration(wait), true
+}
+
+// Deadline returns MaxElapsed, see RetryPolicy
+func (b *ExponentialBackoff) Deadline() time.Duration {
+	return b.MaxElapsed
+}
+
+const (
+	// defaultRetryJitter is the Jitter of the RetryPolicy made from
+	// Timeout and Retries
+	defaultRetryJitter = 0.1
+
+	// minDefaultRetryWait is the shortest wait of an attempt of the
+	// RetryPolicy made from Timeout and Retries, however many Retries
+	// share Timeout
+	minDefaultRetryWait = time.Millisecond
+)
+
+// retryPolicy returns the RetryPolicy for requests made with ctx: the
+// one set with RequestRetryPolicy or x.RetryPolicy, unless Timeout or
+// Retries is overridden for ctx, or otherwise Retries+1 attempts each
+// waiting an equal share of Timeout, jittered
+func (x *GoSNMP) retryPolicy(ctx context.Context) RetryPolicy {
+	o := optionsFrom(ctx)
+	if o.retryPolicy != nil {
+		return o.retryPolicy
+	}
+	if x.RetryPolicy != nil && o.timeout == nil && o.retries == nil {
+		return x.RetryPolicy
+	}
+	timeout, retries := x.Timeout, x.Retries
+	if o.timeout != nil {
+		timeout = *o.timeout
+	}
+	if o.retries != nil {
+		retries = *o.retries
+	}
+	attempts := retries + 1
+	if attempts < 1 {
+		attempts = 1
+	}
+	wait := timeout / time.Duration(attempts)
+	if wait < minDefaultRetryWait && timeout > 0 {
+		wait = minDefaultRetryWait
+		if timeout < wait {
+			wait = timeout
+		}
+	}
+	return &ExponentialBackoff{
+		Initial:    wait,
+		Multiplier: 1,
+		Jitter:     defaultRetryJitter,
+		Attempts:   attempts,
+		MaxElapsed: timeout,
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestExponentialBackoff(t *testing.T) {
+	tests := []struct {
+		policy ExponentialBackoff
+		want   []time.Duration // nominal waits of the attempts made
+	}{
+		{ExponentialBackoff{Initial: 100 * time.Millisecond, Attempts: 4},
+			[]time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond}},
+		{ExponentialBackoff{Initial: 100 * time.Millisecond, Multiplier: 3, Max: 500 * time.Millisecond, Attempts: 3},
+			[]time.Duration{100 * time.Millisecond, 300 * time.Millisecond, 500 * time.Millisecond}},
+		{ExponentialBackoff{Initial: time.Second, Jitter: 0.2, Attempts: 2},
+			[]time.Duration{time.Second, 2 * time.Second}},
+		{ExponentialBackoff{Initial: time.Second}, []time.Duration{time.Second}},
+	}
+	for i, test := range tests {
+		for n := 0; n <= len(test.want); n++ {
+			wait, ok := test.policy.Wait(n)
+			if n == len(test.want) {
+				if ok {
+					t.Errorf("#%d: attempt %d allowed, want %d attempts", i, n, len(test.want))
+				}
+				break
+			}
+			spread := time.Duration(test.policy.Jitter * float64(test.want[n]))
+			if !ok || wait < test.want[n]-spread || wait > test.want[n]+spread {
+				t.Errorf("#%d: attempt %d got %v (%t), want %v±%v", i, n, wait, ok, test.want[n], spread)
+			}
+		}
+	}
+}
+
+func TestDefaultRetryPolicy(t *testing.T) {
+	tests := []struct {
+		timeout time.Duration
+		retries int
+		want    time.Duration
+	}{
+		{2 * time.Second, 3, 500 * time.Millisecond},
+		{600 * time.Millisecond, 2, 200 * time.Millisecond},
+		{time.Second, 0, time.Second},
+		{2 * time.Second, 31, 62500 * time.Microsecond},
+		{2 * time.Second, 1 << 30, minDefaultRetryWait},
+		{time.Microsecond, 100, time.Microsecond},
+	}
+	for _, test := range tests {
+		x := &GoSNMP{Timeout: test.timeout, Retries: test.retries}
+		policy := x.retryPolicy(context.Background())
+		if policy.Deadline() != x.Timeout {
+			t.Errorf("Retries %d: got deadline %v, want %v", test.retries, policy.Deadline(), x.Timeout)
+		}
+		for _, n := range []int{0, test.retries / 2, test.retries} {
+			wait, ok := policy.Wait(n)
+			spread := time.Duration(defaultRetryJitter * float64(test.want))
+			if !ok || wait < test.want-spread || wait > test.want+spread {
+				t.Errorf("Retries %d: attempt %d got %v (%t), want %v±%v", test.retries, n, wait, ok, test.want, spread)
+			}
+		}
+		if _, ok := policy.Wait(test.retries + 1); ok {
+			t.Errorf("Retries %d: got attempt %d", test.retries, test.retries+1)
+		}
+	}
+}
+
+// fixedRetries is a RetryPolicy with the given waits
+type fixedRetries struct {
+	waits    []time.Duration
+	deadline time.Duration
+}
+
+func (f fixedRetries) Wait(n int) (time.Duration, bool) {
+	if n >= len(f.waits) {
+		return 0, false
+	}
+	return f.waits[n], true
+}
+
+func (f fixedRetries) Deadline() time.Duration {
+	return f.deadline
+}
+
+func TestRetryPolicyRequests(t *testing.T) {
+	r := newTestResponder(t, []SnmpPDU{{Name: ".1.3.6.1.2.1.1.5.0", Type: OctetString, Value: []byte("a")}})
+	defer r.Close()
+	x := r.client(t)
+	defer x.Conn.Close()
+
+	tests := []struct {
+		policy fixedRetries
+		drop   int // requests dropped before answering
+		want   int // requests sent
+		err    bool
+	}{
+		{fixedRetries{waits: []time.Duration{20 * time.Millisecond, 40 * time.Millisecond, 80 * time.Millisecond}}, 1, 2, false},
+		{fixedRetries{waits: []time.Duration{20 * time.Millisecond, 40 * time.Millisecond, 80 * time.Millisecond}}, 5, 3, true},
+		// the deadline cuts off the attempts
+		{fixedRetries{waits: []time.Duration{30 * time.Millisecond, 30 * time.Millisecond, 30 * time.Millisecond, 30 * time.Millisecond},
+			deadline: 45 * time.Millisecond}, 5, 2, true},
+	}
+	for i, test := range tests {
+		x.RetryPolicy = test.policy
+		seen := len(r.received())
+		r.setHandler(func(req *SnmpPacket) *SnmpPacket {
+			if len(r.received())-seen <= test.drop {
+				return nil
+			}
+			return r.respond(req)
+		})
+		start := time.Now()
+		_, err := x.Get([]string{".1.3.6.1.2.1.1.5.0"})
+		elapsed := time.Since(start)
+		if test.err != (err != nil) || (err != nil && !errors.Is(err, ErrTimeout)) {
+			t.Errorf("#%d: Get() err: %v", i, err)
+		}
+		if got := len(r.received()) - seen; got != test.want {
+			t.Errorf("#%d: got %d requests, want %d", i, got, test.want)
+		}
+		if test.policy.deadline > 0 && elapsed > 2*test.policy.deadline {
+			t.Errorf("#%d: took %v, with a deadline of %v", i, elapsed, test.policy.deadline)
+		}
+	}
+}
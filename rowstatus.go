@@ -0,0 +1,198 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"context"
+	"fmt"
+)
+
+// RowStatus is the value of a RowStatus (SNMPv2-TC) column, used to create
+// and delete the rows of tables such as snmpTargetAddrTable
+type RowStatus int
+
+// The values of RowStatus. Active, NotInService and NotReady are states of
+// a row; CreateAndGo, CreateAndWait and Destroy are actions that are only
+// written.
+const (
+	RowStatusActive        RowStatus = 1
+	RowStatusNotInService  RowStatus = 2
+	RowStatusNotReady      RowStatus = 3
+	RowStatusCreateAndGo   RowStatus = 4
+	RowStatusCreateAndWait RowStatus = 5
+	RowStatusDestroy       RowStatus = 6
+)
+
+func (s RowStatus) String() string {
+	switch s {
+	case RowStatusActive:
+		return "active"
+	case RowStatusNotInService:
+		return "notInService"
+	case RowStatusNotReady:
+		return "notReady"
+	case RowStatusCreateAndGo:
+		return "createAndGo"
+	case RowStatusCreateAndWait:
+		return "createAndWait"
+	case RowStatusDestroy:
+		return "destroy"
+	}
+	return fmt.Sprintf("RowStatus(%d)", int(s))
+}
+
+// GetRowStatus reads the RowStatus column instance statusOid, eg
+// ".1.3.6.1.6.3.12.1.2.1.9.3.110.109.115" for snmpTargetAddrRowStatus."nms".
+// It is an error if the row doesn't exist.
+func (x *GoSNMP) GetRowStatus(statusOid string) (RowStatus, error) {
+	return x.GetRowStatusCtx(context.Background(), statusOid)
+}
+
+// GetRowStatusCtx is like GetRowStatus, but the request is abandoned when
+// ctx is cancelled or its deadline passes.
+func (x *GoSNMP) GetRowStatusCtx(ctx context.Context, statusOid string) (RowStatus, error) {
+	status, exists, err := x.rowStatus(ctx, statusOid)
+	if err == nil && !exists {
+		err = fmt.Errorf("Row %s does not exist", statusOid)
+	}
+	return status, err
+}
+
+func (x *GoSNMP) rowStatus(ctx context.Context, statusOid string) (status RowStatus, exists bool, err error) {
+	result, err := x.GetCtx(ctx, []string{statusOid})
+	if err != nil {
+		return 0, false, err
+	}
+	if result.Error == NoSuchName {
+		return 0, false, nil
+	}
+	if result.Error != NoError {
+		return 0, false, fmt.Errorf("Unable to get %s: %w", statusOid, result.Err())
+	}
+	if len(result.Variables) != 1 {
+		return 0, false, fmt.Errorf("Unable to get %s: %d varbinds returned", statusOid, len(result.Variables))
+	}
+	pdu := result.Variables[0]
+	switch pdu.Type {
+	case NoSuchObject, NoSuchInstance, Null:
+		return 0, false, nil
+	}
+	n, err := pdu.Int64()
+	if err != nil {
+		return 0, false, err
+	}
+	return RowStatus(n), true, nil
+}
+
+// setRow sets statusOid to status along with columns in a single request
+func (x *GoSNMP) setRow(ctx context.Context, statusOid string, status RowStatus, columns []SnmpPDU) error {
+	pdus := append([]SnmpPDU{{Name: statusOid, Type: Integer, Value: int(status)}}, columns...)
+	result, err := x.SetCtx(ctx, pdus)
+	if err != nil {
+		return err
+	}
+	if result.Error != NoError {
+		return fmt.Errorf("Unable to set %s to %s: %w", statusOid, status,
+			newStatusError(result.Error, int(result.ErrorIndex), pdus))
+	}
+	return nil
+}
+
+// CreateRow creates a row by setting its RowStatus column instance
+// statusOid to createAndGo, along with the columns of the row in the same
+// request, and then checks that the row is active.
+func (x *GoSNMP) CreateRow(statusOid string, columns []SnmpPDU) error {
+	return x.CreateRowCtx(context.Background(), statusOid, columns)
+}
+
+// CreateRowCtx is like CreateRow, but the requests are abandoned when ctx
+// is cancelled or its deadline passes.
+func (x *GoSNMP) CreateRowCtx(ctx context.Context, statusOid string, columns []SnmpPDU) error {
+	if err := x.setRow(ctx, statusOid, RowStatusCreateAndGo, columns); err != nil {
+		return err
+	}
+	return x.checkRowStatus(ctx, statusOid, RowStatusActive)
+}
+
+// CreateRowAndWait creates a row step by step, for agents that don't
+// accept a row's columns in the request creating it: the RowStatus column
+// instance statusOid is set to createAndWait, then the columns are set,
+// and once the agent reports the row as notInService it is set to active.
+// If any step fails the row is destroyed rather than left half created.
+func (x *GoSNMP) CreateRowAndWait(statusOid string, columns []SnmpPDU) error {
+	return x.CreateRowAndWaitCtx(context.Background(), statusOid, columns)
+}
+
+// CreateRowAndWaitCtx is like CreateRowAndWait, but the requests are
+// abandoned when ctx is cancelled or its deadline passes.
+func (x *GoSNMP) CreateRowAndWaitCtx(ctx context.Context, statusOid string, columns []SnmpPDU) error {
+	ctx, cancel := x.withBudget(ctx)
+	defer cancel()
+	if err := x.setRow(ctx, statusOid, RowStatusCreateAndWait, nil); err != nil {
+		return err
+	}
+	err := x.activateRow(ctx, statusOid, columns)
+	if err != nil {
+		if destroyErr := x.setRow(ctx, statusOid, RowStatusDestroy, nil); destroyErr != nil {
+			x.logPrintf("Unable to destroy row %s: %s", statusOid, destroyErr)
+		}
+	}
+	return err
+}
+
+func (x *GoSNMP) activateRow(ctx context.Context, statusOid string, columns []SnmpPDU) error {
+	if len(columns) > 0 {
+		result, err := x.SetCtx(ctx, columns)
+		if err != nil {
+			return err
+		}
+		if result.Error != NoError {
+			return fmt.Errorf("Unable to set the columns of row %s: %w", statusOid,
+				newStatusError(result.Error, int(result.ErrorIndex), columns))
+		}
+	}
+	if err := x.checkRowStatus(ctx, statusOid, RowStatusNotInService); err != nil {
+		return err
+	}
+	if err := x.setRow(ctx, statusOid, RowStatusActive, nil); err != nil {
+		return err
+	}
+	return x.checkRowStatus(ctx, statusOid, RowStatusActive)
+}
+
+// checkRowStatus reads back the status of a row, checking that it is want
+func (x *GoSNMP) checkRowStatus(ctx context.Context, statusOid string, want RowStatus) error {
+	status, err := x.GetRowStatusCtx(ctx, statusOid)
+	if err != nil {
+		return err
+	}
+	if status != want {
+		return fmt.Errorf("Row %s is %s, expected %s", statusOid, status, want)
+	}
+	return nil
+}
+
+// DestroyRow deletes a row by setting its RowStatus column instance
+// statusOid to destroy, and then checks that the row has gone. Destroying
+// a row that doesn't exist isn't an error.
+func (x *GoSNMP) DestroyRow(statusOid string) error {
+	return x.DestroyRowCtx(context.Background(), statusOid)
+}
+
+// DestroyRowCtx is like DestroyRow, but the requests are abandoned when
+// ctx is cancelled or its deadline passes.
+func (x *GoSNMP) DestroyRowCtx(ctx context.Context, statusOid string) error {
+	if err := x.setRow(ctx, statusOid, RowStatusDestroy, nil); err != nil {
+		return err
+	}
+	status, exists, err := x.rowStatus(ctx, statusOid)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("Row %s still exists after destroy, and is %s", statusOid, status)
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+const (
+	testRowStatusOid = ".1.3.6.1.6.3.12.1.2.1.9.3.110.109.115"
+	testRowColumnOid = ".1.3.6.1.6.3.12.1.2.1.3.3.110.109.115"
+)
+
+// testRowAgent handles requests for a single row of a table with a
+// RowStatus column and one other column
+type testRowAgent struct {
+	mu      sync.Mutex
+	exists  bool
+	status  RowStatus
+	column  bool // whether the other column has been set
+	noGo    bool // reject createAndGo, as some agents do
+	sets    []RowStatus
+	destroy bool // whether destroy was requested
+}
+
+func (a *testRowAgent) handle(req *SnmpPacket) *SnmpPacket {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	rsp := &SnmpPacket{Variables: req.Variables}
+	switch req.PDUType {
+	case GetRequest:
+		rsp.Variables = nil
+		for _, v := range req.Variables {
+			if v.Name == testRowStatusOid && a.exists {
+				rsp.Variables = append(rsp.Variables, SnmpPDU{Name: v.Name, Type: Integer, Value: int(a.status)})
+			} else {
+				rsp.Variables = append(rsp.Variables, SnmpPDU{Name: v.Name, Type: Null})
+			}
+		}
+	case SetRequest:
+		for i, v := range req.Variables {
+			fail := func(err SNMPError) *SnmpPacket {
+				rsp.Error, rsp.ErrorIndex = err, uint8(i+1)
+				return rsp
+			}
+			if v.Name == testRowColumnOid {
+				if !a.exists {
+					return fail(NoCreation)
+				}
+				a.column = true
+				continue
+			}
+			status := RowStatus(v.Value.(int))
+			a.sets = append(a.sets, status)
+			switch status {
+			case RowStatusCreateAndGo:
+				if a.noGo || len(req.Variables) == 1 {
+					return fail(InconsistentValue)
+				}
+				a.exists, a.status, a.column = true, RowStatusActive, true
+				return rsp
+			case RowStatusCreateAndWait:
+				a.exists, a.status = true, RowStatusNotReady
+			case RowStatusActive:
+				if a.status != RowStatusNotInService {
+					return fail(InconsistentValue)
+				}
+				a.status = RowStatusActive
+			case RowStatusDestroy:
+				a.exists, a.column, a.destroy = false, false, true
+			default:
+				return fail(WrongValue)
+			}
+		}
+		if a.exists && a.status == RowStatusNotReady && a.column {
+			a.status = RowStatusNotInService
+		}
+	}
+	return rsp
+}
+
+// state returns whether the row exists and its status
+func (a *testRowAgent) state() (bool, RowStatus, []RowStatus, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.exists, a.status, append([]RowStatus(nil), a.sets...), a.destroy
+}
+
+var testRowColumns = []SnmpPDU{{Name: testRowColumnOid, Type: OctetString, Value: "192.0.2.1"}}
+
+func TestCreateRow(t *testing.T) {
+	r := newTestResponder(t, nil)
+	defer r.Close()
+	agent := &testRowAgent{}
+	r.setHandler(agent.handle)
+	x := r.client(t)
+	defer x.Conn.Close()
+
+	if err := x.CreateRow(testRowStatusOid, testRowColumns); err != nil {
+		t.Fatalf("CreateRow() err: %v", err)
+	}
+	if status, err := x.GetRowStatus(testRowStatusOid); err != nil || status != RowStatusActive {
+		t.Errorf("GetRowStatus(): got %s, %v", status, err)
+	}
+	if err := x.DestroyRow(testRowStatusOid); err != nil {
+		t.Errorf("DestroyRow() err: %v", err)
+	}
+	if _, err := x.GetRowStatus(testRowStatusOid); err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Errorf("GetRowStatus() of destroyed row: got %v", err)
+	}
+
+	agent.mu.Lock()
+	agent.noGo = true
+	agent.mu.Unlock()
+	err := x.CreateRow(testRowStatusOid, testRowColumns)
+	if err == nil || !strings.Contains(err.Error(), "InconsistentValue") {
+		t.Errorf("CreateRow() rejected: got %v", err)
+	}
+}
+
+func TestCreateRowAndWait(t *testing.T) {
+	r := newTestResponder(t, nil)
+	defer r.Close()
+	agent := &testRowAgent{noGo: true}
+	r.setHandler(agent.handle)
+	x := r.client(t)
+	defer x.Conn.Close()
+
+	if err := x.CreateRowAndWait(testRowStatusOid, testRowColumns); err != nil {
+		t.Fatalf("CreateRowAndWait() err: %v", err)
+	}
+	exists, status, sets, _ := agent.state()
+	if !exists || status != RowStatusActive {
+		t.Errorf("row is %s", status)
+	}
+	want := []RowStatus{RowStatusCreateAndWait, RowStatusActive}
+	if len(sets) != len(want) || sets[0] != want[0] || sets[1] != want[1] {
+		t.Errorf("got sets %v, want %v", sets, want)
+	}
+
+	// without its columns the row isn't ready, and is destroyed again
+	agent = &testRowAgent{noGo: true}
+	r.setHandler(agent.handle)
+	err := x.CreateRowAndWait(testRowStatusOid, nil)
+	if err == nil || !strings.Contains(err.Error(), "is notReady, expected notInService") {
+		t.Errorf("CreateRowAndWait() without columns: got %v", err)
+	}
+	if exists, _, _, destroyed := agent.state(); exists || !destroyed {
+		t.Errorf("half created row not destroyed")
+	}
+}
+
+func TestRowStatusString(t *testing.T) {
+	if s := RowStatusCreateAndWait.String(); s != "createAndWait" {
+		t.Errorf("got %s", s)
+	}
+	if s := RowStatus(0).String(); s != "RowStatus(0)" {
+		t.Errorf("got %s", s)
+	}
+}
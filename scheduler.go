@@ -0,0 +1,230 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+//
+// Periodic polling
+//
+
+// PollJob is a set of oids to get from a target every Interval, see
+// Scheduler
+type PollJob struct {
+	Target   string
+	Oids     []string
+	Interval time.Duration
+
+	// Rates makes polls report the counters among the values as the rates
+	// they increased at since the previous poll, in PollResult.Rates,
+	// rather than with the other values. sysUpTime is polled too, and the
+	// samples of a target are dropped when it shows the agent restarted,
+	// so that there are no rates from the counters being reset.
+	Rates bool
+}
+
+// PollResult is the result of one poll of a PollJob
+type PollResult struct {
+	// ID is the job's id, as returned by Scheduler.Add
+	ID  int
+	Job PollJob
+
+	// Time is when the poll was scheduled for, which the poll may have
+	// started a little after if the target was busy
+	Time time.Time
+
+	// Variables and Err are as for a Manager Get
+	Variables []SnmpPDU
+	Err       error
+
+	// Rates are the rates of the job's counters, if it has Rates set, for
+	// those with a sample from an earlier poll to compare with
+	Rates []PollRate
+}
+
+// Scheduler polls jobs added to it while Run is running, calling Handler
+// with the result of each poll. The first poll of a job is at a random
+// time within its first Interval, so that jobs added together don't poll
+// together, and later polls are each a whole Interval after it: a slow
+// poll doesn't make the polls after it late. Polls that would start while
+// the one before them is still running are skipped. Polls of the same
+// target are made one at a time.
+//
+// A Scheduler is safe for concurrent use, but must not be copied after
+// first use.
+type Scheduler struct {
+	// Manager makes the polls. If nil, a zero Manager is used, creating
+	// sessions from Default.
+	Manager *Manager
+
+	// Handler is called with each PollResult. It is called from a
+	// goroutine of each job, so must be safe for concurrent use.
+	Handler func(PollResult)
+
+	mu      sync.Mutex
+	ctx     context.Context // of Run, while it is running
+	jobs    map[int]*pollJob
+	nextID  int
+	targets map[string]chan struct{} // held while polling a target
+	manager *Manager
+	wg      sync.WaitGroup
+
+	samples map[rateKey]counterSample // the last of each counter, for Rates
+	boots   map[string]time.Time      // of each target, for Rates
+}
+
+type pollJob struct {
+	PollJob
+	id     int
+	cancel context.CancelFunc // stops the job's goroutine, if running
+}
+
+// Add adds job to the scheduler, returning an id for Remove. If the
+// scheduler is running, the job starts straight away.
+func (s *Scheduler) Add(job PollJob) (int, error) {
+	if job.Interval <= 0 {
+		return 0, fmt.Errorf("Invalid poll interval %v for %s", job.Interval, job.Target)
+	}
+	job.Oids = append([]string(nil), job.Oids...)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.jobs == nil {
+		s.jobs = make(map[int]*pollJob)
+	}
+	s.nextID++
+	j := &pollJob{PollJob: job, id: s.nextID}
+	s.jobs[j.id] = j
+	if s.ctx != nil {
+		s.start(j)
+	}
+	return j.id, nil
+}
+
+// Remove stops and removes the job with id. Its poll in progress, if any,
+// is abandoned and not reported.
+func (s *Scheduler) Remove(id int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if j, ok := s.jobs[id]; ok {
+		if j.cancel != nil {
+			j.cancel()
+		}
+		delete(s.jobs, id)
+	}
+}
+
+// Run polls the scheduler's jobs until ctx is done, and then waits for
+// the polls in progress to be abandoned before returning ctx.Err().
+func (s *Scheduler) Run(ctx context.Context) error {
+	s.mu.Lock()
+	if s.ctx != nil {
+		s.mu.Unlock()
+		return fmt.Errorf("Scheduler is already running")
+	}
+	s.ctx = ctx
+	for _, j := range s.jobs {
+		s.start(j)
+	}
+	s.mu.Unlock()
+
+	<-ctx.Done()
+
+	s.mu.Lock()
+	s.ctx = nil
+	for _, j := range s.jobs {
+		j.cancel = nil
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+	return ctx.Err()
+}
+
+// start starts the goroutine of j, s.mu must be held
+func (s *Scheduler) start(j *pollJob) {
+	if s.manager == nil {
+		s.manager = s.Manager
+		if s.manager == nil {
+			s.manager = new(Manager)
+		}
+	}
+	var ctx context.Context
+	ctx, j.cancel = context.WithCancel(s.ctx)
+	s.wg.Add(1)
+	go s.run(ctx, j)
+}
+
+// run polls j until ctx is done
+func (s *Scheduler) run(ctx context.Context, j *pollJob) {
+	defer s.wg.Done()
+	next := time.Now().Add(time.Duration(rand.Int63n(int64(j.Interval))))
+	timer := time.NewTimer(time.Until(next))
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+		s.poll(ctx, j, next)
+
+		// the first tick after now, on the schedule of the first poll
+		next = next.Add(j.Interval)
+		if now := time.Now(); !next.After(now) {
+			next = next.Add((now.Sub(next)/j.Interval + 1) * j.Interval)
+		}
+		timer.Reset(time.Until(next))
+	}
+}
+
+// poll makes the poll of j scheduled for at, once no other poll of its
+// target is in progress
+func (s *Scheduler) poll(ctx context.Context, j *pollJob, at time.Time) {
+	busy := s.target(j.Target)
+	select {
+	case busy <- struct{}{}:
+	case <-ctx.Done():
+		return
+	}
+	result := s.manager.GetCtx(ctx, []string{j.Target}, j.pollOids())[0]
+	received := time.Now()
+	<-busy
+	if ctx.Err() != nil {
+		return
+	}
+	poll := PollResult{
+		ID:        j.id,
+		Job:       j.PollJob,
+		Time:      at,
+		Variables: result.Variables,
+		Err:       result.Err,
+	}
+	if j.Rates && poll.Err == nil {
+		poll.Variables, poll.Rates = s.rates(j, poll.Variables, received)
+	}
+	if s.Handler != nil {
+		s.Handler(poll)
+	}
+}
+
+func (s *Scheduler) target(target string) chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.targets == nil {
+		s.targets = make(map[string]chan struct{})
+	}
+	busy, ok := s.targets[target]
+	if !ok {
+		busy = make(chan struct{}, 1)
+		s.targets[target] = busy
+	}
+	return busy
+}
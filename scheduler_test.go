@@ -0,0 +1,72 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestScheduler(t *testing.T) {
+	const oid = ".1.3.6.1.2.1.1.5.0"
+	r := newTestResponder(t, []SnmpPDU{{Name: oid, Type: OctetString, Value: []byte("name")}})
+	defer r.Close()
+	m := &Manager{Template: &GoSNMP{
+		Port:      uint16(r.conn.LocalAddr().(*net.UDPAddr).Port),
+		Community: "public",
+		Version:   Version2c,
+		Timeout:   time.Second,
+		MaxOids:   MaxOids,
+	}}
+	defer m.Close()
+
+	var mu sync.Mutex
+	polls := make(map[int][]PollResult)
+	s := &Scheduler{Manager: m, Handler: func(result PollResult) {
+		mu.Lock()
+		defer mu.Unlock()
+		polls[result.ID] = append(polls[result.ID], result)
+	}}
+	if _, err := s.Add(PollJob{Target: "127.0.0.1", Oids: []string{oid}}); err == nil {
+		t.Errorf("Add() of a job without an interval succeeded")
+	}
+	const interval = 20 * time.Millisecond
+	kept, _ := s.Add(PollJob{Target: "127.0.0.1", Oids: []string{oid}, Interval: interval})
+	removed, _ := s.Add(PollJob{Target: "127.0.0.1", Oids: []string{oid}, Interval: interval})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*interval)
+	defer cancel()
+	go func() {
+		time.Sleep(3 * interval)
+		s.Remove(removed)
+		if err := s.Run(ctx); err == nil {
+			t.Errorf("Run() while running succeeded")
+		}
+	}()
+	if err := s.Run(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Run() err: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if n := len(polls[kept]); n < 5 || n > 10 {
+		t.Errorf("got %d polls in %v, every %v", n, 10*interval, interval)
+	}
+	if n := len(polls[removed]); n > 3 {
+		t.Errorf("got %d polls of a job removed after %v", n, 3*interval)
+	}
+	for i, result := range polls[kept] {
+		if result.Err != nil || len(result.Variables) != 1 || result.Variables[0].Name != oid {
+			t.Errorf("poll %d got %v, err: %v", i, result.Variables, result.Err)
+		}
+		// polls keep to the schedule of the first
+		if i > 0 && result.Time.Sub(polls[kept][0].Time)%interval != 0 {
+			t.Errorf("poll %d at %v, not a whole interval after the first at %v", i, result.Time, polls[kept][0].Time)
+		}
+	}
+}
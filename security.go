@@ -0,0 +1,212 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"sync"
+)
+
+//
+// The registry of SNMPv3 security models
+//
+
+var (
+	securityModelsMu sync.RWMutex
+
+	// securityModels makes the empty SnmpV3SecurityParameters of each
+	// security model, by number
+	securityModels = map[SnmpV3SecurityModel]func() SnmpV3SecurityParameters{
+		UserSecurityModel: func() SnmpV3SecurityParameters { return new(UsmSecurityParameters) },
+	}
+)
+
+// RegisterSecurityModel registers an implementation of a security model,
+// by its number (an SnmpSecurityModel of RFC 3411, eg 4 for the Transport
+// Security Model of RFC 5591), so that sessions and trap listeners can
+// use it. newParameters returns empty parameters of the model, that the
+// security parameters of the messages received with it are unmarshalled
+// into, when they aren't those of the session. A listener only accepts
+// SNMPv3 traps of the model of its Params, and Agents only answer
+// requests of the User Security Model.
+//
+// Models implemented outside the package implement
+// ExternalSecurityParameters, see WrapSecurityParameters. A model can be
+// registered once; the User Security Model is registered already.
+func RegisterSecurityModel(model SnmpV3SecurityModel, newParameters func() SnmpV3SecurityParameters) error {
+	if model == 0 || newParameters == nil {
+		return fmt.Errorf("Unable to register security model %d without parameters", model)
+	}
+	securityModelsMu.Lock()
+	defer securityModelsMu.Unlock()
+	if _, ok := securityModels[model]; ok {
+		return fmt.Errorf("Security model %d is already registered", model)
+	}
+	securityModels[model] = newParameters
+	return nil
+}
+
+// securityModelRegistered reports whether model has been registered
+func securityModelRegistered(model SnmpV3SecurityModel) bool {
+	securityModelsMu.RLock()
+	defer securityModelsMu.RUnlock()
+	_, ok := securityModels[model]
+	return ok
+}
+
+// newSecurityParameters returns empty parameters of model, set up with
+// logger, to unmarshal those of a message into
+func newSecurityParameters(model SnmpV3SecurityModel, logger Logger) (SnmpV3SecurityParameters, error) {
+	securityModelsMu.RLock()
+	newParameters, ok := securityModels[model]
+	securityModelsMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("Unable to parse V3 packet - unknown security model %d", model)
+	}
+	if logger == nil {
+		logger = log.New(ioutil.Discard, "", 0)
+	}
+	sp := newParameters()
+	if err := sp.init(logger); err != nil {
+		return nil, err
+	}
+	return sp, nil
+}
+
+// ExternalSecurityParameters are the security parameters of a security
+// model implemented outside the package. Its methods are those of
+// SnmpV3SecurityParameters, exported, as they are called by
+// WrapSecurityParameters:
+//
+//   - SecurityModel returns the number of the model
+//   - Validate checks the parameters of a session with flags
+//   - Init sets up the parameters of a session, eg the source of salts
+//   - InitPacket sets up the parameters of packet, a copy of the
+//     session's, to send it
+//   - DiscoveryRequired returns the request discovering what the
+//     parameters need from the agent, or nil if nothing is needed
+//   - DefaultContextEngineID returns the contextEngineID of requests, once
+//     discovered
+//   - SetSecurityParameters updates the parameters with those of in, eg
+//     what was discovered
+//   - Marshal and Unmarshal encode and decode the msgSecurityParameters
+//     of a message; Unmarshal is given the whole message, and the cursor at
+//     the start of the parameters' contents, and returns the cursor after
+//     them
+//   - Authenticate authenticates msg, an encoded message, in place;
+//     IsAuthentic checks an encoded message, with packet its header
+//   - EncryptPacket encrypts an encoded scopedPDU; DecryptPacket decrypts
+//     the encrypted scopedPDU at cursor of a message, returning the
+//     message with the scopedPDU in plain text
+type ExternalSecurityParameters interface {
+	SecurityModel() SnmpV3SecurityModel
+	Log()
+	Copy() ExternalSecurityParameters
+	Validate(flags SnmpV3MsgFlags) error
+	Init(log Logger) error
+	InitPacket(packet *SnmpPacket) error
+	DiscoveryRequired() *SnmpPacket
+	DefaultContextEngineID() string
+	SetSecurityParameters(in ExternalSecurityParameters) error
+	Marshal(flags SnmpV3MsgFlags) ([]byte, error)
+	Unmarshal(flags SnmpV3MsgFlags, msg []byte, cursor int) (int, error)
+	Authenticate(msg []byte) error
+	IsAuthentic(msg []byte, packet *SnmpPacket) (bool, error)
+	EncryptPacket(scopedPdu []byte) ([]byte, error)
+	DecryptPacket(msg []byte, cursor int) ([]byte, error)
+}
+
+// WrapSecurityParameters returns sp as SnmpV3SecurityParameters, to set as
+// a GoSNMP's SecurityParameters (with its SecurityModel) or return from the
+// function registered with RegisterSecurityModel. eg
+//
+//	err := gosnmp.RegisterSecurityModel(4, func() gosnmp.SnmpV3SecurityParameters {
+//		return gosnmp.WrapSecurityParameters(new(tsm.Parameters))
+//	})
+func WrapSecurityParameters(sp ExternalSecurityParameters) SnmpV3SecurityParameters {
+	return &externalSecurityParameters{sp}
+}
+
+// UnwrapSecurityParameters returns the ExternalSecurityParameters of sp,
+// eg of a packet received, if it was made by WrapSecurityParameters
+func UnwrapSecurityParameters(sp SnmpV3SecurityParameters) (ExternalSecurityParameters, bool) {
+	e, ok := sp.(*externalSecurityParameters)
+	if !ok {
+		return nil, false
+	}
+	return e.sp, true
+}
+
+// externalSecurityParameters is the SnmpV3SecurityParameters of
+// ExternalSecurityParameters
+type externalSecurityParameters struct {
+	sp ExternalSecurityParameters
+}
+
+func (e *externalSecurityParameters) Log() {
+	e.sp.Log()
+}
+
+func (e *externalSecurityParameters) Copy() SnmpV3SecurityParameters {
+	return &externalSecurityParameters{e.sp.Copy()}
+}
+
+func (e *externalSecurityParameters) securityModel() SnmpV3SecurityModel {
+	return e.sp.SecurityModel()
+}
+
+func (e *externalSecurityParameters) validate(flags SnmpV3MsgFlags) error {
+	return e.sp.Validate(flags)
+}
+
+func (e *externalSecurityParameters) init(log Logger) error {
+	return e.sp.Init(log)
+}
+
+func (e *externalSecurityParameters) initPacket(packet *SnmpPacket) error {
+	return e.sp.InitPacket(packet)
+}
+
+func (e *externalSecurityParameters) discoveryRequired() *SnmpPacket {
+	return e.sp.DiscoveryRequired()
+}
+
+func (e *externalSecurityParameters) getDefaultContextEngineID() string {
+	return e.sp.DefaultContextEngineID()
+}
+
+func (e *externalSecurityParameters) setSecurityParameters(in SnmpV3SecurityParameters) error {
+	sp, ok := UnwrapSecurityParameters(in)
+	if !ok {
+		return fmt.Errorf("Security parameters of model %d can't be set from %T", e.sp.SecurityModel(), in)
+	}
+	return e.sp.SetSecurityParameters(sp)
+}
+
+func (e *externalSecurityParameters) marshal(flags SnmpV3MsgFlags) ([]byte, error) {
+	return e.sp.Marshal(flags)
+}
+
+func (e *externalSecurityParameters) unmarshal(flags SnmpV3MsgFlags, packet []byte, cursor int) (int, error) {
+	return e.sp.Unmarshal(flags, packet, cursor)
+}
+
+func (e *externalSecurityParameters) authenticate(packet []byte) error {
+	return e.sp.Authenticate(packet)
+}
+
+func (e *externalSecurityParameters) isAuthentic(packetBytes []byte, packet *SnmpPacket) (bool, error) {
+	return e.sp.IsAuthentic(packetBytes, packet)
+}
+
+func (e *externalSecurityParameters) encryptPacket(scopedPdu []byte) ([]byte, error) {
+	return e.sp.EncryptPacket(scopedPdu)
+}
+
+func (e *externalSecurityParameters) decryptPacket(packet []byte, cursor int) ([]byte, error) {
+	return e.sp.DecryptPacket(packet, cursor)
+}
@@ -0,0 +1,192 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testSecurityModel SnmpV3SecurityModel = 99
+
+// testSecurityParameters are those of a security model without security:
+// msgSecurityParameters is an OCTET STRING naming the principal
+type testSecurityParameters struct {
+	Name string
+}
+
+func (sp *testSecurityParameters) SecurityModel() SnmpV3SecurityModel { return testSecurityModel }
+func (sp *testSecurityParameters) Log()                               {}
+func (sp *testSecurityParameters) Copy() ExternalSecurityParameters {
+	c := *sp
+	return &c
+}
+func (sp *testSecurityParameters) Validate(flags SnmpV3MsgFlags) error {
+	if flags&AuthNoPriv > 0 {
+		return fmt.Errorf("No authentication in the test model")
+	}
+	return nil
+}
+func (sp *testSecurityParameters) Init(log Logger) error               { return nil }
+func (sp *testSecurityParameters) InitPacket(packet *SnmpPacket) error { return nil }
+func (sp *testSecurityParameters) DiscoveryRequired() *SnmpPacket      { return nil }
+func (sp *testSecurityParameters) DefaultContextEngineID() string      { return "" }
+func (sp *testSecurityParameters) SetSecurityParameters(in ExternalSecurityParameters) error {
+	sp.Name = in.(*testSecurityParameters).Name
+	return nil
+}
+func (sp *testSecurityParameters) Marshal(flags SnmpV3MsgFlags) ([]byte, error) {
+	return append([]byte{byte(OctetString), byte(len(sp.Name))}, sp.Name...), nil
+}
+func (sp *testSecurityParameters) Unmarshal(flags SnmpV3MsgFlags, msg []byte, cursor int) (int, error) {
+	if len(msg) < cursor+2 || Asn1BER(msg[cursor]) != OctetString || len(msg) < cursor+2+int(msg[cursor+1]) {
+		return 0, fmt.Errorf("Invalid test security parameters")
+	}
+	end := cursor + 2 + int(msg[cursor+1])
+	sp.Name = string(msg[cursor+2 : end])
+	return end, nil
+}
+func (sp *testSecurityParameters) Authenticate(msg []byte) error { return nil }
+func (sp *testSecurityParameters) IsAuthentic(msg []byte, packet *SnmpPacket) (bool, error) {
+	return sp.Name != "forged", nil
+}
+func (sp *testSecurityParameters) EncryptPacket(scopedPdu []byte) ([]byte, error) {
+	return nil, fmt.Errorf("No privacy in the test model")
+}
+func (sp *testSecurityParameters) DecryptPacket(msg []byte, cursor int) ([]byte, error) {
+	return nil, fmt.Errorf("No privacy in the test model")
+}
+
+var registerTestSecurityModel sync.Once
+
+func TestRegisterSecurityModel(t *testing.T) {
+	registerTestSecurityModel.Do(func() {
+		if err := RegisterSecurityModel(testSecurityModel, func() SnmpV3SecurityParameters {
+			return WrapSecurityParameters(new(testSecurityParameters))
+		}); err != nil {
+			t.Fatalf("RegisterSecurityModel() err: %v", err)
+		}
+	})
+	for _, model := range []SnmpV3SecurityModel{0, UserSecurityModel, testSecurityModel} {
+		if err := RegisterSecurityModel(model, func() SnmpV3SecurityParameters { return nil }); err == nil {
+			t.Errorf("RegisterSecurityModel(%d) succeeded", model)
+		}
+	}
+
+	// an agent of the model, answering with the name requested
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket() err: %v", err)
+	}
+	defer conn.Close()
+	go func() {
+		agent := &GoSNMP{}
+		buf := make([]byte, rxBufSize)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			request, err := agent.Decode(buf[:n])
+			if err != nil {
+				continue
+			}
+			sp, _ := UnwrapSecurityParameters(request.SecurityParameters)
+			response := *request
+			response.PDUType = GetResponse
+			response.MsgFlags = 0
+			response.Variables = []SnmpPDU{{Name: testSysDescr, Type: OctetString, Value: sp.(*testSecurityParameters).Name}}
+			if out, err := agent.Encode(&response); err == nil {
+				conn.WriteTo(out, addr)
+			}
+		}
+	}()
+
+	x := &GoSNMP{
+		Target:             "127.0.0.1",
+		Port:               uint16(conn.LocalAddr().(*net.UDPAddr).Port),
+		Version:            Version3,
+		SecurityModel:      testSecurityModel,
+		SecurityParameters: WrapSecurityParameters(&testSecurityParameters{Name: "principal"}),
+		Timeout:            time.Second,
+	}
+	if err = x.Connect(); err != nil {
+		t.Fatalf("Connect() err: %v", err)
+	}
+	defer x.Conn.Close()
+	result, err := x.Get([]string{testSysDescr})
+	if err != nil {
+		t.Fatalf("Get() err: %v", err)
+	}
+	if sp, ok := UnwrapSecurityParameters(result.SecurityParameters); !ok || sp.(*testSecurityParameters).Name != "principal" {
+		t.Errorf("got security parameters %#v, want the test model's", result.SecurityParameters)
+	}
+	if len(result.Variables) != 1 || string(result.Variables[0].Value.([]byte)) != "principal" {
+		t.Errorf("got %v, want principal", result.Variables)
+	}
+
+	// messages are decoded with the parameters of their model
+	sp := x.SecurityParameters
+	out, err := x.Encode(&SnmpPacket{Version: Version3, SecurityModel: testSecurityModel, MsgID: 1,
+		PDUType: GetRequest, SecurityParameters: sp})
+	if err != nil {
+		t.Fatalf("Encode() err: %v", err)
+	}
+	decoder := &GoSNMP{SecurityParameters: &UsmSecurityParameters{UserName: "user"}}
+	if packet, err := decoder.Decode(out); err != nil {
+		t.Errorf("Decode() err: %v", err)
+	} else if _, ok := UnwrapSecurityParameters(packet.SecurityParameters); !ok {
+		t.Errorf("Decode() got security parameters %T, want the test model's", packet.SecurityParameters)
+	}
+
+	// traps are authenticated by their model, and refused if of another
+	// model than the listener's
+	listener := &GoSNMP{Version: Version3, SecurityModel: testSecurityModel, MsgFlags: AuthNoPriv,
+		SecurityParameters: WrapSecurityParameters(new(testSecurityParameters))}
+	usmListener := &GoSNMP{Version: Version3, SecurityModel: UserSecurityModel,
+		SecurityParameters: &UsmSecurityParameters{UserName: "user"}}
+	for _, test := range []struct {
+		listener *GoSNMP
+		name     string
+		flags    SnmpV3MsgFlags
+		ok       bool
+	}{
+		{listener, "principal", AuthNoPriv, true},
+		{listener, "forged", AuthNoPriv, false},
+		{listener, "principal", NoAuthNoPriv, false},
+		{usmListener, "principal", AuthNoPriv, false},
+	} {
+		trap, err := x.Encode(&SnmpPacket{Version: Version3, SecurityModel: testSecurityModel, MsgID: 2, MsgFlags: test.flags,
+			PDUType: SNMPv2Trap, SecurityParameters: WrapSecurityParameters(&testSecurityParameters{Name: test.name})})
+		if err != nil {
+			t.Fatalf("Encode() err: %v", err)
+		}
+		if _, err = test.listener.unmarshalTrap(trap); (err == nil) != test.ok {
+			t.Errorf("unmarshalTrap() of %s at %s for model %d err: %v",
+				test.name, test.flags, test.listener.SecurityModel, err)
+		}
+	}
+
+	// unregistered models, and parameters of another model, are refused
+	x = &GoSNMP{Version: Version3, SecurityModel: 98, SecurityParameters: &UsmSecurityParameters{}}
+	if err = x.validateParameters(); err == nil {
+		t.Errorf("validateParameters() of an unregistered model succeeded")
+	}
+	x.SecurityModel = testSecurityModel
+	if err = x.validateParameters(); err == nil {
+		t.Errorf("validateParameters() of USM parameters for the test model succeeded")
+	}
+	out, err = x.Encode(&SnmpPacket{Version: Version3, SecurityModel: 98, MsgID: 1,
+		PDUType: GetRequest, SecurityParameters: sp})
+	if err != nil {
+		t.Fatalf("Encode() err: %v", err)
+	}
+	if _, err = decoder.Decode(out); err == nil {
+		t.Errorf("Decode() of an unregistered model succeeded")
+	}
+}
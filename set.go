@@ -0,0 +1,147 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"fmt"
+	"math"
+	"net"
+)
+
+//
+// Sets of values of the SMI types their objects need
+//
+
+// VarBinds builds the varbinds of a SET, each with the SMI type its object
+// is defined with, so that eg an Unsigned32 isn't sent as an INTEGER,
+// which agents refuse as wrongType. The first invalid value (an oid that
+// can't be encoded, a number out of range) is returned by PDUs. eg
+//
+//	pdus, err := new(gosnmp.VarBinds).
+//		String(".1.3.6.1.2.1.1.5.0", "router1").
+//		Unsigned32(".1.3.6.1.4.1.9.9.42.1.2.1.1.6.1", 60).
+//		PDUs()
+//	if err == nil {
+//		result, err = x.Set(pdus)
+//	}
+type VarBinds struct {
+	pdus []SnmpPDU
+	err  error
+}
+
+// add adds the varbind of oid, of type and value, unless oid or an
+// earlier varbind is invalid
+func (v *VarBinds) add(oid string, kind Asn1BER, value interface{}) *VarBinds {
+	if v.err != nil {
+		return v
+	}
+	if _, err := marshalOID(oid); err != nil {
+		v.err = fmt.Errorf("Invalid oid %q: %w", oid, err)
+		return v
+	}
+	v.pdus = append(v.pdus, SnmpPDU{Name: oid, Type: kind, Value: value})
+	return v
+}
+
+// fail records the error of an invalid value, unless there is one already
+func (v *VarBinds) fail(format string, a ...interface{}) *VarBinds {
+	if v.err == nil {
+		v.err = fmt.Errorf(format, a...)
+	}
+	return v
+}
+
+// Integer adds an INTEGER (Integer32), eg of an enumeration
+func (v *VarBinds) Integer(oid string, value int) *VarBinds {
+	if value < math.MinInt32 || value > math.MaxInt32 {
+		return v.fail("Integer32 %d of %s out of range", value, oid)
+	}
+	return v.add(oid, Integer, value)
+}
+
+// Unsigned32 adds an Unsigned32, which is encoded as a Gauge32
+func (v *VarBinds) Unsigned32(oid string, value uint32) *VarBinds {
+	return v.add(oid, Gauge32, value)
+}
+
+// TimeTicks adds a TimeTicks, in hundredths of a second
+func (v *VarBinds) TimeTicks(oid string, value uint32) *VarBinds {
+	return v.add(oid, TimeTicks, value)
+}
+
+// String adds an OCTET STRING, eg a DisplayString
+func (v *VarBinds) String(oid string, value string) *VarBinds {
+	return v.add(oid, OctetString, value)
+}
+
+// Bytes adds an OCTET STRING of binary data, eg a PhysAddress
+func (v *VarBinds) Bytes(oid string, value []byte) *VarBinds {
+	return v.add(oid, OctetString, value)
+}
+
+// OID adds an OBJECT IDENTIFIER, in dotted form
+func (v *VarBinds) OID(oid string, value string) *VarBinds {
+	if _, err := marshalOID(value); err != nil {
+		return v.fail("Invalid OBJECT IDENTIFIER %q of %s: %v", value, oid, err)
+	}
+	return v.add(oid, ObjectIdentifier, value)
+}
+
+// IP adds an IpAddress, which must be an IPv4 address
+func (v *VarBinds) IP(oid string, value net.IP) *VarBinds {
+	ip := value.To4()
+	if ip == nil {
+		return v.fail("IpAddress %v of %s isn't an IPv4 address", value, oid)
+	}
+	return v.add(oid, IPAddress, []byte(ip))
+}
+
+// PDUs returns the varbinds, or the error of the first invalid one
+func (v *VarBinds) PDUs() ([]SnmpPDU, error) {
+	if v.err != nil {
+		return nil, v.err
+	}
+	return v.pdus, nil
+}
+
+// set sends a SET of the varbinds of v
+func (x *GoSNMP) set(v *VarBinds) (*SnmpPacket, error) {
+	pdus, err := v.PDUs()
+	if err != nil {
+		return nil, err
+	}
+	return x.Set(pdus)
+}
+
+// SetInt sets oid to an INTEGER (Integer32). The Set helpers send a SET of
+// one varbind, of the type named; see VarBinds for several, and SetCtx.
+func (x *GoSNMP) SetInt(oid string, value int) (*SnmpPacket, error) {
+	return x.set(new(VarBinds).Integer(oid, value))
+}
+
+// SetUint sets oid to an Unsigned32 (Gauge32)
+func (x *GoSNMP) SetUint(oid string, value uint32) (*SnmpPacket, error) {
+	return x.set(new(VarBinds).Unsigned32(oid, value))
+}
+
+// SetTimeTicks sets oid to a TimeTicks, in hundredths of a second
+func (x *GoSNMP) SetTimeTicks(oid string, value uint32) (*SnmpPacket, error) {
+	return x.set(new(VarBinds).TimeTicks(oid, value))
+}
+
+// SetString sets oid to an OCTET STRING
+func (x *GoSNMP) SetString(oid string, value string) (*SnmpPacket, error) {
+	return x.set(new(VarBinds).String(oid, value))
+}
+
+// SetOID sets oid to an OBJECT IDENTIFIER, in dotted form
+func (x *GoSNMP) SetOID(oid string, value string) (*SnmpPacket, error) {
+	return x.set(new(VarBinds).OID(oid, value))
+}
+
+// SetIP sets oid to an IpAddress, an IPv4 address
+func (x *GoSNMP) SetIP(oid string, value net.IP) (*SnmpPacket, error) {
+	return x.set(new(VarBinds).IP(oid, value))
+}
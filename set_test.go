@@ -0,0 +1,73 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"net"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestTypedSet(t *testing.T) {
+	a := &Agent{WriteCommunity: "private"}
+	defer a.Close()
+	scalars := map[Asn1BER]*testScalar{}
+	for i, kind := range []Asn1BER{Integer, Gauge32, TimeTicks, OctetString, ObjectIdentifier, IPAddress} {
+		scalars[kind] = &testScalar{pdu: SnmpPDU{Type: kind}}
+		if err := a.Register(".1.3.6.1.4.1.99."+string(rune('1'+i))+".0", scalars[kind]); err != nil {
+			t.Fatalf("Register() err: %v", err)
+		}
+	}
+	x := startTestAgent(t, a, &GoSNMP{Community: "private", Version: Version2c})
+	defer x.Conn.Close()
+
+	tests := []struct {
+		set  func() (*SnmpPacket, error)
+		kind Asn1BER
+		want interface{}
+	}{
+		{func() (*SnmpPacket, error) { return x.SetInt(".1.3.6.1.4.1.99.1.0", -7) }, Integer, -7},
+		{func() (*SnmpPacket, error) { return x.SetUint(".1.3.6.1.4.1.99.2.0", 4000000000) }, Gauge32, uint(4000000000)},
+		{func() (*SnmpPacket, error) { return x.SetTimeTicks(".1.3.6.1.4.1.99.3.0", 6000) }, TimeTicks, 6000},
+		{func() (*SnmpPacket, error) { return x.SetString(".1.3.6.1.4.1.99.4.0", "router1") }, OctetString, []byte("router1")},
+		{func() (*SnmpPacket, error) { return x.SetOID(".1.3.6.1.4.1.99.5.0", ".1.3.6.1.4.1.9") }, ObjectIdentifier, ".1.3.6.1.4.1.9"},
+		{func() (*SnmpPacket, error) { return x.SetIP(".1.3.6.1.4.1.99.6.0", net.ParseIP("192.0.2.1")) }, IPAddress, "192.0.2.1"},
+	}
+	for _, test := range tests {
+		result, err := test.set()
+		if err != nil || result.Error != NoError {
+			t.Errorf("%v: Set err: %v, %v", test.kind, err, result)
+			continue
+		}
+		if got := scalars[test.kind].value(); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%v: agent got %#v, want %#v", test.kind, got, test.want)
+		}
+	}
+
+	// invalid values aren't sent
+	invalid := map[string]*VarBinds{
+		"ip":       new(VarBinds).IP(".1.3.6.1.4.1.99.6.0", net.ParseIP("2001:db8::1")),
+		"oid":      new(VarBinds).OID(".1.3.6.1.4.1.99.5.0", "not an oid"),
+		"name":     new(VarBinds).String("1.x", "value"),
+		"earliest": new(VarBinds).IP(".1.3.6.1.4.1.99.6.0", nil).String(".1.3.6.1.4.1.99.4.0", "value"),
+	}
+	if strconv.IntSize == 64 {
+		tooBig := int64(1) << 40
+		invalid["integer"] = new(VarBinds).Integer(".1.3.6.1.4.1.99.1.0", int(tooBig))
+	}
+	for name, v := range invalid {
+		if pdus, err := v.PDUs(); err == nil {
+			t.Errorf("%s: PDUs() = %v, want an error", name, pdus)
+		}
+	}
+	pdus, err := new(VarBinds).String(".1.3.6.1.4.1.99.4.0", "a").Unsigned32(".1.3.6.1.4.1.99.2.0", 5).PDUs()
+	if err != nil || len(pdus) != 2 || pdus[1].Type != Gauge32 || pdus[1].Value != uint32(5) {
+		t.Errorf("PDUs() = %v, %v", pdus, err)
+	}
+	if _, err = x.Set(pdus); err != nil {
+		t.Errorf("Set() err: %v", err)
+	}
+}
@@ -0,0 +1,510 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//
+// Simulating devices from recordings of their objects, for testing
+//
+
+// Simulator is a SubtreeHandler answering for a device from a recording of
+// its object instances, eg an snmpsim .snmprec file (see ReadSnmprec) or
+// the output of net-snmp's snmpwalk (see ReadWalk), so that managers can
+// be tested without the device. Registered with an Agent at the root of
+// the recording, eg
+//
+//	pdus, err := gosnmp.ReadSnmprec(f)
+//	...
+//	agent := &gosnmp.Agent{}
+//	err = agent.RegisterSubtree(".1", gosnmp.NewSimulator(pdus))
+//	go agent.Listen("udp", "127.0.0.1:1161")
+//
+// the agent answers GET, GETNEXT and GETBULK requests with the recorded
+// values. A Simulator is safe for concurrent use.
+type Simulator struct {
+	// Writable lets SETs change the values of the instances recorded,
+	// keeping their types; otherwise they fail with NotWritable. It must
+	// be set before the Simulator is used.
+	Writable bool
+
+	mu   sync.RWMutex
+	pdus []SnmpPDU          // by oid
+	old  map[string]SnmpPDU // the values before the last Sets, to Undo them
+}
+
+// NewSimulator returns a Simulator of pdus; of pdus with the same Name,
+// the last is kept
+func NewSimulator(pdus []SnmpPDU) *Simulator {
+	byName := make(map[string]SnmpPDU, len(pdus))
+	for _, pdu := range pdus {
+		if oid, err := ParseOid(pdu.Name); err == nil {
+			pdu.Name = oid.String()
+			byName[pdu.Name] = pdu
+		}
+	}
+	s := &Simulator{}
+	for _, pdu := range byName {
+		s.pdus = append(s.pdus, pdu)
+	}
+	sort.Slice(s.pdus, func(i, j int) bool {
+		return CompareOids(s.pdus[i].Name, s.pdus[j].Name) < 0
+	})
+	return s
+}
+
+// Len returns the number of instances simulated
+func (s *Simulator) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.pdus)
+}
+
+// search returns the index of the first instance not before oid, and
+// whether it is oid
+func (s *Simulator) search(oid string) (int, bool) {
+	i := sort.Search(len(s.pdus), func(i int) bool {
+		return CompareOids(s.pdus[i].Name, oid) >= 0
+	})
+	return i, i < len(s.pdus) && CompareOids(s.pdus[i].Name, oid) == 0
+}
+
+// Get returns a recorded instance
+func (s *Simulator) Get(oid string) (SnmpPDU, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if i, ok := s.search(oid); ok {
+		return s.pdus[i], nil
+	}
+	return SnmpPDU{Name: oid, Type: NoSuchObject}, nil
+}
+
+// GetNext returns the first recorded instance after oid
+func (s *Simulator) GetNext(oid string) (SnmpPDU, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	i, ok := s.search(oid)
+	if ok {
+		i++
+	}
+	if i < len(s.pdus) {
+		return s.pdus[i], nil
+	}
+	return SnmpPDU{Name: oid, Type: EndOfMibView}, nil
+}
+
+// Test checks that pdu sets a recorded instance, with its type, of a
+// Writable Simulator
+func (s *Simulator) Test(pdu SnmpPDU) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, err := s.instance(pdu)
+	return err
+}
+
+// instance returns the index of the instance pdu sets, or the error the
+// Set fails with
+func (s *Simulator) instance(pdu SnmpPDU) (int, error) {
+	i, ok := s.search(pdu.Name)
+	switch {
+	case !ok:
+		return 0, NoCreation
+	case !s.Writable:
+		return 0, NotWritable
+	case s.pdus[i].Type != pdu.Type:
+		return 0, WrongType
+	}
+	return i, nil
+}
+
+// Set sets a recorded instance, if the Simulator is Writable
+func (s *Simulator) Set(pdu SnmpPDU) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i, err := s.instance(pdu)
+	if err != nil {
+		return err
+	}
+	if s.old == nil {
+		s.old = make(map[string]SnmpPDU)
+	}
+	s.old[s.pdus[i].Name] = s.pdus[i]
+	pdu.Name = s.pdus[i].Name
+	s.pdus[i] = pdu
+	return nil
+}
+
+// Undo sets an instance back to its value before the last Set
+func (s *Simulator) Undo(pdu SnmpPDU) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i, ok := s.search(pdu.Name)
+	if old, set := s.old[pdu.Name]; ok && set {
+		s.pdus[i] = old
+	}
+	return nil
+}
+
+// ReadSnmprec reads the instances of an snmpsim .snmprec file: lines of
+// oid|tag|value, the tag being the number of the value's BER type, eg 4
+// for an OctetString, with an x suffix if the value is in hex. Blank lines
+// and # comments are skipped; variation modules (tags such as 4:numeric)
+// aren't supported.
+func ReadSnmprec(r io.Reader) ([]SnmpPDU, error) {
+	var pdus []SnmpPDU
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(text) == "" || strings.HasPrefix(strings.TrimSpace(text), "#") {
+			continue
+		}
+		fields := strings.SplitN(text, "|", 3)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("Snmprec line %d isn't oid|tag|value", line)
+		}
+		pdu, err := snmprecPDU(fields[0], fields[1], fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("Snmprec line %d: %w", line, err)
+		}
+		pdus = append(pdus, pdu)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return pdus, nil
+}
+
+// snmprecPDU returns the varbind of an snmprec record
+func snmprecPDU(name, tag, value string) (SnmpPDU, error) {
+	oid, err := ParseOid(name)
+	if err != nil {
+		return SnmpPDU{}, err
+	}
+	pdu := SnmpPDU{Name: oid.String()}
+	isHex := strings.HasSuffix(tag, "x")
+	n, err := strconv.Atoi(strings.TrimSuffix(tag, "x"))
+	if err != nil {
+		return pdu, fmt.Errorf("Unsupported tag %q", tag)
+	}
+	pdu.Type = Asn1BER(n)
+	raw := []byte(value)
+	if isHex {
+		if raw, err = hex.DecodeString(value); err != nil {
+			return pdu, fmt.Errorf("Bad hex value %q: %w", value, err)
+		}
+	}
+
+	switch pdu.Type {
+	case OctetString, Opaque:
+		pdu.Value = raw
+	case IPAddress:
+		if isHex && len(raw) == net.IPv4len {
+			pdu.Value = net.IP(raw).String()
+		} else if ip := net.ParseIP(value).To4(); ip != nil && !isHex {
+			pdu.Value = ip.String()
+		} else {
+			return pdu, fmt.Errorf("Bad IpAddress %q", value)
+		}
+	case Null, NoSuchObject, NoSuchInstance, EndOfMibView:
+	case ObjectIdentifier, Integer, Counter32, Gauge32, TimeTicks, Counter64, Uinteger32:
+		if isHex {
+			return pdu, fmt.Errorf("Unsupported tag %q", tag)
+		}
+		err = parseRecordedValue(&pdu, value)
+	default:
+		return pdu, fmt.Errorf("Unsupported tag %q", tag)
+	}
+	return pdu, err
+}
+
+// parseRecordedValue sets the value of a numeric or OID varbind of p.Type
+// from s
+func parseRecordedValue(p *SnmpPDU, s string) error {
+	s = strings.TrimSpace(s)
+	switch p.Type {
+	case ObjectIdentifier:
+		oid, err := ParseOid(s)
+		if err != nil {
+			return err
+		}
+		p.Value = oid.String()
+	case Integer:
+		n, err := strconv.ParseInt(s, 10, 32)
+		if err != nil {
+			return fmt.Errorf("Bad Integer %q", s)
+		}
+		p.Value = int(n)
+	case Counter32, Gauge32, Uinteger32:
+		n, err := strconv.ParseUint(s, 10, 32)
+		if err != nil {
+			return fmt.Errorf("Bad %s %q", p.Type, s)
+		}
+		p.Value = uint(n)
+	case TimeTicks:
+		n, err := strconv.ParseUint(s, 10, 32)
+		if err != nil {
+			return fmt.Errorf("Bad TimeTicks %q", s)
+		}
+		p.Value = uint32(n)
+	case Counter64:
+		n, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return fmt.Errorf("Bad Counter64 %q", s)
+		}
+		p.Value = n
+	}
+	return nil
+}
+
+// walkLine is the start of a varbind of snmpwalk output
+var walkLine = regexp.MustCompile(`^(\S+) = (.*)$`)
+
+// ReadWalk reads the instances of the output of net-snmp's snmpwalk, with
+// numeric oids (-On, or without MIBs, as iso.3.6...), eg
+//
+//	.1.3.6.1.2.1.1.1.0 = STRING: "Linux router"
+//	.1.3.6.1.2.1.1.3.0 = Timeticks: (8642) 0:01:26.42
+//
+// Values continued on the lines after a varbind's, eg strings with
+// newlines and long Hex-STRINGs, are read as part of it. The messages
+// snmpwalk gives for exceptions, eg "No more variables left in this MIB
+// View", are skipped.
+func ReadWalk(r io.Reader) ([]SnmpPDU, error) {
+	var pdus []SnmpPDU
+	var name, value string
+	start := 0
+	flush := func() error {
+		if name == "" {
+			return nil
+		}
+		pdu, ok, err := walkPDU(name, value)
+		if err != nil {
+			return fmt.Errorf("Walk line %d: %w", start, err)
+		}
+		if ok {
+			pdus = append(pdus, pdu)
+		}
+		name = ""
+		return nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		m := walkLine.FindStringSubmatch(text)
+		if m == nil || name != "" && openString(value) {
+			if name == "" {
+				if strings.TrimSpace(text) == "" {
+					continue
+				}
+				return nil, fmt.Errorf("Walk line %d isn't OID = TYPE: VALUE", line)
+			}
+			value += "\n" + text
+			continue
+		}
+		if err := flush(); err != nil {
+			return nil, err
+		}
+		name, value, start = m[1], m[2], line
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return pdus, nil
+}
+
+// openString reports whether the value of a walk's varbind is a quoted
+// string that hasn't been closed, ie continues on the next line
+func openString(value string) bool {
+	s := strings.TrimPrefix(value, "STRING: ")
+	if !strings.HasPrefix(s, `"`) {
+		return false
+	}
+	escaped := false
+	for _, c := range s[1:] {
+		switch {
+		case escaped:
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case c == '"':
+			return false
+		}
+	}
+	return true
+}
+
+// walkTypes are the types of the values of snmpwalk output
+var walkTypes = map[string]Asn1BER{
+	"STRING":          OctetString,
+	"Hex-STRING":      OctetString,
+	"BITS":            OctetString,
+	"INTEGER":         Integer,
+	"Counter32":       Counter32,
+	"Gauge32":         Gauge32,
+	"Unsigned32":      Gauge32,
+	"UInteger32":      Uinteger32,
+	"Counter64":       Counter64,
+	"Timeticks":       TimeTicks,
+	"OID":             ObjectIdentifier,
+	"IpAddress":       IPAddress,
+	"Opaque":          Opaque,
+	"Network Address": IPAddress,
+}
+
+// walkPDU returns the varbind of a line (and its continuations) of snmpwalk
+// output, and whether it is one rather than an exception
+func walkPDU(name, value string) (SnmpPDU, bool, error) {
+	if strings.HasPrefix(name, "iso.") {
+		name = ".1." + name[len("iso."):]
+	}
+	oid, err := ParseOid(name)
+	if err != nil {
+		return SnmpPDU{}, false, fmt.Errorf("Oid %q isn't numeric", name)
+	}
+	pdu := SnmpPDU{Name: oid.String()}
+	if strings.HasPrefix(value, "Wrong Type") {
+		// "Wrong Type (should be X): TYPE: value"
+		if i := strings.Index(value, "): "); i >= 0 {
+			value = value[i+len("): "):]
+		}
+	}
+	switch {
+	case value == `""`:
+		pdu.Type, pdu.Value = OctetString, []byte{}
+		return pdu, true, nil
+	case value == "NULL":
+		pdu.Type = Null
+		return pdu, true, nil
+	case strings.HasPrefix(value, "No Such") || strings.HasPrefix(value, "No more variables"):
+		return pdu, false, nil
+	}
+	i := strings.Index(value, ": ")
+	typ := ""
+	if i >= 0 {
+		typ = value[:i]
+	}
+	t, ok := walkTypes[typ]
+	if !ok {
+		return pdu, false, fmt.Errorf("Unsupported value %q", value)
+	}
+	pdu.Type, value = t, value[i+2:]
+
+	switch typ {
+	case "STRING":
+		pdu.Value, err = walkString(value)
+	case "Hex-STRING", "BITS", "Network Address", "Opaque":
+		if typ == "Opaque" {
+			if f, ok := walkOpaque(value); ok {
+				pdu.Type, pdu.Value = f.Type, f.Value
+				return pdu, true, nil
+			}
+		}
+		var b []byte
+		for _, field := range strings.Fields(strings.ReplaceAll(value, ":", " ")) {
+			octet, err := hex.DecodeString(field)
+			if err != nil || len(octet) != 1 {
+				// eg the names of a BITS
+				break
+			}
+			b = append(b, octet[0])
+		}
+		pdu.Value = b
+		if pdu.Type == IPAddress {
+			if len(b) != net.IPv4len {
+				return pdu, false, fmt.Errorf("Bad Network Address %q", value)
+			}
+			pdu.Value = net.IP(b).String()
+		}
+	case "INTEGER":
+		// eg "up(1)"
+		if i := strings.LastIndex(value, "("); i >= 0 && strings.HasSuffix(value, ")") {
+			value = value[i+1 : len(value)-1]
+		}
+		err = parseRecordedValue(&pdu, value)
+	case "Timeticks":
+		// "(8642) 0:01:26.42"
+		if i := strings.Index(value, ")"); strings.HasPrefix(value, "(") && i > 0 {
+			value = value[1:i]
+		}
+		err = parseRecordedValue(&pdu, value)
+	case "OID":
+		if strings.HasPrefix(value, "iso.") {
+			value = ".1." + value[len("iso."):]
+		}
+		err = parseRecordedValue(&pdu, value)
+	case "IpAddress":
+		ip := net.ParseIP(strings.TrimSpace(value)).To4()
+		if ip == nil {
+			return pdu, false, fmt.Errorf("Bad IpAddress %q", value)
+		}
+		pdu.Value = ip.String()
+	default:
+		// eg "Counter32: 5", or with units "Gauge32: 5 seconds"
+		if fields := strings.Fields(value); len(fields) > 0 {
+			value = fields[0]
+		}
+		err = parseRecordedValue(&pdu, value)
+	}
+	if err != nil {
+		return pdu, false, err
+	}
+	return pdu, true, nil
+}
+
+// walkString returns the octets of a STRING of snmpwalk output, quoted with
+// \" and \\ escaped, or not quoted
+func walkString(value string) ([]byte, error) {
+	if !strings.HasPrefix(value, `"`) {
+		return []byte(value), nil
+	}
+	if len(value) < 2 || !strings.HasSuffix(value, `"`) {
+		return nil, fmt.Errorf("Unterminated STRING %s", value)
+	}
+	var b []byte
+	s := value[1 : len(value)-1]
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+		b = append(b, s[i])
+	}
+	return b, nil
+}
+
+// walkOpaque returns the float of an Opaque of snmpwalk output, eg
+// "Float: 1.5", if it is one
+func walkOpaque(value string) (SnmpPDU, bool) {
+	for prefix, t := range map[string]Asn1BER{"Float: ": OpaqueFloat, "Double: ": OpaqueDouble} {
+		if !strings.HasPrefix(value, prefix) {
+			continue
+		}
+		f, err := strconv.ParseFloat(strings.TrimSpace(value[len(prefix):]), 64)
+		if err != nil {
+			return SnmpPDU{}, false
+		}
+		if t == OpaqueFloat {
+			return SnmpPDU{Type: t, Value: float32(f)}, true
+		}
+		return SnmpPDU{Type: t, Value: f}, true
+	}
+	return SnmpPDU{}, false
+}
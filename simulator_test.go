@@ -0,0 +1,191 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testSnmprec = `# a recorded router
+1.3.6.1.2.1.1.1.0|4|Linux router
+1.3.6.1.2.1.1.2.0|6|1.3.6.1.4.1.8072.3.2.10
+1.3.6.1.2.1.1.3.0|67|8642
+1.3.6.1.2.1.1.5.0|4x|726f75746572
+1.3.6.1.2.1.2.1.0|2|2
+1.3.6.1.2.1.2.2.1.10.1|65|1234
+1.3.6.1.2.1.2.2.1.10.2|65|5678
+1.3.6.1.2.1.2.2.1.5.1|66|1000000000
+1.3.6.1.2.1.4.20.1.1.10.0.0.1|64|10.0.0.1
+1.3.6.1.2.1.31.1.1.1.6.1|70|12345678901
+
+1.3.6.1.2.1.4.20.1.1.10.0.0.2|64x|0a000002
+`
+
+func TestReadSnmprec(t *testing.T) {
+	pdus, err := ReadSnmprec(strings.NewReader(testSnmprec))
+	if err != nil {
+		t.Fatalf("ReadSnmprec() err: %v", err)
+	}
+	want := []SnmpPDU{
+		{Name: ".1.3.6.1.2.1.1.1.0", Type: OctetString, Value: []byte("Linux router")},
+		{Name: ".1.3.6.1.2.1.1.2.0", Type: ObjectIdentifier, Value: ".1.3.6.1.4.1.8072.3.2.10"},
+		{Name: ".1.3.6.1.2.1.1.3.0", Type: TimeTicks, Value: uint32(8642)},
+		{Name: ".1.3.6.1.2.1.1.5.0", Type: OctetString, Value: []byte("router")},
+		{Name: ".1.3.6.1.2.1.2.1.0", Type: Integer, Value: 2},
+		{Name: ".1.3.6.1.2.1.2.2.1.10.1", Type: Counter32, Value: uint(1234)},
+		{Name: ".1.3.6.1.2.1.2.2.1.10.2", Type: Counter32, Value: uint(5678)},
+		{Name: ".1.3.6.1.2.1.2.2.1.5.1", Type: Gauge32, Value: uint(1000000000)},
+		{Name: ".1.3.6.1.2.1.4.20.1.1.10.0.0.1", Type: IPAddress, Value: "10.0.0.1"},
+		{Name: ".1.3.6.1.2.1.31.1.1.1.6.1", Type: Counter64, Value: uint64(12345678901)},
+		{Name: ".1.3.6.1.2.1.4.20.1.1.10.0.0.2", Type: IPAddress, Value: "10.0.0.2"},
+	}
+	if !reflect.DeepEqual(pdus, want) {
+		t.Errorf("ReadSnmprec() = %v, expected %v", pdus, want)
+	}
+
+	for _, bad := range []string{
+		"1.3.6.1.2.1.1.1.0|4",
+		"1.3.6.1.2.1.1.1.0|4:numeric|rate=5",
+		"1.3.6.1.2.1.1.1.0|2|many",
+		"1.3.6.1.2.1.1.1.0|4x|zz",
+		"1.3.6.1.2.1.1.1.0|64|not an address",
+		"not.an.oid|2|1",
+	} {
+		if _, err := ReadSnmprec(strings.NewReader(bad)); err == nil {
+			t.Errorf("ReadSnmprec(%q) succeeded", bad)
+		}
+	}
+}
+
+const testWalk = `.1.3.6.1.2.1.1.1.0 = STRING: "Linux router
+2nd line"
+.1.3.6.1.2.1.1.2.0 = OID: .1.3.6.1.4.1.8072.3.2.10
+.1.3.6.1.2.1.1.3.0 = Timeticks: (8642) 0:01:26.42
+.1.3.6.1.2.1.1.4.0 = ""
+.1.3.6.1.2.1.1.5.0 = STRING: "say \"hi\""
+.1.3.6.1.2.1.2.2.1.6.1 = Hex-STRING: 00 1A 2B 3C 4D 5E 00 1A 2B 3C 4D 5E 00 1A 2B 3C
+00 1A
+.1.3.6.1.2.1.2.2.1.7.1 = INTEGER: up(1)
+.1.3.6.1.2.1.2.2.1.10.1 = Counter32: 1234
+.1.3.6.1.2.1.2.2.1.5.1 = Gauge32: 1000000000
+iso.3.6.1.2.1.4.20.1.1.10.0.0.1 = IpAddress: 10.0.0.1
+.1.3.6.1.2.1.25.1.1.0 = Timeticks: (100) 0:00:01.00
+.1.3.6.1.2.1.31.1.1.1.6.1 = Counter64: 12345678901
+.1.3.6.1.4.1.2021.10.1.6.1 = Opaque: Float: 0.080000
+.1.3.6.1.4.1.99.1.0 = BITS: 80 40 first(0) second(9)
+.1.3.6.1.4.1.99.2.0 = NULL
+.1.3.6.1.4.1.99.3.0 = No more variables left in this MIB View (It is past the end of the MIB tree)
+`
+
+func TestReadWalk(t *testing.T) {
+	pdus, err := ReadWalk(strings.NewReader(testWalk))
+	if err != nil {
+		t.Fatalf("ReadWalk() err: %v", err)
+	}
+	want := []SnmpPDU{
+		{Name: ".1.3.6.1.2.1.1.1.0", Type: OctetString, Value: []byte("Linux router\n2nd line")},
+		{Name: ".1.3.6.1.2.1.1.2.0", Type: ObjectIdentifier, Value: ".1.3.6.1.4.1.8072.3.2.10"},
+		{Name: ".1.3.6.1.2.1.1.3.0", Type: TimeTicks, Value: uint32(8642)},
+		{Name: ".1.3.6.1.2.1.1.4.0", Type: OctetString, Value: []byte{}},
+		{Name: ".1.3.6.1.2.1.1.5.0", Type: OctetString, Value: []byte(`say "hi"`)},
+		{Name: ".1.3.6.1.2.1.2.2.1.6.1", Type: OctetString, Value: []byte{
+			0x00, 0x1a, 0x2b, 0x3c, 0x4d, 0x5e, 0x00, 0x1a, 0x2b, 0x3c, 0x4d, 0x5e, 0x00, 0x1a, 0x2b, 0x3c, 0x00, 0x1a}},
+		{Name: ".1.3.6.1.2.1.2.2.1.7.1", Type: Integer, Value: 1},
+		{Name: ".1.3.6.1.2.1.2.2.1.10.1", Type: Counter32, Value: uint(1234)},
+		{Name: ".1.3.6.1.2.1.2.2.1.5.1", Type: Gauge32, Value: uint(1000000000)},
+		{Name: ".1.3.6.1.2.1.4.20.1.1.10.0.0.1", Type: IPAddress, Value: "10.0.0.1"},
+		{Name: ".1.3.6.1.2.1.25.1.1.0", Type: TimeTicks, Value: uint32(100)},
+		{Name: ".1.3.6.1.2.1.31.1.1.1.6.1", Type: Counter64, Value: uint64(12345678901)},
+		{Name: ".1.3.6.1.4.1.2021.10.1.6.1", Type: OpaqueFloat, Value: float32(0.08)},
+		{Name: ".1.3.6.1.4.1.99.1.0", Type: OctetString, Value: []byte{0x80, 0x40}},
+		{Name: ".1.3.6.1.4.1.99.2.0", Type: Null},
+	}
+	if !reflect.DeepEqual(pdus, want) {
+		t.Errorf("ReadWalk() = %v, expected %v", pdus, want)
+	}
+
+	for _, bad := range []string{
+		"SNMPv2-MIB::sysDescr.0 = STRING: router",
+		`.1.3.6.1.2.1.1.1.0 = STRING: "unterminated`,
+		".1.3.6.1.2.1.1.1.0 = Unknown: 1",
+		".1.3.6.1.2.1.1.1.0 = INTEGER: many",
+		"a continuation before any varbind",
+	} {
+		if _, err := ReadWalk(strings.NewReader(bad)); err == nil {
+			t.Errorf("ReadWalk(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestSimulator(t *testing.T) {
+	pdus, err := ReadSnmprec(strings.NewReader(testSnmprec))
+	if err != nil {
+		t.Fatalf("ReadSnmprec() err: %v", err)
+	}
+	sim := NewSimulator(pdus)
+	set := []SnmpPDU{{Name: ".1.3.6.1.2.1.2.1.0", Type: Integer, Value: 3}}
+	if err = sim.Set(set[0]); err != NotWritable {
+		t.Errorf("Set() of a read only Simulator err: %v", err)
+	}
+	sim.Writable = true
+	a := &Agent{WriteCommunity: "private"}
+	if err = a.RegisterSubtree(".1", sim); err != nil {
+		t.Fatalf("RegisterSubtree() err: %v", err)
+	}
+	defer a.Close()
+	x := startTestAgent(t, a, nil)
+	defer x.Conn.Close()
+
+	result, err := x.Get([]string{".1.3.6.1.2.1.1.5.0", ".1.3.6.1.2.1.1.6.0"})
+	if err != nil {
+		t.Fatalf("Get() err: %v", err)
+	}
+	if v := result.Variables; string(v[0].Value.([]byte)) != "router" || v[1].Type != NoSuchObject {
+		t.Errorf("Get() = %v", v)
+	}
+
+	result, err = x.GetNext([]string{".1.3.6.1.2.1.2.2.1.10", ".1.3.6.1.2.1.31.1.1.1.6.1"})
+	if err != nil {
+		t.Fatalf("GetNext() err: %v", err)
+	}
+	if v := result.Variables; v[0].Name != ".1.3.6.1.2.1.2.2.1.10.1" || v[1].Type != EndOfMibView {
+		t.Errorf("GetNext() = %v", v)
+	}
+
+	walked, err := x.BulkWalkAll(".1.3.6.1.2.1")
+	if err != nil {
+		t.Fatalf("BulkWalkAll() err: %v", err)
+	}
+	if len(walked) != sim.Len() {
+		t.Fatalf("BulkWalkAll() = %v, expected %d instances", walked, sim.Len())
+	}
+	for i := 1; i < len(walked); i++ {
+		if CompareOids(walked[i-1].Name, walked[i].Name) >= 0 {
+			t.Errorf("BulkWalkAll() walked %s after %s", walked[i].Name, walked[i-1].Name)
+		}
+	}
+
+	x.Community = "private"
+	if result, err = x.Set(set); err != nil || result.Error != NoError {
+		t.Errorf("Set() = %v, %v", result, err)
+	}
+	if pdu, _ := sim.Get(".1.3.6.1.2.1.2.1.0"); pdu.Value != 3 {
+		t.Errorf("Get() after Set() = %v", pdu)
+	}
+
+	for _, test := range []struct {
+		pdu  SnmpPDU
+		want SNMPError
+	}{
+		{SnmpPDU{Name: ".1.3.6.1.2.1.2.1.0", Type: OctetString, Value: []byte("3")}, WrongType},
+		{SnmpPDU{Name: ".1.3.6.1.2.1.2.9.0", Type: Integer, Value: 3}, NoCreation},
+	} {
+		if result, err = x.Set([]SnmpPDU{test.pdu}); err != nil || result.Error != test.want {
+			t.Errorf("Set(%v) = %v, %v, expected %s", test.pdu, result, err, test.want)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"fmt"
+	"net"
+)
+
+//
+// Accepting responses from addresses other than the Target's, see AnySource
+//
+
+// anySourceConn is an unconnected udp socket used as the Conn of a
+// session: it sends to remote, and reads datagrams from any address
+type anySourceConn struct {
+	*net.UDPConn
+	remote *net.UDPAddr
+}
+
+func (c *anySourceConn) Read(b []byte) (int, error) {
+	n, _, err := c.UDPConn.ReadFrom(b)
+	return n, err
+}
+
+func (c *anySourceConn) Write(b []byte) (int, error) {
+	return c.UDPConn.WriteTo(b, c.remote)
+}
+
+func (c *anySourceConn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+// listenAnySource opens an anySourceConn from local (which may be nil) to
+// the udp address addr
+func listenAnySource(local *net.UDPAddr, addr string) (net.Conn, error) {
+	remote, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	network := "udp4"
+	if remote.IP.To4() == nil {
+		network = "udp6"
+	}
+	conn, err := net.ListenUDP(network, local)
+	if err != nil {
+		return nil, err
+	}
+	return &anySourceConn{conn, remote}, nil
+}
+
+// readFrom reads a datagram from conn, returning the address it was sent
+// from: for conns without a ReadFrom method (as net.PacketConns have),
+// their RemoteAddr
+func readFrom(conn net.Conn, b []byte) (int, net.Addr, error) {
+	if pc, ok := conn.(interface {
+		ReadFrom(b []byte) (int, net.Addr, error)
+	}); ok {
+		return pc.ReadFrom(b)
+	}
+	n, err := conn.Read(b)
+	return n, conn.RemoteAddr(), err
+}
+
+// sameAddr reports whether a and b are the same udp address
+func sameAddr(a, b net.Addr) bool {
+	ua, ok := a.(*net.UDPAddr)
+	ub, ok2 := b.(*net.UDPAddr)
+	if !ok || !ok2 {
+		return a == nil || b == nil || a.String() == b.String()
+	}
+	return ua.IP.Equal(ub.IP) && ua.Port == ub.Port
+}
+
+// checkSource checks a response from an address other than the Target's:
+// an SNMPv3 response must be from the engine of the request, once it has
+// been discovered
+func (x *GoSNMP) checkSource(request, response *SnmpPacket) error {
+	if sameAddr(response.Source, x.Conn.RemoteAddr()) {
+		return nil
+	}
+	x.logInfo("Response from another address", "target", x.Target, "source", response.Source)
+	if response.Version != Version3 {
+		return nil
+	}
+	sent, ok := request.SecurityParameters.(*UsmSecurityParameters)
+	if !ok || sent.AuthoritativeEngineID == "" {
+		return nil
+	}
+	received, ok := response.SecurityParameters.(*UsmSecurityParameters)
+	if !ok || received.AuthoritativeEngineID != sent.AuthoritativeEngineID {
+		return fmt.Errorf("Response from %s is from another engine", response.Source)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+// startOtherSourceAgent starts an agent that answers requests to the
+// address returned from another socket, whose address is returned too
+func startOtherSourceAgent(t *testing.T) (to, from *net.UDPAddr) {
+	t.Helper()
+	in, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("ListenUDP() err: %v", err)
+	}
+	out, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("ListenUDP() err: %v", err)
+	}
+	t.Cleanup(func() {
+		in.Close()
+		out.Close()
+	})
+	x := &GoSNMP{Community: "public", Version: Version2c}
+	reply := MockReply(x, []SnmpPDU{{Name: testSysDescr, Type: OctetString, Value: []byte("descr")}})
+	go func() {
+		buf := make([]byte, rxBufSize)
+		for {
+			n, addr, err := in.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			for _, response := range reply(buf[:n]) {
+				out.WriteTo(response, addr)
+			}
+		}
+	}()
+	return in.LocalAddr().(*net.UDPAddr), out.LocalAddr().(*net.UDPAddr)
+}
+
+func TestAnySource(t *testing.T) {
+	to, from := startOtherSourceAgent(t)
+	for _, anySource := range []bool{false, true} {
+		x := &GoSNMP{
+			Target:    to.IP.String(),
+			Port:      uint16(to.Port),
+			Community: "public",
+			Version:   Version2c,
+			Timeout:   200 * time.Millisecond,
+			AnySource: anySource,
+		}
+		if err := x.Connect(); err != nil {
+			t.Fatalf("Connect() err: %v", err)
+		}
+		result, err := x.Get([]string{testSysDescr})
+		x.Conn.Close()
+		if !anySource {
+			if err == nil {
+				t.Errorf("Get() accepted a response from another address")
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("AnySource Get() err: %v", err)
+		}
+		if !sameAddr(result.Source, from) {
+			t.Errorf("Source = %v, expected %v", result.Source, from)
+		}
+	}
+}
+
+func TestCheckSource(t *testing.T) {
+	target := &net.UDPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 161}
+	other := &net.UDPAddr{IP: net.IPv4(192, 0, 2, 2), Port: 161}
+	x := &GoSNMP{Conn: &anySourceConn{remote: target}}
+	v3 := func(engineID string) SnmpV3SecurityParameters {
+		return &UsmSecurityParameters{AuthoritativeEngineID: engineID}
+	}
+	tests := []struct {
+		name           string
+		source         net.Addr
+		version        SnmpVersion
+		sent, received SnmpV3SecurityParameters
+		ok             bool
+	}{
+		{"target", target, Version3, v3("engine"), v3("other"), true},
+		{"v2c", other, Version2c, nil, nil, true},
+		{"same engine", other, Version3, v3("engine"), v3("engine"), true},
+		{"undiscovered", other, Version3, v3(""), v3("other"), true},
+		{"other engine", other, Version3, v3("engine"), v3("other"), false},
+	}
+	for _, test := range tests {
+		request := &SnmpPacket{Version: test.version, SecurityParameters: test.sent}
+		response := &SnmpPacket{Version: test.version, SecurityParameters: test.received, Source: test.source}
+		if err := x.checkSource(request, response); (err == nil) != test.ok {
+			t.Errorf("%s: checkSource() err: %v", test.name, err)
+		}
+	}
+}
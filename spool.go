@@ -0,0 +1,281 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//
+// Spooling notifications to disk, for receivers that are unreachable
+//
+
+const (
+	defaultMaxSpooled    = 1000
+	defaultRetryInterval = 10 * time.Second
+	spoolSuffix          = ".json"
+)
+
+// NotificationSpool sends traps and informs with a session, keeping those
+// that can't be delivered in a directory, and sending them again, in the
+// order they were sent, until they are. Spooled notifications are kept
+// across restarts: a spool started on the directory sends those left by
+// an earlier one.
+//
+// Informs are delivered at least once: they are written to the directory
+// before they are sent, and removed once they are acknowledged, so an
+// inform whose acknowledgement is lost, or that is being sent when the
+// program stops, is sent again. Traps aren't acknowledged, so they are
+// only spooled when sending them fails, eg because the receiver's port is
+// unreachable, or while earlier notifications are spooled.
+//
+// The fields must be set before the spool is started.
+type NotificationSpool struct {
+	// Session is the connected session notifications are sent with,
+	// SNMPv2c or SNMPv3; its retries and timeouts are those of informs
+	Session *GoSNMP
+
+	// Dir is the directory notifications are spooled in, which is
+	// created if it doesn't exist. Each is a file of the SnmpPacket JSON
+	// encoding, named by its sequence.
+	Dir string
+
+	// MaxSpooled is the number of notifications that can be spooled;
+	// notifications sent while it is full fail (default: 1000)
+	MaxSpooled int
+
+	// RetryInterval is how long the spool waits after failing to
+	// deliver a notification before sending it again (default: 10s)
+	RetryInterval time.Duration
+
+	m       sync.Mutex
+	sending sync.Mutex // held while the session is sending
+	started bool
+	closed  bool
+	next    uint64   // the sequence of the next notification spooled
+	pending []string // the files spooled, in order
+	wake    chan struct{}
+	done    chan struct{} // closed by Close
+	stopped chan struct{} // closed when the sender has returned
+}
+
+// Start loads the notifications spooled in Dir, and starts sending them.
+// It is called by SendTrap and SendInform if it hasn't been.
+func (s *NotificationSpool) Start() error {
+	s.m.Lock()
+	defer s.m.Unlock()
+	return s.start()
+}
+
+func (s *NotificationSpool) start() error {
+	if s.closed {
+		return fmt.Errorf("NotificationSpool is closed")
+	}
+	if s.started {
+		return nil
+	}
+	switch s.Session.Version {
+	case Version2c, Version3:
+	default:
+		return fmt.Errorf("NotificationSpool doesn't support %s", s.Session.Version)
+	}
+	if err := os.MkdirAll(s.Dir, 0o700); err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, spoolSuffix), 10, 64)
+		if !strings.HasSuffix(name, spoolSuffix) || err != nil {
+			continue
+		}
+		s.pending = append(s.pending, name)
+		if seq >= s.next {
+			s.next = seq + 1
+		}
+	}
+	sort.Strings(s.pending)
+	s.wake = make(chan struct{}, 1)
+	s.done = make(chan struct{})
+	s.stopped = make(chan struct{})
+	s.started = true
+	go s.send()
+	return nil
+}
+
+// SendTrap sends an SNMPv2 trap of pdus, as GoSNMP.SendTrap does, or if
+// it can't be sent or notifications are already spooled, spools it.
+func (s *NotificationSpool) SendTrap(pdus []SnmpPDU) error {
+	pdus, err := notificationPDUs("SendTrap", pdus, s.Session.Logger)
+	if err != nil {
+		return err
+	}
+	s.m.Lock()
+	if err = s.start(); err != nil || len(s.pending) > 0 {
+		defer s.m.Unlock()
+		if err != nil {
+			return err
+		}
+		return s.spool(SNMPv2Trap, pdus)
+	}
+	s.m.Unlock()
+
+	s.sending.Lock()
+	_, err = s.Session.sendTrap(s.Session.mkSnmpPacket(SNMPv2Trap, pdus, 0, 0))
+	s.sending.Unlock()
+	if err == nil {
+		return nil
+	}
+	s.Session.logWarn("Spooling trap", "target", s.Session.Target, "err", err)
+	s.m.Lock()
+	defer s.m.Unlock()
+	return s.spool(SNMPv2Trap, pdus)
+}
+
+// SendInform spools an inform of pdus, returning once it is written to
+// Dir; it is then sent until it is acknowledged.
+func (s *NotificationSpool) SendInform(pdus []SnmpPDU) error {
+	pdus, err := notificationPDUs("SendInform", pdus, s.Session.Logger)
+	if err != nil {
+		return err
+	}
+	s.m.Lock()
+	defer s.m.Unlock()
+	if err = s.start(); err != nil {
+		return err
+	}
+	return s.spool(InformRequest, pdus)
+}
+
+// Len returns the number of notifications spooled
+func (s *NotificationSpool) Len() int {
+	s.m.Lock()
+	defer s.m.Unlock()
+	return len(s.pending)
+}
+
+// Close stops the spool sending notifications, once any being sent has
+// been; those not delivered are left in Dir.
+func (s *NotificationSpool) Close() error {
+	s.m.Lock()
+	if s.closed || !s.started {
+		s.closed = true
+		s.m.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.done)
+	s.m.Unlock()
+	<-s.stopped
+	return nil
+}
+
+// spool writes a notification to Dir, for the sender
+func (s *NotificationSpool) spool(pduType PDUType, pdus []SnmpPDU) error {
+	max := s.MaxSpooled
+	if max <= 0 {
+		max = defaultMaxSpooled
+	}
+	if len(s.pending) >= max {
+		return fmt.Errorf("NotificationSpool %s is full", s.Dir)
+	}
+	data, err := json.Marshal(&SnmpPacket{Version: s.Session.Version, PDUType: pduType, Variables: pdus})
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%020d%s", s.next, spoolSuffix)
+	path := filepath.Join(s.Dir, name)
+	if err = os.WriteFile(path+".tmp", data, 0o600); err != nil {
+		return err
+	}
+	if err = os.Rename(path+".tmp", path); err != nil {
+		return err
+	}
+	s.next++
+	s.pending = append(s.pending, name)
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// send sends the spooled notifications, in order, until the spool is
+// closed
+func (s *NotificationSpool) send() {
+	defer close(s.stopped)
+	interval := s.RetryInterval
+	if interval <= 0 {
+		interval = defaultRetryInterval
+	}
+	for {
+		s.m.Lock()
+		name := ""
+		if len(s.pending) > 0 {
+			name = s.pending[0]
+		}
+		s.m.Unlock()
+		if name == "" {
+			select {
+			case <-s.wake:
+				continue
+			case <-s.done:
+				return
+			}
+		}
+
+		if err := s.deliver(name); err != nil {
+			s.Session.logWarn("Unable to deliver spooled notification", "target", s.Session.Target, "err", err)
+			select {
+			case <-time.After(interval):
+				continue
+			case <-s.done:
+				return
+			}
+		}
+		os.Remove(filepath.Join(s.Dir, name))
+		s.m.Lock()
+		s.pending = s.pending[1:]
+		s.m.Unlock()
+	}
+}
+
+// deliver sends a spooled notification, returning nil if it was sent (or
+// for an inform, acknowledged), or can never be
+func (s *NotificationSpool) deliver(name string) error {
+	x := s.Session
+	var packet SnmpPacket
+	data, err := os.ReadFile(filepath.Join(s.Dir, name))
+	if err == nil {
+		err = json.Unmarshal(data, &packet)
+	}
+	if err != nil {
+		x.logError("Dropping unreadable spooled notification", "file", name, "err", err)
+		return nil
+	}
+	s.sending.Lock()
+	defer s.sending.Unlock()
+	switch packet.PDUType {
+	case InformRequest:
+		_, err = x.sendInform(context.Background(), func() *SnmpPacket { return x.mkInform(packet.Variables) })
+	case SNMPv2Trap:
+		_, err = x.sendTrap(x.mkSnmpPacket(SNMPv2Trap, packet.Variables, 0, 0))
+	default:
+		x.logError("Dropping spooled notification", "file", name, "type", packet.PDUType)
+	}
+	return err
+}
@@ -0,0 +1,102 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"net"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNotificationSpool(t *testing.T) {
+	// a receiver that is unreachable until reachable is set
+	var reachable int32
+	tl := NewTrapListener()
+	received := make(chan *SnmpPacket, 8)
+	tl.OnNewTrap = func(s *SnmpPacket, u *net.UDPAddr) { received <- s }
+	tl.Params = &GoSNMP{}
+	tl.Filter = &TrapFilter{Func: func(addr *net.UDPAddr, header *SnmpPacket) bool {
+		return atomic.LoadInt32(&reachable) == 1
+	}}
+	addr := listenTestTraps(t, tl)
+	defer tl.Close()
+
+	x := &GoSNMP{
+		Target:    "127.0.0.1",
+		Port:      uint16(addr.Port),
+		Community: "public",
+		Version:   Version2c,
+		Timeout:   50 * time.Millisecond,
+		MaxOids:   MaxOids,
+	}
+	if err := x.Connect(); err != nil {
+		t.Fatalf("Connect() err: %v", err)
+	}
+	defer x.Conn.Close()
+	dir := t.TempDir()
+	newSpool := func() *NotificationSpool {
+		return &NotificationSpool{Session: x, Dir: dir, MaxSpooled: 3, RetryInterval: 50 * time.Millisecond}
+	}
+
+	// informs that aren't acknowledged stay spooled, as do the traps
+	// after them, until the spool is full
+	spool := newSpool()
+	for i, value := range []string{"first", "second"} {
+		if err := spool.SendInform([]SnmpPDU{{Name: trapTestOid, Type: OctetString, Value: value}}); err != nil {
+			t.Fatalf("%d: SendInform() err: %v", i, err)
+		}
+	}
+	if err := spool.SendTrap([]SnmpPDU{{Name: trapTestOid, Type: OctetString, Value: "third"}}); err != nil {
+		t.Fatalf("SendTrap() err: %v", err)
+	}
+	if err := spool.SendTrap([]SnmpPDU{{Name: trapTestOid, Type: OctetString, Value: "fourth"}}); err == nil {
+		t.Error("SendTrap() to a full spool succeeded")
+	}
+	time.Sleep(200 * time.Millisecond)
+	if n := spool.Len(); n != 3 {
+		t.Errorf("Len() is %d, expected 3", n)
+	}
+	spool.Close()
+	if entries, _ := os.ReadDir(dir); len(entries) != 3 {
+		t.Errorf("spooled %d files, expected 3", len(entries))
+	}
+
+	// a spool started on the directory delivers them, in order
+	atomic.StoreInt32(&reachable, 1)
+	spool = newSpool()
+	defer spool.Close()
+	if err := spool.Start(); err != nil {
+		t.Fatalf("Start() err: %v", err)
+	}
+	for i, want := range []string{"first", "second", "third"} {
+		select {
+		case s := <-received:
+			if len(s.Variables) != 2 || string(s.Variables[1].Value.([]byte)) != want {
+				t.Errorf("%d: received %s, expected %s", i, s, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%d: timed out waiting for %s", i, want)
+		}
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for spool.Len() > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 || spool.Len() != 0 {
+		t.Errorf("%d files and %d notifications spooled after delivery", len(entries), spool.Len())
+	}
+
+	// and once it is empty, traps are sent straight away
+	if err := spool.SendTrap([]SnmpPDU{{Name: trapTestOid, Type: OctetString, Value: "fifth"}}); err != nil || spool.Len() != 0 {
+		t.Errorf("SendTrap() err: %v, with %d spooled", err, spool.Len())
+	}
+	select {
+	case <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the trap")
+	}
+}
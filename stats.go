@@ -0,0 +1,108 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"sync/atomic"
+)
+
+// Stats is a snapshot of the counts a GoSNMP or TrapListener keeps of its
+// traffic, eg for exporting as metrics. The counts only ever increase.
+type Stats struct {
+	// PacketsSent counts the packets sent, including retransmissions
+	PacketsSent uint64
+
+	// PacketsReceived counts the packets received, including those that
+	// were undecodable, unauthentic or late
+	PacketsReceived uint64
+
+	// Retransmissions counts the packets sent again after an attempt at a
+	// request timed out, see RetryPolicy
+	Retransmissions uint64
+
+	// Timeouts counts the requests that timed out after all their attempts
+	Timeouts uint64
+
+	// LateResponses counts the responses dropped because their request
+	// had completed, eg duplicates, or the responses to attempts that were
+	// retried after another attempt was answered
+	LateResponses uint64
+
+	// DecodeErrors counts the received packets that couldn't be decoded
+	DecodeErrors uint64
+
+	// AuthErrors counts the received SNMPv3 packets that failed
+	// authentication
+	AuthErrors uint64
+
+	// AccessDenied counts the traps received that a TrapListener's Vacm
+	// rejected
+	AccessDenied uint64
+
+	// Dropped counts the traps a TrapListener dropped because its queue
+	// for its Workers was full
+	Dropped uint64
+
+	// Rejected counts the packets a TrapListener's Filter rejected
+	Rejected uint64
+
+	// Duplicates counts the traps a TrapListener dropped as duplicates,
+	// see DedupWindow
+	Duplicates uint64
+}
+
+// counters are the atomically updated counts of a Stats
+type counters struct {
+	sent, received, retransmissions, timeouts, decodeErrors, authErrors uint64
+	accessDenied, dropped, rejected, duplicates, late                   uint64
+}
+
+func (c *counters) snapshot() Stats {
+	return Stats{
+		PacketsSent:     atomic.LoadUint64(&c.sent),
+		PacketsReceived: atomic.LoadUint64(&c.received),
+		Retransmissions: atomic.LoadUint64(&c.retransmissions),
+		Timeouts:        atomic.LoadUint64(&c.timeouts),
+		LateResponses:   atomic.LoadUint64(&c.late),
+		DecodeErrors:    atomic.LoadUint64(&c.decodeErrors),
+		AuthErrors:      atomic.LoadUint64(&c.authErrors),
+		AccessDenied:    atomic.LoadUint64(&c.accessDenied),
+		Dropped:         atomic.LoadUint64(&c.dropped),
+		Rejected:        atomic.LoadUint64(&c.rejected),
+		Duplicates:      atomic.LoadUint64(&c.duplicates),
+	}
+}
+
+// Stats returns the counts of x's traffic so far
+func (x *GoSNMP) Stats() Stats {
+	return x.counters().snapshot()
+}
+
+// counters returns x's counters, creating them on first use
+func (x *GoSNMP) counters() *counters {
+	lazyInitMu.Lock()
+	defer lazyInitMu.Unlock()
+	if x.stats == nil {
+		x.stats = new(counters)
+	}
+	return x.stats
+}
+
+// Stats returns the counts of the traps received so far, of which only
+// PacketsReceived, DecodeErrors, AuthErrors, AccessDenied, Dropped,
+// Rejected and Duplicates are kept, and PacketsSent, the responses to
+// informs and reports
+func (t *TrapListener) Stats() Stats {
+	return t.counters().snapshot()
+}
+
+func (t *TrapListener) counters() *counters {
+	t.m.Lock()
+	defer t.m.Unlock()
+	if t.stats == nil {
+		t.stats = new(counters)
+	}
+	return t.stats
+}
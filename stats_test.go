@@ -0,0 +1,88 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	const oid = ".1.3.6.1.2.1.1.5.0"
+	r := newTestResponder(t, []SnmpPDU{{Name: oid, Type: OctetString, Value: []byte("name")}})
+	defer r.Close()
+	x := r.client(t)
+	defer x.Conn.Close()
+
+	// the first attempt is dropped, the second answered
+	attempts := 0
+	r.setHandler(func(req *SnmpPacket) *SnmpPacket {
+		if attempts++; attempts == 1 {
+			return nil
+		}
+		return r.respond(req)
+	})
+	if _, err := x.Get([]string{oid}); err != nil {
+		t.Fatalf("Get() err: %v", err)
+	}
+	want := Stats{PacketsSent: 2, PacketsReceived: 1, Retransmissions: 1}
+	if got := x.Stats(); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	r.setHandler(func(req *SnmpPacket) *SnmpPacket { return nil })
+	x.Retries = 0
+	x.Timeout = 20 * time.Millisecond
+	x.Get([]string{oid})
+	want.PacketsSent++
+	want.Timeouts++
+	if got := x.Stats(); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestTrapListenerStats(t *testing.T) {
+	tl := NewTrapListener()
+	received := make(chan *SnmpPacket, 1)
+	tl.OnNewTrap = func(s *SnmpPacket, u *net.UDPAddr) { received <- s }
+	tl.Params = Default
+	go tl.Listen("127.0.0.1:0")
+	tl.c.L.Lock()
+	for !tl.ready() {
+		tl.c.Wait()
+	}
+	tl.c.L.Unlock()
+	defer tl.Close()
+
+	addr := tl.Addrs()[0].(*net.UDPAddr)
+	conn, err := net.DialUDP("udp", nil, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	trap := &SnmpPacket{
+		Version:   Version2c,
+		Community: "public",
+		PDUType:   SNMPv2Trap,
+		Variables: []SnmpPDU{{Name: ".1.3.6.1.2.1.1.5.0", Type: OctetString, Value: "name"}},
+	}
+	out, err := trap.marshalMsg()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Write([]byte{0x30, 0x03, 0x02})
+	conn.Write(out)
+	select {
+	case <-received:
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the trap")
+	}
+	want := Stats{PacketsReceived: 2, DecodeErrors: 1}
+	if got := tl.Stats(); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,175 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"context"
+	"sort"
+	"strconv"
+)
+
+// Table is a conceptual table, such as IF-MIB::ifTable, with its varbinds
+// grouped into rows by index. Agents don't have to return every column for
+// every row, so a cell may be missing: Indexes holds every row seen in any
+// column, and Columns every column seen in any row.
+//
+// Cells are matched up by their index rather than their position in the
+// walk, so rows stay aligned however sparse the table; see Missing.
+type Table struct {
+	// Oid is the oid of the table, eg ".1.3.6.1.2.1.2.2"
+	Oid string
+
+	// Columns are the column numbers, in ascending order
+	Columns []int
+
+	// Indexes are the indexes of the rows as dotted suffixes, eg "3" or
+	// "7.97.98", in oid order
+	Indexes []string
+
+	// Rows maps each row's index to its cells, by column number
+	Rows map[string]map[int]SnmpPDU
+}
+
+// NewTable builds a Table from the varbinds of a walk of the table at
+// tableOid. Varbinds that aren't cells of the table (that aren't
+// tableOid.1.column.index) are ignored.
+func NewTable(tableOid string, pdus []SnmpPDU) *Table {
+	t := &Table{
+		Oid:  "." + trimOidDot(tableOid),
+		Rows: make(map[string]map[int]SnmpPDU),
+	}
+	entry := t.Oid + ".1."
+	columns := make(map[int]bool)
+	for _, pdu := range pdus {
+		name := "." + trimOidDot(pdu.Name)
+		if len(name) <= len(entry) || name[:len(entry)] != entry {
+			continue
+		}
+		column, index, ok := splitCell(name[len(entry):])
+		if !ok {
+			continue
+		}
+		row, ok := t.Rows[index]
+		if !ok {
+			row = make(map[int]SnmpPDU)
+			t.Rows[index] = row
+			t.Indexes = append(t.Indexes, index)
+		}
+		row[column] = pdu
+		if !columns[column] {
+			columns[column] = true
+			t.Columns = append(t.Columns, column)
+		}
+	}
+	sort.Ints(t.Columns)
+	sort.SliceStable(t.Indexes, func(i, j int) bool {
+		return CompareOids(t.Indexes[i], t.Indexes[j]) < 0
+	})
+	return t
+}
+
+// splitCell splits the column number from the index in the part of a cell's
+// oid after the entry, eg "2.7.97.98"
+func splitCell(s string) (column int, index string, ok bool) {
+	for i := 0; i < len(s); i++ {
+		if s[i] != '.' {
+			continue
+		}
+		n, err := strconv.ParseUint(s[:i], 10, 32)
+		if err != nil || i == len(s)-1 {
+			return 0, "", false
+		}
+		return int(n), s[i+1:], true
+	}
+	return 0, "", false
+}
+
+// Cell returns the varbind for the given row and column, reporting whether
+// the agent returned it
+func (t *Table) Cell(index string, column int) (SnmpPDU, bool) {
+	pdu, ok := t.Rows[index][column]
+	return pdu, ok
+}
+
+// TableCell identifies a cell of a Table
+type TableCell struct {
+	Index  string
+	Column int
+}
+
+// Missing returns the cells of a sparse table that the agent didn't
+// return, in row and then column order. A column is only known if some row
+// has it, so a column missing from every row isn't reported.
+func (t *Table) Missing() []TableCell {
+	var missing []TableCell
+	for _, index := range t.Indexes {
+		row := t.Rows[index]
+		if len(row) == len(t.Columns) {
+			continue
+		}
+		for _, column := range t.Columns {
+			if _, ok := row[column]; !ok {
+				missing = append(missing, TableCell{index, column})
+			}
+		}
+	}
+	return missing
+}
+
+// Sparse reports whether some rows of the table lack cells in some columns
+func (t *Table) Sparse() bool {
+	for _, row := range t.Rows {
+		if len(row) != len(t.Columns) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetTable retrieves the table at tableOid (the table itself, eg
+// IF-MIB::ifTable, rather than its entry), walking it with GETBULK or, for
+// SNMPv1, GETNEXT.
+func (x *GoSNMP) GetTable(tableOid string) (*Table, error) {
+	return x.GetTableCtx(context.Background(), tableOid)
+}
+
+// GetTableCtx is like GetTable, but the walk stops with ctx.Err() when ctx
+// is cancelled or its deadline passes.
+func (x *GoSNMP) GetTableCtx(ctx context.Context, tableOid string) (*Table, error) {
+	requestType := GetBulkRequest
+	if x.Version == Version1 {
+		requestType = GetNextRequest
+	}
+	pdus, err := x.walkAll(ctx, requestType, tableOid)
+	if err != nil {
+		return nil, err
+	}
+	return NewTable(tableOid, pdus), nil
+}
+
+// GetTableColumns retrieves only the given columns of the table at
+// tableOid, walking them side by side with BulkWalkColumns
+func (x *GoSNMP) GetTableColumns(tableOid string, columns []int) (*Table, error) {
+	return x.GetTableColumnsCtx(context.Background(), tableOid, columns)
+}
+
+// GetTableColumnsCtx is like GetTableColumns, but the walk stops with
+// ctx.Err() when ctx is cancelled or its deadline passes.
+func (x *GoSNMP) GetTableColumnsCtx(ctx context.Context, tableOid string, columns []int) (*Table, error) {
+	entry := "." + trimOidDot(tableOid) + ".1."
+	oids := make([]string, len(columns))
+	for i, column := range columns {
+		oids[i] = entry + strconv.Itoa(column)
+	}
+	var pdus []SnmpPDU
+	err := x.walkColumns(ctx, oids, func(pdu SnmpPDU) error {
+		pdus = append(pdus, pdu)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return NewTable(tableOid, pdus), nil
+}
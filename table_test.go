@@ -0,0 +1,171 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"reflect"
+	"testing"
+)
+
+var tableTestPdus = []SnmpPDU{
+	{Name: ".1.3.6.1.2.1.2.1.0", Type: Integer, Value: 3},
+	{Name: ".1.3.6.1.2.1.2.2.1.1.1", Type: Integer, Value: 1},
+	{Name: ".1.3.6.1.2.1.2.2.1.1.2", Type: Integer, Value: 2},
+	{Name: ".1.3.6.1.2.1.2.2.1.1.10", Type: Integer, Value: 10},
+	{Name: ".1.3.6.1.2.1.2.2.1.2.1", Type: OctetString, Value: []byte("lo")},
+	{Name: ".1.3.6.1.2.1.2.2.1.2.10", Type: OctetString, Value: []byte("eth1")},
+	// a row only present in a later column
+	{Name: ".1.3.6.1.2.1.2.2.1.10.3", Type: Integer, Value: 5},
+	{Name: ".1.3.6.1.2.1.2.2.1.10.10", Type: Integer, Value: 7},
+	{Name: ".1.3.6.1.2.1.2.2.1.10", Type: Integer, Value: 0},
+	{Name: ".1.3.6.1.2.1.2.3.0", Type: Integer, Value: 1},
+}
+
+func TestNewTable(t *testing.T) {
+	table := NewTable("1.3.6.1.2.1.2.2", tableTestPdus)
+
+	if table.Oid != ".1.3.6.1.2.1.2.2" {
+		t.Errorf("got Oid %s", table.Oid)
+	}
+	if want := []int{1, 2, 10}; !reflect.DeepEqual(table.Columns, want) {
+		t.Errorf("got Columns %v, want %v", table.Columns, want)
+	}
+	if want := []string{"1", "2", "3", "10"}; !reflect.DeepEqual(table.Indexes, want) {
+		t.Errorf("got Indexes %v, want %v", table.Indexes, want)
+	}
+
+	tests := []struct {
+		index  string
+		column int
+		ok     bool
+		value  interface{}
+	}{
+		{"1", 2, true, []byte("lo")},
+		{"2", 2, false, nil},
+		{"3", 1, false, nil},
+		{"3", 10, true, 5},
+		{"10", 10, true, 7},
+		{"4", 1, false, nil},
+	}
+	for _, test := range tests {
+		pdu, ok := table.Cell(test.index, test.column)
+		if ok != test.ok || !reflect.DeepEqual(pdu.Value, test.value) {
+			t.Errorf("Cell(%s, %d): got %v, %t", test.index, test.column, pdu, ok)
+		}
+	}
+}
+
+func TestGetTable(t *testing.T) {
+	r := newTestResponder(t, tableTestPdus)
+	defer r.Close()
+
+	for _, version := range []SnmpVersion{Version1, Version2c} {
+		x := r.client(t)
+		x.Version = version
+		table, err := x.GetTable(".1.3.6.1.2.1.2.2")
+		x.Conn.Close()
+		if err != nil {
+			t.Fatalf("%s: GetTable() err: %v", version, err)
+		}
+		want := NewTable(".1.3.6.1.2.1.2.2", tableTestPdus)
+		if !reflect.DeepEqual(table.Indexes, want.Indexes) || !reflect.DeepEqual(table.Columns, want.Columns) {
+			t.Errorf("%s: got %+v, want %+v", version, table, want)
+			continue
+		}
+		for _, index := range want.Indexes {
+			for _, column := range want.Columns {
+				got, _ := table.Cell(index, column)
+				cell, _ := want.Cell(index, column)
+				if got.Name != cell.Name || !reflect.DeepEqual(got.Value, cell.Value) {
+					t.Errorf("%s: Cell(%s, %d): got %s, want %s", version, index, column, got, cell)
+				}
+			}
+		}
+	}
+
+	var bulk bool
+	for _, req := range r.received() {
+		bulk = bulk || req.PDUType == GetBulkRequest
+		if req.Version == Version1 && req.PDUType != GetNextRequest {
+			t.Errorf("SNMPv1 request was %s", req.PDUType)
+		}
+	}
+	if !bulk {
+		t.Errorf("GETBULK not used for SNMPv2c")
+	}
+}
+
+func TestTableMissing(t *testing.T) {
+	table := NewTable(".1.3.6.1.2.1.2.2", tableTestPdus)
+	if !table.Sparse() {
+		t.Errorf("Sparse() = false")
+	}
+	want := []TableCell{{"1", 10}, {"2", 2}, {"2", 10}, {"3", 1}, {"3", 2}}
+	if got := table.Missing(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got Missing %v, want %v", got, want)
+	}
+
+	full := NewTable(".1.3.6.1.2.1.2.2", tableTestPdus[1:4])
+	if full.Sparse() || full.Missing() != nil {
+		t.Errorf("full table: Sparse() = %t, Missing() = %v", full.Sparse(), full.Missing())
+	}
+}
+
+func TestBulkWalkColumns(t *testing.T) {
+	r := newTestResponder(t, tableTestPdus)
+	defer r.Close()
+
+	for _, version := range []SnmpVersion{Version1, Version2c} {
+		x := r.client(t)
+		x.Version = version
+		x.MaxRepetitions = 2
+		before := len(r.received())
+
+		var names []string
+		err := x.BulkWalkColumns([]string{".1.3.6.1.2.1.2.2.1.1", "1.3.6.1.2.1.2.2.1.10"}, func(pdu SnmpPDU) error {
+			names = append(names, pdu.Name)
+			return nil
+		})
+		x.Conn.Close()
+		if err != nil {
+			t.Fatalf("%s: BulkWalkColumns() err: %v", version, err)
+		}
+		want := []string{
+			".1.3.6.1.2.1.2.2.1.1.1", ".1.3.6.1.2.1.2.2.1.10.3",
+			".1.3.6.1.2.1.2.2.1.1.2", ".1.3.6.1.2.1.2.2.1.10.10",
+			".1.3.6.1.2.1.2.2.1.1.10",
+		}
+		if !reflect.DeepEqual(names, want) {
+			t.Errorf("%s: got %v, want %v", version, names, want)
+		}
+		requests := r.received()[before:]
+		if version == Version2c && len(requests) != 2 {
+			t.Errorf("%s: got %d requests, want 2", version, len(requests))
+		}
+		for _, req := range requests {
+			if len(req.Variables) > 2 {
+				t.Errorf("%s: request for %d varbinds", version, len(req.Variables))
+			}
+		}
+	}
+}
+
+func TestGetTableColumns(t *testing.T) {
+	r := newTestResponder(t, tableTestPdus)
+	defer r.Close()
+	x := r.client(t)
+	defer x.Conn.Close()
+
+	table, err := x.GetTableColumns(".1.3.6.1.2.1.2.2", []int{2, 10})
+	if err != nil {
+		t.Fatalf("GetTableColumns() err: %v", err)
+	}
+	if want := []int{2, 10}; !reflect.DeepEqual(table.Columns, want) {
+		t.Errorf("got Columns %v, want %v", table.Columns, want)
+	}
+	if want := []string{"1", "3", "10"}; !reflect.DeepEqual(table.Indexes, want) {
+		t.Errorf("got Indexes %v, want %v", table.Indexes, want)
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+	"time"
+)
+
+//
+// Decoders for common textual conventions. Like ToBigInt they take an
+// SnmpPDU's Value, so no type assertions are needed.
+//
+
+// ToDateAndTime decodes a DateAndTime (SNMPv2-TC), the 8 or 11 byte
+// OctetString holding a date, a time to a tenth of a second, and optionally
+// the offset from UTC. A DateAndTime without an offset is taken to be UTC.
+func ToDateAndTime(value interface{}) (time.Time, error) {
+	b, ok := value.([]byte)
+	if !ok {
+		return time.Time{}, fmt.Errorf("DateAndTime: expected []byte, got %T", value)
+	}
+	if len(b) != 8 && len(b) != 11 {
+		return time.Time{}, fmt.Errorf("DateAndTime: invalid length %d", len(b))
+	}
+	year := int(binary.BigEndian.Uint16(b))
+	month, day, hour, min, sec, deci := int(b[2]), int(b[3]), int(b[4]), int(b[5]), int(b[6]), int(b[7])
+	if month < 1 || month > 12 || day < 1 || day > 31 || hour > 23 || min > 59 || sec > 60 || deci > 9 {
+		return time.Time{}, fmt.Errorf("DateAndTime: invalid value % x", b)
+	}
+
+	loc := time.UTC
+	if len(b) == 11 {
+		if (b[8] != '+' && b[8] != '-') || b[9] > 13 || b[10] > 59 {
+			return time.Time{}, fmt.Errorf("DateAndTime: invalid offset from UTC % x", b[8:])
+		}
+		offset := int(b[9])*3600 + int(b[10])*60
+		if b[8] == '-' {
+			offset = -offset
+		}
+		if offset != 0 {
+			loc = time.FixedZone("", offset)
+		}
+	}
+	return time.Date(year, time.Month(month), day, hour, min, sec, deci*int(100*time.Millisecond), loc), nil
+}
+
+// ToHardwareAddr decodes a MacAddress (SNMPv2-TC), or a PhysAddress that
+// holds one
+func ToHardwareAddr(value interface{}) (net.HardwareAddr, error) {
+	b, ok := value.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("MacAddress: expected []byte, got %T", value)
+	}
+	if len(b) != 6 {
+		return nil, fmt.Errorf("MacAddress: invalid length %d", len(b))
+	}
+	return net.HardwareAddr(append([]byte(nil), b...)), nil
+}
+
+// The values of InetAddressType (INET-ADDRESS-MIB) that ToInetAddress
+// decodes
+const (
+	InetAddressTypeUnknown = 0
+	InetAddressTypeIPv4    = 1
+	InetAddressTypeIPv6    = 2
+	InetAddressTypeIPv4z   = 3
+	InetAddressTypeIPv6z   = 4
+	InetAddressTypeDNS     = 16
+)
+
+// ToInetAddress decodes an InetAddress (INET-ADDRESS-MIB), given the value
+// of the InetAddressType object describing it. The zone index of an ipv4z
+// or ipv6z address is given as the zone of the netip.Addr. An unknown
+// address (of type unknown, with no bytes) decodes as the zero Addr; dns
+// addresses and other types are an error.
+func ToInetAddress(addrType interface{}, addr interface{}) (netip.Addr, error) {
+	b, ok := addr.([]byte)
+	if !ok {
+		return netip.Addr{}, fmt.Errorf("InetAddress: expected []byte, got %T", addr)
+	}
+	t := ToBigInt(addrType).Int64()
+
+	var size int
+	switch t {
+	case InetAddressTypeUnknown:
+		if len(b) != 0 {
+			return netip.Addr{}, fmt.Errorf("InetAddress: unknown address type with %d bytes", len(b))
+		}
+		return netip.Addr{}, nil
+	case InetAddressTypeIPv4, InetAddressTypeIPv4z:
+		size = 4
+	case InetAddressTypeIPv6, InetAddressTypeIPv6z:
+		size = 16
+	case InetAddressTypeDNS:
+		return netip.Addr{}, fmt.Errorf("InetAddress: dns names aren't addresses")
+	default:
+		return netip.Addr{}, fmt.Errorf("InetAddress: unsupported address type %d", t)
+	}
+
+	zoned := t == InetAddressTypeIPv4z || t == InetAddressTypeIPv6z
+	want := size
+	if zoned {
+		want += 4
+	}
+	if len(b) != want {
+		return netip.Addr{}, fmt.Errorf("InetAddress: invalid length %d for address type %d", len(b), t)
+	}
+	ip, _ := netip.AddrFromSlice(b[:size])
+	if zoned {
+		ip = ip.WithZone(strconv.FormatUint(uint64(binary.BigEndian.Uint32(b[size:])), 10))
+	}
+	return ip, nil
+}
+
+// ToTruthValue decodes a TruthValue (SNMPv2-TC), where 1 is true and 2 is
+// false
+func ToTruthValue(value interface{}) (bool, error) {
+	switch v := ToBigInt(value); {
+	case !v.IsInt64():
+	case v.Int64() == 1:
+		return true, nil
+	case v.Int64() == 2:
+		return false, nil
+	}
+	return false, fmt.Errorf("TruthValue: invalid value %v", value)
+}
@@ -0,0 +1,115 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestToDateAndTime(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  time.Time
+		err   string
+	}{
+		{[]byte{0x07, 0xe0, 5, 26, 13, 30, 15, 2},
+			time.Date(2016, 5, 26, 13, 30, 15, 200000000, time.UTC), ""},
+		{[]byte{0x07, 0xe0, 5, 26, 13, 30, 15, 0, '-', 4, 30},
+			time.Date(2016, 5, 26, 18, 0, 15, 0, time.UTC), ""},
+		{[]byte{0x07, 0xe0, 5, 26, 13, 30, 15, 0, '+', 0, 0},
+			time.Date(2016, 5, 26, 13, 30, 15, 0, time.UTC), ""},
+		{[]byte{0x07, 0xe0, 5, 26, 13, 30, 15}, time.Time{}, "invalid length 7"},
+		{[]byte{0x07, 0xe0, 13, 26, 13, 30, 15, 0}, time.Time{}, "invalid value"},
+		{[]byte{0x07, 0xe0, 5, 26, 13, 30, 15, 0, 'x', 0, 0}, time.Time{}, "invalid offset"},
+		{"2016-05-26", time.Time{}, "expected []byte"},
+	}
+	for i, test := range tests {
+		got, err := ToDateAndTime(test.value)
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%d: got error %v, want %q", i, err, test.err)
+			}
+			continue
+		}
+		if err != nil || !got.Equal(test.want) {
+			t.Errorf("%d: got %v, %v, want %v", i, got, err, test.want)
+		}
+	}
+
+	got, _ := ToDateAndTime([]byte{0x07, 0xe0, 5, 26, 13, 30, 15, 0, '+', 10, 0})
+	if _, offset := got.Zone(); offset != 36000 || got.Hour() != 13 {
+		t.Errorf("got %v, want 13:30 at +10:00", got)
+	}
+}
+
+func TestToHardwareAddr(t *testing.T) {
+	mac, err := ToHardwareAddr([]byte{0, 0x1b, 0x21, 0x3c, 0x9d, 0xf8})
+	if err != nil || mac.String() != "00:1b:21:3c:9d:f8" {
+		t.Errorf("got %v, %v", mac, err)
+	}
+	if _, err = ToHardwareAddr([]byte{1, 2, 3}); err == nil {
+		t.Errorf("expected an error for a short address")
+	}
+	if _, err = ToHardwareAddr(nil); err == nil {
+		t.Errorf("expected an error for nil")
+	}
+}
+
+func TestToInetAddress(t *testing.T) {
+	tests := []struct {
+		addrType interface{}
+		addr     interface{}
+		want     string
+		err      bool
+	}{
+		{InetAddressTypeIPv4, []byte{192, 0, 2, 1}, "192.0.2.1", false},
+		{InetAddressTypeIPv6, []byte{0x20, 0x01, 0x0d, 0xb8, 15: 1}, "2001:db8::1", false},
+		{InetAddressTypeIPv6z, []byte{0xfe, 0x80, 15: 1, 0, 0, 0, 3}, "fe80::1%3", false},
+		{InetAddressTypeIPv4z, []byte{10, 0, 0, 1, 0, 0, 0, 2}, "10.0.0.1", false},
+		{InetAddressTypeUnknown, []byte{}, "invalid IP", false},
+		{int(InetAddressTypeIPv4), []byte{192, 0, 2}, "", true},
+		{InetAddressTypeUnknown, []byte{1}, "", true},
+		{InetAddressTypeDNS, []byte("example.com"), "", true},
+		{99, []byte{}, "", true},
+		{InetAddressTypeIPv4, "192.0.2.1", "", true},
+	}
+	for i, test := range tests {
+		got, err := ToInetAddress(test.addrType, test.addr)
+		if (err != nil) != test.err {
+			t.Errorf("%d: got error %v", i, err)
+			continue
+		}
+		if !test.err && got.String() != test.want {
+			t.Errorf("%d: got %v, want %s", i, got, test.want)
+		}
+	}
+	if got, _ := ToInetAddress(InetAddressTypeIPv4, []byte{192, 0, 2, 1}); got != netip.MustParseAddr("192.0.2.1") {
+		t.Errorf("got %v, want an IPv4 Addr", got)
+	}
+}
+
+func TestToTruthValue(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  bool
+		err   bool
+	}{
+		{1, true, false},
+		{2, false, false},
+		{uint32(1), true, false},
+		{0, false, true},
+		{3, false, true},
+		{[]byte{1}, false, true},
+	}
+	for _, test := range tests {
+		got, err := ToTruthValue(test.value)
+		if got != test.want || (err != nil) != test.err {
+			t.Errorf("%v: got %v, %v", test.value, got, err)
+		}
+	}
+}
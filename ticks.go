@@ -0,0 +1,71 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"fmt"
+	"time"
+)
+
+// TimeTicks count hundredths of a second
+const timeTick = 10 * time.Millisecond
+
+// TimeTicksPeriod is how long TimeTicks take to wrap, as they are modulo
+// 2^32: about 497 days
+const TimeTicksPeriod = (1 << 32) * timeTick
+
+// bootTimeTolerance is how far apart two boot times worked out by BootTime
+// may be and still be taken as the same boot, allowing for the agent's
+// clock and the network
+const bootTimeTolerance = time.Minute
+
+// TimeTicksToDuration converts a TimeTicks Value (eg of sysUpTime) to a
+// Duration
+func TimeTicksToDuration(value interface{}) (time.Duration, error) {
+	v := ToBigInt(value)
+	if !v.IsInt64() || v.Sign() < 0 || v.Int64() >= 1<<32 {
+		return 0, fmt.Errorf("Invalid TimeTicks %v", value)
+	}
+	return time.Duration(v.Int64()) * timeTick, nil
+}
+
+// DurationToTimeTicks converts a Duration, rounded down to a hundredth of a
+// second, to TimeTicks. Durations longer than TimeTicksPeriod wrap, as
+// TimeTicks do.
+func DurationToTimeTicks(d time.Duration) uint32 {
+	if d < 0 {
+		return 0
+	}
+	return uint32(d / timeTick)
+}
+
+// BootTime works out when an agent booted (strictly, when its SNMP
+// service last re-initialised) from its sysUpTime and the local time the
+// value was received.
+//
+// sysUpTime wraps every 497 days, so on its own it can't tell a recent
+// boot from one 497 days earlier. Pass the boot time worked out from an
+// earlier sample as previous (or the zero Time if there isn't one). When
+// this sample is consistent with the same boot the previous boot time is
+// kept, counting the wraps since; otherwise the agent has restarted, and
+// the boot time from this sample alone is returned.
+func BootTime(sysUpTime interface{}, received time.Time, previous time.Time) (time.Time, error) {
+	uptime, err := TimeTicksToDuration(sysUpTime)
+	if err != nil {
+		return time.Time{}, err
+	}
+	boot := received.Add(-uptime)
+	if previous.IsZero() || previous.After(boot.Add(bootTimeTolerance)) {
+		return boot, nil
+	}
+
+	// boot is previous plus a whole number of wraps, if it's the same boot
+	wraps := (boot.Sub(previous) + TimeTicksPeriod/2) / TimeTicksPeriod
+	candidate := boot.Add(-wraps * TimeTicksPeriod)
+	if diff := candidate.Sub(previous); diff <= bootTimeTolerance && diff >= -bootTimeTolerance {
+		return previous, nil
+	}
+	return boot, nil
+}
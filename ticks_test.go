@@ -0,0 +1,81 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeTicks(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  time.Duration
+		err   bool
+	}{
+		{0, 0, false},
+		{123456, 1234560 * time.Millisecond, false},
+		{uint32(4294967295), 42949672950 * time.Millisecond, false},
+		{-1, 0, true},
+		{int64(1 << 32), 0, true},
+	}
+	for _, test := range tests {
+		got, err := TimeTicksToDuration(test.value)
+		if got != test.want || (err != nil) != test.err {
+			t.Errorf("%v: got %v, %v, want %v", test.value, got, err, test.want)
+		}
+	}
+
+	for _, test := range []struct {
+		d    time.Duration
+		want uint32
+	}{
+		{1234567 * time.Millisecond, 123456},
+		{-time.Second, 0},
+		{TimeTicksPeriod + time.Second, 100},
+	} {
+		if got := DurationToTimeTicks(test.d); got != test.want {
+			t.Errorf("%v: got %d, want %d", test.d, got, test.want)
+		}
+	}
+}
+
+func TestBootTime(t *testing.T) {
+	boot := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	ticks := func(d time.Duration) uint32 { return DurationToTimeTicks(d) }
+
+	// without a previous boot time, sysUpTime is taken as it is
+	received := boot.Add(10 * 24 * time.Hour)
+	got, err := BootTime(ticks(received.Sub(boot)), received, time.Time{})
+	if err != nil || !got.Equal(boot) {
+		t.Errorf("first sample: got %v, %v", got, err)
+	}
+
+	// two wraps later, with a little clock skew, it's the same boot
+	received = boot.Add(2*TimeTicksPeriod + 3*time.Hour)
+	got, err = BootTime(ticks(received.Sub(boot)+2*time.Second), received, boot)
+	if err != nil || !got.Equal(boot) {
+		t.Errorf("after wrapping: got %v, %v", got, err)
+	}
+
+	// a restart
+	restart := boot.Add(30 * 24 * time.Hour)
+	received = restart.Add(time.Hour)
+	got, err = BootTime(ticks(time.Hour), received, boot)
+	if err != nil || !got.Equal(restart) {
+		t.Errorf("after restarting: got %v, %v, want %v", got, err, restart)
+	}
+
+	// a previous boot time later than this one's can't be right
+	received = boot.Add(time.Hour)
+	later := boot.Add(10 * time.Minute)
+	if got, _ = BootTime(ticks(time.Hour), received, later); !got.Equal(boot) {
+		t.Errorf("with a later previous boot: got %v, want %v", got, boot)
+	}
+
+	if _, err = BootTime(-5, received, boot); err == nil {
+		t.Errorf("expected an error for invalid TimeTicks")
+	}
+}
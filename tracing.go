@@ -0,0 +1,81 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"context"
+)
+
+// Tracer receives spans for tracing requests, see GoSNMP.Tracer. There is
+// a span for each request, and a child of it for each attempt at the
+// request, so that retries show up in a trace. Adapting a tracer such as
+// OpenTelemetry's takes a few lines, eg
+//
+//	func (t otelTracer) StartSpan(ctx context.Context, span gosnmp.Span) (context.Context, func(error)) {
+//		ctx, s := t.tracer.Start(ctx, span.Name(), trace.WithAttributes(
+//			attribute.String("snmp.target", span.Target), ...))
+//		return ctx, func(err error) {
+//			if err != nil {
+//				s.RecordError(err)
+//				s.SetStatus(codes.Error, err.Error())
+//			}
+//			s.End()
+//		}
+//	}
+type Tracer interface {
+	// StartSpan starts span as a child of any span in ctx, returning the
+	// context of the new span and a function that ends it with the
+	// outcome, the error the request or attempt failed with or nil.
+	StartSpan(ctx context.Context, span Span) (context.Context, func(err error))
+}
+
+// Span describes a request, or an attempt at one, for a Tracer
+type Span struct {
+	PDUType PDUType
+	Target  string
+	Port    uint16
+	Version SnmpVersion
+	Oids    []string
+
+	// Attempt is 0 for the span of a request, and counts from 1 for its
+	// attempts
+	Attempt int
+
+	// RequestID is the request-id of an attempt, each attempt being sent
+	// with a new one
+	RequestID uint32
+}
+
+// Name returns the span's name, eg "GetRequest" or "GetRequest attempt"
+func (s Span) Name() string {
+	if s.Attempt > 0 {
+		return s.PDUType.String() + " attempt"
+	}
+	return s.PDUType.String()
+}
+
+// startSpan starts the span of packetOut's request, or of its attempt, if
+// x has a Tracer. end must be called with the outcome.
+func (x *GoSNMP) startSpan(ctx context.Context, packetOut *SnmpPacket, attempt int) (_ context.Context, end func(error)) {
+	if x.Tracer == nil {
+		return ctx, func(error) {}
+	}
+	oids := make([]string, len(packetOut.Variables))
+	for i, pdu := range packetOut.Variables {
+		oids[i] = pdu.Name
+	}
+	span := Span{
+		PDUType: packetOut.PDUType,
+		Target:  x.Target,
+		Port:    x.Port,
+		Version: x.Version,
+		Oids:    oids,
+		Attempt: attempt,
+	}
+	if attempt > 0 {
+		span.RequestID = packetOut.RequestID
+	}
+	return x.Tracer.StartSpan(ctx, span)
+}
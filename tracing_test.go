@@ -0,0 +1,95 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+type testSpanKey struct{}
+
+// recordingTracer records spans, with the index of their parent
+type recordingTracer struct {
+	mu      sync.Mutex
+	spans   []Span
+	parents []int
+	errs    []error
+	ended   []bool
+}
+
+func (r *recordingTracer) StartSpan(ctx context.Context, span Span) (context.Context, func(error)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	parent, ok := ctx.Value(testSpanKey{}).(int)
+	if !ok {
+		parent = -1
+	}
+	i := len(r.spans)
+	r.spans = append(r.spans, span)
+	r.parents = append(r.parents, parent)
+	r.errs = append(r.errs, nil)
+	r.ended = append(r.ended, false)
+	return context.WithValue(ctx, testSpanKey{}, i), func(err error) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.errs[i], r.ended[i] = err, true
+	}
+}
+
+func TestTracer(t *testing.T) {
+	const oid = ".1.3.6.1.2.1.1.5.0"
+	r := newTestResponder(t, []SnmpPDU{{Name: oid, Type: OctetString, Value: []byte("name")}})
+	defer r.Close()
+	x := r.client(t)
+	defer x.Conn.Close()
+	tracer := new(recordingTracer)
+	x.Tracer = tracer
+
+	// the first attempt is dropped, the second answered
+	attempts := 0
+	r.setHandler(func(req *SnmpPacket) *SnmpPacket {
+		if attempts++; attempts == 1 {
+			return nil
+		}
+		return r.respond(req)
+	})
+	if _, err := x.Get([]string{oid}); err != nil {
+		t.Fatalf("Get() err: %v", err)
+	}
+
+	tracer.mu.Lock()
+	defer tracer.mu.Unlock()
+	if len(tracer.spans) != 3 {
+		t.Fatalf("got spans %+v, want a request and 2 attempts", tracer.spans)
+	}
+	for i, span := range tracer.spans {
+		if span.PDUType != GetRequest || span.Target != "127.0.0.1" || span.Version != Version2c ||
+			len(span.Oids) != 1 || span.Oids[0] != oid || span.Attempt != i {
+			t.Errorf("span %d is %+v", i, span)
+		}
+		if !tracer.ended[i] {
+			t.Errorf("span %d wasn't ended", i)
+		}
+		parent := 0 // attempts are children of the request
+		if i == 0 {
+			parent = -1
+		}
+		if tracer.parents[i] != parent {
+			t.Errorf("span %d has parent %d, want %d", i, tracer.parents[i], parent)
+		}
+	}
+	if tracer.spans[1].RequestID == tracer.spans[2].RequestID {
+		t.Errorf("attempts have the same request-id %d", tracer.spans[1].RequestID)
+	}
+	if !errors.Is(tracer.errs[1], ErrTimeout) || tracer.errs[0] != nil || tracer.errs[2] != nil {
+		t.Errorf("spans ended with %v, want the first attempt to time out", tracer.errs)
+	}
+	if name := tracer.spans[1].Name(); name != "GetRequest attempt" {
+		t.Errorf("got span name %q", name)
+	}
+}
@@ -5,10 +5,15 @@
 package gosnmp
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"math"
 	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -23,9 +28,12 @@ import (
 // now. This mirrors the behaviour of the Net-SNMP command-line tools.
 //
 // SendTrap doesn't wait for a return packet from the NMS (Network
-// Management Station).
+// Management Station). SNMPv3 traps are authenticated and encrypted as
+// MsgFlags says, with the session as their authoritative engine (RFC 3414
+// section 3.1.1): their engine id, and contextEngineID, is the session's
+// EngineID, and the keys are localized to it.
 //
-// See also Listen() and examples for creating an NMS.
+// See also SendInform, Listen() and examples for creating an NMS.
 func (x *GoSNMP) SendTrap(pdus []SnmpPDU) (result *SnmpPacket, err error) {
 	switch x.Version {
 	case Version2c, Version3:
@@ -35,56 +43,203 @@ func (x *GoSNMP) SendTrap(pdus []SnmpPDU) (result *SnmpPacket, err error) {
 		return nil, err
 	}
 
+	if pdus, err = notificationPDUs("Sendtrap", pdus, x.Logger); err != nil {
+		return nil, err
+	}
+	return x.sendTrap(x.mkSnmpPacket(SNMPv2Trap, pdus, 0, 0))
+}
+
+// sendTrap sends a trap, from the session's own engine if it is SNMPv3
+// (SNMPv1 Trap-PDUs too)
+func (x *GoSNMP) sendTrap(packetOut *SnmpPacket) (*SnmpPacket, error) {
+	if x.Version == Version3 {
+		if err := x.fromLocalEngine(packetOut); err != nil {
+			return nil, err
+		}
+	}
+
+	// all sends wait for the return packet, except for SNMPv2Trap
+	// -> wait is false
+	return x.send(context.Background(), packetOut, false)
+}
+
+// SendInform sends an SNMP InformRequest (v2c/v3 only), a notification
+// whose receiver acknowledges it, returning the receiver's response. pdus
+// are as for SendTrap. The retries and timeouts are those of requests.
+//
+// The authoritative engine of SNMPv3 informs is their receiver, whose
+// engine id is discovered before the first is sent, as for requests.
+func (x *GoSNMP) SendInform(pdus []SnmpPDU) (result *SnmpPacket, err error) {
+	return x.SendInformCtx(context.Background(), pdus)
+}
+
+// SendInformCtx is like SendInform, but the inform is abandoned when ctx
+// is cancelled or its deadline passes, in which case ctx.Err() is
+// returned. If ctx has a deadline, and no RetryPolicy is set for it or
+// for x, the inform is sent again until it is acknowledged or the
+// deadline passes, with jittered waits that double from those of requests
+// up to Timeout.
+//
+// An inform that is answered with a report rather than acknowledged, eg
+// because the receiver doesn't know the SNMPv3 user, fails with an error
+// matching ErrReport; except that a receiver that has restarted with
+// another engine id is sent the inform again, as its report gives the id.
+func (x *GoSNMP) SendInformCtx(ctx context.Context, pdus []SnmpPDU) (result *SnmpPacket, err error) {
+	switch x.Version {
+	case Version2c, Version3:
+	default:
+		return nil, fmt.Errorf("SendInform doesn't support %s", x.Version)
+	}
+
+	if pdus, err = notificationPDUs("SendInform", pdus, x.Logger); err != nil {
+		return nil, err
+	}
+	return x.sendInform(ctx, func() *SnmpPacket { return x.mkInform(pdus) })
+}
+
+// sendInform sends the inform made by mk, see SendInformCtx
+func (x *GoSNMP) sendInform(ctx context.Context, mk func() *SnmpPacket) (result *SnmpPacket, err error) {
+	if policy := x.informRetryPolicy(ctx); policy != nil {
+		ctx = WithRequestOptions(ctx, RequestRetryPolicy(policy))
+	}
+	result, err = x.send(ctx, mk(), true)
+	if err == nil && reportOid(result) == usmStatsUnknownEngineIDs {
+		// the report's engine id has been stored, for the inform sent again
+		x.logInfo("Detected new engine of inform receiver", "target", x.Target)
+		result, err = x.send(ctx, mk(), true)
+	}
+	if err == nil && result.PDUType == Report {
+		return nil, fmt.Errorf("%w: %s", ErrReport, reportOid(result))
+	}
+	return result, err
+}
+
+// mkInform returns an InformRequest of pdus. Its contextEngineID is the
+// session's own engine, the notification's originator (RFC 3413 section
+// 3.2), although its authoritative engine is the receiver.
+func (x *GoSNMP) mkInform(pdus []SnmpPDU) *SnmpPacket {
+	packet := x.mkSnmpPacket(InformRequest, pdus, 0, 0)
+	if x.Version == Version3 {
+		packet.ContextEngineID, _ = x.localEngine()
+	}
+	return packet
+}
+
+// informRetryPolicy returns the RetryPolicy of an inform sent until ctx's
+// deadline, see SendInformCtx; or nil if the inform's is that of requests
+func (x *GoSNMP) informRetryPolicy(ctx context.Context) RetryPolicy {
+	o := optionsFrom(ctx)
+	if _, ok := ctx.Deadline(); !ok || o.retryPolicy != nil ||
+		x.RetryPolicy != nil && o.timeout == nil && o.retries == nil {
+		return nil
+	}
+	policy := x.retryPolicy(ctx).(*ExponentialBackoff)
+	if policy.Initial <= 0 {
+		return nil
+	}
+	policy.Multiplier, policy.Max = 2, policy.MaxElapsed
+	policy.Attempts, policy.MaxElapsed = math.MaxInt32, 0
+	return policy
+}
+
+// reportOid returns the oid of the counter a Report gives, or "" if packet
+// isn't a Report
+func reportOid(packet *SnmpPacket) string {
+	if packet.PDUType != Report || len(packet.Variables) == 0 {
+		return ""
+	}
+	return packet.Variables[0].Name
+}
+
+// notificationPDUs returns the varbinds of a notification: pdus, with a
+// sysUpTime prepended unless pdus[0] is one
+func notificationPDUs(name string, pdus []SnmpPDU, logger Logger) ([]SnmpPDU, error) {
 	if len(pdus) == 0 {
-		return nil, fmt.Errorf("Sendtrap requires at least 1 pdu")
+		return nil, fmt.Errorf("%s requires at least 1 pdu", name)
 	}
 
 	if pdus[0].Type == TimeTicks {
 		// check is uint32
 		if _, ok := pdus[0].Value.(uint32); !ok {
-			return nil, fmt.Errorf("Sendtrap TimeTick must be uint32")
+			return nil, fmt.Errorf("%s TimeTick must be uint32", name)
 		}
+		return pdus, nil
 	}
 
-	// TODO this always prepends a timetickPDU, even if one was supplied (lines 21-23)
 	// add a timetick to start, set to now
 	now := uint32(time.Now().Unix())
-	timetickPDU := SnmpPDU{"1.3.6.1.2.1.1.3.0", TimeTicks, now, x.Logger}
+	timetickPDU := SnmpPDU{Name: "1.3.6.1.2.1.1.3.0", Type: TimeTicks, Value: now, Logger: logger}
 	// prepend timetickPDU
-	pdus = append([]SnmpPDU{timetickPDU}, pdus...)
-
-	packetOut := x.mkSnmpPacket(SNMPv2Trap, pdus, 0, 0)
+	return append([]SnmpPDU{timetickPDU}, pdus...), nil
+}
 
-	// all sends wait for the return packet, except for SNMPv2Trap
-	// -> wait is false
-	return x.send(packetOut, false)
+// fromLocalEngine makes the session's own engine the authoritative engine
+// of an SNMPv3 trap: its security parameters are given the engine's id,
+// boots and time, and keys localized to the id. Traps aren't reportable.
+func (x *GoSNMP) fromLocalEngine(packet *SnmpPacket) error {
+	usm, err := castUsmSecParams(packet.SecurityParameters)
+	if err != nil {
+		return err
+	}
+	engineID, engineTime := x.localEngine()
+	if usm.AuthoritativeEngineID != engineID {
+		// localized to an engine the session has discovered, eg for informs
+		usm.AuthoritativeEngineID = engineID
+		usm.secretKey, usm.privacyKey = nil, nil
+	}
+	usm.localizeKeys()
+	usm.AuthoritativeEngineBoots, usm.AuthoritativeEngineTime = x.EngineBoots, engineTime
+	packet.ContextEngineID = engineID
+	packet.MsgFlags &^= Reportable
+	return nil
 }
 
+// SendV1Trap sends an SNMPv1 Trap-PDU (v1 only), for managers that only
+// accept SNMPv1 traps. enterprise is the type of the object generating the
+// trap, usually the sender's sysObjectID; agentAddress is its IPv4 address,
+// or if "" that of the session's connection; genericTrap is one of
+// ColdStart to EnterpriseSpecific, and specificTrap identifies an
+// EnterpriseSpecific trap; and timestamp is the sender's sysUpTime, in
+// hundredths of a second. pdus may be empty, eg for a coldStart trap.
+//
+// SendV1Trap doesn't wait for a return packet from the NMS. See also
+// SendSnmpV1Trap.
 func (x *GoSNMP) SendV1Trap(pdus []SnmpPDU, enterprise []int, agentAddress string, genericTrap int, specificTrap int, timestamp int) (result *SnmpPacket, err error) {
-	switch x.Version {
-		case Version2c, Version3:
-			err = fmt.Errorf("SendV1Trap doesn't support %s", x.Version)
-			return nil, err
-		default:
-			// do nothing
+	if timestamp < 0 || int64(timestamp) > math.MaxUint32 {
+		return nil, fmt.Errorf("SendV1Trap time-stamp %d is out of range", timestamp)
 	}
+	return x.sendV1Trap(pdus, enterprise, agentAddress, genericTrap, specificTrap, timestamp)
+}
 
-	if len(pdus) == 0 {
-		return nil, fmt.Errorf("SendV1Trap requires at least 1 pdu")
+// sendV1Trap is SendV1Trap, without checking timestamp: SendSnmpV1Trap's
+// comes from a uint32, which on 32 bit platforms may be a negative int but
+// is encoded as the uint32 again
+func (x *GoSNMP) sendV1Trap(pdus []SnmpPDU, enterprise []int, agentAddress string, genericTrap int, specificTrap int, timestamp int) (result *SnmpPacket, err error) {
+	switch x.Version {
+	case Version2c, Version3:
+		err = fmt.Errorf("SendV1Trap doesn't support %s", x.Version)
+		return nil, err
+	default:
+		// do nothing
 	}
 
-	packetOut := x.mkSnmpPacketV1Trap(Trap, enterprise, agentAddress, genericTrap, specificTrap, timestamp, pdus)
+	if genericTrap < ColdStart || genericTrap > EnterpriseSpecific {
+		return nil, fmt.Errorf("SendV1Trap generic-trap %d isn't one of ColdStart to EnterpriseSpecific", genericTrap)
+	}
+	if agentAddress == "" {
+		agentAddress = "0.0.0.0"
+		if addr, ok := x.Conn.LocalAddr().(*net.UDPAddr); ok && addr.IP.To4() != nil {
+			agentAddress = addr.IP.String()
+		}
+	}
 
-	return x.send(packetOut, false)
+	return x.sendTrap(x.mkSnmpPacketV1Trap(Trap, enterprise, agentAddress, genericTrap, specificTrap, timestamp, pdus))
 }
 
-
 //
 // Receiving Traps ie GoSNMP acting as an NMS (Network Management
 // Station).
 //
-// GoSNMP.unmarshal() currently only handles SNMPv2Trap (ie v2c, v3)
-//
 
 // A TrapListener defineds parameters for running a SNMP Trap receiver.
 // nil values will be replaced by default values.
@@ -92,12 +247,68 @@ type TrapListener struct {
 	OnNewTrap func(s *SnmpPacket, u *net.UDPAddr)
 	Params    *GoSNMP
 
+	// OnNewV1Trap, if set, is called for SNMPv1 traps instead of OnNewTrap,
+	// with the fields of their Trap-PDU
+	OnNewV1Trap func(trap *SnmpV1Trap, u *net.UDPAddr)
+
+	// OnTrap, if set, is called for all traps and informs instead of
+	// OnNewTrap and OnNewV1Trap, with how they were received
+	OnTrap func(s *SnmpPacket, info *TrapInfo)
+
+	// Vacm, if set, rejects traps and informs unless their community (or
+	// SNMPv3 user) is in a group whose notify view includes the trap's oid;
+	// the snmpTrapOID, or for SNMPv1 traps the oid of RFC 3584 section 3.1
+	Vacm *Vacm
+
+	// Filter, if set, rejects packets by their source address, community
+	// or SNMPv3 user before they are decoded, counting them in Stats
+	Filter *TrapFilter
+
+	// DedupWindow, if set, is how long received traps are remembered, so
+	// that retransmissions of them are dropped: traps from the same source
+	// address with the same request-id (and SNMPv3 msgID) and varbinds.
+	// Duplicate informs are acknowledged again, but not handled again.
+	DedupWindow time.Duration
+
+	// StrictTimeliness, if set, rejects authenticated SNMPv3 traps that
+	// are outside their sender's time window (RFC 3414 section 3.2), eg
+	// because they are replayed, counting them as AuthErrors. The senders'
+	// times are learned from the first traps received from them.
+	StrictTimeliness bool
+
+	// Workers, if set, is the number of goroutines calling OnNewTrap and
+	// OnNewV1Trap, concurrently, so that slow handlers don't stop traps
+	// being read; otherwise they are called by the read loop. Traps wait
+	// for a worker in a queue of QueueSize (default: Workers). When the
+	// queue is full traps are dropped, and counted in Stats, unless
+	// ParkWhenFull is set: then the read loop waits for room, and traps
+	// wait in the socket's buffer, or are lost if it fills. Dropped informs
+	// aren't acknowledged, so their senders send them again.
+	Workers      int
+	QueueSize    int
+	ParkWhenFull bool
+
 	// these unexported fields are for letting test cases
 	// know we are ready
 	listening bool
 	c         *sync.Cond
 	m         sync.Mutex
-	conn      *net.UDPConn
+	done      chan struct{} // closed when Serve has returned
+
+	// the endpoints' addresses; and the endpoints and tcp connections
+	// being served, closed by Close
+	addrs   []net.Addr
+	closers map[io.Closer]bool
+
+	stats   *counters    // see Stats
+	filter  *trapFilter  // Filter, compiled by Serve
+	history *trapHistory // for DedupWindow and StrictTimeliness
+
+	// the listener's engine, for SNMPv3 informs; and its usmStats
+	// counters, in the order of their oids
+	engine *GoSNMP
+	unsupportedSecLevels, notInTimeWindows, unknownUserNames,
+	unknownEngineIDs, wrongDigests, decryptionErrors uint32
 }
 
 // optional constructor for TrapListener
@@ -114,28 +325,55 @@ func (t *TrapListener) ready() bool {
 	return t.listening
 }
 
-// Close terminates the listening on TrapListener socket
+// Close terminates the listening on TrapListener socket. The handlers of
+// traps already received may still be running; see Shutdown.
 func (t *TrapListener) Close() {
 	t.m.Lock()
 	defer t.m.Unlock()
 	t.listening = false
 	t.c.Broadcast()
-	t.conn.Close()
-	t.conn = nil
+	for c := range t.closers {
+		c.Close()
+	}
+	t.closers = nil
 }
 
-// Listen listens on the UDP address addr and calls the OnNewTrap
-// function specified in *TrapListener for every trap recieved.
-func (t *TrapListener) Listen(addr string) (err error) {
-	if t.Params == nil {
-		t.Params = Default
+// Shutdown stops the listener receiving packets, as Close does, then waits
+// for the handlers of the traps it has received, including those queued
+// for its Workers, to return. If ctx is done first, ctx.Err() is returned
+// and the handlers are left to finish.
+func (t *TrapListener) Shutdown(ctx context.Context) error {
+	t.m.Lock()
+	done := t.done
+	t.m.Unlock()
+	t.Close()
+	if done == nil {
+		return nil
 	}
-	t.Params.validateParameters()
-
-	if t.OnNewTrap == nil {
-		t.OnNewTrap = debugTrapHandler
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
+}
 
+// Listen listens on the UDP address addr and calls the OnNewTrap
+// function specified in *TrapListener for every trap recieved.
+//
+// Informs are acknowledged with a response once they are accepted: before
+// OnNewTrap is called, or with Workers once they are queued.
+// The authoritative engine of SNMPv3 informs is the listener, whose
+// engine is that of Params (see GoSNMP.EngineID) and whose user is
+// Params' SecurityParameters: senders discover it, and informs from other
+// users, not authenticated and encrypted as Params' MsgFlags say, or not
+// in its time window are answered with reports, as an agent answers
+// requests. SNMPv3 traps are authenticated with keys localized to their
+// sender's engine.
+//
+// To listen on several addresses, eg IPv4 and IPv6 ones, or over tcp, see
+// ListenAll.
+func (t *TrapListener) Listen(addr string) (err error) {
 	udpAddr, err := net.ResolveUDPAddr("udp", addr)
 	if err != nil {
 		return err
@@ -145,13 +383,66 @@ func (t *TrapListener) Listen(addr string) (err error) {
 	if err != nil {
 		return err
 	}
-	t.conn = conn
-	defer conn.Close()
+	return t.Serve(context.Background(), conn)
+}
+
+// Serve is like Listen, but receives traps on conn, which it closes, until
+// ctx is done or the listener is closed or shut down. It returns once the
+// handlers of the traps received have returned: with ctx.Err() if ctx is
+// done, and otherwise nil.
+func (t *TrapListener) Serve(ctx context.Context, conn *net.UDPConn) error {
+	return t.ServeAll(ctx, []*net.UDPConn{conn}, nil)
+}
+
+// ServeAll is like Serve, but receives traps on all of conns, and on the
+// tcp connections accepted from listeners (see RFC 3430), which it closes.
+// Traps from all of them are handled by the same handlers, Workers and so
+// on, and the responses to informs are sent on the connection they were
+// received on.
+func (t *TrapListener) ServeAll(ctx context.Context, conns []*net.UDPConn, listeners []net.Listener) (err error) {
+	defer func() {
+		for _, conn := range conns {
+			conn.Close()
+		}
+		for _, l := range listeners {
+			l.Close()
+		}
+	}()
+	if len(conns) == 0 && len(listeners) == 0 {
+		return fmt.Errorf("TrapListener has no connections to serve")
+	}
+	if t.Params == nil {
+		t.Params = Default
+	}
+	t.Params.validateParameters()
+	if err = t.initEngine(); err != nil {
+		return err
+	}
+	if t.filter, err = t.Filter.compile(); err != nil {
+		return err
+	}
+
+	if t.OnNewTrap == nil {
+		t.OnNewTrap = debugTrapHandler
+	}
+	t.history = newTrapHistory()
 
 	// mark that we are listening now
+	done := make(chan struct{})
+	defer close(done)
 	func() {
 		t.m.Lock()
 		defer t.m.Unlock()
+		t.addrs, t.closers = nil, make(map[io.Closer]bool)
+		for _, conn := range conns {
+			t.addrs = append(t.addrs, conn.LocalAddr())
+			t.closers[conn] = true
+		}
+		for _, l := range listeners {
+			t.addrs = append(t.addrs, l.Addr())
+			t.closers[l] = true
+		}
+		t.done = done
 		t.listening = true
 		t.c.Broadcast()
 	}()
@@ -164,21 +455,148 @@ func (t *TrapListener) Listen(addr string) (err error) {
 		t.c.Broadcast()
 	}()
 
-	for {
-		var buf [4096]byte
-		rlen, remote, err := conn.ReadFromUDP(buf[:])
-		if err != nil {
-			t.Params.logPrintf("TrapListener: error in read %s\n", err)
+	// a done ctx closes the listener, ending the read loop
+	stopped := make(chan struct{})
+	defer close(stopped)
+	go func() {
+		select {
+		case <-ctx.Done():
+			t.Close()
+		case <-stopped:
 		}
+	}()
+
+	queue, stopWorkers := t.startWorkers()
+	defer stopWorkers()
+
+	messages := t.startReaders(conns, listeners)
+	for m := range messages {
+		t.process(m, queue)
+	}
+	return ctx.Err()
+}
 
-		msg := buf[:rlen]
-		traps := t.Params.UnmarshalTrap(msg)
-		if traps != nil {
-			t.OnNewTrap(traps, remote)
+// process processes a message received by one of the listener's
+// endpoints: decoding, checking and handling its trap (or queuing it for
+// the Workers), and acknowledging it if it is an inform
+func (t *TrapListener) process(m trapMessage, queue chan queuedTrap) {
+	conn, remote, msg, received := m.conn, m.remote, m.msg, m.received
+	stats := t.counters()
+	atomic.AddUint64(&stats.received, 1)
+	capture := t.Params.Capture
+	if capture != nil {
+		capture.datagram(remote, conn.LocalAddr(), msg)
+	}
+	var traps *SnmpPacket
+	var err error
+	if t.filter.allowsSource(remote) {
+		traps, err = t.receive(conn, remote, msg)
+	} else {
+		err = errRejected
+	}
+	switch {
+	case err == errRejected:
+		atomic.AddUint64(&stats.rejected, 1)
+		t.Params.logInfo("Dropping filtered packet", "addr", remote)
+	case err == errDiscovery:
+	case errors.Is(err, ErrAuthentication):
+		atomic.AddUint64(&stats.authErrors, 1)
+	case err != nil:
+		atomic.AddUint64(&stats.decodeErrors, 1)
+	case t.StrictTimeliness && traps.Version == Version3 && traps.MsgFlags&AuthNoPriv > 0 &&
+		traps.PDUType != InformRequest && !t.history.timely(traps, received):
+		atomic.AddUint64(&stats.authErrors, 1)
+		t.Params.logWarn("Dropping trap outside its sender's time window", "addr", remote)
+	case t.Vacm != nil && !t.Vacm.allowsNotification(traps):
+		atomic.AddUint64(&stats.accessDenied, 1)
+		t.Params.logInfo("Dropping unauthorized trap", "addr", remote)
+	case t.DedupWindow > 0 && t.history.duplicate(traps, remote, received, t.DedupWindow):
+		atomic.AddUint64(&stats.duplicates, 1)
+		t.Params.logInfo("Dropping duplicate trap", "addr", remote)
+		if traps.PDUType == InformRequest {
+			// its first acknowledgement may have been lost
+			t.acknowledge(conn, remote, traps)
+		}
+	default:
+		if capture != nil {
+			capture.plaintext(remote, conn.LocalAddr(), traps)
+		}
+		info := newTrapInfo(traps, conn.LocalAddr(), remote, len(msg), received)
+		if queue != nil && !t.enqueue(queue, traps, info) {
+			atomic.AddUint64(&stats.dropped, 1)
+			t.Params.logWarn("Dropping trap, the queue is full", "addr", remote)
+			break
+		}
+		if traps.PDUType == InformRequest {
+			t.acknowledge(conn, remote, traps)
+		}
+		if queue == nil {
+			t.handle(traps, info)
 		}
 	}
 }
 
+// queuedTrap is a trap waiting for one of a listener's Workers
+type queuedTrap struct {
+	packet *SnmpPacket
+	info   *TrapInfo
+}
+
+// startWorkers starts the listener's Workers, returning their queue (nil
+// if there are none) and a function stopping them once it has drained
+func (t *TrapListener) startWorkers() (chan queuedTrap, func()) {
+	if t.Workers <= 0 {
+		return nil, func() {}
+	}
+	size := t.QueueSize
+	if size <= 0 {
+		size = t.Workers
+	}
+	queue := make(chan queuedTrap, size)
+	var wg sync.WaitGroup
+	for i := 0; i < t.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for q := range queue {
+				t.handle(q.packet, q.info)
+			}
+		}()
+	}
+	return queue, func() {
+		close(queue)
+		wg.Wait()
+	}
+}
+
+// enqueue queues a trap for the Workers, reporting false if it was dropped
+// because the queue is full
+func (t *TrapListener) enqueue(queue chan queuedTrap, packet *SnmpPacket, info *TrapInfo) bool {
+	if t.ParkWhenFull {
+		queue <- queuedTrap{packet, info}
+		return true
+	}
+	select {
+	case queue <- queuedTrap{packet, info}:
+		return true
+	default:
+		return false
+	}
+}
+
+// handle calls the handler of a trap
+func (t *TrapListener) handle(packet *SnmpPacket, info *TrapInfo) {
+	if t.OnTrap != nil {
+		t.OnTrap(packet, info)
+		return
+	}
+	if v1 := packet.V1Trap(); v1 != nil && t.OnNewV1Trap != nil {
+		t.OnNewV1Trap(v1, info.Remote)
+		return
+	}
+	t.OnNewTrap(packet, info.Remote)
+}
+
 // Default trap handler
 func debugTrapHandler(s *SnmpPacket, u *net.UDPAddr) {
 	log.Printf("got trapdata from %+v: %+v\n", u, s)
@@ -186,6 +604,13 @@ func debugTrapHandler(s *SnmpPacket, u *net.UDPAddr) {
 
 // Unmarshal SNMP Trap
 func (x *GoSNMP) UnmarshalTrap(trap []byte) (result *SnmpPacket) {
+	result, _ = x.unmarshalTrap(trap)
+	return result
+}
+
+// unmarshalTrap is UnmarshalTrap, returning why a trap couldn't be
+// unmarshalled
+func (x *GoSNMP) unmarshalTrap(trap []byte) (result *SnmpPacket, err error) {
 	result = new(SnmpPacket)
 
 	if x.SecurityParameters != nil {
@@ -194,24 +619,45 @@ func (x *GoSNMP) UnmarshalTrap(trap []byte) (result *SnmpPacket) {
 
 	cursor, err := x.unmarshalHeader(trap, result)
 	if err != nil {
-		x.logPrintf("UnmarshalTrap: %s\n", err)
-		return nil
+		x.logWarn("Unable to decode trap header", "err", err)
+		return nil, fmt.Errorf("%w: %v", ErrDecode, err)
 	}
 
 	if result.Version == Version3 {
-		if result.SecurityModel == UserSecurityModel {
-			err = x.testAuthentication(trap, result)
+		// traps of another security model than the listener's have no
+		// credentials to be checked with
+		if x.SecurityParameters != nil && result.SecurityModel != x.SecurityParameters.securityModel() {
+			err = fmt.Errorf("%w: trap of security model %d, discarding", ErrAuthentication, result.SecurityModel)
+			x.logWarn("Trap failed authentication", "err", err)
+			return nil, err
+		}
+		// with the keys localized to the trap's engine, its sender's
+		if usm, ok := result.SecurityParameters.(*UsmSecurityParameters); ok {
+			usm.localizeKeys()
+		}
+		// traps at a lower level than the listener's would go
+		// unauthenticated or be read unencrypted
+		if level := result.MsgFlags & AuthPriv; level < x.MsgFlags&AuthPriv {
+			err = fmt.Errorf("%w: trap of security level %s, discarding", ErrAuthentication, level)
+			x.logWarn("Trap failed authentication", "err", err)
+			return nil, err
+		}
+		if result.MsgFlags&AuthNoPriv > 0 {
+			authentic, err := result.SecurityParameters.isAuthentic(trap, result)
+			if err == nil && !authentic {
+				err = fmt.Errorf("%w, discarding", ErrAuthentication)
+			}
 			if err != nil {
-				x.logPrintf("UnmarshalTrap v3 auth: %s\n", err)
-				return nil
+				x.logWarn("Trap failed authentication", "err", err)
+				return nil, err
 			}
 		}
 		trap, cursor, err = x.decryptPacket(trap, cursor, result)
 	}
 	err = x.unmarshalPayload(trap, cursor, result)
 	if err != nil {
-		x.logPrintf("UnmarshalTrap: %s\n", err)
-		return nil
+		x.logWarn("Unable to decode trap", "err", err)
+		return nil, fmt.Errorf("%w: %v", ErrDecode, err)
 	}
-	return result
+	return result, nil
 }
@@ -0,0 +1,113 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"net"
+	"time"
+)
+
+//
+// Duplicate and replayed traps
+//
+
+// trapHistory is what a listener remembers of the traps it has received,
+// to drop duplicates and check timeliness. It is only used by the read
+// loop.
+type trapHistory struct {
+	seen    map[trapKey]time.Time // when each trap was first received
+	pruned  time.Time             // when expired traps were last removed
+	engines map[string]*engineClock
+}
+
+// trapKey identifies a trap, and its retransmissions
+type trapKey struct {
+	source           string
+	requestID, msgID uint32
+	hash             uint64 // of the varbinds, see hashTrap
+}
+
+// engineClock is the time of a sending engine, as RFC 3414 section 2.3
+// says a non-authoritative engine keeps it
+type engineClock struct {
+	boots, time uint32    // the engine's estimated time at at
+	at          time.Time // when the time was learned
+	latest      uint32    // the latest time received
+}
+
+func newTrapHistory() *trapHistory {
+	return &trapHistory{
+		seen:    make(map[trapKey]time.Time),
+		engines: make(map[string]*engineClock),
+	}
+}
+
+// duplicate reports whether packet, received from remote at now, is a
+// duplicate of a trap received in the window before it
+func (h *trapHistory) duplicate(packet *SnmpPacket, remote *net.UDPAddr, now time.Time, window time.Duration) bool {
+	if now.Sub(h.pruned) > window {
+		for key, at := range h.seen {
+			if now.Sub(at) > window {
+				delete(h.seen, key)
+			}
+		}
+		h.pruned = now
+	}
+	key := trapKey{remote.String(), packet.RequestID, packet.MsgID, hashTrap(packet)}
+	if at, ok := h.seen[key]; ok && now.Sub(at) <= window {
+		return true
+	}
+	h.seen[key] = now
+	return false
+}
+
+// hashTrap returns a hash of the contents of a trap: its varbinds, and
+// the fields of an SNMPv1 Trap-PDU
+func hashTrap(packet *SnmpPacket) uint64 {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%s|%x|", packet.PDUType, packet.Community)
+	if trap := packet.V1Trap(); trap != nil {
+		fmt.Fprintf(h, "%s|%s|%d|%d|%d|", trap.Enterprise, trap.AgentAddress, trap.GenericTrap, trap.SpecificTrap, trap.Timestamp)
+	}
+	for _, pdu := range packet.Variables {
+		fmt.Fprintf(h, "%s|%d|%v|", pdu.Name, pdu.Type, pdu.Value)
+	}
+	return h.Sum64()
+}
+
+// timely reports whether an authenticated SNMPv3 trap, received at now, is
+// in the time window of its sender's engine, as RFC 3414 section 3.2 step
+// 7b says: its boots are those last received from the engine (or later),
+// and its time no more than 150 seconds before the engine's estimated
+// time. The engine's time is learned from the first trap received from it.
+func (h *trapHistory) timely(packet *SnmpPacket, now time.Time) bool {
+	usm, ok := packet.SecurityParameters.(*UsmSecurityParameters)
+	if !ok {
+		return false
+	}
+	boots, engineTime := usm.AuthoritativeEngineBoots, usm.AuthoritativeEngineTime
+	if boots >= math.MaxInt32 {
+		return false
+	}
+	clock := h.engines[usm.AuthoritativeEngineID]
+	switch {
+	case clock == nil || boots > clock.boots:
+		h.engines[usm.AuthoritativeEngineID] = &engineClock{boots: boots, time: engineTime, at: now, latest: engineTime}
+		return true
+	case boots < clock.boots:
+		return false
+	}
+	estimated := clock.time + uint32(now.Sub(clock.at)/time.Second)
+	if uint64(engineTime)+agentTimeWindow < uint64(estimated) {
+		return false
+	}
+	if engineTime > clock.latest {
+		clock.time, clock.at, clock.latest = engineTime, now, engineTime
+	}
+	return true
+}
@@ -0,0 +1,148 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestTrapListenerDedup(t *testing.T) {
+	tl := NewTrapListener()
+	received := make(chan *SnmpPacket, 8)
+	tl.OnNewTrap = func(s *SnmpPacket, u *net.UDPAddr) { received <- s }
+	tl.Params = &GoSNMP{}
+	tl.DedupWindow = time.Minute
+	addr := listenTestTraps(t, tl)
+	defer tl.Close()
+
+	conn, err := net.DialUDP("udp4", nil, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	encode := func(pduType PDUType, requestID uint32, value string) []byte {
+		out, err := (&GoSNMP{}).Encode(&SnmpPacket{
+			Version:   Version2c,
+			Community: "public",
+			PDUType:   pduType,
+			RequestID: requestID,
+			Variables: []SnmpPDU{{Name: trapTestOid, Type: OctetString, Value: value}},
+		})
+		if err != nil {
+			t.Fatalf("Encode() err: %v", err)
+		}
+		return out
+	}
+	for i, test := range []struct {
+		msg []byte
+		ok  bool
+	}{
+		{encode(SNMPv2Trap, 1, trapTestPayload), true},
+		{encode(SNMPv2Trap, 1, trapTestPayload), false},
+		{encode(SNMPv2Trap, 2, trapTestPayload), true},
+		{encode(SNMPv2Trap, 2, "other"), true},
+		{encode(InformRequest, 3, trapTestPayload), true},
+		{encode(InformRequest, 3, trapTestPayload), false},
+	} {
+		if _, err = conn.Write(test.msg); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case trap := <-received:
+			if !test.ok {
+				t.Errorf("%d: received duplicate %s", i, trap)
+			}
+		case <-time.After(time.Second):
+			if test.ok {
+				t.Fatalf("%d: timed out waiting for the trap", i)
+			}
+		}
+	}
+
+	// both informs are acknowledged
+	for i := 0; i < 2; i++ {
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		var buf [4096]byte
+		n, err := conn.Read(buf[:])
+		if err != nil {
+			t.Fatalf("reading acknowledgement %d err: %v", i, err)
+		}
+		if response, err := (&GoSNMP{}).Decode(buf[:n]); err != nil || response.PDUType != GetResponse || response.RequestID != 3 {
+			t.Errorf("acknowledgement %d is %v, err %v", i, response, err)
+		}
+	}
+	if stats := tl.Stats(); stats.Duplicates != 2 {
+		t.Errorf("Duplicates is %d, expected 2", stats.Duplicates)
+	}
+}
+
+func TestTrapListenerStrictTimeliness(t *testing.T) {
+	user := &UsmSecurityParameters{
+		UserName:                 "user",
+		AuthenticationProtocol:   SHA,
+		AuthenticationPassphrase: "authpassphrase",
+	}
+	tl := NewTrapListener()
+	received := make(chan *SnmpPacket, 4)
+	tl.OnNewTrap = func(s *SnmpPacket, u *net.UDPAddr) { received <- s }
+	tl.Params = &GoSNMP{
+		Version:            Version3,
+		MsgFlags:           AuthNoPriv,
+		SecurityModel:      UserSecurityModel,
+		SecurityParameters: user.Copy(),
+	}
+	tl.StrictTimeliness = true
+	addr := listenTestTraps(t, tl)
+	defer tl.Close()
+
+	engineID := "\x80\x00\x00\x00\x05sender"
+	for i, test := range []struct {
+		boots uint32
+		age   time.Duration // of the sender's engine
+		ok    bool
+	}{
+		{3, 1000 * time.Second, true},
+		{3, 1010 * time.Second, true},
+		{3, 0, false}, // eg replayed: more than 150 seconds before
+		{2, 2000 * time.Second, false},
+		{4, 0, true}, // rebooted
+	} {
+		x := &GoSNMP{
+			Target:             "127.0.0.1",
+			Port:               uint16(addr.Port),
+			Version:            Version3,
+			MsgFlags:           AuthNoPriv,
+			SecurityModel:      UserSecurityModel,
+			SecurityParameters: user.Copy(),
+			EngineBoots:        test.boots,
+			Timeout:            time.Second,
+			MaxOids:            MaxOids,
+		}
+		x.localEngineID, x.localStart = engineID, time.Now().Add(-test.age)
+		if err := x.Connect(); err != nil {
+			t.Fatalf("Connect() err: %v", err)
+		}
+		_, err := x.SendTrap([]SnmpPDU{{Name: trapTestOid, Type: OctetString, Value: trapTestPayload}})
+		x.Conn.Close()
+		if err != nil {
+			t.Fatalf("SendTrap() err: %v", err)
+		}
+		select {
+		case trap := <-received:
+			if !test.ok {
+				t.Errorf("%d: received %s", i, trap)
+			}
+		case <-time.After(time.Second):
+			if test.ok {
+				t.Fatalf("%d: timed out waiting for the trap", i)
+			}
+		}
+	}
+	if stats := tl.Stats(); stats.AuthErrors != 2 {
+		t.Errorf("AuthErrors is %d, expected 2", stats.AuthErrors)
+	}
+}
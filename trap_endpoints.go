@@ -0,0 +1,214 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+//
+// Receiving traps on several endpoints: udp sockets, eg for IPv4 and
+// IPv6, and tcp listeners
+//
+
+// TrapEndpoint is an address for a TrapListener to listen on
+type TrapEndpoint struct {
+	// Network is "udp" or "tcp" (or "udp4", "tcp6" etc)
+	Network string
+	Address string
+}
+
+// ListenAll listens on all of endpoints, and calls the listener's handlers
+// for the traps received on any of them, as ServeAll does. It fails if any
+// of them can't be listened on.
+func (t *TrapListener) ListenAll(endpoints ...TrapEndpoint) error {
+	var conns []*net.UDPConn
+	var listeners []net.Listener
+	closeAll := func() {
+		for _, conn := range conns {
+			conn.Close()
+		}
+		for _, l := range listeners {
+			l.Close()
+		}
+	}
+	for _, endpoint := range endpoints {
+		switch endpoint.Network {
+		case "udp", "udp4", "udp6":
+			addr, err := net.ResolveUDPAddr(endpoint.Network, endpoint.Address)
+			if err != nil {
+				closeAll()
+				return err
+			}
+			conn, err := net.ListenUDP(endpoint.Network, addr)
+			if err != nil {
+				closeAll()
+				return err
+			}
+			conns = append(conns, conn)
+		case "tcp", "tcp4", "tcp6":
+			l, err := net.Listen(endpoint.Network, endpoint.Address)
+			if err != nil {
+				closeAll()
+				return err
+			}
+			listeners = append(listeners, l)
+		default:
+			closeAll()
+			return fmt.Errorf("Unsupported network %q", endpoint.Network)
+		}
+	}
+	return t.ServeAll(context.Background(), conns, listeners)
+}
+
+// Addrs returns the addresses the listener is listening on, eg to find the
+// ports of endpoints whose port is 0
+func (t *TrapListener) Addrs() []net.Addr {
+	t.m.Lock()
+	defer t.m.Unlock()
+	return append([]net.Addr(nil), t.addrs...)
+}
+
+// trapConn is a connection a listener receives messages on, and replies to
+// their senders on
+type trapConn interface {
+	LocalAddr() net.Addr
+	reply(msg []byte, remote *net.UDPAddr) error
+}
+
+type udpTrapConn struct{ *net.UDPConn }
+
+func (c udpTrapConn) reply(msg []byte, remote *net.UDPAddr) error {
+	_, err := c.WriteToUDP(msg, remote)
+	return err
+}
+
+// streamTrapConn is a tcp connection, whose replies are to its peer
+type streamTrapConn struct{ net.Conn }
+
+func (c streamTrapConn) reply(msg []byte, remote *net.UDPAddr) error {
+	_, err := c.Write(msg)
+	return err
+}
+
+// trapMessage is a message received by one of a listener's endpoints
+type trapMessage struct {
+	msg      []byte
+	conn     trapConn
+	remote   *net.UDPAddr
+	received time.Time
+}
+
+// startReaders starts reading messages from conns, and the connections
+// accepted from listeners, returning the channel they are sent on, which
+// is closed once the listener is closed and they have all stopped
+func (t *TrapListener) startReaders(conns []*net.UDPConn, listeners []net.Listener) chan trapMessage {
+	messages := make(chan trapMessage)
+	var readers sync.WaitGroup
+	for _, conn := range conns {
+		readers.Add(1)
+		go func(conn *net.UDPConn) {
+			defer readers.Done()
+			t.readPackets(conn, messages)
+		}(conn)
+	}
+	for _, l := range listeners {
+		readers.Add(1)
+		go func(l net.Listener) {
+			defer readers.Done()
+			t.accept(l, messages, &readers)
+		}(l)
+	}
+	go func() {
+		readers.Wait()
+		close(messages)
+	}()
+	return messages
+}
+
+// readPackets sends the datagrams read from conn to messages, until the
+// listener is closed
+func (t *TrapListener) readPackets(conn *net.UDPConn, messages chan trapMessage) {
+	for {
+		var buf [4096]byte
+		rlen, remote, err := conn.ReadFromUDP(buf[:])
+		received := time.Now()
+		if err != nil {
+			if !t.ready() {
+				// closed
+				return
+			}
+			t.Params.logError("TrapListener read failed", "err", err)
+			continue
+		}
+		messages <- trapMessage{append([]byte(nil), buf[:rlen]...), udpTrapConn{conn}, remote, received}
+	}
+}
+
+// accept reads the messages on the tcp connections accepted from l, until
+// the listener is closed or accepting fails
+func (t *TrapListener) accept(l net.Listener, messages chan trapMessage, readers *sync.WaitGroup) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if t.ready() {
+				t.Params.logError("TrapListener accept failed", "addr", l.Addr(), "err", err)
+			}
+			return
+		}
+		if !t.track(conn) {
+			return
+		}
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			defer t.untrack(conn)
+			t.readStream(conn, messages)
+		}()
+	}
+}
+
+// readStream sends the messages read from a tcp connection, each a BER
+// encoded message, to messages until it is closed
+func (t *TrapListener) readStream(conn net.Conn, messages chan trapMessage) {
+	remote := udpAddr(conn.RemoteAddr())
+	for {
+		msg, err := readStreamMessage(conn)
+		received := time.Now()
+		if err != nil {
+			if err != io.EOF && t.ready() {
+				t.Params.logWarn("Unable to read trap", "addr", remote, "err", err)
+			}
+			return
+		}
+		messages <- trapMessage{msg, streamTrapConn{conn}, remote, received}
+	}
+}
+
+// track adds c to the connections closed by Close, returning false (having
+// closed it) if the listener is closed already
+func (t *TrapListener) track(c io.Closer) bool {
+	t.m.Lock()
+	defer t.m.Unlock()
+	if !t.listening {
+		c.Close()
+		return false
+	}
+	t.closers[c] = true
+	return true
+}
+
+// untrack closes c, and removes it from the connections closed by Close
+func (t *TrapListener) untrack(c io.Closer) {
+	t.m.Lock()
+	defer t.m.Unlock()
+	c.Close()
+	delete(t.closers, c)
+}
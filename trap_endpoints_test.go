@@ -0,0 +1,107 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestTrapListenerListenAll(t *testing.T) {
+	tl := NewTrapListener()
+	received := make(chan *TrapInfo, 4)
+	tl.OnTrap = func(s *SnmpPacket, info *TrapInfo) { received <- info }
+	tl.Params = &GoSNMP{}
+	done := make(chan error, 1)
+	go func() {
+		done <- tl.ListenAll(TrapEndpoint{"udp4", "127.0.0.1:0"}, TrapEndpoint{"tcp4", "127.0.0.1:0"})
+	}()
+	tl.c.L.Lock()
+	for !tl.ready() {
+		tl.c.Wait()
+	}
+	tl.c.L.Unlock()
+	addrs := tl.Addrs()
+	if len(addrs) != 2 {
+		t.Fatalf("Addrs() got %v", addrs)
+	}
+
+	receive := func(transport string) {
+		t.Helper()
+		select {
+		case info := <-received:
+			if info.Transport != transport || !info.Remote.IP.IsLoopback() {
+				t.Errorf("OnTrap() got %+v, expected a trap over %s", info, transport)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for the trap over %s", transport)
+		}
+	}
+	x := &GoSNMP{
+		Target:    "127.0.0.1",
+		Port:      uint16(addrs[0].(*net.UDPAddr).Port),
+		Community: "public",
+		Version:   Version2c,
+		Timeout:   time.Second,
+		MaxOids:   MaxOids,
+	}
+	if err := x.Connect(); err != nil {
+		t.Fatalf("Connect() err: %v", err)
+	}
+	_, err := x.SendTrap([]SnmpPDU{{Name: trapTestOid, Type: OctetString, Value: trapTestPayload}})
+	x.Conn.Close()
+	if err != nil {
+		t.Fatalf("SendTrap() err: %v", err)
+	}
+	receive("udp")
+
+	// informs over tcp are acknowledged on their connection
+	conn, err := net.Dial("tcp4", addrs[1].String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	out, err := (&GoSNMP{}).Encode(&SnmpPacket{
+		Version:   Version2c,
+		Community: "public",
+		PDUType:   InformRequest,
+		RequestID: 7,
+		Variables: []SnmpPDU{{Name: trapTestOid, Type: OctetString, Value: trapTestPayload}},
+	})
+	if err != nil {
+		t.Fatalf("Encode() err: %v", err)
+	}
+	if _, err = conn.Write(out); err != nil {
+		t.Fatal(err)
+	}
+	receive("tcp")
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	msg, err := readStreamMessage(conn)
+	if err != nil {
+		t.Fatalf("reading the acknowledgement err: %v", err)
+	}
+	if response, err := (&GoSNMP{}).Decode(msg); err != nil || response.PDUType != GetResponse || response.RequestID != 7 {
+		t.Errorf("acknowledgement is %v, err %v", response, err)
+	}
+
+	// shutting down closes the tcp connection too
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err = tl.Shutdown(ctx); err != nil {
+		t.Errorf("Shutdown() err: %v", err)
+	}
+	if err = <-done; err != nil {
+		t.Errorf("ListenAll() err: %v", err)
+	}
+	if _, err = readStreamMessage(conn); err == nil {
+		t.Error("tcp connection is open after Shutdown")
+	}
+
+	if err = NewTrapListener().ListenAll(TrapEndpoint{"ip4", "127.0.0.1"}); err == nil {
+		t.Error("ListenAll() of an unsupported network succeeded")
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+//
+// Filtering the packets a TrapListener receives, before they are decoded
+//
+
+// errRejected is the error of a packet that a listener's Filter rejected
+var errRejected = errors.New("rejected by filter")
+
+// TrapFilter is the access control list of a TrapListener, which rejects
+// packets by their source address, community or SNMPv3 user before their
+// PDU is decoded (or for SNMPv3, authenticated and decrypted), so that
+// unwanted senders cost little.
+//
+// A packet is rejected if it matches any of the Deny lists, or if an Allow
+// list is set and it doesn't match it. Sources are IP addresses or networks
+// in CIDR notation, eg "192.0.2.1" or "2001:db8::/32". Communities are
+// those of SNMPv1 and SNMPv2c packets, and Users the user names of SNMPv3
+// packets. A TrapFilter must not be changed once the listener is
+// listening.
+type TrapFilter struct {
+	AllowSources, DenySources         []string
+	AllowCommunities, DenyCommunities []string
+	AllowUsers, DenyUsers             []string
+
+	// Func, if set, is also called for packets the lists accept, with the
+	// packet's header: its version, and community or SNMPv3 message
+	// flags, security parameters and so on, but not its PDU. Packets it
+	// returns false for are rejected.
+	Func func(addr *net.UDPAddr, header *SnmpPacket) bool
+}
+
+// trapFilter is a TrapFilter, with its sources parsed
+type trapFilter struct {
+	*TrapFilter
+	allow, deny []*net.IPNet
+}
+
+// compile returns the filter, with its sources parsed; or nil if f is nil
+func (f *TrapFilter) compile() (*trapFilter, error) {
+	if f == nil {
+		return nil, nil
+	}
+	filter := &trapFilter{TrapFilter: f}
+	var err error
+	if filter.allow, err = parseSources(f.AllowSources); err != nil {
+		return nil, err
+	}
+	if filter.deny, err = parseSources(f.DenySources); err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
+
+// parseSources parses addresses and networks in CIDR notation
+func parseSources(sources []string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, source := range sources {
+		if !strings.Contains(source, "/") {
+			ip := net.ParseIP(source)
+			if ip == nil {
+				return nil, fmt.Errorf("TrapFilter source %q isn't an IP address or network", source)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(source)
+		if err != nil {
+			return nil, fmt.Errorf("TrapFilter source %q isn't an IP address or network: %w", source, err)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// allowsSource reports whether packets from addr may be accepted
+func (f *trapFilter) allowsSource(addr *net.UDPAddr) bool {
+	if f == nil {
+		return true
+	}
+	if containsIP(f.deny, addr.IP) {
+		return false
+	}
+	return len(f.AllowSources) == 0 || containsIP(f.allow, addr.IP)
+}
+
+func containsIP(networks []*net.IPNet, ip net.IP) bool {
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// allows reports whether a packet from addr, whose header has been
+// decoded, is accepted
+func (f *trapFilter) allows(addr *net.UDPAddr, header *SnmpPacket) bool {
+	if f == nil {
+		return true
+	}
+	name, allow, deny := header.Community, f.AllowCommunities, f.DenyCommunities
+	if header.Version == Version3 {
+		name, allow, deny = "", f.AllowUsers, f.DenyUsers
+		if usm, ok := header.SecurityParameters.(*UsmSecurityParameters); ok {
+			name = usm.UserName
+		}
+	}
+	if containsString(deny, name) || len(allow) > 0 && !containsString(allow, name) {
+		return false
+	}
+	return f.Func == nil || f.Func(addr, header)
+}
+
+func containsString(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestTrapFilter(t *testing.T) {
+	v2c := func(community string) *SnmpPacket {
+		return &SnmpPacket{Version: Version2c, Community: community}
+	}
+	v3 := func(user string) *SnmpPacket {
+		return &SnmpPacket{Version: Version3, SecurityParameters: &UsmSecurityParameters{UserName: user}}
+	}
+	tests := []struct {
+		filter TrapFilter
+		source string
+		header *SnmpPacket
+		ok     bool
+	}{
+		{TrapFilter{}, "192.0.2.1", v2c("public"), true},
+		{TrapFilter{AllowSources: []string{"192.0.2.0/24"}}, "192.0.2.1", v2c("public"), true},
+		{TrapFilter{AllowSources: []string{"192.0.2.0/24"}}, "198.51.100.1", v2c("public"), false},
+		{TrapFilter{AllowSources: []string{"192.0.2.0/24"}, DenySources: []string{"192.0.2.1"}}, "192.0.2.1", v2c("public"), false},
+		{TrapFilter{AllowSources: []string{"192.0.2.0/24"}}, "::ffff:192.0.2.1", v2c("public"), true},
+		{TrapFilter{DenySources: []string{"2001:db8::/32"}}, "2001:db8::1", v2c("public"), false},
+		{TrapFilter{AllowCommunities: []string{"public"}}, "192.0.2.1", v2c("public"), true},
+		{TrapFilter{AllowCommunities: []string{"public"}}, "192.0.2.1", v2c("private"), false},
+		{TrapFilter{DenyCommunities: []string{"private"}}, "192.0.2.1", v2c("private"), false},
+		{TrapFilter{AllowCommunities: []string{"public"}}, "192.0.2.1", v3("user"), true},
+		{TrapFilter{AllowUsers: []string{"user"}}, "192.0.2.1", v3("user"), true},
+		{TrapFilter{AllowUsers: []string{"user"}}, "192.0.2.1", v3("other"), false},
+		{TrapFilter{DenyUsers: []string{"other"}}, "192.0.2.1", v3("other"), false},
+		{TrapFilter{Func: func(addr *net.UDPAddr, header *SnmpPacket) bool { return header.Version == Version3 }},
+			"192.0.2.1", v2c("public"), false},
+	}
+	for i, test := range tests {
+		filter, err := test.filter.compile()
+		if err != nil {
+			t.Fatalf("%d: compile() err: %v", i, err)
+		}
+		addr := &net.UDPAddr{IP: net.ParseIP(test.source), Port: 162}
+		if ok := filter.allowsSource(addr) && filter.allows(addr, test.header); ok != test.ok {
+			t.Errorf("%d: %s from %s: got %t, expected %t", i, test.header, test.source, ok, test.ok)
+		}
+	}
+
+	for _, source := range []string{"192.0.2", "192.0.2.0/33"} {
+		if _, err := (&TrapFilter{DenySources: []string{source}}).compile(); err == nil {
+			t.Errorf("compile() of source %q succeeded", source)
+		}
+	}
+}
+
+func TestTrapListenerFilter(t *testing.T) {
+	tl := NewTrapListener()
+	received := make(chan *SnmpPacket, 2)
+	tl.OnNewTrap = func(s *SnmpPacket, u *net.UDPAddr) { received <- s }
+	tl.Params = &GoSNMP{}
+	tl.Filter = &TrapFilter{AllowSources: []string{"127.0.0.0/8"}, DenyCommunities: []string{"private"}}
+	addr := listenTestTraps(t, tl)
+	defer tl.Close()
+
+	for _, community := range []string{"private", "public"} {
+		x := &GoSNMP{
+			Target:    "127.0.0.1",
+			Port:      uint16(addr.Port),
+			Community: community,
+			Version:   Version2c,
+			Timeout:   time.Second,
+			MaxOids:   MaxOids,
+		}
+		if err := x.Connect(); err != nil {
+			t.Fatalf("Connect() err: %v", err)
+		}
+		_, err := x.SendTrap([]SnmpPDU{{Name: trapTestOid, Type: OctetString, Value: trapTestPayload}})
+		x.Conn.Close()
+		if err != nil {
+			t.Fatalf("SendTrap() err: %v", err)
+		}
+	}
+	select {
+	case trap := <-received:
+		if trap.Community != "public" {
+			t.Errorf("OnNewTrap() got %s", trap)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the trap")
+	}
+	if stats := tl.Stats(); stats.PacketsReceived != 2 || stats.Rejected != 1 {
+		t.Errorf("got %+v, expected 1 of 2 packets rejected", stats)
+	}
+}
@@ -0,0 +1,255 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+//
+// Forwarding notifications, RFC 3413 section 3.5.2
+//
+
+// The objects of RFC 3584 section 3.1, describing the SNMPv1 trap an
+// SNMPv2 notification was translated from; and the snmpTraps subtree of
+// the generic traps
+const (
+	snmpTrapAddress    = ".1.3.6.1.6.3.18.1.3.0"
+	snmpTrapCommunity  = ".1.3.6.1.6.3.18.1.4.0"
+	snmpTrapEnterprise = ".1.3.6.1.6.3.1.1.4.3.0"
+	snmpTraps          = ".1.3.6.1.6.3.1.1.5"
+)
+
+// defaultForwardQueueSize is the default of TrapDestination.QueueSize
+const defaultForwardQueueSize = 100
+
+// TrapForwarder re-sends the traps and informs it is given to other
+// notification receivers, as the proxy forwarder of RFC 3413 does, eg to
+// relay the traps of devices on one network to managers on another. Its
+// HandleTrap is set as a TrapListener's OnTrap (or is registered with a
+// TrapMux), and each notification is queued for each of the Destinations,
+// which send them in the background in the order they were received.
+//
+// A TrapForwarder must not be changed once it has been given a
+// notification; Close stops it.
+type TrapForwarder struct {
+	Destinations []*TrapDestination
+
+	m       sync.Mutex
+	started bool
+	closed  bool
+	senders sync.WaitGroup
+}
+
+// TrapDestination is a notification receiver a TrapForwarder sends to
+type TrapDestination struct {
+	// Session is the connected session notifications are sent with; it
+	// must not be used otherwise while the forwarder is running. Its
+	// Version, and Community or SecurityParameters, replace those of the
+	// notifications. Informs are sent as informs, retried as Session's
+	// requests are until they are acknowledged, and traps as traps. If the
+	// Version isn't the notification's, it is translated as RFC 3584
+	// section 3 says: SNMPv1 traps are given the snmpTrapOID, and
+	// snmpTrapAddress, snmpTrapCommunity and snmpTrapEnterprise varbinds,
+	// of their fields; and SNMPv2 notifications are sent to SNMPv1
+	// receivers as traps, unless they have Counter64 varbinds, which
+	// SNMPv1 hasn't got.
+	Session *GoSNMP
+
+	// KeepCommunity, if set, sends SNMPv1 and SNMPv2c notifications with
+	// their own community, rather than that of Session
+	KeepCommunity bool
+
+	// QueueSize is the number of notifications that can wait to be sent;
+	// notifications received while it is full are dropped (default: 100)
+	QueueSize int
+
+	queue chan *SnmpPacket
+}
+
+// HandleTrap queues a notification for the forwarder's destinations
+func (f *TrapForwarder) HandleTrap(s *SnmpPacket, info *TrapInfo) {
+	f.m.Lock()
+	defer f.m.Unlock()
+	if f.closed {
+		return
+	}
+	f.start()
+	for _, d := range f.Destinations {
+		select {
+		case d.queue <- s:
+		default:
+			d.Session.logWarn("Dropping notification, the forwarding queue is full", "target", d.Session.Target)
+		}
+	}
+}
+
+// Close stops the forwarder, once the notifications queued have been sent
+func (f *TrapForwarder) Close() {
+	f.m.Lock()
+	if !f.closed && f.started {
+		for _, d := range f.Destinations {
+			close(d.queue)
+		}
+	}
+	f.closed = true
+	f.m.Unlock()
+	f.senders.Wait()
+}
+
+// start starts the destinations' senders, if they haven't been
+func (f *TrapForwarder) start() {
+	if f.started {
+		return
+	}
+	f.started = true
+	for _, d := range f.Destinations {
+		size := d.QueueSize
+		if size <= 0 {
+			size = defaultForwardQueueSize
+		}
+		d.queue = make(chan *SnmpPacket, size)
+		f.senders.Add(1)
+		go func(d *TrapDestination) {
+			defer f.senders.Done()
+			for s := range d.queue {
+				if err := d.forward(s); err != nil {
+					d.Session.logWarn("Unable to forward notification", "target", d.Session.Target, "err", err)
+				}
+			}
+		}(d)
+	}
+}
+
+// forward sends a notification to the destination
+func (d *TrapDestination) forward(s *SnmpPacket) error {
+	x := d.Session
+	community := x.Community
+	if d.KeepCommunity && s.Version != Version3 {
+		community = s.Community
+	}
+	if x.Version == Version1 {
+		trap, err := v1Trap(s)
+		if err != nil {
+			return err
+		}
+		oid, err := ParseOid(trap.Enterprise)
+		if err != nil {
+			return fmt.Errorf("Notification enterprise: %w", err)
+		}
+		enterprise := make([]int, len(oid))
+		for i, n := range oid {
+			enterprise[i] = int(n)
+		}
+		packet := x.mkSnmpPacketV1Trap(Trap, enterprise, trap.AgentAddress, trap.GenericTrap, trap.SpecificTrap, int(trap.Timestamp), trap.Variables)
+		packet.Community = community
+		_, err = x.sendTrap(packet)
+		return err
+	}
+
+	pdus := v2Varbinds(s)
+	if s.PDUType == InformRequest {
+		_, err := x.sendInform(context.Background(), func() *SnmpPacket {
+			packet := x.mkInform(pdus)
+			packet.Community = community
+			return packet
+		})
+		return err
+	}
+	packet := x.mkSnmpPacket(SNMPv2Trap, pdus, 0, 0)
+	packet.Community = community
+	_, err := x.sendTrap(packet)
+	return err
+}
+
+// v2Varbinds returns the varbinds of a notification as an SNMPv2 one,
+// translated as RFC 3584 section 3.1 says if it is an SNMPv1 trap
+func v2Varbinds(s *SnmpPacket) []SnmpPDU {
+	trap := s.V1Trap()
+	if trap == nil {
+		return s.Variables
+	}
+	pdus := []SnmpPDU{
+		{Name: sysUpTimeOid, Type: TimeTicks, Value: trap.Timestamp},
+		{Name: snmpTrapOID, Type: ObjectIdentifier, Value: trap.TrapOID()},
+	}
+	pdus = append(pdus, trap.Variables...)
+	for _, pdu := range []SnmpPDU{
+		{Name: snmpTrapAddress, Type: IPAddress, Value: trap.AgentAddress},
+		{Name: snmpTrapCommunity, Type: OctetString, Value: []byte(trap.Community)},
+		{Name: snmpTrapEnterprise, Type: ObjectIdentifier, Value: trap.Enterprise},
+	} {
+		if !hasVarbind(trap.Variables, pdu.Name) {
+			pdus = append(pdus, pdu)
+		}
+	}
+	return pdus
+}
+
+func hasVarbind(pdus []SnmpPDU, name string) bool {
+	for _, pdu := range pdus {
+		if "."+trimOidDot(pdu.Name) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// v1Trap returns a notification as an SNMPv1 trap, translated as RFC 3584
+// section 3.2 says if it is an SNMPv2 one
+func v1Trap(s *SnmpPacket) (*SnmpV1Trap, error) {
+	if trap := s.V1Trap(); trap != nil {
+		return trap, nil
+	}
+	trap := &SnmpV1Trap{Community: s.Community, AgentAddress: "0.0.0.0"}
+	var oid Oid
+	enterprise := ""
+	for _, pdu := range s.Variables {
+		switch "." + trimOidDot(pdu.Name) {
+		case sysUpTimeOid:
+			if ticks, ok := pdu.Value.(uint32); ok {
+				trap.Timestamp = ticks
+			}
+			continue
+		case snmpTrapOID:
+			oid, _ = ParseOid(fmt.Sprint(pdu.Value))
+			continue
+		case snmpTrapAddress:
+			if address, ok := pdu.Value.(string); ok {
+				trap.AgentAddress = address
+			}
+		case snmpTrapEnterprise:
+			enterprise = fmt.Sprint(pdu.Value)
+		}
+		if pdu.Type == Counter64 {
+			return nil, fmt.Errorf("Notification %s has a Counter64, which SNMPv1 hasn't got", pdu.Name)
+		}
+		trap.Variables = append(trap.Variables, pdu)
+	}
+	if len(oid) < 2 {
+		return nil, fmt.Errorf("Notification has no snmpTrapOID")
+	}
+
+	traps, _ := ParseOid(snmpTraps)
+	last := oid[len(oid)-1]
+	switch {
+	case len(oid) == len(traps)+1 && oid.HasPrefix(traps) && last >= 1 && last <= EnterpriseSpecific:
+		// a generic trap, eg linkDown
+		trap.GenericTrap = int(last) - 1
+		trap.Enterprise = snmpTraps
+		if enterprise != "" {
+			trap.Enterprise = enterprise
+		}
+	case oid[len(oid)-2] == 0:
+		trap.GenericTrap, trap.SpecificTrap = EnterpriseSpecific, int(last)
+		trap.Enterprise = oid[:len(oid)-2].String()
+	default:
+		trap.GenericTrap, trap.SpecificTrap = EnterpriseSpecific, int(last)
+		trap.Enterprise = oid[:len(oid)-1].String()
+	}
+	return trap, nil
+}
@@ -0,0 +1,160 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTrapTranslation(t *testing.T) {
+	payload := SnmpPDU{Name: trapTestOid, Type: OctetString, Value: trapTestPayload}
+	v2 := func(oid string, pdus ...SnmpPDU) *SnmpPacket {
+		return &SnmpPacket{
+			Version:   Version2c,
+			Community: "public",
+			PDUType:   SNMPv2Trap,
+			Variables: append([]SnmpPDU{
+				{Name: ".1.3.6.1.2.1.1.3.0", Type: TimeTicks, Value: uint32(500)},
+				{Name: snmpTrapOID, Type: ObjectIdentifier, Value: oid},
+			}, pdus...),
+		}
+	}
+	tests := []struct {
+		packet *SnmpPacket
+		trap   *SnmpV1Trap
+	}{
+		{v2(".1.3.6.1.6.3.1.1.5.3", payload), &SnmpV1Trap{
+			Community: "public", Enterprise: snmpTraps, AgentAddress: "0.0.0.0",
+			GenericTrap: LinkDown, Timestamp: 500, Variables: []SnmpPDU{payload},
+		}},
+		{v2(".1.3.6.1.4.1.8072.4.0.2"), &SnmpV1Trap{
+			Community: "public", Enterprise: ".1.3.6.1.4.1.8072.4", AgentAddress: "0.0.0.0",
+			GenericTrap: EnterpriseSpecific, SpecificTrap: 2, Timestamp: 500,
+		}},
+		{v2(".1.3.6.1.4.1.8072.4.7",
+			SnmpPDU{Name: snmpTrapAddress, Type: IPAddress, Value: "192.0.2.1"}), &SnmpV1Trap{
+			Community: "public", Enterprise: ".1.3.6.1.4.1.8072.4", AgentAddress: "192.0.2.1",
+			GenericTrap: EnterpriseSpecific, SpecificTrap: 7, Timestamp: 500,
+			Variables: []SnmpPDU{{Name: snmpTrapAddress, Type: IPAddress, Value: "192.0.2.1"}},
+		}},
+		{v2(".1.3.6.1.4.1.8072.4.0.2", SnmpPDU{Name: ".1.3.6.1.2.1.31.1.1.1.6.1", Type: Counter64, Value: uint64(1)}), nil},
+		{&SnmpPacket{Version: Version2c, PDUType: SNMPv2Trap}, nil},
+	}
+	for i, test := range tests {
+		trap, err := v1Trap(test.packet)
+		if test.trap == nil {
+			if err == nil {
+				t.Errorf("%d: v1Trap() got %+v, expected an error", i, trap)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(trap, test.trap) {
+			t.Errorf("%d: v1Trap() got %+v, err %v, expected %+v", i, trap, err, test.trap)
+		}
+	}
+
+	v1 := &SnmpPacket{
+		Version:     Version1,
+		Community:   "public",
+		PDUType:     Trap,
+		Enterprise:  []int{1, 3, 6, 1, 4, 1, 8072, 4},
+		AgentAddr:   "192.0.2.1",
+		GenericTrap: EnterpriseSpecific, SpecificTrap: 2,
+		Timestamp: 500,
+		Variables: []SnmpPDU{payload},
+	}
+	want := []SnmpPDU{
+		{Name: sysUpTimeOid, Type: TimeTicks, Value: uint32(500)},
+		{Name: snmpTrapOID, Type: ObjectIdentifier, Value: ".1.3.6.1.4.1.8072.4.0.2"},
+		payload,
+		{Name: snmpTrapAddress, Type: IPAddress, Value: "192.0.2.1"},
+		{Name: snmpTrapCommunity, Type: OctetString, Value: []byte("public")},
+		{Name: snmpTrapEnterprise, Type: ObjectIdentifier, Value: ".1.3.6.1.4.1.8072.4"},
+	}
+	if got := v2Varbinds(v1); !reflect.DeepEqual(got, want) {
+		t.Errorf("v2Varbinds() got %v, expected %v", got, want)
+	}
+}
+
+func TestTrapForwarder(t *testing.T) {
+	// the downstream receivers, an SNMPv2c and an SNMPv1 one
+	downstream := func() (*TrapListener, chan *SnmpPacket, *net.UDPAddr) {
+		tl := NewTrapListener()
+		received := make(chan *SnmpPacket, 4)
+		tl.OnNewTrap = func(s *SnmpPacket, u *net.UDPAddr) { received <- s }
+		tl.Params = &GoSNMP{}
+		return tl, received, listenTestTraps(t, tl)
+	}
+	tl2, received2, addr2 := downstream()
+	defer tl2.Close()
+	tl1, received1, addr1 := downstream()
+	defer tl1.Close()
+	session := func(addr *net.UDPAddr, version SnmpVersion) *GoSNMP {
+		x := &GoSNMP{
+			Target:    "127.0.0.1",
+			Port:      uint16(addr.Port),
+			Community: "downstream",
+			Version:   version,
+			Timeout:   time.Second,
+			MaxOids:   MaxOids,
+		}
+		if err := x.Connect(); err != nil {
+			t.Fatalf("Connect() err: %v", err)
+		}
+		return x
+	}
+	x2, x1 := session(addr2, Version2c), session(addr1, Version1)
+	defer x2.Conn.Close()
+	defer x1.Conn.Close()
+
+	// the relay
+	forwarder := &TrapForwarder{Destinations: []*TrapDestination{
+		{Session: x2},
+		{Session: x1, KeepCommunity: true},
+	}}
+	relay := NewTrapListener()
+	relay.OnTrap = forwarder.HandleTrap
+	relay.Params = &GoSNMP{}
+	x := session(listenTestTraps(t, relay), Version2c)
+	x.Community = "upstream"
+	defer relay.Close()
+	defer x.Conn.Close()
+
+	receive := func(received chan *SnmpPacket) *SnmpPacket {
+		t.Helper()
+		select {
+		case s := <-received:
+			return s
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for the forwarded notification")
+		}
+		return nil
+	}
+	pdus := []SnmpPDU{{Name: snmpTrapOID, Type: ObjectIdentifier, Value: ".1.3.6.1.6.3.1.1.5.3"}}
+	if _, err := x.SendInform(pdus); err != nil {
+		t.Fatalf("SendInform() err: %v", err)
+	}
+	if s := receive(received2); s.PDUType != InformRequest || s.Community != "downstream" || s.TrapOID() != ".1.3.6.1.6.3.1.1.5.3" {
+		t.Errorf("SNMPv2c receiver got %s", s)
+	}
+	if s := receive(received1); s.PDUType != Trap || s.Community != "upstream" || s.GenericTrap != LinkDown {
+		t.Errorf("SNMPv1 receiver got %s", s)
+	}
+
+	// closing the forwarder waits for the notifications queued
+	if _, err := x.SendTrap(pdus); err != nil {
+		t.Fatalf("SendTrap() err: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	forwarder.Close()
+	if s := receive(received2); s.PDUType != SNMPv2Trap {
+		t.Errorf("SNMPv2c receiver got %s", s)
+	}
+	receive(received1)
+	forwarder.HandleTrap(&SnmpPacket{Version: Version2c, PDUType: SNMPv2Trap}, &TrapInfo{})
+}
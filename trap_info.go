@@ -0,0 +1,68 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"net"
+	"time"
+)
+
+// TrapInfo describes how a TrapListener received a trap or inform, eg for
+// correlating it with other events; see TrapListener.OnTrap
+type TrapInfo struct {
+	// Remote is the address the trap was received from, which may not be
+	// that of the agent that generated it (see SnmpV1Trap.AgentAddress),
+	// and Local the listener's address it was received on. For traps
+	// received over tcp they are the tcp addresses, as UDPAddrs.
+	Remote *net.UDPAddr
+	Local  *net.UDPAddr
+
+	// Transport is the network the trap was received over, eg "udp" or "tcp"
+	Transport string
+
+	// Size is the size of the message, in bytes on the wire
+	Size int
+
+	// Received is when the trap was read from the listener's socket
+	Received time.Time
+
+	// Version is the message's version, and Community its community if it
+	// isn't an SNMPv3 message
+	Version   SnmpVersion
+	Community string
+
+	// UserName, SecurityLevel (NoAuthNoPriv, AuthNoPriv or AuthPriv) and
+	// EngineID are the user, security level and authoritative engine of
+	// an SNMPv3 message: for traps the sender's engine, and for informs
+	// the listener's. ContextEngineID and ContextName are its context.
+	UserName        string
+	SecurityLevel   SnmpV3MsgFlags
+	EngineID        string
+	ContextEngineID string
+	ContextName     string
+}
+
+// newTrapInfo returns the TrapInfo of packet, a message of size bytes
+// received from remote on the listener's address local at received
+func newTrapInfo(packet *SnmpPacket, local net.Addr, remote *net.UDPAddr, size int, received time.Time) *TrapInfo {
+	info := &TrapInfo{
+		Remote:    remote,
+		Local:     udpAddr(local),
+		Transport: local.Network(),
+		Size:      size,
+		Received:  received,
+		Version:   packet.Version,
+		Community: packet.Community,
+	}
+	if packet.Version == Version3 {
+		info.Community = ""
+		info.SecurityLevel = packet.MsgFlags & AuthPriv
+		info.ContextEngineID, info.ContextName = packet.ContextEngineID, packet.ContextName
+		if usm, ok := packet.SecurityParameters.(*UsmSecurityParameters); ok {
+			info.UserName, info.EngineID = usm.UserName, usm.AuthoritativeEngineID
+		}
+	}
+	return info
+}
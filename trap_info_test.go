@@ -0,0 +1,75 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestTrapListenerOnTrap(t *testing.T) {
+	user := &UsmSecurityParameters{
+		UserName:                 "user",
+		AuthenticationProtocol:   SHA,
+		AuthenticationPassphrase: "authpassphrase",
+	}
+	tl := NewTrapListener()
+	received := make(chan *TrapInfo, 1)
+	tl.OnNewTrap = func(s *SnmpPacket, u *net.UDPAddr) { t.Errorf("OnNewTrap() called for %s", s) }
+	tl.OnTrap = func(s *SnmpPacket, info *TrapInfo) { received <- info }
+	tl.Params = &GoSNMP{
+		Version:            Version3,
+		MsgFlags:           AuthNoPriv,
+		SecurityModel:      UserSecurityModel,
+		SecurityParameters: user.Copy(),
+	}
+	addr := listenTestTraps(t, tl)
+	defer tl.Close()
+
+	for _, x := range []*GoSNMP{
+		{Community: "public", Version: Version2c},
+		{
+			Version:            Version3,
+			MsgFlags:           AuthNoPriv,
+			SecurityModel:      UserSecurityModel,
+			SecurityParameters: user.Copy(),
+			EngineID:           "\x80\x00\x00\x00\x04sender",
+			ContextName:        "context",
+		},
+	} {
+		x.Target, x.Port, x.Timeout, x.MaxOids = "127.0.0.1", uint16(addr.Port), time.Second, MaxOids
+		if err := x.Connect(); err != nil {
+			t.Fatalf("Connect() err: %v", err)
+		}
+		before := time.Now()
+		_, err := x.SendTrap([]SnmpPDU{{Name: trapTestOid, Type: OctetString, Value: trapTestPayload}})
+		if err != nil {
+			t.Fatalf("SendTrap() err: %v", err)
+		}
+		var info *TrapInfo
+		select {
+		case info = <-received:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s: timed out waiting for the trap", x.Version)
+		}
+		local := x.Conn.LocalAddr().(*net.UDPAddr)
+		x.Conn.Close()
+
+		if !info.Remote.IP.Equal(local.IP) || info.Remote.Port != local.Port || info.Local.Port != addr.Port ||
+			info.Transport != "udp" || info.Size == 0 || info.Received.Before(before) || info.Version != x.Version {
+			t.Errorf("%s: got %+v", x.Version, info)
+		}
+		want := TrapInfo{Community: "public"}
+		if x.Version == Version3 {
+			want = TrapInfo{UserName: "user", SecurityLevel: AuthNoPriv, EngineID: x.EngineID,
+				ContextEngineID: x.EngineID, ContextName: "context"}
+		}
+		if info.Community != want.Community || info.UserName != want.UserName || info.SecurityLevel != want.SecurityLevel ||
+			info.EngineID != want.EngineID || info.ContextEngineID != want.ContextEngineID || info.ContextName != want.ContextName {
+			t.Errorf("%s: got %+v, expected %+v", x.Version, info, want)
+		}
+	}
+}
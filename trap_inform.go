@@ -0,0 +1,221 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync/atomic"
+)
+
+//
+// Acknowledging informs, ie the TrapListener acting as their authoritative
+// engine (RFC 3414 section 3.2)
+//
+
+// errDiscovery is the error of an SNMPv3 message discovering the
+// listener's engine, which is answered with a report
+var errDiscovery = errors.New("engine discovery")
+
+// initEngine sets up the listener's engine, which is Params' own (see
+// GoSNMP.EngineID), for the informs of Params' SNMPv3 user
+func (t *TrapListener) initEngine() error {
+	usm, ok := t.Params.SecurityParameters.(*UsmSecurityParameters)
+	if t.Params.Version != Version3 || !ok {
+		return nil
+	}
+	if err := t.Params.validateParametersV3(); err != nil {
+		// as for traps, which are received regardless
+		t.Params.logWarn("Not acknowledging SNMPv3 informs", "err", err)
+		return nil
+	}
+	engineID, _ := t.Params.localEngine()
+	sp := usm.Copy().(*UsmSecurityParameters)
+	sp.AuthoritativeEngineID = engineID
+	sp.secretKey, sp.privacyKey = nil, nil
+	sp.localizeKeys()
+	if err := sp.init(t.Params.Logger); err != nil {
+		return err
+	}
+	t.engine = &GoSNMP{Logger: t.Params.Logger, loggingEnabled: t.Params.loggingEnabled, SecurityParameters: sp,
+		DecodeLimits: t.Params.DecodeLimits}
+	return nil
+}
+
+// receive returns the notification in msg, unless the listener's filter
+// rejects its header. SNMPv3 informs, which are reportable, are for the
+// listener's engine: their engine discovery and errors are answered with
+// reports sent to remote.
+func (t *TrapListener) receive(conn trapConn, remote *net.UDPAddr, msg []byte) (*SnmpPacket, error) {
+	header := &SnmpPacket{SecurityParameters: &UsmSecurityParameters{Logger: t.Params.Logger}}
+	_, err := t.Params.unmarshalHeader(append([]byte(nil), msg...), header)
+	if err == nil && !t.filter.allows(remote, header) {
+		return nil, errRejected
+	}
+	if err != nil || header.Version != Version3 || header.SecurityModel != UserSecurityModel ||
+		header.MsgFlags&Reportable == 0 {
+		// eg a trap, whose authoritative engine is its sender's
+		return t.Params.unmarshalTrap(msg)
+	}
+
+	var inform *SnmpPacket
+	var report []byte
+	engineID, _ := t.Params.localEngine()
+	switch id := header.SecurityParameters.(*UsmSecurityParameters).AuthoritativeEngineID; {
+	case id == "":
+		report, err = t.report(header, false, usmStatsUnknownEngineIDs, &t.unknownEngineIDs), errDiscovery
+	case id != engineID:
+		// a trap that is wrongly reportable, or an inform for an engine the
+		// listener had before restarting
+		if inform, err = t.Params.unmarshalTrap(msg); err == nil && inform.PDUType == InformRequest {
+			inform, report, err = nil, t.report(header, false, usmStatsUnknownEngineIDs, &t.unknownEngineIDs), errDiscovery
+		}
+	default:
+		inform, report, err = t.unmarshalInform(msg, header)
+	}
+	if report != nil {
+		t.send(conn, remote, report, nil)
+	}
+	return inform, err
+}
+
+// unmarshalInform returns the SNMPv3 notification in msg, for the
+// listener's engine, whose header has been decoded; or if it can't be
+// processed, a report
+func (t *TrapListener) unmarshalInform(msg []byte, header *SnmpPacket) (*SnmpPacket, []byte, error) {
+	sp := header.SecurityParameters.(*UsmSecurityParameters)
+	if t.engine == nil || sp.UserName != t.engine.SecurityParameters.(*UsmSecurityParameters).UserName {
+		return nil, t.report(header, false, usmStatsUnknownUserNames, &t.unknownUserNames),
+			fmt.Errorf("%w: unknown user %q", ErrAuthentication, sp.UserName)
+	}
+	user := t.engine.SecurityParameters.(*UsmSecurityParameters)
+	level := header.MsgFlags & AuthPriv
+	if level&AuthNoPriv > 0 && user.AuthenticationProtocol <= NoAuth ||
+		level&AuthPriv > AuthNoPriv && user.PrivacyProtocol <= NoPriv || level < t.Params.MsgFlags&AuthPriv {
+		return nil, t.report(header, false, usmStatsUnsupportedSecLevels, &t.unsupportedSecLevels),
+			fmt.Errorf("%w: unsupported security level %s", ErrAuthentication, level)
+	}
+
+	inform, err := t.engine.Decode(msg)
+	switch {
+	case errors.Is(err, ErrAuthentication):
+		return nil, t.report(header, false, usmStatsWrongDigests, &t.wrongDigests), err
+	case err != nil && level&AuthPriv > AuthNoPriv:
+		return nil, t.report(header, false, usmStatsDecryptionErrors, &t.decryptionErrors), err
+	case err != nil:
+		return nil, nil, err
+	}
+	if level&AuthNoPriv > 0 {
+		isp := inform.SecurityParameters.(*UsmSecurityParameters)
+		_, now := t.Params.localEngine()
+		if isp.AuthoritativeEngineBoots != t.Params.EngineBoots ||
+			isp.AuthoritativeEngineTime > now+agentTimeWindow ||
+			isp.AuthoritativeEngineTime+agentTimeWindow < now {
+			return nil, t.report(inform, true, usmStatsNotInTimeWindows, &t.notInTimeWindows),
+				fmt.Errorf("%w: not in time window", ErrAuthentication)
+		}
+	}
+	switch inform.PDUType {
+	case InformRequest, SNMPv2Trap:
+		return inform, nil, nil
+	}
+	return nil, nil, fmt.Errorf("%w: %s isn't a notification", ErrDecode, inform.PDUType)
+}
+
+// usm returns the security parameters of a message from the listener's
+// engine, authenticated as its user if authenticated
+func (t *TrapListener) usm(authenticated bool, flags SnmpV3MsgFlags) *UsmSecurityParameters {
+	sp := &UsmSecurityParameters{Logger: t.Params.Logger}
+	if authenticated {
+		sp = t.engine.SecurityParameters.Copy().(*UsmSecurityParameters)
+	}
+	sp.AuthoritativeEngineID, sp.AuthoritativeEngineTime = t.Params.localEngine()
+	sp.AuthoritativeEngineBoots = t.Params.EngineBoots
+	if authenticated && flags&AuthPriv > AuthNoPriv {
+		salt, _ := t.engine.SecurityParameters.(*UsmSecurityParameters).usmAllocateNewSalt()
+		sp.usmSetSalt(salt)
+	}
+	return sp
+}
+
+// report returns the Report of a usmStats counter for an SNMPv3 message
+// that can't be processed, if the message is reportable, authenticated as
+// the user if authenticated
+func (t *TrapListener) report(request *SnmpPacket, authenticated bool, oid string, counter *uint32) []byte {
+	count := atomic.AddUint32(counter, 1)
+	t.Params.logInfo("Reporting SNMPv3 inform", "counter", oid)
+	if request.MsgFlags&Reportable == 0 {
+		return nil
+	}
+	flags := NoAuthNoPriv
+	if authenticated {
+		flags = AuthNoPriv
+	}
+	engineID, _ := t.Params.localEngine()
+	report := &SnmpPacket{
+		Version:            Version3,
+		MsgID:              request.MsgID,
+		MsgFlags:           flags,
+		SecurityModel:      UserSecurityModel,
+		SecurityParameters: t.usm(authenticated, flags),
+		ContextEngineID:    engineID,
+		ContextName:        request.ContextName,
+		PDUType:            Report,
+		RequestID:          request.RequestID,
+		Variables:          []SnmpPDU{{Name: oid, Type: Counter32, Value: count}},
+	}
+	if !authenticated {
+		report.SecurityParameters.(*UsmSecurityParameters).UserName =
+			request.SecurityParameters.(*UsmSecurityParameters).UserName
+	}
+	out, err := report.marshalMsg()
+	if err != nil {
+		t.Params.logError("Unable to encode report", "err", err)
+		return nil
+	}
+	return out
+}
+
+// acknowledge sends remote the response to an inform: the inform's
+// varbinds, with no error
+func (t *TrapListener) acknowledge(conn trapConn, remote *net.UDPAddr, inform *SnmpPacket) {
+	response := &SnmpPacket{
+		Version:   inform.Version,
+		Community: inform.Community,
+		PDUType:   GetResponse,
+		RequestID: inform.RequestID,
+		Variables: inform.Variables,
+	}
+	if inform.Version == Version3 {
+		response.MsgID = inform.MsgID
+		response.MsgFlags = inform.MsgFlags &^ Reportable
+		response.SecurityModel = UserSecurityModel
+		response.SecurityParameters = t.usm(true, response.MsgFlags)
+		response.ContextEngineID = inform.ContextEngineID
+		response.ContextName = inform.ContextName
+	}
+	out, err := response.marshalMsg()
+	if err != nil {
+		t.Params.logError("Unable to encode inform response", "err", err)
+		return
+	}
+	t.send(conn, remote, out, response)
+}
+
+// send sends msg, of packet if it is set, to remote
+func (t *TrapListener) send(conn trapConn, remote *net.UDPAddr, msg []byte, packet *SnmpPacket) {
+	if err := conn.reply(msg, remote); err != nil {
+		t.Params.logWarn("Unable to send to inform sender", "addr", remote, "err", err)
+		return
+	}
+	atomic.AddUint64(&t.counters().sent, 1)
+	if capture := t.Params.Capture; capture != nil {
+		capture.datagram(conn.LocalAddr(), remote, msg)
+		if packet != nil {
+			capture.plaintext(conn.LocalAddr(), remote, packet)
+		}
+	}
+}
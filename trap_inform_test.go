@@ -0,0 +1,278 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+// startTestInformListener starts a listener with params, returning it and
+// a channel of the notifications it receives; x is connected to it
+func startTestInformListener(t *testing.T, params *GoSNMP, x *GoSNMP) (*TrapListener, chan *SnmpPacket) {
+	t.Helper()
+	tl := NewTrapListener()
+	received := make(chan *SnmpPacket, 4)
+	tl.OnNewTrap = func(s *SnmpPacket, u *net.UDPAddr) { received <- s }
+	tl.Params = params
+	addr := listenTestTraps(t, tl)
+	x.Target = "127.0.0.1"
+	x.Port = uint16(addr.Port)
+	x.Timeout = time.Second
+	x.Retries = 1
+	x.MaxOids = MaxOids
+	if err := x.Connect(); err != nil {
+		t.Fatalf("Connect() err: %v", err)
+	}
+	return tl, received
+}
+
+// receiveTestInform returns the notification received, failing if there is
+// none
+func receiveTestInform(t *testing.T, received chan *SnmpPacket) *SnmpPacket {
+	t.Helper()
+	select {
+	case inform := <-received:
+		return inform
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the inform")
+	}
+	return nil
+}
+
+func TestTrapListenerInform(t *testing.T) {
+	x := &GoSNMP{Community: "public", Version: Version2c}
+	tl, received := startTestInformListener(t, &GoSNMP{}, x)
+	defer tl.Close()
+	defer x.Conn.Close()
+
+	result, err := x.SendInform([]SnmpPDU{{Name: trapTestOid, Type: OctetString, Value: trapTestPayload}})
+	if err != nil {
+		t.Fatalf("SendInform() err: %v", err)
+	}
+	if result.PDUType != GetResponse || result.Error != NoError || len(result.Variables) != 2 {
+		t.Errorf("SendInform() got %s", result)
+	}
+	if inform := receiveTestInform(t, received); inform.PDUType != InformRequest || inform.Community != "public" {
+		t.Errorf("OnNewTrap() got %s", inform)
+	}
+	if stats := tl.Stats(); stats.PacketsSent != 1 {
+		t.Errorf("PacketsSent is %d, expected 1", stats.PacketsSent)
+	}
+}
+
+func TestTrapListenerInformV3(t *testing.T) {
+	user := func(authPass string) *UsmSecurityParameters {
+		return &UsmSecurityParameters{
+			UserName:                 "user",
+			AuthenticationProtocol:   SHA,
+			AuthenticationPassphrase: authPass,
+			PrivacyProtocol:          AES,
+			PrivacyPassphrase:        "privpassphrase",
+		}
+	}
+	params := &GoSNMP{
+		Version:            Version3,
+		MsgFlags:           AuthPriv,
+		SecurityModel:      UserSecurityModel,
+		SecurityParameters: user("authpassphrase"),
+		EngineID:           "\x80\x00\x00\x00\x04listener",
+		EngineBoots:        3,
+	}
+	newSender := func(sp *UsmSecurityParameters) *GoSNMP {
+		return &GoSNMP{
+			Version:            Version3,
+			MsgFlags:           AuthPriv,
+			SecurityModel:      UserSecurityModel,
+			SecurityParameters: sp,
+		}
+	}
+	x := newSender(user("authpassphrase"))
+	tl, received := startTestInformListener(t, params, x)
+	defer tl.Close()
+	defer x.Conn.Close()
+	pdus := []SnmpPDU{{Name: trapTestOid, Type: OctetString, Value: trapTestPayload}}
+
+	// the sender discovers the listener's engine, then is acknowledged
+	result, err := x.SendInform(pdus)
+	if err != nil {
+		t.Fatalf("SendInform() err: %v", err)
+	}
+	if result.PDUType != GetResponse || result.Error != NoError || len(result.Variables) != 2 {
+		t.Errorf("SendInform() got %s", result)
+	}
+	usm := x.SecurityParameters.(*UsmSecurityParameters)
+	if usm.AuthoritativeEngineID != params.EngineID || usm.AuthoritativeEngineBoots != 3 {
+		t.Errorf("SendInform() discovered engine %x boots %d", usm.AuthoritativeEngineID, usm.AuthoritativeEngineBoots)
+	}
+	inform := receiveTestInform(t, received)
+	if engineID, _ := x.localEngine(); inform.PDUType != InformRequest || inform.ContextEngineID != engineID {
+		t.Errorf("OnNewTrap() got %s", inform)
+	}
+
+	// an inform out of the time window is reported, and sent again in it
+	usm.AuthoritativeEngineTime += 1000
+	if _, err = x.SendInform(pdus); err != nil {
+		t.Fatalf("SendInform() out of the time window err: %v", err)
+	}
+	receiveTestInform(t, received)
+
+	// other users and keys are reported, and not received
+	other := user("authpassphrase")
+	other.UserName = "other"
+	for _, sp := range []*UsmSecurityParameters{user("wrongpassphrase"), other} {
+		x := newSender(sp)
+		x.Target, x.Port, x.Timeout, x.MaxOids = "127.0.0.1", uint16(tl.Addrs()[0].(*net.UDPAddr).Port), time.Second, MaxOids
+		if err = x.Connect(); err != nil {
+			t.Fatalf("Connect() err: %v", err)
+		}
+		result, err = x.SendInform(pdus)
+		x.Conn.Close()
+		if err == nil && result.PDUType != Report {
+			t.Errorf("SendInform() as %s got %s", sp.UserName, result)
+		}
+	}
+	select {
+	case inform = <-received:
+		t.Errorf("OnNewTrap() got %s", inform)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	stats := tl.Stats()
+	if stats.AuthErrors != 3 || stats.PacketsSent != 8 {
+		t.Errorf("got %+v, expected 3 AuthErrors and 8 PacketsSent", stats)
+	}
+}
+
+func TestSendInformCtx(t *testing.T) {
+	// a receiver, acknowledging only the third inform it receives
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	go func() {
+		receiver := &GoSNMP{}
+		for n := 1; ; n++ {
+			var buf [4096]byte
+			rlen, remote, err := conn.ReadFromUDP(buf[:])
+			if err != nil {
+				return
+			}
+			inform, err := receiver.Decode(buf[:rlen])
+			if err != nil || n != 3 {
+				continue
+			}
+			inform.PDUType = GetResponse
+			if out, err := receiver.Encode(inform); err == nil {
+				conn.WriteToUDP(out, remote)
+			}
+		}
+	}()
+
+	x := &GoSNMP{
+		Target:    "127.0.0.1",
+		Port:      uint16(conn.LocalAddr().(*net.UDPAddr).Port),
+		Community: "public",
+		Version:   Version2c,
+		Timeout:   50 * time.Millisecond,
+		MaxOids:   MaxOids,
+	}
+	if err = x.Connect(); err != nil {
+		t.Fatalf("Connect() err: %v", err)
+	}
+	defer x.Conn.Close()
+	pdus := []SnmpPDU{{Name: trapTestOid, Type: OctetString, Value: trapTestPayload}}
+
+	// without a deadline the inform is sent once, as requests are
+	if _, err = x.SendInform(pdus); !errors.Is(err, ErrTimeout) {
+		t.Errorf("SendInform() err: %v, expected a timeout", err)
+	}
+
+	// and with one, until it is acknowledged
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	result, err := x.SendInformCtx(ctx, pdus)
+	if err != nil || result.PDUType != GetResponse {
+		t.Fatalf("SendInformCtx() got %v, err %v", result, err)
+	}
+	if stats := x.Stats(); stats.Retransmissions != 1 {
+		t.Errorf("Retransmissions is %d, expected 1", stats.Retransmissions)
+	}
+
+	// or the deadline passes
+	ctx, cancel = context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	done := make(chan error, 1)
+	x.SendInformAsync(ctx, pdus[:1], func(result *SnmpPacket, err error) { done <- err })
+	select {
+	case err = <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("SendInformAsync() err: %v, expected the deadline", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the callback")
+	}
+}
+
+func TestSendInformCtxV3(t *testing.T) {
+	user := &UsmSecurityParameters{
+		UserName:                 "user",
+		AuthenticationProtocol:   SHA,
+		AuthenticationPassphrase: "authpassphrase",
+	}
+	listener := func(engineID string) *GoSNMP {
+		return &GoSNMP{
+			Version:            Version3,
+			MsgFlags:           AuthNoPriv,
+			SecurityModel:      UserSecurityModel,
+			SecurityParameters: user.Copy(),
+			EngineID:           engineID,
+		}
+	}
+	x := &GoSNMP{
+		Version:            Version3,
+		MsgFlags:           AuthNoPriv,
+		SecurityModel:      UserSecurityModel,
+		SecurityParameters: user.Copy(),
+	}
+	tl, received := startTestInformListener(t, listener("\x80\x00\x00\x00\x04first"), x)
+	defer x.Conn.Close()
+	pdus := []SnmpPDU{{Name: trapTestOid, Type: OctetString, Value: trapTestPayload}}
+	if _, err := x.SendInform(pdus); err != nil {
+		t.Fatalf("SendInform() err: %v", err)
+	}
+	receiveTestInform(t, received)
+	port := tl.Addrs()[0].String()
+	tl.Close()
+
+	// a receiver restarted with another engine is rediscovered
+	tl = NewTrapListener()
+	tl.OnNewTrap = func(s *SnmpPacket, u *net.UDPAddr) { received <- s }
+	tl.Params = listener("\x80\x00\x00\x00\x04second")
+	go tl.Listen(port)
+	tl.c.L.Lock()
+	for !tl.ready() {
+		tl.c.Wait()
+	}
+	tl.c.L.Unlock()
+	defer tl.Close()
+	if _, err := x.SendInform(pdus); err != nil {
+		t.Fatalf("SendInform() to the restarted receiver err: %v", err)
+	}
+	inform := receiveTestInform(t, received)
+	if usm := inform.SecurityParameters.(*UsmSecurityParameters); usm.AuthoritativeEngineID != "\x80\x00\x00\x00\x04second" {
+		t.Errorf("OnNewTrap() got %s", inform)
+	}
+
+	// and unknown users are reported
+	x.SecurityParameters.(*UsmSecurityParameters).UserName = "other"
+	if _, err := x.SendInform(pdus); !errors.Is(err, ErrReport) {
+		t.Errorf("SendInform() as an unknown user err: %v, expected a report", err)
+	}
+}
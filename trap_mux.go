@@ -0,0 +1,130 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"sync"
+)
+
+//
+// Dispatching traps to handlers by their oid
+//
+
+// A TrapHandler handles the traps a TrapMux dispatches to it
+type TrapHandler interface {
+	HandleTrap(s *SnmpPacket, info *TrapInfo)
+}
+
+// TrapHandlerFunc is a TrapHandler that is a function
+type TrapHandlerFunc func(s *SnmpPacket, info *TrapInfo)
+
+// HandleTrap calls f(s, info)
+func (f TrapHandlerFunc) HandleTrap(s *SnmpPacket, info *TrapInfo) {
+	f(s, info)
+}
+
+// TrapMux dispatches traps and informs to the handlers registered for
+// their oid: the snmpTrapOID of SNMPv2 notifications, or for SNMPv1 traps
+// their SnmpV1Trap.TrapOID. It is used as a listener's handler by setting
+// TrapListener.OnTrap to its HandleTrap, eg
+//
+//	mux := NewTrapMux()
+//	mux.HandleFunc(".1.3.6.1.6.3.1.1.5.3", linkDown)
+//	mux.HandleSubtree(".1.3.6.1.4.1.8072", TrapHandlerFunc(netSnmp))
+//	tl.OnTrap = mux.HandleTrap
+//
+// A TrapMux is safe for concurrent use, eg by a listener's Workers, and
+// handlers can be registered while it is in use.
+type TrapMux struct {
+	// NotFound, if set, is given the traps that no handler is registered
+	// for, and those with no oid; otherwise they are dropped
+	NotFound TrapHandler
+
+	mu       sync.RWMutex
+	handlers map[string]TrapHandler // by oid
+	subtrees map[string]TrapHandler // by the oid of their root
+}
+
+// NewTrapMux returns a TrapMux with no handlers; as does the zero TrapMux
+func NewTrapMux() *TrapMux {
+	return &TrapMux{}
+}
+
+// Handle registers handler for the traps with the oid, eg
+// ".1.3.6.1.6.3.1.1.5.3" for linkDown. Registering an oid again replaces
+// its handler, and registering a nil handler removes it.
+func (m *TrapMux) Handle(oid string, handler TrapHandler) error {
+	return m.register(false, oid, handler)
+}
+
+// HandleFunc registers f for the traps with the oid, as Handle does
+func (m *TrapMux) HandleFunc(oid string, f func(s *SnmpPacket, info *TrapInfo)) error {
+	return m.Handle(oid, TrapHandlerFunc(f))
+}
+
+// HandleSubtree registers handler for the traps whose oids are in the
+// subtree at oid, eg ".1.3.6.1.4.1.8072" for those of an enterprise. Traps
+// are dispatched to the handler registered for their oid, or else to that
+// of the subtree with the longest prefix of it.
+func (m *TrapMux) HandleSubtree(oid string, handler TrapHandler) error {
+	return m.register(true, oid, handler)
+}
+
+func (m *TrapMux) register(subtree bool, oid string, handler TrapHandler) error {
+	o, err := ParseOid(oid)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.handlers == nil {
+		m.handlers = make(map[string]TrapHandler)
+		m.subtrees = make(map[string]TrapHandler)
+	}
+	handlers := m.handlers
+	if subtree {
+		handlers = m.subtrees
+	}
+	if handler == nil {
+		delete(handlers, o.String())
+	} else {
+		handlers[o.String()] = handler
+	}
+	return nil
+}
+
+// Handler returns the handler a trap with the oid is dispatched to, or nil
+// if there is none (ignoring NotFound)
+func (m *TrapMux) Handler(oid string) TrapHandler {
+	o, err := ParseOid(oid)
+	if err != nil {
+		return nil
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if handler, ok := m.handlers[o.String()]; ok {
+		return handler
+	}
+	for n := len(o); n > 0; n-- {
+		if handler, ok := m.subtrees[o[:n].String()]; ok {
+			return handler
+		}
+	}
+	return nil
+}
+
+// HandleTrap dispatches a trap to the handler registered for its oid, or
+// to NotFound
+func (m *TrapMux) HandleTrap(s *SnmpPacket, info *TrapInfo) {
+	handler := m.NotFound
+	if oid := notificationOid(s); oid != "" {
+		if h := m.Handler(oid); h != nil {
+			handler = h
+		}
+	}
+	if handler != nil {
+		handler.HandleTrap(s, info)
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTrapMux(t *testing.T) {
+	var got string
+	handler := func(name string) TrapHandler {
+		return TrapHandlerFunc(func(s *SnmpPacket, info *TrapInfo) { got = name })
+	}
+	mux := NewTrapMux()
+	mux.Handle(".1.3.6.1.6.3.1.1.5.3", handler("linkDown"))
+	mux.HandleSubtree(".1.3.6.1.6.3.1.1.5", handler("snmpTraps"))
+	mux.HandleSubtree("1.3.6.1.4.1.8072", handler("netSnmp"))
+	mux.HandleSubtree(".1.3.6.1.4.1.8072.4", handler("netSnmpNotifications"))
+	mux.HandleFunc(".1.3.6.1.4.1.8072.4.0.1", func(s *SnmpPacket, info *TrapInfo) { got = "nsNotifyStart" })
+	mux.NotFound = handler("notFound")
+	if err := mux.Handle(".1.3.x", handler("invalid")); err == nil {
+		t.Error("Handle() of an invalid oid succeeded")
+	}
+
+	v2 := func(oid interface{}) *SnmpPacket {
+		return &SnmpPacket{
+			Version: Version2c,
+			PDUType: SNMPv2Trap,
+			Variables: []SnmpPDU{
+				{Name: ".1.3.6.1.2.1.1.3.0", Type: TimeTicks, Value: uint32(100)},
+				{Name: snmpTrapOID, Type: ObjectIdentifier, Value: oid},
+			},
+		}
+	}
+	v1 := func(enterprise []int, generic, specific int) *SnmpPacket {
+		return &SnmpPacket{Version: Version1, PDUType: Trap, Enterprise: enterprise, GenericTrap: generic, SpecificTrap: specific}
+	}
+	tests := []struct {
+		packet  *SnmpPacket
+		handler string
+	}{
+		{v2(".1.3.6.1.6.3.1.1.5.3"), "linkDown"},
+		{v2(".1.3.6.1.6.3.1.1.5.4"), "snmpTraps"},
+		{v2(Oid{1, 3, 6, 1, 4, 1, 8072, 4, 0, 1}), "nsNotifyStart"},
+		{v2(".1.3.6.1.4.1.8072.4.0.2"), "netSnmpNotifications"},
+		{v2(".1.3.6.1.4.1.8072.9"), "netSnmp"},
+		{v2(".1.3.6.1.4.1.9"), "notFound"},
+		{&SnmpPacket{Version: Version2c, PDUType: SNMPv2Trap}, "notFound"},
+		{v1(nil, LinkDown, 0), "linkDown"},
+		{v1([]int{1, 3, 6, 1, 4, 1, 8072, 4}, EnterpriseSpecific, 1), "nsNotifyStart"},
+	}
+	for i, test := range tests {
+		got = ""
+		mux.HandleTrap(test.packet, &TrapInfo{})
+		if got != test.handler {
+			t.Errorf("%d: dispatched to %s, expected %s", i, got, test.handler)
+		}
+	}
+
+	// removing a handler dispatches its traps to the subtree
+	mux.Handle(".1.3.6.1.6.3.1.1.5.3", nil)
+	if mux.HandleTrap(v2(".1.3.6.1.6.3.1.1.5.3"), &TrapInfo{}); got != "snmpTraps" {
+		t.Errorf("dispatched to %s after removing the handler, expected snmpTraps", got)
+	}
+
+	// and the zero TrapMux drops traps
+	(&TrapMux{}).HandleTrap(v2(".1.3.6.1.6.3.1.1.5.3"), &TrapInfo{})
+}
+
+func TestTrapListenerMux(t *testing.T) {
+	received := make(chan string, 1)
+	mux := &TrapMux{}
+	mux.HandleFunc(trapTestOid, func(s *SnmpPacket, info *TrapInfo) { received <- info.Community })
+	tl := NewTrapListener()
+	tl.OnTrap = mux.HandleTrap
+	tl.Params = &GoSNMP{}
+	addr := listenTestTraps(t, tl)
+	defer tl.Close()
+
+	x := &GoSNMP{
+		Target:    "127.0.0.1",
+		Port:      uint16(addr.Port),
+		Community: "public",
+		Version:   Version2c,
+		Timeout:   time.Second,
+		MaxOids:   MaxOids,
+	}
+	if err := x.Connect(); err != nil {
+		t.Fatalf("Connect() err: %v", err)
+	}
+	defer x.Conn.Close()
+	if _, err := x.SendTrap([]SnmpPDU{{Name: snmpTrapOID, Type: ObjectIdentifier, Value: trapTestOid}}); err != nil {
+		t.Fatalf("SendTrap() err: %v", err)
+	}
+	select {
+	case community := <-received:
+		if community != "public" {
+			t.Errorf("handler got community %q", community)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the trap")
+	}
+}
@@ -5,6 +5,8 @@
 package gosnmp
 
 import (
+	"context"
+	"errors"
 	"log"
 	"net"
 	"os" //"io/ioutil"
@@ -180,3 +182,386 @@ func TestSendTrap(t *testing.T) {
 
 	tl.Close()
 }
+
+// listenTestTraps starts tl listening on a local udp port, returning its
+// address
+func listenTestTraps(t *testing.T, tl *TrapListener) *net.UDPAddr {
+	t.Helper()
+	go tl.Listen("127.0.0.1:0")
+	tl.c.L.Lock()
+	for !tl.ready() {
+		tl.c.Wait()
+	}
+	tl.c.L.Unlock()
+	return tl.Addrs()[0].(*net.UDPAddr)
+}
+
+func TestSendTrapV3(t *testing.T) {
+	user := func(authPass string) *UsmSecurityParameters {
+		return &UsmSecurityParameters{
+			UserName:                 "user",
+			AuthenticationProtocol:   SHA,
+			AuthenticationPassphrase: authPass,
+			PrivacyProtocol:          AES,
+			PrivacyPassphrase:        "privpassphrase",
+		}
+	}
+	tl := NewTrapListener()
+	received := make(chan *SnmpPacket, 1)
+	tl.OnNewTrap = func(s *SnmpPacket, u *net.UDPAddr) { received <- s }
+	tl.Params = &GoSNMP{
+		Version:            Version3,
+		MsgFlags:           AuthPriv,
+		SecurityModel:      UserSecurityModel,
+		SecurityParameters: user("authpassphrase"),
+	}
+	addr := listenTestTraps(t, tl)
+	defer tl.Close()
+
+	engineID := "\x80\x00\x00\x00\x05sender"
+	for _, test := range []struct {
+		engineID string
+		flags    SnmpV3MsgFlags
+		authPass string
+		ok       bool
+	}{
+		{engineID, AuthPriv, "authpassphrase", true},
+		{"", AuthPriv | Reportable, "authpassphrase", true},
+		{engineID, AuthPriv, "wrongpassphrase", false},
+	} {
+		x := &GoSNMP{
+			Target:             "127.0.0.1",
+			Port:               uint16(addr.Port),
+			Version:            Version3,
+			MsgFlags:           test.flags,
+			SecurityModel:      UserSecurityModel,
+			SecurityParameters: user(test.authPass),
+			EngineID:           test.engineID,
+			EngineBoots:        3,
+			Timeout:            time.Second,
+			MaxOids:            MaxOids,
+		}
+		if err := x.Connect(); err != nil {
+			t.Fatalf("Connect() err: %v", err)
+		}
+		_, err := x.SendTrap([]SnmpPDU{{Name: trapTestOid, Type: OctetString, Value: trapTestPayload}})
+		x.Conn.Close()
+		if err != nil {
+			t.Fatalf("SendTrap() err: %v", err)
+		}
+
+		select {
+		case trap := <-received:
+			if !test.ok {
+				t.Fatalf("%s: received the trap", test.authPass)
+			}
+			sp := trap.SecurityParameters.(*UsmSecurityParameters)
+			if test.engineID != "" && sp.AuthoritativeEngineID != test.engineID || sp.AuthoritativeEngineID != x.localEngineID {
+				t.Errorf("trap's engine is %x, expected the sender's %x", sp.AuthoritativeEngineID, x.localEngineID)
+			}
+			if trap.ContextEngineID != x.localEngineID || sp.AuthoritativeEngineBoots != 3 || trap.MsgFlags != AuthPriv {
+				t.Errorf("trap's context engine is %x, boots %d and flags %s", trap.ContextEngineID, sp.AuthoritativeEngineBoots, trap.MsgFlags)
+			}
+			if v := trap.Variables[1]; v.Name != trapTestOid || string(v.Value.([]byte)) != trapTestPayload {
+				t.Errorf("trap's varbind is %v", v)
+			}
+		case <-time.After(time.Second):
+			if test.ok {
+				t.Fatalf("timed out waiting for the trap")
+			}
+		}
+	}
+	if got := tl.Stats().AuthErrors; got != 1 {
+		t.Errorf("got %d authentication errors, expected 1", got)
+	}
+}
+
+func TestTrapListenerV3Level(t *testing.T) {
+	user := &UsmSecurityParameters{
+		UserName:                 "user",
+		AuthenticationProtocol:   SHA,
+		AuthenticationPassphrase: "authpassphrase",
+		PrivacyProtocol:          AES,
+		PrivacyPassphrase:        "privpassphrase",
+	}
+	vacm := &Vacm{}
+	vacm.AddGroup("trappers", Version3, "user")
+	vacm.AddAccess(VacmAccess{Group: "trappers", NotifyView: "all"})
+	if err := vacm.AddView("all", ".1", nil, true); err != nil {
+		t.Fatalf("AddView() err: %v", err)
+	}
+
+	// a trap claiming to be from the user, but not authenticated, isn't
+	// received by a listener for authPriv traps, whatever lets it through
+	for name, setup := range map[string]func(tl *TrapListener){
+		"plain":      func(tl *TrapListener) {},
+		"vacm":       func(tl *TrapListener) { tl.Vacm = vacm },
+		"filter":     func(tl *TrapListener) { tl.Filter = &TrapFilter{AllowUsers: []string{"user"}} },
+		"timeliness": func(tl *TrapListener) { tl.StrictTimeliness = true },
+	} {
+		tl := NewTrapListener()
+		received := make(chan *SnmpPacket, 1)
+		tl.OnNewTrap = func(s *SnmpPacket, u *net.UDPAddr) { received <- s }
+		tl.Params = &GoSNMP{
+			Version:            Version3,
+			MsgFlags:           AuthPriv,
+			SecurityModel:      UserSecurityModel,
+			SecurityParameters: user.Copy(),
+		}
+		setup(tl)
+		addr := listenTestTraps(t, tl)
+
+		x := &GoSNMP{
+			Target:             "127.0.0.1",
+			Port:               uint16(addr.Port),
+			Version:            Version3,
+			MsgFlags:           NoAuthNoPriv,
+			SecurityModel:      UserSecurityModel,
+			SecurityParameters: &UsmSecurityParameters{UserName: "user"},
+			EngineID:           "\x80\x00\x00\x00\x05sender",
+			Timeout:            time.Second,
+			MaxOids:            MaxOids,
+		}
+		if err := x.Connect(); err != nil {
+			t.Fatalf("Connect() err: %v", err)
+		}
+		_, err := x.SendTrap([]SnmpPDU{{Name: trapTestOid, Type: OctetString, Value: trapTestPayload}})
+		x.Conn.Close()
+		if err != nil {
+			t.Fatalf("%s: SendTrap() err: %v", name, err)
+		}
+		select {
+		case trap := <-received:
+			t.Errorf("%s: received %s", name, trap)
+		case <-time.After(200 * time.Millisecond):
+		}
+		if got := tl.Stats().AuthErrors; got != 1 {
+			t.Errorf("%s: got %d authentication errors, expected 1", name, got)
+		}
+		tl.Close()
+	}
+
+	// nor are traps flagged as authenticated without a digest
+	sp := user.Copy().(*UsmSecurityParameters)
+	for _, params := range []string{"", "\x00\x01"} {
+		packet := &SnmpPacket{SecurityParameters: &UsmSecurityParameters{AuthenticationParameters: params}}
+		if ok, err := sp.isAuthentic([]byte("message"), packet); ok || err != nil {
+			t.Errorf("isAuthentic() with parameters %q got %t, %v", params, ok, err)
+		}
+	}
+}
+
+func TestSendInform(t *testing.T) {
+	// a receiver, acknowledging informs
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	go func() {
+		receiver := &GoSNMP{}
+		for {
+			var buf [4096]byte
+			n, remote, err := conn.ReadFromUDP(buf[:])
+			if err != nil {
+				return
+			}
+			inform, err := receiver.Decode(buf[:n])
+			if err != nil || inform.PDUType != InformRequest {
+				continue
+			}
+			inform.PDUType = GetResponse
+			out, err := receiver.Encode(inform)
+			if err == nil {
+				conn.WriteToUDP(out, remote)
+			}
+		}
+	}()
+
+	x := &GoSNMP{
+		Target:    "127.0.0.1",
+		Port:      uint16(conn.LocalAddr().(*net.UDPAddr).Port),
+		Community: "public",
+		Version:   Version2c,
+		Timeout:   time.Second,
+		MaxOids:   MaxOids,
+	}
+	if err = x.Connect(); err != nil {
+		t.Fatalf("Connect() err: %v", err)
+	}
+	defer x.Conn.Close()
+	result, err := x.SendInform([]SnmpPDU{{Name: trapTestOid, Type: OctetString, Value: trapTestPayload}})
+	if err != nil {
+		t.Fatalf("SendInform() err: %v", err)
+	}
+	if result.PDUType != GetResponse || len(result.Variables) != 2 || result.Variables[1].Name != trapTestOid {
+		t.Errorf("SendInform() got %s", result)
+	}
+
+	x.Version = Version1
+	if _, err = x.SendInform([]SnmpPDU{{Name: trapTestOid, Type: OctetString, Value: trapTestPayload}}); err == nil {
+		t.Errorf("SendInform() with SNMPv1 succeeded")
+	}
+}
+
+func TestTrapListenerWorkers(t *testing.T) {
+	for _, park := range []bool{false, true} {
+		tl := NewTrapListener()
+		release := make(chan struct{})
+		received := make(chan *SnmpPacket, 8)
+		tl.OnNewTrap = func(s *SnmpPacket, u *net.UDPAddr) {
+			<-release
+			received <- s
+		}
+		tl.Params = &GoSNMP{}
+		tl.Workers, tl.QueueSize, tl.ParkWhenFull = 2, 1, park
+		addr := listenTestTraps(t, tl)
+		x := &GoSNMP{
+			Target:    "127.0.0.1",
+			Port:      uint16(addr.Port),
+			Community: "public",
+			Version:   Version2c,
+			Timeout:   time.Second,
+			MaxOids:   MaxOids,
+		}
+		if err := x.Connect(); err != nil {
+			t.Fatalf("Connect() err: %v", err)
+		}
+
+		// the workers and the queue have room for 3 traps
+		for i := 0; i < 5; i++ {
+			if _, err := x.SendTrap([]SnmpPDU{{Name: trapTestOid, Type: OctetString, Value: trapTestPayload}}); err != nil {
+				t.Fatalf("SendTrap() err: %v", err)
+			}
+		}
+		want := Stats{PacketsReceived: 5, Dropped: 2}
+		if park {
+			want = Stats{PacketsReceived: 4}
+		}
+		for deadline := time.Now().Add(5 * time.Second); tl.Stats() != want && time.Now().Before(deadline); {
+			time.Sleep(10 * time.Millisecond)
+		}
+		if got := tl.Stats(); got != want {
+			t.Errorf("park %t: got %+v, expected %+v", park, got, want)
+		}
+
+		close(release)
+		n := 5 - int(want.Dropped)
+		for i := 0; i < n; i++ {
+			select {
+			case <-received:
+			case <-time.After(5 * time.Second):
+				t.Fatalf("park %t: timed out waiting for trap %d", park, i+1)
+			}
+		}
+		x.Conn.Close()
+		tl.Close()
+	}
+}
+
+func TestTrapListenerShutdown(t *testing.T) {
+	serve := func(ctx context.Context, tl *TrapListener) (*GoSNMP, chan error) {
+		conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		served := make(chan error, 1)
+		go func() { served <- tl.Serve(ctx, conn) }()
+		tl.c.L.Lock()
+		for !tl.ready() {
+			tl.c.Wait()
+		}
+		tl.c.L.Unlock()
+		x := &GoSNMP{
+			Target:    "127.0.0.1",
+			Port:      uint16(conn.LocalAddr().(*net.UDPAddr).Port),
+			Community: "public",
+			Version:   Version2c,
+			Timeout:   time.Second,
+			MaxOids:   MaxOids,
+		}
+		if err = x.Connect(); err != nil {
+			t.Fatalf("Connect() err: %v", err)
+		}
+		return x, served
+	}
+	pdus := []SnmpPDU{{Name: trapTestOid, Type: OctetString, Value: trapTestPayload}}
+
+	// Shutdown waits for the handlers, including those of queued traps
+	tl := NewTrapListener()
+	started, release, handled := make(chan struct{}, 2), make(chan struct{}), make(chan struct{}, 2)
+	tl.OnNewTrap = func(s *SnmpPacket, u *net.UDPAddr) {
+		started <- struct{}{}
+		<-release
+		handled <- struct{}{}
+	}
+	tl.Params = &GoSNMP{}
+	tl.Workers = 1
+	x, served := serve(context.Background(), tl)
+	defer x.Conn.Close()
+	for i := 0; i < 2; i++ {
+		if _, err := x.SendTrap(pdus); err != nil {
+			t.Fatalf("SendTrap() err: %v", err)
+		}
+	}
+	<-started
+	for deadline := time.Now().Add(5 * time.Second); tl.Stats().PacketsReceived < 2 && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := tl.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Shutdown() with a blocked handler err: %v", err)
+	}
+	close(release)
+	if err := tl.Shutdown(context.Background()); err != nil {
+		t.Errorf("Shutdown() err: %v", err)
+	}
+	if len(handled) != 2 {
+		t.Errorf("Shutdown() returned with %d of 2 traps handled", len(handled))
+	}
+	if err := <-served; err != nil {
+		t.Errorf("Serve() err: %v", err)
+	}
+
+	// Serve returns when its ctx is done
+	tl = NewTrapListener()
+	tl.Params = &GoSNMP{}
+	ctx, cancel = context.WithCancel(context.Background())
+	x, served = serve(ctx, tl)
+	defer x.Conn.Close()
+	cancel()
+	select {
+	case err := <-served:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Serve() err: %v, expected context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for Serve() to return")
+	}
+	if err := tl.Shutdown(context.Background()); err != nil {
+		t.Errorf("Shutdown() after Serve() returned err: %v", err)
+	}
+}
+
+func TestNotificationPDUs(t *testing.T) {
+	pdus := []SnmpPDU{{Name: trapTestOid, Type: OctetString, Value: trapTestPayload}}
+	got, err := notificationPDUs("SendTrap", pdus, nil)
+	if err != nil || len(got) != 2 || got[0].Type != TimeTicks || got[1].Name != trapTestOid {
+		t.Errorf("notificationPDUs() got %v, err %v", got, err)
+	}
+
+	// a sysUpTime given is the notification's
+	pdus = append([]SnmpPDU{{Name: sysUpTimeOid, Type: TimeTicks, Value: uint32(100)}}, pdus...)
+	if got, err = notificationPDUs("SendTrap", pdus, nil); err != nil || len(got) != 2 || got[0].Value != uint32(100) {
+		t.Errorf("notificationPDUs() with a sysUpTime got %v, err %v", got, err)
+	}
+	pdus[0].Value = 100
+	if _, err = notificationPDUs("SendTrap", pdus, nil); err == nil {
+		t.Error("notificationPDUs() with an int sysUpTime succeeded")
+	}
+	if _, err = notificationPDUs("SendTrap", nil, nil); err == nil {
+		t.Error("notificationPDUs() of no pdus succeeded")
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"fmt"
+)
+
+//
+// SNMPv1 traps, whose Trap-PDU differs from the later notifications
+//
+
+// The generic-trap values of SNMPv1 traps, RFC 1157 section 4.1.6. Those
+// of EnterpriseSpecific traps are identified by their specific-trap.
+const (
+	ColdStart = iota
+	WarmStart
+	LinkDown
+	LinkUp
+	AuthenticationFailure
+	EGPNeighborLoss
+	EnterpriseSpecific
+)
+
+// SnmpV1Trap is an SNMPv1 Trap-PDU (RFC 1157 section 4.1.6), which has
+// fields of its own rather than the snmpTrapOID varbind of SNMPv2
+// notifications
+type SnmpV1Trap struct {
+	// Community is the community of the trap's message
+	Community string
+
+	// Enterprise is the type of the object generating the trap, usually
+	// the sender's sysObjectID, eg ".1.3.6.1.4.1.8072.3.2.10"
+	Enterprise string
+
+	// AgentAddress is the address of the object generating the trap, eg
+	// "192.0.2.1"; the address it was received from may differ, eg if it
+	// was relayed
+	AgentAddress string
+
+	// GenericTrap is one of ColdStart to EnterpriseSpecific, and
+	// SpecificTrap identifies an EnterpriseSpecific trap
+	GenericTrap  int
+	SpecificTrap int
+
+	// Timestamp is the sender's sysUpTime when the trap was generated, in
+	// hundredths of a second
+	Timestamp uint32
+
+	Variables []SnmpPDU
+}
+
+// V1Trap returns the SNMPv1 trap a packet holds, or nil if it isn't an
+// SNMPv1 Trap-PDU
+func (packet *SnmpPacket) V1Trap() *SnmpV1Trap {
+	if packet.PDUType != Trap {
+		return nil
+	}
+	return &SnmpV1Trap{
+		Community:    packet.Community,
+		Enterprise:   oidToString(packet.Enterprise),
+		AgentAddress: packet.AgentAddr,
+		GenericTrap:  packet.GenericTrap,
+		SpecificTrap: packet.SpecificTrap,
+		Timestamp:    uint32(packet.Timestamp),
+		Variables:    packet.Variables,
+	}
+}
+
+// TrapOID returns the snmpTrapOID of the trap's SNMPv2 equivalent, as RFC
+// 3584 section 3.1 says: the snmpTraps oid of a generic trap, eg
+// ".1.3.6.1.6.3.1.1.5.3" for linkDown, or for an EnterpriseSpecific trap
+// the Enterprise, then 0 and the SpecificTrap
+func (trap *SnmpV1Trap) TrapOID() string {
+	if trap.GenericTrap >= ColdStart && trap.GenericTrap < EnterpriseSpecific {
+		return fmt.Sprintf(".1.3.6.1.6.3.1.1.5.%d", trap.GenericTrap+1)
+	}
+	return fmt.Sprintf("%s.0.%d", trap.Enterprise, trap.SpecificTrap)
+}
+
+// TrapOID returns the oid of the notification a trap or inform is: its
+// snmpTrapOID varbind, or for SNMPv1 traps SnmpV1Trap.TrapOID; or "" if it
+// has none
+func (packet *SnmpPacket) TrapOID() string {
+	return notificationOid(packet)
+}
+
+// SendSnmpV1Trap is SendV1Trap, for a trap's fields; its Community is
+// ignored, the session's being used
+func (x *GoSNMP) SendSnmpV1Trap(trap *SnmpV1Trap) error {
+	oid, err := ParseOid(trap.Enterprise)
+	if err != nil {
+		return fmt.Errorf("SendSnmpV1Trap enterprise: %w", err)
+	}
+	enterprise := make([]int, len(oid))
+	for i, n := range oid {
+		enterprise[i] = int(n)
+	}
+	_, err = x.sendV1Trap(trap.Variables, enterprise, trap.AgentAddress, trap.GenericTrap, trap.SpecificTrap, int(trap.Timestamp))
+	return err
+}
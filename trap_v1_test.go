@@ -0,0 +1,144 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestV1TrapOID(t *testing.T) {
+	tests := []struct {
+		trap SnmpV1Trap
+		oid  string
+	}{
+		{SnmpV1Trap{Enterprise: ".1.3.6.1.4.1.99999", GenericTrap: ColdStart}, ".1.3.6.1.6.3.1.1.5.1"},
+		{SnmpV1Trap{Enterprise: ".1.3.6.1.4.1.99999", GenericTrap: LinkDown}, ".1.3.6.1.6.3.1.1.5.3"},
+		{SnmpV1Trap{Enterprise: ".1.3.6.1.4.1.99999", GenericTrap: EnterpriseSpecific, SpecificTrap: 7},
+			".1.3.6.1.4.1.99999.0.7"},
+	}
+	for _, test := range tests {
+		if got := test.trap.TrapOID(); got != test.oid {
+			t.Errorf("TrapOID() of %+v got %s, expected %s", test.trap, got, test.oid)
+		}
+	}
+
+	if (&SnmpPacket{PDUType: SNMPv2Trap}).V1Trap() != nil {
+		t.Errorf("V1Trap() of an SNMPv2 trap isn't nil")
+	}
+}
+
+func TestTrapListenerV1(t *testing.T) {
+	tl := NewTrapListener()
+	received := make(chan *SnmpV1Trap, 1)
+	tl.OnNewTrap = func(s *SnmpPacket, u *net.UDPAddr) { t.Errorf("OnNewTrap() called for %s", s) }
+	tl.OnNewV1Trap = func(trap *SnmpV1Trap, u *net.UDPAddr) { received <- trap }
+	tl.Params = &GoSNMP{}
+	addr := listenTestTraps(t, tl)
+	defer tl.Close()
+	conn, err := net.DialUDP("udp", nil, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	variables := []SnmpPDU{{Name: ".1.3.6.1.2.1.2.2.1.1.3", Type: Integer, Value: 3}}
+	out, err := (&GoSNMP{}).Encode(&SnmpPacket{
+		Version:     Version1,
+		Community:   "public",
+		PDUType:     Trap,
+		Enterprise:  []int{1, 3, 6, 1, 4, 1, 8072, 3, 2, 10},
+		AgentAddr:   "192.0.2.1",
+		GenericTrap: LinkDown,
+		Timestamp:   300,
+		Variables:   variables,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = conn.Write(out); err != nil {
+		t.Fatal(err)
+	}
+
+	want := &SnmpV1Trap{
+		Community:    "public",
+		Enterprise:   ".1.3.6.1.4.1.8072.3.2.10",
+		AgentAddress: "192.0.2.1",
+		GenericTrap:  LinkDown,
+		Timestamp:    300,
+		Variables:    variables,
+	}
+	select {
+	case trap := <-received:
+		for i := range trap.Variables {
+			trap.Variables[i].Logger, trap.Variables[i].NameOid = nil, nil
+		}
+		if !reflect.DeepEqual(trap, want) {
+			t.Errorf("got %+v, expected %+v", trap, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the trap")
+	}
+}
+
+func TestSendSnmpV1Trap(t *testing.T) {
+	tl := NewTrapListener()
+	received := make(chan *SnmpV1Trap, 1)
+	tl.OnNewV1Trap = func(trap *SnmpV1Trap, u *net.UDPAddr) { received <- trap }
+	tl.Params = &GoSNMP{}
+	addr := listenTestTraps(t, tl)
+	defer tl.Close()
+	x := &GoSNMP{
+		Target:    "127.0.0.1",
+		Port:      uint16(addr.Port),
+		Community: "public",
+		Version:   Version1,
+		Timeout:   time.Second,
+		MaxOids:   MaxOids,
+	}
+	if err := x.Connect(); err != nil {
+		t.Fatalf("Connect() err: %v", err)
+	}
+	defer x.Conn.Close()
+
+	tests := []SnmpV1Trap{
+		{Enterprise: ".1.3.6.1.4.1.8072.3.2.10", AgentAddress: "192.0.2.1", GenericTrap: ColdStart, Timestamp: 1},
+		{Enterprise: ".1.3.6.1.4.1.99999", GenericTrap: EnterpriseSpecific, SpecificTrap: 300, Timestamp: 1 << 31,
+			Variables: []SnmpPDU{{Name: ".1.3.6.1.4.1.99999.1.0", Type: OctetString, Value: []byte("value")}}},
+	}
+	for _, test := range tests {
+		if err := x.SendSnmpV1Trap(&test); err != nil {
+			t.Fatalf("SendSnmpV1Trap() err: %v", err)
+		}
+		want := test
+		want.Community = "public"
+		if want.AgentAddress == "" {
+			want.AgentAddress = "127.0.0.1"
+		}
+		select {
+		case trap := <-received:
+			for i := range trap.Variables {
+				trap.Variables[i].Logger, trap.Variables[i].NameOid = nil, nil
+			}
+			if len(trap.Variables) == 0 {
+				trap.Variables = want.Variables
+			}
+			if !reflect.DeepEqual(trap, &want) {
+				t.Errorf("got %+v, expected %+v", trap, &want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for the trap")
+		}
+	}
+
+	if err := x.SendSnmpV1Trap(&SnmpV1Trap{Enterprise: ".1.3.6.1.4.1.99999", GenericTrap: 7}); err == nil {
+		t.Errorf("SendSnmpV1Trap() of generic-trap 7 succeeded")
+	}
+	if err := x.SendSnmpV1Trap(&SnmpV1Trap{Enterprise: ".1.3.6.1.4.1.99999", AgentAddress: "::1"}); err == nil {
+		t.Errorf("SendSnmpV1Trap() from an IPv6 address succeeded")
+	}
+}
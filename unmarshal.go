@@ -0,0 +1,357 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+	"net/netip"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Unmarshal copies the values of pdus into the struct that target points
+// to, much as encoding/json does. Fields are tagged with the oid of the
+// varbind they're set from:
+//
+//	type System struct {
+//		Descr  string        `snmp:".1.3.6.1.2.1.1.1.0"`
+//		UpTime time.Duration `snmp:".1.3.6.1.2.1.1.3.0"`
+//		Name   string        `snmp:".1.3.6.1.2.1.1.5.0"`
+//	}
+//
+// A field that is a slice of structs is a table, eg from a BulkWalk. The
+// fields of the row struct are tagged with the oids of the columns, and a
+// field tagged `snmp:",index"` is set to the index of the row (as a string
+// such as "7.97.98", an Oid, or an integer for a single number index). Rows
+// are appended in the order their indexes first appear.
+//
+// Values are converted to the type of the field: integers to any integer
+// type that can hold them (with an error if the value is out of range),
+// OctetStrings to string or []byte (and types like Bits based on it),
+// TimeTicks to time.Duration, DateAndTime to time.Time, TruthValue to
+// bool, IpAddress to net.IP or netip.Addr, MacAddress to net.HardwareAddr
+// and OBJECT IDENTIFIERs to string or Oid. Pointer fields are allocated
+// when there is a value for them, so a nil pointer tells a value that
+// wasn't returned from a zero one. Fields without a varbind, or whose
+// varbind is a NoSuchObject, NoSuchInstance or EndOfMibView exception, are
+// left unchanged.
+func Unmarshal(target interface{}, pdus []SnmpPDU) error {
+	return UnmarshalWithResolver(target, pdus, nil)
+}
+
+// UnmarshalWithResolver is Unmarshal, with resolve translating each tag to
+// the numeric oid it names. This allows tags to be object names, eg
+// `snmp:"SNMPv2-MIB::sysName.0"` (see Tree.Unmarshal in the mib package).
+func UnmarshalWithResolver(target interface{}, pdus []SnmpPDU, resolve func(tag string) (string, error)) error {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("Unmarshal: target must be a non-nil pointer to a struct, got %T", target)
+	}
+	d := unmarshaller{
+		pdus:    pdus,
+		byName:  make(map[string]*SnmpPDU, len(pdus)),
+		resolve: resolve,
+	}
+	for i := range pdus {
+		d.byName["."+trimOidDot(pdus[i].Name)] = &pdus[i]
+	}
+	return d.unmarshalStruct(v.Elem())
+}
+
+type unmarshaller struct {
+	pdus    []SnmpPDU
+	byName  map[string]*SnmpPDU
+	resolve func(tag string) (string, error)
+}
+
+const indexTag = ",index"
+
+var (
+	durationType     = reflect.TypeOf(time.Duration(0))
+	timeType         = reflect.TypeOf(time.Time{})
+	ipType           = reflect.TypeOf(net.IP(nil))
+	addrType         = reflect.TypeOf(netip.Addr{})
+	hardwareAddrType = reflect.TypeOf(net.HardwareAddr(nil))
+	oidType          = reflect.TypeOf(Oid(nil))
+)
+
+// oid returns the numeric oid a tag refers to, with a leading dot
+func (d *unmarshaller) oid(tag string) (string, error) {
+	if d.resolve != nil {
+		oid, err := d.resolve(tag)
+		if err != nil {
+			return "", fmt.Errorf("Unmarshal: %w", err)
+		}
+		tag = oid
+	}
+	if _, err := ParseOid(tag); err != nil {
+		return "", fmt.Errorf("Unmarshal: invalid tag %q: %w", tag, err)
+	}
+	return "." + trimOidDot(tag), nil
+}
+
+func (d *unmarshaller) unmarshalStruct(v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("snmp")
+		fv := v.Field(i)
+		switch {
+		case tag == "-":
+		case f.Anonymous && f.Type.Kind() == reflect.Struct && tag == "":
+			// like encoding/json, the fields of embedded structs are set
+			// even if the struct type is unexported
+			if err := d.unmarshalStruct(fv); err != nil {
+				return err
+			}
+		case f.PkgPath != "":
+		case isTable(f.Type):
+			if err := d.unmarshalTable(fv); err != nil {
+				return err
+			}
+		case tag == "":
+		default:
+			oid, err := d.oid(tag)
+			if err != nil {
+				return err
+			}
+			if pdu, ok := d.byName[oid]; ok {
+				if err := setValue(fv, pdu); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// isTable reports whether t is a slice of row structs, rather than of a
+// struct that's a value like time.Time
+func isTable(t reflect.Type) bool {
+	if t.Kind() != reflect.Slice || t.Elem().Kind() != reflect.Struct {
+		return false
+	}
+	e := t.Elem()
+	return e != timeType && e != addrType
+}
+
+func (d *unmarshaller) unmarshalTable(v reflect.Value) error {
+	rowType := v.Type().Elem()
+	index := -1
+	columns := make(map[int]string)
+	for i := 0; i < rowType.NumField(); i++ {
+		f := rowType.Field(i)
+		tag := f.Tag.Get("snmp")
+		switch {
+		case f.PkgPath != "" || tag == "" || tag == "-":
+		case tag == indexTag:
+			index = i
+		default:
+			oid, err := d.oid(tag)
+			if err != nil {
+				return err
+			}
+			columns[i] = oid + "."
+		}
+	}
+	if len(columns) == 0 {
+		return nil
+	}
+
+	rows := make(map[string]int)
+	for i := range d.pdus {
+		pdu := &d.pdus[i]
+		name := "." + trimOidDot(pdu.Name)
+		for field, prefix := range columns {
+			if !strings.HasPrefix(name, prefix) || len(name) == len(prefix) {
+				continue
+			}
+			suffix := name[len(prefix):]
+			row, ok := rows[suffix]
+			if !ok {
+				row = v.Len()
+				rows[suffix] = row
+				v.Set(reflect.Append(v, reflect.Zero(rowType)))
+				if index >= 0 {
+					if err := setIndex(v.Index(row).Field(index), suffix); err != nil {
+						return err
+					}
+				}
+			}
+			if err := setValue(v.Index(row).Field(field), pdu); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func setIndex(v reflect.Value, index string) error {
+	if v.Type() == oidType {
+		oid, err := ParseOid(index)
+		if err != nil {
+			return fmt.Errorf("Unmarshal: invalid index %s: %w", index, err)
+		}
+		v.Set(reflect.ValueOf(oid))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(index)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n, err := strconv.ParseInt(index, 10, 64); err == nil && !v.OverflowInt(n) {
+			v.SetInt(n)
+			return nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if n, err := strconv.ParseUint(index, 10, 64); err == nil && !v.OverflowUint(n) {
+			v.SetUint(n)
+			return nil
+		}
+	}
+	return fmt.Errorf("Unmarshal: cannot set index %s in a %s", index, v.Type())
+}
+
+// integerValue returns the value of a varbind holding a number
+func integerValue(value interface{}) (*big.Int, bool) {
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return big.NewInt(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return new(big.Int).SetUint64(rv.Uint()), true
+	}
+	return nil, false
+}
+
+func setValue(v reflect.Value, pdu *SnmpPDU) error {
+	switch pdu.Type {
+	case Null, NoSuchObject, NoSuchInstance, EndOfMibView:
+		return nil
+	}
+	if v.Kind() == reflect.Ptr {
+		p := reflect.New(v.Type().Elem())
+		if err := setValue(p.Elem(), pdu); err != nil {
+			return err
+		}
+		v.Set(p)
+		return nil
+	}
+
+	cannot := func(reason string) error {
+		return fmt.Errorf("Unmarshal: cannot set %s %s in a %s%s", pdu.Type, pdu.Name, v.Type(), reason)
+	}
+	convert := func(x interface{}, err error) error {
+		if err != nil {
+			return cannot(": " + err.Error())
+		}
+		v.Set(reflect.ValueOf(x).Convert(v.Type()))
+		return nil
+	}
+
+	switch v.Type() {
+	case durationType:
+		return convert(TimeTicksToDuration(pdu.Value))
+	case timeType:
+		return convert(ToDateAndTime(pdu.Value))
+	case hardwareAddrType:
+		return convert(ToHardwareAddr(pdu.Value))
+	case oidType:
+		switch value := pdu.Value.(type) {
+		case string:
+			return convert(ParseOid(value))
+		case Oid:
+			return convert(append(Oid(nil), value...), nil)
+		}
+		return cannot("")
+	case ipType, addrType:
+		var ip netip.Addr
+		switch value := pdu.Value.(type) {
+		case string:
+			ip, _ = netip.ParseAddr(value)
+		case []byte:
+			ip, _ = netip.AddrFromSlice(value)
+		}
+		if !ip.IsValid() {
+			return cannot("")
+		}
+		if v.Type() == ipType {
+			return convert(net.IP(ip.AsSlice()), nil)
+		}
+		return convert(ip, nil)
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		switch value := pdu.Value.(type) {
+		case []byte:
+			v.SetString(string(value))
+		case string:
+			v.SetString(value)
+		case Oid:
+			v.SetString(value.String())
+		default:
+			if _, ok := integerValue(value); !ok {
+				return cannot("")
+			}
+			v.SetString(fmt.Sprint(value))
+		}
+		return nil
+	case reflect.Slice:
+		b, ok := pdu.Value.([]byte)
+		if !ok || v.Type().Elem().Kind() != reflect.Uint8 {
+			return cannot("")
+		}
+		return convert(append([]byte(nil), b...), nil)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, ok := integerValue(pdu.Value)
+		if !ok {
+			return cannot("")
+		}
+		if !n.IsInt64() || v.OverflowInt(n.Int64()) {
+			return cannot(": value " + n.String() + " out of range")
+		}
+		v.SetInt(n.Int64())
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, ok := integerValue(pdu.Value)
+		if !ok {
+			return cannot("")
+		}
+		if !n.IsUint64() || v.OverflowUint(n.Uint64()) {
+			return cannot(": value " + n.String() + " out of range")
+		}
+		v.SetUint(n.Uint64())
+		return nil
+	case reflect.Float32, reflect.Float64:
+		switch value := pdu.Value.(type) {
+		case float32:
+			v.SetFloat(float64(value))
+		case float64:
+			v.SetFloat(value)
+		default:
+			n, ok := integerValue(value)
+			if !ok {
+				return cannot("")
+			}
+			f, _ := new(big.Float).SetInt(n).Float64()
+			v.SetFloat(f)
+		}
+		return nil
+	case reflect.Bool:
+		return convert(ToTruthValue(pdu.Value))
+	case reflect.Interface:
+		if v.NumMethod() == 0 && pdu.Value != nil {
+			v.Set(reflect.ValueOf(pdu.Value))
+			return nil
+		}
+	}
+	return cannot("")
+}
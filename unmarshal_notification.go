@@ -0,0 +1,166 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+const trapTag = ",trap"
+
+// UnmarshalNotification copies the varbinds of a trap or inform into the
+// struct that target points to, as Unmarshal does, but with fields tagged
+// with the oids of the notification's objects rather than of instances: a
+// varbind is set in the field of the object it is an instance of, eg
+//
+//	type LinkDown struct {
+//		Trap        string        `snmp:",trap"`
+//		UpTime      time.Duration `snmp:".1.3.6.1.2.1.1.3.0"`
+//		IfIndex     int           `snmp:".1.3.6.1.2.1.2.2.1.1,index"`
+//		AdminStatus int           `snmp:".1.3.6.1.2.1.2.2.1.7"`
+//		OperStatus  int           `snmp:".1.3.6.1.2.1.2.2.1.8"`
+//	}
+//
+// sets OperStatus from the varbind ifOperStatus.3. A field tagged with an
+// object's oid and ",index" is set to the instance of the object's
+// varbind, converted as the index of a table row is, so IfIndex is set to
+// 3; one tagged `snmp:",index"` is set to the instance of the first
+// varbind that is an instance of a field's object. A field tagged
+// `snmp:",trap"` is set to the notification's oid (see
+// SnmpPacket.TrapOID).
+func UnmarshalNotification(target interface{}, packet *SnmpPacket) error {
+	return UnmarshalNotificationWithResolver(target, packet, nil)
+}
+
+// UnmarshalNotificationWithResolver is UnmarshalNotification, with resolve
+// translating each tag to the numeric oid it names, as for
+// UnmarshalWithResolver. resolve is also given the names of untagged
+// fields, eg "IfIndex"; fields whose names it can't resolve are skipped.
+// See Tree.UnmarshalNotification in the mib package, which resolves them
+// as the objects of the notification's MIB definition.
+func UnmarshalNotificationWithResolver(target interface{}, packet *SnmpPacket, resolve func(tag string) (string, error)) error {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("UnmarshalNotification: target must be a non-nil pointer to a struct, got %T", target)
+	}
+	d := notificationUnmarshaller{
+		unmarshaller: unmarshaller{
+			pdus:    packet.Variables,
+			byName:  make(map[string]*SnmpPDU, len(packet.Variables)),
+			resolve: resolve,
+		},
+		trapOid: packet.TrapOID(),
+	}
+	for i := range packet.Variables {
+		d.byName["."+trimOidDot(packet.Variables[i].Name)] = &packet.Variables[i]
+	}
+	if err := d.unmarshalFields(v.Elem()); err != nil {
+		return err
+	}
+	if d.instance == "" {
+		return nil
+	}
+	for _, fv := range d.indexes {
+		if err := setIndex(fv, d.instance); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type notificationUnmarshaller struct {
+	unmarshaller
+	trapOid string
+
+	// the fields tagged `snmp:",index"`, and the instance they are set to:
+	// that of the first varbind bound to a field as an instance
+	indexes  []reflect.Value
+	instance string
+}
+
+func (d *notificationUnmarshaller) unmarshalFields(v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("snmp")
+		fv := v.Field(i)
+		switch {
+		case tag == "-":
+		case f.Anonymous && f.Type.Kind() == reflect.Struct && tag == "":
+			if err := d.unmarshalFields(fv); err != nil {
+				return err
+			}
+		case f.PkgPath != "":
+		case isTable(f.Type):
+			if err := d.unmarshalTable(fv); err != nil {
+				return err
+			}
+		case tag == trapTag:
+			if d.trapOid != "" {
+				if err := setValue(fv, &SnmpPDU{Name: snmpTrapOID, Type: ObjectIdentifier, Value: d.trapOid}); err != nil {
+					return err
+				}
+			}
+		case tag == indexTag:
+			d.indexes = append(d.indexes, fv)
+		case tag == "":
+			if d.resolve == nil {
+				continue
+			}
+			oid, err := d.resolve(f.Name)
+			if err != nil {
+				continue
+			}
+			if _, err = ParseOid(oid); err != nil {
+				continue
+			}
+			if err = d.bind(fv, "."+trimOidDot(oid), false); err != nil {
+				return err
+			}
+		default:
+			name, index := tag, false
+			if strings.HasSuffix(tag, indexTag) {
+				name, index = strings.TrimSuffix(tag, indexTag), true
+			}
+			oid, err := d.oid(name)
+			if err != nil {
+				return err
+			}
+			if err = d.bind(fv, oid, index); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// bind sets a field from the varbind of the object oid: the varbind named
+// oid, or the first that is an instance of it; or if index, to the
+// varbind's instance
+func (d *notificationUnmarshaller) bind(fv reflect.Value, oid string, index bool) error {
+	if pdu, ok := d.byName[oid]; ok {
+		if index {
+			return nil
+		}
+		return setValue(fv, pdu)
+	}
+	for i := range d.pdus {
+		name := "." + trimOidDot(d.pdus[i].Name)
+		if !strings.HasPrefix(name, oid+".") {
+			continue
+		}
+		instance := name[len(oid)+1:]
+		if d.instance == "" {
+			d.instance = instance
+		}
+		if index {
+			return setIndex(fv, instance)
+		}
+		return setValue(fv, &d.pdus[i])
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+type unmarshalLinkDown struct {
+	Trap        string        `snmp:",trap"`
+	UpTime      time.Duration `snmp:".1.3.6.1.2.1.1.3.0"`
+	IfIndex     int           `snmp:".1.3.6.1.2.1.2.2.1.1,index"`
+	Index       Oid           `snmp:",index"`
+	AdminStatus int           `snmp:".1.3.6.1.2.1.2.2.1.7"`
+	OperStatus  *int          `snmp:".1.3.6.1.2.1.2.2.1.8"`
+	Descr       string        `snmp:".1.3.6.1.2.1.2.2.1.2"`
+	Untagged    string
+}
+
+func TestUnmarshalNotification(t *testing.T) {
+	varbinds := []SnmpPDU{
+		{Name: ".1.3.6.1.2.1.2.2.1.1.3", Type: Integer, Value: 3},
+		{Name: ".1.3.6.1.2.1.2.2.1.7.3", Type: Integer, Value: 1},
+		{Name: ".1.3.6.1.2.1.2.2.1.8.3", Type: Integer, Value: 2},
+	}
+	v2 := &SnmpPacket{
+		Version: Version2c,
+		PDUType: SNMPv2Trap,
+		Variables: append([]SnmpPDU{
+			{Name: ".1.3.6.1.2.1.1.3.0", Type: TimeTicks, Value: uint32(1234)},
+			{Name: snmpTrapOID, Type: ObjectIdentifier, Value: ".1.3.6.1.6.3.1.1.5.3"},
+		}, varbinds...),
+	}
+	v1 := &SnmpPacket{Version: Version1, PDUType: Trap, GenericTrap: LinkDown, Timestamp: 1234, Variables: varbinds}
+
+	for _, packet := range []*SnmpPacket{v2, v1} {
+		var got unmarshalLinkDown
+		if err := UnmarshalNotification(&got, packet); err != nil {
+			t.Fatalf("%s: UnmarshalNotification() err: %v", packet.Version, err)
+		}
+		if got.Trap != ".1.3.6.1.6.3.1.1.5.3" || got.IfIndex != 3 || !got.Index.Equal(Oid{3}) ||
+			got.AdminStatus != 1 || got.OperStatus == nil || *got.OperStatus != 2 || got.Descr != "" {
+			t.Errorf("%s: got %+v", packet.Version, got)
+		}
+		if want := 12340 * time.Millisecond; packet.Version == Version2c && got.UpTime != want {
+			t.Errorf("UpTime is %s, expected %s", got.UpTime, want)
+		}
+	}
+
+	// untagged fields are resolved by their names
+	var got unmarshalLinkDown
+	err := UnmarshalNotificationWithResolver(&got, v2, func(tag string) (string, error) {
+		if tag == "Untagged" {
+			return ".1.3.6.1.2.1.2.2.1.1", nil
+		}
+		if tag[0] == '.' {
+			return tag, nil
+		}
+		return "", fmt.Errorf("unknown %s", tag)
+	})
+	if err != nil || got.Untagged != "3" || got.AdminStatus != 1 {
+		t.Errorf("UnmarshalNotificationWithResolver() got %+v, err %v", got, err)
+	}
+
+	var bad struct {
+		Status string `snmp:".1.3.6.1.2.1.2.2.1.7"`
+	}
+	if err := UnmarshalNotification(&bad, v2); err != nil || bad.Status != "1" {
+		t.Errorf("got %+v, err %v", bad, err)
+	}
+	var wrong struct {
+		Status []string `snmp:".1.3.6.1.2.1.2.2.1.7"`
+	}
+	if err := UnmarshalNotification(&wrong, v2); err == nil || !strings.Contains(err.Error(), "cannot set") {
+		t.Errorf("got err %v, expected cannot set", err)
+	}
+	if err := UnmarshalNotification(got, v2); err == nil {
+		t.Error("UnmarshalNotification() of a struct succeeded")
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"net"
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type unmarshalSystem struct {
+	Descr    string        `snmp:".1.3.6.1.2.1.1.1.0"`
+	ObjectID Oid           `snmp:"1.3.6.1.2.1.1.2.0"`
+	UpTime   time.Duration `snmp:".1.3.6.1.2.1.1.3.0"`
+	Contact  *string       `snmp:".1.3.6.1.2.1.1.4.0"`
+	Location *string       `snmp:".1.3.6.1.2.1.1.6.0"`
+	Services uint8         `snmp:".1.3.6.1.2.1.1.7.0"`
+	Ignored  string        `snmp:"-"`
+}
+
+type unmarshalInterface struct {
+	Index       int              `snmp:",index"`
+	Descr       string           `snmp:".1.3.6.1.2.1.2.2.1.2"`
+	Mtu         int32            `snmp:".1.3.6.1.2.1.2.2.1.4"`
+	PhysAddress net.HardwareAddr `snmp:".1.3.6.1.2.1.2.2.1.6"`
+	InOctets    uint64           `snmp:".1.3.6.1.2.1.2.2.1.10"`
+	Raw         interface{}      `snmp:".1.3.6.1.2.1.2.2.1.11"`
+}
+
+type unmarshalAddress struct {
+	Index   Oid        `snmp:",index"`
+	Addr    netip.Addr `snmp:".1.3.6.1.2.1.4.20.1.1"`
+	Mask    net.IP     `snmp:".1.3.6.1.2.1.4.20.1.3"`
+	Enabled bool       `snmp:".1.3.6.1.2.1.4.20.1.99"`
+}
+
+type unmarshalDevice struct {
+	unmarshalSystem
+	Interfaces []unmarshalInterface
+	Addresses  []unmarshalAddress
+	Load       float32   `snmp:".1.3.6.1.4.1.2021.10.1.6.1"`
+	Capability Bits      `snmp:".1.3.6.1.4.1.99999.1"`
+	Boot       time.Time `snmp:".1.3.6.1.4.1.99999.2"`
+}
+
+func TestUnmarshalStruct(t *testing.T) {
+	pdus := []SnmpPDU{
+		{Name: ".1.3.6.1.2.1.1.1.0", Type: OctetString, Value: []byte("Linux router")},
+		{Name: ".1.3.6.1.2.1.1.2.0", Type: ObjectIdentifier, Value: ".1.3.6.1.4.1.8072.3.2.10"},
+		{Name: ".1.3.6.1.2.1.1.3.0", Type: TimeTicks, Value: 12345},
+		{Name: ".1.3.6.1.2.1.1.4.0", Type: OctetString, Value: []byte("noc")},
+		{Name: ".1.3.6.1.2.1.1.6.0", Type: NoSuchInstance},
+		{Name: ".1.3.6.1.2.1.1.7.0", Type: Integer, Value: 72},
+		{Name: ".1.3.6.1.2.1.2.2.1.2.1", Type: OctetString, Value: []byte("lo")},
+		{Name: ".1.3.6.1.2.1.2.2.1.2.2", Type: OctetString, Value: []byte("eth0")},
+		{Name: ".1.3.6.1.2.1.2.2.1.4.1", Type: Integer, Value: 65536},
+		{Name: ".1.3.6.1.2.1.2.2.1.4.2", Type: Integer, Value: 1500},
+		{Name: ".1.3.6.1.2.1.2.2.1.6.2", Type: OctetString, Value: []byte{0, 0x1b, 0x21, 0x3c, 0x4d, 0x5e}},
+		{Name: ".1.3.6.1.2.1.2.2.1.10.1", Type: Counter32, Value: uint(100)},
+		{Name: ".1.3.6.1.2.1.2.2.1.10.2", Type: Counter32, Value: uint(200)},
+		{Name: ".1.3.6.1.2.1.2.2.1.11.2", Type: Counter32, Value: uint(7)},
+		{Name: ".1.3.6.1.2.1.4.20.1.1.192.0.2.1", Type: IPAddress, Value: "192.0.2.1"},
+		{Name: ".1.3.6.1.2.1.4.20.1.3.192.0.2.1", Type: IPAddress, Value: "255.255.255.0"},
+		{Name: ".1.3.6.1.2.1.4.20.1.99.192.0.2.1", Type: Integer, Value: 1},
+		{Name: ".1.3.6.1.4.1.2021.10.1.6.1", Type: OpaqueFloat, Value: float32(0.25)},
+		{Name: ".1.3.6.1.4.1.99999.1", Type: OctetString, Value: []byte{0xa0}},
+		{Name: ".1.3.6.1.4.1.99999.2", Type: OctetString, Value: []byte{0x07, 0xe2, 1, 2, 3, 4, 5, 0}},
+	}
+
+	location := "unchanged"
+	device := unmarshalDevice{}
+	device.Location = &location
+	device.Ignored = "kept"
+	if err := Unmarshal(&device, pdus); err != nil {
+		t.Fatal(err)
+	}
+
+	contact := "noc"
+	want := unmarshalDevice{
+		unmarshalSystem: unmarshalSystem{
+			Descr:    "Linux router",
+			ObjectID: Oid{1, 3, 6, 1, 4, 1, 8072, 3, 2, 10},
+			UpTime:   123450 * time.Millisecond,
+			Contact:  &contact,
+			Location: &location,
+			Services: 72,
+			Ignored:  "kept",
+		},
+		Interfaces: []unmarshalInterface{
+			{Index: 1, Descr: "lo", Mtu: 65536, InOctets: 100},
+			{Index: 2, Descr: "eth0", Mtu: 1500, PhysAddress: net.HardwareAddr{0, 0x1b, 0x21, 0x3c, 0x4d, 0x5e}, InOctets: 200, Raw: uint(7)},
+		},
+		Addresses: []unmarshalAddress{
+			{Index: Oid{192, 0, 2, 1}, Addr: netip.MustParseAddr("192.0.2.1"), Mask: net.IP{255, 255, 255, 0}, Enabled: true},
+		},
+		Load:       0.25,
+		Capability: Bits{0xa0},
+		Boot:       time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	if !reflect.DeepEqual(device, want) {
+		t.Errorf("got  %+v\nwant %+v", device, want)
+	}
+	if location != "unchanged" {
+		t.Errorf("NoSuchInstance changed Location to %q", location)
+	}
+}
+
+func TestUnmarshalStructErrors(t *testing.T) {
+	tests := []struct {
+		target interface{}
+		pdu    SnmpPDU
+		err    string
+	}{
+		{
+			struct{}{},
+			SnmpPDU{}, "non-nil pointer to a struct",
+		},
+		{
+			&struct {
+				X int8 `snmp:".1.3.6.1.2.1.1.7.0"`
+			}{},
+			SnmpPDU{Name: ".1.3.6.1.2.1.1.7.0", Type: Integer, Value: 300}, "out of range",
+		},
+		{
+			&struct {
+				X uint `snmp:".1.3.6.1.2.1.1.7.0"`
+			}{},
+			SnmpPDU{Name: ".1.3.6.1.2.1.1.7.0", Type: Integer, Value: -1}, "out of range",
+		},
+		{
+			&struct {
+				X int `snmp:".1.3.6.1.2.1.1.5.0"`
+			}{},
+			SnmpPDU{Name: ".1.3.6.1.2.1.1.5.0", Type: OctetString, Value: []byte("x")}, "cannot set OctetString",
+		},
+		{
+			&struct {
+				X int `snmp:"sysName.0"`
+			}{},
+			SnmpPDU{}, "invalid tag",
+		},
+		{
+			&struct {
+				Rows []struct {
+					Index int    `snmp:",index"`
+					Name  string `snmp:".1.3.6.1.2.1.31.1.1.1.1"`
+				}
+			}{},
+			SnmpPDU{Name: ".1.3.6.1.2.1.31.1.1.1.1.1.2", Type: OctetString, Value: []byte("x")}, "cannot set index 1.2",
+		},
+	}
+	for i, test := range tests {
+		err := Unmarshal(test.target, []SnmpPDU{test.pdu})
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%d: got error %v, want %q", i, err, test.err)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"errors"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// closedPort returns a udp port of 127.0.0.1 that nothing listens on
+func closedPort(t *testing.T) int {
+	t.Helper()
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket() err: %v", err)
+	}
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).Port
+}
+
+func TestPortUnreachable(t *testing.T) {
+	x := &GoSNMP{
+		Target:    "127.0.0.1",
+		Port:      uint16(closedPort(t)),
+		Community: "public",
+		Version:   Version2c,
+		Timeout:   5 * time.Second,
+		Retries:   3,
+	}
+	if err := x.Connect(); err != nil {
+		t.Fatalf("Connect() err: %v", err)
+	}
+	defer x.Conn.Close()
+	start := time.Now()
+	_, err := x.Get([]string{testSysDescr})
+	if !errors.Is(err, ErrPortUnreachable) || errors.Is(err, ErrHostUnreachable) {
+		t.Fatalf("Get() err: %v, expected ErrPortUnreachable", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Get() took %s to fail", elapsed)
+	}
+	if sent := x.Stats().PacketsSent; sent != 1 {
+		t.Errorf("sent %d packets, expected no retries", sent)
+	}
+}
+
+func TestRedialOnUnreachable(t *testing.T) {
+	port := closedPort(t)
+	a, _ := newTestAgent(t)
+	defer a.Close()
+	x := &GoSNMP{
+		Target:              "127.0.0.1",
+		Port:                uint16(port),
+		Community:           "public",
+		Version:             Version2c,
+		Timeout:             5 * time.Second,
+		Retries:             3,
+		RedialOnUnreachable: true,
+	}
+	if err := x.Connect(); err != nil {
+		t.Fatalf("Connect() err: %v", err)
+	}
+	defer x.Conn.Close()
+	first := x.Conn.LocalAddr().String()
+
+	// the agent starts after the first attempt is refused
+	x.OnRetry = func(packet *SnmpPacket, attempt int, err error) {
+		if attempt != 2 {
+			return
+		}
+		if !errors.Is(err, ErrPortUnreachable) {
+			t.Errorf("attempt 1 err: %v, expected ErrPortUnreachable", err)
+		}
+		conn, err := net.ListenPacket("udp4", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+		if err != nil {
+			t.Errorf("ListenPacket() err: %v", err)
+			return
+		}
+		go a.Serve(conn)
+	}
+	if _, err := x.Get([]string{testSysDescr}); err != nil {
+		t.Fatalf("Get() err: %v", err)
+	}
+	if x.Conn.LocalAddr().String() == first {
+		t.Errorf("Conn wasn't redialled")
+	}
+}
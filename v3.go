@@ -10,8 +10,12 @@ package gosnmp
 
 import (
 	"bytes"
+	"context"
+	crand "crypto/rand"
 	"encoding/binary"
 	"fmt"
+	"sync"
+	"time"
 )
 
 // SnmpV3MsgFlags contains various message flags to describe Authentication, Privacy, and whether a report PDU must be sent.
@@ -28,15 +32,18 @@ const (
 // SnmpV3SecurityModel describes the security model used by a SnmpV3 connection
 type SnmpV3SecurityModel uint8
 
-// UserSecurityModel is the only SnmpV3SecurityModel currently implemented.
+// UserSecurityModel is the SnmpV3SecurityModel implemented by the package;
+// others can be registered with RegisterSecurityModel.
 const (
 	UserSecurityModel SnmpV3SecurityModel = 3
 )
 
-// SnmpV3SecurityParameters is a generic interface type to contain various implementations of SnmpV3SecurityParameters
+// SnmpV3SecurityParameters is a generic interface type to contain various implementations of SnmpV3SecurityParameters.
+// Implementations outside the package are made with WrapSecurityParameters.
 type SnmpV3SecurityParameters interface {
 	Log()
 	Copy() SnmpV3SecurityParameters
+	securityModel() SnmpV3SecurityModel
 	validate(flags SnmpV3MsgFlags) error
 	init(log Logger) error
 	initPacket(packet *SnmpPacket) error
@@ -52,9 +59,12 @@ type SnmpV3SecurityParameters interface {
 }
 
 func (x *GoSNMP) validateParametersV3() error {
-	// update following code if you implement a new security model
-	if x.SecurityModel != UserSecurityModel {
-		return fmt.Errorf("The SNMPV3 User Security Model is the only SNMPV3 security model currently implemented")
+	if !securityModelRegistered(x.SecurityModel) {
+		return fmt.Errorf("The SNMPV3 security model %d isn't implemented, see RegisterSecurityModel", x.SecurityModel)
+	}
+	if x.SecurityParameters != nil && x.SecurityParameters.securityModel() != x.SecurityModel {
+		return fmt.Errorf("SecurityParameters of security model %d don't match SecurityModel %d",
+			x.SecurityParameters.securityModel(), x.SecurityModel)
 	}
 
 	return x.SecurityParameters.validate(x.MsgFlags)
@@ -85,13 +95,15 @@ func (x *GoSNMP) testAuthentication(packet []byte, result *SnmpPacket) error {
 		return fmt.Errorf("testAuthentication called with non Version3 connection")
 	}
 
-	if x.MsgFlags&AuthNoPriv > 0 {
+	// responses that aren't authenticated, eg reports of the agent's
+	// engine, have no digest to check
+	if x.MsgFlags&AuthNoPriv > 0 && result.MsgFlags&AuthNoPriv > 0 {
 		authentic, err := x.SecurityParameters.isAuthentic(packet, result)
 		if err != nil {
 			return err
 		}
 		if !authentic {
-			return fmt.Errorf("Incoming packet is not authentic, discarding")
+			return fmt.Errorf("%w, discarding", ErrAuthentication)
 		}
 	}
 
@@ -101,18 +113,59 @@ func (x *GoSNMP) testAuthentication(packet []byte, result *SnmpPacket) error {
 func (x *GoSNMP) initPacket(packetOut *SnmpPacket) error {
 
 	if x.MsgFlags&AuthPriv > AuthNoPriv {
+		mu := x.securityLock()
+		mu.Lock()
+		defer mu.Unlock()
 		return x.SecurityParameters.initPacket(packetOut)
 	}
 
 	return nil
 }
 
+// randomEngineID returns an snmpEngineID of RFC 3411 format 5, octets
+// chosen by the engine, with no enterprise number
+func randomEngineID() string {
+	id := make([]byte, 13)
+	copy(id, []byte{0x80, 0, 0, 0, 5})
+	crand.Read(id[5:])
+	return string(id)
+}
+
+// localEngine returns the session's own snmpEngineID, its EngineID or a
+// random one, and its snmpEngineTime, the seconds since it was first used
+func (x *GoSNMP) localEngine() (engineID string, engineTime uint32) {
+	mu := x.securityLock()
+	mu.Lock()
+	defer mu.Unlock()
+	if x.localEngineID == "" {
+		x.localEngineID = x.EngineID
+		if x.localEngineID == "" {
+			x.localEngineID = randomEngineID()
+		}
+		x.localStart = time.Now()
+	}
+	return x.localEngineID, uint32(time.Since(x.localStart) / time.Second)
+}
+
+// securityLock returns the lock guarding x's SNMPv3 state. Requests copy
+// the connection's SecurityParameters into each packet, and store back
+// what they learn from the agent (engine id, boots and time), so this is
+// held around every access to them.
+func (x *GoSNMP) securityLock() *sync.Mutex {
+	lazyInitMu.Lock()
+	defer lazyInitMu.Unlock()
+	if x.secMu == nil {
+		x.secMu = new(sync.Mutex)
+	}
+	return x.secMu
+}
+
 // http://tools.ietf.org/html/rfc2574#section-2.2.3 This code does not
 // check if the last message received was more than 150 seconds ago The
 // snmpds that this code was tested on emit an 'out of time window'
 // error with the new time and this code will retransmit when that is
 // received.
-func (x *GoSNMP) negotiateInitialSecurityParameters(packetOut *SnmpPacket, wait bool) error {
+func (x *GoSNMP) negotiateInitialSecurityParameters(ctx context.Context, packetOut *SnmpPacket, wait bool) error {
 	if x.Version != Version3 || packetOut.Version != Version3 {
 		return fmt.Errorf("negotiateInitialSecurityParameters called with non Version3 connection or packet")
 	}
@@ -122,7 +175,7 @@ func (x *GoSNMP) negotiateInitialSecurityParameters(packetOut *SnmpPacket, wait
 	}
 
 	if discoveryPacket := packetOut.SecurityParameters.discoveryRequired(); discoveryPacket != nil {
-		result, err := x.sendOneRequest(discoveryPacket, wait)
+		result, err := x.sendOneRequest(ctx, discoveryPacket, wait)
 
 		if err != nil {
 			return err
@@ -153,6 +206,10 @@ func (x *GoSNMP) storeSecurityParameters(result *SnmpPacket) error {
 		return fmt.Errorf("connection security model does not match security model extracted from packet")
 	}
 
+	mu := x.securityLock()
+	mu.Lock()
+	defer mu.Unlock()
+
 	if x.ContextEngineID == "" {
 		x.ContextEngineID = result.SecurityParameters.getDefaultContextEngineID()
 	}
@@ -170,6 +227,10 @@ func (x *GoSNMP) updatePktSecurityParameters(packetOut *SnmpPacket) error {
 		return fmt.Errorf("connection security model does not match security model extracted from packet")
 	}
 
+	mu := x.securityLock()
+	mu.Lock()
+	defer mu.Unlock()
+
 	err := packetOut.SecurityParameters.setSecurityParameters(x.SecurityParameters)
 	if err != nil {
 		return err
@@ -186,12 +247,14 @@ func (packet *SnmpPacket) marshalV3(buf *bytes.Buffer) (*bytes.Buffer, error) {
 
 	emptyBuffer := new(bytes.Buffer) // used when returning errors
 
-	header, err := packet.marshalV3Header()
+	header := getBuffer()
+	defer putBuffer(header)
+	err := packet.writeV3Header(header)
 	if err != nil {
 		return emptyBuffer, err
 	}
-	buf.Write([]byte{byte(Sequence), byte(len(header))})
-	buf.Write(header)
+	buf.Write([]byte{byte(Sequence), byte(header.Len())})
+	header.WriteTo(buf)
 
 	var securityParameters []byte
 	securityParameters, err = packet.SecurityParameters.marshal(packet.MsgFlags)
@@ -207,27 +270,27 @@ func (packet *SnmpPacket) marshalV3(buf *bytes.Buffer) (*bytes.Buffer, error) {
 	buf.Write(secParamLen)
 	buf.Write(securityParameters)
 
-	scopedPdu, err := packet.marshalV3ScopedPDU()
+	err = packet.writeV3ScopedPDU(buf)
 	if err != nil {
 		return emptyBuffer, err
 	}
-	buf.Write(scopedPdu)
 	return buf, nil
 }
 
-// marshal a snmp version 3 packet header
-func (packet *SnmpPacket) marshalV3Header() ([]byte, error) {
-	buf := new(bytes.Buffer)
-
+// marshal a snmp version 3 packet header, appending it to buf
+func (packet *SnmpPacket) writeV3Header(buf *bytes.Buffer) error {
 	// msg id
+	var msgID [4]byte
+	binary.BigEndian.PutUint32(msgID[:], packet.MsgID)
 	buf.Write([]byte{byte(Integer), 4})
-	err := binary.Write(buf, binary.BigEndian, packet.MsgID)
-	if err != nil {
-		return nil, err
-	}
+	buf.Write(msgID[:])
 
 	// maximum response msg size
-	maxmsgsize := marshalUvarInt(rxBufSize)
+	size := uint32(rxBufSize)
+	if packet.MsgMaxSize > 0 && packet.MsgMaxSize < rxBufSize {
+		size = uint32(packet.MsgMaxSize)
+	}
+	maxmsgsize := marshalUvarInt(size)
 	buf.Write([]byte{byte(Integer), byte(len(maxmsgsize))})
 	buf.Write(maxmsgsize)
 
@@ -237,59 +300,66 @@ func (packet *SnmpPacket) marshalV3Header() ([]byte, error) {
 	// msg security model
 	buf.Write([]byte{byte(Integer), 1, byte(packet.SecurityModel)})
 
-	return buf.Bytes(), nil
+	return nil
 }
 
-// marshal and encrypt (if necessary) a snmp version 3 Scoped PDU
-func (packet *SnmpPacket) marshalV3ScopedPDU() ([]byte, error) {
-	var b []byte
+// marshal and encrypt (if necessary) a snmp version 3 Scoped PDU,
+// appending it to buf
+func (packet *SnmpPacket) writeV3ScopedPDU(buf *bytes.Buffer) error {
+	plain := getBuffer()
+	defer putBuffer(plain)
 
-	scopedPdu, err := packet.prepareV3ScopedPDU()
+	err := packet.prepareV3ScopedPDU(plain)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	pduLen, err := marshalLength(len(scopedPdu))
+	pduLen, err := marshalLength(plain.Len())
 	if err != nil {
-		return nil, err
+		return err
 	}
-	b = append([]byte{byte(Sequence)}, pduLen...)
-	scopedPdu = append(b, scopedPdu...)
+
 	if packet.MsgFlags&AuthPriv > AuthNoPriv {
-		scopedPdu, err = packet.SecurityParameters.encryptPacket(scopedPdu)
+		scopedPdu := getBuffer()
+		defer putBuffer(scopedPdu)
+		scopedPdu.WriteByte(byte(Sequence))
+		scopedPdu.Write(pduLen)
+		plain.WriteTo(scopedPdu)
+
+		encrypted, err := packet.SecurityParameters.encryptPacket(scopedPdu.Bytes())
 		if err != nil {
-			return nil, err
+			return err
 		}
+		buf.Write(encrypted)
+		return nil
 	}
 
-	return scopedPdu, nil
+	buf.WriteByte(byte(Sequence))
+	buf.Write(pduLen)
+	plain.WriteTo(buf)
+	return nil
 }
 
-// prepare the plain text of a snmp version 3 Scoped PDU
-func (packet *SnmpPacket) prepareV3ScopedPDU() ([]byte, error) {
-	var buf bytes.Buffer
-
+// prepare the plain text of a snmp version 3 Scoped PDU, appending it to buf
+func (packet *SnmpPacket) prepareV3ScopedPDU(buf *bytes.Buffer) error {
 	//ContextEngineID
 	idlen, err := marshalLength(len(packet.ContextEngineID))
 	if err != nil {
-		return nil, err
+		return err
 	}
-	buf.Write(append([]byte{byte(OctetString)}, idlen...))
+	buf.WriteByte(byte(OctetString))
+	buf.Write(idlen)
 	buf.WriteString(packet.ContextEngineID)
 
 	//ContextName
 	namelen, err := marshalLength(len(packet.ContextName))
 	if err != nil {
-		return nil, err
+		return err
 	}
-	buf.Write(append([]byte{byte(OctetString)}, namelen...))
+	buf.WriteByte(byte(OctetString))
+	buf.Write(namelen)
 	buf.WriteString(packet.ContextName)
 
-	data, err := packet.marshalPDU()
-	if err != nil {
-		return nil, err
-	}
-	buf.Write(data)
-	return buf.Bytes(), nil
+	return packet.writePDU(buf)
 }
 
 func (x *GoSNMP) unmarshalV3Header(packet []byte,
@@ -305,24 +375,25 @@ func (x *GoSNMP) unmarshalV3Header(packet []byte,
 
 	rawMsgID, count, err := parseRawField(packet[cursor:], "msgID")
 	if err != nil {
-		return 0, fmt.Errorf("Error parsing SNMPV3 message ID: %s", err.Error())
+		return 0, fmt.Errorf("Error parsing SNMPV3 message ID: %w", err)
 	}
 	cursor += count
 	if MsgID, ok := rawMsgID.(int); ok {
 		response.MsgID = uint32(MsgID)
 		x.logPrintf("Parsed message ID %d", MsgID)
 	}
-	// discard msg max size
-	_, count, err = parseRawField(packet[cursor:], "maxMsgSize")
+	rawMaxMsgSize, count, err := parseRawField(packet[cursor:], "maxMsgSize")
 	if err != nil {
-		return 0, fmt.Errorf("Error parsing SNMPV3 maxMsgSize: %s", err.Error())
+		return 0, fmt.Errorf("Error parsing SNMPV3 maxMsgSize: %w", err)
 	}
 	cursor += count
-	// discard msg max size
+	if MaxMsgSize, ok := rawMaxMsgSize.(int); ok {
+		response.MsgMaxSize = MaxMsgSize
+	}
 
 	rawMsgFlags, count, err := parseRawField(packet[cursor:], "msgFlags")
 	if err != nil {
-		return 0, fmt.Errorf("Error parsing SNMPV3 msgFlags: %s", err.Error())
+		return 0, fmt.Errorf("Error parsing SNMPV3 msgFlags: %w", err)
 	}
 	cursor += count
 	if MsgFlags, ok := rawMsgFlags.(string); ok {
@@ -332,7 +403,7 @@ func (x *GoSNMP) unmarshalV3Header(packet []byte,
 
 	rawSecModel, count, err := parseRawField(packet[cursor:], "msgSecurityModel")
 	if err != nil {
-		return 0, fmt.Errorf("Error parsing SNMPV3 msgSecModel: %s", err.Error())
+		return 0, fmt.Errorf("Error parsing SNMPV3 msgSecModel: %w", err)
 	}
 	cursor += count
 	if SecModel, ok := rawSecModel.(int); ok {
@@ -346,8 +417,12 @@ func (x *GoSNMP) unmarshalV3Header(packet []byte,
 	_, cursorTmp = parseLength(packet[cursor:])
 	cursor += cursorTmp
 
-	if response.SecurityParameters == nil {
-		return 0, fmt.Errorf("Unable to parse V3 packet - unknown security model")
+	// the parameters are unmarshalled into those given, eg a copy of the
+	// session's, if they are of the message's security model
+	if response.SecurityParameters == nil || response.SecurityParameters.securityModel() != response.SecurityModel {
+		if response.SecurityParameters, err = newSecurityParameters(response.SecurityModel, x.Logger); err != nil {
+			return 0, err
+		}
 	}
 
 	cursor, err = response.SecurityParameters.unmarshal(response.MsgFlags, packet, cursor)
@@ -366,6 +441,9 @@ func (x *GoSNMP) decryptPacket(packet []byte, cursor int, response *SnmpPacket)
 		if err != nil {
 			return nil, 0, err
 		}
+		if err = x.DecodeLimits.checkScopedPDU(packet[cursor:]); err != nil {
+			return nil, 0, err
+		}
 		fallthrough
 	case Sequence:
 		// pdu is plaintext
@@ -376,7 +454,7 @@ func (x *GoSNMP) decryptPacket(packet []byte, cursor int, response *SnmpPacket)
 		cursor += cursorTmp
 		rawContextEngineID, count, err := parseRawField(packet[cursor:], "contextEngineID")
 		if err != nil {
-			return nil, 0, fmt.Errorf("Error parsing SNMPV3 contextEngineID: %s", err.Error())
+			return nil, 0, fmt.Errorf("Error parsing SNMPV3 contextEngineID: %w", err)
 		}
 		cursor += count
 		if contextEngineID, ok := rawContextEngineID.(string); ok {
@@ -385,7 +463,7 @@ func (x *GoSNMP) decryptPacket(packet []byte, cursor int, response *SnmpPacket)
 		}
 		rawContextName, count, err := parseRawField(packet[cursor:], "contextName")
 		if err != nil {
-			return nil, 0, fmt.Errorf("Error parsing SNMPV3 contextName: %s", err.Error())
+			return nil, 0, fmt.Errorf("Error parsing SNMPV3 contextName: %w", err)
 		}
 		cursor += count
 		if contextName, ok := rawContextName.(string); ok {
@@ -19,8 +19,8 @@ import (
 	"encoding/binary"
 	"fmt"
 	"hash"
-	"sync/atomic"
 	"sync"
+	"sync/atomic"
 )
 
 // SnmpV3AuthProtocol describes the authentication protocol in use by an authenticated SnmpV3 connection.
@@ -68,7 +68,7 @@ type UsmSecurityParameters struct {
 }
 
 func (sp *UsmSecurityParameters) Log() {
-	sp.Logger.Printf("SECURITY PARAMETERS:%+v", sp)
+	sp.Logger.Printf("SECURITY PARAMETERS:%s", sp)
 }
 
 // Copy method for UsmSecurityParameters used to copy a SnmpV3SecurityParameters without knowing it's implementation
@@ -91,6 +91,10 @@ func (sp *UsmSecurityParameters) Copy() SnmpV3SecurityParameters {
 	}
 }
 
+func (sp *UsmSecurityParameters) securityModel() SnmpV3SecurityModel {
+	return UserSecurityModel
+}
+
 func (sp *UsmSecurityParameters) getDefaultContextEngineID() string {
 	return sp.AuthoritativeEngineID
 }
@@ -170,14 +174,14 @@ func (sp *UsmSecurityParameters) init(log Logger) error {
 		salt := make([]byte, 8)
 		_, err = crand.Read(salt)
 		if err != nil {
-			return fmt.Errorf("Error creating a cryptographically secure salt: %s\n", err.Error())
+			return fmt.Errorf("Error creating a cryptographically secure salt: %w\n", err)
 		}
 		sp.localAESSalt = binary.BigEndian.Uint64(salt)
 	case DES:
 		salt := make([]byte, 4)
 		_, err = crand.Read(salt)
 		if err != nil {
-			return fmt.Errorf("Error creating a cryptographically secure salt: %s\n", err.Error())
+			return fmt.Errorf("Error creating a cryptographically secure salt: %w\n", err)
 		}
 		sp.localDESSalt = binary.BigEndian.Uint32(salt)
 	}
@@ -195,7 +199,7 @@ func castUsmSecParams(secParams SnmpV3SecurityParameters) (*UsmSecurityParameter
 
 var (
 	passwordKeyHashCache = make(map[string][]byte)
- 	passwordKeyHashMutex sync.RWMutex
+	passwordKeyHashMutex sync.RWMutex
 )
 
 // Common passwordToKey algorithm, "caches" the result to avoid extra computation each reuse
@@ -206,7 +210,7 @@ func cachedPasswordToKey(hash hash.Hash, hashType string, password string) []byt
 	value := passwordKeyHashCache[cacheKey]
 	passwordKeyHashMutex.RUnlock()
 
-	if value != nil	{
+	if value != nil {
 		return value
 	}
 	var pi int // password index
@@ -255,7 +259,6 @@ func shaHMAC(password string, engineID string) []byte {
 	return final
 }
 
-
 func genlocalkey(authProtocol SnmpV3AuthProtocol, passphrase string, engineID string) []byte {
 	var secretKey []byte
 
@@ -359,6 +362,29 @@ func usmFindAuthParamStart(packet []byte) (uint32, error) {
 	return uint32(idx + 2), nil
 }
 
+// md5Pool and sha1Pool hold the hashes used to authenticate messages
+var (
+	md5Pool  = sync.Pool{New: func() interface{} { return md5.New() }}
+	sha1Pool = sync.Pool{New: func() interface{} { return sha1.New() }}
+)
+
+// getHash returns a reset hash for the authentication protocol
+func getHash(authProtocol SnmpV3AuthProtocol) hash.Hash {
+	if authProtocol == SHA {
+		return sha1Pool.Get().(hash.Hash)
+	}
+	return md5Pool.Get().(hash.Hash)
+}
+
+func putHash(authProtocol SnmpV3AuthProtocol, h hash.Hash) {
+	h.Reset()
+	if authProtocol == SHA {
+		sha1Pool.Put(h)
+	} else {
+		md5Pool.Put(h)
+	}
+}
+
 func (sp *UsmSecurityParameters) authenticate(packet []byte) error {
 
 	var extkey [64]byte
@@ -372,28 +398,22 @@ func (sp *UsmSecurityParameters) authenticate(packet []byte) error {
 		k2[i] = extkey[i] ^ 0x5c
 	}
 
-	var h, h2 hash.Hash
-
-	switch sp.AuthenticationProtocol {
-	default:
-		h = md5.New()
-		h2 = md5.New()
-	case SHA:
-		h = sha1.New()
-		h2 = sha1.New()
-	}
+	h := getHash(sp.AuthenticationProtocol)
+	defer putHash(sp.AuthenticationProtocol, h)
 
+	var d1, d2 [sha1.Size]byte
 	h.Write(k1[:])
 	h.Write(packet)
-	d1 := h.Sum(nil)
-	h2.Write(k2[:])
-	h2.Write(d1)
+	digest := h.Sum(d1[:0])
+	h.Reset()
+	h.Write(k2[:])
+	h.Write(digest)
 	authParamStart, err := usmFindAuthParamStart(packet)
 	if err != nil {
 		return err
 	}
 
-	copy(packet[authParamStart:authParamStart+12], h2.Sum(nil)[:12])
+	copy(packet[authParamStart:authParamStart+12], h.Sum(d2[:0])[:12])
 
 	return nil
 }
@@ -420,24 +440,22 @@ func (sp *UsmSecurityParameters) isAuthentic(packetBytes []byte, packet *SnmpPac
 		k2[i] = extkey[i] ^ 0x5c
 	}
 
-	var h, h2 hash.Hash
-
-	switch sp.AuthenticationProtocol {
-	default:
-		h = md5.New()
-		h2 = md5.New()
-	case SHA:
-		h = sha1.New()
-		h2 = sha1.New()
-	}
+	h := getHash(sp.AuthenticationProtocol)
+	defer putHash(sp.AuthenticationProtocol, h)
 
+	var d1, d2 [sha1.Size]byte
 	h.Write(k1[:])
 	h.Write(packetBytes)
-	d1 := h.Sum(nil)
-	h2.Write(k2[:])
-	h2.Write(d1)
+	digest := h.Sum(d1[:0])
+	h.Reset()
+	h.Write(k2[:])
+	h.Write(digest)
 
-	result := h2.Sum(nil)[:12]
+	result := h.Sum(d2[:0])[:12]
+	// empty or truncated parameters aren't a digest of the message
+	if len(packetSecParams.AuthenticationParameters) != len(result) {
+		return false, nil
+	}
 	for k, v := range []byte(packetSecParams.AuthenticationParameters) {
 		if result[k] != v {
 			return false, nil
@@ -461,14 +479,15 @@ func (sp *UsmSecurityParameters) encryptPacket(scopedPdu []byte) ([]byte, error)
 			return nil, err
 		}
 		stream := cipher.NewCFBEncrypter(block, iv[:])
-		ciphertext := make([]byte, len(scopedPdu))
-		stream.XORKeyStream(ciphertext, scopedPdu)
-		pduLen, err := marshalLength(len(ciphertext))
+		pduLen, err := marshalLength(len(scopedPdu))
 		if err != nil {
 			return nil, err
 		}
-		b = append([]byte{byte(OctetString)}, pduLen...)
-		scopedPdu = append(b, ciphertext...)
+		b = make([]byte, 1+len(pduLen)+len(scopedPdu))
+		b[0] = byte(OctetString)
+		copy(b[1:], pduLen)
+		stream.XORKeyStream(b[1+len(pduLen):], scopedPdu)
+		scopedPdu = b
 	default:
 		preiv := sp.privacyKey[8:]
 		var iv [8]byte
@@ -481,17 +500,18 @@ func (sp *UsmSecurityParameters) encryptPacket(scopedPdu []byte) ([]byte, error)
 		}
 		mode := cipher.NewCBCEncrypter(block, iv[:])
 
-		pad := make([]byte, des.BlockSize-len(scopedPdu)%des.BlockSize)
-		scopedPdu = append(scopedPdu, pad...)
+		var pad [des.BlockSize]byte
+		scopedPdu = append(scopedPdu, pad[:des.BlockSize-len(scopedPdu)%des.BlockSize]...)
 
-		ciphertext := make([]byte, len(scopedPdu))
-		mode.CryptBlocks(ciphertext, scopedPdu)
-		pduLen, err := marshalLength(len(ciphertext))
+		pduLen, err := marshalLength(len(scopedPdu))
 		if err != nil {
 			return nil, err
 		}
-		b = append([]byte{byte(OctetString)}, pduLen...)
-		scopedPdu = append(b, ciphertext...)
+		b = make([]byte, 1+len(pduLen)+len(scopedPdu))
+		b[0] = byte(OctetString)
+		copy(b[1:], pduLen)
+		mode.CryptBlocks(b[1+len(pduLen):], scopedPdu)
+		scopedPdu = b
 	}
 
 	return scopedPdu, nil
@@ -544,7 +564,8 @@ func (sp *UsmSecurityParameters) decryptPacket(packet []byte, cursor int) ([]byt
 
 // marshal a snmp version 3 security parameters field for the User Security Model
 func (sp *UsmSecurityParameters) marshal(flags SnmpV3MsgFlags) ([]byte, error) {
-	var buf bytes.Buffer
+	buf := getBuffer()
+	defer putBuffer(buf)
 	var err error
 
 	// msgAuthoritativeEngineID
@@ -592,7 +613,9 @@ func (sp *UsmSecurityParameters) marshal(flags SnmpV3MsgFlags) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	tmpseq := append([]byte{byte(Sequence)}, paramLen...)
+	tmpseq := make([]byte, 0, 1+len(paramLen)+buf.Len())
+	tmpseq = append(tmpseq, byte(Sequence))
+	tmpseq = append(tmpseq, paramLen...)
 	tmpseq = append(tmpseq, buf.Bytes()...)
 
 	return tmpseq, nil
@@ -610,7 +633,7 @@ func (sp *UsmSecurityParameters) unmarshal(flags SnmpV3MsgFlags, packet []byte,
 
 	rawMsgAuthoritativeEngineID, count, err := parseRawField(packet[cursor:], "msgAuthoritativeEngineID")
 	if err != nil {
-		return 0, fmt.Errorf("Error parsing SNMPV3 User Security Model msgAuthoritativeEngineID: %s", err.Error())
+		return 0, fmt.Errorf("Error parsing SNMPV3 User Security Model msgAuthoritativeEngineID: %w", err)
 	}
 	cursor += count
 	if AuthoritativeEngineID, ok := rawMsgAuthoritativeEngineID.(string); ok {
@@ -632,7 +655,7 @@ func (sp *UsmSecurityParameters) unmarshal(flags SnmpV3MsgFlags, packet []byte,
 
 	rawMsgAuthoritativeEngineBoots, count, err := parseRawField(packet[cursor:], "msgAuthoritativeEngineBoots")
 	if err != nil {
-		return 0, fmt.Errorf("Error parsing SNMPV3 User Security Model msgAuthoritativeEngineBoots: %s", err.Error())
+		return 0, fmt.Errorf("Error parsing SNMPV3 User Security Model msgAuthoritativeEngineBoots: %w", err)
 	}
 	cursor += count
 	if AuthoritativeEngineBoots, ok := rawMsgAuthoritativeEngineBoots.(int); ok {
@@ -642,7 +665,7 @@ func (sp *UsmSecurityParameters) unmarshal(flags SnmpV3MsgFlags, packet []byte,
 
 	rawMsgAuthoritativeEngineTime, count, err := parseRawField(packet[cursor:], "msgAuthoritativeEngineTime")
 	if err != nil {
-		return 0, fmt.Errorf("Error parsing SNMPV3 User Security Model msgAuthoritativeEngineTime: %s", err.Error())
+		return 0, fmt.Errorf("Error parsing SNMPV3 User Security Model msgAuthoritativeEngineTime: %w", err)
 	}
 	cursor += count
 	if AuthoritativeEngineTime, ok := rawMsgAuthoritativeEngineTime.(int); ok {
@@ -652,7 +675,7 @@ func (sp *UsmSecurityParameters) unmarshal(flags SnmpV3MsgFlags, packet []byte,
 
 	rawMsgUserName, count, err := parseRawField(packet[cursor:], "msgUserName")
 	if err != nil {
-		return 0, fmt.Errorf("Error parsing SNMPV3 User Security Model msgUserName: %s", err.Error())
+		return 0, fmt.Errorf("Error parsing SNMPV3 User Security Model msgUserName: %w", err)
 	}
 	cursor += count
 	if msgUserName, ok := rawMsgUserName.(string); ok {
@@ -662,11 +685,11 @@ func (sp *UsmSecurityParameters) unmarshal(flags SnmpV3MsgFlags, packet []byte,
 
 	rawMsgAuthParameters, count, err := parseRawField(packet[cursor:], "msgAuthenticationParameters")
 	if err != nil {
-		return 0, fmt.Errorf("Error parsing SNMPV3 User Security Model msgAuthenticationParameters: %s", err.Error())
+		return 0, fmt.Errorf("Error parsing SNMPV3 User Security Model msgAuthenticationParameters: %w", err)
 	}
 	if msgAuthenticationParameters, ok := rawMsgAuthParameters.(string); ok {
 		sp.AuthenticationParameters = msgAuthenticationParameters
-		sp.Logger.Printf("Parsed authenticationParameters %s", msgAuthenticationParameters)
+		sp.Logger.Printf("Parsed authenticationParameters %s", redactBytes([]byte(msgAuthenticationParameters)))
 	}
 	// blank msgAuthenticationParameters to prepare for authentication check later
 	if flags&AuthNoPriv > 0 {
@@ -677,12 +700,12 @@ func (sp *UsmSecurityParameters) unmarshal(flags SnmpV3MsgFlags, packet []byte,
 
 	rawMsgPrivacyParameters, count, err := parseRawField(packet[cursor:], "msgPrivacyParameters")
 	if err != nil {
-		return 0, fmt.Errorf("Error parsing SNMPV3 User Security Model msgPrivacyParameters: %s", err.Error())
+		return 0, fmt.Errorf("Error parsing SNMPV3 User Security Model msgPrivacyParameters: %w", err)
 	}
 	cursor += count
 	if msgPrivacyParameters, ok := rawMsgPrivacyParameters.(string); ok {
 		sp.PrivacyParameters = []byte(msgPrivacyParameters)
-		sp.Logger.Printf("Parsed privacyParameters %s", msgPrivacyParameters)
+		sp.Logger.Printf("Parsed privacyParameters %s", redactBytes(sp.PrivacyParameters))
 	}
 
 	return cursor, nil
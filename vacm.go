@@ -0,0 +1,328 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+//
+// View-based Access Control Model (RFC 3415)
+//
+
+// VacmViewType is the kind of access to the objects of a view
+type VacmViewType int
+
+const (
+	// VacmRead is for GET, GETNEXT and GETBULK requests
+	VacmRead VacmViewType = iota
+
+	// VacmWrite is for SET requests
+	VacmWrite
+
+	// VacmNotify is for the objects of traps and informs
+	VacmNotify
+)
+
+// The errors of Vacm.IsAccessAllowed, the statuses of RFC 3415 section 3.2
+var (
+	// ErrVacmNoGroup is returned for a security name that isn't in a group
+	ErrVacmNoGroup = errors.New("No group for the security name")
+
+	// ErrVacmNoAccess is returned when the group has no access entry for
+	// the context, security model and level
+	ErrVacmNoAccess = errors.New("No access entry for the group")
+
+	// ErrVacmNoView is returned when the access entry has no view of the
+	// kind asked for, or the view has no subtrees
+	ErrVacmNoView = errors.New("No view for the access")
+
+	// ErrVacmNotInView is returned for an object that isn't in the view
+	ErrVacmNotInView = errors.New("Object not in the view")
+)
+
+// VacmAccess is an entry of the vacmAccessTable, the views of the objects
+// the members of a group may read, write and be notified about
+type VacmAccess struct {
+	// Group is the group given access
+	Group string
+
+	// Context is the context (SNMPv3 contextName) given access, or with
+	// ContextPrefix the start of it. Communities, and SNMPv3 requests
+	// without a context, have the default context "".
+	Context       string
+	ContextPrefix bool
+
+	// Versions are the versions, ie security models, given access; all
+	// versions if there are none
+	Versions []SnmpVersion
+
+	// Level is the lowest security level given access, eg AuthNoPriv; SNMPv1
+	// and SNMPv2c have NoAuthNoPriv
+	Level SnmpV3MsgFlags
+
+	// ReadView, WriteView and NotifyView are the names of the views given
+	// access, or "" for none
+	ReadView, WriteView, NotifyView string
+}
+
+// Vacm is a View-based Access Control Model: groups of security names
+// (communities or SNMPv3 user names), views of the object tree, and the
+// access entries giving groups the views. It is used by an Agent to
+// restrict which objects can be read and written by whom, and by a
+// TrapListener to reject notifications from unknown sources, see their
+// Vacm fields. The zero Vacm gives no access; it is safe for concurrent
+// use.
+type Vacm struct {
+	mu     sync.RWMutex
+	groups map[vacmSecurity]string
+	access []VacmAccess
+	views  map[string][]vacmViewEntry
+}
+
+// vacmSecurity is a security model and name, the key of a group member
+type vacmSecurity struct {
+	model SnmpVersion
+	name  string
+}
+
+// vacmViewEntry is an entry of the vacmViewTreeFamilyTable
+type vacmViewEntry struct {
+	subtree  Oid
+	mask     []byte
+	included bool
+}
+
+// AddGroup adds securityName, of the security model of version, to group.
+// For SNMPv1 and SNMPv2c the security name is the community, for SNMPv3
+// the user name. A security name is in one group, the last added to.
+func (v *Vacm) AddGroup(group string, version SnmpVersion, securityName string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.groups == nil {
+		v.groups = make(map[vacmSecurity]string)
+	}
+	v.groups[vacmSecurity{version, securityName}] = group
+}
+
+// AddAccess adds an access entry, replacing any for the same group,
+// context, versions and level
+func (v *Vacm) AddAccess(access VacmAccess) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for i, a := range v.access {
+		if a.Group == access.Group && a.Context == access.Context && a.ContextPrefix == access.ContextPrefix &&
+			fmt.Sprint(a.Versions) == fmt.Sprint(access.Versions) && a.Level == access.Level {
+			v.access[i] = access
+			return
+		}
+	}
+	v.access = append(v.access, access)
+}
+
+// AddView adds the subtree at oid to view, or excludes it from the view if
+// included is false. mask is a bit mask (the first sub-identifier being
+// the high bit of the first octet) of the sub-identifiers of oid that must
+// match, the others being wildcards, eg to include a table row whatever its
+// column; it is extended with ones. An oid is in a view if the longest of
+// the view's subtrees matching it is included.
+func (v *Vacm) AddView(view, oid string, mask []byte, included bool) error {
+	subtree, err := ParseOid(oid)
+	if err != nil {
+		return err
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.views == nil {
+		v.views = make(map[string][]vacmViewEntry)
+	}
+	entries := v.views[view]
+	for i, e := range entries {
+		if e.subtree.Equal(subtree) {
+			entries[i] = vacmViewEntry{subtree, mask, included}
+			return nil
+		}
+	}
+	v.views[view] = append(entries, vacmViewEntry{subtree, mask, included})
+	return nil
+}
+
+// IsAccessAllowed checks whether securityName, of the security model of
+// version, at a security level (eg AuthPriv), may access the object oid in
+// context, returning one of the ErrVacm errors if it may not.
+func (v *Vacm) IsAccessAllowed(version SnmpVersion, securityName string, level SnmpV3MsgFlags,
+	context string, viewType VacmViewType, oid string) error {
+	o, err := ParseOid(oid)
+	if err != nil {
+		return err
+	}
+	view, err := v.view(version, securityName, level, context, viewType)
+	if err != nil {
+		return err
+	}
+	if !v.inView(view, o) {
+		return ErrVacmNotInView
+	}
+	return nil
+}
+
+// hasGroup reports whether securityName is in a group
+func (v *Vacm) hasGroup(version SnmpVersion, securityName string) bool {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	_, ok := v.groups[vacmSecurity{version, securityName}]
+	return ok
+}
+
+// view returns the name of the view securityName may access in context,
+// RFC 3415 section 4
+func (v *Vacm) view(version SnmpVersion, securityName string, level SnmpV3MsgFlags,
+	context string, viewType VacmViewType) (string, error) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	group, ok := v.groups[vacmSecurity{version, securityName}]
+	if !ok {
+		return "", ErrVacmNoGroup
+	}
+
+	// the entry matching best: one for the security model over one for
+	// any, an exact context over a prefix, a longer prefix, then a higher
+	// level
+	var best *VacmAccess
+	better := func(a, b *VacmAccess) bool {
+		if (len(a.Versions) > 0) != (len(b.Versions) > 0) {
+			return len(a.Versions) > 0
+		}
+		if a.ContextPrefix != b.ContextPrefix {
+			return !a.ContextPrefix
+		}
+		if len(a.Context) != len(b.Context) {
+			return len(a.Context) > len(b.Context)
+		}
+		return a.Level&AuthPriv > b.Level&AuthPriv
+	}
+	for i := range v.access {
+		a := &v.access[i]
+		if a.Group != group || a.Level&AuthPriv > level&AuthPriv {
+			continue
+		}
+		if a.ContextPrefix && !strings.HasPrefix(context, a.Context) || !a.ContextPrefix && context != a.Context {
+			continue
+		}
+		if len(a.Versions) > 0 {
+			found := false
+			for _, m := range a.Versions {
+				found = found || m == version
+			}
+			if !found {
+				continue
+			}
+		}
+		if best == nil || better(a, best) {
+			best = a
+		}
+	}
+	if best == nil {
+		return "", ErrVacmNoAccess
+	}
+
+	var view string
+	switch viewType {
+	case VacmRead:
+		view = best.ReadView
+	case VacmWrite:
+		view = best.WriteView
+	case VacmNotify:
+		view = best.NotifyView
+	}
+	if view == "" || len(v.views[view]) == 0 {
+		return "", ErrVacmNoView
+	}
+	return view, nil
+}
+
+// inView reports whether oid is in view
+func (v *Vacm) inView(view string, oid Oid) bool {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return inViewEntries(v.views[view], oid)
+}
+
+// inViewEntries reports whether oid is in the view of entries: the longest
+// of the subtrees matching it, or of those as long the greatest, is
+// included
+func inViewEntries(entries []vacmViewEntry, oid Oid) bool {
+	var best *vacmViewEntry
+	for i := range entries {
+		e := &entries[i]
+		if !e.matches(oid) {
+			continue
+		}
+		if best == nil || len(e.subtree) > len(best.subtree) ||
+			len(e.subtree) == len(best.subtree) && e.subtree.Compare(best.subtree) > 0 {
+			best = e
+		}
+	}
+	return best != nil && best.included
+}
+
+// matches reports whether oid is in the entry's subtree, allowing for its
+// mask's wildcards
+func (e *vacmViewEntry) matches(oid Oid) bool {
+	if len(oid) < len(e.subtree) {
+		return false
+	}
+	for i, n := range e.subtree {
+		wildcard := i/8 < len(e.mask) && e.mask[i/8]&(0x80>>(i%8)) == 0
+		if !wildcard && oid[i] != n {
+			return false
+		}
+	}
+	return true
+}
+
+// snmpTrapOID is the oid of the varbind identifying an SNMPv2 notification
+const snmpTrapOID = ".1.3.6.1.6.3.1.1.4.1.0"
+
+// notificationOid returns the oid identifying a trap or inform, with the
+// oids of SNMPv1 traps made as RFC 3584 section 3.1 says
+func notificationOid(packet *SnmpPacket) string {
+	if trap := packet.V1Trap(); trap != nil {
+		return trap.TrapOID()
+	}
+	for _, pdu := range packet.Variables {
+		if pdu.Name == snmpTrapOID {
+			switch v := pdu.Value.(type) {
+			case string:
+				return v
+			case Oid:
+				return v.String()
+			}
+		}
+	}
+	return ""
+}
+
+// allowsNotification reports whether a trap or inform may be received: its
+// community, or user, is in a group whose notify view includes the
+// notification's oid
+func (v *Vacm) allowsNotification(packet *SnmpPacket) bool {
+	securityName, level := packet.Community, NoAuthNoPriv
+	if packet.Version == Version3 {
+		usm, ok := packet.SecurityParameters.(*UsmSecurityParameters)
+		if !ok {
+			return false
+		}
+		securityName, level = usm.UserName, packet.MsgFlags
+	}
+	oid := notificationOid(packet)
+	if oid == "" {
+		return false
+	}
+	return v.IsAccessAllowed(packet.Version, securityName, level, packet.ContextName, VacmNotify, oid) == nil
+}
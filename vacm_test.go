@@ -0,0 +1,198 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func newTestVacm(t *testing.T) *Vacm {
+	t.Helper()
+	v := &Vacm{}
+	v.AddGroup("readers", Version2c, "public")
+	v.AddGroup("readers", Version1, "public")
+	v.AddGroup("admins", Version2c, "private")
+	v.AddGroup("admins", Version3, "admin")
+	v.AddAccess(VacmAccess{Group: "readers", ReadView: "system", NotifyView: "all"})
+	v.AddAccess(VacmAccess{Group: "admins", ReadView: "all", WriteView: "system"})
+	v.AddAccess(VacmAccess{Group: "admins", Versions: []SnmpVersion{Version3}, Level: AuthPriv,
+		ReadView: "all", WriteView: "all"})
+	v.AddAccess(VacmAccess{Group: "admins", Context: "vrf-", ContextPrefix: true, ReadView: "system"})
+	for _, view := range []struct {
+		name, oid string
+		mask      []byte
+		included  bool
+	}{
+		{"system", ".1.3.6.1.2.1.1", nil, true},
+		{"all", ".1.3", nil, true},
+		{"all", ".1.3.6.1.6.3.15", nil, false}, // the usmUser objects
+		// the row of interface 1 in the ifTable, whatever the column
+		{"all", ".1.3.6.1.2.1.2.2.1.0.1", []byte{0xff, 0xa0}, false},
+	} {
+		if err := v.AddView(view.name, view.oid, view.mask, view.included); err != nil {
+			t.Fatalf("AddView(%s) err: %v", view.oid, err)
+		}
+	}
+	return v
+}
+
+func TestVacmIsAccessAllowed(t *testing.T) {
+	v := newTestVacm(t)
+	tests := []struct {
+		version  SnmpVersion
+		name     string
+		level    SnmpV3MsgFlags
+		context  string
+		viewType VacmViewType
+		oid      string
+		want     error
+	}{
+		{Version2c, "public", NoAuthNoPriv, "", VacmRead, testSysDescr, nil},
+		{Version1, "public", NoAuthNoPriv, "", VacmRead, testSysDescr, nil},
+		{Version2c, "public", NoAuthNoPriv, "", VacmRead, testIfNumber, ErrVacmNotInView},
+		{Version2c, "public", NoAuthNoPriv, "", VacmWrite, testSysName, ErrVacmNoView},
+		{Version2c, "public", NoAuthNoPriv, "other", VacmRead, testSysDescr, ErrVacmNoAccess},
+		{Version1, "private", NoAuthNoPriv, "", VacmRead, testSysDescr, ErrVacmNoGroup},
+		{Version2c, "wrong", NoAuthNoPriv, "", VacmRead, testSysDescr, ErrVacmNoGroup},
+
+		{Version2c, "private", NoAuthNoPriv, "", VacmWrite, testSysName, nil},
+		{Version2c, "private", NoAuthNoPriv, "", VacmWrite, testIfNumber, ErrVacmNotInView},
+		{Version2c, "private", NoAuthNoPriv, "", VacmRead, testIfNumber, nil},
+		{Version2c, "private", NoAuthNoPriv, "", VacmRead, ".1.3.6.1.6.3.15.1.2.2.1.3", ErrVacmNotInView},
+		// the mask's wildcard
+		{Version2c, "private", NoAuthNoPriv, "", VacmRead, ".1.3.6.1.2.1.2.2.1.2.1", ErrVacmNotInView},
+		{Version2c, "private", NoAuthNoPriv, "", VacmRead, ".1.3.6.1.2.1.2.2.1.4.1", ErrVacmNotInView},
+		{Version2c, "private", NoAuthNoPriv, "", VacmRead, ".1.3.6.1.2.1.2.2.1.2.2", nil},
+
+		// the best access: for the security model, and at the level
+		{Version3, "admin", AuthPriv, "", VacmWrite, testIfNumber, nil},
+		{Version3, "admin", AuthNoPriv, "", VacmWrite, testIfNumber, ErrVacmNotInView},
+		{Version3, "admin", AuthNoPriv, "", VacmWrite, testSysName, nil},
+		{Version3, "admin", NoAuthNoPriv, "vrf-blue", VacmRead, testSysDescr, nil},
+		{Version3, "admin", NoAuthNoPriv, "vrf-blue", VacmRead, testIfNumber, ErrVacmNotInView},
+		{Version3, "admin", NoAuthNoPriv, "vrf-blue", VacmWrite, testSysName, ErrVacmNoView},
+	}
+	for _, test := range tests {
+		err := v.IsAccessAllowed(test.version, test.name, test.level, test.context, test.viewType, test.oid)
+		if err != test.want {
+			t.Errorf("IsAccessAllowed(%s, %s, %d, %q, %d, %s) got %v, expected %v", test.version, test.name,
+				test.level, test.context, test.viewType, test.oid, err, test.want)
+		}
+	}
+
+	if err := (&Vacm{}).IsAccessAllowed(Version2c, "public", NoAuthNoPriv, "", VacmRead, testSysDescr); err != ErrVacmNoGroup {
+		t.Errorf("the zero Vacm got %v, expected %v", err, ErrVacmNoGroup)
+	}
+	if err := v.AddView("bad", "1.x", nil, true); err == nil {
+		t.Errorf("AddView() of an invalid oid succeeded")
+	}
+}
+
+func TestAgentVacm(t *testing.T) {
+	a, name := newTestAgent(t)
+	a.Vacm = newTestVacm(t)
+	defer a.Close()
+	x := startTestAgent(t, a, nil)
+	defer x.Conn.Close()
+
+	// public can read the system group only
+	result, err := x.Get([]string{testSysDescr, testIfNumber})
+	if err != nil {
+		t.Fatalf("Get() err: %v", err)
+	}
+	if v := result.Variables[0]; v.Type != OctetString {
+		t.Errorf("got %v, expected sysDescr", v)
+	}
+	if v := result.Variables[1]; v.Type != NoSuchObject {
+		t.Errorf("got %v, expected noSuchObject", v)
+	}
+	pdus, err := x.WalkAll(".1.3")
+	if err != nil {
+		t.Fatalf("WalkAll() err: %v", err)
+	}
+	var names []string
+	for _, pdu := range pdus {
+		names = append(names, pdu.Name)
+	}
+	if got, want := fmt.Sprint(names), fmt.Sprint([]string{testSysDescr, testSysName}); got != want {
+		t.Errorf("walked %s, expected %s", got, want)
+	}
+
+	set := []SnmpPDU{{Name: testSysName, Type: OctetString, Value: "new"}}
+	if result, err = x.Set(set); err != nil || result.Error != AuthorizationError {
+		t.Errorf("Set() by public got %v, err %v, expected authorizationError", result, err)
+	}
+
+	// private can write the system group, and read everything
+	x.Community = "private"
+	if result, err = x.Set(set); err != nil || result.Error != NoError {
+		t.Errorf("Set() by private got %v, err %v, expected noError", result, err)
+	}
+	if got := string(name.value().([]byte)); got != "new" {
+		t.Errorf("sysName is %q, expected \"new\"", got)
+	}
+	set = append(set, SnmpPDU{Name: testIfNumber, Type: Integer, Value: 3})
+	if result, err = x.Set(set); err != nil || result.Error != NoAccess || result.ErrorIndex != 2 {
+		t.Errorf("Set() of ifNumber got %v, err %v, expected noAccess at 2", result, err)
+	}
+	if result, err = x.Get([]string{testIfNumber}); err != nil || result.Variables[0].Type != Integer {
+		t.Errorf("Get() by private got %v, err %v, expected ifNumber", result, err)
+	}
+
+	// communities that aren't in a group have no response
+	x.Community = "wrong"
+	x.Retries = 0
+	x.Timeout = 100 * time.Millisecond
+	if _, err = x.Get([]string{testSysName}); err == nil {
+		t.Errorf("Get() with the wrong community succeeded")
+	}
+}
+
+func TestVacmNotification(t *testing.T) {
+	v := newTestVacm(t)
+	if err := v.AddView("traps", ".1.3.6.1.6.3.1.1.5", nil, true); err != nil {
+		t.Fatalf("AddView() err: %v", err)
+	}
+	v.AddGroup("trappers", Version2c, "traps")
+	v.AddAccess(VacmAccess{Group: "trappers", NotifyView: "traps"})
+
+	linkDown := SnmpPDU{Name: snmpTrapOID, Type: ObjectIdentifier, Value: ".1.3.6.1.6.3.1.1.5.3"}
+	vendor := SnmpPDU{Name: snmpTrapOID, Type: ObjectIdentifier, Value: ".1.3.6.1.4.1.99999.0.1"}
+	tests := []struct {
+		packet SnmpPacket
+		oid    string
+		want   bool
+	}{
+		{SnmpPacket{Version: Version2c, Community: "traps", PDUType: SNMPv2Trap,
+			Variables: []SnmpPDU{{Name: ".1.3.6.1.2.1.1.3.0", Type: TimeTicks, Value: uint32(1)}, linkDown}},
+			".1.3.6.1.6.3.1.1.5.3", true},
+		{SnmpPacket{Version: Version2c, Community: "traps", PDUType: InformRequest, Variables: []SnmpPDU{vendor}},
+			".1.3.6.1.4.1.99999.0.1", false},
+		{SnmpPacket{Version: Version2c, Community: "public", PDUType: SNMPv2Trap, Variables: []SnmpPDU{vendor}},
+			".1.3.6.1.4.1.99999.0.1", true},
+		{SnmpPacket{Version: Version2c, Community: "other", PDUType: SNMPv2Trap, Variables: []SnmpPDU{linkDown}},
+			".1.3.6.1.6.3.1.1.5.3", false},
+		{SnmpPacket{Version: Version2c, Community: "traps", PDUType: SNMPv2Trap}, "", false},
+		{SnmpPacket{Version: Version1, Community: "public", PDUType: Trap,
+			GenericTrap: 2, Enterprise: []int{1, 3, 6, 1, 4, 1, 99999}},
+			".1.3.6.1.6.3.1.1.5.3", true},
+		{SnmpPacket{Version: Version1, Community: "public", PDUType: Trap,
+			GenericTrap: 6, SpecificTrap: 7, Enterprise: []int{1, 3, 6, 1, 4, 1, 99999}},
+			".1.3.6.1.4.1.99999.0.7", true},
+		{SnmpPacket{Version: Version3, PDUType: SNMPv2Trap, MsgFlags: AuthPriv,
+			SecurityParameters: &UsmSecurityParameters{UserName: "admin"}, Variables: []SnmpPDU{linkDown}},
+			".1.3.6.1.6.3.1.1.5.3", false},
+	}
+	for i, test := range tests {
+		if got := notificationOid(&test.packet); got != test.oid {
+			t.Errorf("%d: notificationOid() got %q, expected %q", i, got, test.oid)
+		}
+		if got := v.allowsNotification(&test.packet); got != test.want {
+			t.Errorf("%d: allowsNotification() got %t, expected %t", i, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"fmt"
+	"math/big"
+)
+
+//
+// Typed accessors for SnmpPDU values, checking the type of the varbind and
+// the range of the value instead of asserting on Value, eg
+//
+//	n, err := pdu.Uint64()
+//
+// The accessor for text is Text rather than String (which formats the
+// whole varbind), and the one for OBJECT IDENTIFIER values is OidValue, as
+// Oid parses the varbind's Name.
+//
+
+// Int64 returns the value of an integer varbind (Integer, Counter32,
+// Gauge32, TimeTicks, Counter64 or Uinteger32). It is an error if the
+// value doesn't fit in an int64, as a Counter64 may not.
+func (p SnmpPDU) Int64() (int64, error) {
+	n, err := p.integer()
+	if err != nil {
+		return 0, err
+	}
+	if !n.IsInt64() {
+		return 0, fmt.Errorf("%s: value %s out of range for int64", p.Name, n)
+	}
+	return n.Int64(), nil
+}
+
+// Uint64 returns the value of an integer varbind, as Int64 does. It is an
+// error if the value is negative.
+func (p SnmpPDU) Uint64() (uint64, error) {
+	n, err := p.integer()
+	if err != nil {
+		return 0, err
+	}
+	if !n.IsUint64() {
+		return 0, fmt.Errorf("%s: value %s out of range for uint64", p.Name, n)
+	}
+	return n.Uint64(), nil
+}
+
+// Float64 returns the value of an Opaque float or double, or of an integer
+// varbind
+func (p SnmpPDU) Float64() (float64, error) {
+	switch v := p.Value.(type) {
+	case float32:
+		return float64(v), nil
+	case float64:
+		return v, nil
+	}
+	n, err := p.integer()
+	if err != nil {
+		return 0, err
+	}
+	f, _ := new(big.Float).SetInt(n).Float64()
+	return f, nil
+}
+
+func (p SnmpPDU) integer() (*big.Int, error) {
+	switch p.Type {
+	case Integer, Counter32, Gauge32, TimeTicks, Counter64, Uinteger32:
+		if n, ok := integerValue(p.Value); ok {
+			return n, nil
+		}
+	}
+	return nil, p.typeError("an integer")
+}
+
+// Bytes returns the value of an OctetString, or of an Opaque that isn't a
+// float or double. The slice is the PDU's own, not a copy.
+func (p SnmpPDU) Bytes() ([]byte, error) {
+	if p.Type == OctetString || p.Type == Opaque {
+		if b, ok := p.Value.([]byte); ok {
+			return b, nil
+		}
+	}
+	return nil, p.typeError("an OctetString")
+}
+
+// Text returns the value of an OctetString as a string, or the dotted form
+// of an IpAddress or OBJECT IDENTIFIER
+func (p SnmpPDU) Text() (string, error) {
+	switch v := p.Value.(type) {
+	case []byte:
+		if p.Type == OctetString {
+			return string(v), nil
+		}
+	case string:
+		if p.Type == OctetString || p.Type == IPAddress || p.Type == ObjectIdentifier {
+			return v, nil
+		}
+	case Oid:
+		if p.Type == ObjectIdentifier {
+			return v.String(), nil
+		}
+	}
+	return "", p.typeError("text")
+}
+
+// OidValue returns the value of an OBJECT IDENTIFIER varbind, eg the
+// sysObjectID, whether it was decoded as an Oid or a dotted string
+func (p SnmpPDU) OidValue() (Oid, error) {
+	if p.Type == ObjectIdentifier {
+		switch v := p.Value.(type) {
+		case Oid:
+			return v, nil
+		case string:
+			return ParseOid(v)
+		}
+	}
+	return nil, p.typeError("an OBJECT IDENTIFIER")
+}
+
+func (p SnmpPDU) typeError(want string) error {
+	return &valueTypeError{p, want}
+}
+
+// valueTypeError is the error from an accessor for a varbind of another
+// type, which wraps an *ExceptionError for an exception
+type valueTypeError struct {
+	pdu  SnmpPDU
+	want string
+}
+
+func (e *valueTypeError) Error() string {
+	return fmt.Sprintf("%s: %s is not %s", e.pdu.Name, e.pdu.Type, e.want)
+}
+
+func (e *valueTypeError) Unwrap() error {
+	return e.pdu.Err()
+}
@@ -0,0 +1,85 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIntegerAccessors(t *testing.T) {
+	tests := []struct {
+		pdu       SnmpPDU
+		i         int64
+		u         uint64
+		iErr      string
+		uErr      string
+		float     float64
+		floatFail bool
+	}{
+		{pdu: SnmpPDU{Type: Integer, Value: -5}, i: -5, uErr: "out of range for uint64", float: -5},
+		{pdu: SnmpPDU{Type: Counter32, Value: uint(4000000000)}, i: 4000000000, u: 4000000000, float: 4e9},
+		{pdu: SnmpPDU{Type: TimeTicks, Value: 100}, i: 100, u: 100, float: 100},
+		{pdu: SnmpPDU{Type: Uinteger32, Value: uint32(7)}, i: 7, u: 7, float: 7},
+		{pdu: SnmpPDU{Type: Counter64, Value: uint64(1) << 63}, iErr: "out of range for int64", u: 1 << 63, float: 1 << 63},
+		{pdu: SnmpPDU{Type: OpaqueDouble, Value: 1.5}, iErr: "not an integer", uErr: "not an integer", float: 1.5},
+		{pdu: SnmpPDU{Type: OctetString, Value: []byte("5")}, iErr: "not an integer", uErr: "not an integer", floatFail: true},
+		{pdu: SnmpPDU{Type: NoSuchInstance}, iErr: "NoSuchInstance is not an integer", uErr: "not an integer", floatFail: true},
+	}
+	for _, test := range tests {
+		test.pdu.Name = ".1.3.6.1.2.1.1.3.0"
+		i, err := test.pdu.Int64()
+		if !errorMatches(err, test.iErr) || i != test.i {
+			t.Errorf("%v Int64: got %d, %v", test.pdu, i, err)
+		}
+		u, err := test.pdu.Uint64()
+		if !errorMatches(err, test.uErr) || u != test.u {
+			t.Errorf("%v Uint64: got %d, %v", test.pdu, u, err)
+		}
+		f, err := test.pdu.Float64()
+		if (err != nil) != test.floatFail || f != test.float {
+			t.Errorf("%v Float64: got %g, %v", test.pdu, f, err)
+		}
+	}
+}
+
+func errorMatches(err error, want string) bool {
+	if want == "" {
+		return err == nil
+	}
+	return err != nil && strings.Contains(err.Error(), want)
+}
+
+func TestTextAccessors(t *testing.T) {
+	tests := []struct {
+		pdu   SnmpPDU
+		bytes []byte
+		text  string
+		oid   Oid
+		fails string // which of b(ytes), t(ext) and o(id) fail
+	}{
+		{SnmpPDU{Type: OctetString, Value: []byte("eth0")}, []byte("eth0"), "eth0", nil, "o"},
+		{SnmpPDU{Type: Opaque, Value: []byte{1}}, []byte{1}, "", nil, "to"},
+		{SnmpPDU{Type: IPAddress, Value: "192.0.2.1"}, nil, "192.0.2.1", nil, "bo"},
+		{SnmpPDU{Type: ObjectIdentifier, Value: ".1.3.6.1.4.1.8072"}, nil, ".1.3.6.1.4.1.8072", Oid{1, 3, 6, 1, 4, 1, 8072}, "b"},
+		{SnmpPDU{Type: Integer, Value: 1}, nil, "", nil, "bto"},
+		{SnmpPDU{Type: EndOfMibView}, nil, "", nil, "bto"},
+	}
+	for _, test := range tests {
+		b, err := test.pdu.Bytes()
+		if (err != nil) != strings.Contains(test.fails, "b") || !reflect.DeepEqual(b, test.bytes) {
+			t.Errorf("%v Bytes: got %v, %v", test.pdu, b, err)
+		}
+		s, err := test.pdu.Text()
+		if (err != nil) != strings.Contains(test.fails, "t") || s != test.text {
+			t.Errorf("%v Text: got %q, %v", test.pdu, s, err)
+		}
+		oid, err := test.pdu.OidValue()
+		if (err != nil) != strings.Contains(test.fails, "o") || !oid.Equal(test.oid) {
+			t.Errorf("%v OidValue: got %v, %v", test.pdu, oid, err)
+		}
+	}
+}
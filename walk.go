@@ -5,41 +5,55 @@
 package gosnmp
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"strings"
 )
 
-func (x *GoSNMP) walk(getRequestType PDUType, rootOid string, walkFn WalkFunc) error {
-	if rootOid == "" || rootOid == "." {
-		rootOid = baseOid
-	}
+func (x *GoSNMP) walk(ctx context.Context, getRequestType PDUType, rootOid string, walkFn WalkFunc) error {
+	return x.walkFrom(ctx, getRequestType, rootOid, "", walkFn)
+}
 
-	if !strings.HasPrefix(rootOid, ".") {
-		rootOid = string(".") + rootOid
-	}
+// walkFrom walks the subtree at rootOid, starting after startOid if it
+// isn't empty
+func (x *GoSNMP) walkFrom(ctx context.Context, getRequestType PDUType, rootOid string, startOid string, walkFn WalkFunc) error {
+	ctx, cancel := x.withBudget(ctx)
+	defer cancel()
+	rootOid = walkRoot(rootOid)
 
 	oid := rootOid
-	requests := 0
-	maxReps := x.MaxRepetitions
-	if maxReps == 0 {
-		maxReps = defaultMaxRepetitions
+	if startOid != "" {
+		oid = "." + trimOidDot(startOid)
 	}
+	requests := 0
+	maxReps := x.maxRepetitions(ctx)
+	order := x.newWalkOrder(oid)
 
 RequestLoop:
 	for {
 
 		requests++
 
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
 		var response *SnmpPacket
 		var err error
 
 		switch getRequestType {
 		case GetBulkRequest:
-			response, err = x.GetBulk([]string{oid}, uint8(x.NonRepeaters), uint8(maxReps))
+			reps := x.bulkRepetitions(int(maxReps))
+			response, err = x.GetBulkCtx(ctx, []string{oid}, uint8(x.NonRepeaters), uint8(reps))
+			if ctx.Err() == nil && x.adaptRepetitions(reps, int(maxReps), response, err) {
+				requests-- // the request is retried with fewer repetitions
+				continue RequestLoop
+			}
 		case GetNextRequest:
-			response, err = x.GetNext([]string{oid})
+			response, err = x.GetNextCtx(ctx, []string{oid})
 		case GetRequest:
-			response, err = x.Get([]string{oid})
+			response, err = x.GetCtx(ctx, []string{oid})
 		default:
 			response, err = nil, fmt.Errorf("Unsupported request type: %d", getRequestType)
 		}
@@ -51,14 +65,20 @@ RequestLoop:
 			break RequestLoop
 		}
 
+		if getRequestType == GetBulkRequest && response.Error != NoError && response.Error != NoSuchName {
+			return fmt.Errorf("GetBulk of %s failed: %w", oid, response.Err())
+		}
 		if response.Error == NoSuchName {
 			x.Logger.Print("Walk terminated with NoSuchName")
 			break RequestLoop
 		}
 
+		accepted := false
 		for k, v := range response.Variables {
-			if v.Type == EndOfMibView || v.Type == NoSuchObject || v.Type == NoSuchInstance {
-				x.Logger.Printf("BulkWalk terminated with type 0x%x", v.Type)
+			if isException(v.Type) {
+				if err := x.walkException(v, walkFn); err != nil {
+					return err
+				}
 				break RequestLoop
 			}
 			if !strings.HasPrefix(v.Name, rootOid+".") {
@@ -68,31 +88,208 @@ RequestLoop:
 				// need to perform a regular get request
 				// this request has been too narrowly defined to be found with a getNext
 				// Issue #78 #93
-				if requests == 1 && k == 0 {
+				if requests == 1 && k == 0 && oid == rootOid {
 					getRequestType = GetRequest
 					continue RequestLoop
 				}
 				break RequestLoop
 			}
-			if v.Name == oid {
-				return fmt.Errorf("OID not increasing: %s", v.Name)
+			ok, err := order.accept(v.Name)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
 			}
+			accepted = true
 			// Report our pdu
 			if err := walkFn(v); err != nil {
 				return err
 			}
 		}
+		if !accepted {
+			return loopError(oid)
+		}
 		// Save last oid for next request
-		oid = response.Variables[len(response.Variables)-1].Name
+		oid = order.prev
 	}
 	x.Logger.Printf("BulkWalk completed in %d requests", requests)
 	return nil
 }
 
-func (x *GoSNMP) walkAll(getRequestType PDUType, rootOid string) (results []SnmpPDU, err error) {
-	err = x.walk(getRequestType, rootOid, func(dataUnit SnmpPDU) error {
+// walkRoot normalises the root of a walk
+func walkRoot(rootOid string) string {
+	if rootOid == "" || rootOid == "." {
+		return baseOid
+	}
+	if !strings.HasPrefix(rootOid, ".") {
+		return "." + rootOid
+	}
+	return rootOid
+}
+
+// errWalkStopped is returned by a WalkFunc to end a walk early without an
+// error being reported
+var errWalkStopped = errors.New("Walk stopped")
+
+// WalkResult is a value received from WalkChan or BulkWalkChan, or the
+// error that ended the walk
+type WalkResult struct {
+	PDU SnmpPDU
+	Err error
+}
+
+// WalkChan walks the subtree at rootOid in the background as Walk does,
+// sending the values on the returned channel, which is closed when the
+// walk ends. If the walk fails the last result holds the error. Requests
+// are only made as the values are received, so a slow reader holds the
+// walk back; cancel ctx to abandon the walk early.
+func (x *GoSNMP) WalkChan(ctx context.Context, rootOid string) <-chan WalkResult {
+	return x.walkChan(ctx, GetNextRequest, rootOid)
+}
+
+// BulkWalkChan is like WalkChan, but walks with GETBULK as BulkWalk does
+func (x *GoSNMP) BulkWalkChan(ctx context.Context, rootOid string) <-chan WalkResult {
+	return x.walkChan(ctx, GetBulkRequest, rootOid)
+}
+
+func (x *GoSNMP) walkChan(ctx context.Context, getRequestType PDUType, rootOid string) <-chan WalkResult {
+	results := make(chan WalkResult)
+	go func() {
+		defer close(results)
+		err := x.walk(ctx, getRequestType, rootOid, func(pdu SnmpPDU) error {
+			select {
+			case results <- WalkResult{PDU: pdu}:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		if err != nil {
+			select {
+			case results <- WalkResult{Err: err}:
+			case <-ctx.Done():
+			}
+		}
+	}()
+	return results
+}
+
+func (x *GoSNMP) walkAll(ctx context.Context, getRequestType PDUType, rootOid string) (results []SnmpPDU, err error) {
+	err = x.walk(ctx, getRequestType, rootOid, func(dataUnit SnmpPDU) error {
 		results = append(results, dataUnit)
 		return nil
 	})
 	return results, err
 }
+
+// walkColumns walks several columns (or other subtrees) side by side, asking
+// for the next values of all of the unfinished ones in each request
+func (x *GoSNMP) walkColumns(ctx context.Context, columnOids []string, walkFn WalkFunc) error {
+	ctx, cancel := x.withBudget(ctx)
+	defer cancel()
+	type column struct {
+		root  string
+		order *walkOrder
+	}
+	active := make([]*column, len(columnOids))
+	for i, oid := range columnOids {
+		if !strings.HasPrefix(oid, ".") {
+			oid = "." + oid
+		}
+		active[i] = &column{root: oid, order: x.newWalkOrder(oid)}
+	}
+	maxOids := x.MaxOids
+	if maxOids <= 0 {
+		maxOids = MaxOids
+	}
+	maxReps := x.maxRepetitions(ctx)
+
+	requests := 0
+	for len(active) > 0 {
+		requests++
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		batch := active
+		if len(batch) > maxOids {
+			batch = batch[:maxOids]
+		}
+		oids := make([]string, len(batch))
+		for i, c := range batch {
+			oids[i] = c.order.prev
+		}
+
+		var response *SnmpPacket
+		var err error
+		if x.Version == Version1 {
+			response, err = x.GetNextCtx(ctx, oids)
+		} else {
+			response, err = x.GetBulkCtx(ctx, oids, 0, uint8(maxReps))
+		}
+		if err != nil {
+			return err
+		}
+		done := make(map[*column]bool)
+		switch i := int(response.ErrorIndex) - 1; {
+		case response.Error == NoSuchName && i >= 0 && i < len(batch):
+			// an SNMPv1 agent reached the end of the mib in one of the
+			// columns; ask again without it
+			done[batch[i]] = true
+			response.Variables = nil
+		case response.Error == NoSuchName || len(response.Variables) == 0:
+			active = nil
+			continue
+		case response.Error != NoError:
+			return fmt.Errorf("Walk of %s failed: %w", strings.Join(oids, ", "), response.Err())
+		}
+
+		// the varbinds are in rows, of one value from each of the columns
+		returned, accepted := make(map[*column]bool), make(map[*column]bool)
+		for i, v := range response.Variables {
+			c := batch[i%len(batch)]
+			if done[c] {
+				continue
+			}
+			returned[c] = true
+			if isException(v.Type) {
+				done[c] = true
+				if err := x.walkException(v, walkFn); err != nil {
+					return err
+				}
+				continue
+			}
+			if !OidInSubtree(v.Name, c.root) || v.Name == c.root {
+				done[c] = true
+				continue
+			}
+			ok, err := c.order.accept(v.Name)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+			accepted[c] = true
+			if err := walkFn(v); err != nil {
+				return err
+			}
+		}
+		for i, c := range batch {
+			if returned[c] && !done[c] && !accepted[c] {
+				return loopError(oids[i])
+			}
+		}
+
+		remaining := active[:0]
+		for _, c := range active {
+			if !done[c] {
+				remaining = append(remaining, c)
+			}
+		}
+		active = remaining
+	}
+	x.Logger.Printf("Column walk completed in %d requests", requests)
+	return nil
+}
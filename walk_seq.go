@@ -0,0 +1,61 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+//go:build go1.23
+
+package gosnmp
+
+import (
+	"context"
+	"iter"
+)
+
+// WalkSeq is like Walk, but the values are returned as an iterator for use
+// with range, eg
+//
+//	for pdu, err := range x.WalkSeq(".1.3.6.1.2.1.2.2") {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+//
+// Each request is only made once the values of the one before have been
+// consumed, and breaking out of the loop ends the walk. An error ends the
+// sequence, and is yielded with an empty SnmpPDU.
+func (x *GoSNMP) WalkSeq(rootOid string) iter.Seq2[SnmpPDU, error] {
+	return x.walkSeq(context.Background(), GetNextRequest, rootOid)
+}
+
+// WalkSeqCtx is like WalkSeq, but the walk stops with ctx.Err() when ctx is
+// cancelled or its deadline passes.
+func (x *GoSNMP) WalkSeqCtx(ctx context.Context, rootOid string) iter.Seq2[SnmpPDU, error] {
+	return x.walkSeq(ctx, GetNextRequest, rootOid)
+}
+
+// BulkWalkSeq is like BulkWalk, but the values are returned as an iterator,
+// see WalkSeq
+func (x *GoSNMP) BulkWalkSeq(rootOid string) iter.Seq2[SnmpPDU, error] {
+	return x.walkSeq(context.Background(), GetBulkRequest, rootOid)
+}
+
+// BulkWalkSeqCtx is like BulkWalkSeq, but the walk stops with ctx.Err()
+// when ctx is cancelled or its deadline passes.
+func (x *GoSNMP) BulkWalkSeqCtx(ctx context.Context, rootOid string) iter.Seq2[SnmpPDU, error] {
+	return x.walkSeq(ctx, GetBulkRequest, rootOid)
+}
+
+func (x *GoSNMP) walkSeq(ctx context.Context, getRequestType PDUType, rootOid string) iter.Seq2[SnmpPDU, error] {
+	return func(yield func(SnmpPDU, error) bool) {
+		err := x.walk(ctx, getRequestType, rootOid, func(pdu SnmpPDU) error {
+			if !yield(pdu, nil) {
+				return errWalkStopped
+			}
+			return nil
+		})
+		if err != nil && err != errWalkStopped {
+			yield(SnmpPDU{}, err)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+//go:build go1.23
+
+package gosnmp
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestWalkSeq(t *testing.T) {
+	r := newTestResponder(t, tableTestPdus)
+	defer r.Close()
+	x := r.client(t)
+	defer x.Conn.Close()
+	x.MaxRepetitions = 2
+
+	var names []string
+	for pdu, err := range x.BulkWalkSeq(".1.3.6.1.2.1.2.2.1.1") {
+		if err != nil {
+			t.Fatalf("BulkWalkSeq() err: %v", err)
+		}
+		names = append(names, pdu.Name)
+	}
+	want := []string{".1.3.6.1.2.1.2.2.1.1.1", ".1.3.6.1.2.1.2.2.1.1.2", ".1.3.6.1.2.1.2.2.1.1.10"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("got %v, want %v", names, want)
+	}
+
+	// breaking out stops the walk without further requests
+	before := len(r.received())
+	for range x.WalkSeq(".1.3.6.1.2.1.2.2") {
+		break
+	}
+	if n := len(r.received()) - before; n != 1 {
+		t.Errorf("got %d requests after break, want 1", n)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var errs []error
+	for pdu, err := range x.WalkSeqCtx(ctx, ".1.3.6.1.2.1.2.2") {
+		if pdu.Name != "" {
+			t.Errorf("got %s from a cancelled walk", pdu.Name)
+		}
+		errs = append(errs, err)
+	}
+	if len(errs) != 1 || errs[0] != context.Canceled {
+		t.Errorf("got errors %v, want context.Canceled", errs)
+	}
+}
@@ -0,0 +1,40 @@
+// Copyright 2012-2016 The GoSNMP Authors. All rights reserved.  Use of this
+// source code is governed by a BSD-style license that can be found in the
+// LICENSE file.
+
+package gosnmp
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestWalkChan(t *testing.T) {
+	r := newTestResponder(t, tableTestPdus)
+	defer r.Close()
+	x := r.client(t)
+	defer x.Conn.Close()
+
+	var names []string
+	for result := range x.BulkWalkChan(context.Background(), ".1.3.6.1.2.1.2.2.1.2") {
+		if result.Err != nil {
+			t.Fatalf("BulkWalkChan() err: %v", result.Err)
+		}
+		names = append(names, result.PDU.Name)
+	}
+	want := []string{".1.3.6.1.2.1.2.2.1.2.1", ".1.3.6.1.2.1.2.2.1.2.10"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("got %v, want %v", names, want)
+	}
+
+	// abandoning the walk part way closes the channel
+	ctx, cancel := context.WithCancel(context.Background())
+	results := x.WalkChan(ctx, ".1.3.6.1.2.1.2.2")
+	if result := <-results; result.Err != nil || result.PDU.Name != ".1.3.6.1.2.1.2.2.1.1.1" {
+		t.Errorf("got %+v", result)
+	}
+	cancel()
+	for range results {
+	}
+}